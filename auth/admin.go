@@ -0,0 +1,20 @@
+package auth
+
+import (
+	"minhas_economias/models"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AdminRequired restringe a rota a administradores. Deve vir depois de AuthRequired.
+func AdminRequired() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user, ok := c.MustGet("user").(*models.User)
+		if !ok || !user.IsAdmin {
+			abortWithMessage(c, http.StatusForbidden, "Acesso restrito a administradores.")
+			return
+		}
+		c.Next()
+	}
+}
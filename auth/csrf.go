@@ -0,0 +1,108 @@
+package auth
+
+import (
+	"crypto/subtle"
+	"encoding/base64"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/securecookie"
+	"github.com/gorilla/sessions"
+)
+
+const (
+	// csrfSessionKey guarda o token na sessão do servidor (fonte da verdade).
+	csrfSessionKey = "csrf_token"
+	// CSRFCookieName é o cookie legível pelo JavaScript que espelha o token da sessão.
+	CSRFCookieName = "csrf_token"
+	// CSRFHeaderName é o cabeçalho enviado pelas chamadas fetch.
+	CSRFHeaderName = "X-CSRF-Token"
+	// CSRFFormField é o campo oculto enviado pelos formulários HTML.
+	CSRFFormField = "csrf_token"
+)
+
+// CSRFProtect garante que toda requisição que altera estado (POST, PUT, PATCH, DELETE)
+// traga o token CSRF da sessão, seja no cabeçalho X-CSRF-Token ou no campo csrf_token.
+func CSRFProtect() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		session, _ := store.Get(c.Request, "session_token")
+		token, _ := session.Values[csrfSessionKey].(string)
+		if token == "" {
+			token = issueCSRFToken(c, session)
+			if err := session.Save(c.Request, c.Writer); err != nil {
+				log.Printf("Aviso: não foi possível salvar o token CSRF na sessão: %v", err)
+			}
+		} else {
+			setCSRFCookie(c, session, token)
+		}
+
+		if isSafeMethod(c.Request.Method) {
+			c.Next()
+			return
+		}
+
+		sent := c.GetHeader(CSRFHeaderName)
+		if sent == "" {
+			sent = c.PostForm(CSRFFormField)
+		}
+		if sent == "" || subtle.ConstantTimeCompare([]byte(sent), []byte(token)) != 1 {
+			log.Printf("AVISO: Requisição %s %s recusada por token CSRF ausente ou inválido (IP %s).", c.Request.Method, c.Request.URL.Path, c.ClientIP())
+			rejectCSRF(c)
+			return
+		}
+		c.Next()
+	}
+}
+
+// issueCSRFToken gera um novo token na sessão e atualiza o cookie legível.
+// Cabe a quem chama salvar a sessão.
+func issueCSRFToken(c *gin.Context, session *sessions.Session) string {
+	token := base64.RawURLEncoding.EncodeToString(securecookie.GenerateRandomKey(32))
+	session.Values[csrfSessionKey] = token
+	setCSRFCookie(c, session, token)
+	return token
+}
+
+// setCSRFCookie envia o cookie legível apenas quando o navegador ainda não tem o valor atual.
+func setCSRFCookie(c *gin.Context, session *sessions.Session, token string) {
+	if existing, err := c.Request.Cookie(CSRFCookieName); err == nil && existing.Value == token {
+		return
+	}
+	http.SetCookie(c.Writer, &http.Cookie{
+		Name:     CSRFCookieName,
+		Value:    token,
+		Path:     "/",
+		MaxAge:   session.Options.MaxAge,
+		Secure:   session.Options.Secure,
+		HttpOnly: false, // Precisa ser lido pelo static/js/csrf.js
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+func rejectCSRF(c *gin.Context) {
+	abortWithMessage(c, http.StatusForbidden, "Token de segurança (CSRF) ausente ou inválido. Recarregue a página e tente novamente.")
+}
+
+// abortWithMessage interrompe a requisição com a mensagem em JSON (chamadas fetch) ou na página de erro.
+func abortWithMessage(c *gin.Context, status int, msg string) {
+	if strings.Contains(c.GetHeader("Accept"), "application/json") || strings.Contains(c.ContentType(), "json") {
+		c.AbortWithStatusJSON(status, gin.H{"error": msg})
+		return
+	}
+	c.HTML(status, "error.html", gin.H{
+		"Titulo":       "Ocorreu um Erro",
+		"StatusCode":   status,
+		"ErrorMessage": msg,
+	})
+	c.Abort()
+}
+
+func isSafeMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return true
+	}
+	return false
+}
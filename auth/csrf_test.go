@@ -0,0 +1,103 @@
+package auth
+
+import (
+	"minhas_economias/database"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func createCSRFTestRouter() *gin.Engine {
+	store = NewDBStore([]byte("chave-de-teste-com-32-bytes-0123"))
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.LoadHTMLFiles("../templates/error.html")
+	r.Use(CSRFProtect())
+	r.GET("/form", func(c *gin.Context) { c.String(http.StatusOK, "ok") })
+	r.POST("/movimentacoes", func(c *gin.Context) { c.String(http.StatusOK, "gravado") })
+	r.DELETE("/movimentacoes/:id", func(c *gin.Context) { c.String(http.StatusOK, "removido") })
+	return r
+}
+
+// fetchCSRFCookies abre uma página (GET) e devolve os cookies de sessão e o token CSRF emitidos.
+func fetchCSRFCookies(t *testing.T, r http.Handler) ([]*http.Cookie, string) {
+	t.Helper()
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/form", nil))
+	cookies := w.Result().Cookies()
+	for _, c := range cookies {
+		if c.Name == CSRFCookieName {
+			return cookies, c.Value
+		}
+	}
+	t.Fatal("Esperado cookie csrf_token na resposta do GET")
+	return nil, ""
+}
+
+func performCSRFRequest(r http.Handler, method, path string, form url.Values, cookies []*http.Cookie, headers map[string]string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	for _, c := range cookies {
+		req.AddCookie(c)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestCSRFProtect_RejectsCrossSiteForms(t *testing.T) {
+	setupResetTestDB(t)
+	defer database.CloseDB()
+	router := createCSRFTestRouter()
+	cookies, token := fetchCSRFCookies(t, router)
+
+	// Um site malicioso consegue fazer o navegador enviar o cookie de sessão, mas não conhece o token.
+	forged := url.Values{"descricao": {"Transferência"}, "valor": {"-1000"}}
+	if w := performCSRFRequest(router, "POST", "/movimentacoes", forged, cookies, nil); w.Code != http.StatusForbidden {
+		t.Errorf("Esperado status 403 para formulário sem token, obteve %d", w.Code)
+	}
+
+	forged.Set(CSRFFormField, "token-adivinhado")
+	if w := performCSRFRequest(router, "POST", "/movimentacoes", forged, cookies, nil); w.Code != http.StatusForbidden {
+		t.Errorf("Esperado status 403 para token inválido, obteve %d", w.Code)
+	}
+
+	// Sem a sessão, nem mesmo o token correto é aceito.
+	forged.Set(CSRFFormField, token)
+	if w := performCSRFRequest(router, "POST", "/movimentacoes", forged, nil, nil); w.Code != http.StatusForbidden {
+		t.Errorf("Esperado status 403 para token sem sessão correspondente, obteve %d", w.Code)
+	}
+
+	w := performCSRFRequest(router, "DELETE", "/movimentacoes/1", nil, cookies, map[string]string{"Accept": "application/json"})
+	if w.Code != http.StatusForbidden || !strings.Contains(w.Body.String(), "error") {
+		t.Errorf("Esperado 403 em JSON para DELETE sem token, obteve %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestCSRFProtect_AcceptsValidToken(t *testing.T) {
+	setupResetTestDB(t)
+	defer database.CloseDB()
+	router := createCSRFTestRouter()
+	cookies, token := fetchCSRFCookies(t, router)
+
+	form := url.Values{"descricao": {"Mercado"}, CSRFFormField: {token}}
+	if w := performCSRFRequest(router, "POST", "/movimentacoes", form, cookies, nil); w.Code != http.StatusOK {
+		t.Errorf("Esperado status 200 para formulário com token válido, obteve %d", w.Code)
+	}
+
+	if w := performCSRFRequest(router, "DELETE", "/movimentacoes/1", nil, cookies, map[string]string{CSRFHeaderName: token}); w.Code != http.StatusOK {
+		t.Errorf("Esperado status 200 para fetch com cabeçalho X-CSRF-Token, obteve %d", w.Code)
+	}
+
+	// A aplicação não autentica por Bearer: o cabeçalho não dispensa o token da sessão.
+	if w := performCSRFRequest(router, "POST", "/movimentacoes", url.Values{}, cookies, map[string]string{"Authorization": "Bearer abc123"}); w.Code != http.StatusForbidden {
+		t.Errorf("Esperado 403 para requisição com Bearer e sem token CSRF, obteve %d", w.Code)
+	}
+}
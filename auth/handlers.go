@@ -2,16 +2,18 @@ package auth
 
 import (
 	"log"
+	"minhas_economias/middleware"
 	"minhas_economias/models"
 	"net/http"
 	"os"
+	"strconv"
 
 	"github.com/gin-gonic/gin"
 	"github.com/gorilla/sessions"
 )
 
 // A chave para assinar a sessão. DEVE ser secreta e, em produção, vir de uma variável de ambiente.
-var store = sessions.NewCookieStore([]byte(os.Getenv("SESSION_KEY")))
+var store = NewDBStore([]byte(os.Getenv("SESSION_KEY")))
 
 const maxAgeSeconds = 3600 * 24 * 7 // 7 dias
 
@@ -21,7 +23,7 @@ func InitSessionStore() {
 	if key == "" {
 		log.Fatal("ERRO CRÍTICO: SESSION_KEY não foi definida no ambiente ou .env")
 	}
-	store = sessions.NewCookieStore([]byte(key))
+	store = NewDBStore([]byte(key))
     
     // Configurações opcionais de segurança do cookie
     store.Options = &sessions.Options{
@@ -50,17 +52,44 @@ func GetLoginPage(c *gin.Context) {
 func PostLogin(c *gin.Context) {
 	email := c.PostForm("email")
 	password := c.PostForm("password")
+	ip := c.ClientIP()
+
+	// Proteção contra força bruta: recusa a tentativa enquanto o IP ou a conta estiverem em espera.
+	if wait := limiter.RetryAfter(ip, email); wait > 0 {
+		c.Header("Retry-After", strconv.Itoa(int(wait.Seconds())+1))
+		c.HTML(http.StatusTooManyRequests, "login.html", gin.H{
+			"Titulo": "Login",
+			"Error":  "Muitas tentativas de login. Tente novamente em " + formatRetryAfter(wait) + ".",
+		})
+		return
+	}
 
 	user, err := GetUserByEmail(email)
 	if err != nil || !CheckPasswordHash(password, user.PasswordHash) {
+		middleware.AuthFailures.Inc()
+		if limiter.RegisterFailure(ip, email) {
+			log.Printf("AVISO: Login bloqueado temporariamente para '%s' (IP %s) após falhas consecutivas.", email, ip)
+			middleware.RecordAuditEvent(email, "LOCKOUT", "/login", http.StatusTooManyRequests, 0)
+		}
 		c.HTML(http.StatusUnauthorized, "login.html", gin.H{
 			"Titulo": "Login",
 			"Error":  "E-mail ou senha inválidos.",
 		})
 		return
 	}
+	limiter.Reset(email)
+
+	if user.Disabled {
+		c.HTML(http.StatusForbidden, "login.html", gin.H{
+			"Titulo": "Login",
+			"Error":  "Esta conta está desativada. Procure o administrador.",
+		})
+		return
+	}
 
 	session, _ := store.Get(c.Request, "session_token")
+	store.Regenerate(session) // Novo ID a cada login, evitando fixação de sessão
+	issueCSRFToken(c, session) // ...e um novo token CSRF
 	session.Values["user_id"] = user.ID
 	session.Values["user_email"] = user.Email // <-- ALTERADO: Adicionado para o middleware buscar o usuário completo
 	session.Options.MaxAge = maxAgeSeconds    // Define o tempo de expiração do cookie
@@ -78,6 +107,7 @@ func PostLogin(c *gin.Context) {
 		})
 		return
 	}
+	store.Touch(ip, session)
 
 	c.Redirect(http.StatusFound, "/")
 }
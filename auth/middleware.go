@@ -28,8 +28,8 @@ func AuthRequired() gin.HandlerFunc {
 
 		// A partir daqui, temos certeza de que userEmail é uma string válida
 		user, err := GetUserByEmail(userEmail)
-		if err != nil {
-			// Se o usuário não for encontrado no DB (pode ter sido deletado), desloga
+		if err != nil || user.Disabled {
+			// Se o usuário não for encontrado no DB (pode ter sido deletado) ou foi desativado, desloga
 			session.Options.MaxAge = -1
 			session.Save(c.Request, c.Writer)
 			c.Redirect(http.StatusFound, "/login")
@@ -37,9 +37,13 @@ func AuthRequired() gin.HandlerFunc {
 			return
 		}
 
+		store.Touch(c.ClientIP(), session)
+		loadWorkspace(c, session, user)
+
 		// Armazena o objeto User e o ID no contexto para uso nos handlers
 		c.Set("user", user)
 		c.Set("userID", user.ID)
+		c.Set("sessionID", session.ID)
 
 		c.Next()
 	}
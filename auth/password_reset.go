@@ -0,0 +1,246 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"minhas_economias/database"
+	"minhas_economias/mailer"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// resetTokenTTL é o tempo de validade de um link de redefinição de senha.
+const resetTokenTTL = time.Hour
+
+// ErrInvalidResetToken indica um token inexistente, expirado ou já utilizado.
+var ErrInvalidResetToken = errors.New("o link de redefinição é inválido ou expirou")
+
+// hashResetToken retorna o SHA-256 do token. Apenas o hash é persistido no banco.
+func hashResetToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// CreatePasswordResetToken gera um token aleatório de uso único para o usuário e grava seu hash.
+func CreatePasswordResetToken(userID int64) (string, error) {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", fmt.Errorf("erro ao gerar token de redefinição: %w", err)
+	}
+	token := hex.EncodeToString(raw)
+
+	query := database.Rebind("INSERT INTO password_reset_tokens (user_id, token_hash, expires_at) VALUES (?, ?, ?)")
+	if _, err := database.GetDB().Exec(query, userID, hashResetToken(token), time.Now().Add(resetTokenTTL).UTC()); err != nil {
+		return "", fmt.Errorf("erro ao salvar token de redefinição: %w", err)
+	}
+	return token, nil
+}
+
+// rowQuerier é satisfeita tanto por *sql.DB quanto por *sql.Tx.
+type rowQuerier interface {
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+// lookupResetToken retorna o ID do token e o usuário dono, se o token ainda for válido.
+func lookupResetToken(q rowQuerier, token string) (int64, int64, error) {
+	var tokenID, userID int64
+	var expiresAt time.Time
+	var usedAt sql.NullTime
+
+	query := database.Rebind("SELECT id, user_id, expires_at, used_at FROM password_reset_tokens WHERE token_hash = ?")
+	err := q.QueryRow(query, hashResetToken(token)).Scan(&tokenID, &userID, &expiresAt, &usedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return 0, 0, ErrInvalidResetToken
+		}
+		return 0, 0, fmt.Errorf("erro ao buscar token de redefinição: %w", err)
+	}
+	if usedAt.Valid || time.Now().After(expiresAt) {
+		return 0, 0, ErrInvalidResetToken
+	}
+	return tokenID, userID, nil
+}
+
+// ResetPasswordWithToken consome o token e grava a nova senha do usuário numa única transação.
+func ResetPasswordWithToken(token, newPassword string) error {
+	hash, err := HashPassword(newPassword)
+	if err != nil {
+		return fmt.Errorf("erro ao gerar hash da senha: %w", err)
+	}
+
+	tx, err := database.GetDB().Begin()
+	if err != nil {
+		return fmt.Errorf("erro ao iniciar transação: %w", err)
+	}
+	defer tx.Rollback()
+
+	tokenID, userID, err := lookupResetToken(tx, token)
+	if err != nil {
+		return err
+	}
+
+	// Marca o token como usado apenas se ninguém o consumiu entre a leitura e a escrita.
+	now := time.Now().UTC()
+	res, err := tx.Exec(database.Rebind("UPDATE password_reset_tokens SET used_at = ? WHERE id = ? AND used_at IS NULL"), now, tokenID)
+	if err != nil {
+		return fmt.Errorf("erro ao invalidar token de redefinição: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n != 1 {
+		return ErrInvalidResetToken
+	}
+
+	if _, err := tx.Exec(database.Rebind("UPDATE users SET password_hash = ? WHERE id = ?"), hash, userID); err != nil {
+		return fmt.Errorf("erro ao atualizar a senha: %w", err)
+	}
+
+	// Qualquer outro link pendente deixa de valer após a troca de senha.
+	if _, err := tx.Exec(database.Rebind("UPDATE password_reset_tokens SET used_at = ? WHERE user_id = ? AND used_at IS NULL"), now, userID); err != nil {
+		return fmt.Errorf("erro ao invalidar tokens pendentes: %w", err)
+	}
+
+	// Sessões abertas com a senha antiga são encerradas.
+	if _, err := tx.Exec(database.Rebind("DELETE FROM user_sessions WHERE user_id = ?"), userID); err != nil {
+		return fmt.Errorf("erro ao encerrar sessões: %w", err)
+	}
+
+	return tx.Commit()
+}
+
+// baseURL retorna a URL pública da aplicação (APP_BASE_URL), ou "" se não estiver definida.
+// Nunca é derivada do Host ou de X-Forwarded-Proto, que são controlados pelo cliente.
+func baseURL() string {
+	return strings.TrimRight(os.Getenv("APP_BASE_URL"), "/")
+}
+
+// GetForgotPasswordPage renderiza o formulário de solicitação de redefinição de senha.
+func GetForgotPasswordPage(c *gin.Context) {
+	c.HTML(http.StatusOK, "forgot_password.html", gin.H{
+		"Titulo": "Esqueci minha senha",
+	})
+}
+
+// PostForgotPassword envia o link de redefinição por e-mail.
+// A resposta é sempre a mesma, exista ou não a conta, para não revelar e-mails cadastrados.
+// Os pedidos são limitados por IP e por e-mail, para que a rota não sirva para bombardear caixas de entrada.
+func PostForgotPassword(c *gin.Context) {
+	email := strings.TrimSpace(c.PostForm("email"))
+	if email == "" {
+		c.HTML(http.StatusBadRequest, "forgot_password.html", gin.H{
+			"Titulo": "Esqueci minha senha",
+			"Error":  "Informe o e-mail da sua conta.",
+		})
+		return
+	}
+
+	ip := c.ClientIP()
+	if wait := resetLimiter.RetryAfter(ip, email); wait > 0 {
+		c.Header("Retry-After", strconv.Itoa(int(wait.Seconds())+1))
+		c.HTML(http.StatusTooManyRequests, "forgot_password.html", gin.H{
+			"Titulo": "Esqueci minha senha",
+			"Error":  "Muitos pedidos de redefinição. Tente novamente em " + formatRetryAfter(wait) + ".",
+		})
+		return
+	}
+	// Todo pedido conta como tentativa, exista ou não a conta.
+	if resetLimiter.RegisterFailure(ip, email) {
+		log.Printf("AVISO: Pedidos de redefinição de senha bloqueados temporariamente para '%s' (IP %s).", email, ip)
+	}
+
+	base := baseURL()
+	if base == "" {
+		log.Printf("ERRO: APP_BASE_URL não definida; e-mail de redefinição para '%s' não enviado.", email)
+	} else if user, err := GetUserByEmail(email); err == nil {
+		token, err := CreatePasswordResetToken(user.ID)
+		if err != nil {
+			log.Printf("Erro ao criar token de redefinição para '%s': %v", email, err)
+		} else {
+			link := base + "/reset-password?token=" + url.QueryEscape(token)
+			msg := mailer.Message{
+				To:      user.Email,
+				Subject: "Minhas Economias - Redefinição de senha",
+				Body: "Recebemos um pedido para redefinir a senha da sua conta.\n\n" +
+					"Acesse o link abaixo em até 1 hora para escolher uma nova senha:\n" + link + "\n\n" +
+					"Se você não fez este pedido, ignore este e-mail.",
+			}
+			if err := mailer.Send(msg); err != nil {
+				log.Printf("Erro ao enviar e-mail de redefinição para '%s': %v", email, err)
+			}
+		}
+	}
+
+	c.HTML(http.StatusOK, "forgot_password.html", gin.H{
+		"Titulo": "Esqueci minha senha",
+		"Flash":  "Se o e-mail estiver cadastrado, você receberá um link para redefinir a senha.",
+	})
+}
+
+// GetResetPasswordPage valida o token do link e exibe o formulário de nova senha.
+func GetResetPasswordPage(c *gin.Context) {
+	token := c.Query("token")
+	if _, _, err := lookupResetToken(database.GetDB(), token); err != nil {
+		if !errors.Is(err, ErrInvalidResetToken) {
+			log.Printf("Erro ao validar token de redefinição: %v", err)
+		}
+		c.HTML(http.StatusBadRequest, "reset_password.html", gin.H{
+			"Titulo": "Redefinir Senha",
+			"Error":  ErrInvalidResetToken.Error() + ". Solicite um novo.",
+		})
+		return
+	}
+
+	c.HTML(http.StatusOK, "reset_password.html", gin.H{
+		"Titulo": "Redefinir Senha",
+		"Token":  token,
+	})
+}
+
+// PostResetPassword grava a nova senha se o token ainda for válido.
+func PostResetPassword(c *gin.Context) {
+	token := c.PostForm("token")
+	password := c.PostForm("password")
+	confirm := c.PostForm("confirm_password")
+
+	renderError := func(status int, msg string, showForm bool) {
+		data := gin.H{"Titulo": "Redefinir Senha", "Error": msg}
+		if showForm {
+			data["Token"] = token
+		}
+		c.HTML(status, "reset_password.html", data)
+	}
+
+	if len(password) < 6 {
+		renderError(http.StatusBadRequest, "A senha deve ter pelo menos 6 caracteres.", true)
+		return
+	}
+	if password != confirm {
+		renderError(http.StatusBadRequest, "A senha e a confirmação não correspondem.", true)
+		return
+	}
+
+	if err := ResetPasswordWithToken(token, password); err != nil {
+		if errors.Is(err, ErrInvalidResetToken) {
+			renderError(http.StatusBadRequest, err.Error()+". Solicite um novo.", false)
+			return
+		}
+		log.Printf("Erro ao redefinir senha: %v", err)
+		renderError(http.StatusInternalServerError, "Não foi possível redefinir a senha. Tente novamente.", true)
+		return
+	}
+
+	session, _ := store.Get(c.Request, "session_token")
+	session.Values["flash"] = "Senha redefinida com sucesso! Faça o login com a nova senha."
+	session.Save(c.Request, c.Writer)
+
+	c.Redirect(http.StatusFound, "/login")
+}
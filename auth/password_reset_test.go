@@ -0,0 +1,189 @@
+package auth
+
+import (
+	"minhas_economias/database"
+	"minhas_economias/mailer"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// setupResetTestDB cria um banco SQLite em memória com as tabelas usadas pelo fluxo de redefinição.
+func setupResetTestDB(t *testing.T) {
+	os.Setenv("DB_TYPE", "sqlite3")
+	os.Setenv("DB_NAME", "file::memory:?cache=shared")
+	os.Setenv("APP_BASE_URL", "https://economias.test")
+
+	if _, err := database.InitDB(); err != nil {
+		t.Fatalf("Falha ao inicializar o banco de dados de teste: %v", err)
+	}
+	db := database.GetDB()
+	db.Exec("DROP TABLE IF EXISTS user_sessions")
+	db.Exec("DROP TABLE IF EXISTS password_reset_tokens")
+	db.Exec("DROP TABLE IF EXISTS users")
+
+	createTablesSQL := `
+		CREATE TABLE users (id INTEGER PRIMARY KEY, email TEXT UNIQUE, password_hash TEXT, is_admin BOOLEAN DEFAULT FALSE, dark_mode_enabled BOOLEAN DEFAULT FALSE, disabled BOOLEAN DEFAULT FALSE);
+		CREATE TABLE password_reset_tokens (id INTEGER PRIMARY KEY AUTOINCREMENT, user_id INTEGER NOT NULL, token_hash TEXT UNIQUE NOT NULL, expires_at DATETIME NOT NULL, used_at DATETIME, created_at DATETIME DEFAULT CURRENT_TIMESTAMP);
+		CREATE TABLE user_sessions (id TEXT PRIMARY KEY, user_id INTEGER, data TEXT NOT NULL, user_agent TEXT, ip_address TEXT, created_at DATETIME, last_seen_at DATETIME, expires_at DATETIME NOT NULL);
+	`
+	if _, err := db.Exec(createTablesSQL); err != nil {
+		t.Fatalf("Falha ao criar tabelas de teste: %v", err)
+	}
+
+	hash, _ := HashPassword("senhaAntiga")
+	db.Exec("INSERT INTO users (id, email, password_hash) VALUES (?, ?, ?)", 42, "reset@user.com", hash)
+}
+
+func createResetTestRouter() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	resetLimiter = newLoginLimiter(3, 10*time.Minute, time.Second)
+	r := gin.New()
+	r.LoadHTMLFiles("../templates/forgot_password.html", "../templates/reset_password.html")
+	r.POST("/forgot-password", PostForgotPassword)
+	r.GET("/reset-password", GetResetPasswordPage)
+	r.POST("/reset-password", PostResetPassword)
+	return r
+}
+
+func performFormRequest(r http.Handler, method, path string, form url.Values) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest(method, path, strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestPasswordReset_FullFlow(t *testing.T) {
+	setupResetTestDB(t)
+	defer database.CloseDB()
+	outbox := &mailer.MemoryMailer{}
+	mailer.SetMailer(outbox)
+	defer mailer.SetMailer(nil)
+	router := createResetTestRouter()
+
+	w := performFormRequest(router, "POST", "/forgot-password", url.Values{"email": {"reset@user.com"}})
+	if w.Code != http.StatusOK {
+		t.Fatalf("Esperado status 200 ao solicitar redefinição, obteve %d", w.Code)
+	}
+	msg, ok := outbox.Last()
+	if !ok || msg.To != "reset@user.com" {
+		t.Fatalf("Esperado e-mail de redefinição para reset@user.com, obteve %+v", outbox.Messages)
+	}
+	match := regexp.MustCompile(`token=([0-9a-f]+)`).FindStringSubmatch(msg.Body)
+	if match == nil {
+		t.Fatalf("Link de redefinição não encontrado no e-mail: %s", msg.Body)
+	}
+	token := match[1]
+
+	// O token nunca é gravado em texto puro.
+	var stored int
+	database.GetDB().QueryRow("SELECT COUNT(*) FROM password_reset_tokens WHERE token_hash = ?", token).Scan(&stored)
+	if stored != 0 {
+		t.Error("O token não deveria ser armazenado em texto puro")
+	}
+
+	if w := performFormRequest(router, "GET", "/reset-password?token="+token, nil); w.Code != http.StatusOK {
+		t.Errorf("Esperado status 200 ao abrir o link válido, obteve %d", w.Code)
+	}
+
+	form := url.Values{"token": {token}, "password": {"senhaNova"}, "confirm_password": {"senhaNova"}}
+	if w := performFormRequest(router, "POST", "/reset-password", form); w.Code != http.StatusFound {
+		t.Fatalf("Esperado redirecionamento após redefinir, obteve %d: %s", w.Code, w.Body.String())
+	}
+
+	user, err := GetUserByEmail("reset@user.com")
+	if err != nil || !CheckPasswordHash("senhaNova", user.PasswordHash) {
+		t.Error("Esperado que a nova senha fosse gravada")
+	}
+
+	// Token de uso único.
+	if w := performFormRequest(router, "POST", "/reset-password", form); w.Code != http.StatusBadRequest {
+		t.Errorf("Esperado status 400 ao reutilizar o token, obteve %d", w.Code)
+	}
+}
+
+func TestPasswordReset_UnknownEmailAndExpiredToken(t *testing.T) {
+	setupResetTestDB(t)
+	defer database.CloseDB()
+	outbox := &mailer.MemoryMailer{}
+	mailer.SetMailer(outbox)
+	defer mailer.SetMailer(nil)
+	router := createResetTestRouter()
+
+	w := performFormRequest(router, "POST", "/forgot-password", url.Values{"email": {"ninguem@user.com"}})
+	if w.Code != http.StatusOK || len(outbox.Messages) != 0 {
+		t.Errorf("Esperado resposta neutra e nenhum e-mail para conta inexistente (status %d, %d e-mails)", w.Code, len(outbox.Messages))
+	}
+
+	token, err := CreatePasswordResetToken(42)
+	if err != nil {
+		t.Fatalf("Falha ao criar token: %v", err)
+	}
+	database.GetDB().Exec("UPDATE password_reset_tokens SET expires_at = ?", time.Now().Add(-time.Minute).UTC())
+
+	if err := ResetPasswordWithToken(token, "qualquer"); err != ErrInvalidResetToken {
+		t.Errorf("Esperado ErrInvalidResetToken para token expirado, obteve %v", err)
+	}
+}
+
+func TestPasswordReset_IgnoresForgedHost(t *testing.T) {
+	setupResetTestDB(t)
+	defer database.CloseDB()
+	outbox := &mailer.MemoryMailer{}
+	mailer.SetMailer(outbox)
+	defer mailer.SetMailer(nil)
+	router := createResetTestRouter()
+
+	req, _ := http.NewRequest("POST", "/forgot-password", strings.NewReader(url.Values{"email": {"reset@user.com"}}.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Host = "atacante.example"
+	req.Header.Set("X-Forwarded-Proto", "https")
+	router.ServeHTTP(httptest.NewRecorder(), req)
+
+	msg, ok := outbox.Last()
+	if !ok {
+		t.Fatal("Esperado e-mail de redefinição")
+	}
+	if strings.Contains(msg.Body, "atacante.example") || !strings.Contains(msg.Body, "https://economias.test/reset-password?token=") {
+		t.Errorf("O link deveria usar apenas APP_BASE_URL, obteve: %s", msg.Body)
+	}
+
+	// Sem APP_BASE_URL nenhum e-mail é enviado.
+	os.Unsetenv("APP_BASE_URL")
+	defer os.Setenv("APP_BASE_URL", "https://economias.test")
+	resetLimiter = newLoginLimiter(3, 10*time.Minute, time.Second)
+	before := len(outbox.Messages)
+	performFormRequest(router, "POST", "/forgot-password", url.Values{"email": {"reset@user.com"}})
+	if len(outbox.Messages) != before {
+		t.Error("Nenhum e-mail deveria ser enviado sem APP_BASE_URL")
+	}
+}
+
+func TestPasswordReset_Throttled(t *testing.T) {
+	setupResetTestDB(t)
+	defer database.CloseDB()
+	outbox := &mailer.MemoryMailer{}
+	mailer.SetMailer(outbox)
+	defer mailer.SetMailer(nil)
+	router := createResetTestRouter()
+
+	if w := performFormRequest(router, "POST", "/forgot-password", url.Values{"email": {"reset@user.com"}}); w.Code != http.StatusOK {
+		t.Fatalf("Esperado status 200 no primeiro pedido, obteve %d", w.Code)
+	}
+	w := performFormRequest(router, "POST", "/forgot-password", url.Values{"email": {"reset@user.com"}})
+	if w.Code != http.StatusTooManyRequests || w.Header().Get("Retry-After") == "" {
+		t.Errorf("Esperado status 429 com Retry-After no pedido repetido, obteve %d", w.Code)
+	}
+	if len(outbox.Messages) != 1 {
+		t.Errorf("Esperado apenas 1 e-mail enviado, obteve %d", len(outbox.Messages))
+	}
+}
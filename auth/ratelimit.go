@@ -0,0 +1,172 @@
+package auth
+
+import (
+	"fmt"
+	"minhas_economias/config"
+	"strings"
+	"sync"
+	"time"
+)
+
+// loginAttempt guarda o histórico de falhas de login de uma chave (IP ou conta).
+type loginAttempt struct {
+	Failures     int
+	LastFailure  time.Time
+	BlockedUntil time.Time
+}
+
+// loginLimiter aplica backoff exponencial e bloqueio temporário às tentativas de login.
+// As chaves são prefixadas ("ip:" ou "email:") para que IP e conta sejam limitados de forma independente.
+type loginLimiter struct {
+	mu          sync.Mutex
+	attempts    map[string]*loginAttempt
+	maxFailures int
+	lockout     time.Duration
+	baseBackoff time.Duration
+	resetAfter  time.Duration
+	lastPrune   time.Time
+	now         func() time.Time
+}
+
+// limiter é a instância usada pelos handlers de login. Começa com os valores padrão
+// e é reconfigurada por InitLoginLimiter depois que o .env for carregado.
+var limiter = newLoginLimiter(5, 15*time.Minute, time.Second)
+
+// resetLimiter limita os pedidos de redefinição de senha por IP e por e-mail, com as mesmas regras do login.
+// Cada pedido conta como uma "falha", já que não há um sucesso que zere o histórico.
+var resetLimiter = newLoginLimiter(5, 15*time.Minute, time.Second)
+
+// InitLoginLimiter recria os limitadores de login e de redefinição de senha a partir de
+// LOGIN_MAX_FAILURES e LOGIN_LOCKOUT_MINUTES. Deve ser chamada depois de godotenv.Load(), assim como InitSessionStore.
+func InitLoginLimiter() {
+	maxFailures := config.IntFromEnv("LOGIN_MAX_FAILURES", 5, 1)
+	lockout := time.Duration(config.IntFromEnv("LOGIN_LOCKOUT_MINUTES", 15, 1)) * time.Minute
+	limiter = newLoginLimiter(maxFailures, lockout, time.Second)
+	resetLimiter = newLoginLimiter(maxFailures, lockout, time.Second)
+}
+
+func newLoginLimiter(maxFailures int, lockout, baseBackoff time.Duration) *loginLimiter {
+	return &loginLimiter{
+		attempts:    make(map[string]*loginAttempt),
+		maxFailures: maxFailures,
+		lockout:     lockout,
+		baseBackoff: baseBackoff,
+		resetAfter:  lockout,
+		now:         time.Now,
+	}
+}
+
+func ipKey(ip string) string       { return "ip:" + ip }
+func emailKey(email string) string { return "email:" + strings.ToLower(strings.TrimSpace(email)) }
+
+// expired indica se o registro já pode ser descartado: fora de bloqueio e sem falhas recentes.
+func (l *loginLimiter) expired(a *loginAttempt, now time.Time) bool {
+	return now.After(a.BlockedUntil) && now.Sub(a.LastFailure) > l.resetAfter
+}
+
+// lookup retorna o registro ativo da chave, ou nil se não houver. Registros expirados são removidos.
+// Deve ser chamada com o mutex travado.
+func (l *loginLimiter) lookup(key string) *loginAttempt {
+	a, ok := l.attempts[key]
+	if !ok {
+		return nil
+	}
+	if l.expired(a, l.now()) {
+		delete(l.attempts, key)
+		return nil
+	}
+	return a
+}
+
+// entry retorna o registro da chave, criando-o se necessário. Deve ser chamada com o mutex travado.
+func (l *loginLimiter) entry(key string) *loginAttempt {
+	if a := l.lookup(key); a != nil {
+		return a
+	}
+	a := &loginAttempt{}
+	l.attempts[key] = a
+	return a
+}
+
+// prune remove todos os registros expirados, no máximo uma vez por período de reset,
+// para que chaves usadas uma única vez (IPs ou e-mails variados) não acumulem na memória.
+// Deve ser chamada com o mutex travado.
+func (l *loginLimiter) prune() {
+	now := l.now()
+	if now.Sub(l.lastPrune) < l.resetAfter {
+		return
+	}
+	l.lastPrune = now
+	for key, a := range l.attempts {
+		if l.expired(a, now) {
+			delete(l.attempts, key)
+		}
+	}
+}
+
+// RetryAfter retorna quanto tempo o cliente ainda precisa esperar antes de tentar novamente (0 se liberado).
+func (l *loginLimiter) RetryAfter(ip, email string) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	var wait time.Duration
+	for _, key := range []string{ipKey(ip), emailKey(email)} {
+		a := l.lookup(key)
+		if a == nil {
+			continue
+		}
+		if remaining := a.BlockedUntil.Sub(l.now()); remaining > wait {
+			wait = remaining
+		}
+	}
+	return wait
+}
+
+// RegisterFailure contabiliza uma falha para o IP e para a conta.
+// Retorna true quando a falha acabou de disparar um bloqueio temporário.
+func (l *loginLimiter) RegisterFailure(ip, email string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.prune()
+
+	now := l.now()
+	lockedOut := false
+	for _, key := range []string{ipKey(ip), emailKey(email)} {
+		a := l.entry(key)
+		a.Failures++
+		a.LastFailure = now
+
+		if a.Failures >= l.maxFailures {
+			if a.Failures == l.maxFailures {
+				lockedOut = true
+			}
+			a.BlockedUntil = now.Add(l.lockout)
+			continue
+		}
+
+		// Backoff exponencial: 1s, 2s, 4s... limitado à duração do bloqueio.
+		backoff := l.baseBackoff << (a.Failures - 1)
+		if backoff > l.lockout {
+			backoff = l.lockout
+		}
+		a.BlockedUntil = now.Add(backoff)
+	}
+	return lockedOut
+}
+
+// Reset limpa o histórico da conta após um login bem-sucedido.
+// O contador por IP não é zerado, para que uma conta válida não sirva de "reset" a um atacante.
+func (l *loginLimiter) Reset(email string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	delete(l.attempts, emailKey(email))
+}
+
+// formatRetryAfter produz uma mensagem amigável com o tempo de espera restante.
+func formatRetryAfter(wait time.Duration) string {
+	if wait < time.Minute {
+		return fmt.Sprintf("%d segundo(s)", int(wait.Seconds())+1)
+	}
+	return fmt.Sprintf("%d minuto(s)", int(wait.Minutes())+1)
+}
@@ -0,0 +1,107 @@
+package auth
+
+import (
+	"strconv"
+	"testing"
+	"time"
+)
+
+// newTestLimiter cria um limitador com relógio controlado pelo teste.
+func newTestLimiter(now *time.Time) *loginLimiter {
+	l := newLoginLimiter(3, 10*time.Minute, time.Second)
+	l.now = func() time.Time { return *now }
+	return l
+}
+
+func TestLoginLimiter_ExponentialBackoff(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	l := newTestLimiter(&now)
+
+	if wait := l.RetryAfter("10.0.0.1", "a@b.com"); wait != 0 {
+		t.Fatalf("Esperado nenhuma espera inicial, obteve %v", wait)
+	}
+
+	l.RegisterFailure("10.0.0.1", "a@b.com")
+	if wait := l.RetryAfter("10.0.0.1", "a@b.com"); wait != time.Second {
+		t.Errorf("Esperado backoff de 1s após a 1ª falha, obteve %v", wait)
+	}
+
+	now = now.Add(time.Second)
+	l.RegisterFailure("10.0.0.1", "a@b.com")
+	if wait := l.RetryAfter("10.0.0.1", "a@b.com"); wait != 2*time.Second {
+		t.Errorf("Esperado backoff de 2s após a 2ª falha, obteve %v", wait)
+	}
+}
+
+func TestLoginLimiter_LockoutAndReset(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	l := newTestLimiter(&now)
+
+	var lockedOut bool
+	for i := 0; i < 3; i++ {
+		lockedOut = l.RegisterFailure("10.0.0.1", "a@b.com")
+		now = now.Add(5 * time.Second)
+	}
+	if !lockedOut {
+		t.Fatal("Esperado bloqueio após 3 falhas consecutivas")
+	}
+
+	// A conta fica bloqueada mesmo vindo de outro IP.
+	if wait := l.RetryAfter("10.0.0.2", "A@B.com"); wait <= 0 {
+		t.Error("Esperado que a conta continuasse bloqueada a partir de outro IP")
+	}
+	// O IP fica bloqueado mesmo para outra conta.
+	if wait := l.RetryAfter("10.0.0.1", "outra@b.com"); wait <= 0 {
+		t.Error("Esperado que o IP continuasse bloqueado para outra conta")
+	}
+
+	// Após o bloqueio expirar, o histórico é descartado.
+	now = now.Add(11 * time.Minute)
+	if wait := l.RetryAfter("10.0.0.1", "a@b.com"); wait != 0 {
+		t.Errorf("Esperado desbloqueio após o período de bloqueio, obteve espera de %v", wait)
+	}
+	if lockedOut := l.RegisterFailure("10.0.0.1", "a@b.com"); lockedOut {
+		t.Error("Uma única falha após o desbloqueio não deveria bloquear novamente")
+	}
+}
+
+func TestLoginLimiter_ResetKeepsIPCounter(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	l := newTestLimiter(&now)
+
+	l.RegisterFailure("10.0.0.1", "a@b.com")
+	l.RegisterFailure("10.0.0.1", "a@b.com")
+	l.Reset("a@b.com")
+
+	if wait := l.RetryAfter("10.0.0.9", "a@b.com"); wait != 0 {
+		t.Errorf("Esperado que o login bem-sucedido liberasse a conta, obteve espera de %v", wait)
+	}
+	if wait := l.RetryAfter("10.0.0.1", "x@b.com"); wait <= 0 {
+		t.Error("Esperado que o contador do IP fosse mantido após o reset da conta")
+	}
+}
+
+func TestLoginLimiter_PrunesExpiredEntries(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	l := newTestLimiter(&now)
+
+	// Consultas sem falhas não devem criar registros.
+	l.RetryAfter("10.0.0.1", "a@b.com")
+	if len(l.attempts) != 0 {
+		t.Fatalf("Esperado nenhum registro após apenas consultar, obteve %d", len(l.attempts))
+	}
+
+	for i := 0; i < 50; i++ {
+		l.RegisterFailure("10.0.1."+strconv.Itoa(i), "spray"+strconv.Itoa(i)+"@b.com")
+	}
+	if len(l.attempts) != 100 {
+		t.Fatalf("Esperado 100 registros (IP + conta), obteve %d", len(l.attempts))
+	}
+
+	// Depois do período de reset, a próxima falha varre os registros expirados.
+	now = now.Add(11 * time.Minute)
+	l.RegisterFailure("10.0.0.9", "novo@b.com")
+	if len(l.attempts) != 2 {
+		t.Errorf("Esperado que apenas os 2 registros novos restassem, obteve %d", len(l.attempts))
+	}
+}
@@ -0,0 +1,238 @@
+package auth
+
+import (
+	"database/sql"
+	"encoding/base32"
+	"fmt"
+	"log"
+	"minhas_economias/database"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/securecookie"
+	"github.com/gorilla/sessions"
+)
+
+var base32RawStdEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// DBStore é um sessions.Store que guarda os dados da sessão na tabela user_sessions.
+// O cookie carrega apenas o ID assinado, o que permite revogar sessões no servidor
+// (logout em outros dispositivos, troca de senha etc.).
+// Sessões anônimas (token CSRF, mensagens flash) não vão para o banco: seus valores
+// ficam assinados no próprio cookie até o login.
+type DBStore struct {
+	Codecs  []securecookie.Codec
+	Options *sessions.Options
+}
+
+// NewDBStore cria o store com as chaves de assinatura informadas.
+func NewDBStore(keyPairs ...[]byte) *DBStore {
+	s := &DBStore{
+		Codecs: securecookie.CodecsFromPairs(keyPairs...),
+		Options: &sessions.Options{
+			Path:   "/",
+			MaxAge: maxAgeSeconds,
+		},
+	}
+	s.MaxAge(s.Options.MaxAge)
+	return s
+}
+
+// MaxAge define a validade padrão das sessões e dos cookies assinados.
+func (s *DBStore) MaxAge(age int) {
+	s.Options.MaxAge = age
+	for _, codec := range s.Codecs {
+		if sc, ok := codec.(*securecookie.SecureCookie); ok {
+			sc.MaxAge(age)
+		}
+	}
+}
+
+// Get retorna a sessão da requisição, reutilizando-a se já foi carregada.
+func (s *DBStore) Get(r *http.Request, name string) (*sessions.Session, error) {
+	return sessions.GetRegistry(r).Get(s, name)
+}
+
+// New carrega a sessão do banco a partir do cookie. Sessões revogadas ou expiradas
+// voltam como novas e vazias.
+func (s *DBStore) New(r *http.Request, name string) (*sessions.Session, error) {
+	session := sessions.NewSession(s, name)
+	opts := *s.Options
+	session.Options = &opts
+	session.IsNew = true
+
+	c, errCookie := r.Cookie(name)
+	if errCookie != nil {
+		return session, nil
+	}
+	if err := securecookie.DecodeMulti(name, c.Value, &session.ID, s.Codecs...); err != nil {
+		session.ID = ""
+		// Não é um ID: pode ser uma sessão anônima, com os valores no próprio cookie.
+		if errValues := securecookie.DecodeMulti(name, c.Value, &session.Values, s.Codecs...); errValues == nil {
+			session.IsNew = false
+			return session, nil
+		}
+		session.Values = make(map[interface{}]interface{})
+		return session, err
+	}
+
+	found, err := s.load(session)
+	if err != nil {
+		return session, err
+	}
+	if !found {
+		session.ID = ""
+		return session, nil
+	}
+	session.IsNew = false
+	return session, nil
+}
+
+// Save grava a sessão no banco e envia o cookie com o ID assinado.
+// Com Options.MaxAge <= 0 a sessão é apagada do banco e o cookie expirado.
+func (s *DBStore) Save(r *http.Request, w http.ResponseWriter, session *sessions.Session) error {
+	if session.Options.MaxAge <= 0 {
+		if session.ID != "" {
+			if err := s.erase(session.ID); err != nil {
+				return err
+			}
+		}
+		http.SetCookie(w, sessions.NewCookie(session.Name(), "", session.Options))
+		return nil
+	}
+
+	if !isAuthenticated(session) {
+		return s.saveAnonymous(w, session)
+	}
+
+	isNew := session.ID == ""
+	if isNew {
+		session.ID = base32RawStdEncoding.EncodeToString(securecookie.GenerateRandomKey(32))
+	}
+
+	encodedID, err := securecookie.EncodeMulti(session.Name(), session.ID, s.Codecs...)
+	if err != nil {
+		return err
+	}
+	if err := s.save(r, session, isNew); err != nil {
+		return err
+	}
+	http.SetCookie(w, sessions.NewCookie(session.Name(), encodedID, session.Options))
+	return nil
+}
+
+// saveAnonymous grava os valores da sessão no próprio cookie assinado, sem tocar no banco.
+// Se a sessão estava no banco (ex: o user_id foi removido), a linha é apagada.
+func (s *DBStore) saveAnonymous(w http.ResponseWriter, session *sessions.Session) error {
+	if session.ID != "" {
+		if err := s.erase(session.ID); err != nil {
+			return err
+		}
+		session.ID = ""
+	}
+	encoded, err := securecookie.EncodeMulti(session.Name(), session.Values, s.Codecs...)
+	if err != nil {
+		return err
+	}
+	http.SetCookie(w, sessions.NewCookie(session.Name(), encoded, session.Options))
+	return nil
+}
+
+// isAuthenticated indica se a sessão pertence a um usuário logado e, portanto, deve ir para o banco.
+func isAuthenticated(session *sessions.Session) bool {
+	id, ok := session.Values["user_id"].(int64)
+	return ok && id != 0
+}
+
+// Regenerate descarta o ID atual (protege contra fixação de sessão). O próximo Save cria um novo ID.
+func (s *DBStore) Regenerate(session *sessions.Session) {
+	if session.ID != "" {
+		if err := s.erase(session.ID); err != nil {
+			log.Printf("Aviso: não foi possível remover a sessão antiga: %v", err)
+		}
+	}
+	session.ID = ""
+}
+
+// Touch atualiza o "visto por último" e o IP de uma sessão autenticada.
+// O IP deve vir de gin.Context.ClientIP, que só considera X-Forwarded-For de proxies confiáveis.
+func (s *DBStore) Touch(ip string, session *sessions.Session) {
+	if session.ID == "" {
+		return
+	}
+	query := database.Rebind("UPDATE user_sessions SET last_seen_at = ?, ip_address = ? WHERE id = ?")
+	if _, err := database.GetDB().Exec(query, time.Now().UTC(), ip, session.ID); err != nil {
+		log.Printf("Aviso: não foi possível atualizar a sessão: %v", err)
+	}
+}
+
+// load busca e decodifica os dados da sessão. Retorna found=false se não existir ou tiver expirado.
+func (s *DBStore) load(session *sessions.Session) (bool, error) {
+	var data string
+	var expiresAt time.Time
+	query := database.Rebind("SELECT data, expires_at FROM user_sessions WHERE id = ?")
+	err := database.GetDB().QueryRow(query, session.ID).Scan(&data, &expiresAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return false, nil
+		}
+		return false, fmt.Errorf("erro ao carregar sessão: %w", err)
+	}
+	if time.Now().After(expiresAt) {
+		return false, s.erase(session.ID)
+	}
+	if err := securecookie.DecodeMulti(session.Name(), data, &session.Values, s.Codecs...); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// save faz o upsert da linha da sessão.
+func (s *DBStore) save(r *http.Request, session *sessions.Session, isNew bool) error {
+	data, err := securecookie.EncodeMulti(session.Name(), session.Values, s.Codecs...)
+	if err != nil {
+		return err
+	}
+
+	var userID interface{}
+	if id, ok := session.Values["user_id"].(int64); ok && id != 0 {
+		userID = id
+	}
+	now := time.Now().UTC()
+	expiresAt := now.Add(time.Duration(session.Options.MaxAge) * time.Second)
+	db := database.GetDB()
+
+	if isNew {
+		// Aproveita a criação de sessões para descartar as expiradas.
+		if _, err := db.Exec(database.Rebind("DELETE FROM user_sessions WHERE expires_at < ?"), now); err != nil {
+			log.Printf("Aviso: não foi possível limpar sessões expiradas: %v", err)
+		}
+		query := database.Rebind(`INSERT INTO user_sessions (id, user_id, data, user_agent, ip_address, created_at, last_seen_at, expires_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?)`)
+		_, err = db.Exec(query, session.ID, userID, data, r.UserAgent(), remoteIP(r), now, now, expiresAt)
+	} else {
+		query := database.Rebind("UPDATE user_sessions SET user_id = ?, data = ?, last_seen_at = ?, expires_at = ? WHERE id = ?")
+		_, err = db.Exec(query, userID, data, now, expiresAt, session.ID)
+	}
+	if err != nil {
+		return fmt.Errorf("erro ao salvar sessão: %w", err)
+	}
+	return nil
+}
+
+func (s *DBStore) erase(id string) error {
+	if _, err := database.GetDB().Exec(database.Rebind("DELETE FROM user_sessions WHERE id = ?"), id); err != nil {
+		return fmt.Errorf("erro ao remover sessão: %w", err)
+	}
+	return nil
+}
+
+// remoteIP retorna o IP da conexão, sem olhar cabeçalhos enviados pelo cliente.
+// É só o valor inicial: Touch o substitui pelo IP resolvido pelo gin com os proxies confiáveis.
+func remoteIP(r *http.Request) string {
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		return host
+	}
+	return r.RemoteAddr
+}
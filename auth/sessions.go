@@ -0,0 +1,113 @@
+package auth
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"minhas_economias/database"
+	"minhas_economias/models"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// sessionHandle gera o identificador público de uma sessão, exibido na interface.
+func sessionHandle(id string) string {
+	sum := sha256.Sum256([]byte(id))
+	return hex.EncodeToString(sum[:8])
+}
+
+// describeUserAgent produz uma descrição curta do dispositivo (ex: "Chrome em Windows").
+func describeUserAgent(ua string) string {
+	browser := "Navegador desconhecido"
+	switch {
+	case strings.Contains(ua, "Edg/"):
+		browser = "Edge"
+	case strings.Contains(ua, "OPR/"):
+		browser = "Opera"
+	case strings.Contains(ua, "Firefox/"):
+		browser = "Firefox"
+	case strings.Contains(ua, "Chrome/"):
+		browser = "Chrome"
+	case strings.Contains(ua, "Safari/"):
+		browser = "Safari"
+	case strings.Contains(ua, "curl/"):
+		browser = "curl"
+	}
+
+	system := ""
+	switch {
+	case strings.Contains(ua, "Android"):
+		system = "Android"
+	case strings.Contains(ua, "iPhone"), strings.Contains(ua, "iPad"):
+		system = "iOS"
+	case strings.Contains(ua, "Windows"):
+		system = "Windows"
+	case strings.Contains(ua, "Mac OS X"):
+		system = "macOS"
+	case strings.Contains(ua, "Linux"):
+		system = "Linux"
+	}
+	if system == "" {
+		return browser
+	}
+	return browser + " em " + system
+}
+
+// ListUserSessions retorna as sessões ativas do usuário, marcando a sessão atual.
+func ListUserSessions(userID int64, currentSessionID string) ([]models.UserSession, error) {
+	query := database.Rebind(`SELECT id, user_agent, ip_address, created_at, last_seen_at FROM user_sessions
+		WHERE user_id = ? AND expires_at > ? ORDER BY last_seen_at DESC`)
+	rows, err := database.GetDB().Query(query, userID, time.Now().UTC())
+	if err != nil {
+		return nil, fmt.Errorf("erro ao listar sessões: %w", err)
+	}
+	defer rows.Close()
+
+	var result []models.UserSession
+	for rows.Next() {
+		var id string
+		var s models.UserSession
+		if err := rows.Scan(&id, &s.UserAgent, &s.IPAddress, &s.CreatedAt, &s.LastSeenAt); err != nil {
+			return nil, fmt.Errorf("erro ao ler sessão: %w", err)
+		}
+		s.Handle = sessionHandle(id)
+		s.Device = describeUserAgent(s.UserAgent)
+		s.Current = id == currentSessionID
+		result = append(result, s)
+	}
+	return result, rows.Err()
+}
+
+// RevokeOtherSessions encerra todas as sessões do usuário, exceto a atual.
+func RevokeOtherSessions(userID int64, currentSessionID string) (int64, error) {
+	query := database.Rebind("DELETE FROM user_sessions WHERE user_id = ? AND id <> ?")
+	res, err := database.GetDB().Exec(query, userID, currentSessionID)
+	if err != nil {
+		return 0, fmt.Errorf("erro ao encerrar sessões: %w", err)
+	}
+	n, _ := res.RowsAffected()
+	return n, nil
+}
+
+// RevokeAllSessions encerra todas as sessões do usuário (ex: após troca ou redefinição de senha).
+func RevokeAllSessions(userID int64) error {
+	query := database.Rebind("DELETE FROM user_sessions WHERE user_id = ?")
+	if _, err := database.GetDB().Exec(query, userID); err != nil {
+		return fmt.Errorf("erro ao encerrar sessões: %w", err)
+	}
+	return nil
+}
+
+// RotateSession grava a sessão atual sob um novo ID, mantendo o usuário logado neste dispositivo.
+// Usado após RevokeAllSessions, quando o ID anterior já não existe no banco.
+func RotateSession(c *gin.Context) error {
+	session, _ := store.Get(c.Request, "session_token")
+	session.ID = ""
+	if err := session.Save(c.Request, c.Writer); err != nil {
+		return err
+	}
+	c.Set("sessionID", session.ID)
+	return nil
+}
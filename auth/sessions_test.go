@@ -0,0 +1,135 @@
+package auth
+
+import (
+	"minhas_economias/database"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// newSessionRequest cria uma requisição carregando o cookie emitido por uma resposta anterior.
+func newSessionRequest(cookies []*http.Cookie, userAgent string) *http.Request {
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("User-Agent", userAgent)
+	for _, c := range cookies {
+		req.AddCookie(c)
+	}
+	return req
+}
+
+// loginSession simula um login em um dispositivo e retorna os cookies emitidos.
+func loginSession(t *testing.T, s *DBStore, userID int64, userAgent string) []*http.Cookie {
+	t.Helper()
+	req := newSessionRequest(nil, userAgent)
+	w := httptest.NewRecorder()
+	session, _ := s.Get(req, "session_token")
+	session.Values["user_id"] = userID
+	if err := session.Save(req, w); err != nil {
+		t.Fatalf("Falha ao salvar a sessão: %v", err)
+	}
+	return w.Result().Cookies()
+}
+
+func TestDBStore_RoundTripAndRevocation(t *testing.T) {
+	setupResetTestDB(t)
+	defer database.CloseDB()
+	s := NewDBStore([]byte("chave-de-teste-com-32-bytes-0123"))
+
+	desktop := loginSession(t, s, 42, "Mozilla/5.0 (Windows NT 10.0) Chrome/120.0")
+	phone := loginSession(t, s, 42, "Mozilla/5.0 (iPhone) Safari/604.1")
+
+	session, err := s.Get(newSessionRequest(desktop, ""), "session_token")
+	if err != nil || session.IsNew || session.Values["user_id"] != int64(42) {
+		t.Fatalf("Esperado recuperar a sessão gravada no banco (err=%v, nova=%v)", err, session.IsNew)
+	}
+	currentID := session.ID
+
+	list, err := ListUserSessions(42, currentID)
+	if err != nil || len(list) != 2 {
+		t.Fatalf("Esperado 2 sessões ativas, obteve %d (err=%v)", len(list), err)
+	}
+	for _, us := range list {
+		if us.Current != (us.Device == "Chrome em Windows") {
+			t.Errorf("Sessão atual marcada incorretamente: %+v", us)
+		}
+	}
+
+	if n, err := RevokeOtherSessions(42, currentID); err != nil || n != 1 {
+		t.Fatalf("Esperado encerrar 1 sessão, obteve %d (err=%v)", n, err)
+	}
+	if session, _ := s.Get(newSessionRequest(phone, ""), "session_token"); !session.IsNew {
+		t.Error("A sessão revogada não deveria continuar válida")
+	}
+	if session, _ := s.Get(newSessionRequest(desktop, ""), "session_token"); session.IsNew {
+		t.Error("A sessão atual deveria continuar válida")
+	}
+}
+
+func TestDBStore_AnonymousSessionStaysInCookie(t *testing.T) {
+	setupResetTestDB(t)
+	defer database.CloseDB()
+	s := NewDBStore([]byte("chave-de-teste-com-32-bytes-0123"))
+
+	req := newSessionRequest(nil, "bot")
+	w := httptest.NewRecorder()
+	session, _ := s.Get(req, "session_token")
+	session.Values[csrfSessionKey] = "token-anonimo"
+	if err := session.Save(req, w); err != nil {
+		t.Fatalf("Falha ao salvar a sessão anônima: %v", err)
+	}
+
+	var rows int
+	database.GetDB().QueryRow("SELECT COUNT(*) FROM user_sessions").Scan(&rows)
+	if rows != 0 {
+		t.Errorf("Sessões anônimas não deveriam ser gravadas no banco, obteve %d linha(s)", rows)
+	}
+
+	session, err := s.Get(newSessionRequest(w.Result().Cookies(), "bot"), "session_token")
+	if err != nil || session.Values[csrfSessionKey] != "token-anonimo" {
+		t.Fatalf("Esperado recuperar os valores do cookie anônimo (err=%v, valores=%v)", err, session.Values)
+	}
+
+	// Ao logar, a mesma sessão passa a ser gravada no banco e o token é preservado.
+	req = newSessionRequest(w.Result().Cookies(), "bot")
+	w = httptest.NewRecorder()
+	session, _ = s.Get(req, "session_token")
+	session.Values["user_id"] = int64(42)
+	if err := session.Save(req, w); err != nil {
+		t.Fatalf("Falha ao salvar a sessão autenticada: %v", err)
+	}
+	database.GetDB().QueryRow("SELECT COUNT(*) FROM user_sessions WHERE user_id = 42").Scan(&rows)
+	if rows != 1 {
+		t.Errorf("Esperado 1 sessão autenticada no banco, obteve %d", rows)
+	}
+	session, _ = s.Get(newSessionRequest(w.Result().Cookies(), "bot"), "session_token")
+	if session.IsNew || session.Values[csrfSessionKey] != "token-anonimo" {
+		t.Error("Esperado que a sessão autenticada mantivesse os valores da sessão anônima")
+	}
+}
+
+func TestDBStore_TouchIgnoresForwardedHeader(t *testing.T) {
+	setupResetTestDB(t)
+	defer database.CloseDB()
+	s := NewDBStore([]byte("chave-de-teste-com-32-bytes-0123"))
+
+	req := newSessionRequest(nil, "curl")
+	req.Header.Set("X-Forwarded-For", "6.6.6.6")
+	w := httptest.NewRecorder()
+	session, _ := s.Get(req, "session_token")
+	session.Values["user_id"] = int64(42)
+	if err := session.Save(req, w); err != nil {
+		t.Fatalf("Falha ao salvar a sessão: %v", err)
+	}
+
+	var ip string
+	database.GetDB().QueryRow("SELECT ip_address FROM user_sessions WHERE id = ?", session.ID).Scan(&ip)
+	if ip == "6.6.6.6" {
+		t.Error("O IP da sessão não deveria vir do X-Forwarded-For enviado pelo cliente")
+	}
+
+	s.Touch("10.0.0.7", session)
+	database.GetDB().QueryRow("SELECT ip_address FROM user_sessions WHERE id = ?", session.ID).Scan(&ip)
+	if ip != "10.0.0.7" {
+		t.Errorf("Esperado IP 10.0.0.7 após Touch, obteve %q", ip)
+	}
+}
@@ -39,11 +39,11 @@ func CreateUser(email, password string) error {
 func GetUserByEmail(email string) (*models.User, error) {
 	var user models.User
 	// ATUALIZADO para selecionar o campo dark_mode_enabled
-	query := "SELECT id, email, password_hash, is_admin, dark_mode_enabled FROM users WHERE email = ?"
+	query := "SELECT id, email, password_hash, is_admin, dark_mode_enabled, COALESCE(disabled, FALSE) FROM users WHERE email = ?"
 	row := database.GetDB().QueryRow(database.Rebind(query), email)
 
 	// ATUALIZADO para escanear o campo dark_mode_enabled
-	err := row.Scan(&user.ID, &user.Email, &user.PasswordHash, &user.IsAdmin, &user.DarkModeEnabled)
+	err := row.Scan(&user.ID, &user.Email, &user.PasswordHash, &user.IsAdmin, &user.DarkModeEnabled, &user.Disabled)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, fmt.Errorf("usuário não encontrado")
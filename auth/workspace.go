@@ -0,0 +1,93 @@
+package auth
+
+import (
+	"log"
+	"minhas_economias/households"
+	"minhas_economias/models"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/sessions"
+)
+
+// householdSessionKey guarda na sessão o lar ativo (ausente ou 0 = livro-caixa pessoal).
+const householdSessionKey = "household_id"
+
+// loadWorkspace preenche os lares do usuário e o lar ativo, validando a participação a cada
+// requisição: se o usuário foi removido do lar, volta automaticamente ao livro pessoal.
+func loadWorkspace(c *gin.Context, session *sessions.Session, user *models.User) {
+	workspaces, err := households.ListForUser(user.ID)
+	if err != nil {
+		log.Printf("Aviso: não foi possível listar os lares do usuário %d: %v", user.ID, err)
+	}
+	user.Workspaces = workspaces
+
+	householdID, _ := session.Values[householdSessionKey].(int64)
+	if householdID == 0 {
+		return
+	}
+	for i := range workspaces {
+		if workspaces[i].ID == householdID {
+			user.ActiveHousehold = &workspaces[i]
+			c.Set("householdID", householdID)
+			c.Set("householdRole", workspaces[i].Role)
+			c.Set("householdOwnerID", workspaces[i].OwnerID)
+			return
+		}
+	}
+
+	delete(session.Values, householdSessionKey)
+	if err := session.Save(c.Request, c.Writer); err != nil {
+		log.Printf("Aviso: não foi possível atualizar o lar ativo da sessão: %v", err)
+	}
+}
+
+// PostSwitchWorkspace alterna entre o livro-caixa pessoal (household_id=0) e um lar compartilhado.
+func PostSwitchWorkspace(c *gin.Context) {
+	userID := c.MustGet("userID").(int64)
+	householdID, err := strconv.ParseInt(c.DefaultPostForm("household_id", "0"), 10, 64)
+	if err != nil {
+		abortWithMessage(c, http.StatusBadRequest, "Lar inválido.")
+		return
+	}
+
+	session, _ := store.Get(c.Request, "session_token")
+	if householdID == 0 {
+		delete(session.Values, householdSessionKey)
+	} else {
+		if _, err := households.GetMembership(householdID, userID); err != nil {
+			abortWithMessage(c, http.StatusForbidden, "Você não participa deste lar.")
+			return
+		}
+		session.Values[householdSessionKey] = householdID
+	}
+	if err := session.Save(c.Request, c.Writer); err != nil {
+		log.Printf("Erro ao salvar a sessão: %v", err)
+		abortWithMessage(c, http.StatusInternalServerError, "Não foi possível trocar de espaço de trabalho.")
+		return
+	}
+
+	c.Redirect(http.StatusFound, safeRedirectTarget(c.PostForm("redirect")))
+}
+
+// RequireLedgerWrite bloqueia alterações no livro-caixa para quem tem apenas leitura no lar ativo.
+func RequireLedgerWrite() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if role := c.GetString("householdRole"); role != "" && !(models.Household{Role: role}).CanEdit() {
+			abortWithMessage(c, http.StatusForbidden, "Seu papel neste lar é somente leitura.")
+			return
+		}
+		c.Next()
+	}
+}
+
+// safeRedirectTarget aceita apenas caminhos locais, evitando redirecionamentos abertos.
+func safeRedirectTarget(target string) string {
+	u, err := url.Parse(target)
+	if err != nil || target == "" || u.IsAbs() || u.Host != "" || len(u.Path) == 0 || u.Path[0] != '/' || (len(u.Path) > 1 && u.Path[1] == '/') {
+		return "/"
+	}
+	return u.RequestURI()
+}
@@ -0,0 +1,508 @@
+// backup/backup.go
+package backup
+
+import (
+	"archive/zip"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"minhas_economias/database"
+	"minhas_economias/storage"
+	"path"
+	"time"
+)
+
+// FormatVersion é a versão do formato do backup. Restaurações recusam versões mais novas
+// que a suportada; versões antigas devem continuar legíveis quando o formato evoluir.
+const FormatVersion = 1
+
+// manifestName é o JSON com os dados do usuário dentro do .zip; os anexos ficam em anexos/.
+const manifestName = "backup.json"
+
+// Archive é o conteúdo completo do backup de um usuário (livro-caixa pessoal, sem lares compartilhados).
+type Archive struct {
+	Version                     int                         `json:"version"`
+	ExportedAt                  time.Time                   `json:"exported_at"`
+	User                        User                        `json:"user"`
+	Profile                     *Profile                    `json:"profile,omitempty"`
+	Contas                      []Conta                     `json:"contas"`
+	Movimentacoes               []Movimentacao              `json:"movimentacoes"`
+	InvestimentosNacionais      []InvestimentoNacional      `json:"investimentos_nacionais"`
+	InvestimentosInternacionais []InvestimentoInternacional `json:"investimentos_internacionais"`
+	OperacoesInvestimentos      []OperacaoInvestimento      `json:"operacoes_investimentos,omitempty"`
+	Proventos                   []Provento                  `json:"proventos,omitempty"`
+	RendaFixa                   []TituloRendaFixa           `json:"renda_fixa,omitempty"`
+	InvestimentosCripto         []InvestimentoCripto        `json:"investimentos_cripto,omitempty"`
+	AlocacaoAlvo                []AlvoAlocacao              `json:"alocacao_alvo,omitempty"`
+	ChatHistory                 []ChatMessage               `json:"chat_history"`
+	Anexos                      []Anexo                     `json:"anexos"`
+}
+
+// User identifica o dono do backup. A senha não é exportada.
+type User struct {
+	ID       int64  `json:"id"`
+	Email    string `json:"email"`
+	DarkMode bool   `json:"dark_mode"`
+}
+
+// Profile espelha a tabela user_profiles.
+type Profile struct {
+	DateOfBirth   string `json:"date_of_birth,omitempty"`
+	Gender        string `json:"gender,omitempty"`
+	MaritalStatus string `json:"marital_status,omitempty"`
+	ChildrenCount int    `json:"children_count"`
+	Country       string `json:"country,omitempty"`
+	State         string `json:"state,omitempty"`
+	City          string `json:"city,omitempty"`
+}
+
+// Conta espelha a tabela contas.
+type Conta struct {
+	Nome         string  `json:"nome"`
+	SaldoInicial float64 `json:"saldo_inicial"`
+}
+
+// Movimentacao espelha a tabela movimentacoes. O ID só é usado para ligar os anexos;
+// na restauração cada movimentação recebe um ID novo.
+type Movimentacao struct {
+	ID             int        `json:"id"`
+	DataOcorrencia string     `json:"data_ocorrencia"`
+	Descricao      string     `json:"descricao"`
+	Valor          float64    `json:"valor"`
+	Categoria      string     `json:"categoria"`
+	Conta          string     `json:"conta"`
+	Consolidado    bool       `json:"consolidado"`
+	DeletedAt      *time.Time `json:"deleted_at,omitempty"`
+}
+
+// InvestimentoNacional espelha a tabela investimentos_nacionais.
+type InvestimentoNacional struct {
+	Ticker     string `json:"ticker"`
+	Tipo       string `json:"tipo"`
+	Quantidade int    `json:"quantidade"`
+}
+
+// InvestimentoInternacional espelha a tabela investimentos_internacionais.
+type InvestimentoInternacional struct {
+	Ticker     string  `json:"ticker"`
+	Descricao  string  `json:"descricao"`
+	Quantidade float64 `json:"quantidade"`
+	Moeda      string  `json:"moeda"`
+}
+
+// InvestimentoCripto espelha a tabela investimentos_cripto; a quantidade vai como texto para
+// não perder casas decimais.
+type InvestimentoCripto struct {
+	Simbolo    string `json:"simbolo"`
+	Descricao  string `json:"descricao,omitempty"`
+	Quantidade string `json:"quantidade"`
+}
+
+// AlvoAlocacao espelha a tabela alocacao_alvo (Ticker vazio é o alvo da classe).
+type AlvoAlocacao struct {
+	Classe     string  `json:"classe"`
+	Ticker     string  `json:"ticker,omitempty"`
+	Percentual float64 `json:"percentual"`
+}
+
+// OperacaoInvestimento espelha a tabela operacoes_investimentos (livro de compras e vendas).
+type OperacaoInvestimento struct {
+	Ticker     string    `json:"ticker"`
+	TipoAtivo  string    `json:"tipo_ativo"`
+	Tipo       string    `json:"tipo"`
+	Data       string    `json:"data"`
+	Quantidade int       `json:"quantidade"`
+	Preco      float64   `json:"preco"`
+	Taxas      float64   `json:"taxas"`
+	Corretora  string    `json:"corretora,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// Provento espelha a tabela proventos. MovimentacaoID é o ID da entrada no backup (zero se não houver).
+type Provento struct {
+	Ticker         string    `json:"ticker"`
+	TipoAtivo      string    `json:"tipo_ativo"`
+	Tipo           string    `json:"tipo"`
+	DataEx         string    `json:"data_ex"`
+	DataPagamento  string    `json:"data_pagamento"`
+	ValorPorCota   float64   `json:"valor_por_cota"`
+	Quantidade     int       `json:"quantidade"`
+	ImpostoRetido  float64   `json:"imposto_retido"`
+	MovimentacaoID int       `json:"movimentacao_id,omitempty"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+// TituloRendaFixa espelha a tabela renda_fixa.
+type TituloRendaFixa struct {
+	Nome          string    `json:"nome"`
+	Tipo          string    `json:"tipo"`
+	Emissor       string    `json:"emissor,omitempty"`
+	Indexador     string    `json:"indexador"`
+	Taxa          float64   `json:"taxa"`
+	ValorAplicado float64   `json:"valor_aplicado"`
+	DataAplicacao string    `json:"data_aplicacao"`
+	Vencimento    string    `json:"vencimento"`
+	Liquidez      string    `json:"liquidez"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// ChatMessage espelha a tabela chat_history.
+type ChatMessage struct {
+	Role      string    `json:"role"`
+	Content   string    `json:"content"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Anexo descreve um arquivo anexado; Arquivo e Miniatura são caminhos dentro do .zip.
+type Anexo struct {
+	MovimentacaoID int       `json:"movimentacao_id"`
+	NomeArquivo    string    `json:"nome_arquivo"`
+	ContentType    string    `json:"content_type"`
+	Tamanho        int64     `json:"tamanho"`
+	Arquivo        string    `json:"arquivo"`
+	Miniatura      string    `json:"miniatura,omitempty"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+// Summary resume o que foi restaurado.
+type Summary struct {
+	Contas                      int
+	Movimentacoes               int
+	InvestimentosNacionais      int
+	InvestimentosInternacionais int
+	OperacoesInvestimentos      int
+	Proventos                   int
+	RendaFixa                   int
+	InvestimentosCripto         int
+	AlocacaoAlvo                int
+	ChatHistory                 int
+	Anexos                      int
+}
+
+func (s Summary) String() string {
+	return fmt.Sprintf("%d contas, %d movimentações, %d investimentos nacionais (%d operações, %d proventos), %d internacionais, %d títulos de renda fixa, %d criptoativos, %d alvos de alocação, %d mensagens de chat e %d anexos",
+		s.Contas, s.Movimentacoes, s.InvestimentosNacionais, s.OperacoesInvestimentos, s.Proventos, s.InvestimentosInternacionais, s.RendaFixa, s.InvestimentosCripto, s.AlocacaoAlvo, s.ChatHistory, s.Anexos)
+}
+
+// Write exporta todos os dados do usuário como .zip para w.
+func Write(db *sql.DB, store storage.Store, userID int64, w io.Writer) (*Archive, error) {
+	archive, keys, err := load(db, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	zw := zip.NewWriter(w)
+	for i := range archive.Anexos {
+		a := &archive.Anexos[i]
+		data, err := storage.ReadAll(store, keys[i].file)
+		if err != nil {
+			return nil, fmt.Errorf("erro ao ler o anexo '%s': %w", a.NomeArquivo, err)
+		}
+		a.Arquivo = fmt.Sprintf("anexos/%d_%s", i+1, path.Base(a.NomeArquivo))
+		if err := writeZipFile(zw, a.Arquivo, data); err != nil {
+			return nil, err
+		}
+		if keys[i].thumbnail != "" {
+			if thumb, err := storage.ReadAll(store, keys[i].thumbnail); err == nil {
+				a.Miniatura = fmt.Sprintf("anexos/%d_miniatura.jpg", i+1)
+				if err := writeZipFile(zw, a.Miniatura, thumb); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+
+	manifest, err := json.MarshalIndent(archive, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if err := writeZipFile(zw, manifestName, manifest); err != nil {
+		return nil, err
+	}
+	return archive, zw.Close()
+}
+
+func writeZipFile(zw *zip.Writer, name string, data []byte) error {
+	f, err := zw.Create(name)
+	if err != nil {
+		return fmt.Errorf("erro ao criar '%s' no backup: %w", name, err)
+	}
+	_, err = f.Write(data)
+	return err
+}
+
+// Read abre um backup .zip e valida a versão e as referências internas.
+func Read(r io.ReaderAt, size int64) (*Archive, *zip.Reader, error) {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, nil, fmt.Errorf("arquivo de backup inválido: %w", err)
+	}
+	f, err := zr.Open(manifestName)
+	if err != nil {
+		return nil, nil, fmt.Errorf("backup sem '%s': %w", manifestName, err)
+	}
+	defer f.Close()
+
+	var archive Archive
+	if err := json.NewDecoder(f).Decode(&archive); err != nil {
+		return nil, nil, fmt.Errorf("'%s' inválido: %w", manifestName, err)
+	}
+	if err := archive.Validate(zr); err != nil {
+		return nil, nil, err
+	}
+	return &archive, zr, nil
+}
+
+// Validate confere a versão e a integridade referencial do backup: contas, tickers e IDs de
+// movimentações únicos, datas válidas e anexos apontando para movimentações e arquivos existentes.
+func (a *Archive) Validate(zr *zip.Reader) error {
+	if a.Version < 1 || a.Version > FormatVersion {
+		return fmt.Errorf("versão de backup %d não suportada (esta versão lê até a %d)", a.Version, FormatVersion)
+	}
+	if a.User.Email == "" {
+		return errors.New("backup sem o e-mail do usuário")
+	}
+
+	contas := map[string]bool{}
+	for _, c := range a.Contas {
+		if c.Nome == "" || contas[c.Nome] {
+			return fmt.Errorf("conta vazia ou duplicada no backup: '%s'", c.Nome)
+		}
+		contas[c.Nome] = true
+	}
+
+	movimentacoes := map[int]bool{}
+	for _, m := range a.Movimentacoes {
+		if movimentacoes[m.ID] {
+			return fmt.Errorf("movimentação %d duplicada no backup", m.ID)
+		}
+		if _, err := time.Parse("2006-01-02", m.DataOcorrencia); err != nil {
+			return fmt.Errorf("movimentação %d com data inválida '%s'", m.ID, m.DataOcorrencia)
+		}
+		movimentacoes[m.ID] = true
+	}
+
+	tickers := map[string]bool{}
+	for _, inv := range a.InvestimentosNacionais {
+		if inv.Ticker == "" || tickers["N:"+inv.Ticker] {
+			return fmt.Errorf("investimento nacional vazio ou duplicado: '%s'", inv.Ticker)
+		}
+		tickers["N:"+inv.Ticker] = true
+	}
+	for _, inv := range a.InvestimentosInternacionais {
+		if inv.Ticker == "" || tickers["I:"+inv.Ticker] {
+			return fmt.Errorf("investimento internacional vazio ou duplicado: '%s'", inv.Ticker)
+		}
+		tickers["I:"+inv.Ticker] = true
+	}
+
+	for _, anexo := range a.Anexos {
+		if !movimentacoes[anexo.MovimentacaoID] {
+			return fmt.Errorf("anexo '%s' aponta para a movimentação %d, que não está no backup", anexo.NomeArquivo, anexo.MovimentacaoID)
+		}
+		if zr != nil {
+			names := []string{anexo.Arquivo}
+			if anexo.Miniatura != "" {
+				names = append(names, anexo.Miniatura)
+			}
+			for _, name := range names {
+				f, err := zr.Open(name)
+				if err != nil {
+					return fmt.Errorf("arquivo '%s' do anexo '%s' não está no backup", name, anexo.NomeArquivo)
+				}
+				f.Close()
+			}
+		}
+	}
+	return nil
+}
+
+// anexoKeys guarda as chaves de armazenamento de um anexo durante a exportação.
+type anexoKeys struct {
+	file, thumbnail string
+}
+
+// load lê do banco todos os dados pessoais do usuário.
+func load(db *sql.DB, userID int64) (*Archive, []anexoKeys, error) {
+	archive := &Archive{Version: FormatVersion, ExportedAt: time.Now().UTC()}
+
+	err := db.QueryRow(database.Rebind("SELECT id, email, dark_mode_enabled FROM users WHERE id = ?"), userID).Scan(&archive.User.ID, &archive.User.Email, &archive.User.DarkMode)
+	if err != nil {
+		return nil, nil, fmt.Errorf("usuário %d não encontrado: %w", userID, err)
+	}
+
+	var dob interface{}
+	var gender, marital, country, state, city sql.NullString
+	var children sql.NullInt64
+	err = db.QueryRow(database.Rebind("SELECT date_of_birth, gender, marital_status, children_count, country, state, city FROM user_profiles WHERE user_id = ?"), userID).
+		Scan(&dob, &gender, &marital, &children, &country, &state, &city)
+	switch {
+	case err == nil:
+		archive.Profile = &Profile{DateOfBirth: dateString(dob), Gender: gender.String, MaritalStatus: marital.String, ChildrenCount: int(children.Int64), Country: country.String, State: state.String, City: city.String}
+	case err != sql.ErrNoRows:
+		return nil, nil, fmt.Errorf("erro ao ler o perfil: %w", err)
+	}
+
+	if err := queryEach(db, "SELECT nome, saldo_inicial FROM contas WHERE user_id = ? AND household_id IS NULL ORDER BY nome", userID, func(rows *sql.Rows) error {
+		var c Conta
+		err := rows.Scan(&c.Nome, &c.SaldoInicial)
+		archive.Contas = append(archive.Contas, c)
+		return err
+	}); err != nil {
+		return nil, nil, fmt.Errorf("erro ao ler as contas: %w", err)
+	}
+
+	query := fmt.Sprintf("SELECT id, data_ocorrencia, descricao, valor, categoria, conta, consolidado, deleted_at FROM %s WHERE user_id = ? AND household_id IS NULL ORDER BY id", database.TableName)
+	if err := queryEach(db, query, userID, func(rows *sql.Rows) error {
+		var m Movimentacao
+		var rawData interface{}
+		var descricao, categoria, conta sql.NullString
+		var deletedAt sql.NullTime
+		err := rows.Scan(&m.ID, &rawData, &descricao, &m.Valor, &categoria, &conta, &m.Consolidado, &deletedAt)
+		m.DataOcorrencia, m.Descricao, m.Categoria, m.Conta = dateString(rawData), descricao.String, categoria.String, conta.String
+		if deletedAt.Valid {
+			m.DeletedAt = &deletedAt.Time
+		}
+		archive.Movimentacoes = append(archive.Movimentacoes, m)
+		return err
+	}); err != nil {
+		return nil, nil, fmt.Errorf("erro ao ler as movimentações: %w", err)
+	}
+
+	if err := queryEach(db, "SELECT ticker, tipo, quantidade FROM investimentos_nacionais WHERE user_id = ? ORDER BY ticker", userID, func(rows *sql.Rows) error {
+		var inv InvestimentoNacional
+		var tipo sql.NullString
+		err := rows.Scan(&inv.Ticker, &tipo, &inv.Quantidade)
+		inv.Tipo = tipo.String
+		archive.InvestimentosNacionais = append(archive.InvestimentosNacionais, inv)
+		return err
+	}); err != nil {
+		return nil, nil, fmt.Errorf("erro ao ler os investimentos nacionais: %w", err)
+	}
+
+	if err := queryEach(db, "SELECT ticker, descricao, quantidade, moeda FROM investimentos_internacionais WHERE user_id = ? ORDER BY ticker", userID, func(rows *sql.Rows) error {
+		var inv InvestimentoInternacional
+		var descricao, moeda sql.NullString
+		err := rows.Scan(&inv.Ticker, &descricao, &inv.Quantidade, &moeda)
+		inv.Descricao, inv.Moeda = descricao.String, moeda.String
+		archive.InvestimentosInternacionais = append(archive.InvestimentosInternacionais, inv)
+		return err
+	}); err != nil {
+		return nil, nil, fmt.Errorf("erro ao ler os investimentos internacionais: %w", err)
+	}
+
+	if err := queryEach(db, "SELECT ticker, tipo_ativo, tipo, data, quantidade, preco, taxas, corretora, created_at FROM operacoes_investimentos WHERE user_id = ? ORDER BY id", userID, func(rows *sql.Rows) error {
+		var op OperacaoInvestimento
+		var rawData interface{}
+		var corretora sql.NullString
+		err := rows.Scan(&op.Ticker, &op.TipoAtivo, &op.Tipo, &rawData, &op.Quantidade, &op.Preco, &op.Taxas, &corretora, &op.CreatedAt)
+		op.Data, op.Corretora = dateString(rawData), corretora.String
+		archive.OperacoesInvestimentos = append(archive.OperacoesInvestimentos, op)
+		return err
+	}); err != nil {
+		return nil, nil, fmt.Errorf("erro ao ler as operações de investimentos: %w", err)
+	}
+
+	if err := queryEach(db, "SELECT ticker, tipo_ativo, tipo, data_ex, data_pagamento, valor_por_cota, quantidade, imposto_retido, movimentacao_id, created_at FROM proventos WHERE user_id = ? ORDER BY id", userID, func(rows *sql.Rows) error {
+		var p Provento
+		var rawEx, rawPagamento interface{}
+		var movID sql.NullInt64
+		err := rows.Scan(&p.Ticker, &p.TipoAtivo, &p.Tipo, &rawEx, &rawPagamento, &p.ValorPorCota, &p.Quantidade, &p.ImpostoRetido, &movID, &p.CreatedAt)
+		p.DataEx, p.DataPagamento, p.MovimentacaoID = dateString(rawEx), dateString(rawPagamento), int(movID.Int64)
+		archive.Proventos = append(archive.Proventos, p)
+		return err
+	}); err != nil {
+		return nil, nil, fmt.Errorf("erro ao ler os proventos: %w", err)
+	}
+
+	if err := queryEach(db, "SELECT nome, tipo, emissor, indexador, taxa, valor_aplicado, data_aplicacao, vencimento, liquidez, created_at FROM renda_fixa WHERE user_id = ? ORDER BY id", userID, func(rows *sql.Rows) error {
+		var t TituloRendaFixa
+		var emissor sql.NullString
+		var rawAplicacao, rawVencimento interface{}
+		err := rows.Scan(&t.Nome, &t.Tipo, &emissor, &t.Indexador, &t.Taxa, &t.ValorAplicado, &rawAplicacao, &rawVencimento, &t.Liquidez, &t.CreatedAt)
+		t.Emissor, t.DataAplicacao, t.Vencimento = emissor.String, dateString(rawAplicacao), dateString(rawVencimento)
+		archive.RendaFixa = append(archive.RendaFixa, t)
+		return err
+	}); err != nil {
+		return nil, nil, fmt.Errorf("erro ao ler os títulos de renda fixa: %w", err)
+	}
+
+	if err := queryEach(db, "SELECT simbolo, descricao, quantidade FROM investimentos_cripto WHERE user_id = ? ORDER BY simbolo", userID, func(rows *sql.Rows) error {
+		var inv InvestimentoCripto
+		var descricao sql.NullString
+		err := rows.Scan(&inv.Simbolo, &descricao, &inv.Quantidade)
+		inv.Descricao = descricao.String
+		archive.InvestimentosCripto = append(archive.InvestimentosCripto, inv)
+		return err
+	}); err != nil {
+		return nil, nil, fmt.Errorf("erro ao ler os criptoativos: %w", err)
+	}
+
+	if err := queryEach(db, "SELECT classe, ticker, percentual FROM alocacao_alvo WHERE user_id = ? ORDER BY classe, ticker", userID, func(rows *sql.Rows) error {
+		var a AlvoAlocacao
+		err := rows.Scan(&a.Classe, &a.Ticker, &a.Percentual)
+		archive.AlocacaoAlvo = append(archive.AlocacaoAlvo, a)
+		return err
+	}); err != nil {
+		return nil, nil, fmt.Errorf("erro ao ler a alocação-alvo: %w", err)
+	}
+
+	if err := queryEach(db, "SELECT role, content, created_at FROM chat_history WHERE user_id = ? ORDER BY id", userID, func(rows *sql.Rows) error {
+		var msg ChatMessage
+		err := rows.Scan(&msg.Role, &msg.Content, &msg.CreatedAt)
+		archive.ChatHistory = append(archive.ChatHistory, msg)
+		return err
+	}); err != nil {
+		return nil, nil, fmt.Errorf("erro ao ler o histórico do chat: %w", err)
+	}
+
+	var keys []anexoKeys
+	query = fmt.Sprintf(`SELECT a.movimentacao_id, a.nome_arquivo, a.content_type, a.tamanho, a.storage_key, a.thumbnail_key, a.created_at
+		FROM anexos a JOIN %s m ON m.id = a.movimentacao_id WHERE m.user_id = ? AND m.household_id IS NULL ORDER BY a.id`, database.TableName)
+	if err := queryEach(db, query, userID, func(rows *sql.Rows) error {
+		var a Anexo
+		var k anexoKeys
+		var thumb sql.NullString
+		err := rows.Scan(&a.MovimentacaoID, &a.NomeArquivo, &a.ContentType, &a.Tamanho, &k.file, &thumb, &a.CreatedAt)
+		k.thumbnail = thumb.String
+		archive.Anexos = append(archive.Anexos, a)
+		keys = append(keys, k)
+		return err
+	}); err != nil {
+		return nil, nil, fmt.Errorf("erro ao ler os anexos: %w", err)
+	}
+
+	return archive, keys, nil
+}
+
+func queryEach(db *sql.DB, query string, userID int64, scan func(*sql.Rows) error) error {
+	rows, err := db.Query(database.Rebind(query), userID)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		if err := scan(rows); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// dateString normaliza datas lidas do banco (time.Time no PostgreSQL, texto no SQLite) para AAAA-MM-DD.
+func dateString(raw interface{}) string {
+	switch v := raw.(type) {
+	case time.Time:
+		return v.Format("2006-01-02")
+	case string:
+		if len(v) >= 10 {
+			return v[:10]
+		}
+		return v
+	case []byte:
+		return dateString(string(v))
+	}
+	return ""
+}
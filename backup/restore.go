@@ -0,0 +1,313 @@
+// backup/restore.go
+package backup
+
+import (
+	"archive/zip"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"minhas_economias/database"
+	"minhas_economias/storage"
+	"path"
+)
+
+// ErrTargetNotEmpty indica que o usuário de destino já tem dados e Replace não foi pedido.
+var ErrTargetNotEmpty = errors.New("o usuário de destino já possui dados; use a opção de substituição para apagá-los antes da restauração")
+
+// RestoreOptions controla a restauração.
+type RestoreOptions struct {
+	// Replace apaga os dados pessoais existentes do usuário de destino antes de restaurar.
+	Replace bool
+}
+
+// Restore carrega o backup no usuário targetUserID (que pode ser diferente do usuário de origem).
+// Tudo roda em uma transação; os arquivos dos anexos gravados no armazenamento são removidos se ela falhar.
+func Restore(db *sql.DB, store storage.Store, archive *Archive, zr *zip.Reader, targetUserID int64, opts RestoreOptions) (Summary, error) {
+	var summary Summary
+	if err := archive.Validate(zr); err != nil {
+		return summary, err
+	}
+
+	var exists int
+	if err := db.QueryRow(database.Rebind("SELECT COUNT(*) FROM users WHERE id = ?"), targetUserID).Scan(&exists); err != nil || exists == 0 {
+		return summary, fmt.Errorf("usuário de destino %d não encontrado", targetUserID)
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return summary, fmt.Errorf("erro ao iniciar transação: %w", err)
+	}
+	defer tx.Rollback()
+
+	var replacedKeys []string
+	if opts.Replace {
+		if replacedKeys, err = clearUserData(tx, targetUserID); err != nil {
+			return summary, err
+		}
+	} else if hasData, err := userHasData(tx, targetUserID); err != nil {
+		return summary, err
+	} else if hasData {
+		return summary, ErrTargetNotEmpty
+	}
+
+	if _, err := tx.Exec(database.Rebind("UPDATE users SET dark_mode_enabled = ? WHERE id = ?"), archive.User.DarkMode, targetUserID); err != nil {
+		return summary, fmt.Errorf("erro ao restaurar as preferências: %w", err)
+	}
+
+	if p := archive.Profile; p != nil {
+		var dob interface{}
+		if p.DateOfBirth != "" {
+			dob = p.DateOfBirth
+		}
+		if _, err := tx.Exec(database.Rebind("DELETE FROM user_profiles WHERE user_id = ?"), targetUserID); err != nil {
+			return summary, fmt.Errorf("erro ao restaurar o perfil: %w", err)
+		}
+		_, err := tx.Exec(database.Rebind("INSERT INTO user_profiles (user_id, date_of_birth, gender, marital_status, children_count, country, state, city) VALUES (?, ?, ?, ?, ?, ?, ?, ?)"),
+			targetUserID, dob, p.Gender, p.MaritalStatus, p.ChildrenCount, p.Country, p.State, p.City)
+		if err != nil {
+			return summary, fmt.Errorf("erro ao restaurar o perfil: %w", err)
+		}
+	}
+
+	for _, c := range archive.Contas {
+		if _, err := tx.Exec(database.Rebind("INSERT INTO contas (user_id, nome, saldo_inicial) VALUES (?, ?, ?)"), targetUserID, c.Nome, c.SaldoInicial); err != nil {
+			return summary, fmt.Errorf("erro ao restaurar a conta '%s': %w", c.Nome, err)
+		}
+		summary.Contas++
+	}
+
+	// Cada movimentação recebe um ID novo; o mapa liga os anexos ao novo ID.
+	query := fmt.Sprintf("INSERT INTO %s (user_id, created_by, data_ocorrencia, descricao, valor, categoria, conta, consolidado, deleted_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)", database.TableName)
+	if database.DriverName == "postgres" {
+		query += " RETURNING id"
+	}
+	newIDs := make(map[int]int64, len(archive.Movimentacoes))
+	for _, m := range archive.Movimentacoes {
+		id, err := insertReturningID(tx, query, targetUserID, targetUserID, m.DataOcorrencia, m.Descricao, m.Valor, m.Categoria, m.Conta, m.Consolidado, m.DeletedAt)
+		if err != nil {
+			return summary, fmt.Errorf("erro ao restaurar a movimentação %d: %w", m.ID, err)
+		}
+		newIDs[m.ID] = id
+		summary.Movimentacoes++
+	}
+
+	for _, inv := range archive.InvestimentosNacionais {
+		if _, err := tx.Exec(database.Rebind("INSERT INTO investimentos_nacionais (user_id, ticker, tipo, quantidade) VALUES (?, ?, ?, ?)"), targetUserID, inv.Ticker, inv.Tipo, inv.Quantidade); err != nil {
+			return summary, fmt.Errorf("erro ao restaurar o investimento '%s': %w", inv.Ticker, err)
+		}
+		summary.InvestimentosNacionais++
+	}
+	for _, inv := range archive.InvestimentosInternacionais {
+		if _, err := tx.Exec(database.Rebind("INSERT INTO investimentos_internacionais (user_id, ticker, descricao, quantidade, moeda) VALUES (?, ?, ?, ?, ?)"), targetUserID, inv.Ticker, inv.Descricao, inv.Quantidade, inv.Moeda); err != nil {
+			return summary, fmt.Errorf("erro ao restaurar o investimento '%s': %w", inv.Ticker, err)
+		}
+		summary.InvestimentosInternacionais++
+	}
+	for _, op := range archive.OperacoesInvestimentos {
+		if _, err := tx.Exec(database.Rebind("INSERT INTO operacoes_investimentos (user_id, ticker, tipo_ativo, tipo, data, quantidade, preco, taxas, corretora, created_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)"),
+			targetUserID, op.Ticker, op.TipoAtivo, op.Tipo, op.Data, op.Quantidade, op.Preco, op.Taxas, op.Corretora, op.CreatedAt); err != nil {
+			return summary, fmt.Errorf("erro ao restaurar a operação de '%s': %w", op.Ticker, err)
+		}
+		summary.OperacoesInvestimentos++
+	}
+	// A entrada ligada ao provento só está no backup quando era do livro pessoal.
+	for _, p := range archive.Proventos {
+		var movID interface{}
+		if id, ok := newIDs[p.MovimentacaoID]; ok && p.MovimentacaoID != 0 {
+			movID = id
+		}
+		if _, err := tx.Exec(database.Rebind("INSERT INTO proventos (user_id, ticker, tipo_ativo, tipo, data_ex, data_pagamento, valor_por_cota, quantidade, imposto_retido, movimentacao_id, created_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)"),
+			targetUserID, p.Ticker, p.TipoAtivo, p.Tipo, p.DataEx, p.DataPagamento, p.ValorPorCota, p.Quantidade, p.ImpostoRetido, movID, p.CreatedAt); err != nil {
+			return summary, fmt.Errorf("erro ao restaurar o provento de '%s': %w", p.Ticker, err)
+		}
+		summary.Proventos++
+	}
+	for _, t := range archive.RendaFixa {
+		if _, err := tx.Exec(database.Rebind("INSERT INTO renda_fixa (user_id, nome, tipo, emissor, indexador, taxa, valor_aplicado, data_aplicacao, vencimento, liquidez, created_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)"),
+			targetUserID, t.Nome, t.Tipo, t.Emissor, t.Indexador, t.Taxa, t.ValorAplicado, t.DataAplicacao, t.Vencimento, t.Liquidez, t.CreatedAt); err != nil {
+			return summary, fmt.Errorf("erro ao restaurar o título '%s': %w", t.Nome, err)
+		}
+		summary.RendaFixa++
+	}
+	for _, inv := range archive.InvestimentosCripto {
+		if _, err := tx.Exec(database.Rebind("INSERT INTO investimentos_cripto (user_id, simbolo, descricao, quantidade) VALUES (?, ?, ?, ?)"), targetUserID, inv.Simbolo, inv.Descricao, inv.Quantidade); err != nil {
+			return summary, fmt.Errorf("erro ao restaurar o criptoativo '%s': %w", inv.Simbolo, err)
+		}
+		summary.InvestimentosCripto++
+	}
+	for _, a := range archive.AlocacaoAlvo {
+		if _, err := tx.Exec(database.Rebind("INSERT INTO alocacao_alvo (user_id, classe, ticker, percentual) VALUES (?, ?, ?, ?)"), targetUserID, a.Classe, a.Ticker, a.Percentual); err != nil {
+			return summary, fmt.Errorf("erro ao restaurar a alocação-alvo: %w", err)
+		}
+		summary.AlocacaoAlvo++
+	}
+
+	for _, msg := range archive.ChatHistory {
+		if _, err := tx.Exec(database.Rebind("INSERT INTO chat_history (user_id, role, content, created_at) VALUES (?, ?, ?, ?)"), targetUserID, msg.Role, msg.Content, msg.CreatedAt); err != nil {
+			return summary, fmt.Errorf("erro ao restaurar o histórico do chat: %w", err)
+		}
+		summary.ChatHistory++
+	}
+
+	var written []string
+	cleanup := func() {
+		for _, key := range written {
+			if err := store.Delete(key); err != nil {
+				log.Printf("Aviso: não foi possível remover '%s' após falha na restauração: %v", key, err)
+			}
+		}
+	}
+	for _, a := range archive.Anexos {
+		suffix, err := randomHex(16)
+		if err != nil {
+			cleanup()
+			return summary, err
+		}
+		key := fmt.Sprintf("anexos/%d/%s%s", targetUserID, suffix, path.Ext(a.Arquivo))
+		if err := copyFromZip(zr, a.Arquivo, store, key, a.ContentType); err != nil {
+			cleanup()
+			return summary, err
+		}
+		written = append(written, key)
+
+		var thumbKey interface{}
+		if a.Miniatura != "" {
+			k := fmt.Sprintf("anexos/%d/%s_thumb.jpg", targetUserID, suffix)
+			if err := copyFromZip(zr, a.Miniatura, store, k, "image/jpeg"); err != nil {
+				cleanup()
+				return summary, err
+			}
+			written = append(written, k)
+			thumbKey = k
+		}
+
+		_, err = tx.Exec(database.Rebind("INSERT INTO anexos (movimentacao_id, user_id, nome_arquivo, content_type, tamanho, storage_key, thumbnail_key, created_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?)"),
+			newIDs[a.MovimentacaoID], targetUserID, a.NomeArquivo, a.ContentType, a.Tamanho, key, thumbKey, a.CreatedAt)
+		if err != nil {
+			cleanup()
+			return summary, fmt.Errorf("erro ao restaurar o anexo '%s': %w", a.NomeArquivo, err)
+		}
+		summary.Anexos++
+	}
+
+	if err := tx.Commit(); err != nil {
+		cleanup()
+		return summary, fmt.Errorf("erro ao confirmar a restauração: %w", err)
+	}
+	for _, key := range replacedKeys {
+		if err := store.Delete(key); err != nil {
+			log.Printf("Aviso: não foi possível apagar o anexo substituído '%s': %v", key, err)
+		}
+	}
+	return summary, nil
+}
+
+// userHasData informa se o usuário já tem dados pessoais que a restauração duplicaria.
+func userHasData(tx *sql.Tx, userID int64) (bool, error) {
+	for _, query := range []string{
+		fmt.Sprintf("SELECT COUNT(*) FROM %s WHERE user_id = ? AND household_id IS NULL", database.TableName),
+		"SELECT COUNT(*) FROM contas WHERE user_id = ? AND household_id IS NULL",
+		"SELECT COUNT(*) FROM investimentos_nacionais WHERE user_id = ?",
+		"SELECT COUNT(*) FROM investimentos_internacionais WHERE user_id = ?",
+		"SELECT COUNT(*) FROM operacoes_investimentos WHERE user_id = ?",
+		"SELECT COUNT(*) FROM proventos WHERE user_id = ?",
+		"SELECT COUNT(*) FROM renda_fixa WHERE user_id = ?",
+		"SELECT COUNT(*) FROM investimentos_cripto WHERE user_id = ?",
+		"SELECT COUNT(*) FROM alocacao_alvo WHERE user_id = ?",
+		"SELECT COUNT(*) FROM chat_history WHERE user_id = ?",
+	} {
+		var count int
+		if err := tx.QueryRow(database.Rebind(query), userID).Scan(&count); err != nil {
+			return false, fmt.Errorf("erro ao verificar os dados do usuário de destino: %w", err)
+		}
+		if count > 0 {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// clearUserData apaga os dados pessoais do usuário e retorna as chaves dos arquivos dos anexos
+// removidos, que só devem ser apagadas do armazenamento depois do commit.
+func clearUserData(tx *sql.Tx, userID int64) ([]string, error) {
+	owned := fmt.Sprintf("movimentacao_id IN (SELECT id FROM %s WHERE user_id = ? AND household_id IS NULL)", database.TableName)
+	rows, err := tx.Query(database.Rebind("SELECT storage_key, thumbnail_key FROM anexos WHERE "+owned), userID)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao listar os anexos existentes: %w", err)
+	}
+	var keys []string
+	for rows.Next() {
+		var key string
+		var thumb sql.NullString
+		if err := rows.Scan(&key, &thumb); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		keys = append(keys, key)
+		if thumb.String != "" {
+			keys = append(keys, thumb.String)
+		}
+	}
+	rows.Close()
+
+	for _, query := range []string{
+		"DELETE FROM anexos WHERE " + owned,
+		fmt.Sprintf("DELETE FROM %s WHERE user_id = ? AND household_id IS NULL", database.TableName),
+		"DELETE FROM contas WHERE user_id = ? AND household_id IS NULL",
+		"DELETE FROM investimentos_nacionais WHERE user_id = ?",
+		"DELETE FROM investimentos_internacionais WHERE user_id = ?",
+		"DELETE FROM operacoes_investimentos WHERE user_id = ?",
+		"DELETE FROM proventos WHERE user_id = ?",
+		"DELETE FROM renda_fixa WHERE user_id = ?",
+		"DELETE FROM investimentos_cripto WHERE user_id = ?",
+		"DELETE FROM alocacao_alvo WHERE user_id = ?",
+		"DELETE FROM chat_history WHERE user_id = ?",
+	} {
+		if _, err := tx.Exec(database.Rebind(query), userID); err != nil {
+			return nil, fmt.Errorf("erro ao apagar os dados existentes: %w", err)
+		}
+	}
+	return keys, nil
+}
+
+func copyFromZip(zr *zip.Reader, name string, store storage.Store, key, contentType string) error {
+	f, err := zr.Open(name)
+	if err != nil {
+		return fmt.Errorf("arquivo '%s' não está no backup: %w", name, err)
+	}
+	defer f.Close()
+	data, err := io.ReadAll(f)
+	if err != nil {
+		return fmt.Errorf("erro ao ler '%s' do backup: %w", name, err)
+	}
+	if err := store.Put(key, data, contentType); err != nil {
+		return fmt.Errorf("erro ao gravar '%s' no armazenamento: %w", name, err)
+	}
+	return nil
+}
+
+// insertReturningID executa o INSERT e retorna o ID gerado (RETURNING no PostgreSQL, LastInsertId no SQLite).
+func insertReturningID(tx *sql.Tx, query string, args ...interface{}) (int64, error) {
+	if database.DriverName == "postgres" {
+		var id int64
+		err := tx.QueryRow(database.Rebind(query), args...).Scan(&id)
+		return id, err
+	}
+	result, err := tx.Exec(database.Rebind(query), args...)
+	if err != nil {
+		return 0, err
+	}
+	return result.LastInsertId()
+}
+
+func randomHex(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
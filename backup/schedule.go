@@ -0,0 +1,227 @@
+// backup/schedule.go
+package backup
+
+import (
+	"bufio"
+	"database/sql"
+	"fmt"
+	"log"
+	"minhas_economias/config"
+	"minhas_economias/middleware"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// snapshotPrefix e snapshotTimeLayout formam o nome dos arquivos, ex: minhas_economias-20250110-030000.db.gz.
+// A rotação só considera arquivos com esse formato.
+const (
+	snapshotPrefix     = "minhas_economias-"
+	snapshotTimeLayout = "20060102-150405"
+)
+
+// defaultSnapshotDir fica ao lado do banco do container (/app/data), fora do código-fonte.
+var defaultSnapshotDir = filepath.Join("data", "snapshots")
+
+// RetentionPolicy define quantos snapshots manter: o mais recente de cada um dos últimos
+// Daily dias, Weekly semanas (ISO) e Monthly meses. Tudo zero desativa a rotação.
+type RetentionPolicy struct {
+	Daily   int
+	Weekly  int
+	Monthly int
+}
+
+// SnapshotConfig configura os backups automáticos do banco inteiro.
+type SnapshotConfig struct {
+	Dir        string
+	Passphrase string
+	Interval   time.Duration
+	Retention  RetentionPolicy
+}
+
+// SnapshotConfigFromEnv lê BACKUP_DIR, BACKUP_PASSPHRASE, BACKUP_INTERVAL_HOURS e
+// BACKUP_KEEP_DAILY/WEEKLY/MONTHLY. Os backups automáticos só são ativados com
+// BACKUP_INTERVAL_HOURS maior que zero, em uma única instância da API.
+func SnapshotConfigFromEnv() SnapshotConfig {
+	cfg := SnapshotConfig{
+		Dir:        os.Getenv("BACKUP_DIR"),
+		Passphrase: os.Getenv("BACKUP_PASSPHRASE"),
+		Interval:   time.Duration(config.IntFromEnv("BACKUP_INTERVAL_HOURS", 0, 0)) * time.Hour,
+		Retention: RetentionPolicy{
+			Daily:   config.IntFromEnv("BACKUP_KEEP_DAILY", 7, 0),
+			Weekly:  config.IntFromEnv("BACKUP_KEEP_WEEKLY", 4, 0),
+			Monthly: config.IntFromEnv("BACKUP_KEEP_MONTHLY", 12, 0),
+		},
+	}
+	if cfg.Dir == "" {
+		cfg.Dir = defaultSnapshotDir
+	}
+	return cfg
+}
+
+// RunSnapshot grava um snapshot em cfg.Dir, aplica a rotação e atualiza as métricas.
+// Retorna o caminho do arquivo criado.
+func RunSnapshot(db *sql.DB, driver string, cfg SnapshotConfig) (string, error) {
+	start := time.Now()
+	path, err := writeSnapshot(db, driver, cfg, start)
+	if err != nil {
+		middleware.BackupFailures.Inc()
+		return "", err
+	}
+	middleware.BackupLastSuccess.Set(float64(time.Now().Unix()))
+	middleware.BackupDuration.Set(time.Since(start).Seconds())
+
+	removed, err := Rotate(cfg.Dir, cfg.Retention)
+	if err != nil {
+		log.Printf("Aviso: erro na rotação dos backups: %v", err)
+	}
+	for _, name := range removed {
+		log.Printf("Backup antigo removido pela rotação: %s", name)
+	}
+	return path, nil
+}
+
+func writeSnapshot(db *sql.DB, driver string, cfg SnapshotConfig, now time.Time) (string, error) {
+	if err := os.MkdirAll(cfg.Dir, 0o700); err != nil {
+		return "", fmt.Errorf("erro ao criar o diretório de backups: %w", err)
+	}
+	path := filepath.Join(cfg.Dir, snapshotPrefix+now.Format(snapshotTimeLayout)+SnapshotExt(driver, cfg.Passphrase))
+	// Grava com um nome temporário e renomeia no fim, para que um snapshot interrompido nunca
+	// pareça completo para a rotação.
+	tmp, err := os.CreateTemp(cfg.Dir, ".snapshot-*")
+	if err != nil {
+		return "", fmt.Errorf("erro ao gravar o backup: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if err := tmp.Chmod(0o600); err != nil {
+		tmp.Close()
+		return "", fmt.Errorf("erro ao gravar o backup: %w", err)
+	}
+	w := bufio.NewWriter(tmp)
+	if err := WriteSnapshot(w, db, driver, cfg.Passphrase); err != nil {
+		tmp.Close()
+		return "", err
+	}
+	if err := w.Flush(); err != nil {
+		tmp.Close()
+		return "", fmt.Errorf("erro ao gravar o backup: %w", err)
+	}
+	info, err := tmp.Stat()
+	if err != nil {
+		tmp.Close()
+		return "", fmt.Errorf("erro ao gravar o backup: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return "", fmt.Errorf("erro ao gravar o backup: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return "", fmt.Errorf("erro ao gravar o backup: %w", err)
+	}
+	middleware.BackupSizeBytes.Set(float64(info.Size()))
+	return path, nil
+}
+
+// Rotate apaga de dir os snapshots que a política não mantém e retorna os nomes removidos.
+func Rotate(dir string, policy RetentionPolicy) ([]string, error) {
+	if policy.Daily == 0 && policy.Weekly == 0 && policy.Monthly == 0 {
+		return nil, nil
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	snapshots := make(map[string]time.Time)
+	for _, e := range entries {
+		if e.IsDir() {
+			continue
+		}
+		if t, ok := snapshotTime(e.Name()); ok {
+			snapshots[e.Name()] = t
+		}
+	}
+	var removed []string
+	for _, name := range expiredSnapshots(snapshots, policy) {
+		if err := os.Remove(filepath.Join(dir, name)); err != nil {
+			return removed, err
+		}
+		removed = append(removed, name)
+	}
+	return removed, nil
+}
+
+// snapshotTime extrai a data do nome do arquivo gerado por RunSnapshot.
+func snapshotTime(name string) (time.Time, bool) {
+	if !strings.HasPrefix(name, snapshotPrefix) || len(name) < len(snapshotPrefix)+len(snapshotTimeLayout) {
+		return time.Time{}, false
+	}
+	stamp := name[len(snapshotPrefix) : len(snapshotPrefix)+len(snapshotTimeLayout)]
+	t, err := time.ParseInLocation(snapshotTimeLayout, stamp, time.Local)
+	return t, err == nil
+}
+
+// expiredSnapshots aplica a política avô-pai-filho: percorrendo do mais novo para o mais
+// antigo, cada arquivo é mantido se for o primeiro do seu dia, semana ou mês e ainda houver
+// vaga naquele nível.
+func expiredSnapshots(snapshots map[string]time.Time, policy RetentionPolicy) []string {
+	names := make([]string, 0, len(snapshots))
+	for name := range snapshots {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if !snapshots[names[i]].Equal(snapshots[names[j]]) {
+			return snapshots[names[i]].After(snapshots[names[j]])
+		}
+		return names[i] > names[j]
+	})
+
+	type level struct {
+		limit  int
+		bucket func(time.Time) string
+		seen   map[string]bool
+	}
+	levels := []*level{
+		{policy.Daily, func(t time.Time) string { return t.Format("2006-01-02") }, map[string]bool{}},
+		{policy.Weekly, func(t time.Time) string { y, w := t.ISOWeek(); return fmt.Sprintf("%d-W%02d", y, w) }, map[string]bool{}},
+		{policy.Monthly, func(t time.Time) string { return t.Format("2006-01") }, map[string]bool{}},
+	}
+
+	var expired []string
+	for _, name := range names {
+		keep := false
+		for _, l := range levels {
+			key := l.bucket(snapshots[name])
+			if !l.seen[key] && len(l.seen) < l.limit {
+				l.seen[key] = true
+				keep = true
+			}
+		}
+		if !keep {
+			expired = append(expired, name)
+		}
+	}
+	return expired
+}
+
+// StartScheduler gera um snapshot na inicialização e depois a cada cfg.Interval. Com várias
+// réplicas da API, ative-o em apenas uma (ou use `admin -snapshot` em um cron).
+func StartScheduler(db *sql.DB, driver string, cfg SnapshotConfig) {
+	if cfg.Interval <= 0 {
+		log.Println("Backups automáticos do banco desativados (defina BACKUP_INTERVAL_HOURS para ativá-los).")
+		return
+	}
+	run := func() {
+		if path, err := RunSnapshot(db, driver, cfg); err != nil {
+			log.Printf("ERRO no backup automático do banco: %v", err)
+		} else {
+			log.Printf("Backup automático do banco gravado em %s.", path)
+		}
+	}
+	go func() {
+		run()
+		for range time.Tick(cfg.Interval) {
+			run()
+		}
+	}()
+}
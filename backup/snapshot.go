@@ -0,0 +1,450 @@
+// backup/snapshot.go
+package backup
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"database/sql"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mattn/go-sqlite3"
+	"golang.org/x/crypto/scrypt"
+)
+
+// encryptedMagic identifica snapshots cifrados: magic + salt + nonce base + blocos de até
+// encryptedChunkSize bytes em AES-256-GCM, com a chave derivada da senha por scrypt. Cada bloco
+// é gravado como tamanho (uint32 big-endian) + dados cifrados; o nonce é o nonce base com o
+// número do bloco nos últimos 8 bytes e o último bloco é autenticado com a marca final, o que
+// detecta arquivos truncados ou com blocos reordenados.
+const encryptedMagic = "MEBKENC2"
+
+const (
+	saltSize           = 16
+	nonceSize          = 12
+	encryptedChunkSize = 64 << 10
+)
+
+// ErrWrongPassphrase indica que o snapshot não pôde ser decifrado com a senha informada.
+var ErrWrongPassphrase = errors.New("senha incorreta ou arquivo de backup corrompido")
+
+// SnapshotExt retorna a extensão do arquivo gerado por WriteSnapshot (ex: ".db.gz.enc").
+func SnapshotExt(driver, passphrase string) string {
+	ext := ".db.gz"
+	if driver == "postgres" {
+		ext = ".sql.gz"
+	}
+	if passphrase != "" {
+		ext += ".enc"
+	}
+	return ext
+}
+
+// WriteSnapshot escreve em w uma cópia consistente do banco inteiro, comprimida com gzip e
+// cifrada se passphrase não for vazia. No SQLite é usada a API de backup online (o resultado é
+// um arquivo .db); no PostgreSQL, um dump lógico das tabelas da aplicação em SQL. Os dados são
+// transmitidos em fluxo, sem manter o banco inteiro em memória.
+func WriteSnapshot(w io.Writer, db *sql.DB, driver, passphrase string) error {
+	out := io.Writer(w)
+	var enc io.WriteCloser
+	if passphrase != "" {
+		var err error
+		if enc, err = NewEncryptWriter(w, passphrase); err != nil {
+			return err
+		}
+		out = enc
+	}
+	gz := gzip.NewWriter(out)
+	var err error
+	if driver == "postgres" {
+		err = dumpPostgres(db, gz)
+	} else {
+		err = copySQLite(db, gz)
+	}
+	if err != nil {
+		return err
+	}
+	if err := gz.Close(); err != nil {
+		return err
+	}
+	if enc != nil {
+		return enc.Close()
+	}
+	return nil
+}
+
+// copySQLite copia o banco para um arquivo temporário com a API de backup online do SQLite
+// (sem bloquear a aplicação) e escreve o arquivo resultante em w.
+func copySQLite(db *sql.DB, w io.Writer) error {
+	tmp, err := os.CreateTemp("", "minhas_economias_snapshot_*.db")
+	if err != nil {
+		return fmt.Errorf("erro ao criar arquivo temporário: %w", err)
+	}
+	tmpPath := tmp.Name()
+	tmp.Close()
+	defer os.Remove(tmpPath)
+
+	dest, err := sql.Open("sqlite3", tmpPath)
+	if err != nil {
+		return err
+	}
+	defer dest.Close()
+
+	ctx := context.Background()
+	destConn, err := dest.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer destConn.Close()
+	srcConn, err := db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer srcConn.Close()
+
+	err = destConn.Raw(func(destDriver interface{}) error {
+		return srcConn.Raw(func(srcDriver interface{}) error {
+			destSQLite, ok1 := destDriver.(*sqlite3.SQLiteConn)
+			srcSQLite, ok2 := srcDriver.(*sqlite3.SQLiteConn)
+			if !ok1 || !ok2 {
+				return errors.New("conexão não é do driver sqlite3")
+			}
+			bk, err := destSQLite.Backup("main", srcSQLite, "main")
+			if err != nil {
+				return err
+			}
+			// Copia em etapas para não segurar o banco de origem por muito tempo.
+			for {
+				done, err := bk.Step(256)
+				if err != nil {
+					bk.Finish()
+					return err
+				}
+				if done {
+					break
+				}
+			}
+			return bk.Finish()
+		})
+	})
+	if err != nil {
+		return fmt.Errorf("erro no backup online do SQLite: %w", err)
+	}
+	destConn.Close()
+	dest.Close()
+
+	f, err := os.Open(tmpPath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = io.Copy(w, f)
+	return err
+}
+
+// dumpPostgres escreve INSERTs de todas as tabelas da aplicação lidas em uma transação
+// REPEATABLE READ (visão consistente). O dump não contém o schema: para restaurar, crie as
+// tabelas com `cmd/admin -init-db` em um banco vazio e execute o arquivo com psql.
+func dumpPostgres(db *sql.DB, w io.Writer) error {
+	tx, err := db.BeginTx(context.Background(), &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+	if err != nil {
+		return fmt.Errorf("erro ao iniciar a transação do dump: %w", err)
+	}
+	defer tx.Rollback()
+
+	fmt.Fprintf(w, "-- Minhas Economias: dump lógico gerado em %s\n", time.Now().UTC().Format(time.RFC3339))
+	fmt.Fprintln(w, "-- Restauração: crie o schema com `admin -init-db` em um banco vazio e execute este arquivo com psql.")
+	fmt.Fprintln(w, "BEGIN;")
+	for _, table := range AppTables {
+		if err := dumpTable(tx, w, table); err != nil {
+			return fmt.Errorf("erro ao exportar '%s': %w", table.Name, err)
+		}
+	}
+	for _, table := range AppTables {
+		if table.Serial {
+			fmt.Fprintf(w, "SELECT setval(pg_get_serial_sequence('%s', 'id'), MAX(id)) FROM %s HAVING MAX(id) IS NOT NULL;\n", table.Name, table.Name)
+		}
+	}
+	_, err = fmt.Fprintln(w, "COMMIT;")
+	return err
+}
+
+func dumpTable(tx *sql.Tx, w io.Writer, table Table) error {
+	rows, err := tx.Query(fmt.Sprintf("SELECT * FROM %s ORDER BY %s", table.Name, strings.Join(table.Keys, ", ")))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	columns, err := rows.Columns()
+	if err != nil {
+		return err
+	}
+	prefix := fmt.Sprintf("INSERT INTO %s (%s) VALUES (", table.Name, strings.Join(columns, ", "))
+
+	values := make([]interface{}, len(columns))
+	pointers := make([]interface{}, len(columns))
+	for i := range values {
+		pointers[i] = &values[i]
+	}
+	literals := make([]string, len(columns))
+	for rows.Next() {
+		if err := rows.Scan(pointers...); err != nil {
+			return err
+		}
+		for i, v := range values {
+			literals[i] = sqlLiteral(v)
+		}
+		if _, err := fmt.Fprintf(w, "%s%s);\n", prefix, strings.Join(literals, ", ")); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// sqlLiteral formata o valor lido do PostgreSQL como literal SQL.
+func sqlLiteral(v interface{}) string {
+	switch val := v.(type) {
+	case nil:
+		return "NULL"
+	case bool:
+		if val {
+			return "TRUE"
+		}
+		return "FALSE"
+	case int64:
+		return strconv.FormatInt(val, 10)
+	case float64:
+		return strconv.FormatFloat(val, 'f', -1, 64)
+	case time.Time:
+		return "'" + val.Format("2006-01-02 15:04:05.999999Z07:00") + "'"
+	case []byte:
+		return "'" + strings.ReplaceAll(string(val), "'", "''") + "'"
+	case string:
+		return "'" + strings.ReplaceAll(val, "'", "''") + "'"
+	}
+	return "'" + strings.ReplaceAll(fmt.Sprint(v), "'", "''") + "'"
+}
+
+// Encrypt cifra data com AES-256-GCM usando uma chave derivada de passphrase.
+func Encrypt(data []byte, passphrase string) ([]byte, error) {
+	var buf bytes.Buffer
+	enc, err := NewEncryptWriter(&buf, passphrase)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := enc.Write(data); err != nil {
+		return nil, err
+	}
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Decrypt reverte Encrypt.
+func Decrypt(data []byte, passphrase string) ([]byte, error) {
+	r, err := NewDecryptReader(bytes.NewReader(data), passphrase)
+	if err != nil {
+		return nil, err
+	}
+	return io.ReadAll(r)
+}
+
+// NewEncryptWriter retorna um writer que cifra em blocos tudo o que recebe e o escreve em w.
+// Close grava o último bloco e é obrigatório; ele não fecha w.
+func NewEncryptWriter(w io.Writer, passphrase string) (io.WriteCloser, error) {
+	salt := make([]byte, saltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	gcm, err := newGCM(passphrase, salt)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, nonceSize)
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	header := make([]byte, 0, len(encryptedMagic)+saltSize+nonceSize)
+	header = append(header, encryptedMagic...)
+	header = append(header, salt...)
+	header = append(header, nonce...)
+	if _, err := w.Write(header); err != nil {
+		return nil, err
+	}
+	return &encryptWriter{w: w, gcm: gcm, nonce: nonce, buf: make([]byte, 0, encryptedChunkSize)}, nil
+}
+
+type encryptWriter struct {
+	w       io.Writer
+	gcm     cipher.AEAD
+	nonce   []byte
+	counter uint64
+	buf     []byte
+	closed  bool
+}
+
+func (e *encryptWriter) Write(p []byte) (int, error) {
+	if e.closed {
+		return 0, errors.New("escrita após o fechamento do backup cifrado")
+	}
+	written := 0
+	for len(p) > 0 {
+		n := encryptedChunkSize - len(e.buf)
+		if n > len(p) {
+			n = len(p)
+		}
+		e.buf = append(e.buf, p[:n]...)
+		p = p[n:]
+		written += n
+		if len(e.buf) == encryptedChunkSize {
+			if err := e.seal(false); err != nil {
+				return written, err
+			}
+		}
+	}
+	return written, nil
+}
+
+func (e *encryptWriter) Close() error {
+	if e.closed {
+		return nil
+	}
+	e.closed = true
+	return e.seal(true)
+}
+
+func (e *encryptWriter) seal(final bool) error {
+	sealed := e.gcm.Seal(nil, chunkNonce(e.nonce, e.counter), e.buf, chunkAAD(final))
+	var size [4]byte
+	binary.BigEndian.PutUint32(size[:], uint32(len(sealed)))
+	if _, err := e.w.Write(size[:]); err != nil {
+		return err
+	}
+	if _, err := e.w.Write(sealed); err != nil {
+		return err
+	}
+	e.counter++
+	e.buf = e.buf[:0]
+	return nil
+}
+
+// NewDecryptReader lê o cabeçalho de um backup cifrado e retorna um reader com os dados
+// decifrados. Um erro de leitura é devolvido se o arquivo terminar antes do último bloco.
+func NewDecryptReader(r io.Reader, passphrase string) (io.Reader, error) {
+	header := make([]byte, len(encryptedMagic)+saltSize+nonceSize)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, errors.New("o arquivo não é um backup cifrado")
+	}
+	if string(header[:len(encryptedMagic)]) != encryptedMagic {
+		return nil, errors.New("o arquivo não é um backup cifrado")
+	}
+	salt := header[len(encryptedMagic) : len(encryptedMagic)+saltSize]
+	nonce := header[len(encryptedMagic)+saltSize:]
+	gcm, err := newGCM(passphrase, salt)
+	if err != nil {
+		return nil, err
+	}
+	d := &decryptReader{r: r, gcm: gcm, nonce: nonce}
+	// Decifra o primeiro bloco já aqui para que uma senha errada falhe antes de qualquer gravação.
+	if err := d.next(); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+type decryptReader struct {
+	r       io.Reader
+	gcm     cipher.AEAD
+	nonce   []byte
+	counter uint64
+	plain   []byte
+	done    bool
+}
+
+func (d *decryptReader) Read(p []byte) (int, error) {
+	for len(d.plain) == 0 {
+		if d.done {
+			return 0, io.EOF
+		}
+		if err := d.next(); err != nil {
+			return 0, err
+		}
+	}
+	n := copy(p, d.plain)
+	d.plain = d.plain[n:]
+	return n, nil
+}
+
+func (d *decryptReader) next() error {
+	var size [4]byte
+	if _, err := io.ReadFull(d.r, size[:]); err != nil {
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return errors.New("backup cifrado incompleto")
+		}
+		return err
+	}
+	n := binary.BigEndian.Uint32(size[:])
+	if int(n) > encryptedChunkSize+d.gcm.Overhead() {
+		return ErrWrongPassphrase
+	}
+	sealed := make([]byte, n)
+	if _, err := io.ReadFull(d.r, sealed); err != nil {
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return errors.New("backup cifrado incompleto")
+		}
+		return err
+	}
+	nonce := chunkNonce(d.nonce, d.counter)
+	plain, err := d.gcm.Open(nil, nonce, sealed, chunkAAD(false))
+	if err != nil {
+		if plain, err = d.gcm.Open(nil, nonce, sealed, chunkAAD(true)); err != nil {
+			return ErrWrongPassphrase
+		}
+		d.done = true
+	}
+	d.plain = plain
+	d.counter++
+	return nil
+}
+
+// chunkNonce combina o nonce base com o número do bloco.
+func chunkNonce(base []byte, counter uint64) []byte {
+	nonce := make([]byte, len(base))
+	copy(nonce, base)
+	var c [8]byte
+	binary.BigEndian.PutUint64(c[:], counter)
+	for i := range c {
+		nonce[len(nonce)-8+i] ^= c[i]
+	}
+	return nonce
+}
+
+func chunkAAD(final bool) []byte {
+	if final {
+		return []byte(encryptedMagic + "\x01")
+	}
+	return []byte(encryptedMagic + "\x00")
+}
+
+func newGCM(passphrase string, salt []byte) (cipher.AEAD, error) {
+	key, err := scrypt.Key([]byte(passphrase), salt, 1<<15, 8, 1, 32)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
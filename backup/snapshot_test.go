@@ -0,0 +1,141 @@
+package backup
+
+import (
+	"bytes"
+	"compress/gzip"
+	"database/sql"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func TestEncryptDecrypt(t *testing.T) {
+	data := []byte("conteúdo do backup")
+	enc, err := Encrypt(data, "segredo")
+	if err != nil {
+		t.Fatalf("Encrypt: %v", err)
+	}
+	if bytes.Contains(enc, data) {
+		t.Fatal("o arquivo cifrado contém o texto original")
+	}
+	plain, err := Decrypt(enc, "segredo")
+	if err != nil || !bytes.Equal(plain, data) {
+		t.Fatalf("Decrypt = %q, %v", plain, err)
+	}
+	if _, err := Decrypt(enc, "errada"); err != ErrWrongPassphrase {
+		t.Errorf("senha errada: esperado ErrWrongPassphrase, obtido %v", err)
+	}
+}
+
+func TestDecryptMultipleChunks(t *testing.T) {
+	data := bytes.Repeat([]byte("0123456789"), encryptedChunkSize/5+3)
+	enc, err := Encrypt(data, "segredo")
+	if err != nil {
+		t.Fatalf("Encrypt: %v", err)
+	}
+	plain, err := Decrypt(enc, "segredo")
+	if err != nil || !bytes.Equal(plain, data) {
+		t.Fatalf("Decrypt: %d bytes, %v; esperado %d bytes", len(plain), err, len(data))
+	}
+	// Sem o último bloco o arquivo precisa ser rejeitado, mesmo que os anteriores sejam válidos.
+	truncated := enc[:len(encryptedMagic)+saltSize+nonceSize+4+encryptedChunkSize+16]
+	if _, err := Decrypt(truncated, "segredo"); err == nil {
+		t.Error("backup truncado foi aceito")
+	}
+}
+
+func TestSnapshotSQLite(t *testing.T) {
+	db, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "origem.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if _, err := db.Exec("CREATE TABLE contas (nome TEXT); INSERT INTO contas VALUES ('Nubank'), ('Itaú');"); err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if err := WriteSnapshot(&buf, db, "sqlite3", "senha"); err != nil {
+		t.Fatalf("WriteSnapshot: %v", err)
+	}
+	if ext := SnapshotExt("sqlite3", "senha"); ext != ".db.gz.enc" {
+		t.Errorf("extensão = %q", ext)
+	}
+	plain, err := Decrypt(buf.Bytes(), "senha")
+	if err != nil {
+		t.Fatal(err)
+	}
+	gz, err := gzip.NewReader(bytes.NewReader(plain))
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw, err := io.ReadAll(gz)
+	if err != nil {
+		t.Fatal(err)
+	}
+	restored := filepath.Join(t.TempDir(), "restaurado.db")
+	if err := os.WriteFile(restored, raw, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	copyDB, err := sql.Open("sqlite3", restored)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer copyDB.Close()
+	var count int
+	if err := copyDB.QueryRow("SELECT COUNT(*) FROM contas").Scan(&count); err != nil || count != 2 {
+		t.Errorf("cópia com %d contas (%v), esperado 2", count, err)
+	}
+}
+
+func TestRotate(t *testing.T) {
+	dir := t.TempDir()
+	start := time.Date(2025, 1, 1, 3, 0, 0, 0, time.Local)
+	// Dois snapshots por dia durante 90 dias, além de um arquivo que não é snapshot.
+	for day := 0; day < 90; day++ {
+		for _, hour := range []int{0, 12} {
+			name := snapshotPrefix + start.AddDate(0, 0, day).Add(time.Duration(hour)*time.Hour).Format(snapshotTimeLayout) + ".db.gz"
+			if err := os.WriteFile(filepath.Join(dir, name), []byte("x"), 0o600); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if err := os.WriteFile(filepath.Join(dir, "notas.txt"), []byte("x"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := Rotate(dir, RetentionPolicy{Daily: 3, Weekly: 2, Monthly: 3}); err != nil {
+		t.Fatalf("Rotate: %v", err)
+	}
+
+	entries, _ := os.ReadDir(dir)
+	var kept []string
+	for _, e := range entries {
+		kept = append(kept, e.Name())
+	}
+	sort.Strings(kept)
+	// Último dia: 31/03 (segunda-feira). Diários: 31/03, 30/03 e 29/03, sempre o das 15h.
+	// Semanais: as semanas de 31/03 e de 30/03, já cobertas pelos diários.
+	// Mensais: março (coberto), fevereiro (28/02) e janeiro (31/01).
+	want := []string{
+		snapshotPrefix + "20250131-150000.db.gz",
+		snapshotPrefix + "20250228-150000.db.gz",
+		snapshotPrefix + "20250329-150000.db.gz",
+		snapshotPrefix + "20250330-150000.db.gz",
+		snapshotPrefix + "20250331-150000.db.gz",
+		"notas.txt",
+	}
+	if len(kept) != len(want) {
+		t.Fatalf("mantidos %v, esperado %v", kept, want)
+	}
+	for i := range want {
+		if kept[i] != want[i] {
+			t.Fatalf("mantidos %v, esperado %v", kept, want)
+		}
+	}
+}
@@ -0,0 +1,36 @@
+// backup/tables.go
+package backup
+
+import "minhas_economias/database"
+
+// Table descreve uma tabela da aplicação para cópias e dumps do banco inteiro.
+type Table struct {
+	Name   string
+	Keys   []string // Chave primária, usada para ordenar e paginar as linhas
+	Serial bool     // Coluna id gerada por sequência (ajustada no PostgreSQL após a carga)
+}
+
+// AppTables lista as tabelas da aplicação em ordem de dependência (pais antes dos filhos).
+// Novas tabelas do schema (cmd/admin/schema.go) devem ser incluídas aqui.
+var AppTables = []Table{
+	{"users", []string{"id"}, true},
+	{"households", []string{"id"}, true},
+	{"household_members", []string{"household_id", "user_id"}, false},
+	{database.TableName, []string{"id"}, true},
+	{"contas", []string{"user_id", "nome"}, false},
+	{"user_profiles", []string{"user_id"}, false},
+	{"investimentos_nacionais", []string{"user_id", "ticker"}, false},
+	{"investimentos_internacionais", []string{"user_id", "ticker"}, false},
+	{"chat_history", []string{"id"}, true},
+	{"password_reset_tokens", []string{"id"}, true},
+	{"user_sessions", []string{"id"}, false},
+	{"audit_logs", []string{"id"}, true},
+	{"anexos", []string{"id"}, true},
+	{"historico_precos", []string{"ticker", "data"}, false},
+	{"operacoes_investimentos", []string{"id"}, true},
+	{"proventos", []string{"id"}, true},
+	{"indices_economicos", []string{"serie", "data"}, false},
+	{"renda_fixa", []string{"id"}, true},
+	{"investimentos_cripto", []string{"user_id", "simbolo"}, false},
+	{"alocacao_alvo", []string{"user_id", "classe", "ticker"}, false},
+}
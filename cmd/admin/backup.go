@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"log"
 	"minhas_economias/database"
+	"minhas_economias/storage"
 	"os"
 	"path/filepath"
 	"strconv"
@@ -19,6 +20,70 @@ func runExport(db *sql.DB, outputFilename string, userId int64) {
 		os.Exit(1)
 	}
 	log.Printf("Dados exportados com sucesso para '%s'.\n", outputFilename)
+
+	anexosDir := filepath.Join(filepath.Dir(outputFilename), "anexos")
+	if err := exportAnexos(db, anexosDir, userId); err != nil {
+		log.Printf("Erro na exportação dos anexos: %v", err)
+		os.Exit(1)
+	}
+}
+
+// exportAnexos copia do armazenamento os anexos das movimentações exportadas para dir,
+// com um índice (anexos.csv) que liga cada arquivo à sua movimentação.
+func exportAnexos(db *sql.DB, dir string, userId int64) error {
+	query := fmt.Sprintf(`SELECT a.id, a.nome_arquivo, a.storage_key, m.data_ocorrencia, m.descricao, m.valor
+		FROM anexos a JOIN %s m ON m.id = a.movimentacao_id
+		WHERE m.user_id = ? AND m.household_id IS NULL AND m.deleted_at IS NULL ORDER BY m.data_ocorrencia ASC, a.id ASC`, tableName)
+	rows, err := db.Query(database.Rebind(query), userId)
+	if err != nil { return err }
+	defer rows.Close()
+
+	if err := storage.Init(); err != nil { return err }
+	store := storage.Current()
+
+	var index [][]string
+	for rows.Next() {
+		var id int64
+		var nome, key, descricao string
+		var rawDate interface{}
+		var valor float64
+		if err := rows.Scan(&id, &nome, &key, &rawDate, &descricao, &valor); err != nil { return err }
+
+		data, err := storage.ReadAll(store, key)
+		if err != nil {
+			log.Printf("   Aviso: anexo %d ('%s') não encontrado no armazenamento: %v", id, nome, err)
+			continue
+		}
+		if len(index) == 0 {
+			if err := os.MkdirAll(dir, 0755); err != nil { return err }
+		}
+		arquivo := fmt.Sprintf("%d_%s", id, filepath.Base(nome))
+		if err := os.WriteFile(filepath.Join(dir, arquivo), data, 0644); err != nil { return err }
+
+		dataFmt := ""
+		if t, ok := rawDate.(time.Time); ok {
+			dataFmt = t.Format("02/01/2006")
+		} else if s, ok := rawDate.(string); ok {
+			p, _ := time.Parse("2006-01-02", s)
+			dataFmt = p.Format("02/01/2006")
+		}
+		valFmt := strings.Replace(strconv.FormatFloat(valor, 'f', 2, 64), ".", ",", -1)
+		index = append(index, []string{arquivo, dataFmt, descricao, valFmt})
+	}
+	if err := rows.Err(); err != nil { return err }
+	if len(index) == 0 { return nil }
+
+	file, err := os.Create(filepath.Join(dir, "anexos.csv"))
+	if err != nil { return err }
+	defer file.Close()
+	writer := csv.NewWriter(file)
+	writer.Comma = rune(csvDelimiter)
+	defer writer.Flush()
+	writer.Write([]string{"Arquivo", "Data Ocorrência", "Descrição", "Valor"})
+	writer.WriteAll(index)
+
+	log.Printf("   %d anexos exportados para '%s'.", len(index), dir)
+	return nil
 }
 
 func exportToCSV(db *sql.DB, outputFilename string, userId int64) error {
@@ -36,7 +101,7 @@ func exportToCSV(db *sql.DB, outputFilename string, userId int64) error {
 
 	writer.Write([]string{"Data Ocorrência", "Descrição", "Valor", "Categoria", "Conta", "Consolidado"})
 
-	query := fmt.Sprintf("SELECT data_ocorrencia, descricao, valor, categoria, conta, consolidado FROM %s WHERE user_id = ? ORDER BY data_ocorrencia ASC", tableName)
+	query := fmt.Sprintf("SELECT data_ocorrencia, descricao, valor, categoria, conta, consolidado FROM %s WHERE user_id = ? AND household_id IS NULL AND deleted_at IS NULL ORDER BY data_ocorrencia ASC", tableName)
 	rows, err := db.Query(database.Rebind(query), userId)
 	if err != nil { return err }
 	defer rows.Close()
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"log"
+	"minhas_economias/backup"
+	"minhas_economias/database"
+	"minhas_economias/storage"
+	"os"
+	"path/filepath"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// runFullBackup grava o backup completo (.zip) do usuário em outputFilename.
+func runFullBackup(db *sql.DB, outputFilename string, userId int64) {
+	if err := storage.Init(); err != nil {
+		log.Fatalf("Erro ao configurar o armazenamento de anexos: %v", err)
+	}
+	if outputDir := filepath.Dir(outputFilename); outputDir != "" && outputDir != "." {
+		os.MkdirAll(outputDir, 0755)
+	}
+	file, err := os.Create(outputFilename)
+	if err != nil {
+		log.Fatalf("Erro ao criar '%s': %v", outputFilename, err)
+	}
+	defer file.Close()
+
+	archive, err := backup.Write(db, storage.Current(), userId, file)
+	if err != nil {
+		log.Fatalf("Erro no backup: %v", err)
+	}
+	log.Printf("Backup de '%s' gravado em '%s': %d movimentações, %d anexos.", archive.User.Email, outputFilename, len(archive.Movimentacoes), len(archive.Anexos))
+}
+
+// runRestore carrega um backup .zip. O destino é -user-id; sem ele, usa o usuário com o e-mail
+// do backup, criando-o com -password se ainda não existir (restauração em um banco novo).
+func runRestore(db *sql.DB, inputFilename string, userId int64, password string, replace bool) {
+	if err := storage.Init(); err != nil {
+		log.Fatalf("Erro ao configurar o armazenamento de anexos: %v", err)
+	}
+	file, err := os.Open(inputFilename)
+	if err != nil {
+		log.Fatalf("Erro ao abrir '%s': %v", inputFilename, err)
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		log.Fatalf("Erro ao ler '%s': %v", inputFilename, err)
+	}
+
+	archive, zr, err := backup.Read(file, info.Size())
+	if err != nil {
+		log.Fatalf("Backup inválido: %v", err)
+	}
+	log.Printf("Backup versão %d de '%s' (exportado em %s).", archive.Version, archive.User.Email, archive.ExportedAt.Local().Format("02/01/2006 15:04"))
+
+	if userId == 0 {
+		userId = findOrCreateRestoreUser(db, archive.User.Email, password)
+	}
+
+	summary, err := backup.Restore(db, storage.Current(), archive, zr, userId, backup.RestoreOptions{Replace: replace})
+	if errors.Is(err, backup.ErrTargetNotEmpty) {
+		log.Fatalf("ERRO: o usuário %d já possui dados. Use -replace para substituí-los.", userId)
+	}
+	if err != nil {
+		log.Fatalf("Erro na restauração: %v", err)
+	}
+	log.Printf("Restauração concluída no usuário %d: %s.", userId, summary)
+}
+
+func findOrCreateRestoreUser(db *sql.DB, email, password string) int64 {
+	var id int64
+	err := db.QueryRow(database.Rebind("SELECT id FROM users WHERE email = ?"), email).Scan(&id)
+	if err == nil {
+		return id
+	}
+	if err != sql.ErrNoRows {
+		log.Fatalf("Erro ao buscar o usuário '%s': %v", email, err)
+	}
+	if password == "" {
+		log.Fatalf("O usuário '%s' não existe neste banco. Informe -user-id ou -password para criá-lo.", email)
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		log.Fatalf("Erro ao gerar hash: %v", err)
+	}
+	insertUserAutoID(db, email, string(hash), false)
+	if err := db.QueryRow(database.Rebind("SELECT id FROM users WHERE email = ?"), email).Scan(&id); err != nil {
+		log.Fatalf("Erro ao buscar o usuário criado: %v", err)
+	}
+	return id
+}
@@ -7,6 +7,7 @@ import (
 	"io"
 	"log"
 	"minhas_economias/database"
+	"minhas_economias/investimentos"
 	"os"
 	"strconv"
 	"strings"
@@ -256,3 +257,20 @@ func runPopulateSaldos(db *sql.DB, userID int64) {
 		log.Println("Saldos iniciais atualizados com sucesso!")
 	}
 }
+
+// runImportIndices grava a série de índices (taxas ou benchmarks) a partir do CSV no formato do SGS.
+func runImportIndices(serie, filePath string) {
+	if serie == "" || filePath == "" {
+		log.Fatal("Para importar índices, as flags -serie e -indices-file são obrigatórias.")
+	}
+	file, err := os.Open(filePath)
+	if err != nil {
+		log.Fatalf("Erro ao abrir '%s': %v", filePath, err)
+	}
+	defer file.Close()
+	n, err := investimentos.ImportarIndicesCSV(serie, file)
+	if err != nil {
+		log.Fatalf("Erro ao importar a série %s: %v", serie, err)
+	}
+	log.Printf("Série %s: %d valores importados de '%s'.", strings.ToUpper(serie), n, filePath)
+}
@@ -4,6 +4,7 @@ import (
 	"flag"
 	"log"
 	"minhas_economias/database"
+	"minhas_economias/middleware"
 
 	_ "github.com/lib/pq"
 	_ "github.com/mattn/go-sqlite3"
@@ -35,6 +36,16 @@ func main() {
 	// Flags de Usuário e Configuração
 	createUser := flag.Bool("create-user", false, "Criar um novo usuário.")
 	initSchema := flag.Bool("init-db", false, "Criar tabelas do banco de dados.")
+	fullBackup := flag.Bool("backup", false, "Gerar o backup completo (.zip) do usuário em -backup-file.")
+	restoreBackup := flag.Bool("restore", false, "Restaurar o backup completo de -backup-file (em -user-id ou no usuário com o e-mail do backup).")
+	replaceData := flag.Bool("replace", false, "Na restauração, apagar antes os dados existentes do usuário de destino.")
+	dbSnapshot := flag.Bool("snapshot", false, "Gerar um backup do banco inteiro em BACKUP_DIR (com rotação), como o agendador da API.")
+	decryptSnapshot := flag.Bool("decrypt-snapshot", false, "Decifrar o backup do banco (.enc) indicado em -backup-file usando BACKUP_PASSPHRASE.")
+	migrateDB := flag.Bool("migrate", false, "Copiar todas as tabelas para o banco de destino configurado em TARGET_DB_* (retomável).")
+	migrateBatch := flag.Int("migrate-batch", 1000, "Linhas por lote na migração (-migrate).")
+	migrateReset := flag.Bool("migrate-reset", false, "Na migração, ignorar o progresso salvo e recomeçar a cópia.")
+	purgeAudit := flag.Bool("purge-audit", false, "Remover do log de auditoria as entradas mais antigas que AUDIT_RETENTION_DAYS.")
+	importIndices := flag.Bool("import-indices", false, "Importar a série -serie (CDI, SELIC, IPCA, IBOV, IFIX, SP500 ou DOLAR) do CSV em -indices-file (formato do SGS/Banco Central).")
 	
 	// Parâmetros
 	userIdParam := flag.Int64("user-id", 0, "ID do usuário (obrigatório para import/export).")
@@ -42,6 +53,9 @@ func main() {
 	userPass := flag.String("password", "", "Senha para criação de usuário.")
 	userAdmin := flag.Bool("admin", false, "Define se o usuário criado é admin.")
 	outputPathParam := flag.String("output-path", "backup/extrato_exportado.csv", "Caminho para exportação.")
+	backupFileParam := flag.String("backup-file", "backup/minhas_economias_backup.zip", "Arquivo do backup completo (-backup/-restore).")
+	serieParam := flag.String("serie", "", "Série de índices para -import-indices (CDI, SELIC, IPCA, IBOV, IFIX, SP500 ou DOLAR).")
+	indicesFileParam := flag.String("indices-file", "", "CSV da série para -import-indices (data;valor, como exportado pelo SGS).")
 
 	flag.Parse()
 
@@ -55,10 +69,51 @@ func main() {
 
 	// 1. Inicialização de Schema
 	if *initSchema {
-		createTables(db)
+		createTables(db, database.DriverName)
 		// Se for apenas init-db, não precisamos sair, podemos continuar se houver outras flags
 	}
 
+	// Backup do banco inteiro (também feito periodicamente pela API)
+	if *dbSnapshot {
+		runSnapshot(db)
+		return
+	}
+	if *decryptSnapshot {
+		runDecryptSnapshot(*backupFileParam)
+		return
+	}
+
+	// Migração entre bancos (ex: SQLite -> PostgreSQL)
+	if *migrateDB {
+		if *migrateBatch <= 0 {
+			log.Fatal("A flag -migrate-batch deve ser maior que zero.")
+		}
+		runMigration(db, *migrateBatch, *migrateReset)
+		return
+	}
+
+	// Limpeza do log de auditoria (também feita diariamente pelo servidor)
+	if *purgeAudit {
+		removed, err := middleware.PurgeAuditLogs(middleware.AuditRetentionFromEnv())
+		if err != nil {
+			log.Fatalf("Erro ao limpar o log de auditoria: %v", err)
+		}
+		log.Printf("Log de auditoria: %d entradas removidas.", removed)
+		return
+	}
+
+	// Séries de índices usadas na avaliação da renda fixa (compartilhadas por todos os usuários)
+	if *importIndices {
+		runImportIndices(*serieParam, *indicesFileParam)
+		return
+	}
+
+	// Restauração do backup completo (pode criar o usuário em um banco novo)
+	if *restoreBackup {
+		runRestore(db, *backupFileParam, *userIdParam, *userPass, *replaceData)
+		return
+	}
+
 	// 2. Criação de Usuário
 	if *createUser {
 		if *userEmail == "" || *userPass == "" {
@@ -69,7 +124,7 @@ func main() {
 	}
 
 	// 3. Operações de Importação/Exportação
-	hasDataOp := *importMovimentacoes || *exportMovimentacoes || *importNacionais || *importInternacionais
+	hasDataOp := *importMovimentacoes || *exportMovimentacoes || *importNacionais || *importInternacionais || *fullBackup
 
 	if hasDataOp {
 		if *userIdParam == 0 {
@@ -92,6 +147,9 @@ func main() {
 		if *exportMovimentacoes {
 			runExport(db, *outputPathParam, *userIdParam)
 		}
+		if *fullBackup {
+			runFullBackup(db, *backupFileParam, *userIdParam)
+		}
 	} else if !*initSchema && !*createUser {
 		flag.PrintDefaults()
 	}
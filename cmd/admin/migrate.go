@@ -0,0 +1,426 @@
+package main
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"minhas_economias/backup"
+	"minhas_economias/database"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// migrationForeignKeys são as referências conferidas na origem antes da cópia: o SQLite só
+// aplica chaves estrangeiras com foreign_keys ativado, e linhas órfãs seriam recusadas pelo PostgreSQL.
+// Anexos e proventos não têm chave estrangeira no schema, mas órfãos neles copiariam vínculos quebrados.
+var migrationForeignKeys = []struct{ table, column, parent string }{
+	{"households", "owner_id", "users"},
+	{"household_members", "household_id", "households"},
+	{"household_members", "user_id", "users"},
+	{tableName, "user_id", "users"},
+	{tableName, "household_id", "households"},
+	{tableName, "created_by", "users"},
+	{"contas", "user_id", "users"},
+	{"contas", "household_id", "households"},
+	{"user_profiles", "user_id", "users"},
+	{"investimentos_nacionais", "user_id", "users"},
+	{"investimentos_internacionais", "user_id", "users"},
+	{"chat_history", "user_id", "users"},
+	{"password_reset_tokens", "user_id", "users"},
+	{"user_sessions", "user_id", "users"},
+	{"operacoes_investimentos", "user_id", "users"},
+	{"proventos", "user_id", "users"},
+	{"proventos", "movimentacao_id", tableName},
+	{"anexos", "movimentacao_id", tableName},
+	{"anexos", "user_id", "users"},
+	{"renda_fixa", "user_id", "users"},
+	{"investimentos_cripto", "user_id", "users"},
+	{"alocacao_alvo", "user_id", "users"},
+}
+
+// dbHandle agrupa uma conexão e o seu driver, já que a migração trabalha com dois bancos ao mesmo tempo.
+type dbHandle struct {
+	db     *sql.DB
+	driver string
+}
+
+func (h dbHandle) rebind(query string) string {
+	return database.RebindFor(h.driver, query)
+}
+
+// runMigration copia todas as tabelas do banco configurado (DB_*) para o banco de destino (TARGET_DB_*),
+// preservando os IDs. O progresso fica na tabela migration_progress do destino: se a cópia for
+// interrompida, executar o comando de novo continua do último lote confirmado.
+func runMigration(source *sql.DB, batchSize int, reset bool) {
+	target, targetDriver, err := database.Open("TARGET_")
+	if err != nil {
+		log.Fatalf("Erro ao conectar ao banco de destino: %v", err)
+	}
+	defer target.Close()
+
+	src := dbHandle{source, database.DriverName}
+	dst := dbHandle{target, targetDriver}
+	log.Printf("Migração: %s -> %s (lotes de %d linhas).", src.driver, dst.driver, batchSize)
+
+	if err := checkOrphans(src); err != nil {
+		log.Fatalf("Migração abortada: %v", err)
+	}
+
+	createTables(dst.db, dst.driver)
+	if err := ensureProgressTable(dst, reset); err != nil {
+		log.Fatalf("Erro ao preparar a tabela de progresso: %v", err)
+	}
+
+	for _, table := range backup.AppTables {
+		if err := copyTable(src, dst, table, batchSize); err != nil {
+			log.Fatalf("Erro ao copiar '%s': %v (execute novamente para continuar de onde parou)", table.Name, err)
+		}
+	}
+
+	if dst.driver == "postgres" {
+		for _, table := range backup.AppTables {
+			if !table.Serial {
+				continue
+			}
+			query := fmt.Sprintf("SELECT setval(pg_get_serial_sequence('%s', 'id'), MAX(id)) FROM %s HAVING MAX(id) IS NOT NULL", table.Name, table.Name)
+			if _, err := dst.db.Exec(query); err != nil {
+				log.Fatalf("Erro ao ajustar a sequência de '%s': %v", table.Name, err)
+			}
+		}
+		log.Println("Sequências do PostgreSQL ajustadas.")
+	}
+
+	failed := false
+	for _, table := range backup.AppTables {
+		if err := verifyTable(src, dst, table); err != nil {
+			log.Printf("   ERRO de verificação em '%s': %v", table.Name, err)
+			failed = true
+		}
+	}
+	if failed {
+		log.Fatal("Migração concluída com divergências. Use -migrate-reset para copiar novamente.")
+	}
+	log.Println("Migração concluída e verificada: contagens e checksums conferem em todas as tabelas.")
+}
+
+// checkOrphans conta, na origem, as linhas que apontam para registros inexistentes.
+func checkOrphans(src dbHandle) error {
+	var problems []string
+	for _, fk := range migrationForeignKeys {
+		query := fmt.Sprintf("SELECT COUNT(*) FROM %s c WHERE c.%s IS NOT NULL AND NOT EXISTS (SELECT 1 FROM %s p WHERE p.id = c.%s)", fk.table, fk.column, fk.parent, fk.column)
+		var count int
+		if err := src.db.QueryRow(query).Scan(&count); err != nil {
+			return fmt.Errorf("erro ao verificar %s.%s: %w", fk.table, fk.column, err)
+		}
+		if count > 0 {
+			problems = append(problems, fmt.Sprintf("%d linhas em %s.%s sem %s correspondente", count, fk.table, fk.column, fk.parent))
+		}
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("a origem tem referências quebradas que o destino recusaria:\n  - %s\nCorrija-as (ex: apague as linhas órfãs) e execute novamente", strings.Join(problems, "\n  - "))
+	}
+	return nil
+}
+
+func ensureProgressTable(dst dbHandle, reset bool) error {
+	ddl := "CREATE TABLE IF NOT EXISTS migration_progress (table_name TEXT PRIMARY KEY, last_key TEXT, rows_copied BIGINT NOT NULL DEFAULT 0, completed BOOLEAN NOT NULL DEFAULT FALSE)"
+	if _, err := dst.db.Exec(ddl); err != nil {
+		return err
+	}
+	if reset {
+		_, err := dst.db.Exec("DELETE FROM migration_progress")
+		return err
+	}
+	return nil
+}
+
+// columnsOf lista as colunas da tabela na ordem do banco.
+func columnsOf(h dbHandle, table string) ([]string, error) {
+	rows, err := h.db.Query(fmt.Sprintf("SELECT * FROM %s LIMIT 0", table))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return rows.Columns()
+}
+
+// commonColumns retorna as colunas da origem, exigindo que todas existam no destino.
+func commonColumns(src, dst dbHandle, table string) ([]string, error) {
+	srcCols, err := columnsOf(src, table)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao ler colunas na origem: %w", err)
+	}
+	dstCols, err := columnsOf(dst, table)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao ler colunas no destino: %w", err)
+	}
+	inTarget := make(map[string]bool, len(dstCols))
+	for _, c := range dstCols {
+		inTarget[strings.ToLower(c)] = true
+	}
+	for _, c := range srcCols {
+		if !inTarget[strings.ToLower(c)] {
+			return nil, fmt.Errorf("coluna '%s' não existe no destino", c)
+		}
+	}
+	return srcCols, nil
+}
+
+// copyTable copia a tabela em lotes ordenados pela chave primária. Cada lote e o registro do
+// progresso são gravados na mesma transação do destino.
+func copyTable(src, dst dbHandle, table backup.Table, batchSize int) error {
+	var lastKeyJSON sql.NullString
+	var copied int64
+	var completed bool
+	err := dst.db.QueryRow(dst.rebind("SELECT last_key, rows_copied, completed FROM migration_progress WHERE table_name = ?"), table.Name).Scan(&lastKeyJSON, &copied, &completed)
+	if err != nil && err != sql.ErrNoRows {
+		return err
+	}
+	if completed {
+		log.Printf("   %s: já copiada (%d linhas).", table.Name, copied)
+		return nil
+	}
+	var lastKey []interface{}
+	if lastKeyJSON.Valid {
+		if err := json.Unmarshal([]byte(lastKeyJSON.String), &lastKey); err != nil {
+			return fmt.Errorf("progresso inválido: %w", err)
+		}
+		log.Printf("   %s: retomando após %d linhas.", table.Name, copied)
+	}
+
+	columns, err := commonColumns(src, dst, table.Name)
+	if err != nil {
+		return err
+	}
+	keyIndex := make([]int, len(table.Keys))
+	for i, key := range table.Keys {
+		keyIndex[i] = -1
+		for j, col := range columns {
+			if strings.EqualFold(col, key) {
+				keyIndex[i] = j
+			}
+		}
+		if keyIndex[i] < 0 {
+			return fmt.Errorf("chave '%s' não encontrada", key)
+		}
+	}
+
+	keyList := strings.Join(table.Keys, ", ")
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(columns)), ", ")
+	insert := fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)", table.Name, strings.Join(columns, ", "), placeholders)
+	if dst.driver == "postgres" {
+		insert += " ON CONFLICT DO NOTHING"
+	} else {
+		insert = "INSERT OR IGNORE" + strings.TrimPrefix(insert, "INSERT")
+	}
+
+	for {
+		query := fmt.Sprintf("SELECT %s FROM %s", strings.Join(columns, ", "), table.Name)
+		if lastKey != nil {
+			query += fmt.Sprintf(" WHERE (%s) > (%s)", keyList, strings.TrimSuffix(strings.Repeat("?, ", len(lastKey)), ", "))
+		}
+		query += fmt.Sprintf(" ORDER BY %s LIMIT %d", keyList, batchSize)
+
+		batch, err := readBatch(src, query, lastKey, len(columns))
+		if err != nil {
+			return fmt.Errorf("erro ao ler da origem: %w", err)
+		}
+
+		tx, err := dst.db.Begin()
+		if err != nil {
+			return err
+		}
+		for _, row := range batch {
+			if _, err := tx.Exec(dst.rebind(insert), row...); err != nil {
+				tx.Rollback()
+				return fmt.Errorf("erro ao inserir a linha com chave %v: %w", keyValues(row, keyIndex), err)
+			}
+		}
+		if len(batch) > 0 {
+			lastKey = keyValues(batch[len(batch)-1], keyIndex)
+		}
+		copied += int64(len(batch))
+		done := len(batch) < batchSize
+		if err := saveProgress(tx, dst, table.Name, lastKey, copied, done); err != nil {
+			tx.Rollback()
+			return err
+		}
+		if err := tx.Commit(); err != nil {
+			return err
+		}
+		if done {
+			log.Printf("   %s: %d linhas copiadas.", table.Name, copied)
+			return nil
+		}
+	}
+}
+
+// readBatch lê um lote da origem já no formato gravado pelo destino.
+func readBatch(src dbHandle, query string, args []interface{}, width int) ([][]interface{}, error) {
+	rows, err := src.db.Query(src.rebind(query), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	dateCols, err := dateColumns(rows)
+	if err != nil {
+		return nil, err
+	}
+	var batch [][]interface{}
+	for rows.Next() {
+		values := make([]interface{}, width)
+		pointers := make([]interface{}, width)
+		for i := range values {
+			pointers[i] = &values[i]
+		}
+		if err := rows.Scan(pointers...); err != nil {
+			return nil, err
+		}
+		for i, v := range values {
+			// NUMERIC do PostgreSQL chega como []byte; como texto, os dois drivers o aceitam.
+			if b, ok := v.([]byte); ok {
+				values[i] = string(b)
+			}
+			// DATE do PostgreSQL chega como time.Time, que o go-sqlite3 gravaria como
+			// "2024-01-15 00:00:00+00:00"; a aplicação compara essas colunas como AAAA-MM-DD.
+			if t, ok := v.(time.Time); ok && dateCols[i] {
+				values[i] = t.Format("2006-01-02")
+			}
+		}
+		batch = append(batch, values)
+	}
+	return batch, rows.Err()
+}
+
+// dateColumns indica quais colunas do resultado são do tipo DATE (datas puras, sem horário).
+func dateColumns(rows *sql.Rows) ([]bool, error) {
+	types, err := rows.ColumnTypes()
+	if err != nil {
+		return nil, err
+	}
+	isDate := make([]bool, len(types))
+	for i, t := range types {
+		isDate[i] = strings.EqualFold(t.DatabaseTypeName(), "DATE")
+	}
+	return isDate, nil
+}
+
+func keyValues(row []interface{}, keyIndex []int) []interface{} {
+	key := make([]interface{}, len(keyIndex))
+	for i, idx := range keyIndex {
+		key[i] = row[idx]
+	}
+	return key
+}
+
+func saveProgress(tx *sql.Tx, dst dbHandle, table string, lastKey []interface{}, copied int64, completed bool) error {
+	var keyJSON interface{}
+	if lastKey != nil {
+		b, err := json.Marshal(lastKey)
+		if err != nil {
+			return err
+		}
+		keyJSON = string(b)
+	}
+	if _, err := tx.Exec(dst.rebind("DELETE FROM migration_progress WHERE table_name = ?"), table); err != nil {
+		return err
+	}
+	_, err := tx.Exec(dst.rebind("INSERT INTO migration_progress (table_name, last_key, rows_copied, completed) VALUES (?, ?, ?, ?)"), table, keyJSON, copied, completed)
+	return err
+}
+
+// verifyTable compara a contagem de linhas e um checksum independente de ordem (XOR do SHA-256
+// de cada linha normalizada) entre origem e destino.
+func verifyTable(src, dst dbHandle, table backup.Table) error {
+	columns, err := commonColumns(src, dst, table.Name)
+	if err != nil {
+		return err
+	}
+	srcCount, srcSum, err := tableChecksum(src, table.Name, columns)
+	if err != nil {
+		return fmt.Errorf("origem: %w", err)
+	}
+	dstCount, dstSum, err := tableChecksum(dst, table.Name, columns)
+	if err != nil {
+		return fmt.Errorf("destino: %w", err)
+	}
+	if srcCount != dstCount {
+		return fmt.Errorf("%d linhas na origem e %d no destino", srcCount, dstCount)
+	}
+	if srcSum != dstSum {
+		return fmt.Errorf("checksums diferentes (%x na origem, %x no destino)", srcSum[:6], dstSum[:6])
+	}
+	log.Printf("   %s: %d linhas conferidas (checksum %x).", table.Name, srcCount, srcSum[:6])
+	return nil
+}
+
+func tableChecksum(h dbHandle, table string, columns []string) (int64, [sha256.Size]byte, error) {
+	var sum [sha256.Size]byte
+	rows, err := h.db.Query(fmt.Sprintf("SELECT %s FROM %s", strings.Join(columns, ", "), table))
+	if err != nil {
+		return 0, sum, err
+	}
+	defer rows.Close()
+	dateCols, err := dateColumns(rows)
+	if err != nil {
+		return 0, sum, err
+	}
+
+	var count int64
+	values := make([]interface{}, len(columns))
+	pointers := make([]interface{}, len(columns))
+	for i := range values {
+		pointers[i] = &values[i]
+	}
+	for rows.Next() {
+		if err := rows.Scan(pointers...); err != nil {
+			return 0, sum, err
+		}
+		parts := make([]string, len(values))
+		for i, v := range values {
+			parts[i] = normalizeValue(v, dateCols[i])
+		}
+		rowSum := sha256.Sum256([]byte(strings.Join(parts, "\x1f")))
+		for i := range sum {
+			sum[i] ^= rowSum[i]
+		}
+		count++
+	}
+	return count, sum, rows.Err()
+}
+
+// normalizeValue representa o valor de forma igual nos dois drivers: booleanos como 1/0, números
+// sem zeros à direita (NUMERIC vem como texto no PostgreSQL), colunas DATE como AAAA-MM-DD e
+// instantes em UTC com precisão de microssegundos (a do PostgreSQL). Texto é comparado como está
+// gravado: uma data guardada no SQLite como "2024-01-15 00:00:00+00:00" não confere com 2024-01-15.
+func normalizeValue(v interface{}, isDate bool) string {
+	switch val := v.(type) {
+	case nil:
+		return "\x00"
+	case bool:
+		if val {
+			return "1"
+		}
+		return "0"
+	case int64:
+		return strconv.FormatInt(val, 10)
+	case float64:
+		return strconv.FormatFloat(val, 'f', -1, 64)
+	case time.Time:
+		if isDate {
+			return val.Format("2006-01-02")
+		}
+		return val.UTC().Truncate(time.Microsecond).Format(time.RFC3339Nano)
+	case []byte:
+		return normalizeValue(string(val), isDate)
+	case string:
+		if f, err := strconv.ParseFloat(val, 64); err == nil {
+			return strconv.FormatFloat(f, 'f', -1, 64)
+		}
+		return val
+	}
+	return fmt.Sprint(v)
+}
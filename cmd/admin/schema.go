@@ -4,14 +4,14 @@ import (
 	"database/sql"
 	"fmt"
 	"log"
-	"minhas_economias/database"
 )
 
-func createTables(db *sql.DB) {
+// createTables cria ou migra o schema no banco db, que usa o driver informado ("postgres" ou "sqlite3").
+func createTables(db *sql.DB, driver string) {
 	log.Println("Verificando/Criando schema do banco de dados...")
-	var createUsers, createMov, createContas, createProfile, createInvNac, createInvInt, createChat string
+	var createUsers, createMov, createContas, createProfile, createInvNac, createInvInt, createChat, createResetTokens, createSessions, createHouseholds, createHouseholdMembers, createAuditLogs string
 
-	if database.DriverName == "postgres" {
+	if driver == "postgres" {
 		createUsers = `CREATE TABLE IF NOT EXISTS users (id BIGSERIAL PRIMARY KEY, email TEXT UNIQUE NOT NULL, password_hash TEXT NOT NULL, is_admin BOOLEAN DEFAULT FALSE, dark_mode_enabled BOOLEAN DEFAULT FALSE);`
 		createMov = fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (id SERIAL PRIMARY KEY, user_id BIGINT NOT NULL, data_ocorrencia DATE NOT NULL, descricao TEXT, valor NUMERIC(10, 2), categoria TEXT, conta TEXT, consolidado BOOLEAN DEFAULT FALSE, FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE);`, tableName)
 		createContas = `CREATE TABLE IF NOT EXISTS contas (user_id BIGINT NOT NULL, nome TEXT NOT NULL, saldo_inicial NUMERIC(10, 2) NOT NULL DEFAULT 0, PRIMARY KEY (user_id, nome), FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE);`
@@ -19,6 +19,11 @@ func createTables(db *sql.DB) {
 		createInvNac = `CREATE TABLE IF NOT EXISTS investimentos_nacionais (user_id BIGINT NOT NULL, ticker TEXT NOT NULL, tipo TEXT, quantidade INTEGER NOT NULL, PRIMARY KEY (user_id, ticker), FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE);`
 		createInvInt = `CREATE TABLE IF NOT EXISTS investimentos_internacionais (user_id BIGINT NOT NULL, ticker TEXT NOT NULL, descricao TEXT, quantidade REAL NOT NULL, moeda TEXT, PRIMARY KEY (user_id, ticker), FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE);`
 		createChat = `CREATE TABLE IF NOT EXISTS chat_history (id BIGSERIAL PRIMARY KEY, user_id BIGINT NOT NULL, role TEXT NOT NULL, content TEXT NOT NULL, created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP, FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE);`
+		createResetTokens = `CREATE TABLE IF NOT EXISTS password_reset_tokens (id BIGSERIAL PRIMARY KEY, user_id BIGINT NOT NULL, token_hash TEXT UNIQUE NOT NULL, expires_at TIMESTAMPTZ NOT NULL, used_at TIMESTAMPTZ, created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP, FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE);`
+		createSessions = `CREATE TABLE IF NOT EXISTS user_sessions (id TEXT PRIMARY KEY, user_id BIGINT, data TEXT NOT NULL, user_agent TEXT, ip_address TEXT, created_at TIMESTAMPTZ NOT NULL, last_seen_at TIMESTAMPTZ NOT NULL, expires_at TIMESTAMPTZ NOT NULL, FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE);`
+		createHouseholds = `CREATE TABLE IF NOT EXISTS households (id BIGSERIAL PRIMARY KEY, name TEXT NOT NULL, owner_id BIGINT NOT NULL, created_at TIMESTAMPTZ NOT NULL, FOREIGN KEY(owner_id) REFERENCES users(id) ON DELETE CASCADE);`
+		createHouseholdMembers = `CREATE TABLE IF NOT EXISTS household_members (household_id BIGINT NOT NULL, user_id BIGINT NOT NULL, role TEXT NOT NULL, created_at TIMESTAMPTZ NOT NULL, PRIMARY KEY (household_id, user_id), FOREIGN KEY(household_id) REFERENCES households(id) ON DELETE CASCADE, FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE);`
+		createAuditLogs = `CREATE TABLE IF NOT EXISTS audit_logs (id BIGSERIAL PRIMARY KEY, user_id BIGINT, user_email TEXT, action TEXT NOT NULL, path TEXT, status INTEGER, latency_ms BIGINT, entity_type TEXT, entity_id TEXT, changes TEXT, created_at TIMESTAMPTZ NOT NULL);`
 	} else {
 		createUsers = `CREATE TABLE IF NOT EXISTS users (id INTEGER PRIMARY KEY, email TEXT UNIQUE NOT NULL, password_hash TEXT NOT NULL, is_admin BOOLEAN DEFAULT FALSE, dark_mode_enabled BOOLEAN DEFAULT 0);`
 		createMov = fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (id INTEGER PRIMARY KEY AUTOINCREMENT, user_id INTEGER NOT NULL, data_ocorrencia TEXT NOT NULL, descricao TEXT, valor REAL, categoria TEXT, conta TEXT, consolidado BOOLEAN DEFAULT FALSE, FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE);`, tableName)
@@ -27,6 +32,11 @@ func createTables(db *sql.DB) {
 		createInvNac = `CREATE TABLE IF NOT EXISTS investimentos_nacionais (user_id INTEGER NOT NULL, ticker TEXT NOT NULL, tipo TEXT, quantidade INTEGER NOT NULL, PRIMARY KEY (user_id, ticker), FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE);`
 		createInvInt = `CREATE TABLE IF NOT EXISTS investimentos_internacionais (user_id INTEGER NOT NULL, ticker TEXT NOT NULL, descricao TEXT, quantidade REAL NOT NULL, moeda TEXT, PRIMARY KEY (user_id, ticker), FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE);`
 		createChat = `CREATE TABLE IF NOT EXISTS chat_history (id INTEGER PRIMARY KEY AUTOINCREMENT, user_id INTEGER NOT NULL, role TEXT NOT NULL, content TEXT NOT NULL, created_at DATETIME DEFAULT CURRENT_TIMESTAMP, FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE);`
+		createResetTokens = `CREATE TABLE IF NOT EXISTS password_reset_tokens (id INTEGER PRIMARY KEY AUTOINCREMENT, user_id INTEGER NOT NULL, token_hash TEXT UNIQUE NOT NULL, expires_at DATETIME NOT NULL, used_at DATETIME, created_at DATETIME DEFAULT CURRENT_TIMESTAMP, FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE);`
+		createSessions = `CREATE TABLE IF NOT EXISTS user_sessions (id TEXT PRIMARY KEY, user_id INTEGER, data TEXT NOT NULL, user_agent TEXT, ip_address TEXT, created_at DATETIME NOT NULL, last_seen_at DATETIME NOT NULL, expires_at DATETIME NOT NULL, FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE);`
+		createHouseholds = `CREATE TABLE IF NOT EXISTS households (id INTEGER PRIMARY KEY AUTOINCREMENT, name TEXT NOT NULL, owner_id INTEGER NOT NULL, created_at DATETIME NOT NULL, FOREIGN KEY(owner_id) REFERENCES users(id) ON DELETE CASCADE);`
+		createHouseholdMembers = `CREATE TABLE IF NOT EXISTS household_members (household_id INTEGER NOT NULL, user_id INTEGER NOT NULL, role TEXT NOT NULL, created_at DATETIME NOT NULL, PRIMARY KEY (household_id, user_id), FOREIGN KEY(household_id) REFERENCES households(id) ON DELETE CASCADE, FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE);`
+		createAuditLogs = `CREATE TABLE IF NOT EXISTS audit_logs (id INTEGER PRIMARY KEY AUTOINCREMENT, user_id INTEGER, user_email TEXT, action TEXT NOT NULL, path TEXT, status INTEGER, latency_ms INTEGER, entity_type TEXT, entity_id TEXT, changes TEXT, created_at DATETIME NOT NULL);`
 	}
 
 	execQuery(db, createUsers, "users")
@@ -36,10 +46,105 @@ func createTables(db *sql.DB) {
 	execQuery(db, createInvNac, "investimentos_nacionais")
 	execQuery(db, createInvInt, "investimentos_internacionais")
 	execQuery(db, createChat, "chat_history")
+	execQuery(db, createResetTokens, "password_reset_tokens")
+	execQuery(db, createSessions, "user_sessions")
+	execQuery(db, createHouseholds, "households")
+	execQuery(db, createHouseholdMembers, "household_members")
+	execQuery(db, createAuditLogs, "audit_logs")
+	addColumnIfMissing(db, "users", "disabled", "BOOLEAN DEFAULT FALSE")
+
+	// Lares compartilhados: colunas adicionadas às tabelas existentes (bancos criados antes dos lares também são migrados).
+	idType := "INTEGER"
+	if driver == "postgres" {
+		idType = "BIGINT"
+	}
+	addColumnIfMissing(db, tableName, "household_id", idType+" REFERENCES households(id) ON DELETE CASCADE")
+	addColumnIfMissing(db, tableName, "created_by", idType+" REFERENCES users(id) ON DELETE SET NULL")
+	addColumnIfMissing(db, "contas", "household_id", idType+" REFERENCES households(id) ON DELETE CASCADE")
+	execQuery(db, fmt.Sprintf("CREATE INDEX IF NOT EXISTS idx_%s_household ON %s (household_id);", tableName, tableName), "idx_"+tableName+"_household")
+	// As transações de um lar pertencem ao proprietário do lar (o autor fica em created_by), para que
+	// o ON DELETE CASCADE de user_id não apague dados compartilhados quando um membro exclui a conta.
+	// Migra as linhas lançadas por membros antes dessa regra.
+	execQuery(db, fmt.Sprintf(`UPDATE %[1]s SET user_id = (SELECT h.owner_id FROM households h WHERE h.id = %[1]s.household_id)
+		WHERE household_id IS NOT NULL AND user_id <> (SELECT h.owner_id FROM households h WHERE h.id = %[1]s.household_id);`, tableName), tableName+".user_id (lares)")
+
+	// Lixeira: movimentações excluídas ficam com deleted_at preenchido até a limpeza automática.
+	timestampType := "DATETIME"
+	autoIDType := "INTEGER PRIMARY KEY AUTOINCREMENT"
+	if driver == "postgres" {
+		timestampType = "TIMESTAMPTZ"
+		autoIDType = "BIGSERIAL PRIMARY KEY"
+	}
+	addColumnIfMissing(db, tableName, "deleted_at", timestampType)
+	execQuery(db, fmt.Sprintf("CREATE INDEX IF NOT EXISTS idx_%s_deleted_at ON %s (deleted_at);", tableName, tableName), "idx_"+tableName+"_deleted_at")
+
+	// Auditoria: entidade alterada e diff antes/depois (changes, em JSON).
+	addColumnIfMissing(db, "audit_logs", "user_id", idType)
+	addColumnIfMissing(db, "audit_logs", "entity_type", "TEXT")
+	addColumnIfMissing(db, "audit_logs", "entity_id", "TEXT")
+	addColumnIfMissing(db, "audit_logs", "changes", "TEXT")
+	execQuery(db, "CREATE INDEX IF NOT EXISTS idx_audit_logs_created_at ON audit_logs (created_at);", "idx_audit_logs_created_at")
+	execQuery(db, "CREATE INDEX IF NOT EXISTS idx_audit_logs_user ON audit_logs (user_id, created_at);", "idx_audit_logs_user")
+	execQuery(db, "CREATE INDEX IF NOT EXISTS idx_audit_logs_entity ON audit_logs (entity_type, entity_id);", "idx_audit_logs_entity")
+
+	// Anexos: metadados dos arquivos guardados no backend de armazenamento (STORAGE_DRIVER).
+	// Sem chave estrangeira para movimentacoes: ao apagar a movimentação a linha fica órfã
+	// e a limpeza remove também o arquivo no armazenamento.
+	execQuery(db, fmt.Sprintf(`CREATE TABLE IF NOT EXISTS anexos (id %s, movimentacao_id %s NOT NULL, user_id %s NOT NULL, nome_arquivo TEXT NOT NULL, content_type TEXT NOT NULL, tamanho %s NOT NULL, storage_key TEXT NOT NULL, thumbnail_key TEXT, created_at %s NOT NULL);`, autoIDType, idType, idType, idType, timestampType), "anexos")
+	execQuery(db, "CREATE INDEX IF NOT EXISTS idx_anexos_movimentacao ON anexos (movimentacao_id);", "idx_anexos_movimentacao")
+	execQuery(db, "CREATE INDEX IF NOT EXISTS idx_anexos_user ON anexos (user_id);", "idx_anexos_user")
+
+	// Histórico de preços dos ativos (e do dólar, ticker USDBRL), gravado a cada busca de cotações.
+	dateType, priceType := "TEXT", "REAL"
+	if driver == "postgres" {
+		dateType, priceType = "DATE", "NUMERIC(18, 6)"
+	}
+	execQuery(db, fmt.Sprintf(`CREATE TABLE IF NOT EXISTS historico_precos (ticker TEXT NOT NULL, data %s NOT NULL, fechamento %s NOT NULL, moeda TEXT NOT NULL, fonte TEXT, PRIMARY KEY (ticker, data));`, dateType, priceType), "historico_precos")
+
+	// Livro de compras (C) e vendas (V) de ações e FIIs; a quantidade em investimentos_nacionais
+	// é derivada dele e o preço médio é calculado a partir das operações.
+	execQuery(db, fmt.Sprintf(`CREATE TABLE IF NOT EXISTS operacoes_investimentos (id %s, user_id %s NOT NULL, ticker TEXT NOT NULL, tipo_ativo TEXT NOT NULL, tipo TEXT NOT NULL, data %s NOT NULL, quantidade INTEGER NOT NULL, preco %s NOT NULL, taxas %s NOT NULL DEFAULT 0, corretora TEXT, created_at %s NOT NULL, FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE);`, autoIDType, idType, dateType, priceType, priceType, timestampType), "operacoes_investimentos")
+	execQuery(db, "CREATE INDEX IF NOT EXISTS idx_operacoes_investimentos_user ON operacoes_investimentos (user_id, ticker, data);", "idx_operacoes_investimentos_user")
+
+	// Proventos (dividendos, JCP e rendimentos de FIIs). movimentacao_id aponta para a entrada
+	// criada no livro-caixa, sem chave estrangeira: a movimentação pode ir para a lixeira.
+	execQuery(db, fmt.Sprintf(`CREATE TABLE IF NOT EXISTS proventos (id %s, user_id %s NOT NULL, ticker TEXT NOT NULL, tipo_ativo TEXT NOT NULL, tipo TEXT NOT NULL, data_ex %s NOT NULL, data_pagamento %s NOT NULL, valor_por_cota %s NOT NULL, quantidade INTEGER NOT NULL, imposto_retido %s NOT NULL DEFAULT 0, movimentacao_id %s, created_at %s NOT NULL, FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE);`, autoIDType, idType, dateType, dateType, priceType, priceType, idType, timestampType), "proventos")
+	execQuery(db, "CREATE INDEX IF NOT EXISTS idx_proventos_user ON proventos (user_id, data_pagamento);", "idx_proventos_user")
+
+	// Renda fixa: títulos avaliados na curva com as séries de índices (CDI, SELIC e IPCA)
+	// importadas do SGS do Banco Central (-import-indices). A mesma tabela guarda os
+	// benchmarks de desempenho (Ibovespa, IFIX, S&P 500 e dólar PTAX).
+	indiceType := "REAL"
+	if driver == "postgres" {
+		indiceType = "NUMERIC(18, 8)"
+	}
+	execQuery(db, fmt.Sprintf(`CREATE TABLE IF NOT EXISTS indices_economicos (serie TEXT NOT NULL, data %s NOT NULL, valor %s NOT NULL, PRIMARY KEY (serie, data));`, dateType, indiceType), "indices_economicos")
+	execQuery(db, fmt.Sprintf(`CREATE TABLE IF NOT EXISTS renda_fixa (id %s, user_id %s NOT NULL, nome TEXT NOT NULL, tipo TEXT NOT NULL, emissor TEXT, indexador TEXT NOT NULL, taxa %s NOT NULL, valor_aplicado %s NOT NULL, data_aplicacao %s NOT NULL, vencimento %s NOT NULL, liquidez TEXT NOT NULL, created_at %s NOT NULL, FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE);`, autoIDType, idType, priceType, priceType, dateType, dateType, timestampType), "renda_fixa")
+	execQuery(db, "CREATE INDEX IF NOT EXISTS idx_renda_fixa_user ON renda_fixa (user_id, vencimento);", "idx_renda_fixa_user")
+
+	// Criptoativos: a quantidade é um decimal exato de até 18 casas (texto no SQLite).
+	quantidadeCriptoType := "TEXT"
+	if driver == "postgres" {
+		quantidadeCriptoType = "NUMERIC(38, 18)"
+	}
+	execQuery(db, fmt.Sprintf(`CREATE TABLE IF NOT EXISTS investimentos_cripto (user_id %s NOT NULL, simbolo TEXT NOT NULL, descricao TEXT, quantidade %s NOT NULL, PRIMARY KEY (user_id, simbolo), FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE);`, idType, quantidadeCriptoType), "investimentos_cripto")
+
+	// Alocação-alvo: percentual de cada classe na carteira (ticker vazio) e de cada ativo
+	// dentro da sua classe.
+	execQuery(db, fmt.Sprintf(`CREATE TABLE IF NOT EXISTS alocacao_alvo (user_id %s NOT NULL, classe TEXT NOT NULL, ticker TEXT NOT NULL DEFAULT '', percentual %s NOT NULL, PRIMARY KEY (user_id, classe, ticker), FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE);`, idType, priceType), "alocacao_alvo")
 	
 	log.Println("Schema verificado/criado com sucesso.")
 }
 
+// addColumnIfMissing adiciona a coluna à tabela caso ela ainda não exista.
+// A verificação por SELECT funciona tanto no PostgreSQL quanto no SQLite (que não tem ADD COLUMN IF NOT EXISTS).
+func addColumnIfMissing(db *sql.DB, table, column, definition string) {
+	if _, err := db.Exec(fmt.Sprintf("SELECT %s FROM %s LIMIT 0", column, table)); err == nil {
+		return
+	}
+	execQuery(db, fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s;", table, column, definition), table+"."+column)
+}
+
 func execQuery(db *sql.DB, query, tableName string) {
 	if _, err := db.Exec(query); err != nil {
 		log.Fatalf("Erro crítico ao criar tabela '%s': %v", tableName, err)
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"bufio"
+	"database/sql"
+	"io"
+	"log"
+	"minhas_economias/backup"
+	"minhas_economias/database"
+	"os"
+	"strings"
+)
+
+// runSnapshot gera um backup do banco inteiro com a mesma configuração (BACKUP_*) usada pelo
+// agendador da API, incluindo a rotação dos arquivos antigos.
+func runSnapshot(db *sql.DB) {
+	cfg := backup.SnapshotConfigFromEnv()
+	path, err := backup.RunSnapshot(db, database.DriverName, cfg)
+	if err != nil {
+		log.Fatalf("Erro ao gerar o backup do banco: %v", err)
+	}
+	log.Printf("Backup do banco gravado em %s.", path)
+}
+
+// runDecryptSnapshot decifra um snapshot .enc com BACKUP_PASSPHRASE, gravando-o ao lado sem a extensão .enc.
+func runDecryptSnapshot(path string) {
+	if !strings.HasSuffix(path, ".enc") {
+		log.Fatalf("O arquivo '%s' não tem a extensão .enc.", path)
+	}
+	passphrase := os.Getenv("BACKUP_PASSPHRASE")
+	if passphrase == "" {
+		log.Fatal("Defina BACKUP_PASSPHRASE com a senha usada na geração do backup.")
+	}
+	in, err := os.Open(path)
+	if err != nil {
+		log.Fatalf("Erro ao ler '%s': %v", path, err)
+	}
+	defer in.Close()
+	plain, err := backup.NewDecryptReader(bufio.NewReader(in), passphrase)
+	if err != nil {
+		log.Fatalf("Erro ao decifrar '%s': %v", path, err)
+	}
+	output := strings.TrimSuffix(path, ".enc")
+	out, err := os.OpenFile(output, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600)
+	if err != nil {
+		log.Fatalf("Erro ao gravar '%s': %v", output, err)
+	}
+	if _, err := io.Copy(out, plain); err != nil {
+		out.Close()
+		os.Remove(output)
+		log.Fatalf("Erro ao decifrar '%s': %v", path, err)
+	}
+	if err := out.Close(); err != nil {
+		log.Fatalf("Erro ao gravar '%s': %v", output, err)
+	}
+	log.Printf("Backup decifrado em %s.", output)
+}
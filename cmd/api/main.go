@@ -3,14 +3,18 @@ package main
 import (
 	"log"
 	"minhas_economias/auth"
+	"minhas_economias/backup"
 	"minhas_economias/database"
 	"minhas_economias/handlers"
 	"minhas_economias/investimentos"
 	"minhas_economias/gemini"
+	"minhas_economias/mailer"
+	"minhas_economias/storage"
 	"minhas_economias/middleware"
 
 	"os"
 	"path/filepath"
+	"strings"
 
 	"github.com/joho/godotenv"
 	"github.com/gin-contrib/multitemplate"
@@ -20,6 +24,9 @@ import (
 	"github.com/prometheus/client_golang/prometheus/promhttp" // <-- IMPORTANTE
 )
 
+// standalonePages são renderizadas sem o _layout.html (telas públicas de autenticação).
+var standalonePages = []string{"login.html", "register.html", "forgot_password.html", "reset_password.html"}
+
 func createMyRender() multitemplate.Renderer {
 	r := multitemplate.NewRenderer()
 	pages, err := filepath.Glob("templates/*.html")
@@ -27,18 +34,34 @@ func createMyRender() multitemplate.Renderer {
 		panic(err.Error())
 	}
 	layout := "templates/_layout.html"
+	isStandalone := make(map[string]bool)
+	for _, name := range standalonePages {
+		isStandalone[name] = true
+	}
 	for _, page := range pages {
 		pageName := filepath.Base(page)
-		if pageName == "_layout.html" || pageName == "login.html" || pageName == "register.html" {
+		if pageName == "_layout.html" || isStandalone[pageName] {
 			continue
 		}
 		r.AddFromFiles(pageName, layout, page)
 	}
-	r.AddFromFiles("login.html", "templates/login.html")
-	r.AddFromFiles("register.html", "templates/register.html")
+	for _, name := range standalonePages {
+		r.AddFromFiles(name, "templates/"+name)
+	}
 	return r
 }
 
+// trustedProxiesFromEnv lê TRUSTED_PROXIES (IPs ou CIDRs separados por vírgula).
+func trustedProxiesFromEnv() []string {
+	var proxies []string
+	for _, p := range strings.Split(os.Getenv("TRUSTED_PROXIES"), ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			proxies = append(proxies, p)
+		}
+	}
+	return proxies
+}
+
 func main() {
 	errEnv := godotenv.Load()
 	if errEnv != nil {
@@ -54,6 +77,7 @@ func main() {
 	}
 
 	auth.InitSessionStore()
+	auth.InitLoginLimiter()
 	
 	_, err := database.InitDB()
 	if err != nil {
@@ -61,11 +85,33 @@ func main() {
 	}
 	defer database.CloseDB()
 
+	middleware.StartAuditRetention(middleware.AuditRetentionFromEnv())
+	handlers.StartTrashPurge(handlers.TrashRetentionFromEnv())
+	backup.StartScheduler(database.GetDB(), database.DriverName, backup.SnapshotConfigFromEnv())
+
+	if err := mailer.Init(); err != nil {
+		log.Fatalf("Erro ao configurar o envio de e-mails: %v", err)
+	}
+
+	if err := storage.Init(); err != nil {
+		log.Fatalf("Erro ao configurar o armazenamento de anexos: %v", err)
+	}
+
+	if err := investimentos.InitMarketData(); err != nil {
+		log.Fatalf("Erro ao configurar os dados de mercado: %v", err)
+	}
+	investimentos.StartAtualizacaoPrecos(investimentos.AtualizacaoConfigFromEnv())
+
 	if err := gemini.InitClient(); err != nil {
         log.Printf("AVISO: Não foi possível inicializar o cliente do Gemini AI. A funcionalidade de análise estará indisponível. Erro: %v", err)
     }
 
 	r := gin.Default()
+	// Só os proxies listados podem informar o IP do cliente via X-Forwarded-For
+	// (usado no limite de login e na lista de sessões); sem a variável, nenhum é confiável.
+	if err := r.SetTrustedProxies(trustedProxiesFromEnv()); err != nil {
+		log.Fatalf("TRUSTED_PROXIES inválida: %v", err)
+	}
 	r.Use(middleware.PrometheusMiddleware())
 	r.Use(middleware.AuditLogger())
 	r.HTMLRender = createMyRender()
@@ -75,10 +121,17 @@ func main() {
 	r.GET("/readyz", handlers.ReadinessProbe)
 	r.GET("/metrics", gin.WrapH(promhttp.Handler()))
 
+	// Proteção CSRF para as rotas registradas a partir daqui (arquivos estáticos e probes ficam de fora).
+	r.Use(auth.CSRFProtect())
+
 	r.GET("/login", auth.GetLoginPage)
 	r.POST("/login", auth.PostLogin)
 	r.GET("/register", auth.GetRegisterPage)
 	r.POST("/register", auth.PostRegister)
+	r.GET("/forgot-password", auth.GetForgotPasswordPage)
+	r.POST("/forgot-password", auth.PostForgotPassword)
+	r.GET("/reset-password", auth.GetResetPasswordPage)
+	r.POST("/reset-password", auth.PostResetPassword)
 
 	authorized := r.Group("/")
 	authorized.Use(auth.AuthRequired())
@@ -88,6 +141,7 @@ func main() {
 		authorized.GET("/relatorio", handlers.GetRelatorio)
 		authorized.GET("/sobre", handlers.GetSobrePage)
 		authorized.GET("/configuracoes", handlers.GetConfiguracoesPage)
+		authorized.GET("/configuracoes/backup", handlers.DownloadBackup)
 		authorized.GET("/investimentos", investimentos.GetInvestimentosPage)
 		authorized.POST("/logout", auth.PostLogout)
 
@@ -99,14 +153,38 @@ func main() {
 		authorized.POST("/api/user/settings", handlers.UpdateUserSettings)
 		authorized.POST("/api/user/profile", handlers.UpdateUserProfile)
 		authorized.POST("/api/user/password", handlers.ChangePassword)
+		authorized.POST("/api/user/sessions/logout-others", handlers.LogoutOtherSessions)
+		authorized.GET("/atividade", handlers.GetAtividadePage)
+		authorized.GET("/api/user/activity", handlers.GetAtividadePage)
 		authorized.GET("/api/investimentos/precos", investimentos.GetPrecosInvestimentosAPI)
+		authorized.GET("/api/investimentos/historico/:ticker", investimentos.GetHistoricoPrecosAPI)
+		authorized.GET("/api/investimentos/avaliacao", investimentos.GetAvaliacaoCarteiraAPI)
+		authorized.GET("/api/investimentos/desempenho", investimentos.GetDesempenhoAPI)
 		authorized.GET("/api/saldos", handlers.GetSaldosAPI) // <-- NOVA ROTA
 
-		// Movimentações
-		authorized.POST("/movimentacoes", handlers.AddMovimentacao)
-		authorized.POST("/movimentacoes/transferencia", handlers.AddTransferencia) // <-- NOVA ROTA
-		authorized.DELETE("/movimentacoes/:id", handlers.DeleteMovimentacao)
-		authorized.POST("/movimentacoes/update/:id", handlers.UpdateMovimentacao)
+		// Lares compartilhados
+		authorized.POST("/workspace", auth.PostSwitchWorkspace)
+		authorized.POST("/api/households", handlers.CreateHousehold)
+		authorized.DELETE("/api/households/:id", handlers.DeleteHousehold)
+		authorized.POST("/api/households/:id/members", handlers.AddHouseholdMember)
+		authorized.DELETE("/api/households/:id/members/:userID", handlers.RemoveHouseholdMember)
+
+		// Movimentações (leitores de um lar compartilhado não podem alterar)
+		authorized.POST("/movimentacoes", auth.RequireLedgerWrite(), handlers.AddMovimentacao)
+		authorized.POST("/movimentacoes/transferencia", auth.RequireLedgerWrite(), handlers.AddTransferencia) // <-- NOVA ROTA
+		authorized.POST("/movimentacoes/bulk", auth.RequireLedgerWrite(), handlers.BulkUpdateMovimentacoes)
+		authorized.DELETE("/movimentacoes/:id", auth.RequireLedgerWrite(), handlers.DeleteMovimentacao)
+		authorized.POST("/movimentacoes/update/:id", auth.RequireLedgerWrite(), handlers.UpdateMovimentacao)
+		authorized.POST("/movimentacoes/:id/restore", auth.RequireLedgerWrite(), handlers.RestoreMovimentacao)
+		authorized.GET("/movimentacoes/:id/anexos", handlers.ListAnexosAPI)
+		authorized.POST("/movimentacoes/:id/anexos", auth.RequireLedgerWrite(), handlers.UploadAnexo)
+		authorized.GET("/anexos/:id", handlers.GetAnexo)
+		authorized.GET("/anexos/:id/thumbnail", handlers.GetAnexoThumbnail)
+		authorized.DELETE("/anexos/:id", auth.RequireLedgerWrite(), handlers.DeleteAnexo)
+		authorized.GET("/lixeira", handlers.GetLixeiraPage)
+		authorized.GET("/api/lixeira", handlers.GetLixeiraPage)
+		authorized.DELETE("/lixeira", auth.RequireLedgerWrite(), handlers.EmptyLixeira)
+		authorized.DELETE("/lixeira/:id", auth.RequireLedgerWrite(), handlers.PurgeMovimentacao)
 		authorized.GET("/relatorio/transactions", handlers.GetTransactionsByCategory)
 		authorized.POST("/relatorio/pdf", handlers.DownloadRelatorioPDF)
 		authorized.GET("/export/csv", handlers.ExportTransactionsCSV)
@@ -115,9 +193,43 @@ func main() {
 		authorized.POST("/investimentos/nacional", investimentos.AddAtivoNacional)
 		authorized.POST("/investimentos/nacional/:ticker", investimentos.UpdateAtivoNacional)
 		authorized.DELETE("/investimentos/nacional/:ticker", investimentos.DeleteAtivoNacional)
+		authorized.POST("/investimentos/importar", investimentos.ImportarCarteira)
+		authorized.GET("/api/investimentos/operacoes", investimentos.GetOperacoesAPI)
+		authorized.POST("/investimentos/operacoes", investimentos.AddOperacao)
+		authorized.POST("/investimentos/operacoes/:id", investimentos.UpdateOperacao)
+		authorized.DELETE("/investimentos/operacoes/:id", investimentos.DeleteOperacao)
+		authorized.GET("/api/investimentos/proventos", investimentos.GetProventosAPI)
+		authorized.POST("/investimentos/proventos", investimentos.AddProvento)
+		authorized.DELETE("/investimentos/proventos/:id", investimentos.DeleteProvento)
+		authorized.GET("/api/investimentos/renda-fixa", investimentos.GetRendaFixaAPI)
+		authorized.POST("/investimentos/renda-fixa", investimentos.AddRendaFixa)
+		authorized.POST("/investimentos/renda-fixa/:id", investimentos.UpdateRendaFixa)
+		authorized.DELETE("/investimentos/renda-fixa/:id", investimentos.DeleteRendaFixa)
+		authorized.GET("/api/investimentos/alocacao", investimentos.GetAlocacaoAPI)
+		authorized.POST("/investimentos/alocacao", investimentos.SaveAlocacao)
+		authorized.GET("/api/investimentos/ir", investimentos.GetImpostoRendaAPI)
+		authorized.GET("/investimentos/ir/pdf", investimentos.DownloadImpostoRendaPDF)
 		authorized.POST("/investimentos/internacional", investimentos.AddAtivoInternacional)
 		authorized.POST("/investimentos/internacional/:ticker", investimentos.UpdateAtivoInternacional)
 		authorized.DELETE("/investimentos/internacional/:ticker", investimentos.DeleteAtivoInternacional)
+		authorized.POST("/investimentos/cripto", investimentos.AddAtivoCripto)
+		authorized.POST("/investimentos/cripto/:simbolo", investimentos.UpdateAtivoCripto)
+		authorized.DELETE("/investimentos/cripto/:simbolo", investimentos.DeleteAtivoCripto)
+
+		// Console de administração
+		admin := authorized.Group("/")
+		admin.Use(auth.AdminRequired())
+		{
+			admin.GET("/admin", handlers.GetAdminPage)
+			admin.GET("/api/admin/users", handlers.ListAdminUsersAPI)
+			admin.POST("/api/admin/users", handlers.AdminCreateUser)
+			admin.POST("/api/admin/users/:id/disabled", handlers.AdminSetUserDisabled)
+			admin.POST("/api/admin/users/:id/admin", handlers.AdminSetUserAdmin)
+			admin.POST("/api/admin/users/:id/password", handlers.AdminResetPassword)
+			admin.DELETE("/api/admin/users/:id", handlers.AdminDeleteUser)
+			admin.GET("/api/admin/audit", handlers.GetAuditLogAPI)
+			admin.POST("/api/admin/audit/purge", handlers.PurgeAuditLogAPI)
+		}
 	}
 
 	log.Println("Servidor Gin iniciado na porta :8080")
@@ -0,0 +1,23 @@
+// Package config lê as configurações numéricas da aplicação a partir das variáveis de ambiente.
+package config
+
+import (
+	"log"
+	"os"
+	"strconv"
+)
+
+// IntFromEnv lê a variável name como um inteiro maior ou igual a min. Se ela não estiver
+// definida, retorna def; se for inválida, avisa no log e também retorna def.
+func IntFromEnv(name string, def, min int) int {
+	value := os.Getenv(name)
+	if value == "" {
+		return def
+	}
+	parsed, err := strconv.Atoi(value)
+	if err != nil || parsed < min {
+		log.Printf("Aviso: %s inválido (%q); usando %d.", name, value, def)
+		return def
+	}
+	return parsed
+}
@@ -0,0 +1,26 @@
+package config
+
+import (
+	"os"
+	"testing"
+)
+
+func TestIntFromEnv(t *testing.T) {
+	const name = "CONFIG_TEST_INT"
+	defer os.Unsetenv(name)
+
+	os.Unsetenv(name)
+	if v := IntFromEnv(name, 7, 0); v != 7 {
+		t.Errorf("Sem a variável: esperado 7, obtido %d", v)
+	}
+	for valor, esperado := range map[string]int{"0": 0, "30": 30, "-1": 7, "abc": 7, "1.5": 7} {
+		os.Setenv(name, valor)
+		if v := IntFromEnv(name, 7, 0); v != esperado {
+			t.Errorf("%s=%q: esperado %d, obtido %d", name, valor, esperado, v)
+		}
+	}
+	os.Setenv(name, "0")
+	if v := IntFromEnv(name, 7, 1); v != 7 {
+		t.Errorf("Abaixo do mínimo: esperado 7, obtido %d", v)
+	}
+}
@@ -42,56 +42,71 @@ func InitDB() (*sql.DB, error) {
 		log.Println("Arquivo .env carregado com sucesso.")
 	}
 
-	DriverName = os.Getenv("DB_TYPE")
-	if DriverName == "" {
+	var err error
+	DBConnection, DriverName, err = Open("")
+	if err != nil {
+		return nil, err
+	}
+	return DBConnection, nil
+}
+
+// Open abre e testa uma conexão configurada pelas variáveis <prefix>DB_TYPE, <prefix>DB_HOST,
+// <prefix>DB_PORT, <prefix>DB_USER, <prefix>DB_PASS e <prefix>DB_NAME. Com prefixo vazio são as
+// variáveis da aplicação; a migração entre bancos usa "TARGET_" para o banco de destino.
+func Open(prefix string) (*sql.DB, string, error) {
+	driver := os.Getenv(prefix + "DB_TYPE")
+	if driver == "" {
 		// Se a variável não for definida, o programa para com uma mensagem de erro clara.
-		return nil, fmt.Errorf("a variável de ambiente DB_TYPE não foi definida. Por favor, configure-a (ex: 'postgres' ou 'sqlite3')")
+		return nil, "", fmt.Errorf("a variável de ambiente %sDB_TYPE não foi definida. Por favor, configure-a (ex: 'postgres' ou 'sqlite3')", prefix)
 	}
 
+	var db *sql.DB
 	var err error
 
-	switch DriverName {
+	switch driver {
 	case "postgres":
-		user := getEnv("DB_USER", "postgres")
-		pass := getEnv("DB_PASS", "postgres")
-		host := getEnv("DB_HOST", "localhost")
-		dbname := getEnv("DB_NAME", "minhas_economias")
-		port := getEnv("DB_PORT", "5432")
+		user := getEnv(prefix+"DB_USER", "postgres")
+		pass := getEnv(prefix+"DB_PASS", "postgres")
+		host := getEnv(prefix+"DB_HOST", "localhost")
+		dbname := getEnv(prefix+"DB_NAME", "minhas_economias")
+		port := getEnv(prefix+"DB_PORT", "5432")
 
 		connStr := fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=disable",
 			host, port, user, pass, dbname)
 
-		DBConnection, err = sql.Open("postgres", connStr)
+		db, err = sql.Open("postgres", connStr)
 		if err != nil {
-			return nil, fmt.Errorf("erro ao abrir o banco de dados postgres: %w", err)
+			return nil, "", fmt.Errorf("erro ao abrir o banco de dados postgres: %w", err)
 		}
 		log.Printf("Conectado ao banco de dados PostgreSQL '%s' em '%s'.", dbname, host)
 
 	case "sqlite3":
-		dbPath := getEnv("DB_NAME", "extratos.db")
-		DBConnection, err = sql.Open("sqlite3", dbPath)
+		dbPath := getEnv(prefix+"DB_NAME", "extratos.db")
+		db, err = sql.Open("sqlite3", dbPath)
 		if err != nil {
-			return nil, fmt.Errorf("erro ao abrir o banco de dados sqlite '%s': %w", dbPath, err)
+			return nil, "", fmt.Errorf("erro ao abrir o banco de dados sqlite '%s': %w", dbPath, err)
 		}
 		log.Printf("Conectado ao banco de dados SQLite em '%s'.", dbPath)
 
 	default:
-		return nil, fmt.Errorf("DB_TYPE '%s' não suportado", DriverName)
+		return nil, "", fmt.Errorf("DB_TYPE '%s' não suportado", driver)
 	}
 
-	err = DBConnection.Ping()
-	if err != nil {
-		if DBConnection != nil {
-			DBConnection.Close()
-		}
-		return nil, fmt.Errorf("erro ao conectar ao banco de dados: %w", err)
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, "", fmt.Errorf("erro ao conectar ao banco de dados: %w", err)
 	}
-	return DBConnection, nil
+	return db, driver, nil
 }
 
 // Rebind adapta uma query com placeholders '?' para a sintaxe do driver de banco de dados atual.
 func Rebind(query string) string {
-	if DriverName == "postgres" {
+	return RebindFor(DriverName, query)
+}
+
+// RebindFor adapta os placeholders '?' para o driver informado (usado quando há dois bancos abertos).
+func RebindFor(driver, query string) string {
+	if driver == "postgres" {
 		parts := strings.Split(query, "?")
 		var result strings.Builder
 		for i, part := range parts {
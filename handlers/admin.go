@@ -0,0 +1,392 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"fmt"
+	"log"
+	"math/big"
+	"minhas_economias/auth"
+	"minhas_economias/database"
+	"minhas_economias/households"
+	"minhas_economias/middleware"
+	"minhas_economias/models"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetAdminPage renderiza o console de administração: usuários, uso de armazenamento e log de auditoria.
+func GetAdminPage(c *gin.Context) {
+	user := c.MustGet("user").(*models.User)
+
+	users, err := listAdminUsers()
+	if err != nil {
+		renderErrorPage(c, http.StatusInternalServerError, "Não foi possível carregar a lista de usuários.", err)
+		return
+	}
+	auditLog, err := searchAuditLog(auditFilter{Limit: auditPageSize})
+	if err != nil {
+		log.Printf("Aviso: não foi possível carregar o log de auditoria: %v", err)
+	}
+
+	c.HTML(http.StatusOK, "admin.html", gin.H{
+		"Titulo":   "Administração",
+		"User":     user,
+		"Users":    users,
+		"AuditLog": auditLog,
+	})
+}
+
+// ListAdminUsersAPI retorna os usuários com o uso de armazenamento de cada um.
+func ListAdminUsersAPI(c *gin.Context) {
+	users, err := listAdminUsers()
+	if err != nil {
+		renderErrorPage(c, http.StatusInternalServerError, "Não foi possível carregar a lista de usuários.", err)
+		return
+	}
+	c.JSON(http.StatusOK, users)
+}
+
+// AdminCreateUserPayload é o corpo da requisição de criação de usuário pelo administrador.
+type AdminCreateUserPayload struct {
+	Email    string `json:"email" binding:"required,email"`
+	Password string `json:"password" binding:"required,min=6"`
+	IsAdmin  bool   `json:"is_admin"`
+}
+
+// AdminCreateUser cria um usuário (opcionalmente administrador).
+func AdminCreateUser(c *gin.Context) {
+	var payload AdminCreateUserPayload
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		renderErrorPage(c, http.StatusBadRequest, "Informe um e-mail válido e uma senha com no mínimo 6 caracteres.", err)
+		return
+	}
+
+	if err := auth.CreateUser(payload.Email, payload.Password); err != nil {
+		renderErrorPage(c, http.StatusConflict, err.Error(), err)
+		return
+	}
+	if payload.IsAdmin {
+		query := database.Rebind("UPDATE users SET is_admin = ? WHERE email = ?")
+		if _, err := database.GetDB().Exec(query, true, payload.Email); err != nil {
+			renderErrorPage(c, http.StatusInternalServerError, "Usuário criado, mas não foi possível torná-lo administrador.", err)
+			return
+		}
+	}
+
+	auditAdminAction(c, "ADMIN_CREATE_USER", payload.Email)
+	c.JSON(http.StatusCreated, gin.H{"message": "Usuário criado com sucesso!"})
+}
+
+// AdminToggleFlagPayload é o corpo das requisições que ligam/desligam um atributo do usuário.
+type AdminToggleFlagPayload struct {
+	Value bool `json:"value"`
+}
+
+// AdminSetUserDisabled ativa ou desativa uma conta. Desativar encerra todas as sessões do usuário.
+func AdminSetUserDisabled(c *gin.Context) {
+	target, ok := loadAdminTarget(c, false)
+	if !ok {
+		return
+	}
+	var payload AdminToggleFlagPayload
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		renderErrorPage(c, http.StatusBadRequest, "Dados inválidos.", err)
+		return
+	}
+
+	query := database.Rebind("UPDATE users SET disabled = ? WHERE id = ?")
+	if _, err := database.GetDB().Exec(query, payload.Value, target.ID); err != nil {
+		renderErrorPage(c, http.StatusInternalServerError, "Erro ao atualizar o usuário.", err)
+		return
+	}
+	if payload.Value {
+		if err := auth.RevokeAllSessions(target.ID); err != nil {
+			log.Printf("Aviso: não foi possível encerrar as sessões do usuário %d: %v", target.ID, err)
+		}
+		auditAdminAction(c, "ADMIN_DISABLE_USER", target.Email)
+		c.JSON(http.StatusOK, gin.H{"message": "Usuário desativado."})
+		return
+	}
+	auditAdminAction(c, "ADMIN_ENABLE_USER", target.Email)
+	c.JSON(http.StatusOK, gin.H{"message": "Usuário reativado."})
+}
+
+// AdminSetUserAdmin concede ou revoga o papel de administrador.
+func AdminSetUserAdmin(c *gin.Context) {
+	target, ok := loadAdminTarget(c, false)
+	if !ok {
+		return
+	}
+	var payload AdminToggleFlagPayload
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		renderErrorPage(c, http.StatusBadRequest, "Dados inválidos.", err)
+		return
+	}
+
+	query := database.Rebind("UPDATE users SET is_admin = ? WHERE id = ?")
+	if _, err := database.GetDB().Exec(query, payload.Value, target.ID); err != nil {
+		renderErrorPage(c, http.StatusInternalServerError, "Erro ao atualizar o usuário.", err)
+		return
+	}
+	if payload.Value {
+		auditAdminAction(c, "ADMIN_GRANT_ADMIN", target.Email)
+		c.JSON(http.StatusOK, gin.H{"message": "Usuário agora é administrador."})
+		return
+	}
+	auditAdminAction(c, "ADMIN_REVOKE_ADMIN", target.Email)
+	c.JSON(http.StatusOK, gin.H{"message": "Papel de administrador removido."})
+}
+
+// AdminResetPasswordPayload é o corpo da redefinição de senha pelo administrador.
+// Sem senha informada, uma senha temporária é gerada e devolvida uma única vez.
+type AdminResetPasswordPayload struct {
+	Password string `json:"password"`
+}
+
+// AdminResetPassword redefine a senha do usuário e encerra todas as sessões dele.
+func AdminResetPassword(c *gin.Context) {
+	target, ok := loadAdminTarget(c, true)
+	if !ok {
+		return
+	}
+	var payload AdminResetPasswordPayload
+	c.ShouldBindJSON(&payload) // Corpo opcional
+
+	password := payload.Password
+	generated := password == ""
+	if generated {
+		var err error
+		if password, err = generateTemporaryPassword(12); err != nil {
+			renderErrorPage(c, http.StatusInternalServerError, "Erro ao gerar a senha temporária.", err)
+			return
+		}
+	} else if len(password) < 6 {
+		renderErrorPage(c, http.StatusBadRequest, "A senha deve ter no mínimo 6 caracteres.", nil)
+		return
+	}
+
+	hash, err := auth.HashPassword(password)
+	if err != nil {
+		renderErrorPage(c, http.StatusInternalServerError, "Erro ao processar a nova senha.", err)
+		return
+	}
+	query := database.Rebind("UPDATE users SET password_hash = ? WHERE id = ?")
+	if _, err := database.GetDB().Exec(query, hash, target.ID); err != nil {
+		renderErrorPage(c, http.StatusInternalServerError, "Erro ao atualizar a senha no banco de dados.", err)
+		return
+	}
+	if err := auth.RevokeAllSessions(target.ID); err != nil {
+		log.Printf("Aviso: não foi possível encerrar as sessões do usuário %d: %v", target.ID, err)
+	}
+
+	auditAdminAction(c, "ADMIN_RESET_PASSWORD", target.Email)
+	response := gin.H{"message": "Senha redefinida com sucesso!"}
+	if generated {
+		response["temporary_password"] = password
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// AdminDeleteUser exclui o usuário e todos os seus dados, inclusive os lares dos quais é proprietário.
+func AdminDeleteUser(c *gin.Context) {
+	target, ok := loadAdminTarget(c, false)
+	if !ok {
+		return
+	}
+
+	if err := deleteUserAndData(target.ID); err != nil {
+		renderErrorPage(c, http.StatusInternalServerError, "Erro ao excluir o usuário.", err)
+		return
+	}
+
+	auditAdminAction(c, "ADMIN_DELETE_USER", target.Email)
+	c.JSON(http.StatusOK, gin.H{"message": "Usuário excluído com sucesso."})
+}
+
+// GetAuditLogAPI pesquisa o log de auditoria de todos os usuários.
+// Filtros: q (texto livre), email, entity_type, entity_id, action, from/to (AAAA-MM-DD) e limit.
+func GetAuditLogAPI(c *gin.Context) {
+	filter := auditFilterFromQuery(c)
+	filter.UserEmail = strings.TrimSpace(c.Query("email"))
+
+	entries, err := searchAuditLog(filter)
+	if err != nil {
+		renderErrorPage(c, http.StatusInternalServerError, "Erro ao consultar o log de auditoria.", err)
+		return
+	}
+	c.JSON(http.StatusOK, entries)
+}
+
+// PurgeAuditLogPayload é o corpo da limpeza manual do log; sem dias, usa AUDIT_RETENTION_DAYS.
+type PurgeAuditLogPayload struct {
+	Days int `json:"days"`
+}
+
+// PurgeAuditLogAPI remove as entradas mais antigas que o período de retenção.
+func PurgeAuditLogAPI(c *gin.Context) {
+	var payload PurgeAuditLogPayload
+	c.ShouldBindJSON(&payload) // Corpo opcional
+
+	retention := middleware.AuditRetentionFromEnv()
+	if payload.Days > 0 {
+		retention = time.Duration(payload.Days) * 24 * time.Hour
+	}
+	if retention <= 0 {
+		renderErrorPage(c, http.StatusBadRequest, "Informe o número de dias a manter (a retenção automática está desativada).", nil)
+		return
+	}
+
+	removed, err := middleware.PurgeAuditLogs(retention)
+	if err != nil {
+		renderErrorPage(c, http.StatusInternalServerError, "Erro ao limpar o log de auditoria.", err)
+		return
+	}
+	auditAdminAction(c, "ADMIN_PURGE_AUDIT", fmt.Sprintf("%d entradas com mais de %d dias", removed, int(retention.Hours()/24)))
+	c.JSON(http.StatusOK, gin.H{"message": fmt.Sprintf("%d entradas removidas do log de auditoria.", removed), "removed": removed})
+}
+
+// =============================================================================
+// Helper Functions
+// =============================================================================
+
+// loadAdminTarget carrega o usuário do parâmetro :id. Com allowSelf=false, impede que o
+// administrador aplique a ação a si mesmo (ex: desativar ou excluir a própria conta).
+func loadAdminTarget(c *gin.Context, allowSelf bool) (*models.User, bool) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		renderErrorPage(c, http.StatusBadRequest, "ID de usuário inválido.", err)
+		return nil, false
+	}
+	if !allowSelf && id == c.MustGet("userID").(int64) {
+		renderErrorPage(c, http.StatusBadRequest, "Você não pode aplicar esta ação à sua própria conta.", nil)
+		return nil, false
+	}
+
+	target := &models.User{ID: id}
+	err = database.GetDB().QueryRow(database.Rebind("SELECT email FROM users WHERE id = ?"), id).Scan(&target.Email)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			renderErrorPage(c, http.StatusNotFound, "Usuário não encontrado.", nil)
+		} else {
+			renderErrorPage(c, http.StatusInternalServerError, "Erro ao buscar o usuário.", err)
+		}
+		return nil, false
+	}
+	return target, true
+}
+
+// auditAdminAction registra a ação administrativa no log de auditoria, identificando o usuário afetado.
+func auditAdminAction(c *gin.Context, action, targetEmail string) {
+	admin := c.MustGet("user").(*models.User)
+	middleware.RecordAuditEvent(admin.Email, action, fmt.Sprintf("%s [alvo: %s]", c.Request.URL.Path, targetEmail), http.StatusOK, 0)
+}
+
+func listAdminUsers() ([]models.AdminUserSummary, error) {
+	query := fmt.Sprintf(`SELECT u.id, u.email, u.is_admin, COALESCE(u.disabled, FALSE),
+			(SELECT COUNT(*) FROM %[1]s m WHERE m.user_id = u.id),
+			(SELECT COUNT(*) FROM investimentos_nacionais n WHERE n.user_id = u.id) + (SELECT COUNT(*) FROM investimentos_internacionais i WHERE i.user_id = u.id),
+			(SELECT COUNT(*) FROM chat_history ch WHERE ch.user_id = u.id),
+			(SELECT COALESCE(SUM(LENGTH(COALESCE(m.descricao, '')) + LENGTH(COALESCE(m.categoria, '')) + LENGTH(COALESCE(m.conta, ''))), 0) FROM %[1]s m WHERE m.user_id = u.id)
+				+ (SELECT COALESCE(SUM(LENGTH(ch.content)), 0) FROM chat_history ch WHERE ch.user_id = u.id)
+				+ (SELECT COALESCE(SUM(a.tamanho), 0) FROM anexos a WHERE a.user_id = u.id)
+		FROM users u ORDER BY u.email ASC`, database.TableName)
+
+	rows, err := database.GetDB().Query(query)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao listar usuários: %w", err)
+	}
+	defer rows.Close()
+
+	var users []models.AdminUserSummary
+	for rows.Next() {
+		var u models.AdminUserSummary
+		if err := rows.Scan(&u.ID, &u.Email, &u.IsAdmin, &u.Disabled, &u.Transactions, &u.Investments, &u.ChatMessages, &u.ApproxBytes); err != nil {
+			return nil, fmt.Errorf("erro ao ler usuário: %w", err)
+		}
+		users = append(users, u)
+	}
+	return users, rows.Err()
+}
+
+// deleteUserAndData apaga o usuário e seus dados numa única transação. As exclusões são explícitas
+// porque o SQLite só respeita ON DELETE CASCADE com foreign_keys ativado.
+// Os lares de que ele é proprietário são excluídos; nos demais lares, as transações e anexos que
+// ele lançou passam ao proprietário do lar (o autor some de created_by), para que o ON DELETE CASCADE
+// de movimentacoes.user_id não apague dados compartilhados.
+func deleteUserAndData(userID int64) error {
+	tx, err := database.GetDB().Begin()
+	if err != nil {
+		return fmt.Errorf("erro ao iniciar transação: %w", err)
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.Query(database.Rebind("SELECT id FROM households WHERE owner_id = ?"), userID)
+	if err != nil {
+		return fmt.Errorf("erro ao listar os lares do usuário: %w", err)
+	}
+	var owned []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return fmt.Errorf("erro ao ler lar do usuário: %w", err)
+		}
+		owned = append(owned, id)
+	}
+	rows.Close()
+	for _, id := range owned {
+		if err := households.DeleteTx(tx, id); err != nil {
+			return err
+		}
+	}
+
+	for _, query := range []string{
+		fmt.Sprintf(`UPDATE anexos SET user_id = (SELECT h.owner_id FROM %[1]s m JOIN households h ON h.id = m.household_id WHERE m.id = anexos.movimentacao_id)
+			WHERE user_id = ? AND movimentacao_id IN (SELECT id FROM %[1]s WHERE household_id IS NOT NULL)`, database.TableName),
+		fmt.Sprintf("UPDATE %[1]s SET user_id = (SELECT h.owner_id FROM households h WHERE h.id = %[1]s.household_id) WHERE user_id = ? AND household_id IS NOT NULL", database.TableName),
+		fmt.Sprintf("UPDATE %s SET created_by = NULL WHERE created_by = ?", database.TableName),
+		fmt.Sprintf("DELETE FROM %s WHERE user_id = ? AND household_id IS NULL", database.TableName),
+		"DELETE FROM contas WHERE user_id = ? AND household_id IS NULL",
+		"DELETE FROM investimentos_nacionais WHERE user_id = ?",
+		"DELETE FROM investimentos_internacionais WHERE user_id = ?",
+		"DELETE FROM operacoes_investimentos WHERE user_id = ?",
+		"DELETE FROM proventos WHERE user_id = ?",
+		"DELETE FROM renda_fixa WHERE user_id = ?",
+		"DELETE FROM investimentos_cripto WHERE user_id = ?",
+		"DELETE FROM alocacao_alvo WHERE user_id = ?",
+		"DELETE FROM chat_history WHERE user_id = ?",
+		"DELETE FROM user_profiles WHERE user_id = ?",
+		"DELETE FROM password_reset_tokens WHERE user_id = ?",
+		"DELETE FROM user_sessions WHERE user_id = ?",
+		"DELETE FROM household_members WHERE user_id = ?",
+		"DELETE FROM users WHERE id = ?",
+	} {
+		if _, err := tx.Exec(database.Rebind(query), userID); err != nil {
+			return fmt.Errorf("erro ao excluir dados do usuário: %w", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	cleanupOrphanAnexos()
+	return nil
+}
+
+// generateTemporaryPassword gera uma senha aleatória legível (sem caracteres ambíguos).
+func generateTemporaryPassword(length int) (string, error) {
+	const alphabet = "abcdefghjkmnpqrstuvwxyzABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+	b := make([]byte, length)
+	for i := range b {
+		n, err := rand.Int(rand.Reader, big.NewInt(int64(len(alphabet))))
+		if err != nil {
+			return "", err
+		}
+		b[i] = alphabet[n.Int64()]
+	}
+	return string(b), nil
+}
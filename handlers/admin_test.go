@@ -0,0 +1,206 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"minhas_economias/auth"
+	"minhas_economias/database"
+	"minhas_economias/households"
+	"minhas_economias/models"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// setupAdminTestDB estende o banco de teste com as tabelas tocadas pelo console de administração.
+func setupAdminTestDB(t *testing.T) {
+	setupHouseholdTestDB(t)
+	db := database.GetDB()
+
+	createTablesSQL := map[string]string{
+		"chat_history":                 `CREATE TABLE chat_history (id INTEGER PRIMARY KEY AUTOINCREMENT, user_id BIGINT NOT NULL, role TEXT NOT NULL, content TEXT NOT NULL, created_at TIMESTAMP);`,
+		"investimentos_nacionais":      `CREATE TABLE investimentos_nacionais (user_id BIGINT NOT NULL, ticker TEXT NOT NULL, quantidade INTEGER);`,
+		"investimentos_internacionais": `CREATE TABLE investimentos_internacionais (user_id BIGINT NOT NULL, ticker TEXT NOT NULL, quantidade NUMERIC);`,
+		"operacoes_investimentos":      `CREATE TABLE operacoes_investimentos (id INTEGER PRIMARY KEY AUTOINCREMENT, user_id BIGINT NOT NULL, ticker TEXT NOT NULL);`,
+		"proventos":                    `CREATE TABLE proventos (id INTEGER PRIMARY KEY AUTOINCREMENT, user_id BIGINT NOT NULL, ticker TEXT NOT NULL);`,
+		"renda_fixa":                   `CREATE TABLE renda_fixa (id INTEGER PRIMARY KEY AUTOINCREMENT, user_id BIGINT NOT NULL, nome TEXT NOT NULL);`,
+		"investimentos_cripto":         `CREATE TABLE investimentos_cripto (user_id BIGINT NOT NULL, simbolo TEXT NOT NULL, quantidade TEXT NOT NULL);`,
+		"alocacao_alvo":                `CREATE TABLE alocacao_alvo (user_id BIGINT NOT NULL, classe TEXT NOT NULL, ticker TEXT NOT NULL DEFAULT '', percentual REAL NOT NULL);`,
+		"user_profiles":                `CREATE TABLE user_profiles (user_id BIGINT PRIMARY KEY);`,
+		"password_reset_tokens":        `CREATE TABLE password_reset_tokens (token_hash TEXT PRIMARY KEY, user_id BIGINT NOT NULL);`,
+		"user_sessions":                `CREATE TABLE user_sessions (id TEXT PRIMARY KEY, user_id BIGINT NOT NULL);`,
+	}
+	for table, query := range createTablesSQL {
+		db.Exec(fmt.Sprintf("DROP TABLE IF EXISTS %s", table))
+		if _, err := db.Exec(query); err != nil {
+			t.Fatalf("Falha ao criar a tabela de teste '%s': %v", table, err)
+		}
+	}
+	createAuditLogTable(t)
+	createAnexosTable(t)
+
+	// O segundo usuário tem dados próprios que devem sumir junto com ele.
+	db.Exec(database.Rebind(fmt.Sprintf("INSERT INTO %s (user_id, data_ocorrencia, descricao, valor, conta) VALUES (?, ?, ?, ?, ?)", database.TableName)),
+		testSpouseID, "2025-02-01", "Padaria", -20.0, "Carteira")
+	db.Exec(database.Rebind("INSERT INTO chat_history (user_id, role, content) VALUES (?, ?, ?)"), testSpouseID, "user", "Como economizar?")
+}
+
+// createAdminTestRouter monta as rotas de administração com um usuário logado (admin ou não).
+func createAdminTestRouter(isAdmin bool) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		c.Set("userID", testUserID)
+		c.Set("user", &models.User{ID: testUserID, Email: "test@user.com", IsAdmin: isAdmin})
+		c.Next()
+	})
+	admin := r.Group("/")
+	admin.Use(auth.AdminRequired())
+	admin.GET("/api/admin/users", ListAdminUsersAPI)
+	admin.POST("/api/admin/users/:id/disabled", AdminSetUserDisabled)
+	admin.DELETE("/api/admin/users/:id", AdminDeleteUser)
+	admin.GET("/api/admin/audit", GetAuditLogAPI)
+	return r
+}
+
+// performAdminRequest envia a requisição como o static/js/admin.js: JSON com Accept JSON.
+func performAdminRequest(r http.Handler, method, path string, payload interface{}) *httptest.ResponseRecorder {
+	body, _ := json.Marshal(payload)
+	req, _ := http.NewRequest(method, path, bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestAdminRoutes_RejectNonAdmin(t *testing.T) {
+	setupAdminTestDB(t)
+	defer teardownTestDB()
+
+	w := performAdminRequest(createAdminTestRouter(false), "GET", "/api/admin/users", nil)
+	if w.Code != http.StatusForbidden {
+		t.Errorf("Esperado status 403 para usuário comum, obteve %d", w.Code)
+	}
+}
+
+func TestAdminConsole_ManageUsersAndAudit(t *testing.T) {
+	setupAdminTestDB(t)
+	defer teardownTestDB()
+	r := createAdminTestRouter(true)
+
+	// A listagem traz o uso de armazenamento de cada usuário.
+	w := performAdminRequest(r, "GET", "/api/admin/users", nil)
+	var users []models.AdminUserSummary
+	if err := json.Unmarshal(w.Body.Bytes(), &users); err != nil || w.Code != http.StatusOK {
+		t.Fatalf("Esperado lista de usuários (status 200), obteve %d: %s", w.Code, w.Body.String())
+	}
+	for _, u := range users {
+		if u.ID == testSpouseID && (u.Transactions != 1 || u.ChatMessages != 1 || u.ApproxBytes == 0) {
+			t.Errorf("Uso de armazenamento inesperado para o segundo usuário: %+v", u)
+		}
+	}
+
+	// O administrador não pode desativar a própria conta...
+	url := fmt.Sprintf("/api/admin/users/%d/disabled", testUserID)
+	if w := performAdminRequest(r, "POST", url, map[string]bool{"value": true}); w.Code != http.StatusBadRequest {
+		t.Errorf("Esperado status 400 ao desativar a própria conta, obteve %d", w.Code)
+	}
+	// ...mas pode desativar a de outro usuário.
+	url = fmt.Sprintf("/api/admin/users/%d/disabled", testSpouseID)
+	if w := performAdminRequest(r, "POST", url, map[string]bool{"value": true}); w.Code != http.StatusOK {
+		t.Fatalf("Esperado status 200 ao desativar usuário, obteve %d: %s", w.Code, w.Body.String())
+	}
+	user, err := auth.GetUserByEmail("spouse@user.com")
+	if err != nil || !user.Disabled {
+		t.Errorf("Esperado usuário desativado, obteve %+v (erro: %v)", user, err)
+	}
+
+	// A exclusão remove o usuário e seus dados.
+	if w := performAdminRequest(r, "DELETE", fmt.Sprintf("/api/admin/users/%d", testSpouseID), nil); w.Code != http.StatusOK {
+		t.Fatalf("Esperado status 200 ao excluir usuário, obteve %d: %s", w.Code, w.Body.String())
+	}
+	var count int
+	database.GetDB().QueryRow(database.Rebind(fmt.Sprintf("SELECT COUNT(*) FROM %s WHERE user_id = ?", database.TableName)), testSpouseID).Scan(&count)
+	if count != 0 {
+		t.Errorf("Esperado que as transações do usuário excluído fossem apagadas, restaram %d", count)
+	}
+
+	// Cada ação administrativa fica registrada no log de auditoria.
+	w = performAdminRequest(r, "GET", "/api/admin/audit?q=spouse@user.com", nil)
+	var entries []models.AuditLogEntry
+	if err := json.Unmarshal(w.Body.Bytes(), &entries); err != nil {
+		t.Fatalf("Resposta JSON inválida: %v", err)
+	}
+	if len(entries) != 2 || entries[0].Action != "ADMIN_DELETE_USER" || entries[1].Action != "ADMIN_DISABLE_USER" {
+		t.Errorf("Esperado registro de desativação e exclusão no log de auditoria, obteve %+v", entries)
+	}
+}
+
+func TestAdminDeleteUser_KeepsSharedHouseholdData(t *testing.T) {
+	setupAdminTestDB(t)
+	defer teardownTestDB()
+	db := database.GetDB()
+
+	// O cônjuge participa do lar do usuário de teste e é dono de um lar próprio.
+	var sharedID int64
+	db.QueryRow(database.Rebind("SELECT id FROM households WHERE owner_id = ?"), testUserID).Scan(&sharedID)
+	if err := households.AddMember(testUserID, sharedID, "spouse@user.com", models.RoleEditor); err != nil {
+		t.Fatalf("Falha ao adicionar membro: %v", err)
+	}
+	ownHousehold, err := households.Create(testSpouseID, "Casa da praia")
+	if err != nil {
+		t.Fatalf("Falha ao criar lar do cônjuge: %v", err)
+	}
+
+	// Linha lançada pelo cônjuge no lar compartilhado antes da regra de propriedade (user_id do membro).
+	insertMov := database.Rebind(fmt.Sprintf("INSERT INTO %s (user_id, household_id, created_by, data_ocorrencia, descricao, valor, conta) VALUES (?, ?, ?, ?, ?, ?, ?)", database.TableName))
+	db.Exec(insertMov, testSpouseID, sharedID, testSpouseID, "2025-02-02", "Mercado", -300.0, "Conjunta")
+	db.Exec(insertMov, testSpouseID, ownHousehold, testSpouseID, "2025-02-03", "Aluguel", -900.0, "Praia")
+	var sharedMovID int64
+	db.QueryRow(fmt.Sprintf("SELECT id FROM %s WHERE descricao = 'Mercado'", database.TableName)).Scan(&sharedMovID)
+	db.Exec(database.Rebind("INSERT INTO anexos (movimentacao_id, user_id, nome_arquivo, content_type, tamanho, storage_key, created_at) VALUES (?, ?, 'nota.pdf', 'application/pdf', 2048, 'x', CURRENT_TIMESTAMP)"), sharedMovID, testSpouseID)
+
+	r := createAdminTestRouter(true)
+
+	// Os anexos entram no uso de armazenamento.
+	var users []models.AdminUserSummary
+	json.Unmarshal(performAdminRequest(r, "GET", "/api/admin/users", nil).Body.Bytes(), &users)
+	for _, u := range users {
+		if u.ID == testSpouseID && u.ApproxBytes < 2048 {
+			t.Errorf("Esperado que o uso de armazenamento incluísse os anexos, obteve %d bytes", u.ApproxBytes)
+		}
+	}
+
+	if w := performAdminRequest(r, "DELETE", fmt.Sprintf("/api/admin/users/%d", testSpouseID), nil); w.Code != http.StatusOK {
+		t.Fatalf("Esperado status 200 ao excluir usuário, obteve %d: %s", w.Code, w.Body.String())
+	}
+
+	var owner int64
+	var createdBy *int64
+	if err := db.QueryRow(database.Rebind(fmt.Sprintf("SELECT user_id, created_by FROM %s WHERE id = ?", database.TableName)), sharedMovID).Scan(&owner, &createdBy); err != nil {
+		t.Fatalf("A transação do lar compartilhado deveria continuar existindo: %v", err)
+	}
+	if owner != testUserID || createdBy != nil {
+		t.Errorf("Esperado que a transação passasse ao proprietário do lar, obteve user_id=%d created_by=%v", owner, createdBy)
+	}
+	var anexoOwner int64
+	db.QueryRow(database.Rebind("SELECT user_id FROM anexos WHERE movimentacao_id = ?"), sharedMovID).Scan(&anexoOwner)
+	if anexoOwner != testUserID {
+		t.Errorf("Esperado que o anexo passasse ao proprietário do lar, obteve user_id=%d", anexoOwner)
+	}
+
+	var count int
+	db.QueryRow(database.Rebind("SELECT COUNT(*) FROM households WHERE id = ?"), ownHousehold).Scan(&count)
+	if count != 0 {
+		t.Error("O lar de que o usuário excluído era proprietário deveria ser apagado")
+	}
+	db.QueryRow(database.Rebind(fmt.Sprintf("SELECT COUNT(*) FROM %s WHERE household_id = ?", database.TableName)), ownHousehold).Scan(&count)
+	if count != 0 {
+		t.Errorf("Esperado que as transações do lar excluído fossem apagadas, restaram %d", count)
+	}
+}
@@ -57,7 +57,7 @@ func PostAnaliseChat(c *gin.Context) {
 		log.Printf("AVISO: Falha ao extrair datas da pergunta: %v", err)
 	}
 
-	financialData, err := fetchFinancialDataForPeriod(userID, startDate, endDate)
+	financialData, err := fetchFinancialDataForPeriod(currentLedger(c), startDate, endDate)
 	if err != nil {
 		renderErrorPage(c, http.StatusInternalServerError, "Erro ao buscar dados financeiros.", err)
 		return
@@ -115,7 +115,7 @@ func saveChatMessage(msg models.ChatMessage) error {
 }
 
 // fetchFinancialDataForPeriod (sem alterações)
-func fetchFinancialDataForPeriod(userID int64, startDate, endDate string) (string, error) {
+func fetchFinancialDataForPeriod(scope ledgerScope, startDate, endDate string) (string, error) {
 	if startDate == "" || endDate == "" {
 		now := time.Now()
 		endOfLastMonth := time.Date(now.Year(), now.Month(), 0, 23, 59, 59, 0, now.Location())
@@ -126,12 +126,12 @@ func fetchFinancialDataForPeriod(userID int64, startDate, endDate string) (strin
 	}
 
 	var args []interface{}
-	args = append(args, userID)
+	args = append(args, scope.arg())
 
 	query := fmt.Sprintf(`
 		SELECT data_ocorrencia, descricao, valor, categoria, conta
 		FROM %s
-		WHERE user_id = ?`, database.TableName)
+		WHERE %s`, database.TableName, scope.activeWhere())
 
 	query += " AND data_ocorrencia BETWEEN ? AND ?"
 	args = append(args, startDate, endDate)
@@ -0,0 +1,454 @@
+package handlers
+
+import (
+	"bytes"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	_ "image/gif" // Registra o decodificador GIF para as miniaturas
+	"image/jpeg"
+	_ "image/png" // Registra o decodificador PNG para as miniaturas
+	"io"
+	"log"
+	"minhas_economias/config"
+	"minhas_economias/database"
+	"minhas_economias/middleware"
+	"minhas_economias/models"
+	"minhas_economias/storage"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Limites padrão dos anexos, sobrescritos por ATTACHMENT_MAX_MB e ATTACHMENT_QUOTA_MB.
+const (
+	DefaultAttachmentMaxMB   = 10
+	DefaultAttachmentQuotaMB = 200
+	thumbnailMaxSide         = 240
+	// thumbnailMaxPixels limita largura × altura das imagens decodificadas para a miniatura:
+	// um PNG ou JPEG pequeno pode declarar dimensões enormes e esgotar a memória ao ser decodificado.
+	thumbnailMaxPixels = 40_000_000
+)
+
+// allowedAttachmentTypes mapeia o tipo detectado pelo conteúdo (não pelo nome do arquivo) para a extensão gravada.
+var allowedAttachmentTypes = map[string]string{
+	"image/jpeg":      ".jpg",
+	"image/png":       ".png",
+	"image/gif":       ".gif",
+	"image/webp":      ".webp",
+	"application/pdf": ".pdf",
+}
+
+// megabytesFromEnv lê um limite em MB de uma variável de ambiente; zero desativa o limite.
+func megabytesFromEnv(name string, fallback int) int64 {
+	return int64(config.IntFromEnv(name, fallback, 0)) * 1024 * 1024
+}
+
+// attachmentURLs preenche os links de download e miniatura do anexo.
+func attachmentURLs(a *models.Anexo) {
+	a.URL = fmt.Sprintf("/anexos/%d", a.ID)
+	if a.ThumbnailKey != "" {
+		a.ThumbnailURL = fmt.Sprintf("/anexos/%d/thumbnail", a.ID)
+	}
+}
+
+const anexoColumns = "a.id, a.movimentacao_id, a.user_id, a.nome_arquivo, a.content_type, a.tamanho, a.storage_key, a.thumbnail_key, a.created_at"
+
+func scanAnexo(scanner interface{ Scan(...interface{}) error }) (models.Anexo, error) {
+	var a models.Anexo
+	var thumb sql.NullString
+	err := scanner.Scan(&a.ID, &a.MovimentacaoID, &a.UserID, &a.NomeArquivo, &a.ContentType, &a.Tamanho, &a.StorageKey, &thumb, &a.CreatedAt)
+	a.ThumbnailKey = thumb.String
+	attachmentURLs(&a)
+	return a, err
+}
+
+// loadAnexo busca o anexo cuja movimentação pertence ao livro-caixa ativo (inclusive na lixeira).
+func loadAnexo(scope ledgerScope, id int64) (*models.Anexo, error) {
+	query := fmt.Sprintf("SELECT %s FROM anexos a WHERE a.id = ? AND a.movimentacao_id IN (SELECT id FROM %s WHERE %s)", anexoColumns, database.TableName, scope.where())
+	a, err := scanAnexo(database.GetDB().QueryRow(database.Rebind(query), id, scope.arg()))
+	if err != nil {
+		return nil, err
+	}
+	return &a, nil
+}
+
+// listAnexos retorna os anexos de uma movimentação.
+func listAnexos(movimentacaoID int) ([]models.Anexo, error) {
+	query := fmt.Sprintf("SELECT %s FROM anexos a WHERE a.movimentacao_id = ? ORDER BY a.created_at ASC, a.id ASC", anexoColumns)
+	rows, err := database.GetDB().Query(database.Rebind(query), movimentacaoID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	anexos := []models.Anexo{}
+	for rows.Next() {
+		a, err := scanAnexo(rows)
+		if err != nil {
+			return nil, err
+		}
+		anexos = append(anexos, a)
+	}
+	return anexos, rows.Err()
+}
+
+// movimentacaoInScope confere se a movimentação existe (fora da lixeira) no livro-caixa ativo.
+func movimentacaoInScope(c *gin.Context) (ledgerScope, int, bool) {
+	scope := currentLedger(c)
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		renderErrorPage(c, http.StatusBadRequest, "O ID da transação é inválido.", err)
+		return scope, 0, false
+	}
+	if _, err := loadMovimentacao(scope, id); err != nil {
+		renderErrorPage(c, http.StatusNotFound, "Movimentação não encontrada.", err)
+		return scope, 0, false
+	}
+	return scope, id, true
+}
+
+// ListAnexosAPI lista os anexos de uma movimentação.
+func ListAnexosAPI(c *gin.Context) {
+	_, id, ok := movimentacaoInScope(c)
+	if !ok {
+		return
+	}
+	anexos, err := listAnexos(id)
+	if err != nil {
+		renderErrorPage(c, http.StatusInternalServerError, "Erro ao carregar os anexos.", err)
+		return
+	}
+	c.JSON(http.StatusOK, anexos)
+}
+
+// UploadAnexo recebe um arquivo (campo "arquivo") e o associa à movimentação.
+// O tipo é validado pelo conteúdo; imagens ganham uma miniatura em JPEG.
+func UploadAnexo(c *gin.Context) {
+	scope, movID, ok := movimentacaoInScope(c)
+	if !ok {
+		return
+	}
+
+	maxBytes := megabytesFromEnv("ATTACHMENT_MAX_MB", DefaultAttachmentMaxMB)
+	if maxBytes > 0 {
+		// Folga para os cabeçalhos do multipart; o tamanho do arquivo é conferido abaixo.
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxBytes+64*1024)
+	}
+	fileHeader, err := c.FormFile("arquivo")
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			renderErrorPage(c, http.StatusRequestEntityTooLarge, fmt.Sprintf("O arquivo excede o limite de %d MB.", maxBytes/(1024*1024)), err)
+			return
+		}
+		renderErrorPage(c, http.StatusBadRequest, "Envie o arquivo no campo 'arquivo'.", err)
+		return
+	}
+	if maxBytes > 0 && fileHeader.Size > maxBytes {
+		renderErrorPage(c, http.StatusRequestEntityTooLarge, fmt.Sprintf("O arquivo excede o limite de %d MB.", maxBytes/(1024*1024)), nil)
+		return
+	}
+	file, err := fileHeader.Open()
+	if err != nil {
+		renderErrorPage(c, http.StatusBadRequest, "Não foi possível ler o arquivo enviado.", err)
+		return
+	}
+	data, err := io.ReadAll(file)
+	file.Close()
+	if err != nil {
+		renderErrorPage(c, http.StatusBadRequest, "Não foi possível ler o arquivo enviado.", err)
+		return
+	}
+	if len(data) == 0 {
+		renderErrorPage(c, http.StatusBadRequest, "O arquivo enviado está vazio.", nil)
+		return
+	}
+
+	contentType := http.DetectContentType(data)
+	ext, allowed := allowedAttachmentTypes[contentType]
+	if !allowed {
+		renderErrorPage(c, http.StatusUnsupportedMediaType, "Tipo de arquivo não permitido. Envie imagens (JPEG, PNG, GIF, WebP) ou PDF.", nil)
+		return
+	}
+
+	quota := megabytesFromEnv("ATTACHMENT_QUOTA_MB", DefaultAttachmentQuotaMB)
+	if quota > 0 {
+		used, err := attachmentUsage(scope.UserID)
+		if err != nil {
+			renderErrorPage(c, http.StatusInternalServerError, "Erro ao verificar a cota de anexos.", err)
+			return
+		}
+		if used+int64(len(data)) > quota {
+			renderErrorPage(c, http.StatusRequestEntityTooLarge, fmt.Sprintf("Cota de anexos excedida: %.1f de %d MB em uso.", float64(used)/(1024*1024), quota/(1024*1024)), nil)
+			return
+		}
+	}
+
+	suffix, err := randomHex(16)
+	if err != nil {
+		renderErrorPage(c, http.StatusInternalServerError, "Erro ao gerar o nome do arquivo.", err)
+		return
+	}
+	store := storage.Current()
+	key := fmt.Sprintf("anexos/%d/%s%s", scope.UserID, suffix, ext)
+	if err := store.Put(key, data, contentType); err != nil {
+		renderErrorPage(c, http.StatusInternalServerError, "Erro ao gravar o anexo.", err)
+		return
+	}
+
+	var thumbKey sql.NullString
+	if thumb, err := makeThumbnail(data); err == nil {
+		thumbKey.String = fmt.Sprintf("anexos/%d/%s_thumb.jpg", scope.UserID, suffix)
+		if err := store.Put(thumbKey.String, thumb, "image/jpeg"); err != nil {
+			log.Printf("Aviso: não foi possível gravar a miniatura '%s': %v", thumbKey.String, err)
+			thumbKey.String = ""
+		} else {
+			thumbKey.Valid = true
+		}
+	}
+
+	anexo := models.Anexo{
+		MovimentacaoID: movID,
+		UserID:         scope.UserID,
+		NomeArquivo:    sanitizeFilename(fileHeader.Filename, ext),
+		ContentType:    contentType,
+		Tamanho:        int64(len(data)),
+		StorageKey:     key,
+		ThumbnailKey:   thumbKey.String,
+		CreatedAt:      time.Now().UTC(),
+	}
+	query := "INSERT INTO anexos (movimentacao_id, user_id, nome_arquivo, content_type, tamanho, storage_key, thumbnail_key, created_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?)"
+	if database.DriverName == "postgres" {
+		query += " RETURNING id"
+	}
+	stmt, err := database.GetDB().Prepare(database.Rebind(query))
+	if err == nil {
+		var id int
+		id, err = insertReturningID(stmt, anexo.MovimentacaoID, anexo.UserID, anexo.NomeArquivo, anexo.ContentType, anexo.Tamanho, anexo.StorageKey, thumbKey, anexo.CreatedAt)
+		anexo.ID = int64(id)
+		stmt.Close()
+	}
+	if err != nil {
+		deleteAnexoBlobs(anexo)
+		renderErrorPage(c, http.StatusInternalServerError, "Erro ao registrar o anexo.", err)
+		return
+	}
+	attachmentURLs(&anexo)
+
+	middleware.SetAuditChange(c, "anexo", anexo.ID, nil, anexo)
+	c.JSON(http.StatusCreated, anexo)
+}
+
+// GetAnexo devolve o arquivo original. PDFs e imagens são exibidos no navegador.
+func GetAnexo(c *gin.Context) {
+	serveAnexo(c, false)
+}
+
+// GetAnexoThumbnail devolve a miniatura JPEG de um anexo de imagem.
+func GetAnexoThumbnail(c *gin.Context) {
+	serveAnexo(c, true)
+}
+
+func serveAnexo(c *gin.Context, thumbnail bool) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		renderErrorPage(c, http.StatusBadRequest, "O ID do anexo é inválido.", err)
+		return
+	}
+	anexo, err := loadAnexo(currentLedger(c), id)
+	if err != nil {
+		renderErrorPage(c, http.StatusNotFound, "Anexo não encontrado.", err)
+		return
+	}
+
+	key, contentType := anexo.StorageKey, anexo.ContentType
+	if thumbnail {
+		if anexo.ThumbnailKey == "" {
+			renderErrorPage(c, http.StatusNotFound, "Este anexo não tem miniatura.", nil)
+			return
+		}
+		key, contentType = anexo.ThumbnailKey, "image/jpeg"
+	}
+	data, err := storage.ReadAll(storage.Current(), key)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, storage.ErrNotFound) {
+			status = http.StatusNotFound
+		}
+		renderErrorPage(c, status, "Não foi possível ler o anexo no armazenamento.", err)
+		return
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf("inline; filename=%q", anexo.NomeArquivo))
+	c.Header("X-Content-Type-Options", "nosniff")
+	c.Header("Cache-Control", "private, max-age=3600")
+	c.Data(http.StatusOK, contentType, data)
+}
+
+// DeleteAnexo remove o anexo e seus arquivos no armazenamento.
+func DeleteAnexo(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		renderErrorPage(c, http.StatusBadRequest, "O ID do anexo é inválido.", err)
+		return
+	}
+	anexo, err := loadAnexo(currentLedger(c), id)
+	if err != nil {
+		renderErrorPage(c, http.StatusNotFound, "Anexo não encontrado.", err)
+		return
+	}
+	if _, err := database.GetDB().Exec(database.Rebind("DELETE FROM anexos WHERE id = ?"), anexo.ID); err != nil {
+		renderErrorPage(c, http.StatusInternalServerError, "Erro ao excluir o anexo.", err)
+		return
+	}
+	deleteAnexoBlobs(*anexo)
+
+	middleware.SetAuditChange(c, "anexo", anexo.ID, anexo, nil)
+	c.JSON(http.StatusOK, gin.H{"message": "Anexo excluído com sucesso!"})
+}
+
+// attachmentUsage soma o tamanho dos anexos enviados pelo usuário (miniaturas não contam).
+func attachmentUsage(userID int64) (int64, error) {
+	var used int64
+	err := database.GetDB().QueryRow(database.Rebind("SELECT COALESCE(SUM(tamanho), 0) FROM anexos WHERE user_id = ?"), userID).Scan(&used)
+	return used, err
+}
+
+// deleteAnexoBlobs apaga o arquivo e a miniatura; falhas só são registradas no log.
+func deleteAnexoBlobs(a models.Anexo) {
+	store := storage.Current()
+	for _, key := range []string{a.StorageKey, a.ThumbnailKey} {
+		if key == "" {
+			continue
+		}
+		if err := store.Delete(key); err != nil {
+			log.Printf("Aviso: não foi possível apagar '%s' do armazenamento: %v", key, err)
+		}
+	}
+}
+
+// PurgeOrphanAnexos apaga os anexos cujas movimentações não existem mais (apagadas da
+// lixeira, de um lar excluído ou de um usuário removido), incluindo os arquivos.
+func PurgeOrphanAnexos() (int64, error) {
+	query := fmt.Sprintf("SELECT %s FROM anexos a WHERE NOT EXISTS (SELECT 1 FROM %s m WHERE m.id = a.movimentacao_id)", anexoColumns, database.TableName)
+	rows, err := database.GetDB().Query(query)
+	if err != nil {
+		return 0, fmt.Errorf("erro ao buscar anexos órfãos: %w", err)
+	}
+	var orphans []models.Anexo
+	for rows.Next() {
+		a, err := scanAnexo(rows)
+		if err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("erro ao ler anexos órfãos: %w", err)
+		}
+		orphans = append(orphans, a)
+	}
+	rows.Close()
+
+	var removed int64
+	for _, a := range orphans {
+		if _, err := database.GetDB().Exec(database.Rebind("DELETE FROM anexos WHERE id = ?"), a.ID); err != nil {
+			return removed, fmt.Errorf("erro ao apagar anexo órfão %d: %w", a.ID, err)
+		}
+		deleteAnexoBlobs(a)
+		removed++
+	}
+	return removed, nil
+}
+
+// cleanupOrphanAnexos é chamado depois de apagar movimentações definitivamente; erros só vão para o log.
+func cleanupOrphanAnexos() {
+	if _, err := PurgeOrphanAnexos(); err != nil {
+		log.Printf("Aviso: %v", err)
+	}
+}
+
+// sanitizeFilename mantém apenas o nome base (sem diretórios nem caracteres de controle) e garante a extensão detectada.
+func sanitizeFilename(name, ext string) string {
+	name = filepath.Base(strings.ReplaceAll(name, "\\", "/"))
+	name = strings.Map(func(r rune) rune {
+		if r < 32 || r == '"' || r == 127 {
+			return -1
+		}
+		return r
+	}, name)
+	if name == "" || name == "." || name == "/" {
+		name = "anexo"
+	}
+	if len(name) > 120 {
+		name = name[:120]
+	}
+	if !strings.EqualFold(filepath.Ext(name), ext) && !(ext == ".jpg" && strings.EqualFold(filepath.Ext(name), ".jpeg")) {
+		name += ext
+	}
+	return name
+}
+
+func randomHex(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// makeThumbnail reduz a imagem para caber em thumbnailMaxSide (média por área) e a codifica em JPEG.
+// Retorna erro para formatos que a biblioteca padrão não decodifica (PDF, WebP) e para imagens
+// acima de thumbnailMaxPixels, verificadas pelo cabeçalho antes de decodificar.
+func makeThumbnail(data []byte) ([]byte, error) {
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	if cfg.Width <= 0 || cfg.Height <= 0 || int64(cfg.Width)*int64(cfg.Height) > thumbnailMaxPixels {
+		return nil, fmt.Errorf("dimensões da imagem fora do limite: %dx%d", cfg.Width, cfg.Height)
+	}
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	b := src.Bounds()
+	w, h := b.Dx(), b.Dy()
+	if w == 0 || h == 0 {
+		return nil, fmt.Errorf("imagem vazia")
+	}
+	scale := float64(thumbnailMaxSide) / float64(w)
+	if h > w {
+		scale = float64(thumbnailMaxSide) / float64(h)
+	}
+	if scale > 1 {
+		scale = 1
+	}
+	tw, th := max(1, int(float64(w)*scale)), max(1, int(float64(h)*scale))
+
+	dst := image.NewRGBA(image.Rect(0, 0, tw, th))
+	for y := 0; y < th; y++ {
+		y0, y1 := b.Min.Y+y*h/th, b.Min.Y+max((y+1)*h/th, y*h/th+1)
+		for x := 0; x < tw; x++ {
+			x0, x1 := b.Min.X+x*w/tw, b.Min.X+max((x+1)*w/tw, x*w/tw+1)
+			var r, g, bl, n uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					cr, cg, cb, _ := src.At(sx, sy).RGBA()
+					r, g, bl, n = r+uint64(cr), g+uint64(cg), bl+uint64(cb), n+1
+				}
+			}
+			dst.Set(x, y, color.RGBA64{R: uint16(r / n), G: uint16(g / n), B: uint16(bl / n), A: 0xffff})
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, dst, &jpeg.Options{Quality: 80}); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
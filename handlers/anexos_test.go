@@ -0,0 +1,129 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/png"
+	"mime/multipart"
+	"minhas_economias/database"
+	"minhas_economias/models"
+	"minhas_economias/storage"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func createAnexosTable(t *testing.T) {
+	db := database.GetDB()
+	db.Exec("DROP TABLE IF EXISTS anexos")
+	query := `CREATE TABLE anexos (id INTEGER PRIMARY KEY AUTOINCREMENT, movimentacao_id INTEGER NOT NULL, user_id INTEGER NOT NULL, nome_arquivo TEXT NOT NULL,
+		content_type TEXT NOT NULL, tamanho INTEGER NOT NULL, storage_key TEXT NOT NULL, thumbnail_key TEXT, created_at TIMESTAMP NOT NULL);`
+	if _, err := db.Exec(query); err != nil {
+		t.Fatalf("Falha ao criar a tabela de teste 'anexos': %v", err)
+	}
+}
+
+func uploadAnexo(r http.Handler, movID, filename string, content []byte) *httptest.ResponseRecorder {
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	part, _ := writer.CreateFormFile("arquivo", filename)
+	part.Write(content)
+	writer.Close()
+
+	req, _ := http.NewRequest("POST", "/movimentacoes/"+movID+"/anexos", &body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	req.Header.Set("Accept", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestAnexos_UploadThumbnailQuotaAndPurge(t *testing.T) {
+	setupTestDB(t)
+	createAnexosTable(t)
+	defer teardownTestDB()
+
+	store := storage.NewMemoryStore()
+	storage.SetStore(store)
+	defer storage.SetStore(nil)
+	t.Setenv("ATTACHMENT_QUOTA_MB", "1")
+
+	r := createWorkspaceRouter(testUserID, 0)
+	r.POST("/movimentacoes/:id/anexos", UploadAnexo)
+	r.GET("/anexos/:id/thumbnail", GetAnexoThumbnail)
+	r.DELETE("/lixeira/:id", PurgeMovimentacao)
+
+	// Imagem: gravada com miniatura reduzida.
+	img := image.NewRGBA(image.Rect(0, 0, 800, 400))
+	for x := 0; x < 800; x++ {
+		img.Set(x, x%400, color.RGBA{R: 200, A: 255})
+	}
+	var pngData bytes.Buffer
+	png.Encode(&pngData, img)
+	w := uploadAnexo(r, "1", "recibo.png", pngData.Bytes())
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Esperado status 201, obteve %d: %s", w.Code, w.Body.String())
+	}
+	var anexo models.Anexo
+	json.Unmarshal(w.Body.Bytes(), &anexo)
+	if anexo.ContentType != "image/png" || anexo.ThumbnailURL == "" {
+		t.Fatalf("Esperado anexo PNG com miniatura, obteve %+v", anexo)
+	}
+	w = performRequest(r, "GET", anexo.ThumbnailURL, nil, nil)
+	thumb, _, err := image.Decode(w.Body)
+	if err != nil || thumb.Bounds().Dx() != thumbnailMaxSide || thumb.Bounds().Dy() != thumbnailMaxSide/2 {
+		t.Errorf("Miniatura inválida (erro: %v)", err)
+	}
+
+	// Tipo validado pelo conteúdo, não pela extensão.
+	if w := uploadAnexo(r, "1", "nota.pdf", []byte("apenas texto")); w.Code != http.StatusUnsupportedMediaType {
+		t.Errorf("Esperado status 415 para arquivo que não é PDF, obteve %d", w.Code)
+	}
+	// Movimentação de outro escopo.
+	if w := uploadAnexo(r, "999", "nota.pdf", []byte("%PDF-1.4")); w.Code != http.StatusNotFound {
+		t.Errorf("Esperado status 404 para movimentação inexistente, obteve %d", w.Code)
+	}
+
+	// Cota de 1 MB por usuário.
+	pdf := append([]byte("%PDF-1.4\n"), make([]byte, 600*1024)...)
+	if w := uploadAnexo(r, "2", "fatura.pdf", pdf); w.Code != http.StatusCreated {
+		t.Fatalf("Esperado status 201 no primeiro PDF, obteve %d: %s", w.Code, w.Body.String())
+	}
+	if w := uploadAnexo(r, "2", "fatura2.pdf", pdf); w.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("Esperado status 413 ao exceder a cota, obteve %d", w.Code)
+	}
+
+	// Apagar a movimentação da lixeira remove os anexos e os arquivos.
+	database.GetDB().Exec(database.Rebind("UPDATE movimentacoes SET deleted_at = CURRENT_TIMESTAMP WHERE id = ?"), 1)
+	if w := performRequest(r, "DELETE", "/lixeira/1", nil, nil); w.Code != http.StatusOK {
+		t.Fatalf("Esperado status 200 ao apagar da lixeira, obteve %d", w.Code)
+	}
+	var restantes int
+	database.GetDB().QueryRow("SELECT COUNT(*) FROM anexos").Scan(&restantes)
+	if restantes != 1 || len(store.Objects) != 1 {
+		t.Errorf("Esperado restar só o PDF (1 linha, 1 arquivo), obteve %d linhas e %d arquivos", restantes, len(store.Objects))
+	}
+}
+
+func TestMakeThumbnail_RejectsDecompressionBomb(t *testing.T) {
+	var pngData bytes.Buffer
+	png.Encode(&pngData, image.NewGray(image.Rect(0, 0, 1, 1)))
+	data := pngData.Bytes()
+
+	// Reescreve o IHDR (após a assinatura de 8 bytes e o cabeçalho do chunk) declarando 100000x100000,
+	// com o CRC recalculado para que o cabeçalho continue válido.
+	binary.BigEndian.PutUint32(data[16:20], 100000)
+	binary.BigEndian.PutUint32(data[20:24], 100000)
+	binary.BigEndian.PutUint32(data[29:33], crc32.ChecksumIEEE(data[12:29]))
+
+	if cfg, err := png.DecodeConfig(bytes.NewReader(data)); err != nil || cfg.Width != 100000 {
+		t.Fatalf("PNG de teste inválido (cfg=%+v, err=%v)", cfg, err)
+	}
+	if _, err := makeThumbnail(data); err == nil {
+		t.Error("Esperado erro para imagem acima do limite de pixels")
+	}
+}
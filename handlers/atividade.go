@@ -0,0 +1,146 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"minhas_economias/database"
+	"minhas_economias/models"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	auditPageSize = 50
+	auditMaxLimit = 500
+)
+
+// auditFilter reúne os critérios de busca no log de auditoria. Campos vazios não filtram.
+type auditFilter struct {
+	Term       string // Texto livre em e-mail, ação e caminho
+	UserID     int64
+	UserEmail  string
+	EntityType string
+	EntityID   string
+	Action     string
+	From, To   string // AAAA-MM-DD, inclusivos
+	Limit      int
+}
+
+// auditFilterFromQuery lê os filtros comuns da query string (?q=&entity_type=&entity_id=&action=&from=&to=&limit=).
+func auditFilterFromQuery(c *gin.Context) auditFilter {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(auditPageSize)))
+	if err != nil || limit <= 0 {
+		limit = auditPageSize
+	}
+	if limit > auditMaxLimit {
+		limit = auditMaxLimit
+	}
+	return auditFilter{
+		Term:       strings.TrimSpace(c.Query("q")),
+		EntityType: c.Query("entity_type"),
+		EntityID:   c.Query("entity_id"),
+		Action:     c.Query("action"),
+		From:       c.Query("from"),
+		To:         c.Query("to"),
+		Limit:      limit,
+	}
+}
+
+// GetAtividadePage mostra o histórico de atividades do usuário logado, com o diff de cada alteração.
+// Também atende /api/user/activity, devolvendo JSON.
+func GetAtividadePage(c *gin.Context) {
+	user := c.MustGet("user").(*models.User)
+
+	filter := auditFilterFromQuery(c)
+	filter.UserID = user.ID
+
+	entries, err := searchAuditLog(filter)
+	if err != nil {
+		renderErrorPage(c, http.StatusInternalServerError, "Não foi possível carregar o histórico de atividades.", err)
+		return
+	}
+
+	if strings.Contains(c.GetHeader("Accept"), "application/json") || c.Request.URL.Path == "/api/user/activity" {
+		c.JSON(http.StatusOK, entries)
+		return
+	}
+
+	c.HTML(http.StatusOK, "atividade.html", gin.H{
+		"Titulo":  "Histórico de Atividades",
+		"User":    user,
+		"Entries": entries,
+		"Filter":  filter,
+		"EntityTypes": []struct{ Value, Label string }{
+			{"", "Todas"}, {"movimentacao", "Transações"}, {"investimento_nacional", "Ativos nacionais"},
+			{"investimento_internacional", "Ativos internacionais"}, {"perfil", "Perfil"},
+		},
+	})
+}
+
+func searchAuditLog(f auditFilter) ([]models.AuditLogEntry, error) {
+	var conditions []string
+	var args []interface{}
+
+	if f.Term != "" {
+		like := "LIKE"
+		if database.DriverName == "postgres" {
+			like = "ILIKE"
+		}
+		conditions = append(conditions, fmt.Sprintf("(user_email %[1]s ? OR action %[1]s ? OR path %[1]s ?)", like))
+		pattern := "%" + f.Term + "%"
+		args = append(args, pattern, pattern, pattern)
+	}
+	if f.UserID != 0 {
+		conditions = append(conditions, "user_id = ?")
+		args = append(args, f.UserID)
+	}
+	for column, value := range map[string]string{"user_email": f.UserEmail, "entity_type": f.EntityType, "entity_id": f.EntityID, "action": f.Action} {
+		if value != "" {
+			conditions = append(conditions, column+" = ?")
+			args = append(args, value)
+		}
+	}
+	if from, err := time.Parse("2006-01-02", f.From); err == nil {
+		conditions = append(conditions, "created_at >= ?")
+		args = append(args, from.UTC())
+	}
+	if to, err := time.Parse("2006-01-02", f.To); err == nil {
+		conditions = append(conditions, "created_at < ?")
+		args = append(args, to.AddDate(0, 0, 1).UTC())
+	}
+
+	query := `SELECT id, COALESCE(user_id, 0), COALESCE(user_email, ''), action, COALESCE(path, ''), COALESCE(status, 0), COALESCE(latency_ms, 0),
+		COALESCE(entity_type, ''), COALESCE(entity_id, ''), COALESCE(changes, ''), created_at FROM audit_logs`
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	query += " ORDER BY id DESC LIMIT ?"
+	args = append(args, f.Limit)
+
+	rows, err := database.GetDB().Query(database.Rebind(query), args...)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao consultar audit_logs: %w", err)
+	}
+	defer rows.Close()
+
+	var entries []models.AuditLogEntry
+	for rows.Next() {
+		var e models.AuditLogEntry
+		var changes string
+		if err := rows.Scan(&e.ID, &e.UserID, &e.UserEmail, &e.Action, &e.Path, &e.Status, &e.LatencyMs, &e.EntityType, &e.EntityID, &changes, &e.CreatedAt); err != nil {
+			return nil, fmt.Errorf("erro ao ler entrada de auditoria: %w", err)
+		}
+		if changes != "" {
+			if err := json.Unmarshal([]byte(changes), &e.Changes); err != nil {
+				log.Printf("Aviso: diff inválido na entrada de auditoria %d: %v", e.ID, err)
+			}
+		}
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
@@ -0,0 +1,69 @@
+package handlers
+
+import (
+	"encoding/json"
+	"minhas_economias/database"
+	"minhas_economias/middleware"
+	"minhas_economias/models"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// createAuditLogTable cria a tabela audit_logs no banco de teste.
+func createAuditLogTable(t *testing.T) {
+	db := database.GetDB()
+	db.Exec("DROP TABLE IF EXISTS audit_logs")
+	query := `CREATE TABLE audit_logs (id INTEGER PRIMARY KEY AUTOINCREMENT, user_id BIGINT, user_email TEXT, action TEXT NOT NULL, path TEXT, status INTEGER, latency_ms BIGINT,
+		entity_type TEXT, entity_id TEXT, changes TEXT, created_at TIMESTAMP NOT NULL);`
+	if _, err := db.Exec(query); err != nil {
+		t.Fatalf("Falha ao criar a tabela de teste 'audit_logs': %v", err)
+	}
+}
+
+func TestAuditLog_RecordsEntityDiffInActivityHistory(t *testing.T) {
+	setupTestDB(t)
+	createAuditLogTable(t)
+	defer teardownTestDB()
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(mockAuthMiddleware(), middleware.AuditLogger())
+	r.POST("/movimentacoes/update/:id", UpdateMovimentacao)
+	r.DELETE("/movimentacoes/:id", DeleteMovimentacao)
+	r.GET("/api/user/activity", GetAtividadePage)
+
+	form := url.Values{"data_ocorrencia": {"2025-01-15"}, "descricao": {"Salario"}, "valor": {"3500.00"}, "categoria": {"Renda"}, "conta": {"Banco A"}, "consolidado": {"on"}}
+	if w := performRequest(r, "POST", "/movimentacoes/update/2", form, nil); w.Code != http.StatusFound {
+		t.Fatalf("Esperado redirecionamento na atualização, obteve %d", w.Code)
+	}
+	if w := performRequest(r, "DELETE", "/movimentacoes/1", nil, nil); w.Code != http.StatusOK {
+		t.Fatalf("Esperado status 200 na exclusão, obteve %d", w.Code)
+	}
+
+	w := performRequest(r, "GET", "/api/user/activity?entity_type=movimentacao", nil, nil)
+	var entries []models.AuditLogEntry
+	if err := json.Unmarshal(w.Body.Bytes(), &entries); err != nil {
+		t.Fatalf("Resposta JSON inválida: %v (%s)", err, w.Body.String())
+	}
+	if len(entries) != 2 {
+		t.Fatalf("Esperado 2 alterações no histórico, obteve %d: %+v", len(entries), entries)
+	}
+
+	// A mais recente é a exclusão: todos os campos passam a nulo.
+	deleted := entries[0]
+	if deleted.EntityID != "1" || deleted.Changes["descricao"].Antes != "Aluguel" || deleted.Changes["descricao"].Depois != nil {
+		t.Errorf("Diff inesperado para a exclusão: %+v", deleted)
+	}
+
+	// A atualização registra apenas o campo que mudou.
+	updated := entries[1]
+	if updated.EntityID != "2" || len(updated.Changes) != 1 {
+		t.Fatalf("Esperado diff apenas do valor na atualização, obteve %+v", updated.Changes)
+	}
+	if change := updated.Changes["valor"]; change.Antes != 3000.0 || change.Depois != 3500.0 {
+		t.Errorf("Esperado valor de 3000 para 3500, obteve %+v", change)
+	}
+}
@@ -0,0 +1,123 @@
+package handlers
+
+import (
+	"bytes"
+	"errors"
+	"minhas_economias/backup"
+	"minhas_economias/database"
+	"minhas_economias/storage"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func createBackupTables(t *testing.T) {
+	db := database.GetDB()
+	for _, ddl := range []string{
+		"DROP TABLE IF EXISTS user_profiles",
+		"CREATE TABLE user_profiles (user_id BIGINT PRIMARY KEY, date_of_birth TEXT, gender TEXT, marital_status TEXT, children_count INTEGER, country TEXT, state TEXT, city TEXT)",
+		"DROP TABLE IF EXISTS investimentos_nacionais",
+		"CREATE TABLE investimentos_nacionais (user_id BIGINT NOT NULL, ticker TEXT NOT NULL, tipo TEXT, quantidade INTEGER NOT NULL, PRIMARY KEY (user_id, ticker))",
+		"DROP TABLE IF EXISTS investimentos_internacionais",
+		"CREATE TABLE investimentos_internacionais (user_id BIGINT NOT NULL, ticker TEXT NOT NULL, descricao TEXT, quantidade REAL NOT NULL, moeda TEXT, PRIMARY KEY (user_id, ticker))",
+		"DROP TABLE IF EXISTS operacoes_investimentos",
+		"CREATE TABLE operacoes_investimentos (id INTEGER PRIMARY KEY AUTOINCREMENT, user_id BIGINT NOT NULL, ticker TEXT NOT NULL, tipo_ativo TEXT NOT NULL, tipo TEXT NOT NULL, data TEXT NOT NULL, quantidade INTEGER NOT NULL, preco REAL NOT NULL, taxas REAL NOT NULL DEFAULT 0, corretora TEXT, created_at TIMESTAMP NOT NULL)",
+		"DROP TABLE IF EXISTS proventos",
+		"CREATE TABLE proventos (id INTEGER PRIMARY KEY AUTOINCREMENT, user_id BIGINT NOT NULL, ticker TEXT NOT NULL, tipo_ativo TEXT NOT NULL, tipo TEXT NOT NULL, data_ex TEXT NOT NULL, data_pagamento TEXT NOT NULL, valor_por_cota REAL NOT NULL, quantidade INTEGER NOT NULL, imposto_retido REAL NOT NULL DEFAULT 0, movimentacao_id BIGINT, created_at TIMESTAMP NOT NULL)",
+		"DROP TABLE IF EXISTS renda_fixa",
+		"CREATE TABLE renda_fixa (id INTEGER PRIMARY KEY AUTOINCREMENT, user_id BIGINT NOT NULL, nome TEXT NOT NULL, tipo TEXT NOT NULL, emissor TEXT, indexador TEXT NOT NULL, taxa REAL NOT NULL, valor_aplicado REAL NOT NULL, data_aplicacao TEXT NOT NULL, vencimento TEXT NOT NULL, liquidez TEXT NOT NULL, created_at TIMESTAMP NOT NULL)",
+		"DROP TABLE IF EXISTS investimentos_cripto",
+		"CREATE TABLE investimentos_cripto (user_id BIGINT NOT NULL, simbolo TEXT NOT NULL, descricao TEXT, quantidade TEXT NOT NULL, PRIMARY KEY (user_id, simbolo))",
+		"DROP TABLE IF EXISTS alocacao_alvo",
+		"CREATE TABLE alocacao_alvo (user_id BIGINT NOT NULL, classe TEXT NOT NULL, ticker TEXT NOT NULL DEFAULT '', percentual REAL NOT NULL, PRIMARY KEY (user_id, classe, ticker))",
+		"DROP TABLE IF EXISTS chat_history",
+		"CREATE TABLE chat_history (id INTEGER PRIMARY KEY AUTOINCREMENT, user_id BIGINT NOT NULL, role TEXT NOT NULL, content TEXT NOT NULL, created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP)",
+	} {
+		if _, err := db.Exec(ddl); err != nil {
+			t.Fatalf("Falha ao preparar tabelas do backup: %v", err)
+		}
+	}
+	createAnexosTable(t)
+}
+
+func TestBackup_DownloadAndRestoreIntoAnotherUser(t *testing.T) {
+	setupTestDB(t)
+	createBackupTables(t)
+	defer teardownTestDB()
+
+	store := storage.NewMemoryStore()
+	storage.SetStore(store)
+	defer storage.SetStore(nil)
+
+	db := database.GetDB()
+	db.Exec(database.Rebind("INSERT INTO users (id, email, password_hash) VALUES (?, ?, ?)"), 2, "destino@user.com", "x")
+	db.Exec(database.Rebind("INSERT INTO contas (user_id, nome, saldo_inicial) VALUES (?, ?, ?)"), testUserID, "Banco A", 100.0)
+	db.Exec(database.Rebind("INSERT INTO investimentos_nacionais (user_id, ticker, tipo, quantidade) VALUES (?, ?, ?, ?)"), testUserID, "PETR4", "Ação", 10)
+	db.Exec(database.Rebind("INSERT INTO operacoes_investimentos (user_id, ticker, tipo_ativo, tipo, data, quantidade, preco, taxas, corretora, created_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)"),
+		testUserID, "PETR4", "ACAO", "C", "2025-01-10", 10, 30.5, 4.9, "XP", time.Now())
+	db.Exec(database.Rebind("INSERT INTO proventos (user_id, ticker, tipo_ativo, tipo, data_ex, data_pagamento, valor_por_cota, quantidade, imposto_retido, movimentacao_id, created_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)"),
+		testUserID, "PETR4", "ACAO", "JCP", "2025-01-20", "2025-01-31", 0.5, 10, 0.75, 2, time.Now())
+	db.Exec(database.Rebind("INSERT INTO chat_history (user_id, role, content) VALUES (?, ?, ?)"), testUserID, "user", "Quanto gastei?")
+	db.Exec(database.Rebind("INSERT INTO user_profiles (user_id, date_of_birth, city) VALUES (?, ?, ?)"), testUserID, "1990-05-01", "Recife")
+
+	r := createWorkspaceRouter(testUserID, 0)
+	r.POST("/movimentacoes/:id/anexos", UploadAnexo)
+	r.GET("/configuracoes/backup", DownloadBackup)
+	if w := uploadAnexo(r, "2", "holerite.pdf", []byte("%PDF-1.4 holerite")); w.Code != http.StatusCreated {
+		t.Fatalf("Esperado status 201 no upload, obteve %d: %s", w.Code, w.Body.String())
+	}
+
+	w := performRequest(r, "GET", "/configuracoes/backup", nil, nil)
+	if w.Code != http.StatusOK || w.Header().Get("Content-Type") != "application/zip" {
+		t.Fatalf("Esperado .zip com status 200, obteve %d (%s)", w.Code, w.Header().Get("Content-Type"))
+	}
+	data := w.Body.Bytes()
+	archive, zr, err := backup.Read(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatalf("Backup inválido: %v", err)
+	}
+	if archive.Version != backup.FormatVersion || len(archive.Movimentacoes) != 2 || len(archive.Anexos) != 1 || archive.Profile == nil || archive.Profile.City != "Recife" {
+		t.Fatalf("Conteúdo inesperado no backup: %+v", archive)
+	}
+
+	// Um destino que só tem histórico do chat também já tem dados: sem -replace, a restauração o duplicaria.
+	db.Exec(database.Rebind("INSERT INTO chat_history (user_id, role, content) VALUES (?, ?, ?)"), 2, "user", "Olá")
+	if _, err := backup.Restore(db, store, archive, zr, 2, backup.RestoreOptions{}); !errors.Is(err, backup.ErrTargetNotEmpty) {
+		t.Errorf("Esperado ErrTargetNotEmpty para destino com histórico do chat, obteve %v", err)
+	}
+	db.Exec(database.Rebind("DELETE FROM chat_history WHERE user_id = ?"), 2)
+
+	summary, err := backup.Restore(db, store, archive, zr, 2, backup.RestoreOptions{})
+	if err != nil {
+		t.Fatalf("Restauração falhou: %v", err)
+	}
+	if summary.Movimentacoes != 2 || summary.Contas != 1 || summary.InvestimentosNacionais != 1 || summary.OperacoesInvestimentos != 1 || summary.Proventos != 1 || summary.ChatHistory != 1 || summary.Anexos != 1 {
+		t.Errorf("Resumo inesperado: %s", summary)
+	}
+	var anexosDestino int
+	db.QueryRow(database.Rebind(`SELECT COUNT(*) FROM anexos a JOIN movimentacoes m ON m.id = a.movimentacao_id
+		WHERE m.user_id = ? AND m.descricao = 'Salario' AND a.user_id = ?`), 2, 2).Scan(&anexosDestino)
+	if anexosDestino != 1 {
+		t.Errorf("Esperado o anexo ligado à nova movimentação 'Salario' do usuário 2, obteve %d", anexosDestino)
+	}
+	var proventosDestino int
+	db.QueryRow(database.Rebind(`SELECT COUNT(*) FROM proventos p JOIN movimentacoes m ON m.id = p.movimentacao_id
+		WHERE m.user_id = ? AND m.descricao = 'Salario' AND p.user_id = ?`), 2, 2).Scan(&proventosDestino)
+	if proventosDestino != 1 {
+		t.Errorf("Esperado o provento ligado à nova movimentação 'Salario' do usuário 2, obteve %d", proventosDestino)
+	}
+
+	// Sem -replace, restaurar de novo no mesmo usuário é recusado.
+	if _, err := backup.Restore(db, store, archive, zr, 2, backup.RestoreOptions{}); !errors.Is(err, backup.ErrTargetNotEmpty) {
+		t.Errorf("Esperado ErrTargetNotEmpty, obteve %v", err)
+	}
+	if summary, err := backup.Restore(db, store, archive, zr, 2, backup.RestoreOptions{Replace: true}); err != nil || summary.Movimentacoes != 2 {
+		t.Errorf("Restauração com substituição falhou: %v (%s)", err, summary)
+	}
+
+	// Referências quebradas são detectadas antes de gravar qualquer coisa.
+	archive.Anexos[0].MovimentacaoID = 999
+	if err := archive.Validate(zr); err == nil {
+		t.Error("Esperado erro de validação para anexo sem movimentação")
+	}
+}
@@ -1,9 +1,18 @@
 package handlers
 
 import (
+	"bytes"
+	"fmt"
+	"log"
+	"minhas_economias/auth"
+	"minhas_economias/backup"
 	"minhas_economias/database"
+	"minhas_economias/households"
+	"minhas_economias/middleware"
 	"minhas_economias/models"
+	"minhas_economias/storage"
 	"net/http"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
@@ -19,13 +28,59 @@ func GetConfiguracoesPage(c *gin.Context) {
 		return
 	}
 
+	// Sessões ativas (dispositivos logados)
+	sessions, err := auth.ListUserSessions(user.ID, c.GetString("sessionID"))
+	if err != nil {
+		log.Printf("Aviso: não foi possível listar as sessões do usuário %d: %v", user.ID, err)
+	}
+
+	// Lares compartilhados, com os membros de cada um
+	householdList := append([]models.Household(nil), user.Workspaces...)
+	for i := range householdList {
+		if householdList[i].Members, err = households.ListMembers(householdList[i].ID); err != nil {
+			log.Printf("Aviso: não foi possível listar os membros do lar %d: %v", householdList[i].ID, err)
+		}
+	}
+
 	c.HTML(http.StatusOK, "configuracoes.html", gin.H{
 		"Titulo":      "Configurações",
 		"User":        user,
 		"UserProfile": userProfile, // Passa o perfil para o template
+		"Sessions":    sessions,
+		"Households":  householdList,
 	})
 }
 
+// DownloadBackup gera o backup completo do usuário (.zip com backup.json e os anexos).
+// O arquivo é montado em memória para que um erro no meio não entregue um backup truncado.
+func DownloadBackup(c *gin.Context) {
+	user := c.MustGet("user").(*models.User)
+
+	var buf bytes.Buffer
+	if _, err := backup.Write(database.GetDB(), storage.Current(), user.ID, &buf); err != nil {
+		renderErrorPage(c, http.StatusInternalServerError, "Erro ao gerar o backup.", err)
+		return
+	}
+	middleware.RecordAuditEvent(user.Email, "BACKUP_DOWNLOAD", c.Request.URL.Path, http.StatusOK, 0)
+
+	filename := fmt.Sprintf("minhas_economias_backup_%s.zip", time.Now().Format("20060102"))
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	c.Data(http.StatusOK, "application/zip", buf.Bytes())
+}
+
+// LogoutOtherSessions encerra todas as sessões do usuário, exceto a do dispositivo atual.
+func LogoutOtherSessions(c *gin.Context) {
+	userID := c.MustGet("userID").(int64)
+
+	revoked, err := auth.RevokeOtherSessions(userID, c.GetString("sessionID"))
+	if err != nil {
+		renderErrorPage(c, http.StatusInternalServerError, "Erro ao encerrar as outras sessões.", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Outras sessões encerradas com sucesso!", "revoked": revoked})
+}
+
 // UpdateUserSettingsPayload é o struct para o corpo da requisição de atualização do tema.
 type UpdateUserSettingsPayload struct {
 	DarkMode bool `json:"dark_mode"`
@@ -0,0 +1,175 @@
+package handlers
+
+import (
+	"errors"
+	"minhas_economias/households"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ledgerScope identifica o livro-caixa ativo da requisição: o pessoal do usuário
+// ou o de um lar compartilhado (HouseholdID != 0, com OwnerID sendo o proprietário do lar).
+type ledgerScope struct {
+	UserID      int64
+	HouseholdID int64
+	OwnerID     int64
+}
+
+// currentLedger monta o escopo a partir do contexto preenchido pelo middleware de autenticação.
+func currentLedger(c *gin.Context) ledgerScope {
+	scope := ledgerScope{UserID: c.MustGet("userID").(int64)}
+	if id, ok := c.Get("householdID"); ok {
+		scope.HouseholdID = id.(int64)
+	}
+	if id, ok := c.Get("householdOwnerID"); ok {
+		scope.OwnerID = id.(int64)
+	}
+	return scope
+}
+
+// where retorna a condição SQL que restringe movimentações e contas ao escopo.
+// Sempre tem um único placeholder, preenchido por arg().
+func (s ledgerScope) where() string {
+	if s.HouseholdID != 0 {
+		return "household_id = ?"
+	}
+	return "user_id = ? AND household_id IS NULL"
+}
+
+// activeWhere é o where() restrito às movimentações fora da lixeira. Toda listagem,
+// saldo, relatório e exportação de movimentações deve usá-lo.
+func (s ledgerScope) activeWhere() string {
+	return s.where() + " AND deleted_at IS NULL"
+}
+
+// trashedWhere é o where() restrito às movimentações na lixeira.
+func (s ledgerScope) trashedWhere() string {
+	return s.where() + " AND deleted_at IS NOT NULL"
+}
+
+// arg retorna o valor do placeholder de where().
+func (s ledgerScope) arg() int64 {
+	if s.HouseholdID != 0 {
+		return s.HouseholdID
+	}
+	return s.UserID
+}
+
+// rowUserID retorna o valor da coluna user_id para novos registros. As linhas de um lar
+// pertencem ao proprietário do lar, não ao membro que as lançou (registrado em created_by),
+// para que a exclusão da conta de um membro não leve junto os dados compartilhados.
+func (s ledgerScope) rowUserID() int64 {
+	if s.HouseholdID != 0 && s.OwnerID != 0 {
+		return s.OwnerID
+	}
+	return s.UserID
+}
+
+// householdValue retorna o valor da coluna household_id para novos registros (NULL no livro pessoal).
+func (s ledgerScope) householdValue() interface{} {
+	if s.HouseholdID != 0 {
+		return s.HouseholdID
+	}
+	return nil
+}
+
+// CreateHouseholdPayload é o corpo da requisição de criação de um lar.
+type CreateHouseholdPayload struct {
+	Name string `json:"name"`
+}
+
+// CreateHousehold cria um lar compartilhado tendo o usuário logado como proprietário.
+func CreateHousehold(c *gin.Context) {
+	userID := c.MustGet("userID").(int64)
+
+	var payload CreateHouseholdPayload
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		renderErrorPage(c, http.StatusBadRequest, "Dados inválidos.", err)
+		return
+	}
+
+	id, err := households.Create(userID, payload.Name)
+	if err != nil {
+		renderErrorPage(c, http.StatusBadRequest, err.Error(), err)
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"message": "Lar criado com sucesso!", "id": id})
+}
+
+// AddHouseholdMemberPayload é o corpo da requisição para adicionar um membro.
+type AddHouseholdMemberPayload struct {
+	Email string `json:"email"`
+	Role  string `json:"role"`
+}
+
+// AddHouseholdMember adiciona um usuário ao lar (ou altera seu papel).
+func AddHouseholdMember(c *gin.Context) {
+	userID := c.MustGet("userID").(int64)
+	householdID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		renderErrorPage(c, http.StatusBadRequest, "ID de lar inválido.", err)
+		return
+	}
+
+	var payload AddHouseholdMemberPayload
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		renderErrorPage(c, http.StatusBadRequest, "Dados inválidos.", err)
+		return
+	}
+
+	if err := households.AddMember(userID, householdID, payload.Email, payload.Role); err != nil {
+		renderErrorPage(c, householdErrorStatus(err), err.Error(), err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Membro adicionado com sucesso!"})
+}
+
+// RemoveHouseholdMember remove um membro do lar; usado também para o próprio usuário sair.
+func RemoveHouseholdMember(c *gin.Context) {
+	userID := c.MustGet("userID").(int64)
+	householdID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		renderErrorPage(c, http.StatusBadRequest, "ID de lar inválido.", err)
+		return
+	}
+	memberID, err := strconv.ParseInt(c.Param("userID"), 10, 64)
+	if err != nil {
+		renderErrorPage(c, http.StatusBadRequest, "ID de membro inválido.", err)
+		return
+	}
+
+	if err := households.RemoveMember(userID, householdID, memberID); err != nil {
+		renderErrorPage(c, householdErrorStatus(err), err.Error(), err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Membro removido do lar."})
+}
+
+// DeleteHousehold exclui o lar e todas as suas contas e transações.
+func DeleteHousehold(c *gin.Context) {
+	userID := c.MustGet("userID").(int64)
+	householdID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		renderErrorPage(c, http.StatusBadRequest, "ID de lar inválido.", err)
+		return
+	}
+
+	if err := households.Delete(userID, householdID); err != nil {
+		renderErrorPage(c, householdErrorStatus(err), err.Error(), err)
+		return
+	}
+	cleanupOrphanAnexos()
+	c.JSON(http.StatusOK, gin.H{"message": "Lar excluído com sucesso."})
+}
+
+func householdErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, households.ErrNotMember):
+		return http.StatusNotFound
+	case errors.Is(err, households.ErrForbidden):
+		return http.StatusForbidden
+	}
+	return http.StatusBadRequest
+}
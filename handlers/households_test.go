@@ -0,0 +1,152 @@
+package handlers
+
+import (
+	"encoding/json"
+	"minhas_economias/database"
+	"minhas_economias/households"
+	"minhas_economias/models"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+const testSpouseID int64 = 1000
+
+// setupHouseholdTestDB estende o banco de teste com as tabelas de lares e um segundo usuário.
+func setupHouseholdTestDB(t *testing.T) int64 {
+	setupTestDB(t)
+	db := database.GetDB()
+	db.Exec("DROP TABLE IF EXISTS household_members")
+	db.Exec("DROP TABLE IF EXISTS households")
+
+	createTablesSQL := []string{
+		`CREATE TABLE households (id INTEGER PRIMARY KEY AUTOINCREMENT, name TEXT NOT NULL, owner_id BIGINT NOT NULL, created_at TIMESTAMP NOT NULL);`,
+		`CREATE TABLE household_members (household_id BIGINT NOT NULL, user_id BIGINT NOT NULL, role TEXT NOT NULL, created_at TIMESTAMP NOT NULL, PRIMARY KEY (household_id, user_id));`,
+	}
+	for _, query := range createTablesSQL {
+		if _, err := db.Exec(query); err != nil {
+			t.Fatalf("Falha ao criar tabelas de lares: %v", err)
+		}
+	}
+	if _, err := db.Exec(database.Rebind("INSERT INTO users (id, email, password_hash) VALUES (?, ?, ?)"), testSpouseID, "spouse@user.com", "x"); err != nil {
+		t.Fatalf("Falha ao inserir segundo usuário: %v", err)
+	}
+
+	householdID, err := households.Create(testUserID, "Casa")
+	if err != nil {
+		t.Fatalf("Falha ao criar lar de teste: %v", err)
+	}
+	return householdID
+}
+
+// createWorkspaceRouter simula o middleware de autenticação com um lar ativo.
+func createWorkspaceRouter(userID, householdID int64) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		c.Set("userID", userID)
+		c.Set("user", &models.User{ID: userID})
+		if householdID != 0 {
+			c.Set("householdID", householdID)
+			if h, err := households.GetMembership(householdID, userID); err == nil {
+				c.Set("householdRole", h.Role)
+				c.Set("householdOwnerID", h.OwnerID)
+			}
+		}
+		c.Next()
+	})
+	r.GET("/api/movimentacoes", GetTransacoesPage)
+	r.POST("/movimentacoes", AddMovimentacao)
+	r.DELETE("/movimentacoes/:id", DeleteMovimentacao)
+	return r
+}
+
+func listMovimentacoes(t *testing.T, r http.Handler) []models.Movimentacao {
+	t.Helper()
+	w := performRequest(r, "GET", "/api/movimentacoes", nil, nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Esperado status 200 ao listar movimentações, obteve %d", w.Code)
+	}
+	var body struct {
+		Movimentacoes []models.Movimentacao `json:"movimentacoes"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatalf("Resposta JSON inválida: %v", err)
+	}
+	return body.Movimentacoes
+}
+
+func TestSharedLedger_MembersShareTransactionsAndPersonalStaysPrivate(t *testing.T) {
+	householdID := setupHouseholdTestDB(t)
+	defer teardownTestDB()
+
+	if err := households.AddMember(testUserID, householdID, "spouse@user.com", models.RoleEditor); err != nil {
+		t.Fatalf("Falha ao adicionar membro: %v", err)
+	}
+
+	// O cônjuge lança uma transação no lar compartilhado.
+	spouseShared := createWorkspaceRouter(testSpouseID, householdID)
+	form := url.Values{"data_ocorrencia": {"2025-01-20"}, "descricao": {"Mercado"}, "valor": {"-250.00"}, "conta": {"Conta Conjunta"}}
+	if w := performRequest(spouseShared, "POST", "/movimentacoes", form, nil); w.Code != http.StatusFound {
+		t.Fatalf("Esperado redirecionamento ao lançar no lar, obteve %d", w.Code)
+	}
+
+	// O proprietário vê a transação no lar, com o autor registrado...
+	shared := listMovimentacoes(t, createWorkspaceRouter(testUserID, householdID))
+	if len(shared) != 1 || shared[0].Descricao != "Mercado" || shared[0].CriadoPor != "spouse@user.com" {
+		t.Fatalf("Esperado ver a transação compartilhada lançada pelo cônjuge, obteve %+v", shared)
+	}
+
+	// A linha pertence ao lar (user_id do proprietário); o autor fica em created_by.
+	var rowUserID, createdBy int64
+	database.GetDB().QueryRow("SELECT user_id, created_by FROM movimentacoes WHERE household_id = ?", householdID).Scan(&rowUserID, &createdBy)
+	if rowUserID != testUserID || createdBy != testSpouseID {
+		t.Errorf("Esperado user_id do proprietário (%d) e created_by do cônjuge (%d), obteve %d e %d", testUserID, testSpouseID, rowUserID, createdBy)
+	}
+
+	// ...mas ela não aparece no livro pessoal de ninguém, e o pessoal do proprietário continua privado.
+	if personal := listMovimentacoes(t, createWorkspaceRouter(testUserID, 0)); len(personal) != 2 {
+		t.Errorf("Esperado apenas as 2 transações pessoais do proprietário, obteve %d", len(personal))
+	}
+	if personal := listMovimentacoes(t, createWorkspaceRouter(testSpouseID, 0)); len(personal) != 0 {
+		t.Errorf("Esperado livro pessoal vazio para o cônjuge, obteve %d", len(personal))
+	}
+
+	// Dentro do lar não é possível apagar transações pessoais de outro membro.
+	if w := performRequest(spouseShared, "DELETE", "/movimentacoes/1", nil, nil); w.Code != http.StatusOK {
+		t.Fatalf("Esperado status 200 na exclusão, obteve %d", w.Code)
+	}
+	var count int
+	database.GetDB().QueryRow("SELECT COUNT(*) FROM movimentacoes WHERE id = 1").Scan(&count)
+	if count != 1 {
+		t.Error("A transação pessoal do proprietário não deveria ser apagada a partir do lar")
+	}
+}
+
+func TestHouseholds_OnlyOwnerManagesMembers(t *testing.T) {
+	householdID := setupHouseholdTestDB(t)
+	defer teardownTestDB()
+
+	if err := households.AddMember(testSpouseID, householdID, "test@user.com", models.RoleViewer); err != households.ErrNotMember {
+		t.Errorf("Esperado ErrNotMember para quem não participa do lar, obteve %v", err)
+	}
+	if err := households.AddMember(testUserID, householdID, "spouse@user.com", models.RoleViewer); err != nil {
+		t.Fatalf("Falha ao adicionar leitor: %v", err)
+	}
+	if err := households.AddMember(testSpouseID, householdID, "spouse@user.com", models.RoleEditor); err != households.ErrForbidden {
+		t.Errorf("Esperado ErrForbidden para leitor promovendo a si mesmo, obteve %v", err)
+	}
+	if err := households.RemoveMember(testSpouseID, householdID, testUserID); err == nil {
+		t.Error("O proprietário não deveria poder ser removido do lar")
+	}
+
+	// Qualquer membro pode sair do lar.
+	if err := households.RemoveMember(testSpouseID, householdID, testSpouseID); err != nil {
+		t.Fatalf("Esperado que o membro pudesse sair do lar, obteve %v", err)
+	}
+	if _, err := households.GetMembership(householdID, testSpouseID); err != households.ErrNotMember {
+		t.Errorf("Esperado que o membro não participasse mais do lar, obteve %v", err)
+	}
+}
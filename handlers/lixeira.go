@@ -0,0 +1,173 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"minhas_economias/config"
+	"minhas_economias/database"
+	"minhas_economias/middleware"
+	"minhas_economias/models"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// DefaultTrashRetentionDays é o tempo que uma movimentação fica na lixeira quando TRASH_RETENTION_DAYS não é definido.
+const DefaultTrashRetentionDays = 30
+
+// TrashRetentionFromEnv lê TRASH_RETENTION_DAYS. Zero mantém os itens na lixeira até serem apagados manualmente.
+func TrashRetentionFromEnv() time.Duration {
+	return time.Duration(config.IntFromEnv("TRASH_RETENTION_DAYS", DefaultTrashRetentionDays, 0)) * 24 * time.Hour
+}
+
+// GetLixeiraPage lista as movimentações excluídas do livro-caixa ativo. Também atende /api/lixeira (JSON).
+func GetLixeiraPage(c *gin.Context) {
+	scope := currentLedger(c)
+	user := c.MustGet("user").(*models.User)
+
+	query := fmt.Sprintf("SELECT id, data_ocorrencia, descricao, valor, categoria, conta, consolidado, deleted_at FROM %s WHERE %s ORDER BY deleted_at DESC", database.TableName, scope.trashedWhere())
+	rows, err := database.GetDB().Query(database.Rebind(query), scope.arg())
+	if err != nil {
+		renderErrorPage(c, http.StatusInternalServerError, "Erro ao carregar a lixeira.", err)
+		return
+	}
+	defer rows.Close()
+
+	var movimentacoes []models.Movimentacao
+	for rows.Next() {
+		var mov models.Movimentacao
+		var rawData interface{}
+		var deletedAt time.Time
+		if err := rows.Scan(&mov.ID, &rawData, &mov.Descricao, &mov.Valor, &mov.Categoria, &mov.Conta, &mov.Consolidado, &deletedAt); err != nil {
+			renderErrorPage(c, http.StatusInternalServerError, "Erro ao ler a lixeira.", err)
+			return
+		}
+		mov.DataOcorrencia = scanDate(rawData)
+		mov.DeletedAt = &deletedAt
+		movimentacoes = append(movimentacoes, mov)
+	}
+	if err := rows.Err(); err != nil {
+		renderErrorPage(c, http.StatusInternalServerError, "Erro ao ler a lixeira.", err)
+		return
+	}
+
+	retentionDays := int(TrashRetentionFromEnv().Hours() / 24)
+	if strings.Contains(c.GetHeader("Accept"), "application/json") || c.Request.URL.Path == "/api/lixeira" {
+		c.JSON(http.StatusOK, gin.H{"movimentacoes": movimentacoes, "retention_days": retentionDays})
+		return
+	}
+
+	c.HTML(http.StatusOK, "lixeira.html", gin.H{
+		"Titulo":        "Lixeira",
+		"User":          user,
+		"Movimentacoes": movimentacoes,
+		"RetentionDays": retentionDays,
+	})
+}
+
+// RestoreMovimentacao tira a movimentação da lixeira.
+func RestoreMovimentacao(c *gin.Context) {
+	scope := currentLedger(c)
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		renderErrorPage(c, http.StatusBadRequest, "O ID da transação é inválido.", err)
+		return
+	}
+
+	query := fmt.Sprintf("UPDATE %s SET deleted_at = NULL WHERE id = ? AND %s", database.TableName, scope.trashedWhere())
+	result, err := database.GetDB().Exec(database.Rebind(query), id, scope.arg())
+	if err != nil {
+		renderErrorPage(c, http.StatusInternalServerError, "Erro ao restaurar a movimentação.", err)
+		return
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		renderErrorPage(c, http.StatusNotFound, "Movimentação não encontrada na lixeira.", nil)
+		return
+	}
+	if restored, err := loadMovimentacao(scope, id); err == nil {
+		middleware.SetAuditChange(c, "movimentacao", id, nil, restored)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Movimentação restaurada com sucesso!"})
+}
+
+// PurgeMovimentacao apaga definitivamente uma movimentação que já está na lixeira (e seus anexos).
+func PurgeMovimentacao(c *gin.Context) {
+	scope := currentLedger(c)
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		renderErrorPage(c, http.StatusBadRequest, "O ID da transação é inválido.", err)
+		return
+	}
+
+	query := fmt.Sprintf("DELETE FROM %s WHERE id = ? AND %s", database.TableName, scope.trashedWhere())
+	result, err := database.GetDB().Exec(database.Rebind(query), id, scope.arg())
+	if err != nil {
+		renderErrorPage(c, http.StatusInternalServerError, "Erro ao apagar a movimentação.", err)
+		return
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		renderErrorPage(c, http.StatusNotFound, "Movimentação não encontrada na lixeira.", nil)
+		return
+	}
+	cleanupOrphanAnexos()
+
+	c.JSON(http.StatusOK, gin.H{"message": "Movimentação apagada definitivamente."})
+}
+
+// EmptyLixeira apaga definitivamente todas as movimentações da lixeira do livro-caixa ativo.
+func EmptyLixeira(c *gin.Context) {
+	scope := currentLedger(c)
+
+	query := fmt.Sprintf("DELETE FROM %s WHERE %s", database.TableName, scope.trashedWhere())
+	result, err := database.GetDB().Exec(database.Rebind(query), scope.arg())
+	if err != nil {
+		renderErrorPage(c, http.StatusInternalServerError, "Erro ao esvaziar a lixeira.", err)
+		return
+	}
+	removed, _ := result.RowsAffected()
+	cleanupOrphanAnexos()
+
+	c.JSON(http.StatusOK, gin.H{"message": fmt.Sprintf("%d movimentações apagadas definitivamente.", removed), "removed": removed})
+}
+
+// PurgeExpiredTrash apaga as movimentações que estão na lixeira há mais tempo que a retenção.
+func PurgeExpiredTrash(retention time.Duration) (int64, error) {
+	if retention <= 0 {
+		return 0, nil
+	}
+	query := database.Rebind(fmt.Sprintf("DELETE FROM %s WHERE deleted_at IS NOT NULL AND deleted_at < ?", database.TableName))
+	result, err := database.GetDB().Exec(query, time.Now().UTC().Add(-retention))
+	if err != nil {
+		return 0, fmt.Errorf("erro ao limpar a lixeira: %w", err)
+	}
+	removed, err := result.RowsAffected()
+	if removed > 0 {
+		cleanupOrphanAnexos()
+	}
+	return removed, err
+}
+
+// StartTrashPurge executa a limpeza da lixeira na inicialização e depois a cada 24 horas.
+func StartTrashPurge(retention time.Duration) {
+	if retention <= 0 {
+		log.Println("Limpeza automática da lixeira desativada (TRASH_RETENTION_DAYS=0).")
+		return
+	}
+	purge := func() {
+		if removed, err := PurgeExpiredTrash(retention); err != nil {
+			log.Printf("Aviso: %v", err)
+		} else if removed > 0 {
+			log.Printf("Lixeira: %d movimentações com mais de %d dias apagadas definitivamente.", removed, int(retention.Hours()/24))
+		}
+	}
+	go func() {
+		purge()
+		for range time.Tick(24 * time.Hour) {
+			purge()
+		}
+	}()
+}
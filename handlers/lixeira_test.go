@@ -0,0 +1,73 @@
+package handlers
+
+import (
+	"encoding/json"
+	"minhas_economias/database"
+	"minhas_economias/models"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func saldoBancoA(t *testing.T) float64 {
+	t.Helper()
+	saldos, err := calculateAccountBalances(ledgerScope{UserID: testUserID})
+	if err != nil {
+		t.Fatalf("Falha ao calcular saldos: %v", err)
+	}
+	for _, s := range saldos {
+		if s.Nome == "Banco A" {
+			return s.SaldoAtual
+		}
+	}
+	return 0
+}
+
+func TestLixeira_SoftDeleteRestoreAndPurge(t *testing.T) {
+	setupTestDB(t)
+	defer teardownTestDB()
+
+	r := createWorkspaceRouter(testUserID, 0)
+	r.GET("/api/lixeira", GetLixeiraPage)
+	r.POST("/movimentacoes/:id/restore", RestoreMovimentacao)
+
+	if w := performRequest(r, "DELETE", "/movimentacoes/1", nil, nil); w.Code != http.StatusOK {
+		t.Fatalf("Esperado status 200 na exclusão, obteve %d", w.Code)
+	}
+
+	// A movimentação excluída some da listagem e dos saldos, mas aparece na lixeira.
+	if movs := listMovimentacoes(t, r); len(movs) != 1 || movs[0].ID != 2 {
+		t.Errorf("Esperado apenas a movimentação 2 na listagem, obteve %+v", movs)
+	}
+	if saldo := saldoBancoA(t); saldo != 3000 {
+		t.Errorf("Esperado saldo 3000 sem a movimentação excluída, obteve %.2f", saldo)
+	}
+	w := performRequest(r, "GET", "/api/lixeira", nil, nil)
+	var lixeira struct {
+		Movimentacoes []models.Movimentacao `json:"movimentacoes"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &lixeira); err != nil {
+		t.Fatalf("Resposta JSON inválida: %v", err)
+	}
+	if len(lixeira.Movimentacoes) != 1 || lixeira.Movimentacoes[0].ID != 1 || lixeira.Movimentacoes[0].DeletedAt == nil {
+		t.Fatalf("Esperado a movimentação 1 na lixeira, obteve %+v", lixeira.Movimentacoes)
+	}
+
+	// Restaurar devolve a movimentação aos saldos.
+	if w := performRequest(r, "POST", "/movimentacoes/1/restore", nil, nil); w.Code != http.StatusOK {
+		t.Fatalf("Esperado status 200 ao restaurar, obteve %d", w.Code)
+	}
+	if saldo := saldoBancoA(t); saldo != 1500 {
+		t.Errorf("Esperado saldo 1500 após restaurar, obteve %.2f", saldo)
+	}
+
+	// A limpeza automática só apaga o que passou do período de retenção.
+	performRequest(r, "DELETE", "/movimentacoes/1", nil, nil)
+	if removed, err := PurgeExpiredTrash(24 * time.Hour); err != nil || removed != 0 {
+		t.Errorf("Nada deveria ser apagado antes do fim da retenção, removidos %d (erro: %v)", removed, err)
+	}
+	database.GetDB().Exec(database.Rebind("UPDATE movimentacoes SET deleted_at = ? WHERE id = ?"), time.Now().UTC().Add(-48*time.Hour), 1)
+	if removed, err := PurgeExpiredTrash(24 * time.Hour); err != nil || removed != 1 {
+		t.Errorf("Esperado apagar 1 movimentação expirada, removidos %d (erro: %v)", removed, err)
+	}
+}
@@ -24,7 +24,7 @@ import (
 
 // AddTransferencia handles the creation of a transfer between two accounts.
 func AddTransferencia(c *gin.Context) {
-	userID := c.MustGet("userID").(int64)
+	scope := currentLedger(c)
 
 	// Bind form data
 	dataOcorrencia := c.PostForm("data_ocorrencia")
@@ -56,7 +56,10 @@ func AddTransferencia(c *gin.Context) {
 	}
 
 	// Prepare statement inside transaction
-	query := fmt.Sprintf(`INSERT INTO %s (user_id, data_ocorrencia, descricao, valor, categoria, conta, consolidado) VALUES (?, ?, ?, ?, ?, ?, ?)`, database.TableName)
+	query := fmt.Sprintf(`INSERT INTO %s (user_id, household_id, created_by, data_ocorrencia, descricao, valor, categoria, conta, consolidado) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`, database.TableName)
+	if database.DriverName == "postgres" {
+		query += " RETURNING id"
+	}
 	stmt, err := tx.Prepare(database.Rebind(query))
 	if err != nil {
 		tx.Rollback()
@@ -68,7 +71,8 @@ func AddTransferencia(c *gin.Context) {
 	// 1. Débito da conta de origem (valor negativo)
 	valorNegativo := -math.Abs(valor)
 	descricaoOrigem := fmt.Sprintf("Transferência para %s: %s", contaDestino, descricao)
-	if _, err := stmt.Exec(userID, dataOcorrencia, descricaoOrigem, valorNegativo, "Transferência", contaOrigem, true); err != nil {
+	saida := models.Movimentacao{DataOcorrencia: dataOcorrencia, Descricao: descricaoOrigem, Valor: valorNegativo, Categoria: "Transferência", Conta: contaOrigem, Consolidado: true}
+	if saida.ID, err = insertReturningID(stmt, scope.rowUserID(), scope.householdValue(), scope.UserID, dataOcorrencia, descricaoOrigem, valorNegativo, "Transferência", contaOrigem, true); err != nil {
 		tx.Rollback()
 		renderErrorPage(c, http.StatusInternalServerError, "Erro ao registrar a saída da conta de origem.", err)
 		return
@@ -77,7 +81,8 @@ func AddTransferencia(c *gin.Context) {
 	// 2. Crédito na conta de destino (valor positivo)
 	valorPositivo := math.Abs(valor)
 	descricaoDestino := fmt.Sprintf("Transferência de %s: %s", contaOrigem, descricao)
-	if _, err := stmt.Exec(userID, dataOcorrencia, descricaoDestino, valorPositivo, "Transferência", contaDestino, true); err != nil {
+	entrada := models.Movimentacao{DataOcorrencia: dataOcorrencia, Descricao: descricaoDestino, Valor: valorPositivo, Categoria: "Transferência", Conta: contaDestino, Consolidado: true}
+	if entrada.ID, err = insertReturningID(stmt, scope.rowUserID(), scope.householdValue(), scope.UserID, dataOcorrencia, descricaoDestino, valorPositivo, "Transferência", contaDestino, true); err != nil {
 		tx.Rollback()
 		renderErrorPage(c, http.StatusInternalServerError, "Erro ao registrar a entrada na conta de destino.", err)
 		return
@@ -88,14 +93,32 @@ func AddTransferencia(c *gin.Context) {
 		renderErrorPage(c, http.StatusInternalServerError, "Erro ao finalizar a transação.", err)
 		return
 	}
+	middleware.SetAuditChange(c, "movimentacao", saida.ID, nil, saida)
+	middleware.SetAuditChange(c, "movimentacao", entrada.ID, nil, entrada)
 
 	c.Redirect(http.StatusFound, "/transacoes")
 }
 
+// insertReturningID executa o INSERT preparado e retorna o ID gerado
+// (RETURNING no PostgreSQL, LastInsertId no SQLite).
+func insertReturningID(stmt *sql.Stmt, args ...interface{}) (int, error) {
+	if database.DriverName == "postgres" {
+		var id int
+		err := stmt.QueryRow(args...).Scan(&id)
+		return id, err
+	}
+	result, err := stmt.Exec(args...)
+	if err != nil {
+		return 0, err
+	}
+	id, err := result.LastInsertId()
+	return int(id), err
+}
+
 func GetSaldosAPI(c *gin.Context) {
-	userID := c.MustGet("userID").(int64)
+	scope := currentLedger(c)
 
-	saldosContas, err := calculateAccountBalances(userID)
+	saldosContas, err := calculateAccountBalances(scope)
 	if err != nil {
 		renderErrorPage(c, http.StatusInternalServerError, "Não foi possível carregar os saldos das contas.", err)
 		return
@@ -116,9 +139,9 @@ func GetSaldosAPI(c *gin.Context) {
 // Helper Functions
 // =============================================================================
 
-func bindAndQuery(userID int64, query string, args ...interface{}) (*sql.Rows, error) {
+func bindAndQuery(scope ledgerScope, query string, args ...interface{}) (*sql.Rows, error) {
 	db := database.GetDB()
-	finalArgs := append([]interface{}{userID}, args...)
+	finalArgs := append([]interface{}{scope.arg()}, args...)
 	reboundQuery := database.Rebind(query)
 	return db.Query(reboundQuery, finalArgs...)
 }
@@ -139,9 +162,22 @@ func scanDate(rawData interface{}) string {
 	return ""
 }
 
-func getDistinctColumnValues(userID int64, columnName string) []string {
-	query := fmt.Sprintf("SELECT DISTINCT %s FROM %s WHERE user_id = ? AND %s <> '' ORDER BY %s ASC", columnName, database.TableName, columnName, columnName)
-	rows, err := bindAndQuery(userID, query)
+// loadMovimentacao busca a transação dentro do escopo; usado para registrar o estado anterior na auditoria.
+func loadMovimentacao(scope ledgerScope, id int) (*models.Movimentacao, error) {
+	var mov models.Movimentacao
+	var rawData interface{}
+	query := fmt.Sprintf("SELECT id, data_ocorrencia, descricao, valor, categoria, conta, consolidado FROM %s WHERE id = ? AND %s", database.TableName, scope.activeWhere())
+	err := database.GetDB().QueryRow(database.Rebind(query), id, scope.arg()).Scan(&mov.ID, &rawData, &mov.Descricao, &mov.Valor, &mov.Categoria, &mov.Conta, &mov.Consolidado)
+	if err != nil {
+		return nil, err
+	}
+	mov.DataOcorrencia = scanDate(rawData)
+	return &mov, nil
+}
+
+func getDistinctColumnValues(scope ledgerScope, columnName string) []string {
+	query := fmt.Sprintf("SELECT DISTINCT %s FROM %s WHERE %s AND %s <> '' ORDER BY %s ASC", columnName, database.TableName, scope.activeWhere(), columnName, columnName)
+	rows, err := bindAndQuery(scope, query)
 	if err != nil {
 		log.Printf("Erro ao buscar valores distintos para a coluna '%s': %v", columnName, err)
 		return []string{}
@@ -197,10 +233,10 @@ func validateMovimentacao(c *gin.Context) (models.Movimentacao, error) {
 // =============================================================================
 
 func GetIndexPage(c *gin.Context) {
-	userID := c.MustGet("userID").(int64)
+	scope := currentLedger(c)
 	user := c.MustGet("user").(*models.User)
 
-	saldosContas, err := calculateAccountBalances(userID)
+	saldosContas, err := calculateAccountBalances(scope)
 	if err != nil {
 		renderErrorPage(c, http.StatusInternalServerError, "Não foi possível carregar os saldos das contas.", err)
 		return
@@ -220,7 +256,7 @@ func GetIndexPage(c *gin.Context) {
 }
 
 func GetTransacoesPage(c *gin.Context) {
-	userID := c.MustGet("userID").(int64)
+	scope := currentLedger(c)
 	user := c.MustGet("user").(*models.User)
 
 	searchDescricao := c.Query("search_descricao")
@@ -234,29 +270,105 @@ func GetTransacoesPage(c *gin.Context) {
 	isApiRequest := strings.Contains(c.GetHeader("Accept"), "application/json") || c.Request.URL.Path == "/api/movimentacoes"
 
 	if !isApiRequest && selectedStartDate == "" && selectedEndDate == "" {
-		now := time.Now()
-		firstOfMonth := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
-		selectedStartDate = firstOfMonth.Format("2006-01-02")
-		lastOfMonth := time.Date(now.Year(), now.Month()+1, 0, 0, 0, 0, 0, now.Location())
-		selectedEndDate = lastOfMonth.Format("2006-01-02")
+		selectedStartDate, selectedEndDate = currentMonthRange()
+	}
+
+	// criado_por identifica o autor de cada lançamento (relevante nos lares compartilhados).
+	query := fmt.Sprintf("SELECT id, data_ocorrencia, descricao, valor, categoria, conta, consolidado, COALESCE((SELECT email FROM users WHERE users.id = %s.created_by), '') FROM %s WHERE %s", database.TableName, database.TableName, scope.activeWhere())
+	filter := transacoesFilter{
+		SearchDescricao: searchDescricao, Categories: selectedCategories, Accounts: selectedAccounts,
+		StartDate: selectedStartDate, EndDate: selectedEndDate, Consolidado: selectedConsolidado, ValueFilter: selectedValueFilter,
+	}
+	whereClauses, args := filter.clauses()
+	if len(whereClauses) > 0 {
+		query += " AND " + strings.Join(whereClauses, " AND ")
+	}
+	query += " ORDER BY data_ocorrencia DESC, id DESC"
+
+	rows, err := bindAndQuery(scope, query, args...)
+	if err != nil {
+		renderErrorPage(c, http.StatusInternalServerError, "Erro ao buscar movimentações.", err)
+		return
+	}
+	defer rows.Close()
+
+	var movimentacoes []models.Movimentacao
+	var totalValor, totalEntradas, totalSaidas float64
+	for rows.Next() {
+		var mov models.Movimentacao
+		var rawData interface{}
+		if err := rows.Scan(&mov.ID, &rawData, &mov.Descricao, &mov.Valor, &mov.Categoria, &mov.Conta, &mov.Consolidado, &mov.CriadoPor); err != nil {
+			log.Printf("Erro ao escanear linha da movimentação: %v", err)
+			continue
+		}
+		mov.DataOcorrencia = scanDate(rawData)
+		movimentacoes = append(movimentacoes, mov)
+		totalValor += mov.Valor
+		if mov.Valor >= 0 {
+			totalEntradas += mov.Valor
+		} else {
+			totalSaidas += mov.Valor
+		}
+	}
+	if err = rows.Err(); err != nil {
+		renderErrorPage(c, http.StatusInternalServerError, "Erro durante a leitura das movimentações.", err)
+		return
 	}
 
-	query := fmt.Sprintf("SELECT id, data_ocorrencia, descricao, valor, categoria, conta, consolidado FROM %s WHERE user_id = ?", database.TableName)
+	if strings.Contains(c.GetHeader("Accept"), "application/json") || c.Request.URL.Path == "/api/movimentacoes" {
+		c.JSON(http.StatusOK, gin.H{"movimentacoes": movimentacoes, "totalValor": totalValor, "totalEntradas": totalEntradas, "totalSaidas": totalSaidas})
+		return
+	}
+
+	c.HTML(http.StatusOK, "transacoes.html", gin.H{
+		"Movimentacoes":       movimentacoes, "Titulo": "Transações Financeiras", "SearchDescricao": searchDescricao,
+		"SelectedCategories":  selectedCategories, "SelectedStartDate": selectedStartDate, "SelectedEndDate": selectedEndDate,
+		"SelectedConsolidado": selectedConsolidado, "SelectedAccounts": selectedAccounts, "SelectedValueFilter": selectedValueFilter,
+		"Categories":          getDistinctColumnValues(scope, "categoria"), "Accounts": getDistinctColumnValues(scope, "conta"),
+		"ConsolidatedOptions": []struct{ Value, Label string }{{"", "Todos"}, {"true", "Sim"}, {"false", "Não"}},
+		"TotalValor":          totalValor, "TotalEntradas": totalEntradas, "TotalSaidas": totalSaidas,
+		"CurrentDate":         time.Now().Format("2006-01-02"),
+		"User":                user,
+	})
+}
+
+// currentMonthRange retorna o primeiro e o último dia do mês atual (AAAA-MM-DD), o período
+// padrão da tela de transações quando nenhuma data é informada.
+func currentMonthRange() (string, string) {
+	now := time.Now()
+	firstOfMonth := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+	lastOfMonth := time.Date(now.Year(), now.Month()+1, 0, 0, 0, 0, 0, now.Location())
+	return firstOfMonth.Format("2006-01-02"), lastOfMonth.Format("2006-01-02")
+}
+
+// transacoesFilter são os filtros da tela de transações, os mesmos da query string de GetTransacoesPage.
+type transacoesFilter struct {
+	SearchDescricao string   `json:"search_descricao"`
+	Categories      []string `json:"category"`
+	Accounts        []string `json:"account"`
+	StartDate       string   `json:"start_date"`
+	EndDate         string   `json:"end_date"`
+	Consolidado     string   `json:"consolidated_filter"`
+	ValueFilter     string   `json:"value_filter"`
+}
+
+// clauses converte os filtros em condições SQL (unidas com AND) e seus argumentos.
+func (f transacoesFilter) clauses() ([]string, []interface{}) {
 	var args []interface{}
 	var whereClauses []string
 
-	if searchDescricao != "" {
+	if f.SearchDescricao != "" {
 		clause := "descricao LIKE ?"
 		if database.DriverName == "postgres" {
 			clause = "descricao ILIKE ?"
 		}
 		whereClauses = append(whereClauses, clause)
-		args = append(args, "%"+searchDescricao+"%")
+		args = append(args, "%"+f.SearchDescricao+"%")
 	}
 
 	// --- CORREÇÃO: Filtragem Robustecida para Categorias ---
 	var validCategories []string
-	for _, cat := range selectedCategories {
+	for _, cat := range f.Categories {
 		if strings.TrimSpace(cat) != "" {
 			validCategories = append(validCategories, cat)
 		}
@@ -272,7 +384,7 @@ func GetTransacoesPage(c *gin.Context) {
 
 	// --- CORREÇÃO: Filtragem Robustecida para Contas ---
 	var validAccounts []string
-	for _, acc := range selectedAccounts {
+	for _, acc := range f.Accounts {
 		if strings.TrimSpace(acc) != "" {
 			validAccounts = append(validAccounts, acc)
 		}
@@ -286,80 +398,31 @@ func GetTransacoesPage(c *gin.Context) {
 	}
 	// ----------------------------------------------------
 
-	if selectedStartDate != "" {
+	if f.StartDate != "" {
 		whereClauses = append(whereClauses, "data_ocorrencia >= ?")
-		args = append(args, selectedStartDate)
+		args = append(args, f.StartDate)
 	}
-	if selectedEndDate != "" {
+	if f.EndDate != "" {
 		whereClauses = append(whereClauses, "data_ocorrencia <= ?")
-		args = append(args, selectedEndDate)
+		args = append(args, f.EndDate)
 	}
-	if selectedConsolidado != "" {
-		if b, err := strconv.ParseBool(selectedConsolidado); err == nil {
+	if f.Consolidado != "" {
+		if b, err := strconv.ParseBool(f.Consolidado); err == nil {
 			whereClauses = append(whereClauses, "consolidado = ?")
 			args = append(args, b)
 		}
 	}
-	if selectedValueFilter == "income" {
+	if f.ValueFilter == "income" {
 		whereClauses = append(whereClauses, "valor >= 0")
 	}
-	if selectedValueFilter == "expense" {
+	if f.ValueFilter == "expense" {
 		whereClauses = append(whereClauses, "valor < 0")
 	}
-	if len(whereClauses) > 0 {
-		query += " AND " + strings.Join(whereClauses, " AND ")
-	}
-	query += " ORDER BY data_ocorrencia DESC, id DESC"
-
-	rows, err := bindAndQuery(userID, query, args...)
-	if err != nil {
-		renderErrorPage(c, http.StatusInternalServerError, "Erro ao buscar movimentações.", err)
-		return
-	}
-	defer rows.Close()
-
-	var movimentacoes []models.Movimentacao
-	var totalValor, totalEntradas, totalSaidas float64
-	for rows.Next() {
-		var mov models.Movimentacao
-		var rawData interface{}
-		if err := rows.Scan(&mov.ID, &rawData, &mov.Descricao, &mov.Valor, &mov.Categoria, &mov.Conta, &mov.Consolidado); err != nil {
-			log.Printf("Erro ao escanear linha da movimentação: %v", err)
-			continue
-		}
-		mov.DataOcorrencia = scanDate(rawData)
-		movimentacoes = append(movimentacoes, mov)
-		totalValor += mov.Valor
-		if mov.Valor >= 0 {
-			totalEntradas += mov.Valor
-		} else {
-			totalSaidas += mov.Valor
-		}
-	}
-	if err = rows.Err(); err != nil {
-		renderErrorPage(c, http.StatusInternalServerError, "Erro durante a leitura das movimentações.", err)
-		return
-	}
-
-	if strings.Contains(c.GetHeader("Accept"), "application/json") || c.Request.URL.Path == "/api/movimentacoes" {
-		c.JSON(http.StatusOK, gin.H{"movimentacoes": movimentacoes, "totalValor": totalValor, "totalEntradas": totalEntradas, "totalSaidas": totalSaidas})
-		return
-	}
-
-	c.HTML(http.StatusOK, "transacoes.html", gin.H{
-		"Movimentacoes":       movimentacoes, "Titulo": "Transações Financeiras", "SearchDescricao": searchDescricao,
-		"SelectedCategories":  selectedCategories, "SelectedStartDate": selectedStartDate, "SelectedEndDate": selectedEndDate,
-		"SelectedConsolidado": selectedConsolidado, "SelectedAccounts": selectedAccounts, "SelectedValueFilter": selectedValueFilter,
-		"Categories":          getDistinctColumnValues(userID, "categoria"), "Accounts": getDistinctColumnValues(userID, "conta"),
-		"ConsolidatedOptions": []struct{ Value, Label string }{{"", "Todos"}, {"true", "Sim"}, {"false", "Não"}},
-		"TotalValor":          totalValor, "TotalEntradas": totalEntradas, "TotalSaidas": totalSaidas,
-		"CurrentDate":         time.Now().Format("2006-01-02"),
-		"User":                user,
-	})
+	return whereClauses, args
 }
 
 func GetRelatorio(c *gin.Context) {
-	userID := c.MustGet("userID").(int64)
+	scope := currentLedger(c)
 	user := c.MustGet("user").(*models.User)
 
 	searchDescricao := c.Query("search_descricao")
@@ -377,7 +440,7 @@ func GetRelatorio(c *gin.Context) {
 		selectedEndDate = lastOfMonth.Format("2006-01-02")
 	}
 
-	relatorioData, err := fetchReportData(userID, selectedStartDate, selectedEndDate, selectedCategories, selectedAccounts, selectedConsolidado, searchDescricao)
+	relatorioData, err := fetchReportData(scope, selectedStartDate, selectedEndDate, selectedCategories, selectedAccounts, selectedConsolidado, searchDescricao)
 	if err != nil {
 		renderErrorPage(c, http.StatusInternalServerError, "Erro ao buscar dados para o relatório.", err)
 		return
@@ -387,7 +450,7 @@ func GetRelatorio(c *gin.Context) {
 		"Titulo": "Relatório de Despesas por Categoria", "ReportData": relatorioData,
 		"SearchDescricao":     searchDescricao, "SelectedCategories": selectedCategories, "SelectedStartDate": selectedStartDate,
 		"SelectedEndDate":     selectedEndDate, "SelectedConsolidado": selectedConsolidado, "SelectedAccounts": selectedAccounts,
-		"Categories":          getDistinctColumnValues(userID, "categoria"), "Accounts": getDistinctColumnValues(userID, "conta"),
+		"Categories":          getDistinctColumnValues(scope, "categoria"), "Accounts": getDistinctColumnValues(scope, "conta"),
 		"ConsolidatedOptions": []struct{ Value, Label string }{{"", "Todos"}, {"true", "Sim"}, {"false", "Não"}},
 		"CurrentDate":         time.Now().Format("2006-01-02"),
 		"User":                user,
@@ -399,7 +462,7 @@ func GetRelatorio(c *gin.Context) {
 // =============================================================================
 
 func AddMovimentacao(c *gin.Context) {
-	userID := c.MustGet("userID").(int64)
+	scope := currentLedger(c)
 	mov, err := validateMovimentacao(c)
 	if err != nil {
 		renderErrorPage(c, http.StatusBadRequest, err.Error(), err)
@@ -412,11 +475,11 @@ func AddMovimentacao(c *gin.Context) {
 
 	// Lógica de inserção que funciona para PostgreSQL e SQLite
 	if database.DriverName == "postgres" {
-		query := fmt.Sprintf(`INSERT INTO %s (user_id, data_ocorrencia, descricao, valor, categoria, conta, consolidado) VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id`, database.TableName)
-		err = db.QueryRow(query, userID, mov.DataOcorrencia, mov.Descricao, mov.Valor, mov.Categoria, mov.Conta, mov.Consolidado).Scan(&mov.ID)
+		query := fmt.Sprintf(`INSERT INTO %s (user_id, household_id, created_by, data_ocorrencia, descricao, valor, categoria, conta, consolidado) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9) RETURNING id`, database.TableName)
+		err = db.QueryRow(query, scope.rowUserID(), scope.householdValue(), scope.UserID, mov.DataOcorrencia, mov.Descricao, mov.Valor, mov.Categoria, mov.Conta, mov.Consolidado).Scan(&mov.ID)
 	} else { // Padrão para SQLite
-		query := fmt.Sprintf(`INSERT INTO %s (user_id, data_ocorrencia, descricao, valor, categoria, conta, consolidado) VALUES (?, ?, ?, ?, ?, ?, ?)`, database.TableName)
-		result, execErr := db.Exec(database.Rebind(query), userID, mov.DataOcorrencia, mov.Descricao, mov.Valor, mov.Categoria, mov.Conta, mov.Consolidado)
+		query := fmt.Sprintf(`INSERT INTO %s (user_id, household_id, created_by, data_ocorrencia, descricao, valor, categoria, conta, consolidado) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`, database.TableName)
+		result, execErr := db.Exec(database.Rebind(query), scope.rowUserID(), scope.householdValue(), scope.UserID, mov.DataOcorrencia, mov.Descricao, mov.Valor, mov.Categoria, mov.Conta, mov.Consolidado)
 		if execErr == nil {
 			lastID, _ := result.LastInsertId()
 			mov.ID = int(lastID)
@@ -428,6 +491,7 @@ func AddMovimentacao(c *gin.Context) {
 		renderErrorPage(c, http.StatusInternalServerError, "Erro ao inserir os dados no banco de dados.", err)
 		return
 	}
+	middleware.SetAuditChange(c, "movimentacao", mov.ID, nil, mov)
 
 	if strings.Contains(c.GetHeader("Accept"), "application/json") {
 		c.JSON(http.StatusCreated, mov)
@@ -437,7 +501,7 @@ func AddMovimentacao(c *gin.Context) {
 }
 
 func UpdateMovimentacao(c *gin.Context) {
-	userID := c.MustGet("userID").(int64)
+	scope := currentLedger(c)
 	id, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
 		renderErrorPage(c, http.StatusBadRequest, "O ID da transação é inválido.", err)
@@ -450,45 +514,57 @@ func UpdateMovimentacao(c *gin.Context) {
 		return
 	}
 
-	query := fmt.Sprintf(`UPDATE %s SET data_ocorrencia = ?, descricao = ?, valor = ?, categoria = ?, conta = ?, consolidado = ? WHERE id = ? AND user_id = ?`, database.TableName)
+	before, _ := loadMovimentacao(scope, id)
+
+	query := fmt.Sprintf(`UPDATE %s SET data_ocorrencia = ?, descricao = ?, valor = ?, categoria = ?, conta = ?, consolidado = ? WHERE id = ? AND %s`, database.TableName, scope.activeWhere())
 	reboundQuery := database.Rebind(query)
 	db := database.GetDB()
 
-	_, err = db.Exec(reboundQuery, mov.DataOcorrencia, mov.Descricao, mov.Valor, mov.Categoria, mov.Conta, mov.Consolidado, id, userID)
+	_, err = db.Exec(reboundQuery, mov.DataOcorrencia, mov.Descricao, mov.Valor, mov.Categoria, mov.Conta, mov.Consolidado, id, scope.arg())
 
 	if err != nil {
 		renderErrorPage(c, http.StatusInternalServerError, "Erro ao atualizar os dados.", err)
 		return
 	}
+	if before != nil {
+		mov.ID = id
+		middleware.SetAuditChange(c, "movimentacao", id, before, mov)
+	}
 
 	c.Redirect(http.StatusFound, "/transacoes")
 }
 
 func DeleteMovimentacao(c *gin.Context) {
-	userID := c.MustGet("userID").(int64)
+	scope := currentLedger(c)
 	id, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "ID inválido."})
 		return
 	}
 
-	query := fmt.Sprintf("DELETE FROM %s WHERE id = ? AND user_id = ?", database.TableName)
+	before, _ := loadMovimentacao(scope, id)
+
+	// Exclusão lógica: a movimentação vai para a lixeira e pode ser restaurada até a limpeza automática.
+	query := fmt.Sprintf("UPDATE %s SET deleted_at = ? WHERE id = ? AND %s", database.TableName, scope.activeWhere())
 	reboundQuery := database.Rebind(query)
 	db := database.GetDB()
 
-	_, err = db.Exec(reboundQuery, id, userID)
+	_, err = db.Exec(reboundQuery, time.Now().UTC(), id, scope.arg())
 
 	if err != nil {
-		log.Printf("Erro ao deletar movimentação ID %d para usuário %d: %v", id, userID, err)
+		log.Printf("Erro ao deletar movimentação ID %d para usuário %d: %v", id, scope.UserID, err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao deletar a movimentação."})
 		return
 	}
+	if before != nil {
+		middleware.SetAuditChange(c, "movimentacao", id, before, nil)
+	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Movimentação deletada com sucesso!"})
+	c.JSON(http.StatusOK, gin.H{"message": "Movimentação movida para a lixeira.", "restore_url": fmt.Sprintf("/movimentacoes/%d/restore", id)})
 }
 
 func GetTransactionsByCategory(c *gin.Context) {
-	userID := c.MustGet("userID").(int64)
+	scope := currentLedger(c)
 	category := c.Query("category")
 	searchDescricao := c.Query("search_descricao")
 	selectedStartDate := c.Query("start_date")
@@ -515,7 +591,7 @@ func GetTransactionsByCategory(c *gin.Context) {
 	// Porém, o fetchAllTransactions padrão tem um "AND valor < 0" hardcoded. Vamos verificar.
 	// Sim, fetchAllTransactions força "valor < 0". Então podemos reutilizá-la.
 
-	transactions, err := fetchAllTransactions(userID, selectedStartDate, selectedEndDate, categories, selectedAccounts, selectedConsolidado, searchDescricao)
+	transactions, err := fetchAllTransactions(scope, selectedStartDate, selectedEndDate, categories, selectedAccounts, selectedConsolidado, searchDescricao)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao buscar transações: " + err.Error()})
 		return
@@ -524,18 +600,18 @@ func GetTransactionsByCategory(c *gin.Context) {
 }
 
 func DownloadRelatorioPDF(c *gin.Context) {
-	userID := c.MustGet("userID").(int64)
+	scope := currentLedger(c)
 	var payload models.PDFRequestPayload
 	if err := c.ShouldBindJSON(&payload); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Payload inválido: " + err.Error()})
 		return
 	}
-	reportData, err := fetchReportData(userID, payload.StartDate, payload.EndDate, payload.Categories, payload.Accounts, payload.ConsolidatedFilter, payload.SearchDescricao)
+	reportData, err := fetchReportData(scope, payload.StartDate, payload.EndDate, payload.Categories, payload.Accounts, payload.ConsolidatedFilter, payload.SearchDescricao)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao buscar dados do relatório: " + err.Error()})
 		return
 	}
-	transactions, err := fetchAllTransactions(userID, payload.StartDate, payload.EndDate, payload.Categories, payload.Accounts, payload.ConsolidatedFilter, payload.SearchDescricao)
+	transactions, err := fetchAllTransactions(scope, payload.StartDate, payload.EndDate, payload.Categories, payload.Accounts, payload.ConsolidatedFilter, payload.SearchDescricao)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao buscar transações detalhadas: " + err.Error()})
 		return
@@ -555,11 +631,11 @@ func DownloadRelatorioPDF(c *gin.Context) {
 }
 
 // Internal Data Fetching Functions
-func calculateAccountBalances(userID int64) ([]models.ContaSaldo, error) {
+func calculateAccountBalances(scope ledgerScope) ([]models.ContaSaldo, error) {
 	saldos := make(map[string]float64)
 	db := database.GetDB()
-	queryContas := database.Rebind("SELECT nome, saldo_inicial FROM contas WHERE user_id = ?")
-	rowsContas, err := db.Query(queryContas, userID)
+	queryContas := database.Rebind("SELECT nome, saldo_inicial FROM contas WHERE " + scope.where())
+	rowsContas, err := db.Query(queryContas, scope.arg())
 	if err == nil {
 		for rowsContas.Next() {
 			var nome string
@@ -570,10 +646,10 @@ func calculateAccountBalances(userID int64) ([]models.ContaSaldo, error) {
 		}
 		rowsContas.Close()
 	} else {
-		log.Printf("Aviso: Não foi possível ler a tabela 'contas' para o usuário %d: %v.", userID, err)
+		log.Printf("Aviso: Não foi possível ler a tabela 'contas' para o usuário %d: %v.", scope.UserID, err)
 	}
-	queryMov := database.Rebind(fmt.Sprintf("SELECT conta, SUM(valor) FROM %s WHERE user_id = ? GROUP BY conta", database.TableName))
-	rowsMov, err := db.Query(queryMov, userID)
+	queryMov := database.Rebind(fmt.Sprintf("SELECT conta, SUM(valor) FROM %s WHERE %s GROUP BY conta", database.TableName, scope.activeWhere()))
+	rowsMov, err := db.Query(queryMov, scope.arg())
 	if err != nil {
 		return nil, fmt.Errorf("erro ao calcular totais por conta: %w", err)
 	}
@@ -594,8 +670,8 @@ func calculateAccountBalances(userID int64) ([]models.ContaSaldo, error) {
 	return result, nil
 }
 
-func fetchReportData(userID int64, startDate, endDate string, categories, accounts []string, consolidated, searchDescricao string) ([]models.RelatorioCategoria, error) {
-	query := fmt.Sprintf("SELECT categoria, SUM(valor) FROM %s WHERE user_id = ? AND valor < 0", database.TableName)
+func fetchReportData(scope ledgerScope, startDate, endDate string, categories, accounts []string, consolidated, searchDescricao string) ([]models.RelatorioCategoria, error) {
+	query := fmt.Sprintf("SELECT categoria, SUM(valor) FROM %s WHERE %s AND valor < 0", database.TableName, scope.activeWhere())
 	var args []interface{}
 	var whereClauses []string
 	if searchDescricao != "" {
@@ -653,7 +729,7 @@ func fetchReportData(userID int64, startDate, endDate string, categories, accoun
 		query += " AND " + strings.Join(whereClauses, " AND ")
 	}
 	query += " GROUP BY categoria ORDER BY SUM(valor) ASC"
-	rows, err := bindAndQuery(userID, query, args...)
+	rows, err := bindAndQuery(scope, query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -670,8 +746,8 @@ func fetchReportData(userID int64, startDate, endDate string, categories, accoun
 	return relatorioData, rows.Err()
 }
 
-func fetchAllTransactions(userID int64, startDate, endDate string, categories, accounts []string, consolidated, searchDescricao string) ([]models.Movimentacao, error) {
-	query := fmt.Sprintf("SELECT id, data_ocorrencia, descricao, valor, categoria, conta, consolidado FROM %s WHERE user_id = ?", database.TableName)
+func fetchAllTransactions(scope ledgerScope, startDate, endDate string, categories, accounts []string, consolidated, searchDescricao string) ([]models.Movimentacao, error) {
+	query := fmt.Sprintf("SELECT id, data_ocorrencia, descricao, valor, categoria, conta, consolidado FROM %s WHERE %s", database.TableName, scope.activeWhere())
 	var args []interface{}
 	var whereClauses []string
 	whereClauses = append(whereClauses, "valor < 0") // Força apenas despesas para o relatório
@@ -731,7 +807,7 @@ func fetchAllTransactions(userID int64, startDate, endDate string, categories, a
 		query += " AND " + strings.Join(whereClauses, " AND ")
 	}
 	query += " ORDER BY data_ocorrencia DESC"
-	rows, err := bindAndQuery(userID, query, args...)
+	rows, err := bindAndQuery(scope, query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -753,7 +829,7 @@ func fetchAllTransactions(userID int64, startDate, endDate string, categories, a
 // ExportTransactionsCSV gera um arquivo CSV com as transações do usuário, aplicando os filtros da requisição.
 func ExportTransactionsCSV(c *gin.Context) {
 	log.Println("--- EXECUTANDO HANDLER: ExportTransactionsCSV ---")
-	userID := c.MustGet("userID").(int64)
+	scope := currentLedger(c)
 
 	middleware.ReportsGenerated.WithLabelValues("csv").Inc()
 
@@ -767,7 +843,7 @@ func ExportTransactionsCSV(c *gin.Context) {
 	selectedValueFilter := c.Query("value_filter")
 
 	// 2. Constrói a Query SQL
-	query := fmt.Sprintf("SELECT id, data_ocorrencia, descricao, valor, categoria, conta, consolidado FROM %s WHERE user_id = ?", database.TableName)
+	query := fmt.Sprintf("SELECT id, data_ocorrencia, descricao, valor, categoria, conta, consolidado FROM %s WHERE %s", database.TableName, scope.activeWhere())
 	var args []interface{}
 	var whereClauses []string
 
@@ -838,7 +914,7 @@ func ExportTransactionsCSV(c *gin.Context) {
 	query += " ORDER BY data_ocorrencia ASC, id ASC"
 
 	// 3. Executa a Query
-	rows, err := bindAndQuery(userID, query, args...)
+	rows, err := bindAndQuery(scope, query, args...)
 	if err != nil {
 		renderErrorPage(c, http.StatusInternalServerError, "Erro ao buscar movimentações para exportação.", err)
 		return
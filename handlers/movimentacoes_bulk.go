@@ -0,0 +1,200 @@
+package handlers
+
+import (
+	"fmt"
+	"minhas_economias/database"
+	"minhas_economias/middleware"
+	"minhas_economias/models"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxBulkIDs limita a lista explícita de IDs (placeholders por consulta no SQLite).
+const maxBulkIDs = 5000
+
+// Operações aceitas pela edição em lote.
+const (
+	bulkSetCategory    = "set_category"
+	bulkSetAccount     = "set_account"
+	bulkSetConsolidado = "set_consolidado"
+	bulkDelete         = "delete"
+	bulkShiftDate      = "shift_date"
+)
+
+// BulkMovimentacoesPayload seleciona as movimentações por IDs ou pelos mesmos filtros da tela
+// de transações e descreve a operação a aplicar.
+type BulkMovimentacoesPayload struct {
+	IDs       []int             `json:"ids"`
+	Filter    *transacoesFilter `json:"filter"`
+	Operation string            `json:"operation"`
+	Value     string            `json:"value"` // Categoria, conta, "true"/"false" ou dias (pode ser negativo)
+}
+
+// BulkUpdateMovimentacoes aplica uma operação a várias movimentações em uma única transação
+// de banco e retorna quantas linhas foram afetadas.
+func BulkUpdateMovimentacoes(c *gin.Context) {
+	scope := currentLedger(c)
+
+	var payload BulkMovimentacoesPayload
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		renderErrorPage(c, http.StatusBadRequest, "Dados inválidos.", err)
+		return
+	}
+	if len(payload.IDs) == 0 && payload.Filter == nil {
+		renderErrorPage(c, http.StatusBadRequest, "Selecione as movimentações (ids) ou informe um filtro.", nil)
+		return
+	}
+	if payload.Filter != nil && len(payload.IDs) == 0 {
+		// Um filtro vazio selecionaria o livro-caixa inteiro.
+		if clauses, _ := payload.Filter.clauses(); len(clauses) == 0 {
+			renderErrorPage(c, http.StatusBadRequest, "O filtro não pode ser vazio; informe ao menos um critério.", nil)
+			return
+		}
+		// Sem datas, vale o mesmo período padrão da tela de transações (mês atual).
+		if payload.Filter.StartDate == "" && payload.Filter.EndDate == "" {
+			payload.Filter.StartDate, payload.Filter.EndDate = currentMonthRange()
+		}
+	}
+	if len(payload.IDs) > maxBulkIDs {
+		renderErrorPage(c, http.StatusBadRequest, fmt.Sprintf("No máximo %d movimentações por vez; use um filtro para selecionar mais.", maxBulkIDs), nil)
+		return
+	}
+
+	set, setArgs, err := bulkSetClause(payload.Operation, payload.Value)
+	if err != nil {
+		renderErrorPage(c, http.StatusBadRequest, err.Error(), err)
+		return
+	}
+
+	// Seleção: sempre restrita ao livro-caixa ativo e fora da lixeira.
+	where := []string{scope.activeWhere()}
+	whereArgs := []interface{}{scope.arg()}
+	if len(payload.IDs) > 0 {
+		where = append(where, fmt.Sprintf("id IN (%s)", strings.TrimSuffix(strings.Repeat("?,", len(payload.IDs)), ",")))
+		for _, id := range payload.IDs {
+			whereArgs = append(whereArgs, id)
+		}
+	}
+	if payload.Filter != nil {
+		clauses, args := payload.Filter.clauses()
+		where = append(where, clauses...)
+		whereArgs = append(whereArgs, args...)
+	}
+	condition := strings.Join(where, " AND ")
+
+	tx, err := database.GetDB().Begin()
+	if err != nil {
+		renderErrorPage(c, http.StatusInternalServerError, "Erro ao iniciar a transação no banco de dados.", err)
+		return
+	}
+	defer tx.Rollback()
+
+	// As movimentações afetadas são lidas antes, para que a auditoria guarde os valores originais.
+	selectQuery := fmt.Sprintf("SELECT id, data_ocorrencia, descricao, valor, categoria, conta, consolidado FROM %s WHERE %s ORDER BY id", database.TableName, condition)
+	rows, err := tx.Query(database.Rebind(selectQuery), whereArgs...)
+	if err != nil {
+		renderErrorPage(c, http.StatusInternalServerError, "Erro ao selecionar as movimentações.", err)
+		return
+	}
+	var before []models.Movimentacao
+	var affectedIDs []int
+	for rows.Next() {
+		var mov models.Movimentacao
+		var rawData interface{}
+		if err := rows.Scan(&mov.ID, &rawData, &mov.Descricao, &mov.Valor, &mov.Categoria, &mov.Conta, &mov.Consolidado); err != nil {
+			rows.Close()
+			renderErrorPage(c, http.StatusInternalServerError, "Erro ao ler as movimentações selecionadas.", err)
+			return
+		}
+		mov.DataOcorrencia = scanDate(rawData)
+		before = append(before, mov)
+		affectedIDs = append(affectedIDs, mov.ID)
+	}
+	rows.Close()
+
+	query := fmt.Sprintf("UPDATE %s SET %s WHERE %s", database.TableName, set, condition)
+	result, err := tx.Exec(database.Rebind(query), append(setArgs, whereArgs...)...)
+	if err != nil {
+		renderErrorPage(c, http.StatusInternalServerError, "Erro ao aplicar a edição em lote.", err)
+		return
+	}
+	affected, _ := result.RowsAffected()
+	if err := tx.Commit(); err != nil {
+		renderErrorPage(c, http.StatusInternalServerError, "Erro ao finalizar a transação.", err)
+		return
+	}
+
+	if affected > 0 {
+		middleware.SetAuditChange(c, "movimentacao_lote", fmt.Sprintf("%d itens", affected), nil, gin.H{
+			"operacao": payload.Operation,
+			"valor":    payload.Value,
+			"ids":      affectedIDs,
+		})
+		// Uma entrada por movimentação, com antes/depois, como nas edições individuais.
+		for _, mov := range before {
+			middleware.SetAuditChange(c, "movimentacao", mov.ID, mov, bulkApply(mov, payload.Operation, payload.Value, setArgs))
+		}
+	}
+	c.JSON(http.StatusOK, gin.H{"message": fmt.Sprintf("%d movimentações atualizadas.", affected), "affected": affected})
+}
+
+// bulkApply devolve o estado da movimentação depois da operação, para a auditoria
+// (nil na exclusão). args são os argumentos já validados e normalizados por bulkSetClause.
+func bulkApply(mov models.Movimentacao, operation, value string, args []interface{}) interface{} {
+	switch operation {
+	case bulkSetCategory:
+		mov.Categoria = args[0].(string)
+	case bulkSetAccount:
+		mov.Conta = args[0].(string)
+	case bulkSetConsolidado:
+		mov.Consolidado = args[0].(bool)
+	case bulkDelete:
+		return nil
+	case bulkShiftDate:
+		days, _ := strconv.Atoi(strings.TrimSpace(value))
+		if date, err := time.Parse("2006-01-02", mov.DataOcorrencia); err == nil {
+			mov.DataOcorrencia = date.AddDate(0, 0, days).Format("2006-01-02")
+		}
+	}
+	return mov
+}
+
+// bulkSetClause valida a operação e retorna o trecho SET do UPDATE com seus argumentos.
+func bulkSetClause(operation, value string) (string, []interface{}, error) {
+	switch operation {
+	case bulkSetCategory:
+		value = strings.TrimSpace(value)
+		if value == "" {
+			value = "Sem Categoria"
+		}
+		return "categoria = ?", []interface{}{value}, nil
+	case bulkSetAccount:
+		value = strings.TrimSpace(value)
+		if value == "" {
+			return "", nil, fmt.Errorf("Informe a conta de destino.")
+		}
+		return "conta = ?", []interface{}{value}, nil
+	case bulkSetConsolidado:
+		consolidado, err := strconv.ParseBool(value)
+		if err != nil {
+			return "", nil, fmt.Errorf("Informe 'true' ou 'false' para consolidado.")
+		}
+		return "consolidado = ?", []interface{}{consolidado}, nil
+	case bulkDelete:
+		return "deleted_at = ?", []interface{}{time.Now().UTC()}, nil
+	case bulkShiftDate:
+		days, err := strconv.Atoi(strings.TrimSpace(value))
+		if err != nil || days == 0 || days > 3650 || days < -3650 {
+			return "", nil, fmt.Errorf("Informe o número de dias a deslocar (ex: 1 ou -30).")
+		}
+		if database.DriverName == "postgres" {
+			return "data_ocorrencia = data_ocorrencia + CAST(? AS INTEGER)", []interface{}{days}, nil
+		}
+		return "data_ocorrencia = date(data_ocorrencia, ?)", []interface{}{fmt.Sprintf("%+d days", days)}, nil
+	}
+	return "", nil, fmt.Errorf("Operação em lote desconhecida: '%s'.", operation)
+}
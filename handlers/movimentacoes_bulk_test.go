@@ -0,0 +1,123 @@
+package handlers
+
+import (
+	"encoding/json"
+	"minhas_economias/database"
+	"minhas_economias/middleware"
+	"minhas_economias/models"
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestBulkUpdateMovimentacoes(t *testing.T) {
+	setupTestDB(t)
+	defer teardownTestDB()
+
+	r := createWorkspaceRouter(testUserID, 0)
+	r.POST("/movimentacoes/bulk", BulkUpdateMovimentacoes)
+
+	// Por IDs: recategoriza as duas movimentações.
+	w := performAdminRequest(r, "POST", "/movimentacoes/bulk", map[string]interface{}{
+		"ids": []int{1, 2}, "operation": "set_category", "value": "Revisar",
+	})
+	if w.Code != http.StatusOK {
+		t.Fatalf("Esperado status 200, obteve %d: %s", w.Code, w.Body.String())
+	}
+	for _, mov := range listMovimentacoes(t, r) {
+		if mov.Categoria != "Revisar" {
+			t.Errorf("Esperado categoria 'Revisar' na movimentação %d, obteve '%s'", mov.ID, mov.Categoria)
+		}
+	}
+
+	// Pelo filtro: desloca só as saídas em 5 dias.
+	w = performAdminRequest(r, "POST", "/movimentacoes/bulk", map[string]interface{}{
+		"filter": map[string]interface{}{"value_filter": "expense", "start_date": "2025-01-01", "end_date": "2025-01-31"}, "operation": "shift_date", "value": "5",
+	})
+	if w.Code != http.StatusOK {
+		t.Fatalf("Esperado status 200, obteve %d: %s", w.Code, w.Body.String())
+	}
+	for _, mov := range listMovimentacoes(t, r) {
+		if mov.ID == 1 && mov.DataOcorrencia != "2025-01-15" {
+			t.Errorf("Esperado data 2025-01-15 após deslocar, obteve %s", mov.DataOcorrencia)
+		}
+		if mov.ID == 2 && mov.DataOcorrencia != "2025-01-15" {
+			t.Errorf("A entrada não deveria ser alterada, obteve %s", mov.DataOcorrencia)
+		}
+	}
+
+	// Exclusão em lote vai para a lixeira.
+	w = performAdminRequest(r, "POST", "/movimentacoes/bulk", map[string]interface{}{
+		"ids": []int{1, 2, 999}, "operation": "delete",
+	})
+	if w.Code != http.StatusOK {
+		t.Fatalf("Esperado status 200, obteve %d", w.Code)
+	}
+	var trashed int
+	database.GetDB().QueryRow("SELECT COUNT(*) FROM movimentacoes WHERE deleted_at IS NOT NULL").Scan(&trashed)
+	if trashed != 2 {
+		t.Errorf("Esperado 2 movimentações na lixeira, obteve %d", trashed)
+	}
+
+	// Seleção vazia, filtro sem critérios e operação desconhecida são rejeitados.
+	if w := performAdminRequest(r, "POST", "/movimentacoes/bulk", map[string]interface{}{"operation": "delete"}); w.Code != http.StatusBadRequest {
+		t.Errorf("Esperado status 400 sem seleção, obteve %d", w.Code)
+	}
+	for _, filter := range []map[string]interface{}{{}, {"value_filter": "all", "category": []string{""}}} {
+		if w := performAdminRequest(r, "POST", "/movimentacoes/bulk", map[string]interface{}{"filter": filter, "operation": "delete"}); w.Code != http.StatusBadRequest {
+			t.Errorf("Esperado status 400 para filtro sem critérios %v, obteve %d", filter, w.Code)
+		}
+	}
+	if w := performAdminRequest(r, "POST", "/movimentacoes/bulk", map[string]interface{}{"ids": []int{1}, "operation": "drop"}); w.Code != http.StatusBadRequest {
+		t.Errorf("Esperado status 400 para operação desconhecida, obteve %d", w.Code)
+	}
+}
+
+func TestBulkUpdateMovimentacoes_DefaultsAndAudit(t *testing.T) {
+	setupTestDB(t)
+	defer teardownTestDB()
+	createAuditLogTable(t)
+
+	r := gin.New()
+	r.Use(middleware.AuditLogger())
+	r.Use(func(c *gin.Context) {
+		c.Set("userID", testUserID)
+		c.Set("user", &models.User{ID: testUserID, Email: "test@user.com"})
+		c.Next()
+	})
+	r.POST("/movimentacoes/bulk", BulkUpdateMovimentacoes)
+
+	// Sem datas no filtro vale o mês atual: as movimentações de 2025 não são tocadas.
+	w := performAdminRequest(r, "POST", "/movimentacoes/bulk", map[string]interface{}{
+		"filter": map[string]interface{}{"value_filter": "expense"}, "operation": "delete",
+	})
+	if w.Code != http.StatusOK {
+		t.Fatalf("Esperado status 200, obteve %d: %s", w.Code, w.Body.String())
+	}
+	var trashed int
+	database.GetDB().QueryRow("SELECT COUNT(*) FROM movimentacoes WHERE deleted_at IS NOT NULL").Scan(&trashed)
+	if trashed != 0 {
+		t.Errorf("Esperado que o filtro sem datas ficasse restrito ao mês atual, %d movimentações foram excluídas", trashed)
+	}
+
+	// A auditoria guarda, por movimentação, a categoria original.
+	w = performAdminRequest(r, "POST", "/movimentacoes/bulk", map[string]interface{}{
+		"ids": []int{1}, "operation": "set_category", "value": "Revisar",
+	})
+	if w.Code != http.StatusOK {
+		t.Fatalf("Esperado status 200, obteve %d: %s", w.Code, w.Body.String())
+	}
+	var changes string
+	err := database.GetDB().QueryRow("SELECT changes FROM audit_logs WHERE entity_type = 'movimentacao' AND entity_id = '1'").Scan(&changes)
+	if err != nil {
+		t.Fatalf("Esperado registro de auditoria da movimentação 1: %v", err)
+	}
+	var diff map[string]models.FieldChange
+	if err := json.Unmarshal([]byte(changes), &diff); err != nil {
+		t.Fatalf("Diff de auditoria inválido: %v", err)
+	}
+	if diff["categoria"].Depois != "Revisar" || diff["categoria"].Antes == nil || diff["categoria"].Antes == "Revisar" {
+		t.Errorf("Esperado diff com a categoria original e a nova, obteve %+v", diff)
+	}
+}
@@ -60,7 +60,8 @@ func setupTestDB(t *testing.T) {
 			email TEXT UNIQUE NOT NULL,
 			password_hash TEXT NOT NULL,
 			is_admin BOOLEAN DEFAULT FALSE,
-			dark_mode_enabled BOOLEAN DEFAULT FALSE
+			dark_mode_enabled BOOLEAN DEFAULT FALSE,
+			disabled BOOLEAN DEFAULT FALSE
 	);`
 	createMovimentacoesSQL_sqlite := fmt.Sprintf(`
 	CREATE TABLE %s (
@@ -72,6 +73,9 @@ func setupTestDB(t *testing.T) {
 			categoria TEXT,
 			conta TEXT,
 			consolidado BOOLEAN DEFAULT FALSE,
+			household_id BIGINT,
+			created_by BIGINT,
+			deleted_at TIMESTAMP,
 			FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE
 	);`, database.TableName)
 	createMovimentacoesSQL_postgres := fmt.Sprintf(`
@@ -84,6 +88,9 @@ func setupTestDB(t *testing.T) {
 			categoria TEXT,
 			conta TEXT,
 			consolidado BOOLEAN DEFAULT FALSE,
+			household_id BIGINT,
+			created_by BIGINT,
+			deleted_at TIMESTAMP,
 			FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE
 	);`, database.TableName)
 	createContasSQL := `
@@ -91,6 +98,7 @@ func setupTestDB(t *testing.T) {
 			user_id BIGINT NOT NULL,
 			nome TEXT NOT NULL,
 			saldo_inicial NUMERIC(10, 2) NOT NULL DEFAULT 0,
+			household_id BIGINT,
 			PRIMARY KEY (user_id, nome),
 			FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE
 	);`
@@ -435,7 +443,7 @@ func TestDeleteMovimentacao_Success(t *testing.T) {
 
 	db := database.GetDB()
 	var count int
-	query := database.Rebind("SELECT COUNT(*) FROM movimentacoes WHERE id = ? AND user_id = ?")
+	query := database.Rebind("SELECT COUNT(*) FROM movimentacoes WHERE id = ? AND user_id = ? AND deleted_at IS NULL")
 	err := db.QueryRow(query, 1, testUserID).Scan(&count)
 	if err != nil && err != sql.ErrNoRows {
 		if err == sql.ErrNoRows {
@@ -445,7 +453,7 @@ func TestDeleteMovimentacao_Success(t *testing.T) {
 		}
 	}
 	if count != 0 {
-		t.Errorf("Esperado que a movimentação fosse deletada (contagem 0), mas a contagem é %d", count)
+		t.Errorf("Esperado que a movimentação fosse para a lixeira (contagem 0), mas a contagem é %d", count)
 	}
 }
 
@@ -2,8 +2,10 @@ package handlers
 
 import (
 	"database/sql"
+	"log"
 	"minhas_economias/auth"
 	"minhas_economias/database"
+	"minhas_economias/middleware"
 	"minhas_economias/models"
 	"net/http"
 	"time"
@@ -51,6 +53,12 @@ func UpdateUserProfile(c *gin.Context) {
 		return
 	}
 
+	profile.UserID = userID
+	before, err := GetUserProfileByUserID(userID)
+	if err != nil {
+		log.Printf("Aviso: não foi possível carregar o perfil atual para auditoria: %v", err)
+	}
+
 	db := database.GetDB()
 	var query string
 
@@ -84,11 +92,12 @@ func UpdateUserProfile(c *gin.Context) {
 		dob = parsedTime
 	}
 
-	_, err := db.Exec(query, userID, dob, profile.Gender, profile.MaritalStatus, profile.ChildrenCount, profile.Country, profile.State, profile.City)
+	_, err = db.Exec(query, userID, dob, profile.Gender, profile.MaritalStatus, profile.ChildrenCount, profile.Country, profile.State, profile.City)
 	if err != nil {
 		renderErrorPage(c, http.StatusInternalServerError, "Erro ao salvar o perfil do usuário.", err)
 		return
 	}
+	middleware.SetAuditChange(c, "perfil", userID, before, profile)
 
 	c.JSON(http.StatusOK, gin.H{"message": "Perfil atualizado com sucesso!"})
 }
@@ -148,5 +157,14 @@ func ChangePassword(c *gin.Context) {
 		return
 	}
 
+	// Invalida todas as sessões abertas com a senha antiga; este dispositivo recebe uma sessão nova.
+	if err := auth.RevokeAllSessions(userID); err != nil {
+		renderErrorPage(c, http.StatusInternalServerError, "Senha alterada, mas não foi possível encerrar as sessões abertas.", err)
+		return
+	}
+	if err := auth.RotateSession(c); err != nil {
+		log.Printf("Aviso: não foi possível renovar a sessão do usuário %d: %v", userID, err)
+	}
+
 	c.JSON(http.StatusOK, gin.H{"message": "Senha alterada com sucesso!"})
 }
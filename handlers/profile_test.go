@@ -27,7 +27,7 @@ func setupProfileTestDB(t *testing.T) {
 	db := database.GetDB()
 
 	// Limpa as tabelas na ordem correta para evitar erros de chave estrangeira
-	tables := []string{"movimentacoes", "contas", "user_profiles", "users"}
+	tables := []string{"movimentacoes", "contas", "user_sessions", "user_profiles", "users"}
 	for _, table := range tables {
 		_, err := db.Exec(fmt.Sprintf("DROP TABLE IF EXISTS %s CASCADE", table))
 		if err != nil {
@@ -42,7 +42,8 @@ func setupProfileTestDB(t *testing.T) {
 		email TEXT UNIQUE NOT NULL,
 		password_hash TEXT NOT NULL,
 		is_admin BOOLEAN DEFAULT FALSE,
-		dark_mode_enabled BOOLEAN DEFAULT FALSE
+		dark_mode_enabled BOOLEAN DEFAULT FALSE,
+		disabled BOOLEAN DEFAULT FALSE
 	);`
 	createUserProfilesSQL := `
 	CREATE TABLE user_profiles (
@@ -63,6 +64,20 @@ func setupProfileTestDB(t *testing.T) {
 	if _, err := db.Exec(createUserProfilesSQL); err != nil {
 		t.Fatalf("Falha ao criar a tabela de teste 'user_profiles': %v", err)
 	}
+	createUserSessionsSQL := `
+	CREATE TABLE user_sessions (
+		id TEXT PRIMARY KEY,
+		user_id BIGINT,
+		data TEXT NOT NULL,
+		user_agent TEXT,
+		ip_address TEXT,
+		created_at TIMESTAMP,
+		last_seen_at TIMESTAMP,
+		expires_at TIMESTAMP NOT NULL
+	);`
+	if _, err := db.Exec(createUserSessionsSQL); err != nil {
+		t.Fatalf("Falha ao criar a tabela de teste 'user_sessions': %v", err)
+	}
 
 	// Insere o usuário de teste
 	hashedPassword, err := auth.HashPassword("senha_antiga_123")
@@ -0,0 +1,223 @@
+// households/households.go
+package households
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"minhas_economias/database"
+	"minhas_economias/models"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrNotMember indica que o usuário não participa do lar (ou o lar não existe).
+	ErrNotMember = errors.New("você não participa deste lar")
+	// ErrForbidden indica que o papel do usuário não permite a operação.
+	ErrForbidden = errors.New("seu papel neste lar não permite esta operação")
+)
+
+// Create cria um lar tendo o usuário como proprietário.
+func Create(ownerID int64, name string) (int64, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return 0, fmt.Errorf("o nome do lar é obrigatório")
+	}
+	if len(name) > 60 {
+		return 0, fmt.Errorf("o nome do lar não pode ter mais de 60 caracteres")
+	}
+
+	db := database.GetDB()
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("erro ao iniciar transação: %w", err)
+	}
+	defer tx.Rollback()
+
+	now := time.Now().UTC()
+	var id int64
+	if database.DriverName == "postgres" {
+		err = tx.QueryRow("INSERT INTO households (name, owner_id, created_at) VALUES ($1, $2, $3) RETURNING id", name, ownerID, now).Scan(&id)
+	} else {
+		var res sql.Result
+		res, err = tx.Exec("INSERT INTO households (name, owner_id, created_at) VALUES (?, ?, ?)", name, ownerID, now)
+		if err == nil {
+			id, err = res.LastInsertId()
+		}
+	}
+	if err != nil {
+		return 0, fmt.Errorf("erro ao criar lar: %w", err)
+	}
+
+	query := database.Rebind("INSERT INTO household_members (household_id, user_id, role, created_at) VALUES (?, ?, ?, ?)")
+	if _, err := tx.Exec(query, id, ownerID, models.RoleOwner, now); err != nil {
+		return 0, fmt.Errorf("erro ao registrar proprietário do lar: %w", err)
+	}
+	return id, tx.Commit()
+}
+
+// ListForUser retorna os lares dos quais o usuário participa, com o papel dele em cada um.
+func ListForUser(userID int64) ([]models.Household, error) {
+	query := database.Rebind(`SELECT h.id, h.name, h.owner_id, m.role, h.created_at
+		FROM households h JOIN household_members m ON m.household_id = h.id
+		WHERE m.user_id = ? ORDER BY h.name ASC`)
+	rows, err := database.GetDB().Query(query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao listar lares: %w", err)
+	}
+	defer rows.Close()
+
+	var result []models.Household
+	for rows.Next() {
+		var h models.Household
+		if err := rows.Scan(&h.ID, &h.Name, &h.OwnerID, &h.Role, &h.CreatedAt); err != nil {
+			return nil, fmt.Errorf("erro ao ler lar: %w", err)
+		}
+		result = append(result, h)
+	}
+	return result, rows.Err()
+}
+
+// GetMembership retorna o lar visto pelo usuário, ou ErrNotMember se ele não participar.
+func GetMembership(householdID, userID int64) (*models.Household, error) {
+	var h models.Household
+	query := database.Rebind(`SELECT h.id, h.name, h.owner_id, m.role, h.created_at
+		FROM households h JOIN household_members m ON m.household_id = h.id
+		WHERE h.id = ? AND m.user_id = ?`)
+	err := database.GetDB().QueryRow(query, householdID, userID).Scan(&h.ID, &h.Name, &h.OwnerID, &h.Role, &h.CreatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrNotMember
+		}
+		return nil, fmt.Errorf("erro ao verificar participação no lar: %w", err)
+	}
+	return &h, nil
+}
+
+// ListMembers retorna os membros do lar, começando pelo proprietário.
+func ListMembers(householdID int64) ([]models.HouseholdMember, error) {
+	query := database.Rebind(`SELECT u.id, u.email, m.role FROM household_members m
+		JOIN users u ON u.id = m.user_id
+		WHERE m.household_id = ?
+		ORDER BY CASE m.role WHEN 'owner' THEN 0 WHEN 'editor' THEN 1 ELSE 2 END, u.email ASC`)
+	rows, err := database.GetDB().Query(query, householdID)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao listar membros: %w", err)
+	}
+	defer rows.Close()
+
+	var members []models.HouseholdMember
+	for rows.Next() {
+		var m models.HouseholdMember
+		if err := rows.Scan(&m.UserID, &m.Email, &m.Role); err != nil {
+			return nil, fmt.Errorf("erro ao ler membro: %w", err)
+		}
+		members = append(members, m)
+	}
+	return members, rows.Err()
+}
+
+// AddMember adiciona (ou atualiza o papel de) um usuário existente no lar. Apenas o proprietário pode fazê-lo.
+func AddMember(actorID, householdID int64, email, role string) error {
+	if err := requireOwner(actorID, householdID); err != nil {
+		return err
+	}
+	if !models.IsValidMemberRole(role) {
+		return fmt.Errorf("papel inválido: use 'editor' ou 'viewer'")
+	}
+
+	db := database.GetDB()
+	var userID int64
+	err := db.QueryRow(database.Rebind("SELECT id FROM users WHERE email = ?"), strings.TrimSpace(email)).Scan(&userID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return fmt.Errorf("nenhum usuário cadastrado com o e-mail '%s'", email)
+		}
+		return fmt.Errorf("erro ao buscar usuário: %w", err)
+	}
+	if userID == actorID {
+		return fmt.Errorf("o proprietário já participa do lar")
+	}
+
+	var query string
+	if database.DriverName == "postgres" {
+		query = `INSERT INTO household_members (household_id, user_id, role, created_at) VALUES ($1, $2, $3, $4)
+			ON CONFLICT (household_id, user_id) DO UPDATE SET role = EXCLUDED.role`
+	} else {
+		query = `INSERT INTO household_members (household_id, user_id, role, created_at) VALUES (?, ?, ?, ?)
+			ON CONFLICT (household_id, user_id) DO UPDATE SET role = excluded.role`
+	}
+	if _, err := db.Exec(query, householdID, userID, role, time.Now().UTC()); err != nil {
+		return fmt.Errorf("erro ao adicionar membro: %w", err)
+	}
+	return nil
+}
+
+// RemoveMember remove um membro do lar. O proprietário pode remover qualquer outro membro;
+// os demais só podem remover a si mesmos (sair do lar).
+func RemoveMember(actorID, householdID, memberID int64) error {
+	h, err := GetMembership(householdID, actorID)
+	if err != nil {
+		return err
+	}
+	if memberID == h.OwnerID {
+		return fmt.Errorf("o proprietário não pode sair do lar; exclua o lar se desejar")
+	}
+	if actorID != memberID && !h.CanManage() {
+		return ErrForbidden
+	}
+
+	query := database.Rebind("DELETE FROM household_members WHERE household_id = ? AND user_id = ?")
+	if _, err := database.GetDB().Exec(query, householdID, memberID); err != nil {
+		return fmt.Errorf("erro ao remover membro: %w", err)
+	}
+	return nil
+}
+
+// Delete exclui o lar e, em cascata, suas contas e transações. Apenas o proprietário pode fazê-lo.
+func Delete(actorID, householdID int64) error {
+	if err := requireOwner(actorID, householdID); err != nil {
+		return err
+	}
+
+	db := database.GetDB()
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("erro ao iniciar transação: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := DeleteTx(tx, householdID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// DeleteTx exclui o lar, suas contas e transações dentro da transação informada, sem checar
+// permissões. Usada por Delete e pela exclusão de contas de usuário no console de administração.
+func DeleteTx(tx *sql.Tx, householdID int64) error {
+	// Apagados explicitamente: o SQLite só respeita ON DELETE CASCADE com foreign_keys ativado.
+	for _, query := range []string{
+		fmt.Sprintf("DELETE FROM %s WHERE household_id = ?", database.TableName),
+		"DELETE FROM contas WHERE household_id = ?",
+		"DELETE FROM household_members WHERE household_id = ?",
+		"DELETE FROM households WHERE id = ?",
+	} {
+		if _, err := tx.Exec(database.Rebind(query), householdID); err != nil {
+			return fmt.Errorf("erro ao excluir lar: %w", err)
+		}
+	}
+	return nil
+}
+
+func requireOwner(actorID, householdID int64) error {
+	h, err := GetMembership(householdID, actorID)
+	if err != nil {
+		return err
+	}
+	if !h.CanManage() {
+		return ErrForbidden
+	}
+	return nil
+}
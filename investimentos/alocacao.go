@@ -0,0 +1,421 @@
+package investimentos
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"minhas_economias/database"
+	"minhas_economias/middleware"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Classes de ativos da alocação-alvo.
+const (
+	ClasseAcoes         = "ACAO"
+	ClasseFIIs          = "FII"
+	ClasseInternacional = "INTERNACIONAL"
+	ClasseCripto        = TipoCripto
+	ClasseRendaFixa     = "RENDA_FIXA"
+)
+
+var classesAlocacao = []string{ClasseAcoes, ClasseFIIs, ClasseInternacional, ClasseRendaFixa, ClasseCripto}
+
+var nomesClasses = map[string]string{
+	ClasseAcoes:         "Ações",
+	ClasseFIIs:          "FIIs",
+	ClasseInternacional: "Exterior",
+	ClasseRendaFixa:     "Renda Fixa",
+	ClasseCripto:        "Cripto",
+}
+
+// entityAlocacao é o tipo de entidade da alocação-alvo no log de auditoria.
+const entityAlocacao = "alocacao_alvo"
+
+// toleranciaAlvo é a folga aceita na soma dos percentuais (arredondamentos da planilha).
+const toleranciaAlvo = 0.01
+
+// AlvoAlocacao é o percentual-alvo de uma classe (Ticker vazio) ou de um ativo. O alvo de um
+// ativo é a sua fatia dentro da classe, não da carteira: 50% de PETR4 com 40% em ações são 20%
+// da carteira.
+type AlvoAlocacao struct {
+	Classe     string  `json:"classe"`
+	Ticker     string  `json:"ticker,omitempty"`
+	Percentual float64 `json:"percentual"`
+}
+
+// AlocacaoAtivo compara um ativo com o seu alvo dentro da classe.
+type AlocacaoAtivo struct {
+	Ticker          string  `json:"ticker"`
+	Valor           float64 `json:"valor"`
+	PercentualAtual float64 `json:"percentual_atual"`
+	PercentualAlvo  float64 `json:"percentual_alvo"`
+	TemAlvo         bool    `json:"tem_alvo"`
+	Aporte          float64 `json:"aporte"`
+	Cotacao         float64 `json:"cotacao,omitempty"`
+	Quantidade      int     `json:"quantidade,omitempty"` // Cotas inteiras que cabem no aporte (ações e FIIs)
+}
+
+// AlocacaoClasse compara uma classe com o seu alvo na carteira.
+type AlocacaoClasse struct {
+	Classe          string          `json:"classe"`
+	Nome            string          `json:"nome"`
+	Valor           float64         `json:"valor"`
+	PercentualAtual float64         `json:"percentual_atual"`
+	PercentualAlvo  float64         `json:"percentual_alvo"`
+	Desvio          float64         `json:"desvio"` // Pontos percentuais acima (+) ou abaixo (-) do alvo
+	Aporte          float64         `json:"aporte"`
+	Ativos          []AlocacaoAtivo `json:"ativos"`
+}
+
+// Alocacao é a carteira atual comparada com os alvos e a sugestão de compras para um aporte.
+type Alocacao struct {
+	Total   float64          `json:"total"`
+	Aporte  float64          `json:"aporte"`
+	Classes []AlocacaoClasse `json:"classes"`
+	// DesvioApos é a soma dos desvios absolutos das classes depois do aporte sugerido.
+	DesvioApos float64 `json:"desvio_apos"`
+}
+
+// distribuirAporte divide o aporte entre posições sem vender nada, minimizando a soma dos
+// quadrados das diferenças para o alvo: cada posição abaixo do alvo recebe o que falta menos um
+// mesmo nível λ, escolhido para que os valores somem o aporte. pesos são proporcionais (não
+// precisam somar 1); posições com peso zero não recebem nada.
+func distribuirAporte(atual, pesos []float64, aporte float64) []float64 {
+	x := make([]float64, len(atual))
+	var somaPesos, total float64
+	for i := range atual {
+		somaPesos += pesos[i]
+		total += atual[i]
+	}
+	if aporte <= 0 || somaPesos <= 0 {
+		return x
+	}
+	total += aporte
+	falta := make([]float64, len(atual))
+	var candidatos []int
+	for i := range atual {
+		falta[i] = pesos[i]/somaPesos*total - atual[i]
+		if pesos[i] > 0 {
+			candidatos = append(candidatos, i)
+		}
+	}
+	sort.Slice(candidatos, func(a, b int) bool { return falta[candidatos[a]] > falta[candidatos[b]] })
+	// Com os k maiores déficits recebendo, λ = (soma dos déficits - aporte) / k; o k certo é o
+	// maior em que todos os k ainda ficam acima de λ.
+	var soma, nivel float64
+	for k, i := range candidatos {
+		soma += falta[i]
+		l := (soma - aporte) / float64(k+1)
+		if falta[i] <= l {
+			break
+		}
+		nivel = l
+	}
+	for _, i := range candidatos {
+		if falta[i] > nivel {
+			x[i] = falta[i] - nivel
+		}
+	}
+	return x
+}
+
+// CalcularAlocacao compara os valores atuais (em reais, por classe e ticker) com os alvos e
+// sugere como dividir o aporte. cotacoes, em reais, permite sugerir cotas inteiras de ações e
+// FIIs. Sem alvos de ativos em uma classe, o aporte da classe fica sem divisão por ticker.
+func CalcularAlocacao(valores map[string]map[string]float64, cotacoes map[string]float64, alvos []AlvoAlocacao, aporte float64) Alocacao {
+	alvoClasse := make(map[string]float64)
+	alvoAtivo := make(map[string]map[string]float64)
+	for _, a := range alvos {
+		if a.Ticker == "" {
+			alvoClasse[a.Classe] = a.Percentual
+			continue
+		}
+		if alvoAtivo[a.Classe] == nil {
+			alvoAtivo[a.Classe] = make(map[string]float64)
+		}
+		alvoAtivo[a.Classe][a.Ticker] = a.Percentual
+	}
+
+	al := Alocacao{Aporte: aporte, Classes: []AlocacaoClasse{}}
+	atual := make([]float64, len(classesAlocacao))
+	pesos := make([]float64, len(classesAlocacao))
+	for i, classe := range classesAlocacao {
+		for _, v := range valores[classe] {
+			atual[i] += v
+		}
+		pesos[i] = alvoClasse[classe]
+		al.Total += atual[i]
+	}
+	aportes := distribuirAporte(atual, pesos, aporte)
+
+	totalApos := al.Total + aporte
+	for i, classe := range classesAlocacao {
+		if atual[i] == 0 && pesos[i] == 0 && len(alvoAtivo[classe]) == 0 {
+			continue
+		}
+		ac := AlocacaoClasse{Classe: classe, Nome: nomesClasses[classe], Valor: arredondar(atual[i]), PercentualAlvo: pesos[i], Aporte: arredondar(aportes[i]), Ativos: []AlocacaoAtivo{}}
+		if al.Total > 0 {
+			ac.PercentualAtual = atual[i] / al.Total * 100
+		}
+		ac.Desvio = ac.PercentualAtual - ac.PercentualAlvo
+		if totalApos > 0 {
+			al.DesvioApos += math.Abs((atual[i]+aportes[i])/totalApos*100 - pesos[i])
+		}
+
+		// Ativos da classe: os que estão na carteira e os que só têm alvo (compras novas).
+		tickers := make([]string, 0, len(valores[classe]))
+		for t := range valores[classe] {
+			tickers = append(tickers, t)
+		}
+		for t := range alvoAtivo[classe] {
+			if _, ok := valores[classe][t]; !ok {
+				tickers = append(tickers, t)
+			}
+		}
+		sort.Strings(tickers)
+		atualAtivos := make([]float64, len(tickers))
+		pesosAtivos := make([]float64, len(tickers))
+		for j, t := range tickers {
+			atualAtivos[j] = valores[classe][t]
+			pesosAtivos[j] = alvoAtivo[classe][t]
+		}
+		aportesAtivos := distribuirAporte(atualAtivos, pesosAtivos, aportes[i])
+		for j, t := range tickers {
+			_, temAlvo := alvoAtivo[classe][t]
+			a := AlocacaoAtivo{Ticker: t, Valor: arredondar(atualAtivos[j]), PercentualAlvo: pesosAtivos[j], TemAlvo: temAlvo, Aporte: arredondar(aportesAtivos[j])}
+			if atual[i] > 0 {
+				a.PercentualAtual = atualAtivos[j] / atual[i] * 100
+			}
+			if cotacao := cotacoes[t]; cotacao > 0 && (classe == ClasseAcoes || classe == ClasseFIIs) {
+				a.Cotacao = cotacao
+				a.Quantidade = int(aportesAtivos[j] / cotacao)
+			}
+			ac.Ativos = append(ac.Ativos, a)
+		}
+		al.Classes = append(al.Classes, ac)
+	}
+	al.Total = arredondar(al.Total)
+	al.DesvioApos = arredondar(al.DesvioApos)
+	return al
+}
+
+// validarAlvos normaliza os alvos e confere as somas: os alvos das classes, quando existirem,
+// somam 100%, e os dos ativos de uma classe também.
+func validarAlvos(alvos []AlvoAlocacao) error {
+	vistos := make(map[string]bool)
+	somaClasses := 0.0
+	temClasses := false
+	somaAtivos := make(map[string]float64)
+	for i := range alvos {
+		a := &alvos[i]
+		a.Classe = strings.ToUpper(strings.TrimSpace(a.Classe))
+		a.Ticker = strings.TrimSpace(a.Ticker)
+		if a.Classe != ClasseRendaFixa {
+			a.Ticker = strings.ToUpper(a.Ticker)
+		}
+		if _, ok := nomesClasses[a.Classe]; !ok {
+			return fmt.Errorf("classe '%s' desconhecida; use %s", a.Classe, strings.Join(classesAlocacao, ", "))
+		}
+		if a.Percentual < 0 || a.Percentual > 100 {
+			return fmt.Errorf("o percentual de %s deve estar entre 0 e 100", nomeAlvo(*a))
+		}
+		chave := a.Classe + "|" + a.Ticker
+		if vistos[chave] {
+			return fmt.Errorf("alvo de %s repetido", nomeAlvo(*a))
+		}
+		vistos[chave] = true
+		if a.Ticker == "" {
+			somaClasses += a.Percentual
+			temClasses = true
+		} else {
+			somaAtivos[a.Classe] += a.Percentual
+		}
+	}
+	if temClasses && math.Abs(somaClasses-100) > toleranciaAlvo {
+		return fmt.Errorf("os alvos das classes somam %.2f%%; devem somar 100%%", somaClasses)
+	}
+	for classe, soma := range somaAtivos {
+		if math.Abs(soma-100) > toleranciaAlvo {
+			return fmt.Errorf("os alvos dos ativos de %s somam %.2f%%; devem somar 100%% da classe", nomesClasses[classe], soma)
+		}
+	}
+	return nil
+}
+
+func nomeAlvo(a AlvoAlocacao) string {
+	if a.Ticker == "" {
+		return nomesClasses[a.Classe]
+	}
+	return a.Ticker
+}
+
+// listarAlvos lê a alocação-alvo do usuário.
+func listarAlvos(userID int64) ([]AlvoAlocacao, error) {
+	rows, err := database.GetDB().Query(database.Rebind("SELECT classe, ticker, percentual FROM alocacao_alvo WHERE user_id = ? ORDER BY classe, ticker"), userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	alvos := []AlvoAlocacao{}
+	for rows.Next() {
+		var a AlvoAlocacao
+		if err := rows.Scan(&a.Classe, &a.Ticker, &a.Percentual); err != nil {
+			return nil, err
+		}
+		alvos = append(alvos, a)
+	}
+	return alvos, rows.Err()
+}
+
+// SalvarAlvos substitui a alocação-alvo do usuário.
+func SalvarAlvos(userID int64, alvos []AlvoAlocacao) error {
+	if err := validarAlvos(alvos); err != nil {
+		return err
+	}
+	tx, err := database.GetDB().Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if _, err := tx.Exec(database.Rebind("DELETE FROM alocacao_alvo WHERE user_id = ?"), userID); err != nil {
+		return err
+	}
+	for _, a := range alvos {
+		if _, err := tx.Exec(database.Rebind("INSERT INTO alocacao_alvo (user_id, classe, ticker, percentual) VALUES (?, ?, ?, ?)"), userID, a.Classe, a.Ticker, a.Percentual); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// valoresCarteira agrupa o valor de mercado em reais de cada posição por classe, com as mesmas
+// cotações da API de preços; os títulos de renda fixa entram pelo valor bruto na curva.
+func valoresCarteira(userID int64) (map[string]map[string]float64, map[string]float64, error) {
+	valores := make(map[string]map[string]float64, len(classesAlocacao))
+	for _, classe := range classesAlocacao {
+		valores[classe] = make(map[string]float64)
+	}
+	cotacoes := make(map[string]float64)
+
+	p := BuscarPrecosCarteira(userID)
+	for _, a := range p.Acoes {
+		t := strings.TrimSpace(a.Ticker)
+		valores[ClasseAcoes][t] += a.ValorTotal
+		cotacoes[t] = a.Cotacao
+	}
+	for _, f := range p.FIIs {
+		t := strings.TrimSpace(f.Ticker)
+		valores[ClasseFIIs][t] += f.ValorTotal
+		cotacoes[t] = f.Cotacao
+	}
+	for _, i := range p.Internacionais {
+		valores[ClasseInternacional][strings.TrimSpace(i.Ticker)] += i.ValorTotalBRL
+	}
+	for _, c := range p.Cripto {
+		valores[ClasseCripto][c.Simbolo] += c.ValorTotalBRL
+	}
+
+	titulos, err := listarRendaFixa(userID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(titulos) > 0 {
+		series, err := CarregarSeries()
+		if err != nil {
+			return nil, nil, err
+		}
+		hoje := time.Now()
+		for _, t := range titulos {
+			valores[ClasseRendaFixa][t.Nome] += AvaliarTitulo(t, series, hoje).ValorBruto
+		}
+	}
+	return valores, cotacoes, nil
+}
+
+// completarCotacoes busca na tabela de mercado a cotação dos ativos que só têm alvo (compras
+// novas), para sugerir a quantidade de cotas.
+func completarCotacoes(cotacoes map[string]float64, alvos []AlvoAlocacao) {
+	for _, a := range alvos {
+		if a.Ticker == "" || cotacoes[a.Ticker] > 0 {
+			continue
+		}
+		switch a.Classe {
+		case ClasseAcoes:
+			if dados, err := getDadosMercadoAcoes(); err == nil {
+				cotacoes[a.Ticker] = dados[a.Ticker].Cotacao
+			}
+		case ClasseFIIs:
+			if dados, err := getDadosMercadoFIIs(); err == nil {
+				cotacoes[a.Ticker] = dados[a.Ticker].Cotacao
+			}
+		}
+	}
+}
+
+// GetAlocacaoAPI retorna os alvos, a alocação atual e, com ?aporte=, a sugestão de compras.
+func GetAlocacaoAPI(c *gin.Context) {
+	userID := c.MustGet("userID").(int64)
+	aporte := 0.0
+	if s := strings.TrimSpace(c.Query("aporte")); s != "" {
+		if strings.Contains(s, ",") {
+			s = strings.ReplaceAll(strings.ReplaceAll(s, ".", ""), ",", ".")
+		}
+		v, err := strconv.ParseFloat(s, 64)
+		if err != nil || v < 0 || math.IsNaN(v) || math.IsInf(v, 0) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "O aporte deve ser um valor positivo."})
+			return
+		}
+		aporte = v
+	}
+	alvos, err := listarAlvos(userID)
+	if err != nil {
+		log.Printf("Erro ao listar a alocação-alvo do usuário %d: %v", userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao carregar a alocação-alvo."})
+		return
+	}
+	valores, cotacoes, err := valoresCarteira(userID)
+	if err != nil {
+		log.Printf("Erro ao avaliar a carteira do usuário %d: %v", userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao avaliar a carteira."})
+		return
+	}
+	completarCotacoes(cotacoes, alvos)
+	c.JSON(http.StatusOK, gin.H{
+		"alvos":    alvos,
+		"alocacao": CalcularAlocacao(valores, cotacoes, alvos, aporte),
+	})
+}
+
+// AlocacaoPayload é o corpo da gravação da alocação-alvo (substitui a anterior).
+type AlocacaoPayload struct {
+	Alvos []AlvoAlocacao `json:"alvos"`
+}
+
+// SaveAlocacao grava a alocação-alvo do usuário.
+func SaveAlocacao(c *gin.Context) {
+	userID := c.MustGet("userID").(int64)
+	var payload AlocacaoPayload
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Payload inválido: " + err.Error()})
+		return
+	}
+	if err := validarAlvos(payload.Alvos); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	before, _ := listarAlvos(userID)
+	if err := SalvarAlvos(userID, payload.Alvos); err != nil {
+		log.Printf("Erro ao salvar a alocação-alvo do usuário %d: %v", userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao salvar a alocação-alvo."})
+		return
+	}
+	after, _ := listarAlvos(userID)
+	middleware.SetAuditChange(c, entityAlocacao, userID, before, after)
+	c.JSON(http.StatusOK, gin.H{"message": "Alocação-alvo salva com sucesso!"})
+}
@@ -0,0 +1,95 @@
+package investimentos
+
+import (
+	"encoding/json"
+	"math"
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestDistribuirAporte(t *testing.T) {
+	casos := []struct {
+		atual, pesos []float64
+		aporte       float64
+		esperado     []float64
+	}{
+		// Só a posição abaixo do alvo recebe; as acima dele não são vendidas nem compradas.
+		{[]float64{5000, 1000, 4000}, []float64{40, 30, 30}, 1000, []float64{0, 1000, 0}},
+		// O aporte não basta para zerar os déficits: as duas posições abaixo ficam iguais.
+		{[]float64{100, 300, 600}, []float64{1, 1, 1}, 300, []float64{250, 50, 0}},
+		// Carteira vazia segue os pesos.
+		{[]float64{0, 0}, []float64{50, 50}, 1000, []float64{500, 500}},
+		// Peso zero não recebe nada.
+		{[]float64{0, 0}, []float64{100, 0}, 1000, []float64{1000, 0}},
+		{[]float64{10, 20}, []float64{0, 0}, 1000, []float64{0, 0}},
+	}
+	for _, c := range casos {
+		x := distribuirAporte(c.atual, c.pesos, c.aporte)
+		for i := range x {
+			if math.Abs(x[i]-c.esperado[i]) > 1e-9 {
+				t.Errorf("distribuirAporte(%v, %v, %v) = %v, esperado %v", c.atual, c.pesos, c.aporte, x, c.esperado)
+				break
+			}
+		}
+	}
+}
+
+func TestAlocacaoAPI(t *testing.T) {
+	setupInvestimentosTestDB(t)
+	defer teardownInvestimentosTestDB()
+	SetProvider(FixtureProvider{Dir: "../downloads"})
+	defer SetProvider(nil)
+	router := createInvestimentosTestRouter()
+
+	invalido := gin.H{"alvos": []gin.H{{"classe": "ACAO", "percentual": 60}, {"classe": "FII", "percentual": 30}}}
+	if w := performInvestimentosJSONRequest(router, "POST", "/investimentos/alocacao", invalido); w.Code != http.StatusBadRequest {
+		t.Errorf("Alvos somando 90%% deveriam ser rejeitados, status %d", w.Code)
+	}
+	alvos := gin.H{"alvos": []gin.H{
+		{"classe": "ACAO", "percentual": 40},
+		{"classe": "FII", "percentual": 40},
+		{"classe": "INTERNACIONAL", "percentual": 20},
+		{"classe": "acao", "ticker": "petr4", "percentual": 50},
+		{"classe": "ACAO", "ticker": "VALE3", "percentual": 50},
+	}}
+	if w := performInvestimentosJSONRequest(router, "POST", "/investimentos/alocacao", alvos); w.Code != http.StatusOK {
+		t.Fatalf("Esperado status 200 ao salvar os alvos, obtido %d: %s", w.Code, w.Body.String())
+	}
+
+	// Carteira: PETR4 R$ 3.252,00, MXRF11 R$ 474,50 e VOO (US$ 6.009,68 a R$ 5,57) muito acima
+	// dos 20%; o aporte vai todo para ações e FIIs.
+	w := performInvestimentosJSONRequest(router, "GET", "/api/investimentos/alocacao?aporte=10000", nil)
+	var resp struct {
+		Alvos    []AlvoAlocacao `json:"alvos"`
+		Alocacao Alocacao       `json:"alocacao"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	if w.Code != http.StatusOK || len(resp.Alvos) != 5 || len(resp.Alocacao.Classes) != 3 {
+		t.Fatalf("Resposta inesperada (%d): %s", w.Code, w.Body.String())
+	}
+	soma := 0.0
+	for _, c := range resp.Alocacao.Classes {
+		soma += c.Aporte
+		if c.Classe == ClasseInternacional && c.Aporte != 0 {
+			t.Errorf("Classe acima do alvo não deveria receber aporte: %+v", c)
+		}
+		if c.Classe == ClasseAcoes {
+			if len(c.Ativos) != 2 || c.Ativos[1].Ticker != "VALE3" || c.Ativos[1].Cotacao <= 0 || c.Ativos[1].Quantidade != int(c.Ativos[1].Aporte/c.Ativos[1].Cotacao) {
+				t.Errorf("Sugestão de ações inesperada: %+v", c.Ativos)
+			}
+			if math.Abs(c.Ativos[0].Aporte+c.Ativos[1].Aporte-c.Aporte) > 0.02 {
+				t.Errorf("O aporte da classe deveria ser dividido entre os ativos: %+v", c)
+			}
+		}
+	}
+	if math.Abs(soma-10000) > 0.05 {
+		t.Errorf("Os aportes das classes somam %.2f, esperado 10000", soma)
+	}
+	for _, aporte := range []string{"abc", "NaN", "Inf", "-1"} {
+		if w := performInvestimentosJSONRequest(router, "GET", "/api/investimentos/alocacao?aporte="+aporte, nil); w.Code != http.StatusBadRequest {
+			t.Errorf("Aporte %q: esperado 400, obtido %d", aporte, w.Code)
+		}
+	}
+}
@@ -0,0 +1,223 @@
+package investimentos
+
+import (
+	"fmt"
+	"log"
+	"minhas_economias/config"
+	"minhas_economias/database"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// horarioBrasilia é o fuso da B3 (sem horário de verão desde 2019).
+var horarioBrasilia = time.FixedZone("BRT", -3*60*60)
+
+// ttlFundamentos é a idade máxima de LPA e VPA antes de o agendador buscá-los de novo: os
+// fundamentos só mudam com os balanços e cada ação é uma visita ao StatusInvest.
+const ttlFundamentos = 24 * time.Hour
+
+// pausaFundamentos espaça as visitas do agendador ao StatusInvest.
+var pausaFundamentos = time.Second
+
+var (
+	// atualizacaoAtiva indica que o agendador mantém o cache; as requisições usam o último
+	// snapshot em vez de consultar os provedores.
+	atualizacaoAtiva atomic.Bool
+	// atualizacaoMu impede duas atualizações completas ao mesmo tempo.
+	atualizacaoMu sync.Mutex
+)
+
+// AtualizacaoConfig configura a atualização periódica das cotações.
+type AtualizacaoConfig struct {
+	Intervalo  time.Duration // 0 desativa: as cotações são buscadas nas requisições
+	Abertura   time.Duration // Início da janela, desde a meia-noite (horário de Brasília)
+	Fechamento time.Duration // Fim da janela (exclusivo)
+}
+
+// AtualizacaoConfigFromEnv lê MARKET_DATA_REFRESH_MINUTES (padrão 15; 0 desativa) e
+// MARKET_DATA_REFRESH_WINDOW (padrão "10:00-18:30", horário de Brasília).
+func AtualizacaoConfigFromEnv() AtualizacaoConfig {
+	cfg := AtualizacaoConfig{Intervalo: 15 * time.Minute, Abertura: 10 * time.Hour, Fechamento: 18*time.Hour + 30*time.Minute}
+	cfg.Intervalo = time.Duration(config.IntFromEnv("MARKET_DATA_REFRESH_MINUTES", 15, 0)) * time.Minute
+	if value := os.Getenv("MARKET_DATA_REFRESH_WINDOW"); value != "" {
+		if abertura, fechamento, err := parseJanela(value); err == nil {
+			cfg.Abertura, cfg.Fechamento = abertura, fechamento
+		} else {
+			log.Printf("Aviso: MARKET_DATA_REFRESH_WINDOW inválido (%q): %v; usando 10:00-18:30.", value, err)
+		}
+	}
+	return cfg
+}
+
+// parseJanela lê uma janela "HH:MM-HH:MM".
+func parseJanela(s string) (time.Duration, time.Duration, error) {
+	partes := strings.Split(s, "-")
+	if len(partes) != 2 {
+		return 0, 0, fmt.Errorf("use o formato HH:MM-HH:MM")
+	}
+	var limites [2]time.Duration
+	for i, parte := range partes {
+		t, err := time.Parse("15:04", strings.TrimSpace(parte))
+		if err != nil {
+			return 0, 0, fmt.Errorf("horário '%s' inválido", strings.TrimSpace(parte))
+		}
+		limites[i] = time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute
+	}
+	if limites[0] >= limites[1] {
+		return 0, 0, fmt.Errorf("a abertura deve ser anterior ao fechamento")
+	}
+	return limites[0], limites[1], nil
+}
+
+// pregaoAberto indica se o momento cai em um dia útil dentro da janela, no horário de Brasília.
+// Feriados não são considerados.
+func (cfg AtualizacaoConfig) pregaoAberto(t time.Time) bool {
+	t = t.In(horarioBrasilia)
+	if !diaUtil(t) {
+		return false
+	}
+	desdeMeiaNoite := time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute
+	return desdeMeiaNoite >= cfg.Abertura && desdeMeiaNoite < cfg.Fechamento
+}
+
+// AtualizacoesCarteira retorna, para cada classe com ativos cotados em p, quando foi obtida a
+// cotação mais antiga usada nela, segundo o cache. Os ativos do exterior incluem o dólar.
+func AtualizacoesCarteira(p PrecosCarteira) map[string]time.Time {
+	atualizacoes := make(map[string]time.Time)
+	considerar := func(classe, key string) {
+		guardadoEm, found := guardadoNoCache(key)
+		if !found {
+			return
+		}
+		if atual, ok := atualizacoes[classe]; !ok || guardadoEm.Before(atual) {
+			atualizacoes[classe] = guardadoEm
+		}
+	}
+	if len(p.Acoes) > 0 {
+		considerar("acoes", cacheAcoes)
+	}
+	if len(p.FIIs) > 0 {
+		considerar("fiis", cacheFIIs)
+	}
+	for _, a := range p.Internacionais {
+		considerar("internacionais", cacheInternacionais+strings.TrimSpace(a.Ticker))
+	}
+	if len(p.Internacionais) > 0 {
+		considerar("internacionais", cacheDolar)
+	}
+	for _, a := range p.Cripto {
+		considerar("cripto", cacheCripto+a.Simbolo)
+	}
+	return atualizacoes
+}
+
+// tickersEmCarteira lista os valores distintos da primeira coluna da consulta (sem parâmetros).
+func tickersEmCarteira(query string) ([]string, error) {
+	rows, err := database.GetDB().Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var tickers []string
+	for rows.Next() {
+		var ticker string
+		if err := rows.Scan(&ticker); err != nil {
+			return nil, err
+		}
+		tickers = append(tickers, strings.TrimSpace(ticker))
+	}
+	return tickers, rows.Err()
+}
+
+// AtualizarPrecos busca de uma vez as cotações usadas por todos os usuários (tabelas de ações e
+// FIIs, dólar, ativos do exterior e criptoativos em carteira, além dos fundamentos com mais de
+// um dia) e as grava no cache compartilhado e no histórico de preços. Uma classe que falha
+// mantém o snapshot anterior; os erros são reunidos no retorno.
+func AtualizarPrecos() error {
+	atualizacaoMu.Lock()
+	defer atualizacaoMu.Unlock()
+	var errs []string
+	falha := func(classe string, err error) {
+		errs = append(errs, fmt.Sprintf("%s: %v", classe, err))
+	}
+
+	if _, err := buscarDadosMercadoAcoes(); err != nil {
+		falha("ações", err)
+	}
+	if _, err := buscarDadosMercadoFIIs(); err != nil {
+		falha("FIIs", err)
+	}
+	if _, err := buscarCotacaoDolar(); err != nil {
+		falha("dólar", err)
+	}
+
+	if tickers, err := tickersEmCarteira("SELECT DISTINCT ticker FROM investimentos_internacionais"); err != nil {
+		falha("exterior", err)
+	} else if len(tickers) > 0 {
+		if _, err := buscarPrecosInternacionais(tickers); err != nil {
+			falha("exterior", err)
+		}
+	}
+
+	if simbolos, err := tickersEmCarteira("SELECT DISTINCT simbolo FROM investimentos_cripto"); err != nil {
+		falha("cripto", err)
+	} else if len(simbolos) > 0 {
+		if _, err := buscarPrecosCripto(simbolos); err != nil {
+			falha("cripto", err)
+		}
+	}
+
+	acoes, err := tickersEmCarteira("SELECT DISTINCT ticker FROM investimentos_nacionais WHERE tipo = 'ACAO' OR tipo = 'Acao'")
+	if err != nil {
+		falha("fundamentos", err)
+	}
+	visitadas := 0
+	for _, ticker := range acoes {
+		if idade, ok := idadeCache(chaveFundamentos(ticker)); ok && idade < ttlFundamentos {
+			continue
+		}
+		if visitadas > 0 {
+			time.Sleep(pausaFundamentos)
+		}
+		visitadas++
+		// Sem fundamentos, o serviço deriva LPA e VPA da tabela de ações; a falha só é registrada.
+		if _, _, err := buscarFundamentos(ticker); err != nil {
+			log.Printf("AVISO (Graham): Falha ao atualizar LPA/VPA de %s: %v", ticker, err)
+		}
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("falha ao atualizar %s", strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// StartAtualizacaoPrecos atualiza as cotações na inicialização e depois a cada cfg.Intervalo
+// enquanto o pregão estiver aberto. Com o agendador ativo, as requisições respondem com o
+// último snapshot; ativos ainda sem cotação (recém-cadastrados) são buscados na hora.
+func StartAtualizacaoPrecos(cfg AtualizacaoConfig) {
+	if cfg.Intervalo <= 0 {
+		log.Println("Atualização automática das cotações desativada (MARKET_DATA_REFRESH_MINUTES=0); as cotações serão buscadas a cada requisição.")
+		return
+	}
+	atualizacaoAtiva.Store(true)
+	run := func() {
+		inicio := time.Now()
+		if err := AtualizarPrecos(); err != nil {
+			log.Printf("AVISO: Atualização das cotações incompleta: %v", err)
+		} else {
+			log.Printf("[MarketData] Cotações atualizadas em %s.", time.Since(inicio).Round(time.Millisecond))
+		}
+	}
+	go func() {
+		run()
+		for t := range time.Tick(cfg.Intervalo) {
+			if cfg.pregaoAberto(t) {
+				run()
+			}
+		}
+	}()
+}
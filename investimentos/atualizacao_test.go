@@ -0,0 +1,90 @@
+package investimentos
+
+import (
+	"encoding/json"
+	"minhas_economias/database"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestAtualizacaoPrecos(t *testing.T) {
+	setupInvestimentosTestDB(t)
+	defer teardownInvestimentosTestDB()
+	SetProvider(FixtureProvider{Dir: "../downloads"})
+	defer SetProvider(nil)
+	SetCriptoProvider(FixtureProvider{Dir: "../downloads"})
+	defer SetCriptoProvider(nil)
+	pausaFundamentos = 0
+	defer func() { pausaFundamentos = time.Second }()
+	atualizacaoAtiva.Store(true)
+	defer atualizacaoAtiva.Store(false)
+	router := createInvestimentosTestRouter()
+	database.GetDB().Exec("INSERT INTO investimentos_cripto (user_id, simbolo, quantidade) VALUES (?, 'BTC', '0.5')", testUserID)
+
+	if err := AtualizarPrecos(); err != nil {
+		t.Fatalf("Erro inesperado na atualização: %v", err)
+	}
+	for _, ticker := range []string{"PETR4", "MXRF11", "VOO", "USDBRL", "BTC-USD"} {
+		if precos, _ := HistoricoPrecos(ticker, "", ""); len(precos) != 1 {
+			t.Errorf("Esperado um preço de %s no histórico, obtidos %d", ticker, len(precos))
+		}
+	}
+
+	// Com o agendador ativo, a API responde com o snapshot mesmo com o provedor fora do ar.
+	providerMu.Lock()
+	provider = falhaProvider{}
+	providerMu.Unlock()
+	ClearNacionalCache()
+	w := performInvestimentosJSONRequest(router, "GET", "/api/investimentos/precos", nil)
+	var resp struct {
+		Acoes        []AcaoNacional `json:"acoes"`
+		AtualizadoEm *time.Time     `json:"atualizado_em"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	if w.Code != http.StatusOK || len(resp.Acoes) != 1 || resp.Acoes[0].Cotacao != 32.52 || resp.AtualizadoEm == nil || time.Since(*resp.AtualizadoEm) > time.Minute {
+		t.Errorf("Resposta inesperada (%d): %s", w.Code, w.Body.String())
+	}
+
+	// Cada classe informa a própria cotação; atualizado_em é a mais antiga usada na resposta.
+	antiga := time.Now().Add(-time.Hour).Truncate(time.Second)
+	cacheMutex.Lock()
+	cache[cacheFIIs].Timestamp = antiga
+	cacheMutex.Unlock()
+	w = performInvestimentosJSONRequest(router, "GET", "/api/investimentos/precos", nil)
+	var porClasse struct {
+		AtualizadoEm *time.Time           `json:"atualizado_em"`
+		Atualizacoes map[string]time.Time `json:"atualizacoes"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &porClasse)
+	if porClasse.AtualizadoEm == nil || !porClasse.AtualizadoEm.Equal(antiga) || !porClasse.Atualizacoes["fiis"].Equal(antiga) || time.Since(porClasse.Atualizacoes["acoes"]) > time.Minute {
+		t.Errorf("Datas das cotações inesperadas: %s", w.Body.String())
+	}
+}
+
+func TestAtualizacaoConfig(t *testing.T) {
+	t.Setenv("MARKET_DATA_REFRESH_MINUTES", "5")
+	t.Setenv("MARKET_DATA_REFRESH_WINDOW", "09:45-17:15")
+	cfg := AtualizacaoConfigFromEnv()
+	if cfg.Intervalo != 5*time.Minute || cfg.Abertura != 9*time.Hour+45*time.Minute || cfg.Fechamento != 17*time.Hour+15*time.Minute {
+		t.Fatalf("Configuração inesperada: %+v", cfg)
+	}
+	// 13h UTC é 10h em Brasília.
+	casos := map[string]bool{
+		"2025-07-09T13:00:00Z": true,  // quarta-feira, 10h
+		"2025-07-09T12:30:00Z": false, // antes da abertura
+		"2025-07-09T20:15:00Z": false, // fechamento (exclusivo)
+		"2025-07-12T15:00:00Z": false, // sábado
+	}
+	for quando, esperado := range casos {
+		instante, _ := time.Parse(time.RFC3339, quando)
+		if cfg.pregaoAberto(instante) != esperado {
+			t.Errorf("pregaoAberto(%s): esperado %v", quando, esperado)
+		}
+	}
+
+	t.Setenv("MARKET_DATA_REFRESH_WINDOW", "18:00-10:00")
+	if cfg := AtualizacaoConfigFromEnv(); cfg.Abertura != 10*time.Hour {
+		t.Errorf("Janela invertida deveria usar o padrão, obtido %+v", cfg)
+	}
+}
@@ -0,0 +1,149 @@
+package investimentos
+
+import (
+	"container/list"
+	"log"
+	"minhas_economias/config"
+	"minhas_economias/middleware"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CacheItem é um dado de mercado guardado com a sua validade.
+type CacheItem struct {
+	Data      interface{}
+	Timestamp time.Time
+	Expira    time.Time
+	elemento  *list.Element // Posição da chave em cacheLRU
+}
+
+// cacheMaxItensPadrao limita o cache quando MARKET_DATA_CACHE_SIZE não é definida.
+const cacheMaxItensPadrao = 2000
+
+var (
+	cache         = make(map[string]*CacheItem)
+	cacheLRU      = list.New() // Chaves da usada mais recentemente para a menos recente
+	cacheMutex    = &sync.Mutex{}
+	cacheTTL      = 15 * time.Minute
+	cacheMaxItens = cacheMaxItensPadrao
+)
+
+// Chaves do cache de cotações. As cotações avulsas usam o tipo como prefixo seguido do ticker
+// (internacional:VOO, cripto:BTC, fundamentos:PETR4); o prefixo é o rótulo das métricas.
+const (
+	cacheAcoes          = "acoes"
+	cacheFIIs           = "fiis"
+	cacheDolar          = "dolar"
+	cacheInternacionais = "internacional:"
+	cacheCripto         = "cripto:"
+	cacheFundamentos    = "fundamentos:"
+)
+
+// tipoCache retorna o rótulo da chave nas métricas.
+func tipoCache(key string) string {
+	tipo, _, _ := strings.Cut(key, ":")
+	return tipo
+}
+
+// CacheSizeFromEnv lê MARKET_DATA_CACHE_SIZE, o número máximo de itens no cache de cotações.
+func CacheSizeFromEnv() int {
+	return config.IntFromEnv("MARKET_DATA_CACHE_SIZE", cacheMaxItensPadrao, 1)
+}
+
+// SetCacheSize define o número máximo de itens, descartando os menos usados que sobrarem.
+func SetCacheSize(n int) {
+	cacheMutex.Lock()
+	defer cacheMutex.Unlock()
+	cacheMaxItens = n
+	descartarExcedentes()
+}
+
+// getFromCache retorna o dado guardado e marca a chave como usada. Com a atualização em segundo
+// plano ativa, os itens não expiram: o cache é o último snapshot e o agendador o substitui.
+func getFromCache(key string) (interface{}, bool) {
+	cacheMutex.Lock()
+	defer cacheMutex.Unlock()
+	item, found := cache[key]
+	if !found || (!atualizacaoAtiva.Load() && time.Now().After(item.Expira)) {
+		middleware.MarketDataCacheMisses.WithLabelValues(tipoCache(key)).Inc()
+		return nil, false
+	}
+	middleware.MarketDataCacheHits.WithLabelValues(tipoCache(key)).Inc()
+	cacheLRU.MoveToFront(item.elemento)
+	return item.Data, true
+}
+
+// idadeCache retorna há quanto tempo o item foi guardado.
+func idadeCache(key string) (time.Duration, bool) {
+	guardadoEm, found := guardadoNoCache(key)
+	if !found {
+		return 0, false
+	}
+	return time.Since(guardadoEm), true
+}
+
+// guardadoNoCache retorna quando o item foi guardado (CacheItem.Timestamp).
+func guardadoNoCache(key string) (time.Time, bool) {
+	cacheMutex.Lock()
+	defer cacheMutex.Unlock()
+	item, found := cache[key]
+	if !found {
+		return time.Time{}, false
+	}
+	return item.Timestamp, true
+}
+
+// setToCache guarda o dado com a validade padrão (cacheTTL).
+func setToCache(key string, data interface{}) {
+	setToCacheTTL(key, data, cacheTTL)
+}
+
+// setToCacheTTL guarda o dado válido por ttl. Quando o cache passa do limite, os itens usados há
+// mais tempo são descartados.
+func setToCacheTTL(key string, data interface{}, ttl time.Duration) {
+	cacheMutex.Lock()
+	defer cacheMutex.Unlock()
+	agora := time.Now()
+	if item, found := cache[key]; found {
+		item.Data, item.Timestamp, item.Expira = data, agora, agora.Add(ttl)
+		cacheLRU.MoveToFront(item.elemento)
+		return
+	}
+	cache[key] = &CacheItem{Data: data, Timestamp: agora, Expira: agora.Add(ttl), elemento: cacheLRU.PushFront(key)}
+	descartarExcedentes()
+}
+
+// descartarExcedentes remove os itens menos usados acima do limite; exige cacheMutex.
+func descartarExcedentes() {
+	for len(cache) > cacheMaxItens {
+		elemento := cacheLRU.Back()
+		key := elemento.Value.(string)
+		cacheLRU.Remove(elemento)
+		delete(cache, key)
+		middleware.MarketDataCacheEvictions.WithLabelValues(tipoCache(key)).Inc()
+	}
+}
+
+// limparCache remove as chaves com o prefixo (vazio remove tudo).
+func limparCache(prefixo string) {
+	cacheMutex.Lock()
+	defer cacheMutex.Unlock()
+	for key, item := range cache {
+		if strings.HasPrefix(key, prefixo) {
+			cacheLRU.Remove(item.elemento)
+			delete(cache, key)
+		}
+	}
+}
+
+// ClearNacionalCache remove os dados de Ações e FIIs do cache. Com a atualização em segundo
+// plano ativa, nada é removido: as tabelas cobrem o mercado inteiro e o agendador as renova.
+func ClearNacionalCache() {
+	if atualizacaoAtiva.Load() {
+		return
+	}
+	limparCache(cacheAcoes)
+	limparCache(cacheFIIs)
+	log.Println("[Cache] Cache de ativos nacionais (Ações e FIIs) limpo.")
+}
@@ -0,0 +1,67 @@
+package investimentos
+
+import (
+	"minhas_economias/middleware"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+// contadorProvider registra os tickers internacionais pedidos às fixtures.
+type contadorProvider struct {
+	FixtureProvider
+	pedidos *[][]string
+}
+
+func (p contadorProvider) PrecosInternacionais(tickers []string) (map[string]Cotacao, error) {
+	*p.pedidos = append(*p.pedidos, tickers)
+	return p.FixtureProvider.PrecosInternacionais(tickers)
+}
+
+func TestCachePrecosInternacionais(t *testing.T) {
+	setupInvestimentosTestDB(t)
+	defer teardownInvestimentosTestDB()
+	var pedidos [][]string
+	SetProvider(contadorProvider{FixtureProvider{Dir: "../downloads"}, &pedidos})
+	defer SetProvider(nil)
+	hits := func() float64 {
+		return testutil.ToFloat64(middleware.MarketDataCacheHits.WithLabelValues("internacional"))
+	}
+	misses := func() float64 {
+		return testutil.ToFloat64(middleware.MarketDataCacheMisses.WithLabelValues("internacional"))
+	}
+	hitsAntes, missesAntes := hits(), misses()
+
+	// Cada ticker é guardado separadamente: a segunda carteira só pede o que faltava.
+	getPrecosInternacionais([]string{"VOO", "IVV"})
+	precos, err := getPrecosInternacionais([]string{"VOO", "AAPL"})
+	if err != nil || precos["VOO"] != 572.35 || precos["AAPL"] != 211.14 {
+		t.Fatalf("Preços inesperados: %v, %v", precos, err)
+	}
+	if len(pedidos) != 2 || len(pedidos[1]) != 1 || pedidos[1][0] != "AAPL" {
+		t.Errorf("Esperado pedir só AAPL na segunda consulta, pedidos: %v", pedidos)
+	}
+	if hits()-hitsAntes != 1 || misses()-missesAntes != 3 {
+		t.Errorf("Métricas inesperadas: %v acertos, %v faltas", hits()-hitsAntes, misses()-missesAntes)
+	}
+
+	// Cada item expira com a própria validade.
+	setToCacheTTL(cacheInternacionais+"VOO", 500.0, -time.Second)
+	getPrecosInternacionais([]string{"VOO", "IVV"})
+	if len(pedidos) != 3 || len(pedidos[2]) != 1 || pedidos[2][0] != "VOO" {
+		t.Errorf("Esperado pedir só VOO após expirar, pedidos: %v", pedidos)
+	}
+
+	// Acima do limite, sai o item usado há mais tempo (AAPL, já que VOO e IVV acabaram de ser lidos).
+	SetCacheSize(3)
+	defer SetCacheSize(cacheMaxItensPadrao)
+	evictions := testutil.ToFloat64(middleware.MarketDataCacheEvictions.WithLabelValues("internacional"))
+	getPrecosInternacionais([]string{"QQQ"})
+	if _, ok := idadeCache(cacheInternacionais + "AAPL"); ok || len(cache) != 3 {
+		t.Errorf("Esperado descartar AAPL, cache com %d itens", len(cache))
+	}
+	if testutil.ToFloat64(middleware.MarketDataCacheEvictions.WithLabelValues("internacional"))-evictions != 1 {
+		t.Error("Esperada uma remoção por falta de espaço nas métricas")
+	}
+}
@@ -0,0 +1,493 @@
+package investimentos
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"minhas_economias/database"
+	"minhas_economias/middleware"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Criptoativos ficam em investimentos_cripto com a quantidade guardada como decimal exato
+// (texto no SQLite, NUMERIC no PostgreSQL): frações de satoshi ou de wei não cabem em REAL
+// sem arredondamento, e somas sucessivas acumulariam erro.
+
+// casasCripto é a precisão máxima da quantidade (a menor fração do ether, o wei, é 10^-18).
+const casasCripto = 18
+
+// TipoCripto identifica os criptoativos na avaliação da carteira.
+const TipoCripto = "CRIPTO"
+
+const entityCripto = "investimento_cripto"
+
+var escalaCripto = new(big.Rat).SetInt(new(big.Int).Exp(big.NewInt(10), big.NewInt(casasCripto), nil))
+
+// tickerCripto é o ticker da cotação em USD no histórico de preços, no padrão do Yahoo
+// (BTC-USD), para não confundir o símbolo com ações de mesmo nome.
+func tickerCripto(simbolo string) string {
+	return simbolo + "-USD"
+}
+
+// parseQuantidadeCripto lê uma quantidade decimal ("0.00012345" ou "0,00012345") sem passar
+// por float64; rejeita valores não positivos e com mais de 18 casas.
+func parseQuantidadeCripto(s string) (*big.Rat, error) {
+	s = strings.TrimSpace(s)
+	if strings.Contains(s, ",") {
+		s = strings.ReplaceAll(strings.ReplaceAll(s, ".", ""), ",", ".")
+	}
+	q, ok := new(big.Rat).SetString(s)
+	if !ok || strings.Contains(s, "/") {
+		return nil, fmt.Errorf("quantidade inválida '%s'", s)
+	}
+	if q.Sign() <= 0 {
+		return nil, errors.New("a quantidade deve ser positiva")
+	}
+	if !new(big.Rat).Mul(q, escalaCripto).IsInt() {
+		return nil, fmt.Errorf("a quantidade aceita no máximo %d casas decimais", casasCripto)
+	}
+	return q, nil
+}
+
+// formatarQuantidadeCripto escreve a quantidade sem zeros à direita ("0.5", "1").
+func formatarQuantidadeCripto(q *big.Rat) string {
+	s := q.FloatString(casasCripto)
+	s = strings.TrimRight(strings.TrimRight(s, "0"), ".")
+	return s
+}
+
+// quantidadeFloat converte a quantidade guardada para a avaliação em dinheiro.
+func quantidadeFloat(s string) float64 {
+	q, ok := new(big.Rat).SetString(strings.TrimSpace(s))
+	if !ok {
+		return 0
+	}
+	f, _ := q.Float64()
+	return f
+}
+
+// --- Provedores de preço ---
+
+// CriptoProvider é uma fonte de preços de criptoativos, separada de MarketDataProvider
+// porque as fontes (exchanges e agregadores) não têm nada em comum com as de ações.
+type CriptoProvider interface {
+	Nome() string
+	// PrecosCripto retorna o preço em USD dos símbolos encontrados (BTC, ETH...).
+	PrecosCripto(simbolos []string) (map[string]Cotacao, error)
+}
+
+var criptoProvider CriptoProvider
+
+// CriptoProviderFromEnv monta a cadeia de CRIPTO_PROVIDERS, uma lista separada por vírgulas
+// tentada em ordem (padrão "coingecko"). Nomes aceitos: coingecko e fixtures (arquivo
+// cotacoes_cripto_AAAA-MM-DD.csv em MARKET_DATA_FIXTURES_DIR).
+func CriptoProviderFromEnv() (CriptoProvider, error) {
+	spec := os.Getenv("CRIPTO_PROVIDERS")
+	if spec == "" {
+		spec = "coingecko"
+	}
+	fixturesDir := os.Getenv("MARKET_DATA_FIXTURES_DIR")
+	if fixturesDir == "" {
+		fixturesDir = "downloads"
+	}
+	var chain FallbackCriptoProvider
+	for _, name := range strings.Split(spec, ",") {
+		switch name = strings.ToLower(strings.TrimSpace(name)); name {
+		case "coingecko":
+			chain = append(chain, CoinGeckoProvider{})
+		case "fixtures":
+			chain = append(chain, FixtureProvider{Dir: fixturesDir})
+		case "":
+		default:
+			return nil, fmt.Errorf("provedor de criptoativos '%s' desconhecido em CRIPTO_PROVIDERS", name)
+		}
+	}
+	if len(chain) == 1 {
+		return chain[0], nil
+	}
+	return chain, nil
+}
+
+// SetCriptoProvider substitui o provedor de criptoativos e limpa as cotações em cache.
+func SetCriptoProvider(p CriptoProvider) {
+	providerMu.Lock()
+	criptoProvider = p
+	providerMu.Unlock()
+	ClearCriptoCache()
+}
+
+// ProviderCripto retorna o provedor de criptoativos; sem configuração, usa o CoinGecko.
+func ProviderCripto() CriptoProvider {
+	providerMu.RLock()
+	p := criptoProvider
+	providerMu.RUnlock()
+	if p != nil {
+		return p
+	}
+	return CoinGeckoProvider{}
+}
+
+// FallbackCriptoProvider pede a cada provedor, em ordem, os símbolos que os anteriores não
+// retornaram.
+type FallbackCriptoProvider []CriptoProvider
+
+func (f FallbackCriptoProvider) Nome() string { return nomesEmCadeia(f) }
+
+func (f FallbackCriptoProvider) PrecosCripto(simbolos []string) (map[string]Cotacao, error) {
+	return precosEmCadeia(f, "preços de criptoativos", simbolos, CriptoProvider.PrecosCripto)
+}
+
+// coingeckoIDs traduz os símbolos mais comuns para os identificadores da API do CoinGecko.
+var coingeckoIDs = map[string]string{
+	"BTC":   "bitcoin",
+	"ETH":   "ethereum",
+	"SOL":   "solana",
+	"BNB":   "binancecoin",
+	"XRP":   "ripple",
+	"ADA":   "cardano",
+	"DOGE":  "dogecoin",
+	"DOT":   "polkadot",
+	"LTC":   "litecoin",
+	"LINK":  "chainlink",
+	"AVAX":  "avalanche-2",
+	"MATIC": "matic-network",
+	"USDT":  "tether",
+	"USDC":  "usd-coin",
+}
+
+// CoinGeckoProvider consulta a API pública de preços simples do CoinGecko. Símbolos fora de
+// coingeckoIDs ficam sem preço (e passam ao próximo provedor da cadeia).
+type CoinGeckoProvider struct{}
+
+func (CoinGeckoProvider) Nome() string { return "coingecko" }
+
+func (CoinGeckoProvider) PrecosCripto(simbolos []string) (map[string]Cotacao, error) {
+	ids := make(map[string]string)
+	for _, s := range simbolos {
+		if id, ok := coingeckoIDs[strings.ToUpper(s)]; ok {
+			ids[id] = s
+		}
+	}
+	if len(ids) == 0 {
+		return map[string]Cotacao{}, nil
+	}
+	lista := make([]string, 0, len(ids))
+	for id := range ids {
+		lista = append(lista, id)
+	}
+	sort.Strings(lista)
+	endereco := "https://api.coingecko.com/api/v3/simple/price?vs_currencies=usd&ids=" + url.QueryEscape(strings.Join(lista, ","))
+	client := &http.Client{Timeout: 15 * time.Second}
+	resp, err := client.Get(endereco)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("status %d do CoinGecko", resp.StatusCode)
+	}
+	var result map[string]struct {
+		USD float64 `json:"usd"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, err
+	}
+	precos := make(map[string]Cotacao, len(result))
+	for id, preco := range result {
+		if simbolo, ok := ids[id]; ok && preco.USD > 0 {
+			precos[simbolo] = Cotacao{Preco: preco.USD, Origem: Origem{Fonte: "coingecko"}}
+		}
+	}
+	return precos, nil
+}
+
+// --- Carteira ---
+
+// getPrecosCripto retorna as cotações em USD dos símbolos, guardando cada uma no cache; só os
+// símbolos sem cotação em cache são pedidos ao provedor.
+func getPrecosCripto(simbolos []string) (map[string]Cotacao, error) {
+	precos := make(map[string]Cotacao, len(simbolos))
+	var faltando []string
+	for _, s := range simbolos {
+		if data, found := getFromCache(cacheCripto + s); found {
+			precos[s] = data.(Cotacao)
+		} else {
+			faltando = append(faltando, s)
+		}
+	}
+	if len(faltando) == 0 {
+		return precos, nil
+	}
+	cotacoes, err := buscarPrecosCripto(faltando)
+	for simbolo, cotacao := range cotacoes {
+		precos[simbolo] = cotacao
+	}
+	return precos, err
+}
+
+// buscarPrecosCripto consulta o provedor e guarda cada cotação no cache e no histórico.
+func buscarPrecosCripto(simbolos []string) (map[string]Cotacao, error) {
+	cotacoes, err := ProviderCripto().PrecosCripto(simbolos)
+	if err != nil {
+		return nil, err
+	}
+	historico := make([]PrecoHistorico, 0, len(cotacoes))
+	for simbolo, cotacao := range cotacoes {
+		setToCache(cacheCripto+simbolo, cotacao)
+		historico = append(historico, historicoCotacao(tickerCripto(simbolo), "USD", cotacao))
+	}
+	registrarNoHistorico(historico)
+	return cotacoes, nil
+}
+
+// ClearCriptoCache remove as cotações de criptoativos do cache.
+func ClearCriptoCache() {
+	limparCache(cacheCripto)
+}
+
+// listarCripto lê os criptoativos do usuário, ainda sem preço.
+func listarCripto(userID int64) ([]AtivoCripto, error) {
+	rows, err := database.GetDB().Query(database.Rebind("SELECT simbolo, COALESCE(descricao, ''), quantidade FROM investimentos_cripto WHERE user_id = ? ORDER BY simbolo"), userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var ativos []AtivoCripto
+	for rows.Next() {
+		var a AtivoCripto
+		if err := rows.Scan(&a.Simbolo, &a.Descricao, &a.Quantidade); err != nil {
+			return nil, err
+		}
+		if q, ok := new(big.Rat).SetString(a.Quantidade); ok {
+			a.Quantidade = formatarQuantidadeCripto(q)
+		}
+		ativos = append(ativos, a)
+	}
+	return ativos, rows.Err()
+}
+
+// GetAtivosCripto retorna os criptoativos do usuário avaliados em USD e em reais.
+func GetAtivosCripto(userID int64) ([]AtivoCripto, error) {
+	log.Println("[InvestimentosService] Iniciando busca de Criptoativos...")
+	ativos, err := listarCripto(userID)
+	if err != nil || len(ativos) == 0 {
+		return ativos, err
+	}
+	cotacaoDolar, err := getCotacaoDolar()
+	if err != nil {
+		log.Printf("AVISO: Falha ao buscar cotação do dólar para os criptoativos: %v", err)
+	}
+	simbolos := make([]string, len(ativos))
+	for i, a := range ativos {
+		simbolos[i] = a.Simbolo
+	}
+	precos, err := getPrecosCripto(simbolos)
+	if err != nil {
+		log.Printf("ERRO ao buscar preços de criptoativos: %v. Os preços não serão preenchidos.", err)
+	}
+	for i := range ativos {
+		cotacao, ok := precos[ativos[i].Simbolo]
+		if !ok {
+			log.Printf("AVISO (Cripto): Símbolo '%s' da sua carteira não foi encontrado nos provedores de preço.", ativos[i].Simbolo)
+			continue
+		}
+		quantidade := quantidadeFloat(ativos[i].Quantidade)
+		ativos[i].PrecoUSD = cotacao.Preco
+		ativos[i].PrecoBRL = cotacao.Preco * cotacaoDolar
+		ativos[i].ValorTotalUSD = cotacao.Preco * quantidade
+		ativos[i].ValorTotalBRL = ativos[i].ValorTotalUSD * cotacaoDolar
+		ativos[i].DataPreco = cotacao.DataPregao()
+		ativos[i].Fonte = cotacao.Fonte
+	}
+	return ativos, nil
+}
+
+// --- Handlers ---
+
+// QuantidadeCripto aceita a quantidade como número JSON ou como texto ("0,00012345"),
+// guardando os dígitos recebidos; texto preserva todas as casas.
+type QuantidadeCripto string
+
+func (q *QuantidadeCripto) UnmarshalJSON(b []byte) error {
+	if len(b) > 0 && b[0] == '"' {
+		var s string
+		if err := json.Unmarshal(b, &s); err != nil {
+			return err
+		}
+		*q = QuantidadeCripto(s)
+		return nil
+	}
+	var n json.Number
+	if err := json.Unmarshal(b, &n); err != nil {
+		return err
+	}
+	*q = QuantidadeCripto(n)
+	return nil
+}
+
+// CriptoPayload é o corpo de inclusão e edição de criptoativos.
+type CriptoPayload struct {
+	Simbolo    string           `json:"simbolo"`
+	Descricao  string           `json:"descricao"`
+	Quantidade QuantidadeCripto `json:"quantidade" binding:"required"`
+}
+
+// criptoSnapshot é o estado de um criptoativo registrado no diff da auditoria.
+type criptoSnapshot struct {
+	Simbolo    string `json:"simbolo"`
+	Descricao  string `json:"descricao,omitempty"`
+	Quantidade string `json:"quantidade"`
+}
+
+// loadCripto retorna o estado atual do criptoativo, ou nil se ele não existir.
+func loadCripto(q queryer, userID int64, simbolo string) *criptoSnapshot {
+	return scanCripto(q, "SELECT simbolo, COALESCE(descricao, ''), quantidade FROM investimentos_cripto WHERE user_id = ? AND simbolo = ?", userID, simbolo)
+}
+
+// loadCriptoParaAtualizar é loadCripto com a linha bloqueada até o fim da transação no
+// PostgreSQL, para que somas simultâneas não se percam (o SQLite já serializa as escritas).
+func loadCriptoParaAtualizar(tx *sql.Tx, userID int64, simbolo string) *criptoSnapshot {
+	query := "SELECT simbolo, COALESCE(descricao, ''), quantidade FROM investimentos_cripto WHERE user_id = ? AND simbolo = ?"
+	if database.DriverName == "postgres" {
+		query += " FOR UPDATE"
+	}
+	return scanCripto(tx, query, userID, simbolo)
+}
+
+func scanCripto(q queryer, query string, userID int64, simbolo string) *criptoSnapshot {
+	var s criptoSnapshot
+	if err := q.QueryRow(database.Rebind(query), userID, simbolo).Scan(&s.Simbolo, &s.Descricao, &s.Quantidade); err != nil {
+		return nil
+	}
+	return &s
+}
+
+// simboloDoPedido normaliza o símbolo da URL como na inclusão.
+func simboloDoPedido(c *gin.Context) string {
+	return strings.ToUpper(strings.TrimSpace(c.Param("simbolo")))
+}
+
+// AddAtivoCripto soma a quantidade informada à posição existente (ou cria o ativo). A soma é
+// feita em decimal exato, dentro de uma transação.
+func AddAtivoCripto(c *gin.Context) {
+	userID := c.MustGet("userID").(int64)
+	var payload CriptoPayload
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Payload inválido: " + err.Error()})
+		return
+	}
+	simbolo := strings.ToUpper(strings.TrimSpace(payload.Simbolo))
+	if simbolo == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "O símbolo é obrigatório."})
+		return
+	}
+	quantidade, err := parseQuantidadeCripto(string(payload.Quantidade))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	db := database.GetDB()
+	tx, err := db.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao salvar o ativo no banco de dados."})
+		return
+	}
+	defer tx.Rollback()
+	before := loadCriptoParaAtualizar(tx, userID, simbolo)
+	descricao := strings.TrimSpace(payload.Descricao)
+	if before == nil {
+		// Outra requisição pode ter criado o ativo depois da leitura: nesse caso nada é inserido e
+		// a quantidade é somada à linha dela, agora visível e bloqueada.
+		var result sql.Result
+		result, err = tx.Exec(database.Rebind("INSERT INTO investimentos_cripto (user_id, simbolo, descricao, quantidade) VALUES (?, ?, ?, ?) ON CONFLICT (user_id, simbolo) DO NOTHING"),
+			userID, simbolo, descricao, formatarQuantidadeCripto(quantidade))
+		if err == nil {
+			if n, _ := result.RowsAffected(); n == 0 {
+				if before = loadCriptoParaAtualizar(tx, userID, simbolo); before == nil {
+					err = errors.New("criptoativo não encontrado após conflito na inclusão")
+				}
+			}
+		}
+	}
+	if err == nil && before != nil {
+		atual, ok := new(big.Rat).SetString(before.Quantidade)
+		if !ok {
+			atual = new(big.Rat)
+		}
+		if descricao == "" {
+			descricao = before.Descricao
+		}
+		_, err = tx.Exec(database.Rebind("UPDATE investimentos_cripto SET quantidade = ?, descricao = ? WHERE user_id = ? AND simbolo = ?"),
+			formatarQuantidadeCripto(atual.Add(atual, quantidade)), descricao, userID, simbolo)
+	}
+	if err == nil {
+		err = tx.Commit()
+	}
+	if err != nil {
+		log.Printf("Erro ao adicionar/atualizar criptoativo: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao salvar o ativo no banco de dados."})
+		return
+	}
+	middleware.SetAuditChange(c, entityCripto, simbolo, before, loadCripto(db, userID, simbolo))
+	middleware.InvestmentsCreated.WithLabelValues("cripto").Inc()
+	c.JSON(http.StatusOK, gin.H{"message": "Ativo adicionado/atualizado com sucesso!"})
+}
+
+// UpdateAtivoCripto substitui a quantidade do criptoativo.
+func UpdateAtivoCripto(c *gin.Context) {
+	userID := c.MustGet("userID").(int64)
+	simbolo := simboloDoPedido(c)
+	var payload CriptoPayload
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Payload inválido: " + err.Error()})
+		return
+	}
+	quantidade, err := parseQuantidadeCripto(string(payload.Quantidade))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	db := database.GetDB()
+	before := loadCripto(db, userID, simbolo)
+	result, err := db.Exec(database.Rebind("UPDATE investimentos_cripto SET quantidade = ? WHERE user_id = ? AND simbolo = ?"), formatarQuantidadeCripto(quantidade), userID, simbolo)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao atualizar o ativo no banco de dados."})
+		return
+	}
+	if rowsAffected, _ := result.RowsAffected(); rowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Ativo não encontrado ou não pertence a este usuário."})
+		return
+	}
+	middleware.SetAuditChange(c, entityCripto, simbolo, before, loadCripto(db, userID, simbolo))
+	c.JSON(http.StatusOK, gin.H{"message": "Ativo atualizado com sucesso!"})
+}
+
+// DeleteAtivoCripto remove o criptoativo da carteira.
+func DeleteAtivoCripto(c *gin.Context) {
+	userID := c.MustGet("userID").(int64)
+	simbolo := simboloDoPedido(c)
+	db := database.GetDB()
+	before := loadCripto(db, userID, simbolo)
+	result, err := db.Exec(database.Rebind("DELETE FROM investimentos_cripto WHERE user_id = ? AND simbolo = ?"), userID, simbolo)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao excluir o ativo do banco de dados."})
+		return
+	}
+	if rowsAffected, _ := result.RowsAffected(); rowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Ativo não encontrado ou não pertence a este usuário."})
+		return
+	}
+	middleware.SetAuditChange(c, entityCripto, simbolo, before, nil)
+	c.JSON(http.StatusOK, gin.H{"message": "Ativo excluído com sucesso!"})
+}
@@ -0,0 +1,67 @@
+package investimentos
+
+import (
+	"encoding/json"
+	"math"
+	"minhas_economias/database"
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestAtivosCripto(t *testing.T) {
+	setupInvestimentosTestDB(t)
+	defer teardownInvestimentosTestDB()
+	SetProvider(FixtureProvider{Dir: "../downloads"})
+	defer SetProvider(nil)
+	SetCriptoProvider(FixtureProvider{Dir: "../downloads"})
+	defer SetCriptoProvider(nil)
+	router := createInvestimentosTestRouter()
+
+	// Frações pequenas são somadas sem perda de precisão, em texto ou com vírgula decimal.
+	for _, q := range []interface{}{"0.00012345", "0,00012345", 1.5} {
+		w := performInvestimentosJSONRequest(router, "POST", "/investimentos/cripto", gin.H{"simbolo": "btc", "descricao": "Carteira fria", "quantidade": q})
+		if w.Code != http.StatusOK {
+			t.Fatalf("Esperado status 200 ao adicionar %v, obtido %d: %s", q, w.Code, w.Body.String())
+		}
+	}
+	var quantidade string
+	database.GetDB().QueryRow("SELECT quantidade FROM investimentos_cripto WHERE user_id = ? AND simbolo = 'BTC'", testUserID).Scan(&quantidade)
+	if quantidade != "1.5002469" {
+		t.Errorf("Quantidade de BTC esperada 1.5002469, obtida %s", quantidade)
+	}
+	w := performInvestimentosJSONRequest(router, "POST", "/investimentos/cripto", gin.H{"simbolo": "ETH", "quantidade": "0.0000000000000000001"})
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Quantidade com 19 casas deveria ser rejeitada, status %d", w.Code)
+	}
+	// O símbolo da URL é normalizado como na inclusão.
+	w = performInvestimentosJSONRequest(router, "POST", "/investimentos/cripto/btc", gin.H{"quantidade": "0.5"})
+	if w.Code != http.StatusOK {
+		t.Fatalf("Esperado status 200 ao editar, obtido %d: %s", w.Code, w.Body.String())
+	}
+
+	// Preço das fixtures (BTC a US$ 108.950,12 e dólar a R$ 5,57) entra nos totais da carteira.
+	w = performInvestimentosJSONRequest(router, "GET", "/api/investimentos/precos", nil)
+	var precos struct {
+		Cripto []AtivoCripto  `json:"cripto"`
+		Totais TotaisCarteira `json:"totais"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &precos)
+	if len(precos.Cripto) != 1 || precos.Cripto[0].Quantidade != "0.5" || precos.Cripto[0].ValorTotalUSD != 54475.06 {
+		t.Fatalf("Criptoativos inesperados: %+v", precos.Cripto)
+	}
+	if math.Abs(precos.Cripto[0].ValorTotalBRL-54475.06*5.57) > 1e-6 || math.Abs(precos.Totais.Cripto-precos.Cripto[0].ValorTotalBRL) > 1e-6 {
+		t.Errorf("Valor em reais inesperado: %+v, totais %+v", precos.Cripto[0], precos.Totais)
+	}
+	if math.Abs(precos.Totais.Total-(precos.Totais.Acoes+precos.Totais.FIIs+precos.Totais.Internacionais+precos.Totais.Cripto)) > 1e-6 || precos.Totais.Acoes == 0 {
+		t.Errorf("Totais inconsistentes: %+v", precos.Totais)
+	}
+
+	if w := performInvestimentosJSONRequest(router, "DELETE", "/investimentos/cripto/btc", nil); w.Code != http.StatusOK {
+		t.Errorf("Esperado status 200 ao excluir, obtido %d", w.Code)
+	}
+	if w := performInvestimentosJSONRequest(router, "DELETE", "/investimentos/cripto/BTC", nil); w.Code != http.StatusNotFound {
+		t.Errorf("Esperado status 404 ao excluir de novo, obtido %d", w.Code)
+	}
+}
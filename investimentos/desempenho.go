@@ -0,0 +1,497 @@
+package investimentos
+
+import (
+	"log"
+	"math"
+	"minhas_economias/database"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Períodos de desempenho, contados até a data de referência.
+const (
+	PeriodoMes    = "mes"    // Desde o fim do mês anterior
+	PeriodoAno    = "ano"    // Desde o fim do ano anterior
+	Periodo12m    = "12m"    // Últimos 12 meses
+	PeriodoInicio = "inicio" // Desde o primeiro aporte
+)
+
+var periodosDesempenho = []string{PeriodoMes, PeriodoAno, Periodo12m, PeriodoInicio}
+
+var nomesPeriodos = map[string]string{
+	PeriodoMes:    "No mês",
+	PeriodoAno:    "No ano",
+	Periodo12m:    "12 meses",
+	PeriodoInicio: "Desde o início",
+}
+
+// benchmarks são as séries comparadas com a carteira. O S&P 500 é convertido para reais pelo
+// dólar PTAX e o CDI é acumulado dia útil a dia útil.
+var benchmarks = []string{SerieIBOV, SerieIFIX, SerieCDI, SerieSP500}
+
+// RetornoPeriodo é o desempenho da carteira e dos benchmarks em um período. Os retornos estão
+// em %; nulos quando não há como calculá-los (carteira vazia ou série sem dados na data inicial).
+type RetornoPeriodo struct {
+	Periodo      string              `json:"periodo"`
+	Nome         string              `json:"nome"`
+	Inicio       string              `json:"inicio"` // Limitado à véspera do primeiro aporte
+	Fim          string              `json:"fim"`
+	ValorInicial float64             `json:"valor_inicial"`
+	ValorFinal   float64             `json:"valor_final"`
+	Aportes      float64             `json:"aportes"` // Aportes menos vendas e proventos no período
+	TWR          *float64            `json:"twr"`     // Retorno ponderado pelo tempo
+	XIRR         *float64            `json:"xirr"`    // Retorno ponderado pelo dinheiro, ao ano
+	Benchmarks   map[string]*float64 `json:"benchmarks"`
+}
+
+// PontoDesempenho é um ponto do gráfico: o retorno acumulado desde o início em base 100.
+type PontoDesempenho struct {
+	Data       string              `json:"data"`
+	Valor      float64             `json:"valor"`
+	Carteira   float64             `json:"carteira"`
+	Benchmarks map[string]*float64 `json:"benchmarks"`
+}
+
+// Desempenho reúne os retornos por período e a série mensal do gráfico.
+type Desempenho struct {
+	Data         string            `json:"data"`
+	Inicio       string            `json:"inicio,omitempty"`
+	Periodos     []RetornoPeriodo  `json:"periodos"`
+	Serie        []PontoDesempenho `json:"serie"`
+	SemHistorico []string          `json:"sem_historico"` // Ativos sem data de compra, fora do cálculo
+}
+
+// fluxoCarteira é um aporte (positivo) ou uma retirada (negativa) da carteira em uma data.
+type fluxoCarteira struct {
+	Data  string
+	Valor float64
+}
+
+// carteiraHistorica reúne o que tem data de entrada na carteira: o livro de operações de ações e
+// FIIs, os proventos e os títulos de renda fixa. Ativos do exterior e cripto não têm data de
+// compra e ficam fora do desempenho.
+type carteiraHistorica struct {
+	operacoes map[string][]Operacao
+	precos    map[string][]PrecoHistorico
+	titulos   []TituloRendaFixa
+	series    map[string]*SerieIndice
+	fluxos    []fluxoCarteira
+}
+
+// novaCarteiraHistorica monta os fluxos até a data: compras (com taxas) entram como aportes;
+// vendas (líquidas de taxas), proventos líquidos na data ex e o resgate dos títulos vencidos
+// saem da carteira.
+func novaCarteiraHistorica(ops []Operacao, proventos []Provento, titulos []TituloRendaFixa, precos map[string][]PrecoHistorico, series map[string]*SerieIndice, ate string) *carteiraHistorica {
+	c := &carteiraHistorica{operacoes: make(map[string][]Operacao), precos: precos, titulos: titulos, series: series}
+	for _, op := range ops {
+		c.operacoes[op.Ticker] = append(c.operacoes[op.Ticker], op)
+	}
+	for ticker, opsTicker := range c.operacoes {
+		ordenarOperacoes(opsTicker)
+		for _, op := range opsTicker {
+			if op.Data > ate {
+				break
+			}
+			preco := op.Preco
+			if preco == 0 { // Saldo anterior: vale o preço de mercado da data
+				preco = c.preco(ticker, op.Data)
+			}
+			valor := float64(op.Quantidade) * preco
+			if op.Tipo == OperacaoCompra {
+				valor += op.Taxas
+			} else {
+				valor = -(valor - op.Taxas)
+			}
+			c.fluxos = append(c.fluxos, fluxoCarteira{Data: op.Data, Valor: valor})
+		}
+	}
+	for _, p := range proventos {
+		if p.DataEx <= ate {
+			c.fluxos = append(c.fluxos, fluxoCarteira{Data: p.DataEx, Valor: -p.ValorLiquido})
+		}
+	}
+	for _, t := range titulos {
+		if t.DataAplicacao > ate {
+			continue
+		}
+		c.fluxos = append(c.fluxos, fluxoCarteira{Data: t.DataAplicacao, Valor: t.ValorAplicado})
+		if t.Vencimento <= ate {
+			vencimento, _ := time.Parse("2006-01-02", t.Vencimento)
+			fator, _ := fatorCorrecao(t, series, vencimento)
+			c.fluxos = append(c.fluxos, fluxoCarteira{Data: t.Vencimento, Valor: -t.ValorAplicado * fator})
+		}
+	}
+	sort.SliceStable(c.fluxos, func(i, j int) bool { return c.fluxos[i].Data < c.fluxos[j].Data })
+	return c
+}
+
+// preco retorna o último preço gravado até a data ou, sem histórico, o da última operação com preço.
+func (c *carteiraHistorica) preco(ticker, data string) float64 {
+	precos := c.precos[ticker]
+	if i := sort.Search(len(precos), func(i int) bool { return precos[i].Data > data }); i > 0 {
+		return precos[i-1].Fechamento
+	}
+	preco := 0.0
+	for _, op := range c.operacoes[ticker] {
+		if op.Data > data {
+			break
+		}
+		if op.Preco > 0 {
+			preco = op.Preco
+		}
+	}
+	return preco
+}
+
+// valor avalia a carteira no fim do dia: posições do livro a preço de mercado e títulos na curva
+// (os vencidos já saíram como resgate).
+func (c *carteiraHistorica) valor(data string) float64 {
+	total := 0.0
+	for ticker, ops := range c.operacoes {
+		quantidade := 0
+		for _, op := range ops {
+			if op.Data > data {
+				break
+			}
+			if op.Tipo == OperacaoCompra {
+				quantidade += op.Quantidade
+			} else {
+				quantidade -= op.Quantidade
+			}
+		}
+		if quantidade > 0 {
+			total += float64(quantidade) * c.preco(ticker, data)
+		}
+	}
+	d, _ := time.Parse("2006-01-02", data)
+	for _, t := range c.titulos {
+		if t.DataAplicacao > data || t.Vencimento <= data {
+			continue
+		}
+		fator, _ := fatorCorrecao(t, c.series, d)
+		total += t.ValorAplicado * fator
+	}
+	return total
+}
+
+// retornoAcumulado calcula o fator do retorno ponderado pelo tempo de ini até cada marca (em
+// ordem crescente). A carteira é avaliada no fim de cada dia com fluxo e cada subperíodo rende
+// (valor final - fluxos do dia) / valor inicial; subperíodos sem valor inicial não contam.
+func (c *carteiraHistorica) retornoAcumulado(ini string, marcas []string) []float64 {
+	fim := marcas[len(marcas)-1]
+	fluxosDia := make(map[string]float64)
+	for _, f := range c.fluxos {
+		if f.Data > ini && f.Data <= fim {
+			fluxosDia[f.Data] += f.Valor
+		}
+	}
+	pontos := make(map[string]bool, len(fluxosDia)+len(marcas))
+	for d := range fluxosDia {
+		pontos[d] = true
+	}
+	for _, m := range marcas {
+		if m > ini {
+			pontos[m] = true
+		}
+	}
+	datas := make([]string, 0, len(pontos))
+	for d := range pontos {
+		datas = append(datas, d)
+	}
+	sort.Strings(datas)
+
+	fatores := make([]float64, len(marcas))
+	k := 0
+	for ; k < len(marcas) && marcas[k] <= ini; k++ {
+		fatores[k] = 1
+	}
+	fator, anterior := 1.0, c.valor(ini)
+	for _, d := range datas {
+		v := c.valor(d)
+		if anterior > 0 {
+			fator *= (v - fluxosDia[d]) / anterior
+		}
+		anterior = v
+		for ; k < len(marcas) && marcas[k] == d; k++ {
+			fatores[k] = fator
+		}
+	}
+	return fatores
+}
+
+// xirr encontra, por bisseção, a taxa anual que zera o valor presente dos fluxos do investidor
+// (aportes negativos, resgates e valor final positivos), com anos de 365 dias. ok é falso quando
+// os fluxos não trocam de sinal.
+func xirr(fluxos []fluxoCarteira) (float64, bool) {
+	if len(fluxos) < 2 {
+		return 0, false
+	}
+	inicio, _ := time.Parse("2006-01-02", fluxos[0].Data)
+	anos := make([]float64, len(fluxos))
+	for i, f := range fluxos {
+		d, _ := time.Parse("2006-01-02", f.Data)
+		anos[i] = d.Sub(inicio).Hours() / 24 / 365
+	}
+	vpl := func(taxa float64) float64 {
+		total := 0.0
+		for i, f := range fluxos {
+			total += f.Valor / math.Pow(1+taxa, anos[i])
+		}
+		return total
+	}
+	baixa, alta := -0.9999, 1.0
+	for vpl(baixa)*vpl(alta) > 0 {
+		if alta > 1e6 {
+			return 0, false
+		}
+		alta *= 10
+	}
+	for i := 0; i < 200; i++ {
+		meio := (baixa + alta) / 2
+		if vpl(baixa)*vpl(meio) <= 0 {
+			alta = meio
+		} else {
+			baixa = meio
+		}
+	}
+	return (baixa + alta) / 2, true
+}
+
+// retornoBenchmark calcula a variação do benchmark de ini a fim; ok é falso quando a série não
+// tem valor importado até a data inicial.
+func retornoBenchmark(nome string, series map[string]*SerieIndice, ini, fim string) (float64, bool) {
+	switch nome {
+	case SerieCDI:
+		cdi := series[SerieCDI]
+		if !cdi.cobre(ini) {
+			return 0, false
+		}
+		de, _ := time.Parse("2006-01-02", ini)
+		ate, _ := time.Parse("2006-01-02", fim)
+		fator := 1.0
+		for d := de; d.Before(ate); d = d.AddDate(0, 0, 1) {
+			if diaUtil(d) {
+				taxa, _ := cdi.valorEm(d.Format("2006-01-02"))
+				fator *= 1 + taxa/100
+			}
+		}
+		return fator - 1, true
+	case SerieSP500:
+		sp, dolar := series[SerieSP500], series[SerieDolar]
+		if !sp.cobre(ini) || !dolar.cobre(ini) {
+			return 0, false
+		}
+		spIni, _ := sp.valorEm(ini)
+		spFim, _ := sp.valorEm(fim)
+		dolarIni, _ := dolar.valorEm(ini)
+		dolarFim, _ := dolar.valorEm(fim)
+		if spIni*dolarIni == 0 {
+			return 0, false
+		}
+		return spFim*dolarFim/(spIni*dolarIni) - 1, true
+	}
+	s := series[nome]
+	if !s.cobre(ini) {
+		return 0, false
+	}
+	valorIni, _ := s.valorEm(ini)
+	valorFim, _ := s.valorEm(fim)
+	if valorIni == 0 {
+		return 0, false
+	}
+	return valorFim/valorIni - 1, true
+}
+
+// percentual converte um retorno em % com duas casas.
+func percentual(r float64) *float64 {
+	p := arredondar(r * 100)
+	return &p
+}
+
+// retornosBenchmarks calcula a variação de cada benchmark no período.
+func retornosBenchmarks(series map[string]*SerieIndice, ini, fim string) map[string]*float64 {
+	retornos := make(map[string]*float64, len(benchmarks))
+	for _, b := range benchmarks {
+		retornos[b] = nil
+		if r, ok := retornoBenchmark(b, series, ini, fim); ok {
+			retornos[b] = percentual(r)
+		}
+	}
+	return retornos
+}
+
+// inicioPeriodo retorna a data (exclusiva) em que o período começa.
+func inicioPeriodo(periodo string, ref time.Time) time.Time {
+	switch periodo {
+	case PeriodoMes:
+		return time.Date(ref.Year(), ref.Month(), 0, 0, 0, 0, 0, time.UTC)
+	case PeriodoAno:
+		return time.Date(ref.Year(), 1, 0, 0, 0, 0, 0, time.UTC)
+	case Periodo12m:
+		return ref.AddDate(-1, 0, 0)
+	}
+	return time.Time{}
+}
+
+// desempenho calcula os retornos da carteira até a data de referência e a série mensal (base
+// 100 na véspera do primeiro aporte) usada no gráfico.
+func (c *carteiraHistorica) desempenho(ref time.Time) Desempenho {
+	fim := ref.Format("2006-01-02")
+	d := Desempenho{Data: fim, Periodos: []RetornoPeriodo{}, Serie: []PontoDesempenho{}, SemHistorico: []string{}}
+	if len(c.fluxos) == 0 || c.fluxos[0].Data > fim {
+		return d
+	}
+	primeiro, _ := time.Parse("2006-01-02", c.fluxos[0].Data)
+	origem := primeiro.AddDate(0, 0, -1).Format("2006-01-02")
+	d.Inicio = origem
+
+	for _, periodo := range periodosDesempenho {
+		ini := origem
+		if i := inicioPeriodo(periodo, ref).Format("2006-01-02"); periodo != PeriodoInicio && i > origem {
+			ini = i
+		}
+		r := RetornoPeriodo{Periodo: periodo, Nome: nomesPeriodos[periodo], Inicio: ini, Fim: fim, Benchmarks: retornosBenchmarks(c.series, ini, fim)}
+		r.ValorInicial = c.valor(ini)
+		r.ValorFinal = c.valor(fim)
+
+		// Fluxos do ponto de vista do investidor: o valor inicial e os aportes saem do bolso;
+		// retiradas e o valor final voltam.
+		fluxosInvestidor := []fluxoCarteira{{Data: ini, Valor: -r.ValorInicial}}
+		temFluxo := false
+		for _, f := range c.fluxos {
+			if f.Data > ini && f.Data <= fim {
+				r.Aportes += f.Valor
+				fluxosInvestidor = append(fluxosInvestidor, fluxoCarteira{Data: f.Data, Valor: -f.Valor})
+				temFluxo = true
+			}
+		}
+		fluxosInvestidor = append(fluxosInvestidor, fluxoCarteira{Data: fim, Valor: r.ValorFinal})
+		if r.ValorInicial > 0 || temFluxo {
+			r.TWR = percentual(c.retornoAcumulado(ini, []string{fim})[0] - 1)
+			if taxa, ok := xirr(fluxosInvestidor); ok {
+				r.XIRR = percentual(taxa)
+			}
+		}
+		r.ValorInicial, r.ValorFinal, r.Aportes = arredondar(r.ValorInicial), arredondar(r.ValorFinal), arredondar(r.Aportes)
+		d.Periodos = append(d.Periodos, r)
+	}
+
+	// Um ponto no fim de cada mês desde o início, mais a data de referência.
+	var marcas []string
+	for m := time.Date(primeiro.Year(), primeiro.Month()+1, 0, 0, 0, 0, 0, time.UTC); m.Before(ref); m = time.Date(m.Year(), m.Month()+2, 0, 0, 0, 0, 0, time.UTC) {
+		marcas = append(marcas, m.Format("2006-01-02"))
+	}
+	marcas = append(marcas, fim)
+	base := func(r float64) *float64 {
+		v := arredondar(100 * (1 + r))
+		return &v
+	}
+	ponto := PontoDesempenho{Data: origem, Carteira: 100, Benchmarks: make(map[string]*float64)}
+	for _, b := range benchmarks {
+		ponto.Benchmarks[b] = nil
+		if c.series[b].cobre(origem) && (b != SerieSP500 || c.series[SerieDolar].cobre(origem)) {
+			ponto.Benchmarks[b] = base(0)
+		}
+	}
+	d.Serie = append(d.Serie, ponto)
+	for i, fator := range c.retornoAcumulado(origem, marcas) {
+		ponto := PontoDesempenho{Data: marcas[i], Valor: arredondar(c.valor(marcas[i])), Carteira: arredondar(100 * fator), Benchmarks: make(map[string]*float64)}
+		for _, b := range benchmarks {
+			ponto.Benchmarks[b] = nil
+			if r, ok := retornoBenchmark(b, c.series, origem, marcas[i]); ok {
+				ponto.Benchmarks[b] = base(r)
+			}
+		}
+		d.Serie = append(d.Serie, ponto)
+	}
+	return d
+}
+
+// DesempenhoCarteira carrega o livro de operações, os proventos, a renda fixa, os preços e os
+// benchmarks do usuário e calcula o desempenho até a data.
+func DesempenhoCarteira(userID int64, ref time.Time) (*Desempenho, error) {
+	db := database.GetDB()
+	ate := ref.Format("2006-01-02")
+	ops, err := listarOperacoes(db, userID, "")
+	if err != nil {
+		return nil, err
+	}
+	proventos, err := listarProventos(userID, "")
+	if err != nil {
+		return nil, err
+	}
+	titulos, err := listarRendaFixa(userID)
+	if err != nil {
+		return nil, err
+	}
+	series, err := CarregarSeries()
+	if err != nil {
+		return nil, err
+	}
+	precos := make(map[string][]PrecoHistorico)
+	for _, op := range ops {
+		if _, ok := precos[op.Ticker]; ok {
+			continue
+		}
+		if precos[op.Ticker], err = HistoricoPrecos(op.Ticker, "", ate); err != nil {
+			return nil, err
+		}
+	}
+
+	c := novaCarteiraHistorica(ops, proventos, titulos, precos, series, ate)
+	d := c.desempenho(ref)
+
+	rows, err := db.Query(database.Rebind(`SELECT ticker FROM investimentos_nacionais WHERE user_id = ?
+		UNION SELECT ticker FROM investimentos_internacionais WHERE user_id = ? ORDER BY ticker`), userID, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var ticker string
+		if err := rows.Scan(&ticker); err != nil {
+			return nil, err
+		}
+		if _, ok := c.operacoes[strings.TrimSpace(ticker)]; !ok {
+			d.SemHistorico = append(d.SemHistorico, strings.TrimSpace(ticker))
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	cripto, err := listarCripto(userID)
+	if err != nil {
+		return nil, err
+	}
+	for _, a := range cripto {
+		d.SemHistorico = append(d.SemHistorico, a.Simbolo)
+	}
+	return &d, nil
+}
+
+// GetDesempenhoAPI retorna o desempenho da carteira até a data pedida (?data=AAAA-MM-DD, padrão hoje).
+func GetDesempenhoAPI(c *gin.Context) {
+	userID := c.MustGet("userID").(int64)
+	hoje := time.Now().Format("2006-01-02")
+	data := c.DefaultQuery("data", hoje)
+	ref, err := time.Parse("2006-01-02", data)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "A data deve estar no formato AAAA-MM-DD."})
+		return
+	}
+	if data > hoje {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Não é possível calcular o desempenho em uma data futura."})
+		return
+	}
+	d, err := DesempenhoCarteira(userID, ref)
+	if err != nil {
+		log.Printf("Erro ao calcular o desempenho da carteira do usuário %d: %v", userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao calcular o desempenho da carteira."})
+		return
+	}
+	c.JSON(http.StatusOK, d)
+}
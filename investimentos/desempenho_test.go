@@ -0,0 +1,68 @@
+package investimentos
+
+import (
+	"encoding/json"
+	"math"
+	"net/http"
+	"testing"
+)
+
+func TestXIRR(t *testing.T) {
+	// 10% em um ano bissexto de 366 dias.
+	taxa, ok := xirr([]fluxoCarteira{{Data: "2024-01-01", Valor: -1000}, {Data: "2025-01-01", Valor: 1100}})
+	if !ok || math.Abs(taxa-(math.Pow(1.1, 365.0/366)-1)) > 1e-6 {
+		t.Errorf("XIRR inesperada: %v (%v)", taxa, ok)
+	}
+	if _, ok := xirr([]fluxoCarteira{{Data: "2024-01-01", Valor: -1000}, {Data: "2025-01-01", Valor: -100}}); ok {
+		t.Error("Esperado erro para fluxos sem troca de sinal")
+	}
+}
+
+func TestDesempenhoAPI(t *testing.T) {
+	setupInvestimentosTestDB(t)
+	defer teardownInvestimentosTestDB()
+	router := createInvestimentosTestRouter()
+
+	// 10 ações a R$ 10 em 02/01 e mais 10 a R$ 12 em 14/02: a cotação sobe 32% até 28/02, e o
+	// retorno ponderado pelo tempo acompanha a cotação, independentemente do segundo aporte.
+	for _, op := range []Operacao{
+		{Ticker: "ITSA4", TipoAtivo: "ACAO", Tipo: OperacaoCompra, Data: "2025-01-02", Quantidade: 10, Preco: 10},
+		{Ticker: "ITSA4", TipoAtivo: "ACAO", Tipo: OperacaoCompra, Data: "2025-02-14", Quantidade: 10, Preco: 12},
+	} {
+		if _, err := RegistrarOperacao(testUserID, op, 0); err != nil {
+			t.Fatalf("Erro ao registrar a operação: %v", err)
+		}
+	}
+	RegistrarPrecos([]PrecoHistorico{
+		{Ticker: "ITSA4", Data: "2025-01-31", Fechamento: 11, Moeda: "BRL", Fonte: "teste"},
+		{Ticker: "ITSA4", Data: "2025-02-14", Fechamento: 12, Moeda: "BRL", Fonte: "teste"},
+		{Ticker: "ITSA4", Data: "2025-02-28", Fechamento: 13.2, Moeda: "BRL", Fonte: "teste"},
+	})
+	RegistrarIndices(SerieIBOV, []Indice{{Data: "2024-12-30", Valor: 120000}, {Data: "2025-01-31", Valor: 126000}, {Data: "2025-02-28", Valor: 123480}})
+	RegistrarIndices(SerieCDI, []Indice{{Data: "2024-12-02", Valor: 0.05}})
+
+	w := performInvestimentosJSONRequest(router, "GET", "/api/investimentos/desempenho?data=2025-02-28", nil)
+	var d Desempenho
+	if err := json.Unmarshal(w.Body.Bytes(), &d); err != nil || w.Code != http.StatusOK || len(d.Periodos) != 4 {
+		t.Fatalf("Resposta inesperada (%d): %s", w.Code, w.Body.String())
+	}
+	mes, inicio := d.Periodos[0], d.Periodos[3]
+	if d.Inicio != "2025-01-01" || *mes.TWR != 20 || mes.Inicio != "2025-01-31" || mes.Aportes != 120 || *mes.Benchmarks[SerieIBOV] != -2 {
+		t.Errorf("Mês inesperado: %+v", mes)
+	}
+	if *inicio.TWR != 32 || inicio.ValorFinal != 264 || inicio.XIRR == nil || *inicio.Benchmarks[SerieIBOV] != 2.9 || inicio.Benchmarks[SerieIFIX] != nil {
+		t.Errorf("Desde o início inesperado: %+v", inicio)
+	}
+	// 42 dias úteis de 01/01 a 27/02 a 0,05% ao dia (o CDI do dia rende para o dia seguinte).
+	if cdi := *inicio.Benchmarks[SerieCDI]; cdi != arredondar((math.Pow(1.0005, 42)-1)*100) {
+		t.Errorf("CDI inesperado: %v", cdi)
+	}
+	// Véspera do primeiro aporte, fim de janeiro e 28/02.
+	if len(d.Serie) != 3 || d.Serie[1].Carteira != 110 || d.Serie[2].Carteira != 132 || *d.Serie[2].Benchmarks[SerieIBOV] != 102.9 {
+		t.Errorf("Série inesperada: %+v", d.Serie)
+	}
+	// PETR4 e MXRF11 (sem operações) e VOO (exterior) ficam fora do cálculo.
+	if len(d.SemHistorico) != 3 {
+		t.Errorf("Esperados 3 ativos sem histórico, obtidos %v", d.SemHistorico)
+	}
+}
@@ -0,0 +1,189 @@
+package investimentos
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// fixtureDolar é o "ticker" da cotação do dólar no arquivo de cotações internacionais.
+const fixtureDolar = "USDBRL"
+
+// fixtureDate extrai a data (AAAA-MM-DD) do nome dos arquivos salvos.
+var fixtureDate = regexp.MustCompile(`_(\d{4}-\d{2}-\d{2})\.csv$`)
+
+// FixtureProvider serve dados de mercado salvos em arquivos, sem acesso à rede (testes,
+// desenvolvimento offline e último recurso da cadeia de fallback). Em Dir, usa o arquivo
+// mais recente (pela data no nome) de cada padrão:
+//   - fundamentus_acoes_AAAA-MM-DD.csv e fundamentus_fii_AAAA-MM-DD.csv: páginas de
+//     resultado do Fundamentus salvas pelo navegador (HTML, apesar da extensão);
+//   - cotacoes_internacionais_AAAA-MM-DD.csv: linhas "ticker;preço em USD", com USDBRL
+//     para a cotação do dólar;
+//   - cotacoes_cripto_AAAA-MM-DD.csv: linhas "símbolo;preço em USD" (também implementa
+//     CriptoProvider).
+type FixtureProvider struct {
+	Dir string
+}
+
+func (f FixtureProvider) Nome() string { return "fixtures" }
+
+func (f FixtureProvider) CotacoesAcoes() (map[string]DadosAcao, error) {
+	linhas, origem, err := f.tabelaFundamentus("fundamentus_acoes_*.csv", "#resultado > tbody > tr")
+	if err != nil {
+		return nil, err
+	}
+	return acoesDeLinhas(linhas, origem)
+}
+
+func (f FixtureProvider) CotacoesFIIs() (map[string]DadosFII, error) {
+	linhas, origem, err := f.tabelaFundamentus("fundamentus_fii_*.csv", "#tabelaResultado > tbody > tr")
+	if err != nil {
+		return nil, err
+	}
+	return fiisDeLinhas(linhas, origem)
+}
+
+// Fundamentos não é oferecido: o serviço deriva LPA e VPA da tabela de ações.
+func (f FixtureProvider) Fundamentos(string) (float64, float64, error) {
+	return 0, 0, ErrNaoSuportado
+}
+
+func (f FixtureProvider) PrecosInternacionais(tickers []string) (map[string]Cotacao, error) {
+	cotacoes, err := f.cotacoes("cotacoes_internacionais_*.csv")
+	if err != nil {
+		return nil, err
+	}
+	precos := make(map[string]Cotacao)
+	for _, t := range tickers {
+		if preco, ok := cotacoes[strings.ToUpper(strings.TrimSpace(t))]; ok {
+			precos[t] = preco
+		}
+	}
+	return precos, nil
+}
+
+func (f FixtureProvider) PrecosCripto(simbolos []string) (map[string]Cotacao, error) {
+	cotacoes, err := f.cotacoes("cotacoes_cripto_*.csv")
+	if err != nil {
+		return nil, err
+	}
+	precos := make(map[string]Cotacao)
+	for _, s := range simbolos {
+		if preco, ok := cotacoes[strings.ToUpper(strings.TrimSpace(s))]; ok {
+			precos[s] = preco
+		}
+	}
+	return precos, nil
+}
+
+func (f FixtureProvider) CotacaoDolar() (Cotacao, error) {
+	cotacoes, err := f.cotacoes("cotacoes_internacionais_*.csv")
+	if err != nil {
+		return Cotacao{}, err
+	}
+	taxa, ok := cotacoes[fixtureDolar]
+	if !ok {
+		return Cotacao{}, fmt.Errorf("%s não encontrado nas cotações internacionais salvas", fixtureDolar)
+	}
+	return taxa, nil
+}
+
+// latest retorna o arquivo mais recente do padrão e a origem dos seus dados (a data do
+// nome, AAAA-MM-DD); como a data está no nome, a ordem alfabética é a cronológica.
+func (f FixtureProvider) latest(pattern string) (string, Origem, error) {
+	matches, err := filepath.Glob(filepath.Join(f.Dir, pattern))
+	if err != nil {
+		return "", Origem{}, err
+	}
+	if len(matches) == 0 {
+		return "", Origem{}, fmt.Errorf("nenhum arquivo %s em '%s': %w", pattern, f.Dir, ErrNaoSuportado)
+	}
+	sort.Strings(matches)
+	path := matches[len(matches)-1]
+	origem := Origem{Fonte: "fixtures"}
+	if m := fixtureDate.FindStringSubmatch(filepath.Base(path)); m != nil {
+		origem.Data, _ = time.Parse("2006-01-02", m[1])
+	}
+	return path, origem, nil
+}
+
+// tabelaFundamentus lê as linhas da tabela de uma página do Fundamentus salva, no mesmo
+// formato do scraping (colunas indexadas pelo ticker).
+func (f FixtureProvider) tabelaFundamentus(pattern, seletor string) (map[string][]string, Origem, error) {
+	path, origem, err := f.latest(pattern)
+	if err != nil {
+		return nil, origem, err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, origem, err
+	}
+	doc, err := goquery.NewDocumentFromReader(bytes.NewReader(latin1ToUTF8(data)))
+	if err != nil {
+		return nil, origem, fmt.Errorf("erro ao ler '%s': %w", path, err)
+	}
+	linhas := make(map[string][]string)
+	doc.Find(seletor).Each(func(_ int, tr *goquery.Selection) {
+		var cols []string
+		tr.Find("td").Each(func(_ int, td *goquery.Selection) {
+			cols = append(cols, strings.TrimSpace(td.Text()))
+		})
+		if len(cols) > 1 {
+			linhas[cols[0]] = cols
+		}
+	})
+	return linhas, origem, nil
+}
+
+// cotacoes lê um arquivo "ticker;preço" (vírgula decimal) do padrão.
+func (f FixtureProvider) cotacoes(pattern string) (map[string]Cotacao, error) {
+	path, origem, err := f.latest(pattern)
+	if err != nil {
+		return nil, err
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	reader := csv.NewReader(file)
+	reader.Comma = ';'
+	reader.Comment = '#'
+	reader.FieldsPerRecord = -1
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("erro ao ler '%s': %w", path, err)
+	}
+	cotacoes := make(map[string]Cotacao)
+	for _, rec := range records {
+		if len(rec) < 2 || strings.EqualFold(rec[0], "ticker") || strings.EqualFold(rec[0], "simbolo") {
+			continue
+		}
+		cotacoes[strings.ToUpper(strings.TrimSpace(rec[0]))] = Cotacao{Preco: ParsePtBrFloat(rec[1]), Origem: origem}
+	}
+	return cotacoes, nil
+}
+
+// latin1ToUTF8 converte páginas salvas em ISO-8859-1 (como as do Fundamentus); conteúdo
+// que já é UTF-8 válido é mantido.
+func latin1ToUTF8(data []byte) []byte {
+	if utf8.Valid(data) {
+		return data
+	}
+	var buf bytes.Buffer
+	buf.Grow(len(data) * 2)
+	for _, b := range data {
+		buf.WriteRune(rune(b))
+	}
+	return buf.Bytes()
+}
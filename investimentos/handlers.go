@@ -4,7 +4,7 @@ import (
     "log"
     "net/http"
     "strings"
-    "sync"
+    "time"
 
     "minhas_economias/database"
     "minhas_economias/models"
@@ -57,54 +57,46 @@ func GetInvestimentosPage(c *gin.Context) {
         }
     }
 
+    cripto, errCripto := listarCripto(userID)
+    if errCripto != nil {
+        log.Printf("ERRO ao carregar criptoativos do BD: %v", errCripto)
+    }
+
     c.HTML(http.StatusOK, "investimentos.html", gin.H{
         "Titulo":         "Meus Investimentos",
         "Acoes":          acoes,
         "FIIs":           fiis,
         "Internacionais": internacionais,
+        "Cripto":         cripto,
+        "Contas":         contasDoLivro(userID, householdDoPedido(c)),
         "User":           user,
     })
 }
 
-// GetPrecosInvestimentosAPI é o novo handler que faz o trabalho pesado em background.
+// GetPrecosInvestimentosAPI cota a carteira do usuário. Com a atualização em segundo plano
+// ativa, responde com o último snapshot compartilhado; atualizacoes indica quando as cotações de
+// cada classe foram obtidas e atualizado_em, a mais antiga delas.
 func GetPrecosInvestimentosAPI(c *gin.Context) {
     userID := c.MustGet("userID").(int64)
-    var wg sync.WaitGroup
-    var errAcoes, errFIIs, errInt error
-
-    var acoes []AcaoNacional
-    var fiis []FundoImobiliario
-    var internacionais []AtivoInternacional
-    var cotacaoDolar float64
-
-    wg.Add(3)
-
-    go func() {
-        defer wg.Done()
-        acoes, errAcoes = GetAcoesNacionais(userID)
-    }()
-
-    go func() {
-        defer wg.Done()
-        fiis, errFIIs = GetFIIsNacionais(userID)
-    }()
-
-    go func() {
-        defer wg.Done()
-        internacionais, cotacaoDolar, errInt = GetAtivosInternacionais(userID)
-    }()
-
-    wg.Wait()
-
-    if errAcoes != nil { log.Printf("ERRO na API de preços (Ações): %v", errAcoes) }
-    if errFIIs != nil { log.Printf("ERRO na API de preços (FIIs): %v", errFIIs) }
-    if errInt != nil { log.Printf("ERRO na API de preços (Internacionais): %v", errInt) }
+    p := BuscarPrecosCarteira(userID)
 
+    atualizacoes := AtualizacoesCarteira(p)
+    var atualizadoEm *time.Time
+    for _, t := range atualizacoes {
+        if atualizadoEm == nil || t.Before(*atualizadoEm) {
+            t := t
+            atualizadoEm = &t
+        }
+    }
     c.JSON(http.StatusOK, gin.H{
-        "acoes":          acoes,
-        "fiis":           fiis,
-        "internacionais": internacionais,
-        "cotacaoDolar":   cotacaoDolar,
+        "atualizado_em":  atualizadoEm,
+        "atualizacoes":   atualizacoes,
+        "acoes":          p.Acoes,
+        "fiis":           p.FIIs,
+        "internacionais": p.Internacionais,
+        "cripto":         p.Cripto,
+        "cotacaoDolar":   p.CotacaoDolar,
+        "totais":         calcularTotais(p.Acoes, p.FIIs, p.Internacionais, p.Cripto),
     })
 }
 
@@ -116,6 +108,13 @@ type AddNacionalPayload struct {
     Ticker     string `json:"ticker" binding:"required"`
     Tipo       string `json:"tipo" binding:"required"`
     Quantidade int    `json:"quantidade" binding:"required"`
+    // Campos opcionais da compra registrada no livro de operações
+    Preco      float64 `json:"preco"`
+    Data       string  `json:"data"`
+    Taxas      float64 `json:"taxas"`
+    Corretora  string  `json:"corretora"`
+    // Preço médio da quantidade já cadastrada, exigido quando ela entra no livro de operações
+    PrecoMedioAnterior float64 `json:"preco_medio_anterior"`
 }
 type AddInternacionalPayload struct {
     Ticker      string  `json:"ticker" binding:"required"`
@@ -125,6 +124,8 @@ type AddInternacionalPayload struct {
 
 // --- Handlers de Adição, Edição e Exclusão ---
 
+// AddAtivoNacional registra uma compra do ativo no livro de operações; a quantidade informada
+// é somada à posição existente.
 func AddAtivoNacional(c *gin.Context) {
     userID := c.MustGet("userID").(int64)
     
@@ -140,20 +141,21 @@ func AddAtivoNacional(c *gin.Context) {
         c.JSON(http.StatusBadRequest, gin.H{"error": "Ticker e quantidade são obrigatórios e a quantidade deve ser positiva."})
         return 
     }
-    db := database.GetDB()
-    var query string
-    if database.DriverName == "postgres" {
-        query = `INSERT INTO investimentos_nacionais (user_id, ticker, tipo, quantidade) VALUES ($1, $2, $3, $4) ON CONFLICT (user_id, ticker) DO UPDATE SET quantidade = investimentos_nacionais.quantidade + EXCLUDED.quantidade;`
-    } else {
-        query = `INSERT OR REPLACE INTO investimentos_nacionais (user_id, ticker, tipo, quantidade) VALUES (?, ?, ?, ?);`
+    op := Operacao{
+        Ticker: payload.Ticker, TipoAtivo: payload.Tipo, Tipo: OperacaoCompra, Data: payload.Data,
+        Quantidade: payload.Quantidade, Preco: payload.Preco, Taxas: payload.Taxas, Corretora: payload.Corretora,
+    }
+    if err := validarOperacao(&op); err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+        return
     }
 
-    _, err := db.Exec(query, userID, payload.Ticker, payload.Tipo, payload.Quantidade)
-    if err != nil { 
-        log.Printf("Erro ao adicionar/atualizar ativo nacional: %v", err)
-        c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao salvar o ativo no banco de dados."})
+    before := loadHolding(entityNacional, userID, payload.Ticker)
+    if _, err := RegistrarOperacao(userID, op, payload.PrecoMedioAnterior); err != nil {
+        respostaErroOperacao(c, err, "salvar o ativo no banco de dados")
         return 
     }
+    middleware.SetAuditChange(c, entityNacional, payload.Ticker, before, loadHolding(entityNacional, userID, payload.Ticker))
 
     ClearNacionalCache()
     
@@ -177,21 +179,17 @@ func AddAtivoInternacional(c *gin.Context) {
         return 
     }
     db := database.GetDB()
-    var query string
-    if database.DriverName == "postgres" {
-        query = `INSERT INTO investimentos_internacionais (user_id, ticker, descricao, quantidade, moeda) VALUES ($1, $2, $3, $4, 'USD') ON CONFLICT (user_id, ticker) DO UPDATE SET quantidade = investimentos_internacionais.quantidade + EXCLUDED.quantidade, descricao = EXCLUDED.descricao;`
-    } else {
-        query = `INSERT OR REPLACE INTO investimentos_internacionais (user_id, ticker, descricao, quantidade, moeda) VALUES (?, ?, ?, ?, 'USD');`
-    }
+    // A quantidade é somada à existente nos dois bancos (o SQLite aceita ON CONFLICT desde a 3.24).
+    query := database.Rebind(`INSERT INTO investimentos_internacionais (user_id, ticker, descricao, quantidade, moeda) VALUES (?, ?, ?, ?, 'USD') ON CONFLICT (user_id, ticker) DO UPDATE SET quantidade = investimentos_internacionais.quantidade + excluded.quantidade, descricao = excluded.descricao;`)
 
+    before := loadHolding(entityInternacional, userID, payload.Ticker)
     _, err := db.Exec(query, userID, payload.Ticker, payload.Descricao, payload.Quantidade)
     if err != nil { 
         log.Printf("Erro ao adicionar/atualizar ativo internacional: %v", err)
         c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao salvar o ativo no banco de dados."})
         return 
     }
-
-    ClearInternacionalCache()
+    middleware.SetAuditChange(c, entityInternacional, payload.Ticker, before, loadHolding(entityInternacional, userID, payload.Ticker))
 
     // Métrica OK
     middleware.InvestmentsCreated.WithLabelValues("internacional").Inc()
@@ -207,7 +205,13 @@ func UpdateAtivoNacional(c *gin.Context) {
         c.JSON(http.StatusBadRequest, gin.H{"error": "Payload inválido: " + err.Error()})
         return 
     }
+    // Com operações registradas, a quantidade é derivada do livro e não pode ser editada.
+    if temOperacoes(userID, ticker) {
+        c.JSON(http.StatusConflict, gin.H{"error": "Este ativo tem operações registradas; registre uma compra ou venda para alterar a quantidade."})
+        return
+    }
     db := database.GetDB()
+    before := loadHolding(entityNacional, userID, ticker)
     query := database.Rebind("UPDATE investimentos_nacionais SET quantidade = ? WHERE user_id = ? AND ticker = ?")
     result, err := db.Exec(query, int(payload.Quantidade), userID, ticker)
     if err != nil { 
@@ -219,6 +223,7 @@ func UpdateAtivoNacional(c *gin.Context) {
         c.JSON(http.StatusNotFound, gin.H{"error": "Ativo não encontrado ou não pertence a este usuário."})
         return 
     }
+    middleware.SetAuditChange(c, entityNacional, ticker, before, loadHolding(entityNacional, userID, ticker))
     ClearNacionalCache()
     c.JSON(http.StatusOK, gin.H{"message": "Ativo atualizado com sucesso!"})
 }
@@ -227,8 +232,15 @@ func DeleteAtivoNacional(c *gin.Context) {
     userID := c.MustGet("userID").(int64)
     ticker := c.Param("ticker")
     db := database.GetDB()
+    before := loadHolding(entityNacional, userID, ticker)
+    tx, err := db.Begin()
+    if err != nil {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao excluir o ativo do banco de dados."})
+        return
+    }
+    defer tx.Rollback()
     query := database.Rebind("DELETE FROM investimentos_nacionais WHERE user_id = ? AND ticker = ?")
-    result, err := db.Exec(query, userID, ticker)
+    result, err := tx.Exec(query, userID, ticker)
     if err != nil { 
         c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao excluir o ativo do banco de dados."})
         return 
@@ -238,6 +250,17 @@ func DeleteAtivoNacional(c *gin.Context) {
         c.JSON(http.StatusNotFound, gin.H{"error": "Ativo não encontrado ou não pertence a este usuário."})
         return 
     }
+    // A exclusão do ativo remove também o seu livro de operações, na mesma transação.
+    if _, err := tx.Exec(database.Rebind("DELETE FROM operacoes_investimentos WHERE user_id = ? AND ticker = ?"), userID, ticker); err != nil {
+        log.Printf("Erro ao excluir as operações de %s: %v", ticker, err)
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao excluir o ativo do banco de dados."})
+        return
+    }
+    if err := tx.Commit(); err != nil {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao excluir o ativo do banco de dados."})
+        return
+    }
+    middleware.SetAuditChange(c, entityNacional, ticker, before, nil)
     ClearNacionalCache()
     c.JSON(http.StatusOK, gin.H{"message": "Ativo excluído com sucesso!"})
 }
@@ -251,6 +274,7 @@ func UpdateAtivoInternacional(c *gin.Context) {
         return 
     }
     db := database.GetDB()
+    before := loadHolding(entityInternacional, userID, ticker)
     query := database.Rebind("UPDATE investimentos_internacionais SET quantidade = ? WHERE user_id = ? AND ticker = ?")
     result, err := db.Exec(query, payload.Quantidade, userID, ticker)
     if err != nil { 
@@ -262,7 +286,7 @@ func UpdateAtivoInternacional(c *gin.Context) {
         c.JSON(http.StatusNotFound, gin.H{"error": "Ativo não encontrado ou não pertence a este usuário."})
         return 
     }
-    ClearInternacionalCache()
+    middleware.SetAuditChange(c, entityInternacional, ticker, before, loadHolding(entityInternacional, userID, ticker))
     c.JSON(http.StatusOK, gin.H{"message": "Ativo atualizado com sucesso!"})
 }
 
@@ -270,6 +294,7 @@ func DeleteAtivoInternacional(c *gin.Context) {
     userID := c.MustGet("userID").(int64)
     ticker := c.Param("ticker")
     db := database.GetDB()
+    before := loadHolding(entityInternacional, userID, ticker)
     query := database.Rebind("DELETE FROM investimentos_internacionais WHERE user_id = ? AND ticker = ?")
     result, err := db.Exec(query, userID, ticker)
     if err != nil { 
@@ -281,6 +306,36 @@ func DeleteAtivoInternacional(c *gin.Context) {
         c.JSON(http.StatusNotFound, gin.H{"error": "Ativo não encontrado ou não pertence a este usuário."})
         return 
     }
-    ClearInternacionalCache()
+    middleware.SetAuditChange(c, entityInternacional, ticker, before, nil)
     c.JSON(http.StatusOK, gin.H{"message": "Ativo excluído com sucesso!"})
-}
\ No newline at end of file
+}
+
+// --- Auditoria ---
+
+// Tipos de entidade usados no log de auditoria.
+const (
+    entityNacional      = "investimento_nacional"
+    entityInternacional = "investimento_internacional"
+)
+
+// holdingSnapshot é o estado de um ativo registrado no diff da auditoria.
+type holdingSnapshot struct {
+    Ticker     string  `json:"ticker"`
+    Tipo       string  `json:"tipo,omitempty"`
+    Descricao  string  `json:"descricao,omitempty"`
+    Quantidade float64 `json:"quantidade"`
+}
+
+// loadHolding retorna o estado atual do ativo, ou nil se ele não existir.
+func loadHolding(entityType string, userID int64, ticker string) *holdingSnapshot {
+    query := "SELECT ticker, COALESCE(tipo, ''), '', quantidade FROM investimentos_nacionais WHERE user_id = ? AND ticker = ?"
+    if entityType == entityInternacional {
+        query = "SELECT ticker, '', COALESCE(descricao, ''), quantidade FROM investimentos_internacionais WHERE user_id = ? AND ticker = ?"
+    }
+    var h holdingSnapshot
+    err := database.GetDB().QueryRow(database.Rebind(query), userID, ticker).Scan(&h.Ticker, &h.Tipo, &h.Descricao, &h.Quantidade)
+    if err != nil {
+        return nil
+    }
+    return &h
+}
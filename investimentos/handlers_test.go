@@ -3,6 +3,7 @@ package investimentos
 import (
 	"bytes"
 	"encoding/json"
+	"mime/multipart"
 	// "fmt" foi removido pois não estava sendo utilizado
 	"minhas_economias/auth"
 	"minhas_economias/database"
@@ -14,6 +15,7 @@ import (
 
 	"github.com/gin-gonic/gin"
 	_ "github.com/mattn/go-sqlite3" // Driver para o banco de dados de teste
+	"github.com/xuri/excelize/v2"
 )
 
 // testUserID é o ID do usuário que usaremos para todos os testes de investimento.
@@ -51,6 +53,16 @@ func setupInvestimentosTestDB(t *testing.T) {
         CREATE TABLE users (id INTEGER PRIMARY KEY, email TEXT UNIQUE, password_hash TEXT, is_admin BOOLEAN, dark_mode_enabled BOOLEAN);
         CREATE TABLE investimentos_nacionais (user_id INTEGER, ticker TEXT, tipo TEXT, quantidade INTEGER, PRIMARY KEY (user_id, ticker));
         CREATE TABLE investimentos_internacionais (user_id INTEGER, ticker TEXT, descricao TEXT, quantidade REAL, moeda TEXT, PRIMARY KEY (user_id, ticker));
+        CREATE TABLE historico_precos (ticker TEXT NOT NULL, data TEXT NOT NULL, fechamento REAL NOT NULL, moeda TEXT NOT NULL, fonte TEXT, PRIMARY KEY (ticker, data));
+        CREATE TABLE operacoes_investimentos (id INTEGER PRIMARY KEY AUTOINCREMENT, user_id INTEGER NOT NULL, ticker TEXT NOT NULL, tipo_ativo TEXT NOT NULL, tipo TEXT NOT NULL, data TEXT NOT NULL, quantidade INTEGER NOT NULL, preco REAL NOT NULL, taxas REAL NOT NULL DEFAULT 0, corretora TEXT, created_at DATETIME NOT NULL);
+        CREATE TABLE proventos (id INTEGER PRIMARY KEY AUTOINCREMENT, user_id INTEGER NOT NULL, ticker TEXT NOT NULL, tipo_ativo TEXT NOT NULL, tipo TEXT NOT NULL, data_ex TEXT NOT NULL, data_pagamento TEXT NOT NULL, valor_por_cota REAL NOT NULL, quantidade INTEGER NOT NULL, imposto_retido REAL NOT NULL DEFAULT 0, movimentacao_id INTEGER, created_at DATETIME NOT NULL);
+        CREATE TABLE indices_economicos (serie TEXT NOT NULL, data TEXT NOT NULL, valor REAL NOT NULL, PRIMARY KEY (serie, data));
+        CREATE TABLE renda_fixa (id INTEGER PRIMARY KEY AUTOINCREMENT, user_id INTEGER NOT NULL, nome TEXT NOT NULL, tipo TEXT NOT NULL, emissor TEXT, indexador TEXT NOT NULL, taxa REAL NOT NULL, valor_aplicado REAL NOT NULL, data_aplicacao TEXT NOT NULL, vencimento TEXT NOT NULL, liquidez TEXT NOT NULL, created_at DATETIME NOT NULL);
+        CREATE TABLE investimentos_cripto (user_id INTEGER NOT NULL, simbolo TEXT NOT NULL, descricao TEXT, quantidade TEXT NOT NULL, PRIMARY KEY (user_id, simbolo));
+        CREATE TABLE alocacao_alvo (user_id INTEGER NOT NULL, classe TEXT NOT NULL, ticker TEXT NOT NULL DEFAULT '', percentual REAL NOT NULL, PRIMARY KEY (user_id, classe, ticker));
+        CREATE TABLE movimentacoes (id INTEGER PRIMARY KEY AUTOINCREMENT, user_id INTEGER NOT NULL, household_id INTEGER, created_by INTEGER, data_ocorrencia TEXT NOT NULL, descricao TEXT, valor REAL, categoria TEXT, conta TEXT, consolidado BOOLEAN DEFAULT 0, deleted_at DATETIME);
+        CREATE TABLE households (id INTEGER PRIMARY KEY AUTOINCREMENT, name TEXT NOT NULL, owner_id INTEGER NOT NULL, created_at DATETIME NOT NULL);
+        CREATE TABLE household_members (household_id INTEGER NOT NULL, user_id INTEGER NOT NULL, role TEXT NOT NULL, created_at DATETIME NOT NULL, PRIMARY KEY (household_id, user_id));
     `
 	if _, err := db.Exec(createTablesSQL); err != nil {
 		t.Fatalf("Falha ao criar tabelas de teste: %v", err)
@@ -86,7 +98,27 @@ func createInvestimentosTestRouter() *gin.Engine {
 		authorized.POST("/investimentos/internacional", AddAtivoInternacional)
 		authorized.POST("/investimentos/internacional/:ticker", UpdateAtivoInternacional)
 		authorized.DELETE("/investimentos/internacional/:ticker", DeleteAtivoInternacional)
+		authorized.POST("/investimentos/cripto", AddAtivoCripto)
+		authorized.POST("/investimentos/cripto/:simbolo", UpdateAtivoCripto)
+		authorized.DELETE("/investimentos/cripto/:simbolo", DeleteAtivoCripto)
 		authorized.GET("/api/investimentos/precos", GetPrecosInvestimentosAPI)
+		authorized.GET("/api/investimentos/historico/:ticker", GetHistoricoPrecosAPI)
+		authorized.GET("/api/investimentos/avaliacao", GetAvaliacaoCarteiraAPI)
+		authorized.GET("/api/investimentos/desempenho", GetDesempenhoAPI)
+		authorized.POST("/investimentos/importar", ImportarCarteira)
+		authorized.GET("/api/investimentos/operacoes", GetOperacoesAPI)
+		authorized.POST("/investimentos/operacoes", AddOperacao)
+		authorized.POST("/investimentos/operacoes/:id", UpdateOperacao)
+		authorized.DELETE("/investimentos/operacoes/:id", DeleteOperacao)
+		authorized.GET("/api/investimentos/proventos", GetProventosAPI)
+		authorized.POST("/investimentos/proventos", AddProvento)
+		authorized.DELETE("/investimentos/proventos/:id", DeleteProvento)
+		authorized.GET("/api/investimentos/renda-fixa", GetRendaFixaAPI)
+		authorized.POST("/investimentos/renda-fixa", AddRendaFixa)
+		authorized.POST("/investimentos/renda-fixa/:id", UpdateRendaFixa)
+		authorized.DELETE("/investimentos/renda-fixa/:id", DeleteRendaFixa)
+		authorized.GET("/api/investimentos/alocacao", GetAlocacaoAPI)
+		authorized.POST("/investimentos/alocacao", SaveAlocacao)
 	}
 	return r
 }
@@ -150,6 +182,10 @@ func TestDeleteAtivoNacional_Success(t *testing.T) {
 	defer teardownInvestimentosTestDB()
 	router := createInvestimentosTestRouter()
 
+	if _, err := RegistrarOperacao(testUserID, Operacao{Ticker: "PETR4", Tipo: OperacaoCompra, Data: "2025-01-10", Quantidade: 10, Preco: 30}, 28); err != nil {
+		t.Fatalf("Erro ao registrar operação: %v", err)
+	}
+
 	req, _ := http.NewRequest("DELETE", "/investimentos/nacional/PETR4", nil)
 	w := httptest.NewRecorder()
 	router.ServeHTTP(w, req)
@@ -157,6 +193,10 @@ func TestDeleteAtivoNacional_Success(t *testing.T) {
 	if w.Code != http.StatusOK {
 		t.Errorf("Esperado status 200, mas obteve %d. Corpo: %s", w.Code, w.Body.String())
 	}
+	// O livro de operações sai junto com o ativo.
+	if temOperacoes(testUserID, "PETR4") {
+		t.Error("As operações de PETR4 deveriam ter sido excluídas com o ativo")
+	}
 }
 
 // --- NOVOS TESTES PARA FIIs ---
@@ -193,7 +233,6 @@ func TestDeleteFII_Success(t *testing.T) {
 	}
 }
 
-
 // --- Testes para Ativos Internacionais ---
 
 func TestAddAtivoInternacional_Success(t *testing.T) {
@@ -246,6 +285,9 @@ func TestDeleteAtivoInternacional_NotFound(t *testing.T) {
 func TestGetPrecosInvestimentosAPI_Success(t *testing.T) {
 	setupInvestimentosTestDB(t)
 	defer teardownInvestimentosTestDB()
+	// Usa as páginas salvas em downloads/ em vez de acessar a rede.
+	SetProvider(FixtureProvider{Dir: "../downloads"})
+	defer SetProvider(nil)
 	router := createInvestimentosTestRouter()
 
 	req, _ := http.NewRequest("GET", "/api/investimentos/precos", nil)
@@ -270,4 +312,83 @@ func TestGetPrecosInvestimentosAPI_Success(t *testing.T) {
 			t.Errorf("A chave '%s' não foi encontrada na resposta da API de preços.", key)
 		}
 	}
+
+	// Valores das fixtures de 09/07/2025.
+	var precos struct {
+		Acoes          []AcaoNacional       `json:"acoes"`
+		FIIs           []FundoImobiliario   `json:"fiis"`
+		Internacionais []AtivoInternacional `json:"internacionais"`
+		CotacaoDolar   float64              `json:"cotacaoDolar"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &precos)
+	if len(precos.Acoes) != 1 || precos.Acoes[0].Cotacao != 32.52 || precos.Acoes[0].ValorGraham <= 0 {
+		t.Errorf("Cotação de PETR4 inesperada: %+v", precos.Acoes)
+	}
+	if len(precos.FIIs) != 1 || precos.FIIs[0].Cotacao != 9.49 || precos.FIIs[0].Segmento != "Híbrido" {
+		t.Errorf("Dados de MXRF11 inesperados: %+v", precos.FIIs)
+	}
+	if precos.CotacaoDolar != 5.57 {
+		t.Errorf("Cotação do dólar esperada 5.57, obtida %v", precos.CotacaoDolar)
+	}
+	if len(precos.Internacionais) != 1 || precos.Internacionais[0].PrecoUnitarioUSD != 572.35 {
+		t.Errorf("Preço de VOO inesperado: %+v", precos.Internacionais)
+	}
+}
+
+// performImportacaoRequest envia o arquivo para /investimentos/importar como multipart.
+func performImportacaoRequest(r http.Handler, nome string, conteudo []byte, modo string, confirmar bool) *httptest.ResponseRecorder {
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	part, _ := writer.CreateFormFile("arquivo", nome)
+	part.Write(conteudo)
+	writer.WriteField("modo", modo)
+	if confirmar {
+		writer.WriteField("confirmar", "true")
+	}
+	writer.Close()
+	req, _ := http.NewRequest("POST", "/investimentos/importar", &body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+// planilhaTeste monta um XLSX com uma aba por entrada de abas (cabeçalho na primeira linha).
+func planilhaTeste(t *testing.T, abas map[string][][]interface{}) []byte {
+	f := excelize.NewFile()
+	defer f.Close()
+	for nome, linhas := range abas {
+		f.NewSheet(nome)
+		for i, linha := range linhas {
+			celula, _ := excelize.CoordinatesToCellName(1, i+1)
+			if err := f.SetSheetRow(nome, celula, &linha); err != nil {
+				t.Fatalf("Falha ao montar a planilha: %v", err)
+			}
+		}
+	}
+	f.DeleteSheet("Sheet1")
+	buf, err := f.WriteToBuffer()
+	if err != nil {
+		t.Fatalf("Falha ao gravar a planilha: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func itensPorTicker(t *testing.T, w *httptest.ResponseRecorder) (map[string]ItemImportacao, PlanoImportacao) {
+	var resp struct {
+		PlanoImportacao
+		Plano *PlanoImportacao `json:"plano"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil || w.Code != http.StatusOK {
+		t.Fatalf("Resposta inesperada (%d): %s", w.Code, w.Body.String())
+	}
+	plano := resp.PlanoImportacao
+	if resp.Plano != nil {
+		plano = *resp.Plano
+	}
+	itens := make(map[string]ItemImportacao)
+	for _, item := range plano.Itens {
+		itens[item.Ticker] = item
+	}
+	return itens, plano
 }
@@ -0,0 +1,305 @@
+package investimentos
+
+import (
+	"fmt"
+	"log"
+	"minhas_economias/database"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// tickerDolar guarda no histórico a cotação do dólar em reais.
+const tickerDolar = "USDBRL"
+
+// PrecoHistorico é o preço de fechamento (ou a última cotação do dia) de um ativo.
+type PrecoHistorico struct {
+	Ticker     string  `json:"ticker"`
+	Data       string  `json:"data"`
+	Fechamento float64 `json:"fechamento"`
+	Moeda      string  `json:"moeda"`
+	Fonte      string  `json:"fonte"`
+}
+
+// historicoMu serializa as gravações no histórico: as buscas de cada classe de ativos rodam em
+// paralelo e o SQLite não aceita transações de escrita simultâneas.
+var historicoMu sync.Mutex
+
+// RegistrarPrecos grava os preços no histórico; um novo preço no mesmo dia substitui o anterior,
+// de modo que a última cotação do pregão fica como fechamento.
+func RegistrarPrecos(precos []PrecoHistorico) error {
+	if len(precos) == 0 {
+		return nil
+	}
+	historicoMu.Lock()
+	defer historicoMu.Unlock()
+	tx, err := database.GetDB().Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	stmt, err := tx.Prepare(database.Rebind(`INSERT INTO historico_precos (ticker, data, fechamento, moeda, fonte) VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT (ticker, data) DO UPDATE SET fechamento = excluded.fechamento, moeda = excluded.moeda, fonte = excluded.fonte`))
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+	for _, p := range precos {
+		if p.Fechamento <= 0 {
+			continue
+		}
+		if _, err := stmt.Exec(strings.ToUpper(p.Ticker), p.Data, p.Fechamento, p.Moeda, p.Fonte); err != nil {
+			return fmt.Errorf("erro ao gravar o preço de %s: %w", p.Ticker, err)
+		}
+	}
+	return tx.Commit()
+}
+
+// registrarNoHistorico grava os preços buscados sem interromper a consulta em caso de erro.
+func registrarNoHistorico(precos []PrecoHistorico) {
+	if err := RegistrarPrecos(precos); err != nil {
+		log.Printf("AVISO: Falha ao gravar o histórico de preços: %v", err)
+	}
+}
+
+func historicoAcoes(dados map[string]DadosAcao) []PrecoHistorico {
+	precos := make([]PrecoHistorico, 0, len(dados))
+	for ticker, d := range dados {
+		precos = append(precos, PrecoHistorico{Ticker: ticker, Data: d.DataPregao(), Fechamento: d.Cotacao, Moeda: "BRL", Fonte: d.Fonte})
+	}
+	return precos
+}
+
+func historicoFIIs(dados map[string]DadosFII) []PrecoHistorico {
+	precos := make([]PrecoHistorico, 0, len(dados))
+	for ticker, d := range dados {
+		precos = append(precos, PrecoHistorico{Ticker: ticker, Data: d.DataPregao(), Fechamento: d.Cotacao, Moeda: "BRL", Fonte: d.Fonte})
+	}
+	return precos
+}
+
+func historicoCotacao(ticker, moeda string, c Cotacao) PrecoHistorico {
+	return PrecoHistorico{Ticker: ticker, Data: c.DataPregao(), Fechamento: c.Preco, Moeda: moeda, Fonte: c.Fonte}
+}
+
+// HistoricoPrecos retorna os preços gravados do ticker entre as datas (AAAA-MM-DD, vazias = sem limite).
+func HistoricoPrecos(ticker, de, ate string) ([]PrecoHistorico, error) {
+	query := "SELECT ticker, data, fechamento, moeda, COALESCE(fonte, '') FROM historico_precos WHERE ticker = ?"
+	args := []interface{}{strings.ToUpper(ticker)}
+	if de != "" {
+		query += " AND data >= ?"
+		args = append(args, de)
+	}
+	if ate != "" {
+		query += " AND data <= ?"
+		args = append(args, ate)
+	}
+	rows, err := database.GetDB().Query(database.Rebind(query+" ORDER BY data"), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	precos := []PrecoHistorico{}
+	for rows.Next() {
+		var p PrecoHistorico
+		var rawData interface{}
+		if err := rows.Scan(&p.Ticker, &rawData, &p.Fechamento, &p.Moeda, &p.Fonte); err != nil {
+			return nil, err
+		}
+		p.Data = dateString(rawData)
+		precos = append(precos, p)
+	}
+	return precos, rows.Err()
+}
+
+// precoEm retorna o último preço gravado do ticker até a data (inclusive).
+func precoEm(ticker, data string) (*PrecoHistorico, error) {
+	query := database.Rebind("SELECT ticker, data, fechamento, moeda, COALESCE(fonte, '') FROM historico_precos WHERE ticker = ? AND data <= ? ORDER BY data DESC LIMIT 1")
+	var p PrecoHistorico
+	var rawData interface{}
+	err := database.GetDB().QueryRow(query, strings.ToUpper(ticker), data).Scan(&p.Ticker, &rawData, &p.Fechamento, &p.Moeda, &p.Fonte)
+	if err != nil {
+		return nil, err
+	}
+	p.Data = dateString(rawData)
+	return &p, nil
+}
+
+// dateString normaliza datas lidas do banco (time.Time no PostgreSQL, texto no SQLite).
+func dateString(raw interface{}) string {
+	switch v := raw.(type) {
+	case time.Time:
+		return v.Format("2006-01-02")
+	case []byte:
+		return string(v)
+	case string:
+		return v
+	}
+	return ""
+}
+
+// ItemAvaliacao é um ativo da carteira avaliado em uma data.
+type ItemAvaliacao struct {
+	Ticker     string  `json:"ticker"`
+	Tipo       string  `json:"tipo"`
+	Quantidade float64 `json:"quantidade"`
+	Preco      float64 `json:"preco"`
+	Moeda      string  `json:"moeda"`
+	DataPreco  string  `json:"data_preco"` // Data do preço usado (último pregão gravado até a data pedida)
+	ValorBRL   float64 `json:"valor_brl"`
+	// QuantidadeAtual indica um ativo sem livro de operações: a quantidade é a de hoje, não a da data.
+	QuantidadeAtual bool `json:"quantidade_atual,omitempty"`
+}
+
+// AvaliacaoCarteira é o valor da carteira em uma data, calculado com os preços do histórico.
+type AvaliacaoCarteira struct {
+	Data             string          `json:"data"`
+	CotacaoDolar     float64         `json:"cotacao_dolar"`
+	DataCotacaoDolar string          `json:"data_cotacao_dolar,omitempty"`
+	Itens            []ItemAvaliacao `json:"itens"`
+	TotalBRL         float64         `json:"total_brl"`
+	SemPreco         []string        `json:"sem_preco"` // Ativos sem preço gravado até a data
+}
+
+// AvaliarCarteira avalia a carteira do usuário na data com os preços gravados até ela. As ações
+// e FIIs com livro de operações entram com a posição derivada das operações até a data; os
+// demais ativos, sem histórico de quantidades, entram com a quantidade atual (QuantidadeAtual).
+func AvaliarCarteira(userID int64, data string) (*AvaliacaoCarteira, error) {
+	db := database.GetDB()
+	av := &AvaliacaoCarteira{Data: data, Itens: []ItemAvaliacao{}, SemPreco: []string{}}
+
+	if dolar, err := precoEm(tickerDolar, data); err == nil {
+		av.CotacaoDolar = dolar.Fechamento
+		av.DataCotacaoDolar = dolar.Data
+	}
+
+	ops, err := listarOperacoes(db, userID, "")
+	if err != nil {
+		return nil, err
+	}
+	porTicker := make(map[string][]Operacao)
+	for _, op := range ops {
+		porTicker[op.Ticker] = append(porTicker[op.Ticker], op)
+	}
+	var itens []ItemAvaliacao
+	for ticker, opsTicker := range porTicker {
+		var ate []Operacao
+		for _, op := range opsTicker {
+			if op.Data <= data {
+				ate = append(ate, op)
+			}
+		}
+		p, err := CalcularPosicao(ate)
+		if err != nil {
+			return nil, err
+		}
+		if p.Quantidade > 0 {
+			itens = append(itens, ItemAvaliacao{Ticker: ticker, Tipo: p.TipoAtivo, Quantidade: float64(p.Quantidade), Moeda: "BRL"})
+		}
+	}
+	sort.Slice(itens, func(i, j int) bool { return itens[i].Ticker < itens[j].Ticker })
+
+	rows, err := db.Query(database.Rebind("SELECT ticker, COALESCE(tipo, ''), quantidade FROM investimentos_nacionais WHERE user_id = ? ORDER BY ticker"), userID)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		item := ItemAvaliacao{Moeda: "BRL", QuantidadeAtual: true}
+		if err := rows.Scan(&item.Ticker, &item.Tipo, &item.Quantidade); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		if _, temLivro := porTicker[item.Ticker]; !temLivro {
+			itens = append(itens, item)
+		}
+	}
+	rows.Close()
+
+	rows, err = db.Query(database.Rebind("SELECT ticker, quantidade FROM investimentos_internacionais WHERE user_id = ? ORDER BY ticker"), userID)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		item := ItemAvaliacao{Tipo: "INTERNACIONAL", Moeda: "USD", QuantidadeAtual: true}
+		if err := rows.Scan(&item.Ticker, &item.Quantidade); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		itens = append(itens, item)
+	}
+	rows.Close()
+
+	cripto, err := listarCripto(userID)
+	if err != nil {
+		return nil, err
+	}
+	for _, a := range cripto {
+		itens = append(itens, ItemAvaliacao{Ticker: a.Simbolo, Tipo: TipoCripto, Quantidade: quantidadeFloat(a.Quantidade), Moeda: "USD", QuantidadeAtual: true})
+	}
+
+	for _, item := range itens {
+		tickerPreco := strings.TrimSpace(item.Ticker)
+		if item.Tipo == TipoCripto {
+			tickerPreco = tickerCripto(tickerPreco)
+		}
+		preco, err := precoEm(tickerPreco, data)
+		if err != nil || (item.Moeda == "USD" && av.CotacaoDolar == 0) {
+			av.SemPreco = append(av.SemPreco, item.Ticker)
+			continue
+		}
+		item.Preco = preco.Fechamento
+		item.DataPreco = preco.Data
+		item.ValorBRL = item.Preco * item.Quantidade
+		if item.Moeda == "USD" {
+			item.ValorBRL *= av.CotacaoDolar
+		}
+		av.TotalBRL += item.ValorBRL
+		av.Itens = append(av.Itens, item)
+	}
+	return av, nil
+}
+
+// GetHistoricoPrecosAPI retorna o histórico de preços de um ticker (filtros opcionais de e ate).
+func GetHistoricoPrecosAPI(c *gin.Context) {
+	ticker := strings.ToUpper(strings.TrimSpace(c.Param("ticker")))
+	de, ate := c.Query("de"), c.Query("ate")
+	for _, d := range []string{de, ate} {
+		if _, err := time.Parse("2006-01-02", d); d != "" && err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Datas devem estar no formato AAAA-MM-DD."})
+			return
+		}
+	}
+	precos, err := HistoricoPrecos(ticker, de, ate)
+	if err != nil {
+		log.Printf("Erro ao buscar o histórico de preços de %s: %v", ticker, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao buscar o histórico de preços."})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"ticker": ticker, "precos": precos})
+}
+
+// GetAvaliacaoCarteiraAPI avalia a carteira do usuário na data pedida (?data=AAAA-MM-DD, padrão hoje).
+func GetAvaliacaoCarteiraAPI(c *gin.Context) {
+	userID := c.MustGet("userID").(int64)
+	hoje := time.Now().Format("2006-01-02")
+	data := c.DefaultQuery("data", hoje)
+	if _, err := time.Parse("2006-01-02", data); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "A data deve estar no formato AAAA-MM-DD."})
+		return
+	}
+	if data > hoje {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Não é possível avaliar a carteira em uma data futura."})
+		return
+	}
+	av, err := AvaliarCarteira(userID, data)
+	if err != nil {
+		log.Printf("Erro ao avaliar a carteira do usuário %d em %s: %v", userID, data, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao avaliar a carteira."})
+		return
+	}
+	c.JSON(http.StatusOK, av)
+}
@@ -0,0 +1,92 @@
+package investimentos
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+)
+
+func TestHistoricoEAvaliacaoCarteira(t *testing.T) {
+	setupInvestimentosTestDB(t)
+	defer teardownInvestimentosTestDB()
+	SetProvider(FixtureProvider{Dir: "../downloads"})
+	defer SetProvider(nil)
+	router := createInvestimentosTestRouter()
+
+	// A busca de cotações grava os preços com a data das fixtures.
+	performInvestimentosJSONRequest(router, "GET", "/api/investimentos/precos", nil)
+	RegistrarPrecos([]PrecoHistorico{
+		{Ticker: "PETR4", Data: "2025-06-30", Fechamento: 30, Moeda: "BRL", Fonte: "teste"},
+		{Ticker: "USDBRL", Data: "2025-06-30", Fechamento: 5, Moeda: "BRL", Fonte: "teste"},
+		{Ticker: "VOO", Data: "2025-06-30", Fechamento: 500, Moeda: "USD", Fonte: "teste"},
+	})
+
+	w := performInvestimentosJSONRequest(router, "GET", "/api/investimentos/historico/petr4?de=2025-01-01", nil)
+	var historico struct {
+		Precos []PrecoHistorico `json:"precos"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &historico)
+	if w.Code != http.StatusOK || len(historico.Precos) != 2 || historico.Precos[1].Fechamento != 32.52 || historico.Precos[1].Data != "2025-07-09" || historico.Precos[1].Fonte != "fixtures" {
+		t.Fatalf("Histórico de PETR4 inesperado (%d): %s", w.Code, w.Body.String())
+	}
+
+	// Em 05/07 vale o último preço gravado até a data (30/06); MXRF11 ainda não tinha preço.
+	w = performInvestimentosJSONRequest(router, "GET", "/api/investimentos/avaliacao?data=2025-07-05", nil)
+	var av AvaliacaoCarteira
+	json.Unmarshal(w.Body.Bytes(), &av)
+	if w.Code != http.StatusOK || av.TotalBRL != 100*30+10.5*500*5 || len(av.SemPreco) != 1 || av.SemPreco[0] != "MXRF11" {
+		t.Errorf("Avaliação em 05/07 inesperada (%d): %s", w.Code, w.Body.String())
+	}
+
+	w = performInvestimentosJSONRequest(router, "GET", "/api/investimentos/avaliacao?data=2025-07-10", nil)
+	json.Unmarshal(w.Body.Bytes(), &av)
+	if len(av.Itens) != 3 || av.CotacaoDolar != 5.57 || av.Itens[0].DataPreco != "2025-07-09" {
+		t.Errorf("Avaliação em 10/07 inesperada: %s", w.Body.String())
+	}
+
+	if w := performInvestimentosJSONRequest(router, "GET", "/api/investimentos/avaliacao?data=10/07/2025", nil); w.Code != http.StatusBadRequest {
+		t.Errorf("Data inválida: esperado 400, obtido %d", w.Code)
+	}
+}
+
+func TestAvaliarCarteiraUsaPosicaoDoLivro(t *testing.T) {
+	setupInvestimentosTestDB(t)
+	defer teardownInvestimentosTestDB()
+
+	for _, op := range []Operacao{
+		{Ticker: "VALE3", TipoAtivo: "ACAO", Tipo: OperacaoCompra, Data: "2025-07-01", Quantidade: 10, Preco: 50},
+		{Ticker: "VALE3", TipoAtivo: "ACAO", Tipo: OperacaoCompra, Data: "2025-07-08", Quantidade: 5, Preco: 55},
+	} {
+		if _, err := RegistrarOperacao(testUserID, op, 0); err != nil {
+			t.Fatalf("Erro ao registrar operação: %v", err)
+		}
+	}
+	RegistrarPrecos([]PrecoHistorico{{Ticker: "VALE3", Data: "2025-06-30", Fechamento: 60, Moeda: "BRL", Fonte: "teste"}})
+
+	quantidades := func(data string) map[string]ItemAvaliacao {
+		av, err := AvaliarCarteira(testUserID, data)
+		if err != nil {
+			t.Fatalf("Erro ao avaliar a carteira em %s: %v", data, err)
+		}
+		itens := make(map[string]ItemAvaliacao)
+		for _, item := range av.Itens {
+			itens[item.Ticker] = item
+		}
+		return itens
+	}
+
+	if _, ok := quantidades("2025-06-30")["VALE3"]; ok {
+		t.Error("VALE3 não deveria estar na carteira antes da primeira compra")
+	}
+	if item := quantidades("2025-07-05")["VALE3"]; item.Quantidade != 10 || item.ValorBRL != 600 || item.QuantidadeAtual {
+		t.Errorf("VALE3 em 05/07 inesperado: %+v", item)
+	}
+	itens := quantidades("2025-07-10")
+	if item := itens["VALE3"]; item.Quantidade != 15 || item.QuantidadeAtual {
+		t.Errorf("VALE3 em 10/07 inesperado: %+v", item)
+	}
+	// Os ativos sem livro de operações entram com a quantidade atual, sinalizados na resposta.
+	if item := itens["PETR4"]; item.Quantidade != 100 || !item.QuantidadeAtual {
+		t.Errorf("PETR4 deveria usar a quantidade atual: %+v", item)
+	}
+}
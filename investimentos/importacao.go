@@ -0,0 +1,821 @@
+package investimentos
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"minhas_economias/database"
+	"minhas_economias/middleware"
+	"net/http"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/xuri/excelize/v2"
+)
+
+// Formatos de arquivo aceitos na importação da carteira.
+const (
+	FormatoCSV            = "csv"             // tipo;ticker;quantidade[;moeda], como em cmd/admin
+	FormatoB3Posicao      = "b3_posicao"      // Posição da Área do Investidor da B3 (XLSX)
+	FormatoB3Movimentacao = "b3_movimentacao" // Movimentação da Área do Investidor da B3 (XLSX)
+)
+
+// Modos de importação: mesclar altera só os ativos do arquivo; substituir também remove os
+// ativos da carteira (e as suas operações) que não estão no arquivo.
+const (
+	ModoMesclar    = "mesclar"
+	ModoSubstituir = "substituir"
+)
+
+// Efeitos da importação sobre um ativo.
+const (
+	acaoIncluir   = "incluir"
+	acaoAtualizar = "atualizar"
+	acaoManter    = "manter"
+	acaoRemover   = "remover"
+	acaoIgnorar   = "ignorar"
+)
+
+// entityImportacao é o tipo de entidade das importações no log de auditoria.
+const entityImportacao = "importacao_carteira"
+
+// limiteImportacao é o tamanho máximo do arquivo enviado.
+const limiteImportacao = 5 * 1024 * 1024
+
+// Limites da leitura das planilhas .xlsx, que são ZIPs e podem descomprimir para muito mais que
+// o arquivo enviado: o total descomprimido, o XML mantido em memória por aba (acima disso o
+// excelize usa arquivo temporário) e o número de linhas somando todas as abas.
+const (
+	limiteDescompactado    = 20 * limiteImportacao
+	limiteXMLDescompactado = 4 * limiteImportacao
+	limiteLinhasPlanilha   = 20000
+)
+
+// tickerB3 reconhece os códigos de negociação de ações e FIIs (o sufixo F do mercado
+// fracionário é removido antes).
+var tickerB3 = regexp.MustCompile(`^[A-Z0-9]{4}[0-9]{1,2}$`)
+
+// tickerBDR reconhece os códigos de BDRs (finais 32 a 35) e de BDRs de ETFs (final 39).
+var tickerBDR = regexp.MustCompile(`^[A-Z0-9]{4}(3[2-5]|39)$`)
+
+// semAcentos normaliza cabeçalhos e textos das planilhas da B3, que variam na acentuação.
+var semAcentos = strings.NewReplacer("á", "a", "à", "a", "â", "a", "ã", "a", "é", "e", "ê", "e", "í", "i", "ó", "o", "ô", "o", "õ", "o", "ú", "u", "ç", "c")
+
+// RegistroImportado é uma linha aproveitada do arquivo: uma posição (CSV e posição da B3) ou
+// uma compra/venda (movimentação da B3).
+type RegistroImportado struct {
+	Linha      int     `json:"linha"`
+	Classe     string  `json:"classe"` // ACAO, FII ou INTERNACIONAL
+	Ticker     string  `json:"ticker"`
+	Descricao  string  `json:"descricao,omitempty"`
+	Moeda      string  `json:"moeda,omitempty"` // Ativos do exterior (CSV)
+	Quantidade float64 `json:"quantidade"`
+	Operacao   string  `json:"operacao,omitempty"` // C ou V (movimentação)
+	Data       string  `json:"data,omitempty"`
+	Preco      float64 `json:"preco,omitempty"`
+	Corretora  string  `json:"corretora,omitempty"`
+}
+
+// ArquivoCarteira é o conteúdo lido de um arquivo da carteira.
+type ArquivoCarteira struct {
+	Formato   string
+	Registros []RegistroImportado
+	Ignorados []string // Linhas e abas descartadas, com o motivo
+}
+
+func (a *ArquivoCarteira) ignorar(formato string, args ...interface{}) {
+	a.Ignorados = append(a.Ignorados, fmt.Sprintf(formato, args...))
+}
+
+// LerArquivoCarteira identifica o formato pelo nome e pelo conteúdo e lê o arquivo.
+func LerArquivoCarteira(nome string, data []byte) (*ArquivoCarteira, error) {
+	switch ext := strings.ToLower(filepath.Ext(nome)); {
+	case ext == ".xls":
+		return nil, errors.New("planilhas .xls não são suportadas; exporte o extrato da B3 em .xlsx")
+	case ext == ".xlsx" || bytes.HasPrefix(data, []byte("PK")):
+		return lerPlanilhaB3(data)
+	}
+	return lerCSVCarteira(bytes.NewReader(data))
+}
+
+// parseNumero aceita "1234.5", "1.234,5" e "R$ 1.234,50".
+func parseNumero(s string) (float64, error) {
+	s = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(s), "R$"))
+	if strings.Contains(s, ",") {
+		s = strings.ReplaceAll(strings.ReplaceAll(s, ".", ""), ",", ".")
+	}
+	return strconv.ParseFloat(s, 64)
+}
+
+// normalizarTipoAtivo converte o tipo informado no arquivo em ACAO ou FII (vazio se inválido).
+func normalizarTipoAtivo(tipo string) string {
+	switch strings.ToUpper(semAcentos.Replace(strings.ToLower(strings.TrimSpace(tipo)))) {
+	case "ACAO", "ACOES":
+		return ClasseAcoes
+	case "FII", "FIIS":
+		return ClasseFIIs
+	}
+	return ""
+}
+
+// lerCSVCarteira lê o CSV separado por ";" usado por cmd/admin: tipo;ticker;quantidade para
+// ações e FIIs e tipo;ticker;quantidade;moeda para ativos do exterior (o tipo vira a descrição).
+// A primeira linha pode ser um cabeçalho.
+func lerCSVCarteira(r io.Reader) (*ArquivoCarteira, error) {
+	reader := csv.NewReader(r)
+	reader.Comma = ';'
+	reader.LazyQuotes = true
+	reader.FieldsPerRecord = -1
+	arq := &ArquivoCarteira{Formato: FormatoCSV}
+	for linha := 1; ; linha++ {
+		campos, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("linha %d: %w", linha, err)
+		}
+		for i := range campos {
+			campos[i] = strings.TrimSpace(campos[i])
+		}
+		if strings.Join(campos, "") == "" {
+			continue
+		}
+		if len(campos) < 3 {
+			arq.ignorar("Linha %d: esperado tipo;ticker;quantidade.", linha)
+			continue
+		}
+		quantidade, err := parseNumero(campos[2])
+		if err != nil {
+			if linha == 1 {
+				continue // cabeçalho
+			}
+			arq.ignorar("Linha %d: quantidade '%s' inválida.", linha, campos[2])
+			continue
+		}
+		reg := RegistroImportado{Linha: linha, Ticker: strings.ToUpper(campos[1]), Quantidade: quantidade}
+		if len(campos) >= 4 {
+			reg.Classe, reg.Descricao, reg.Moeda = ClasseInternacional, campos[0], strings.ToUpper(campos[3])
+			if reg.Moeda == "" {
+				reg.Moeda = "USD"
+			}
+		} else if reg.Classe = normalizarTipoAtivo(campos[0]); reg.Classe == "" {
+			arq.ignorar("Linha %d: tipo '%s' inválido (use ACAO ou FII).", linha, campos[0])
+			continue
+		}
+		switch {
+		case reg.Ticker == "" || quantidade <= 0:
+			arq.ignorar("Linha %d: ticker vazio ou quantidade não positiva.", linha)
+		case reg.Classe != ClasseInternacional && quantidade != math.Trunc(quantidade):
+			arq.ignorar("Linha %d: quantidade fracionária de %s.", linha, reg.Ticker)
+		default:
+			arq.Registros = append(arq.Registros, reg)
+		}
+	}
+	if len(arq.Registros) == 0 {
+		return nil, errors.New("nenhuma posição encontrada no arquivo")
+	}
+	return arq, nil
+}
+
+// colunasPlanilha mapeia o cabeçalho normalizado de uma aba para o índice da coluna.
+type colunasPlanilha map[string]int
+
+func novasColunas(cabecalho []string) colunasPlanilha {
+	col := make(colunasPlanilha, len(cabecalho))
+	for i, nome := range cabecalho {
+		col[normalizarTexto(nome)] = i
+	}
+	return col
+}
+
+func (col colunasPlanilha) tem(nomes ...string) bool {
+	for _, nome := range nomes {
+		if _, ok := col[nome]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+func (col colunasPlanilha) valor(row []string, nome string) string {
+	if i, ok := col[nome]; ok && i < len(row) {
+		return strings.TrimSpace(row[i])
+	}
+	return ""
+}
+
+func normalizarTexto(s string) string {
+	return semAcentos.Replace(strings.ToLower(strings.TrimSpace(s)))
+}
+
+// tickerNegociacao extrai o ticker de um código de negociação da B3 (PETR4F vira PETR4).
+func tickerNegociacao(codigo string) (string, bool) {
+	ticker := strings.TrimSuffix(strings.ToUpper(strings.TrimSpace(codigo)), "F")
+	return ticker, tickerB3.MatchString(ticker)
+}
+
+// parseDataPlanilha aceita datas em texto (DD/MM/AAAA ou AAAA-MM-DD) ou seriais do Excel.
+func parseDataPlanilha(s string) (string, error) {
+	for _, layout := range []string{"02/01/2006", "2006-01-02"} {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t.Format("2006-01-02"), nil
+		}
+	}
+	if serial, err := strconv.ParseFloat(s, 64); err == nil {
+		if t, err := excelize.ExcelDateToTime(serial, false); err == nil {
+			return t.Format("2006-01-02"), nil
+		}
+	}
+	return "", fmt.Errorf("data '%s' inválida", s)
+}
+
+// lerPlanilhaB3 lê o extrato de posição (uma aba por tipo de produto) ou de movimentação
+// exportado pela Área do Investidor da B3.
+func lerPlanilhaB3(data []byte) (*ArquivoCarteira, error) {
+	f, err := excelize.OpenReader(bytes.NewReader(data), excelize.Options{UnzipSizeLimit: limiteDescompactado, UnzipXMLSizeLimit: limiteXMLDescompactado})
+	if err != nil {
+		return nil, fmt.Errorf("planilha inválida: %w", err)
+	}
+	defer f.Close()
+
+	arq := &ArquivoCarteira{}
+	linhas := 0
+	for _, aba := range f.GetSheetList() {
+		rows, err := lerLinhasAba(f, aba, limiteLinhasPlanilha-linhas)
+		if err != nil {
+			return nil, fmt.Errorf("aba '%s': %w", aba, err)
+		}
+		linhas += len(rows)
+		if len(rows) == 0 {
+			continue
+		}
+		col := novasColunas(rows[0])
+		formato := ""
+		switch {
+		case col.tem("entrada/saida", "movimentacao", "produto", "quantidade"):
+			formato = FormatoB3Movimentacao
+		case col.tem("codigo de negociacao", "quantidade"):
+			formato = FormatoB3Posicao
+		default:
+			arq.ignorar("Aba '%s': formato não reconhecido.", aba)
+			continue
+		}
+		if arq.Formato != "" && arq.Formato != formato {
+			return nil, errors.New("a planilha mistura posição e movimentação; envie um extrato de cada vez")
+		}
+		arq.Formato = formato
+		if formato == FormatoB3Movimentacao {
+			lerMovimentacaoB3(arq, aba, rows, col)
+		} else {
+			lerPosicaoB3(arq, aba, rows, col)
+		}
+	}
+	if arq.Formato == "" {
+		return nil, errors.New("a planilha não é um extrato de posição ou de movimentação da Área do Investidor da B3")
+	}
+	if len(arq.Registros) == 0 {
+		return nil, errors.New("nenhuma ação ou FII encontrado na planilha")
+	}
+	return arq, nil
+}
+
+// lerLinhasAba lê as linhas da aba em fluxo, parando com erro se passarem de limite.
+func lerLinhasAba(f *excelize.File, aba string, limite int) ([][]string, error) {
+	it, err := f.Rows(aba)
+	if err != nil {
+		return nil, err
+	}
+	defer it.Close()
+	var rows [][]string
+	for it.Next() {
+		if len(rows) >= limite {
+			return nil, fmt.Errorf("a planilha tem mais de %d linhas", limiteLinhasPlanilha)
+		}
+		row, err := it.Columns(excelize.Options{RawCellValue: true})
+		if err != nil {
+			return nil, err
+		}
+		rows = append(rows, row)
+	}
+	return rows, it.Error()
+}
+
+// classeAbaB3 retorna a classe dos ativos de uma aba do extrato de posição; BDRs, ETFs, renda
+// fixa e Tesouro Direto não são importados.
+func classeAbaB3(aba string) string {
+	switch nome := normalizarTexto(aba); {
+	case strings.HasPrefix(nome, "acoes"):
+		return ClasseAcoes
+	case strings.HasPrefix(nome, "fundo de investimento"), strings.HasPrefix(nome, "fii"):
+		return ClasseFIIs
+	}
+	return ""
+}
+
+// classeProdutoB3 classifica um produto do extrato de movimentação, que não traz o tipo. Como
+// nas abas do extrato de posição (classeAbaB3), BDRs e ETFs não são importados (vazio); os FIIs
+// se identificam pelo nome.
+func classeProdutoB3(ticker, nome string) string {
+	n := normalizarTexto(nome)
+	switch {
+	case tickerBDR.MatchString(ticker), strings.Contains(n, "fundo de indice"), strings.Contains(" "+n+" ", " etf "):
+		return ""
+	case strings.Contains(n, "imobiliari"), strings.HasSuffix(n, " fii"):
+		return ClasseFIIs
+	}
+	return ClasseAcoes
+}
+
+// lerPosicaoB3 lê uma aba do extrato de posição. A B3 separa a posição por corretora; as
+// linhas do mesmo ativo são somadas no planejamento.
+func lerPosicaoB3(arq *ArquivoCarteira, aba string, rows [][]string, col colunasPlanilha) {
+	classe := classeAbaB3(aba)
+	if classe == "" {
+		arq.ignorar("Aba '%s': só ações e FIIs são importados.", aba)
+		return
+	}
+	for i, row := range rows[1:] {
+		linha := i + 2
+		codigo := col.valor(row, "codigo de negociacao")
+		if codigo == "" {
+			continue // linha de total ou em branco
+		}
+		ticker, ok := tickerNegociacao(codigo)
+		if !ok {
+			arq.ignorar("Aba '%s', linha %d: código '%s' não é de uma ação ou FII.", aba, linha, codigo)
+			continue
+		}
+		quantidade, err := parseNumero(col.valor(row, "quantidade"))
+		if err != nil || quantidade <= 0 || quantidade != math.Trunc(quantidade) {
+			arq.ignorar("Aba '%s', linha %d: quantidade de %s inválida.", aba, linha, ticker)
+			continue
+		}
+		arq.Registros = append(arq.Registros, RegistroImportado{
+			Linha: linha, Classe: classe, Ticker: ticker, Quantidade: quantidade, Corretora: col.valor(row, "instituicao"),
+		})
+	}
+}
+
+// lerMovimentacaoB3 lê as compras e vendas do extrato de movimentação: os lançamentos de
+// "Transferência - Liquidação", datados na liquidação. Proventos, eventos corporativos e
+// demais lançamentos são contados e ignorados.
+func lerMovimentacaoB3(arq *ArquivoCarteira, aba string, rows [][]string, col colunasPlanilha) {
+	outros := make(map[string]int)
+	for i, row := range rows[1:] {
+		linha := i + 2
+		movimentacao := col.valor(row, "movimentacao")
+		if movimentacao == "" {
+			continue
+		}
+		if normalizarTexto(movimentacao) != "transferencia - liquidacao" {
+			outros[movimentacao]++
+			continue
+		}
+		produto := col.valor(row, "produto")
+		codigo, nome, _ := strings.Cut(produto, " - ")
+		ticker, ok := tickerNegociacao(codigo)
+		if !ok {
+			arq.ignorar("Aba '%s', linha %d: '%s' não é uma ação ou FII.", aba, linha, produto)
+			continue
+		}
+		classe := classeProdutoB3(ticker, nome)
+		if classe == "" {
+			arq.ignorar("Aba '%s', linha %d: '%s' é um BDR ou ETF; só ações e FIIs são importados.", aba, linha, produto)
+			continue
+		}
+		data, errData := parseDataPlanilha(col.valor(row, "data"))
+		quantidade, errQtd := parseNumero(col.valor(row, "quantidade"))
+		preco, errPreco := parseNumero(col.valor(row, "preco unitario"))
+		switch {
+		case errData != nil:
+			arq.ignorar("Aba '%s', linha %d: %v.", aba, linha, errData)
+			continue
+		case errQtd != nil || quantidade <= 0 || quantidade != math.Trunc(quantidade):
+			arq.ignorar("Aba '%s', linha %d: quantidade de %s inválida.", aba, linha, ticker)
+			continue
+		case errPreco != nil || preco < 0:
+			arq.ignorar("Aba '%s', linha %d: preço de %s inválido.", aba, linha, ticker)
+			continue
+		}
+
+		reg := RegistroImportado{
+			Linha: linha, Classe: classe, Ticker: ticker, Descricao: strings.TrimSpace(nome), Quantidade: quantidade,
+			Operacao: OperacaoCompra, Data: data, Preco: preco, Corretora: col.valor(row, "instituicao"),
+		}
+		if normalizarTexto(col.valor(row, "entrada/saida")) == "debito" {
+			reg.Operacao = OperacaoVenda
+		}
+		arq.Registros = append(arq.Registros, reg)
+	}
+	tipos := make([]string, 0, len(outros))
+	for tipo := range outros {
+		tipos = append(tipos, tipo)
+	}
+	sort.Strings(tipos)
+	for _, tipo := range tipos {
+		arq.ignorar("%d lançamento(s) de '%s' ignorado(s): só compras e vendas são importadas.", outros[tipo], tipo)
+	}
+}
+
+// --- Planejamento e aplicação ---
+
+// ItemImportacao é o efeito da importação sobre um ativo.
+type ItemImportacao struct {
+	Classe          string  `json:"classe"`
+	Ticker          string  `json:"ticker"`
+	Acao            string  `json:"acao"` // incluir, atualizar, manter, remover ou ignorar
+	QuantidadeAtual float64 `json:"quantidade_atual"`
+	QuantidadeNova  float64 `json:"quantidade_nova"`
+	Operacoes       int     `json:"operacoes,omitempty"` // Compras e vendas a registrar (movimentação)
+	Aviso           string  `json:"aviso,omitempty"`
+
+	descricao string
+	moeda     string
+	ops       []Operacao
+}
+
+// PlanoImportacao é a prévia da importação: o que muda na carteira se ela for confirmada.
+type PlanoImportacao struct {
+	Formato   string           `json:"formato"`
+	Modo      string           `json:"modo"`
+	Itens     []ItemImportacao `json:"itens"`
+	Ignorados []string         `json:"ignorados"`
+	Resumo    map[string]int   `json:"resumo"` // Número de ativos por efeito
+}
+
+// posicaoCadastrada é a quantidade de um ativo na carteira antes da importação.
+type posicaoCadastrada struct {
+	classe     string
+	quantidade float64
+}
+
+// carregarPosicoes lê as quantidades cadastradas de ações e FIIs ou, com internacional, dos
+// ativos do exterior.
+func carregarPosicoes(userID int64, internacional bool) (map[string]posicaoCadastrada, error) {
+	query := "SELECT ticker, COALESCE(tipo, ''), quantidade FROM investimentos_nacionais WHERE user_id = ?"
+	if internacional {
+		query = "SELECT ticker, 'INTERNACIONAL', quantidade FROM investimentos_internacionais WHERE user_id = ?"
+	}
+	rows, err := database.GetDB().Query(database.Rebind(query), userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	posicoes := make(map[string]posicaoCadastrada)
+	for rows.Next() {
+		var ticker string
+		var p posicaoCadastrada
+		if err := rows.Scan(&ticker, &p.classe, &p.quantidade); err != nil {
+			return nil, err
+		}
+		p.classe = strings.ToUpper(p.classe)
+		posicoes[ticker] = p
+	}
+	return posicoes, rows.Err()
+}
+
+// PlanejarImportacao compara o arquivo com a carteira atual e monta a prévia sem gravar nada.
+func PlanejarImportacao(userID int64, arq *ArquivoCarteira, modo string) (*PlanoImportacao, error) {
+	nacionais, err := carregarPosicoes(userID, false)
+	if err != nil {
+		return nil, err
+	}
+	internacionais, err := carregarPosicoes(userID, true)
+	if err != nil {
+		return nil, err
+	}
+	ops, err := listarOperacoes(database.GetDB(), userID, "")
+	if err != nil {
+		return nil, err
+	}
+	livro := make(map[string][]Operacao)
+	for _, op := range ops {
+		livro[op.Ticker] = append(livro[op.Ticker], op)
+	}
+
+	plano := &PlanoImportacao{Formato: arq.Formato, Modo: modo, Itens: []ItemImportacao{}, Ignorados: append([]string{}, arq.Ignorados...)}
+	if arq.Formato == FormatoB3Movimentacao {
+		plano.planejarMovimentacao(arq.Registros, nacionais, livro)
+	} else {
+		plano.planejarPosicoes(arq.Registros, nacionais, internacionais, livro)
+	}
+
+	sort.SliceStable(plano.Itens, func(i, j int) bool {
+		if plano.Itens[i].Classe != plano.Itens[j].Classe {
+			return plano.Itens[i].Classe < plano.Itens[j].Classe
+		}
+		return plano.Itens[i].Ticker < plano.Itens[j].Ticker
+	})
+	plano.Resumo = map[string]int{acaoIncluir: 0, acaoAtualizar: 0, acaoManter: 0, acaoRemover: 0, acaoIgnorar: 0}
+	for _, item := range plano.Itens {
+		plano.Resumo[item.Acao]++
+	}
+	return plano, nil
+}
+
+// planejarPosicoes define a quantidade de cada ativo do arquivo. A quantidade de um ativo com
+// operações registradas é derivada do livro e não é sobrescrita.
+func (plano *PlanoImportacao) planejarPosicoes(regs []RegistroImportado, nacionais, internacionais map[string]posicaoCadastrada, livro map[string][]Operacao) {
+	itens := make(map[string]*ItemImportacao)
+	var ordem []string
+	for _, reg := range regs {
+		chave := reg.Classe + ":" + reg.Ticker
+		if reg.Classe != ClasseInternacional {
+			chave = reg.Ticker
+		}
+		item, ok := itens[chave]
+		if !ok {
+			item = &ItemImportacao{Classe: reg.Classe, Ticker: reg.Ticker, descricao: reg.Descricao, moeda: reg.Moeda}
+			itens[chave] = item
+			ordem = append(ordem, chave)
+		}
+		item.QuantidadeNova += reg.Quantidade
+	}
+
+	substituiNacionais, substituiInternacionais := false, false
+	for _, chave := range ordem {
+		item := itens[chave]
+		cadastradas := nacionais
+		if item.Classe == ClasseInternacional {
+			cadastradas, substituiInternacionais = internacionais, true
+		} else {
+			substituiNacionais = true
+		}
+		atual, existe := cadastradas[item.Ticker]
+		item.QuantidadeAtual = atual.quantidade
+		switch {
+		case !existe:
+			item.Acao = acaoIncluir
+		case atual.quantidade == item.QuantidadeNova && (item.Classe == ClasseInternacional || atual.classe == item.Classe):
+			item.Acao = acaoManter
+		case len(livro[item.Ticker]) > 0:
+			item.Acao = acaoIgnorar
+			item.Aviso = "A quantidade é derivada do livro de operações; registre a diferença como compra ou venda ou importe a movimentação da B3."
+		default:
+			item.Acao = acaoAtualizar
+		}
+		plano.Itens = append(plano.Itens, *item)
+	}
+
+	if plano.Modo != ModoSubstituir {
+		return
+	}
+	if substituiNacionais {
+		for ticker, atual := range nacionais {
+			if _, ok := itens[ticker]; !ok {
+				plano.Itens = append(plano.Itens, itemRemocao(atual.classe, ticker, atual.quantidade, len(livro[ticker])))
+			}
+		}
+	}
+	if substituiInternacionais {
+		for ticker, atual := range internacionais {
+			if _, ok := itens[ClasseInternacional+":"+ticker]; !ok {
+				plano.Itens = append(plano.Itens, itemRemocao(ClasseInternacional, ticker, atual.quantidade, 0))
+			}
+		}
+	}
+}
+
+func itemRemocao(classe, ticker string, quantidade float64, operacoes int) ItemImportacao {
+	item := ItemImportacao{Classe: classe, Ticker: ticker, Acao: acaoRemover, QuantidadeAtual: quantidade}
+	if operacoes > 0 {
+		item.Aviso = fmt.Sprintf("As %d operações registradas do ativo também serão excluídas.", operacoes)
+	}
+	return item
+}
+
+// chaveOperacao identifica uma operação já registrada ao mesclar a movimentação, para que
+// importar o mesmo extrato duas vezes não duplique as compras e vendas.
+func chaveOperacao(op Operacao) string {
+	return fmt.Sprintf("%s|%s|%d|%.2f", op.Tipo, op.Data, op.Quantidade, op.Preco)
+}
+
+// planejarMovimentacao monta as operações de cada ativo do extrato. Ao mesclar, entram só as
+// que ainda não estão no livro e a quantidade cadastrada de um ativo sem operações dá lugar à
+// posição derivada delas; ao substituir, o livro do ativo passa a ser o do arquivo. Ativos cujas
+// vendas superam a posição são ignorados (e mantidos como estão).
+func (plano *PlanoImportacao) planejarMovimentacao(regs []RegistroImportado, nacionais map[string]posicaoCadastrada, livro map[string][]Operacao) {
+	arquivo := make(map[string][]Operacao)
+	var ordem []string
+	for _, reg := range regs {
+		if _, ok := arquivo[reg.Ticker]; !ok {
+			ordem = append(ordem, reg.Ticker)
+		}
+		arquivo[reg.Ticker] = append(arquivo[reg.Ticker], Operacao{
+			Ticker: reg.Ticker, TipoAtivo: reg.Classe, Tipo: reg.Operacao, Data: reg.Data,
+			Quantidade: int(reg.Quantidade), Preco: reg.Preco, Corretora: reg.Corretora,
+		})
+	}
+
+	for _, ticker := range ordem {
+		existentes := livro[ticker]
+		atual, existe := nacionais[ticker]
+		tipoAtivo := arquivo[ticker][0].TipoAtivo
+		if len(existentes) > 0 {
+			tipoAtivo = existentes[0].TipoAtivo
+		} else if existe && (atual.classe == ClasseAcoes || atual.classe == ClasseFIIs) {
+			tipoAtivo = atual.classe
+		}
+		item := ItemImportacao{Classe: tipoAtivo, Ticker: ticker, QuantidadeAtual: atual.quantidade}
+
+		base := existentes
+		registradas := make(map[string]int)
+		if plano.Modo == ModoSubstituir {
+			base = nil
+		} else {
+			for _, op := range existentes {
+				registradas[chaveOperacao(op)]++
+			}
+		}
+		var erro error
+		for _, op := range arquivo[ticker] {
+			op.TipoAtivo = tipoAtivo
+			if registradas[chaveOperacao(op)] > 0 {
+				registradas[chaveOperacao(op)]--
+				continue
+			}
+			if err := validarOperacao(&op); err != nil && erro == nil {
+				erro = fmt.Errorf("operação de %s: %s", op.Data, strings.TrimSuffix(err.Error(), "."))
+			}
+			item.ops = append(item.ops, op)
+		}
+		posicao, err := CalcularPosicao(append(append([]Operacao{}, base...), item.ops...))
+		if erro == nil {
+			erro = err
+		}
+		item.Operacoes = len(item.ops)
+		item.QuantidadeNova = float64(posicao.Quantidade)
+
+		switch {
+		case erro != nil:
+			item.Acao, item.QuantidadeNova, item.ops = acaoIgnorar, atual.quantidade, nil
+			item.Aviso = fmt.Sprintf("Não importado: %v.", erro)
+		case item.Operacoes == 0:
+			item.Acao = acaoManter
+		case !existe && len(existentes) == 0:
+			item.Acao = acaoIncluir
+		default:
+			item.Acao = acaoAtualizar
+			if plano.Modo == ModoSubstituir && len(existentes) > 0 {
+				item.Aviso = fmt.Sprintf("As %d operações registradas serão substituídas pelas do arquivo.", len(existentes))
+			} else if existe && len(existentes) == 0 {
+				item.Aviso = fmt.Sprintf("A quantidade cadastrada (%g) passa a ser a posição derivada da movimentação.", atual.quantidade)
+			}
+		}
+		plano.Itens = append(plano.Itens, item)
+	}
+
+	if plano.Modo != ModoSubstituir {
+		return
+	}
+	for ticker, atual := range nacionais {
+		if _, ok := arquivo[ticker]; !ok {
+			plano.Itens = append(plano.Itens, itemRemocao(atual.classe, ticker, atual.quantidade, len(livro[ticker])))
+		}
+	}
+	// Ativos já vendidos por completo não estão na carteira, mas as suas operações também saem.
+	for ticker, ops := range livro {
+		if _, ok := arquivo[ticker]; !ok {
+			if _, naCarteira := nacionais[ticker]; !naCarteira {
+				plano.Itens = append(plano.Itens, itemRemocao(ops[0].TipoAtivo, ticker, 0, len(ops)))
+			}
+		}
+	}
+}
+
+// AplicarImportacao grava o plano em uma única transação.
+func AplicarImportacao(userID int64, plano *PlanoImportacao) error {
+	tx, err := database.GetDB().Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, item := range plano.Itens {
+		var err error
+		switch {
+		case item.Acao == acaoIgnorar || item.Acao == acaoManter:
+			continue
+		case item.Acao == acaoRemover && item.Classe == ClasseInternacional:
+			_, err = tx.Exec(database.Rebind("DELETE FROM investimentos_internacionais WHERE user_id = ? AND ticker = ?"), userID, item.Ticker)
+		case item.Acao == acaoRemover:
+			if _, err = tx.Exec(database.Rebind("DELETE FROM investimentos_nacionais WHERE user_id = ? AND ticker = ?"), userID, item.Ticker); err == nil {
+				_, err = tx.Exec(database.Rebind("DELETE FROM operacoes_investimentos WHERE user_id = ? AND ticker = ?"), userID, item.Ticker)
+			}
+		case item.Classe == ClasseInternacional:
+			_, err = tx.Exec(database.Rebind(`INSERT INTO investimentos_internacionais (user_id, ticker, descricao, quantidade, moeda) VALUES (?, ?, ?, ?, ?)
+				ON CONFLICT (user_id, ticker) DO UPDATE SET descricao = excluded.descricao, quantidade = excluded.quantidade, moeda = excluded.moeda`), userID, item.Ticker, item.descricao, item.QuantidadeNova, item.moeda)
+		case plano.Formato == FormatoB3Movimentacao:
+			err = gravarOperacoesImportadas(tx, userID, item, plano.Modo)
+		default:
+			_, err = tx.Exec(database.Rebind(`INSERT INTO investimentos_nacionais (user_id, ticker, tipo, quantidade) VALUES (?, ?, ?, ?)
+				ON CONFLICT (user_id, ticker) DO UPDATE SET tipo = excluded.tipo, quantidade = excluded.quantidade`), userID, item.Ticker, item.Classe, int(item.QuantidadeNova))
+		}
+		if err != nil {
+			return fmt.Errorf("%s: %w", item.Ticker, err)
+		}
+	}
+	return tx.Commit()
+}
+
+// gravarOperacoesImportadas registra as operações do ativo e recalcula a sua posição.
+func gravarOperacoesImportadas(tx *sql.Tx, userID int64, item ItemImportacao, modo string) error {
+	if modo == ModoSubstituir {
+		if _, err := tx.Exec(database.Rebind("DELETE FROM operacoes_investimentos WHERE user_id = ? AND ticker = ?"), userID, item.Ticker); err != nil {
+			return err
+		}
+	}
+	for _, op := range item.ops {
+		if _, err := inserirOperacao(tx, userID, op); err != nil {
+			return err
+		}
+	}
+	return sincronizarPosicao(tx, userID, item.Ticker)
+}
+
+// --- Handler ---
+
+// ImportarCarteira recebe um arquivo da carteira (campo "arquivo"): o CSV separado por ";" de
+// cmd/admin ou os extratos de posição e de movimentação (XLSX) da Área do Investidor da B3.
+// Sem confirmar=true responde só com a prévia; com ele, aplica o plano no modo escolhido
+// (mesclar, o padrão, ou substituir). O arquivo é reenviado na confirmação e o plano é
+// recalculado sobre a carteira daquele momento.
+func ImportarCarteira(c *gin.Context) {
+	userID := c.MustGet("userID").(int64)
+
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, limiteImportacao+64*1024)
+	fileHeader, err := c.FormFile("arquivo")
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": fmt.Sprintf("O arquivo excede o limite de %d MB.", limiteImportacao/(1024*1024))})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Envie o arquivo no campo 'arquivo'."})
+		return
+	}
+	modo := c.DefaultPostForm("modo", ModoMesclar)
+	if modo != ModoMesclar && modo != ModoSubstituir {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "O modo deve ser 'mesclar' ou 'substituir'."})
+		return
+	}
+	if fileHeader.Size > limiteImportacao {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": fmt.Sprintf("O arquivo excede o limite de %d MB.", limiteImportacao/(1024*1024))})
+		return
+	}
+	file, err := fileHeader.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Não foi possível ler o arquivo enviado."})
+		return
+	}
+	data, err := io.ReadAll(file)
+	file.Close()
+	if err != nil || len(data) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Não foi possível ler o arquivo enviado."})
+		return
+	}
+
+	arq, err := LerArquivoCarteira(fileHeader.Filename, data)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Arquivo não reconhecido: " + err.Error() + "."})
+		return
+	}
+	plano, err := PlanejarImportacao(userID, arq, modo)
+	if err != nil {
+		log.Printf("Erro ao planejar a importação da carteira: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao comparar o arquivo com a carteira."})
+		return
+	}
+	if c.PostForm("confirmar") != "true" {
+		c.JSON(http.StatusOK, plano)
+		return
+	}
+
+	if err := AplicarImportacao(userID, plano); err != nil {
+		log.Printf("Erro ao importar a carteira: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao gravar a importação; nada foi alterado."})
+		return
+	}
+	middleware.SetAuditChange(c, entityImportacao, fileHeader.Filename, nil, gin.H{
+		"formato": plano.Formato,
+		"modo":    plano.Modo,
+		"resumo":  plano.Resumo,
+	})
+	ClearNacionalCache()
+	c.JSON(http.StatusOK, gin.H{
+		"message": fmt.Sprintf("Carteira importada: %d ativo(s) incluído(s), %d atualizado(s) e %d removido(s).",
+			plano.Resumo[acaoIncluir], plano.Resumo[acaoAtualizar], plano.Resumo[acaoRemover]),
+		"plano": plano,
+	})
+}
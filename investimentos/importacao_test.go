@@ -0,0 +1,162 @@
+package investimentos
+
+import (
+	"minhas_economias/database"
+	"net/http"
+	"testing"
+)
+
+func TestImportarCarteiraCSV(t *testing.T) {
+	setupInvestimentosTestDB(t)
+	defer teardownInvestimentosTestDB()
+	router := createInvestimentosTestRouter()
+	db := database.GetDB()
+	db.Exec("INSERT INTO investimentos_nacionais (user_id, ticker, tipo, quantidade) VALUES (?, 'ITSA4', 'ACAO', 100)", testUserID)
+	// PETR4 (100 cadastradas) passa a ter o livro de operações: 150.
+	RegistrarOperacao(testUserID, Operacao{Ticker: "PETR4", Tipo: OperacaoCompra, Data: "2024-01-10", Quantidade: 50, Preco: 30}, 28)
+
+	csv := []byte("TIPO;TICKER;QUANTIDADE\nACAO;ITSA4;150\nFII;knri11;10\nACAO;PETR4;80\nACAO;VALE3;abc\n")
+	itens, plano := itensPorTicker(t, performImportacaoRequest(router, "carteira.csv", csv, ModoMesclar, false))
+	if plano.Formato != FormatoCSV || len(plano.Ignorados) != 1 || len(itens) != 3 {
+		t.Fatalf("Prévia inesperada: %+v", plano)
+	}
+	if itens["ITSA4"].Acao != acaoAtualizar || itens["ITSA4"].QuantidadeNova != 150 || itens["KNRI11"].Acao != acaoIncluir || itens["KNRI11"].Classe != ClasseFIIs {
+		t.Errorf("Itens inesperados: %+v", itens)
+	}
+	// A quantidade de PETR4 vem do livro de operações e não é sobrescrita.
+	if itens["PETR4"].Acao != acaoIgnorar || itens["PETR4"].Aviso == "" {
+		t.Errorf("PETR4 deveria ser ignorado: %+v", itens["PETR4"])
+	}
+	var qtd int
+	db.QueryRow("SELECT quantidade FROM investimentos_nacionais WHERE user_id = ? AND ticker = 'ITSA4'", testUserID).Scan(&qtd)
+	if qtd != 100 {
+		t.Errorf("A prévia não deveria gravar nada, ITSA4 tem %d", qtd)
+	}
+
+	// Substituir remove MXRF11, que não está no arquivo, e mantém PETR4.
+	itens, plano = itensPorTicker(t, performImportacaoRequest(router, "carteira.csv", csv, ModoSubstituir, true))
+	if itens["MXRF11"].Acao != acaoRemover || plano.Resumo[acaoIncluir] != 1 || plano.Resumo[acaoAtualizar] != 1 || plano.Resumo[acaoRemover] != 1 {
+		t.Errorf("Plano inesperado: %+v", plano)
+	}
+	posicoes := map[string]int{}
+	rows, _ := db.Query("SELECT ticker, quantidade FROM investimentos_nacionais WHERE user_id = ?", testUserID)
+	for rows.Next() {
+		var ticker string
+		rows.Scan(&ticker, &qtd)
+		posicoes[ticker] = qtd
+	}
+	rows.Close()
+	if len(posicoes) != 3 || posicoes["ITSA4"] != 150 || posicoes["KNRI11"] != 10 || posicoes["PETR4"] != 150 {
+		t.Errorf("Carteira inesperada após a importação: %v", posicoes)
+	}
+
+	// CSV de ativos do exterior (tipo;ticker;quantidade;moeda).
+	itens, _ = itensPorTicker(t, performImportacaoRequest(router, "exterior.csv", []byte("TYPE;STOCK;QUANTITY;MOEDA\nETF;VOO;1,5;US\nETF;SGOV;2;US\n"), ModoMesclar, true))
+	if itens["VOO"].Classe != ClasseInternacional || itens["VOO"].Acao != acaoAtualizar || itens["SGOV"].Acao != acaoIncluir {
+		t.Errorf("Itens do exterior inesperados: %+v", itens)
+	}
+	var voo float64
+	var descricao, moeda string
+	db.QueryRow("SELECT quantidade, descricao, moeda FROM investimentos_internacionais WHERE user_id = ? AND ticker = 'VOO'", testUserID).Scan(&voo, &descricao, &moeda)
+	if voo != 1.5 || descricao != "ETF" || moeda != "US" {
+		t.Errorf("Esperado 1,5 VOO (ETF, US), obtido %v (%s, %s)", voo, descricao, moeda)
+	}
+
+	if w := performImportacaoRequest(router, "carteira.csv", csv, "somar", false); w.Code != http.StatusBadRequest {
+		t.Errorf("Modo inválido deveria retornar 400, obteve %d", w.Code)
+	}
+	if w := performImportacaoRequest(router, "vazio.csv", []byte("TIPO;TICKER;QUANTIDADE\n"), ModoMesclar, false); w.Code != http.StatusBadRequest {
+		t.Errorf("Arquivo sem posições deveria retornar 400, obteve %d", w.Code)
+	}
+}
+
+func TestImportarCarteiraB3(t *testing.T) {
+	setupInvestimentosTestDB(t)
+	defer teardownInvestimentosTestDB()
+	router := createInvestimentosTestRouter()
+	db := database.GetDB()
+
+	cabecalho := []interface{}{"Entrada/Saída", "Data", "Movimentação", "Produto", "Instituição", "Quantidade", "Preço unitário", "Valor da Operação"}
+	movimentacao := planilhaTeste(t, map[string][][]interface{}{"Movimentação": {
+		cabecalho,
+		{"Credito", "12/01/2024", "Transferência - Liquidação", "PETR4 - PETROLEO BRASILEIRO S/A PETROBRAS", "XP INVESTIMENTOS", 100, 30, 3000},
+		{"Debito", "14/02/2024", "Transferência - Liquidação", "PETR4 - PETROLEO BRASILEIRO S/A PETROBRAS", "XP INVESTIMENTOS", 40, 35, 1400},
+		{"Credito", "15/01/2024", "Transferência - Liquidação", "MXRF11 - MAXI RENDA FUNDO DE INVESTIMENTO IMOBILIARIO - FII", "XP INVESTIMENTOS", 10, 10.5, 105},
+		{"Credito", "15/01/2024", "Transferência - Liquidação", "ITSA4 - ITAUSA S.A.", "XP INVESTIMENTOS", 20, 10, 200},
+		{"Debito", "15/01/2024", "Transferência - Liquidação", "VALE3 - VALE S.A.", "XP INVESTIMENTOS", 5, 60, 300},
+		{"Credito", "15/02/2024", "Rendimento", "MXRF11 - MAXI RENDA FUNDO DE INVESTIMENTO IMOBILIARIO - FII", "XP INVESTIMENTOS", 10, 0.1, 1},
+		{"Credito", "01/03/2024", "Transferência - Liquidação", "Tesouro Selic 2029", "XP INVESTIMENTOS", 1, 14000, 14000},
+		// BDRs e ETFs têm códigos de negociação como os das ações, mas não são importados.
+		{"Credito", "01/03/2024", "Transferência - Liquidação", "AAPL34 - APPLE INC", "XP INVESTIMENTOS", 3, 50, 150},
+		{"Credito", "01/03/2024", "Transferência - Liquidação", "BOVA11 - ISHARES BOVESPA FUNDO DE ÍNDICE", "XP INVESTIMENTOS", 2, 120, 240},
+	}})
+
+	itens, plano := itensPorTicker(t, performImportacaoRequest(router, "movimentacao-2024.xlsx", movimentacao, ModoMesclar, false))
+	if plano.Formato != FormatoB3Movimentacao || len(plano.Ignorados) != 4 {
+		t.Fatalf("Prévia inesperada: %+v", plano)
+	}
+	if _, ok := itens["AAPL34"]; ok {
+		t.Errorf("O BDR não deveria ser importado: %+v", itens["AAPL34"])
+	}
+	if _, ok := itens["BOVA11"]; ok {
+		t.Errorf("O ETF não deveria ser importado: %+v", itens["BOVA11"])
+	}
+	// PETR4 tinha 100 cadastradas sem operações; a posição passa a ser a da movimentação.
+	if p := itens["PETR4"]; p.Acao != acaoAtualizar || p.Operacoes != 2 || p.QuantidadeAtual != 100 || p.QuantidadeNova != 60 || p.Classe != ClasseAcoes || p.Aviso == "" {
+		t.Errorf("PETR4 inesperado: %+v", p)
+	}
+	if itens["ITSA4"].Acao != acaoIncluir || itens["MXRF11"].Classe != ClasseFIIs || itens["VALE3"].Acao != acaoIgnorar {
+		t.Errorf("Itens inesperados: %+v", itens)
+	}
+
+	performImportacaoRequest(router, "movimentacao-2024.xlsx", movimentacao, ModoMesclar, true)
+	ops, _ := listarOperacoes(db, testUserID, "PETR4")
+	if len(ops) != 2 || ops[1].Tipo != OperacaoVenda || ops[0].Corretora != "XP INVESTIMENTOS" || ops[0].Data != "2024-01-12" {
+		t.Errorf("Operações de PETR4 inesperadas: %+v", ops)
+	}
+	var tipo string
+	var qtd int
+	db.QueryRow("SELECT tipo, quantidade FROM investimentos_nacionais WHERE user_id = ? AND ticker = 'MXRF11'", testUserID).Scan(&tipo, &qtd)
+	if tipo != ClasseFIIs || qtd != 10 {
+		t.Errorf("MXRF11 inesperado: %s %d", tipo, qtd)
+	}
+
+	// Importar o mesmo extrato de novo não duplica as operações.
+	_, plano = itensPorTicker(t, performImportacaoRequest(router, "movimentacao-2024.xlsx", movimentacao, ModoMesclar, false))
+	if plano.Resumo[acaoManter] != 3 || plano.Resumo[acaoIncluir]+plano.Resumo[acaoAtualizar] != 0 {
+		t.Errorf("Reimportação deveria manter tudo: %+v", plano.Resumo)
+	}
+
+	// Na posição, a B3 separa o ativo por corretora; as linhas são somadas.
+	cabecalhoPosicao := []interface{}{"Produto", "Instituição", "Conta", "Código de Negociação", "Tipo", "Quantidade", "Preço de Fechamento"}
+	posicao := planilhaTeste(t, map[string][][]interface{}{
+		"Acoes": {
+			cabecalhoPosicao,
+			{"PETR4 - PETROBRAS", "XP INVESTIMENTOS", "1", "PETR4", "PN", 60, 38},
+			{"BBAS3 - BANCO DO BRASIL", "XP INVESTIMENTOS", "1", "BBAS3", "ON", 20, 27},
+			{"BBAS3 - BANCO DO BRASIL", "NU INVEST", "2", "BBAS3F", "ON", 5, 27},
+			{"", "", "", "", "", "Total", ""},
+		},
+		"Fundo de Investimento": {cabecalhoPosicao, {"MXRF11 - MAXI RENDA", "XP INVESTIMENTOS", "1", "MXRF11", "Cotas", 12, 10}},
+		"BDR":                   {cabecalhoPosicao, {"AAPL34 - APPLE", "XP INVESTIMENTOS", "1", "AAPL34", "DRN", 3, 50}},
+	})
+	itens, plano = itensPorTicker(t, performImportacaoRequest(router, "posicao-2024-03-01.xlsx", posicao, ModoMesclar, false))
+	if plano.Formato != FormatoB3Posicao || len(plano.Ignorados) != 1 {
+		t.Fatalf("Prévia inesperada: %+v", plano)
+	}
+	if itens["PETR4"].Acao != acaoManter || itens["BBAS3"].QuantidadeNova != 25 || itens["BBAS3"].Acao != acaoIncluir {
+		t.Errorf("Itens inesperados: %+v", itens)
+	}
+	if itens["MXRF11"].Acao != acaoIgnorar {
+		t.Errorf("MXRF11 tem operações e quantidade diferente; deveria ser ignorado: %+v", itens["MXRF11"])
+	}
+
+	// Planilhas com linhas demais são recusadas antes de qualquer processamento.
+	grande := [][]interface{}{cabecalhoPosicao}
+	for len(grande) <= limiteLinhasPlanilha {
+		grande = append(grande, []interface{}{"PETR4 - PETROBRAS", "XP INVESTIMENTOS", "1", "PETR4", "PN", 1, 38})
+	}
+	if w := performImportacaoRequest(router, "posicao.xlsx", planilhaTeste(t, map[string][][]interface{}{"Acoes": grande}), ModoMesclar, false); w.Code != http.StatusBadRequest {
+		t.Errorf("Planilha com mais de %d linhas: esperado 400, obtido %d", limiteLinhasPlanilha, w.Code)
+	}
+}
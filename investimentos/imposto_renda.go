@@ -0,0 +1,241 @@
+package investimentos
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"minhas_economias/database"
+	"minhas_economias/middleware"
+	"minhas_economias/models"
+	"minhas_economias/pdfgenerator"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Parâmetros da apuração mensal de ganhos de capital em renda variável.
+const (
+	limiteIsencaoAcoes = 20000.0 // Vendas mensais de ações comuns até este valor têm lucro isento
+	aliquotaIRRFComum  = 0.00005 // 0,005% sobre o valor das vendas comuns
+	aliquotaIRRFDay    = 0.01    // 1% sobre o resultado positivo do day trade
+	valorMinimoDARF    = 10.0    // DARF abaixo deste valor é somado ao dos meses seguintes
+	codigoReceitaDARF  = "6015"
+)
+
+// aliquotas por categoria de apuração.
+var aliquotas = map[string]float64{
+	models.CategoriaComum:    0.15,
+	models.CategoriaDayTrade: 0.20,
+	models.CategoriaFII:      0.20,
+}
+
+var categorias = []string{models.CategoriaComum, models.CategoriaDayTrade, models.CategoriaFII}
+
+// VendasRealizadas apura o resultado de todas as vendas do usuário, em ordem cronológica.
+func VendasRealizadas(userID int64) ([]models.VendaRealizada, error) {
+	ops, err := listarOperacoes(database.GetDB(), userID, "")
+	if err != nil {
+		return nil, err
+	}
+	porTicker := make(map[string][]Operacao)
+	for _, op := range ops {
+		porTicker[op.Ticker] = append(porTicker[op.Ticker], op)
+	}
+	var vendas []models.VendaRealizada
+	for _, opsTicker := range porTicker {
+		_, v, err := ApurarAtivo(opsTicker)
+		if err != nil {
+			return nil, err
+		}
+		vendas = append(vendas, v...)
+	}
+	sort.SliceStable(vendas, func(i, j int) bool {
+		if vendas[i].Data != vendas[j].Data {
+			return vendas[i].Data < vendas[j].Data
+		}
+		return vendas[i].Ticker < vendas[j].Ticker
+	})
+	return vendas, nil
+}
+
+// ApurarImpostoRenda calcula a apuração mensal e os DARFs do ano. Os meses anteriores ao ano
+// pedido também são apurados, pois os prejuízos, as retenções e o imposto abaixo do mínimo do
+// DARF passam de um mês (e de um ano) para o outro. Os ativos com vendas no ano sem o custo de
+// aquisição conhecido são listados em CustoDesconhecido, pois o imposto deles está superestimado.
+func ApurarImpostoRenda(vendas []models.VendaRealizada, ano int) models.ApuracaoAnual {
+	porMes := make(map[string][]models.VendaRealizada)
+	var meses []string
+	for _, v := range vendas {
+		mes := v.Data[:7]
+		if _, ok := porMes[mes]; !ok {
+			meses = append(meses, mes)
+		}
+		porMes[mes] = append(porMes[mes], v)
+	}
+	sort.Strings(meses)
+
+	apuracao := models.ApuracaoAnual{Ano: ano, Meses: []models.ApuracaoMensal{}, PrejuizosACompensar: make(map[string]float64), CustoDesconhecido: []string{}}
+	prejuizos := make(map[string]float64)
+	var irrfACompensar, saldo float64
+	ultimoMes := fmt.Sprintf("%04d-12", ano)
+	for _, mes := range meses {
+		if mes > ultimoMes {
+			break
+		}
+		m := apurarMes(mes, porMes[mes], prejuizos, &irrfACompensar, &saldo)
+		if mes[:4] == strconv.Itoa(ano) {
+			apuracao.Meses = append(apuracao.Meses, m)
+			apuracao.TotalDARF += m.ValorDARF
+			for _, cat := range m.Categorias {
+				apuracao.LucroIsento += cat.LucroIsento
+			}
+			for _, v := range m.Vendas {
+				if v.CustoDesconhecido && !contem(apuracao.CustoDesconhecido, v.Ticker) {
+					apuracao.CustoDesconhecido = append(apuracao.CustoDesconhecido, v.Ticker)
+				}
+			}
+		}
+	}
+	for _, cat := range categorias {
+		apuracao.PrejuizosACompensar[cat] = arredondar(prejuizos[cat])
+	}
+	apuracao.TotalDARF = arredondar(apuracao.TotalDARF)
+	apuracao.LucroIsento = arredondar(apuracao.LucroIsento)
+	apuracao.SaldoAPagar = arredondar(saldo)
+	return apuracao
+}
+
+// apurarMes apura um mês, atualizando os prejuízos a compensar por categoria, o IRRF ainda não
+// compensado e o saldo de imposto abaixo do mínimo do DARF.
+func apurarMes(mes string, vendas []models.VendaRealizada, prejuizos map[string]float64, irrfACompensar, saldo *float64) models.ApuracaoMensal {
+	m := models.ApuracaoMensal{Mes: mes, Vendas: vendas, CodigoReceita: codigoReceitaDARF}
+	totais := make(map[string]*models.ApuracaoCategoria)
+	for _, cat := range categorias {
+		totais[cat] = &models.ApuracaoCategoria{Categoria: cat, Aliquota: aliquotas[cat]}
+	}
+	for _, v := range vendas {
+		t := totais[v.Categoria()]
+		t.Vendas += v.ValorVenda
+		t.Resultado += v.Resultado
+		if v.DayTrade {
+			m.IRRF += math.Max(v.Resultado, 0) * aliquotaIRRFDay
+		} else {
+			m.IRRF += v.ValorVenda * aliquotaIRRFComum
+		}
+	}
+	totais[models.CategoriaComum].Isento = totais[models.CategoriaComum].Vendas <= limiteIsencaoAcoes
+
+	for _, cat := range categorias {
+		t := totais[cat]
+		if t.Vendas == 0 && t.Resultado == 0 {
+			continue
+		}
+		t.PrejuizoAnterior = prejuizos[cat]
+		switch {
+		case t.Resultado < 0:
+			// Prejuízos são acumulados mesmo em meses isentos.
+			prejuizos[cat] -= t.Resultado
+		case t.Isento:
+			t.LucroIsento = t.Resultado
+		default:
+			t.PrejuizoCompensado = math.Min(prejuizos[cat], t.Resultado)
+			prejuizos[cat] -= t.PrejuizoCompensado
+			t.BaseCalculo = t.Resultado - t.PrejuizoCompensado
+			t.Imposto = arredondar(t.BaseCalculo * t.Aliquota)
+		}
+		t.PrejuizoAcumulado = prejuizos[cat]
+		m.ImpostoDevido += t.Imposto
+		m.Categorias = append(m.Categorias, *t)
+	}
+
+	m.IRRF = arredondar(m.IRRF)
+	*irrfACompensar += m.IRRF
+	m.IRRFCompensado = math.Min(*irrfACompensar, m.ImpostoDevido)
+	*irrfACompensar -= m.IRRFCompensado
+	m.SaldoAnterior = *saldo
+
+	total := arredondar(m.ImpostoDevido - m.IRRFCompensado + *saldo)
+	if total < valorMinimoDARF {
+		*saldo = total
+		return m
+	}
+	*saldo = 0
+	m.ValorDARF = total
+	m.Vencimento = vencimentoDARF(mes)
+	return m
+}
+
+// vencimentoDARF retorna o último dia útil (segunda a sexta) do mês seguinte ao da apuração.
+func vencimentoDARF(mes string) string {
+	inicio, err := time.Parse("2006-01", mes)
+	if err != nil {
+		return ""
+	}
+	dia := inicio.AddDate(0, 2, -1)
+	for dia.Weekday() == time.Saturday || dia.Weekday() == time.Sunday {
+		dia = dia.AddDate(0, 0, -1)
+	}
+	return dia.Format("2006-01-02")
+}
+
+func contem(lista []string, valor string) bool {
+	for _, item := range lista {
+		if item == valor {
+			return true
+		}
+	}
+	return false
+}
+
+func arredondar(v float64) float64 {
+	return math.Round(v*100) / 100
+}
+
+// apuracaoDoPedido lê o ano (?ano=, padrão o atual) e apura o imposto do usuário logado.
+func apuracaoDoPedido(c *gin.Context) (models.ApuracaoAnual, bool) {
+	userID := c.MustGet("userID").(int64)
+	ano, err := strconv.Atoi(c.DefaultQuery("ano", strconv.Itoa(time.Now().Year())))
+	if err != nil || ano < 1900 || ano > 9999 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Ano inválido."})
+		return models.ApuracaoAnual{}, false
+	}
+	vendas, err := VendasRealizadas(userID)
+	if err != nil {
+		log.Printf("Erro ao apurar as vendas do usuário %d: %v", userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao apurar as vendas."})
+		return models.ApuracaoAnual{}, false
+	}
+	return ApurarImpostoRenda(vendas, ano), true
+}
+
+// GetImpostoRendaAPI retorna a apuração mensal e os DARFs do ano (?ano=AAAA).
+func GetImpostoRendaAPI(c *gin.Context) {
+	apuracao, ok := apuracaoDoPedido(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, apuracao)
+}
+
+// DownloadImpostoRendaPDF gera o relatório de apuração e DARFs do ano em PDF.
+func DownloadImpostoRendaPDF(c *gin.Context) {
+	apuracao, ok := apuracaoDoPedido(c)
+	if !ok {
+		return
+	}
+	pdf, err := pdfgenerator.GenerateImpostoRendaPDF(apuracao)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao gerar PDF: " + err.Error()})
+		return
+	}
+	middleware.ReportsGenerated.WithLabelValues("pdf").Inc()
+
+	c.Header("Content-Type", "application/pdf")
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="apuracao_ir_%d.pdf"`, apuracao.Ano))
+	if err := pdf.Output(c.Writer); err != nil {
+		log.Printf("Erro ao enviar PDF para o cliente: %v", err)
+	}
+}
@@ -0,0 +1,45 @@
+package investimentos
+
+import (
+	"minhas_economias/models"
+	"testing"
+)
+
+func TestApurarImpostoRenda(t *testing.T) {
+	vendas := []models.VendaRealizada{
+		// Prejuízo do ano anterior, compensado em 2025.
+		{Ticker: "PETR4", TipoAtivo: "ACAO", Data: "2024-12-10", ValorVenda: 50000, Resultado: -500},
+		// Janeiro: vendas de ações até R$ 20 mil (lucro isento) e prejuízo em FII.
+		{Ticker: "PETR4", TipoAtivo: "ACAO", Data: "2025-01-15", ValorVenda: 15000, Resultado: 3000},
+		{Ticker: "MXRF11", TipoAtivo: "FII", Data: "2025-01-20", ValorVenda: 5000, Resultado: -1000},
+		// Fevereiro: prejuízo comum e imposto de day trade abaixo do mínimo do DARF.
+		{Ticker: "VALE3", TipoAtivo: "ACAO", Data: "2025-02-03", ValorVenda: 30000, Resultado: -2000},
+		{Ticker: "VALE3", TipoAtivo: "ACAO", Data: "2025-02-04", DayTrade: true, ValorVenda: 1000, Resultado: 30},
+		// Março: lucros que compensam os prejuízos de cada categoria.
+		{Ticker: "ITSA4", TipoAtivo: "ACAO", Data: "2025-03-12", ValorVenda: 25000, Resultado: 5000},
+		{Ticker: "MXRF11", TipoAtivo: "FII", Data: "2025-03-20", ValorVenda: 2000, Resultado: 1500},
+	}
+	ap := ApurarImpostoRenda(vendas, 2025)
+	if len(ap.Meses) != 3 {
+		t.Fatalf("Esperados 3 meses em 2025, obtidos %d", len(ap.Meses))
+	}
+	jan, fev, mar := ap.Meses[0], ap.Meses[1], ap.Meses[2]
+	if !jan.Categorias[0].Isento || jan.Categorias[0].LucroIsento != 3000 || jan.ImpostoDevido != 0 || jan.Categorias[1].PrejuizoAcumulado != 1000 {
+		t.Errorf("Janeiro inesperado: %+v", jan)
+	}
+	// Day trade: 20% de 30 = 6,00, menos o IRRF acumulado (2,50 em dezembro, 1,00 em janeiro e 1,80
+	// em fevereiro) = 0,70, abaixo do mínimo e somado ao mês seguinte.
+	if fev.ImpostoDevido != 6 || fev.IRRFCompensado != 5.3 || fev.ValorDARF != 0 || fev.Categorias[0].PrejuizoAcumulado != 2500 {
+		t.Errorf("Fevereiro inesperado: %+v", fev)
+	}
+	// Comum: 15% de (5000 - 2500); FII: 20% de (1500 - 1000); menos IRRF de 1,35, mais o saldo de 0,70.
+	if mar.ImpostoDevido != 475 || mar.SaldoAnterior != 0.7 || mar.ValorDARF != 474.35 || mar.Vencimento != "2025-04-30" || mar.CodigoReceita != "6015" {
+		t.Errorf("Março inesperado: %+v", mar)
+	}
+	if ap.TotalDARF != 474.35 || ap.LucroIsento != 3000 || ap.PrejuizosACompensar[models.CategoriaComum] != 0 || ap.SaldoAPagar != 0 {
+		t.Errorf("Resumo anual inesperado: %+v", ap)
+	}
+	if v := vencimentoDARF("2025-07"); v != "2025-08-29" {
+		t.Errorf("Vencimento de julho/2025 esperado em 2025-08-29 (sexta), obtido %s", v)
+	}
+}
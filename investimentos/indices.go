@@ -0,0 +1,192 @@
+package investimentos
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"minhas_economias/database"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Séries de índices guardadas em indices_economicos, no formato das séries do Banco Central
+// (SGS): CDI (série 12) e SELIC (série 11) em % ao dia útil; IPCA (série 433) em % ao mês,
+// com a data no primeiro dia do mês de referência. Os benchmarks de desempenho guardam o
+// fechamento diário: Ibovespa e IFIX em pontos, S&P 500 em pontos (dólares) e o dólar PTAX de
+// venda (SGS série 1), usado para levar o S&P 500 a reais.
+const (
+	SerieCDI   = "CDI"
+	SerieSELIC = "SELIC"
+	SerieIPCA  = "IPCA"
+	SerieIBOV  = "IBOV"
+	SerieIFIX  = "IFIX"
+	SerieSP500 = "SP500"
+	SerieDolar = "DOLAR"
+)
+
+var seriesIndices = []string{SerieCDI, SerieSELIC, SerieIPCA, SerieIBOV, SerieIFIX, SerieSP500, SerieDolar}
+
+// Indice é o valor de uma série em uma data.
+type Indice struct {
+	Data  string  `json:"data"` // AAAA-MM-DD
+	Valor float64 `json:"valor"`
+}
+
+// SerieIndice é uma série ordenada por data. Datas sem valor (feriados, dias futuros ou ainda
+// não importados) usam o último valor conhecido.
+type SerieIndice struct {
+	Nome    string
+	Indices []Indice
+}
+
+// valorEm retorna o valor da série na data ou, se não houver, o último anterior a ela; sem
+// nenhum anterior, usa o primeiro da série. ok é falso apenas para séries vazias.
+func (s *SerieIndice) valorEm(data string) (float64, bool) {
+	if s == nil || len(s.Indices) == 0 {
+		return 0, false
+	}
+	i := sort.Search(len(s.Indices), func(i int) bool { return s.Indices[i].Data > data })
+	if i == 0 {
+		return s.Indices[0].Valor, true
+	}
+	return s.Indices[i-1].Valor, true
+}
+
+// cobre indica se a série tem valor importado na data ou antes dela.
+func (s *SerieIndice) cobre(data string) bool {
+	return s != nil && len(s.Indices) > 0 && s.Indices[0].Data <= data
+}
+
+// ultimaData retorna a data do último valor importado.
+func (s *SerieIndice) ultimaData() string {
+	if s == nil || len(s.Indices) == 0 {
+		return ""
+	}
+	return s.Indices[len(s.Indices)-1].Data
+}
+
+// validarSerie normaliza o nome da série.
+func validarSerie(serie string) (string, error) {
+	serie = strings.ToUpper(strings.TrimSpace(serie))
+	for _, s := range seriesIndices {
+		if s == serie {
+			return serie, nil
+		}
+	}
+	return "", fmt.Errorf("série '%s' desconhecida; use %s", serie, strings.Join(seriesIndices, ", "))
+}
+
+// LerIndicesCSV lê o CSV exportado pelo SGS do Banco Central (linhas "data";"valor", com a data
+// em DD/MM/AAAA e vírgula decimal). Também aceita datas AAAA-MM-DD e ponto decimal; o
+// cabeçalho e linhas em branco são ignorados.
+func LerIndicesCSV(r io.Reader) ([]Indice, error) {
+	reader := csv.NewReader(r)
+	reader.Comma = ';'
+	reader.LazyQuotes = true
+	reader.FieldsPerRecord = -1
+	var indices []Indice
+	for linha := 1; ; linha++ {
+		campos, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("linha %d: %w", linha, err)
+		}
+		if len(campos) < 2 || strings.TrimSpace(campos[0]) == "" {
+			continue
+		}
+		dataStr := strings.TrimSpace(campos[0])
+		data, err := time.Parse("02/01/2006", dataStr)
+		if err != nil {
+			if data, err = time.Parse("2006-01-02", dataStr); err != nil {
+				if linha == 1 {
+					continue // cabeçalho
+				}
+				return nil, fmt.Errorf("linha %d: data inválida '%s'", linha, dataStr)
+			}
+		}
+		valorStr := strings.TrimSpace(campos[1])
+		if valorStr == "" {
+			continue
+		}
+		if strings.Contains(valorStr, ",") {
+			valorStr = strings.ReplaceAll(strings.ReplaceAll(valorStr, ".", ""), ",", ".")
+		}
+		valor, err := strconv.ParseFloat(valorStr, 64)
+		if err != nil {
+			return nil, fmt.Errorf("linha %d: valor inválido '%s'", linha, campos[1])
+		}
+		indices = append(indices, Indice{Data: data.Format("2006-01-02"), Valor: valor})
+	}
+	if len(indices) == 0 {
+		return nil, errors.New("nenhum valor encontrado no arquivo")
+	}
+	return indices, nil
+}
+
+// RegistrarIndices grava os valores da série; valores já existentes na mesma data são substituídos.
+func RegistrarIndices(serie string, indices []Indice) error {
+	serie, err := validarSerie(serie)
+	if err != nil {
+		return err
+	}
+	tx, err := database.GetDB().Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	stmt, err := tx.Prepare(database.Rebind(`INSERT INTO indices_economicos (serie, data, valor) VALUES (?, ?, ?)
+		ON CONFLICT (serie, data) DO UPDATE SET valor = excluded.valor`))
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+	for _, i := range indices {
+		if _, err := stmt.Exec(serie, i.Data, i.Valor); err != nil {
+			return fmt.Errorf("erro ao gravar %s em %s: %w", serie, i.Data, err)
+		}
+	}
+	return tx.Commit()
+}
+
+// ImportarIndicesCSV lê o CSV do SGS (veja LerIndicesCSV) e grava os valores na série.
+func ImportarIndicesCSV(serie string, r io.Reader) (int, error) {
+	indices, err := LerIndicesCSV(r)
+	if err != nil {
+		return 0, err
+	}
+	if err := RegistrarIndices(serie, indices); err != nil {
+		return 0, err
+	}
+	return len(indices), nil
+}
+
+// CarregarSeries lê todas as séries de índices guardadas.
+func CarregarSeries() (map[string]*SerieIndice, error) {
+	rows, err := database.GetDB().Query("SELECT serie, data, valor FROM indices_economicos ORDER BY serie, data")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	series := make(map[string]*SerieIndice, len(seriesIndices))
+	for _, nome := range seriesIndices {
+		series[nome] = &SerieIndice{Nome: nome}
+	}
+	for rows.Next() {
+		var nome string
+		var rawData interface{}
+		var i Indice
+		if err := rows.Scan(&nome, &rawData, &i.Valor); err != nil {
+			return nil, err
+		}
+		i.Data = dateString(rawData)
+		if s, ok := series[nome]; ok {
+			s.Indices = append(s.Indices, i)
+		}
+	}
+	return series, rows.Err()
+}
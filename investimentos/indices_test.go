@@ -0,0 +1,20 @@
+package investimentos
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestLerIndicesCSV(t *testing.T) {
+	csv := "\"data\";\"valor\"\n\"02/01/2025\";\"0,045513\"\n\"03/01/2025\";\"0,045513\"\n2025-01-06;0.0455\n"
+	indices, err := LerIndicesCSV(bytes.NewBufferString(csv))
+	if err != nil {
+		t.Fatalf("Erro inesperado: %v", err)
+	}
+	if len(indices) != 3 || indices[0].Data != "2025-01-02" || indices[0].Valor != 0.045513 || indices[2].Valor != 0.0455 {
+		t.Errorf("Índices inesperados: %+v", indices)
+	}
+	if _, err := LerIndicesCSV(bytes.NewBufferString("\"data\";\"valor\"\n31/02/2025;0,1\n")); err == nil {
+		t.Error("Esperado erro para data inválida")
+	}
+}
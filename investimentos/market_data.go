@@ -157,7 +157,6 @@ func BuscarLPAeVPA(ticker string) (float64, float64, error) {
 	if err != nil {
 		return 0, 0, err
 	}
-	time.Sleep(1 * time.Second)
 	c.Wait()
 	if scrapingErr != nil {
 		return 0, 0, scrapingErr
@@ -191,6 +190,180 @@ func BuscarCotacaoDolarBRL() (float64, error) {
 	return taxa, nil
 }
 
+// =================================================================================
+// >> PROVEDORES WEB (MarketDataProvider) <<
+// =================================================================================
+
+const (
+	urlFundamentusAcoes = "https://www.fundamentus.com.br/resultado.php"
+	urlFundamentusFIIs  = "https://www.fundamentus.com.br/fii_resultado.php"
+)
+
+// FundamentusProvider raspa as tabelas de ações e FIIs do Fundamentus.
+type FundamentusProvider struct{}
+
+func (FundamentusProvider) Nome() string { return "fundamentus" }
+
+func (FundamentusProvider) CotacoesAcoes() (map[string]DadosAcao, error) {
+	linhas, err := RasparDadosFundamentus(urlFundamentusAcoes, "acoes")
+	if err != nil {
+		return nil, err
+	}
+	return acoesDeLinhas(linhas, Origem{Fonte: "fundamentus"})
+}
+
+func (FundamentusProvider) CotacoesFIIs() (map[string]DadosFII, error) {
+	linhas, err := RasparDadosFundamentus(urlFundamentusFIIs, "fii")
+	if err != nil {
+		return nil, err
+	}
+	return fiisDeLinhas(linhas, Origem{Fonte: "fundamentus"})
+}
+
+func (FundamentusProvider) Fundamentos(string) (float64, float64, error) {
+	return 0, 0, ErrNaoSuportado
+}
+
+func (FundamentusProvider) PrecosInternacionais([]string) (map[string]Cotacao, error) {
+	return nil, ErrNaoSuportado
+}
+
+func (FundamentusProvider) CotacaoDolar() (Cotacao, error) { return Cotacao{}, ErrNaoSuportado }
+
+// StatusInvestProvider busca LPA e VPA na página de cada ação do StatusInvest.
+type StatusInvestProvider struct{}
+
+func (StatusInvestProvider) Nome() string { return "statusinvest" }
+
+func (StatusInvestProvider) CotacoesAcoes() (map[string]DadosAcao, error) {
+	return nil, ErrNaoSuportado
+}
+
+func (StatusInvestProvider) CotacoesFIIs() (map[string]DadosFII, error) {
+	return nil, ErrNaoSuportado
+}
+
+func (StatusInvestProvider) Fundamentos(ticker string) (float64, float64, error) {
+	lpa, vpa, err := BuscarLPAeVPA(ticker)
+	if err == nil && lpa == 0 && vpa == 0 {
+		err = fmt.Errorf("LPA e VPA de %s não encontrados na página", ticker)
+	}
+	return lpa, vpa, err
+}
+
+func (StatusInvestProvider) PrecosInternacionais([]string) (map[string]Cotacao, error) {
+	return nil, ErrNaoSuportado
+}
+
+func (StatusInvestProvider) CotacaoDolar() (Cotacao, error) { return Cotacao{}, ErrNaoSuportado }
+
+// YahooProvider raspa as cotações internacionais do Yahoo Finance.
+type YahooProvider struct{}
+
+func (YahooProvider) Nome() string { return "yahoo" }
+
+func (YahooProvider) CotacoesAcoes() (map[string]DadosAcao, error) { return nil, ErrNaoSuportado }
+
+func (YahooProvider) CotacoesFIIs() (map[string]DadosFII, error) { return nil, ErrNaoSuportado }
+
+func (YahooProvider) Fundamentos(string) (float64, float64, error) {
+	return 0, 0, ErrNaoSuportado
+}
+
+func (YahooProvider) PrecosInternacionais(tickers []string) (map[string]Cotacao, error) {
+	carteira := make(map[string]string, len(tickers))
+	for _, t := range tickers {
+		carteira[t] = "USD"
+	}
+	precos, err := BuscarMuitosPrecosInternacionais(carteira)
+	if err != nil {
+		return nil, err
+	}
+	cotacoes := make(map[string]Cotacao, len(precos))
+	for ticker, preco := range precos {
+		cotacoes[ticker] = Cotacao{Preco: preco, Origem: Origem{Fonte: "yahoo"}}
+	}
+	return cotacoes, nil
+}
+
+func (YahooProvider) CotacaoDolar() (Cotacao, error) { return Cotacao{}, ErrNaoSuportado }
+
+// FrankfurterProvider consulta a cotação do dólar na API pública frankfurter.app.
+type FrankfurterProvider struct{}
+
+func (FrankfurterProvider) Nome() string { return "frankfurter" }
+
+func (FrankfurterProvider) CotacoesAcoes() (map[string]DadosAcao, error) {
+	return nil, ErrNaoSuportado
+}
+
+func (FrankfurterProvider) CotacoesFIIs() (map[string]DadosFII, error) {
+	return nil, ErrNaoSuportado
+}
+
+func (FrankfurterProvider) Fundamentos(string) (float64, float64, error) {
+	return 0, 0, ErrNaoSuportado
+}
+
+func (FrankfurterProvider) PrecosInternacionais([]string) (map[string]Cotacao, error) {
+	return nil, ErrNaoSuportado
+}
+
+func (FrankfurterProvider) CotacaoDolar() (Cotacao, error) {
+	taxa, err := BuscarCotacaoDolarBRL()
+	if err != nil {
+		return Cotacao{}, err
+	}
+	return Cotacao{Preco: taxa, Origem: Origem{Fonte: "frankfurter"}}, nil
+}
+
+// acoesDeLinhas converte as linhas da tabela de ações do Fundamentus
+// (Papel, Cotação, P/L, P/VP, PSR, Div.Yield, ...).
+func acoesDeLinhas(linhas map[string][]string, origem Origem) (map[string]DadosAcao, error) {
+	if len(linhas) == 0 {
+		return nil, fmt.Errorf("tabela de ações vazia")
+	}
+	dados := make(map[string]DadosAcao, len(linhas))
+	for ticker, cols := range linhas {
+		if len(cols) < 6 {
+			continue
+		}
+		dados[ticker] = DadosAcao{
+			Origem:   origem,
+			Cotacao:  ParsePtBrFloat(cols[1]),
+			PL:       ParsePtBrFloat(cols[2]),
+			PVP:      ParsePtBrFloat(cols[3]),
+			DivYield: ParsePtBrFloat(cols[5]) / 100.0,
+		}
+	}
+	return dados, nil
+}
+
+// fiisDeLinhas converte as linhas da tabela de FIIs do Fundamentus (Papel, Segmento, Cotação,
+// FFO Yield, Dividend Yield, P/VP, Valor de Mercado, Liquidez, Qtd de imóveis, ..., Vacância Média).
+func fiisDeLinhas(linhas map[string][]string, origem Origem) (map[string]DadosFII, error) {
+	if len(linhas) == 0 {
+		return nil, fmt.Errorf("tabela de FIIs vazia")
+	}
+	dados := make(map[string]DadosFII, len(linhas))
+	for ticker, cols := range linhas {
+		if len(cols) < 13 {
+			continue
+		}
+		numImoveis, _ := strconv.Atoi(cols[8])
+		dados[ticker] = DadosFII{
+			Origem:     origem,
+			Segmento:   cols[1],
+			Cotacao:    ParsePtBrFloat(cols[2]),
+			DivYield:   ParsePtBrFloat(cols[4]) / 100.0,
+			PVP:        ParsePtBrFloat(cols[5]),
+			Vacancia:   ParsePtBrFloat(cols[12]),
+			NumImoveis: numImoveis,
+		}
+	}
+	return dados, nil
+}
+
 // --- Funções Auxiliares ---
 // ParsePtBrFloat agora é exportada (letra maiúscula)
 func ParsePtBrFloat(s string) float64 {
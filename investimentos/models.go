@@ -12,21 +12,23 @@ type AcaoNacional struct {
 	ValorGraham          float64 `json:"valor_graham"`
 	DivYieldPercent      float64 `json:"div_yield_percent"`
 	IsGrahamAdvantageous bool    `json:"is_graham_advantageous"`
+	Resultado
 }
 
 // FundoImobiliario representa um FII com dados do BD e dados dinâmicos.
 type FundoImobiliario struct {
-	Ticker       string  `json:"ticker"`
-	Tipo         string  `json:"tipo"`
-	Quantidade   int     `json:"quantidade"`
-	Cotacao      float64 `json:"cotacao"`
-	ValorTotal   float64 `json:"valor_total"`
-	Segmento     string  `json:"segmento"`
-	PVP          float64 `json:"pvp"`
-	DivYield     float64 `json:"div_yield"`
-	Vacancia     float64 `json:"vacancia"`
-	NumImoveis   int     `json:"num_imoveis"`
+	Ticker          string  `json:"ticker"`
+	Tipo            string  `json:"tipo"`
+	Quantidade      int     `json:"quantidade"`
+	Cotacao         float64 `json:"cotacao"`
+	ValorTotal      float64 `json:"valor_total"`
+	Segmento        string  `json:"segmento"`
+	PVP             float64 `json:"pvp"`
+	DivYield        float64 `json:"div_yield"`
+	Vacancia        float64 `json:"vacancia"`
+	NumImoveis      int     `json:"num_imoveis"`
 	DivYieldPercent float64 `json:"div_yield_percent"`
+	Resultado
 }
 
 // AtivoInternacional representa um ativo no exterior.
@@ -39,3 +41,65 @@ type AtivoInternacional struct {
 	ValorTotalUSD    float64 `json:"valor_total_usd"`
 	ValorTotalBRL    float64 `json:"valor_total_brl"`
 }
+
+// AtivoCripto representa um criptoativo. A quantidade é um decimal exato em texto (até 18
+// casas); os valores em dinheiro são calculados em float64 como nas demais classes.
+type AtivoCripto struct {
+	Simbolo       string  `json:"simbolo"`
+	Descricao     string  `json:"descricao"`
+	Quantidade    string  `json:"quantidade"`
+	PrecoUSD      float64 `json:"preco_usd"`
+	PrecoBRL      float64 `json:"preco_brl"`
+	ValorTotalUSD float64 `json:"valor_total_usd"`
+	ValorTotalBRL float64 `json:"valor_total_brl"`
+	DataPreco     string  `json:"data_preco,omitempty"`
+	Fonte         string  `json:"fonte,omitempty"`
+}
+
+// TotaisCarteira é o valor de mercado em reais de cada classe de ativos cotados.
+type TotaisCarteira struct {
+	Acoes          float64 `json:"acoes"`
+	FIIs           float64 `json:"fiis"`
+	Internacionais float64 `json:"internacionais"`
+	Cripto         float64 `json:"cripto"`
+	Total          float64 `json:"total"`
+}
+
+// calcularTotais soma o valor de mercado das posições de cada classe.
+func calcularTotais(acoes []AcaoNacional, fiis []FundoImobiliario, internacionais []AtivoInternacional, cripto []AtivoCripto) TotaisCarteira {
+	var t TotaisCarteira
+	for _, a := range acoes {
+		t.Acoes += a.ValorTotal
+	}
+	for _, f := range fiis {
+		t.FIIs += f.ValorTotal
+	}
+	for _, i := range internacionais {
+		t.Internacionais += i.ValorTotalBRL
+	}
+	for _, c := range cripto {
+		t.Cripto += c.ValorTotalBRL
+	}
+	t.Total = t.Acoes + t.FIIs + t.Internacionais + t.Cripto
+	return t
+}
+
+// Resultado é o custo de aquisição (preço médio do livro de operações) e o ganho ou perda
+// ainda não realizado de uma posição; fica zerado para ativos sem operações registradas.
+type Resultado struct {
+	PrecoMedio            float64 `json:"preco_medio"`
+	CustoTotal            float64 `json:"custo_total"`
+	ResultadoNaoRealizado float64 `json:"resultado_nao_realizado"`
+	ResultadoPercent      float64 `json:"resultado_percent"`
+}
+
+// calcularResultado compara o valor de mercado da posição com o custo de aquisição.
+func calcularResultado(p Posicao, valorMercado float64) Resultado {
+	r := Resultado{PrecoMedio: p.PrecoMedio, CustoTotal: p.CustoTotal}
+	if valorMercado <= 0 || p.CustoTotal <= 0 {
+		return r
+	}
+	r.ResultadoNaoRealizado = valorMercado - p.CustoTotal
+	r.ResultadoPercent = r.ResultadoNaoRealizado / p.CustoTotal * 100
+	return r
+}
@@ -0,0 +1,592 @@
+package investimentos
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"minhas_economias/database"
+	"minhas_economias/middleware"
+	"minhas_economias/models"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Tipos de operação do livro de compras e vendas.
+const (
+	OperacaoCompra = "C"
+	OperacaoVenda  = "V"
+)
+
+// corretoraSaldoAnterior identifica a compra criada a partir da quantidade cadastrada antes do
+// livro de operações existir, com o preço médio informado pelo usuário na primeira operação.
+const corretoraSaldoAnterior = "Saldo anterior"
+
+// entityOperacao é o tipo de entidade das operações no log de auditoria.
+const entityOperacao = "operacao_investimento"
+
+var (
+	// ErrVendaDescoberto indica uma venda maior que a posição na data da operação.
+	ErrVendaDescoberto = errors.New("a venda é maior que a quantidade em carteira na data")
+	// ErrTipoAtivo indica um ativo novo sem o tipo (ACAO ou FII) informado.
+	ErrTipoAtivo = errors.New("informe o tipo do ativo (ACAO ou FII)")
+	// ErrPrecoSaldoAnterior indica a primeira operação de um ativo cadastrado manualmente sem o
+	// preço médio da quantidade que já estava em carteira.
+	ErrPrecoSaldoAnterior = errors.New("informe o preço médio (preco_medio_anterior) da quantidade já em carteira")
+)
+
+// Operacao é uma compra ou venda de ação/FII.
+type Operacao struct {
+	ID         int64   `json:"id"`
+	Ticker     string  `json:"ticker"`
+	TipoAtivo  string  `json:"tipo_ativo"` // ACAO ou FII
+	Tipo       string  `json:"tipo"`       // C (compra) ou V (venda)
+	Data       string  `json:"data"`       // AAAA-MM-DD
+	Quantidade int     `json:"quantidade"`
+	Preco      float64 `json:"preco"`
+	Taxas      float64 `json:"taxas"` // Corretagem, emolumentos e liquidação
+	Corretora  string  `json:"corretora"`
+}
+
+// Posicao é o saldo de um ativo derivado do livro de operações.
+type Posicao struct {
+	Ticker     string  `json:"ticker"`
+	TipoAtivo  string  `json:"tipo_ativo"`
+	Quantidade int     `json:"quantidade"`
+	PrecoMedio float64 `json:"preco_medio"`
+	CustoTotal float64 `json:"custo_total"`
+}
+
+// ordenarOperacoes coloca as operações em ordem cronológica (no mesmo dia, compras antes das vendas).
+func ordenarOperacoes(ops []Operacao) {
+	sort.SliceStable(ops, func(i, j int) bool {
+		if ops[i].Data != ops[j].Data {
+			return ops[i].Data < ops[j].Data
+		}
+		if ops[i].Tipo != ops[j].Tipo {
+			return ops[i].Tipo == OperacaoCompra
+		}
+		return ops[i].ID < ops[j].ID
+	})
+}
+
+// CalcularPosicao aplica as operações de um ativo em ordem cronológica e retorna a posição
+// final com o preço médio pela regra da Receita Federal (veja ApurarAtivo).
+func CalcularPosicao(ops []Operacao) (Posicao, error) {
+	p, _, err := ApurarAtivo(ops)
+	return p, err
+}
+
+// ApurarAtivo percorre as operações de um ativo, pregão a pregão, e retorna a posição final e
+// o resultado de cada venda. Regras da Receita Federal:
+//   - as compras somam quantidade × preço mais as taxas ao custo; as vendas baixam o custo pelo
+//     preço médio, sem alterá-lo, e o preço médio recomeça quando a posição é zerada;
+//   - compras e vendas do mesmo ativo no mesmo pregão formam um day trade na menor das duas
+//     quantidades, apurado pelos preços médios do dia e sem afetar o preço médio da posição;
+//     só a diferença entra na posição (compra) ou é vendida dela (venda).
+//
+// As vendas de uma posição que contém um "Saldo anterior" sem preço (registrado antes de o
+// preço médio ser exigido) saem marcadas com CustoDesconhecido.
+func ApurarAtivo(ops []Operacao) (Posicao, []models.VendaRealizada, error) {
+	ordenadas := append([]Operacao(nil), ops...)
+	ordenarOperacoes(ordenadas)
+
+	var p Posicao
+	var vendas []models.VendaRealizada
+	custoDesconhecido := false // a posição atual contém um saldo anterior sem preço
+	for i := 0; i < len(ordenadas); {
+		data := ordenadas[i].Data
+		var qtdCompra, qtdVenda int
+		var custoCompras, valorVendas, taxasVendas float64
+		comprasSemCusto := false
+		for ; i < len(ordenadas) && ordenadas[i].Data == data; i++ {
+			op := ordenadas[i]
+			p.Ticker, p.TipoAtivo = op.Ticker, op.TipoAtivo
+			switch op.Tipo {
+			case OperacaoCompra:
+				qtdCompra += op.Quantidade
+				custoCompras += float64(op.Quantidade)*op.Preco + op.Taxas
+				if op.Preco == 0 && op.Corretora == corretoraSaldoAnterior {
+					comprasSemCusto = true
+				}
+			case OperacaoVenda:
+				qtdVenda += op.Quantidade
+				valorVendas += float64(op.Quantidade) * op.Preco
+				taxasVendas += op.Taxas
+			default:
+				return p, nil, fmt.Errorf("tipo de operação '%s' inválido", op.Tipo)
+			}
+		}
+		// venda retorna a fração qtd das vendas do dia com o custo informado.
+		venda := func(qtd int, custo float64, dayTrade, semCusto bool) models.VendaRealizada {
+			fracao := float64(qtd) / float64(qtdVenda)
+			v := models.VendaRealizada{
+				Ticker: p.Ticker, TipoAtivo: p.TipoAtivo, Data: data, DayTrade: dayTrade, Quantidade: qtd,
+				ValorVenda: valorVendas * fracao, Taxas: taxasVendas * fracao, Custo: custo, CustoDesconhecido: semCusto,
+			}
+			v.Resultado = v.ValorVenda - v.Taxas - v.Custo
+			return v
+		}
+
+		dayTrade := qtdCompra
+		if qtdVenda < dayTrade {
+			dayTrade = qtdVenda
+		}
+		if dayTrade > 0 {
+			vendas = append(vendas, venda(dayTrade, custoCompras*float64(dayTrade)/float64(qtdCompra), true, comprasSemCusto))
+		}
+		if resto := qtdCompra - dayTrade; resto > 0 {
+			p.CustoTotal += custoCompras * float64(resto) / float64(qtdCompra)
+			p.Quantidade += resto
+			custoDesconhecido = custoDesconhecido || comprasSemCusto
+		}
+		if resto := qtdVenda - dayTrade; resto > 0 {
+			if resto > p.Quantidade {
+				return p, nil, fmt.Errorf("%s em %s: %w", p.Ticker, data, ErrVendaDescoberto)
+			}
+			custo := p.PrecoMedio * float64(resto)
+			vendas = append(vendas, venda(resto, custo, false, custoDesconhecido))
+			p.CustoTotal -= custo
+			p.Quantidade -= resto
+		}
+		if p.Quantidade == 0 {
+			p.CustoTotal, p.PrecoMedio = 0, 0
+			custoDesconhecido = false
+		} else {
+			p.PrecoMedio = p.CustoTotal / float64(p.Quantidade)
+		}
+	}
+	return p, vendas, nil
+}
+
+// queryer é atendido por *sql.DB e *sql.Tx.
+type queryer interface {
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+// listarOperacoes retorna as operações do usuário em ordem cronológica; ticker vazio lista todas.
+func listarOperacoes(q queryer, userID int64, ticker string) ([]Operacao, error) {
+	query := "SELECT id, ticker, tipo_ativo, tipo, data, quantidade, preco, taxas, COALESCE(corretora, '') FROM operacoes_investimentos WHERE user_id = ?"
+	args := []interface{}{userID}
+	if ticker != "" {
+		query += " AND ticker = ?"
+		args = append(args, ticker)
+	}
+	rows, err := q.Query(database.Rebind(query+" ORDER BY data, id"), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	ops := []Operacao{}
+	for rows.Next() {
+		var op Operacao
+		var rawData interface{}
+		if err := rows.Scan(&op.ID, &op.Ticker, &op.TipoAtivo, &op.Tipo, &rawData, &op.Quantidade, &op.Preco, &op.Taxas, &op.Corretora); err != nil {
+			return nil, err
+		}
+		op.Data = dateString(rawData)
+		ops = append(ops, op)
+	}
+	ordenarOperacoes(ops)
+	return ops, rows.Err()
+}
+
+// PosicoesDoLivro calcula a posição de cada ativo que tem operações registradas.
+func PosicoesDoLivro(userID int64) (map[string]Posicao, error) {
+	ops, err := listarOperacoes(database.GetDB(), userID, "")
+	if err != nil {
+		return nil, err
+	}
+	porTicker := make(map[string][]Operacao)
+	for _, op := range ops {
+		porTicker[op.Ticker] = append(porTicker[op.Ticker], op)
+	}
+	posicoes := make(map[string]Posicao, len(porTicker))
+	for ticker, opsTicker := range porTicker {
+		p, err := CalcularPosicao(opsTicker)
+		if err != nil {
+			return nil, err
+		}
+		posicoes[ticker] = p
+	}
+	return posicoes, nil
+}
+
+// sincronizarPosicao grava em investimentos_nacionais a quantidade derivada das operações do
+// ativo, removendo a linha quando a posição é zerada.
+func sincronizarPosicao(tx *sql.Tx, userID int64, ticker string) error {
+	ops, err := listarOperacoes(tx, userID, ticker)
+	if err != nil {
+		return err
+	}
+	p, err := CalcularPosicao(ops)
+	if err != nil {
+		return err
+	}
+	if p.Quantidade == 0 {
+		_, err = tx.Exec(database.Rebind("DELETE FROM investimentos_nacionais WHERE user_id = ? AND ticker = ?"), userID, ticker)
+		return err
+	}
+	_, err = tx.Exec(database.Rebind(`INSERT INTO investimentos_nacionais (user_id, ticker, tipo, quantidade) VALUES (?, ?, ?, ?)
+		ON CONFLICT (user_id, ticker) DO UPDATE SET tipo = excluded.tipo, quantidade = excluded.quantidade`), userID, ticker, p.TipoAtivo, p.Quantidade)
+	return err
+}
+
+// bloquearPosicao bloqueia a linha do ativo em investimentos_nacionais até o fim da transação no
+// PostgreSQL, para que operações simultâneas no mesmo ativo não validem o livro sem ver uma à
+// outra (o SQLite já serializa as escritas).
+func bloquearPosicao(tx *sql.Tx, userID int64, ticker string) error {
+	if database.DriverName != "postgres" {
+		return nil
+	}
+	var quantidade int
+	err := tx.QueryRow(database.Rebind("SELECT quantidade FROM investimentos_nacionais WHERE user_id = ? AND ticker = ? FOR UPDATE"), userID, ticker).Scan(&quantidade)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	return err
+}
+
+// saldoAnterior retorna a compra que representa a quantidade cadastrada manualmente antes da
+// primeira operação do ativo, datada do dia anterior a ela para não formar um day trade com a
+// operação, ou nil se o ativo já tem operações ou não está na carteira. O preço é preenchido
+// por RegistrarOperacao.
+func saldoAnterior(tx *sql.Tx, userID int64, ticker, data string) (*Operacao, error) {
+	var existentes int
+	if err := tx.QueryRow(database.Rebind("SELECT COUNT(*) FROM operacoes_investimentos WHERE user_id = ? AND ticker = ?"), userID, ticker).Scan(&existentes); err != nil {
+		return nil, err
+	}
+	if existentes > 0 {
+		return nil, nil
+	}
+	dia, err := time.Parse("2006-01-02", data)
+	if err != nil {
+		return nil, err
+	}
+	op := Operacao{Ticker: ticker, Tipo: OperacaoCompra, Data: dia.AddDate(0, 0, -1).Format("2006-01-02"), Corretora: corretoraSaldoAnterior}
+	err = tx.QueryRow(database.Rebind("SELECT COALESCE(tipo, ''), quantidade FROM investimentos_nacionais WHERE user_id = ? AND ticker = ?"), userID, ticker).Scan(&op.TipoAtivo, &op.Quantidade)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil || op.Quantidade <= 0 {
+		return nil, err
+	}
+	op.TipoAtivo = strings.ToUpper(op.TipoAtivo)
+	return &op, nil
+}
+
+func inserirOperacao(tx *sql.Tx, userID int64, op Operacao) (int64, error) {
+	query := `INSERT INTO operacoes_investimentos (user_id, ticker, tipo_ativo, tipo, data, quantidade, preco, taxas, corretora, created_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+	args := []interface{}{userID, op.Ticker, op.TipoAtivo, op.Tipo, op.Data, op.Quantidade, op.Preco, op.Taxas, op.Corretora, time.Now()}
+	if database.DriverName == "postgres" {
+		var id int64
+		err := tx.QueryRow(database.Rebind(query+" RETURNING id"), args...).Scan(&id)
+		return id, err
+	}
+	res, err := tx.Exec(query, args...)
+	if err != nil {
+		return 0, err
+	}
+	return res.LastInsertId()
+}
+
+// validarOperacao normaliza e confere os campos informados pelo usuário.
+func validarOperacao(op *Operacao) error {
+	op.Ticker = strings.ToUpper(strings.TrimSpace(op.Ticker))
+	op.Tipo = strings.ToUpper(strings.TrimSpace(op.Tipo))
+	op.TipoAtivo = strings.ToUpper(strings.TrimSpace(op.TipoAtivo))
+	op.Corretora = strings.TrimSpace(op.Corretora)
+	if op.Data == "" {
+		op.Data = time.Now().Format("2006-01-02")
+	}
+	switch {
+	case op.Ticker == "":
+		return errors.New("O ticker é obrigatório.")
+	case op.Tipo != OperacaoCompra && op.Tipo != OperacaoVenda:
+		return errors.New("O tipo da operação deve ser C (compra) ou V (venda).")
+	case op.Quantidade <= 0:
+		return errors.New("A quantidade deve ser positiva.")
+	case op.Preco < 0 || op.Taxas < 0 || math.IsNaN(op.Preco) || math.IsNaN(op.Taxas):
+		return errors.New("Preço e taxas não podem ser negativos.")
+	}
+	data, err := time.Parse("2006-01-02", op.Data)
+	if err != nil {
+		return errors.New("A data deve estar no formato AAAA-MM-DD.")
+	}
+	if data.After(time.Now()) {
+		return errors.New("Não é possível registrar uma operação em data futura.")
+	}
+	return nil
+}
+
+// RegistrarOperacao grava a operação e atualiza a posição do ativo. Se o ativo tinha uma
+// quantidade cadastrada antes do livro de operações, ela vira uma compra de "Saldo anterior"
+// no dia anterior, ao preço precoAnterior (obrigatório nesse caso, ErrPrecoSaldoAnterior se
+// zero). O tipo do ativo (ACAO ou FII) vem da carteira quando não for informado.
+func RegistrarOperacao(userID int64, op Operacao, precoAnterior float64) (Operacao, error) {
+	tx, err := database.GetDB().Begin()
+	if err != nil {
+		return op, err
+	}
+	defer tx.Rollback()
+
+	if err := bloquearPosicao(tx, userID, op.Ticker); err != nil {
+		return op, err
+	}
+	ops, err := listarOperacoes(tx, userID, op.Ticker)
+	if err != nil {
+		return op, err
+	}
+	abertura, err := saldoAnterior(tx, userID, op.Ticker, op.Data)
+	if err != nil {
+		return op, err
+	}
+	if abertura != nil {
+		if precoAnterior <= 0 || math.IsNaN(precoAnterior) || math.IsInf(precoAnterior, 0) {
+			return op, ErrPrecoSaldoAnterior
+		}
+		abertura.Preco = precoAnterior
+		ops = append(ops, *abertura)
+	}
+	if op.TipoAtivo == "" {
+		for _, existente := range ops {
+			if existente.TipoAtivo != "" {
+				op.TipoAtivo = existente.TipoAtivo
+			}
+		}
+	}
+	if op.TipoAtivo != "ACAO" && op.TipoAtivo != "FII" {
+		return op, ErrTipoAtivo
+	}
+	if _, err := CalcularPosicao(append(ops, op)); err != nil {
+		return op, err
+	}
+
+	if abertura != nil {
+		abertura.TipoAtivo = op.TipoAtivo
+		if _, err := inserirOperacao(tx, userID, *abertura); err != nil {
+			return op, err
+		}
+	}
+	if op.ID, err = inserirOperacao(tx, userID, op); err != nil {
+		return op, err
+	}
+	if err := sincronizarPosicao(tx, userID, op.Ticker); err != nil {
+		return op, err
+	}
+	return op, tx.Commit()
+}
+
+// carregarOperacao retorna a operação do usuário, ou nil se ela não existir.
+func carregarOperacao(userID, id int64) *Operacao {
+	query := database.Rebind("SELECT id, ticker, tipo_ativo, tipo, data, quantidade, preco, taxas, COALESCE(corretora, '') FROM operacoes_investimentos WHERE user_id = ? AND id = ?")
+	var op Operacao
+	var rawData interface{}
+	err := database.GetDB().QueryRow(query, userID, id).Scan(&op.ID, &op.Ticker, &op.TipoAtivo, &op.Tipo, &rawData, &op.Quantidade, &op.Preco, &op.Taxas, &op.Corretora)
+	if err != nil {
+		return nil
+	}
+	op.Data = dateString(rawData)
+	return &op
+}
+
+// alterarOperacao substitui (nova != nil) ou remove a operação e recalcula a posição,
+// recusando a mudança se alguma venda ficar maior que a posição.
+func alterarOperacao(userID int64, atual Operacao, nova *Operacao) error {
+	tx, err := database.GetDB().Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := bloquearPosicao(tx, userID, atual.Ticker); err != nil {
+		return err
+	}
+	ops, err := listarOperacoes(tx, userID, atual.Ticker)
+	if err != nil {
+		return err
+	}
+	restantes := ops[:0]
+	for _, op := range ops {
+		if op.ID != atual.ID {
+			restantes = append(restantes, op)
+		}
+	}
+	if nova != nil {
+		restantes = append(restantes, *nova)
+	}
+	if _, err := CalcularPosicao(restantes); err != nil {
+		return err
+	}
+
+	if nova != nil {
+		_, err = tx.Exec(database.Rebind("UPDATE operacoes_investimentos SET tipo = ?, data = ?, quantidade = ?, preco = ?, taxas = ?, corretora = ? WHERE user_id = ? AND id = ?"),
+			nova.Tipo, nova.Data, nova.Quantidade, nova.Preco, nova.Taxas, nova.Corretora, userID, atual.ID)
+	} else {
+		_, err = tx.Exec(database.Rebind("DELETE FROM operacoes_investimentos WHERE user_id = ? AND id = ?"), userID, atual.ID)
+	}
+	if err != nil {
+		return err
+	}
+	if err := sincronizarPosicao(tx, userID, atual.Ticker); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// temOperacoes indica se a quantidade do ativo é derivada do livro de operações.
+func temOperacoes(userID int64, ticker string) bool {
+	var n int
+	database.GetDB().QueryRow(database.Rebind("SELECT COUNT(*) FROM operacoes_investimentos WHERE user_id = ? AND ticker = ?"), userID, ticker).Scan(&n)
+	return n > 0
+}
+
+// --- Handlers ---
+
+// OperacaoPayload é o corpo das requisições de inclusão e alteração de operações.
+type OperacaoPayload struct {
+	Ticker     string  `json:"ticker"`
+	TipoAtivo  string  `json:"tipo_ativo"`
+	Tipo       string  `json:"tipo" binding:"required"`
+	Data       string  `json:"data"`
+	Quantidade int     `json:"quantidade" binding:"required"`
+	Preco      float64 `json:"preco"`
+	Taxas      float64 `json:"taxas"`
+	Corretora  string  `json:"corretora"`
+	// Preço médio da quantidade cadastrada antes do livro; exigido só na primeira operação do ativo.
+	PrecoMedioAnterior float64 `json:"preco_medio_anterior"`
+}
+
+func (p OperacaoPayload) operacao() Operacao {
+	return Operacao{Ticker: p.Ticker, TipoAtivo: p.TipoAtivo, Tipo: p.Tipo, Data: p.Data, Quantidade: p.Quantidade, Preco: p.Preco, Taxas: p.Taxas, Corretora: p.Corretora}
+}
+
+// respostaErroOperacao traduz os erros de validação do livro para a resposta HTTP.
+func respostaErroOperacao(c *gin.Context, err error, contexto string) {
+	if errors.Is(err, ErrPrecoSaldoAnterior) {
+		// A tela pergunta o preço médio e reenvia a operação.
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Operação recusada: " + err.Error() + ".", "precisa_preco_medio_anterior": true})
+		return
+	}
+	if errors.Is(err, ErrVendaDescoberto) || errors.Is(err, ErrTipoAtivo) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Operação recusada: " + err.Error() + "."})
+		return
+	}
+	log.Printf("Erro ao %s: %v", contexto, err)
+	c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao " + contexto + "."})
+}
+
+// GetOperacoesAPI lista as operações do usuário (?ticker= filtra um ativo) e as posições derivadas.
+func GetOperacoesAPI(c *gin.Context) {
+	userID := c.MustGet("userID").(int64)
+	ticker := strings.ToUpper(strings.TrimSpace(c.Query("ticker")))
+	ops, err := listarOperacoes(database.GetDB(), userID, ticker)
+	if err != nil {
+		log.Printf("Erro ao listar as operações do usuário %d: %v", userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao listar as operações."})
+		return
+	}
+	posicoes, err := PosicoesDoLivro(userID)
+	if err != nil {
+		log.Printf("Erro ao calcular as posições do usuário %d: %v", userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao calcular as posições."})
+		return
+	}
+	lista := []Posicao{}
+	for t, p := range posicoes {
+		if ticker == "" || t == ticker {
+			lista = append(lista, p)
+		}
+	}
+	sort.Slice(lista, func(i, j int) bool { return lista[i].Ticker < lista[j].Ticker })
+	c.JSON(http.StatusOK, gin.H{"operacoes": ops, "posicoes": lista})
+}
+
+// AddOperacao registra uma compra ou venda de ação/FII.
+func AddOperacao(c *gin.Context) {
+	userID := c.MustGet("userID").(int64)
+	var payload OperacaoPayload
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Payload inválido: " + err.Error()})
+		return
+	}
+	op := payload.operacao()
+	if err := validarOperacao(&op); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	before := loadHolding(entityNacional, userID, op.Ticker)
+	op, err := RegistrarOperacao(userID, op, payload.PrecoMedioAnterior)
+	if err != nil {
+		respostaErroOperacao(c, err, "registrar a operação")
+		return
+	}
+	middleware.SetAuditChange(c, entityOperacao, strconv.FormatInt(op.ID, 10), nil, op)
+	if before == nil {
+		middleware.InvestmentsCreated.WithLabelValues("nacional").Inc()
+	}
+	ClearNacionalCache()
+	c.JSON(http.StatusOK, gin.H{"message": "Operação registrada com sucesso!", "operacao": op})
+}
+
+// UpdateOperacao altera data, tipo, quantidade, preço, taxas ou corretora de uma operação.
+func UpdateOperacao(c *gin.Context) {
+	userID := c.MustGet("userID").(int64)
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID de operação inválido."})
+		return
+	}
+	atual := carregarOperacao(userID, id)
+	if atual == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Operação não encontrada."})
+		return
+	}
+	var payload OperacaoPayload
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Payload inválido: " + err.Error()})
+		return
+	}
+	nova := payload.operacao()
+	nova.ID, nova.Ticker, nova.TipoAtivo = atual.ID, atual.Ticker, atual.TipoAtivo
+	if err := validarOperacao(&nova); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := alterarOperacao(userID, *atual, &nova); err != nil {
+		respostaErroOperacao(c, err, "alterar a operação")
+		return
+	}
+	middleware.SetAuditChange(c, entityOperacao, strconv.FormatInt(id, 10), atual, nova)
+	ClearNacionalCache()
+	c.JSON(http.StatusOK, gin.H{"message": "Operação atualizada com sucesso!", "operacao": nova})
+}
+
+// DeleteOperacao remove uma operação e recalcula a posição do ativo.
+func DeleteOperacao(c *gin.Context) {
+	userID := c.MustGet("userID").(int64)
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID de operação inválido."})
+		return
+	}
+	atual := carregarOperacao(userID, id)
+	if atual == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Operação não encontrada."})
+		return
+	}
+	if err := alterarOperacao(userID, *atual, nil); err != nil {
+		respostaErroOperacao(c, err, "excluir a operação")
+		return
+	}
+	middleware.SetAuditChange(c, entityOperacao, strconv.FormatInt(id, 10), atual, nil)
+	ClearNacionalCache()
+	c.JSON(http.StatusOK, gin.H{"message": "Operação excluída com sucesso!"})
+}
@@ -0,0 +1,198 @@
+package investimentos
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"minhas_economias/database"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+)
+
+func TestCalcularPosicao(t *testing.T) {
+	ops := []Operacao{
+		{ID: 3, Ticker: "ITSA4", TipoAtivo: "ACAO", Tipo: OperacaoVenda, Data: "2025-03-01", Quantidade: 60, Preco: 15},
+		{ID: 1, Ticker: "ITSA4", TipoAtivo: "ACAO", Tipo: OperacaoCompra, Data: "2025-01-10", Quantidade: 100, Preco: 10, Taxas: 5},
+		{ID: 2, Ticker: "ITSA4", TipoAtivo: "ACAO", Tipo: OperacaoCompra, Data: "2025-02-10", Quantidade: 50, Preco: 13, Taxas: 5},
+	}
+	p, err := CalcularPosicao(ops)
+	if err != nil {
+		t.Fatalf("Erro inesperado: %v", err)
+	}
+	// Custo das compras: 100×10 + 5 + 50×13 + 5 = 1660 para 150 ações; a venda não altera o preço médio.
+	precoMedio := 1660.0 / 150
+	if p.Quantidade != 90 || math.Abs(p.PrecoMedio-precoMedio) > 1e-9 || math.Abs(p.CustoTotal-90*precoMedio) > 1e-9 {
+		t.Errorf("Posição inesperada: %+v (preço médio esperado %.4f)", p, precoMedio)
+	}
+
+	// Zerada a posição, o preço médio recomeça na próxima compra.
+	ops = append(ops,
+		Operacao{ID: 4, Tipo: OperacaoVenda, Data: "2025-03-05", Quantidade: 90, Preco: 16},
+		Operacao{ID: 5, Tipo: OperacaoCompra, Data: "2025-04-01", Quantidade: 10, Preco: 20},
+	)
+	if p, _ = CalcularPosicao(ops); p.Quantidade != 10 || p.PrecoMedio != 20 {
+		t.Errorf("Preço médio deveria recomeçar após zerar a posição: %+v", p)
+	}
+
+	ops = append(ops, Operacao{ID: 6, Tipo: OperacaoVenda, Data: "2025-04-02", Quantidade: 11, Preco: 20})
+	if _, err := CalcularPosicao(ops); !errors.Is(err, ErrVendaDescoberto) {
+		t.Errorf("Esperado ErrVendaDescoberto, obtido %v", err)
+	}
+}
+
+func TestOperacoesDerivamPosicao(t *testing.T) {
+	setupInvestimentosTestDB(t)
+	defer teardownInvestimentosTestDB()
+	router := createInvestimentosTestRouter()
+	db := database.GetDB()
+	quantidade := func(ticker string) int {
+		var q int
+		db.QueryRow("SELECT quantidade FROM investimentos_nacionais WHERE user_id = ? AND ticker = ?", testUserID, ticker).Scan(&q)
+		return q
+	}
+
+	// PETR4 tinha 100 ações cadastradas manualmente: a primeira operação exige o preço médio
+	// delas e cria o saldo anterior.
+	compra := OperacaoPayload{Ticker: "petr4", Tipo: "C", Data: "2025-01-10", Quantidade: 100, Preco: 30, Taxas: 10, Corretora: "XP"}
+	if w := performInvestimentosJSONRequest(router, "POST", "/investimentos/operacoes", compra); w.Code != http.StatusBadRequest || quantidade("PETR4") != 100 {
+		t.Fatalf("Esperado status 400 sem o preço médio anterior, obtido %d: %s", w.Code, w.Body.String())
+	}
+	compra.PrecoMedioAnterior = 25
+	if w := performInvestimentosJSONRequest(router, "POST", "/investimentos/operacoes", compra); w.Code != http.StatusOK {
+		t.Fatalf("Esperado status 200, obtido %d: %s", w.Code, w.Body.String())
+	}
+	if q := quantidade("PETR4"); q != 200 {
+		t.Errorf("Esperadas 200 ações de PETR4, obtidas %d", q)
+	}
+
+	// Adicionar pelo formulário antigo soma à posição também no SQLite.
+	w := performInvestimentosJSONRequest(router, "POST", "/investimentos/nacional", AddNacionalPayload{Ticker: "PETR4", Tipo: "ACAO", Quantidade: 50, Preco: 32, Data: "2025-01-15"})
+	if w.Code != http.StatusOK || quantidade("PETR4") != 250 {
+		t.Errorf("Esperadas 250 ações de PETR4 após nova compra (status %d, quantidade %d)", w.Code, quantidade("PETR4"))
+	}
+
+	venda := OperacaoPayload{Ticker: "PETR4", Tipo: "V", Data: "2025-02-01", Quantidade: 300, Preco: 35}
+	if w := performInvestimentosJSONRequest(router, "POST", "/investimentos/operacoes", venda); w.Code != http.StatusBadRequest {
+		t.Errorf("Venda maior que a posição deveria ser recusada, status %d", w.Code)
+	}
+	venda.Quantidade = 50
+	if w := performInvestimentosJSONRequest(router, "POST", "/investimentos/operacoes", venda); w.Code != http.StatusOK {
+		t.Fatalf("Esperado status 200 na venda, obtido %d: %s", w.Code, w.Body.String())
+	}
+
+	// Com operações, a quantidade não pode mais ser editada manualmente.
+	if w := performInvestimentosJSONRequest(router, "POST", "/investimentos/nacional/PETR4", UpdatePayload{Quantidade: 10}); w.Code != http.StatusConflict {
+		t.Errorf("Esperado status 409 ao editar ativo com operações, obtido %d", w.Code)
+	}
+
+	req, _ := http.NewRequest("GET", "/api/investimentos/operacoes?ticker=PETR4", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	var livro struct {
+		Operacoes []Operacao `json:"operacoes"`
+		Posicoes  []Posicao  `json:"posicoes"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &livro)
+	if len(livro.Operacoes) != 4 || livro.Operacoes[0].Corretora != corretoraSaldoAnterior || livro.Operacoes[0].Data != "2025-01-09" || livro.Operacoes[0].Preco != 25 {
+		t.Fatalf("Operações inesperadas: %+v", livro.Operacoes)
+	}
+	// Custo: 100×25 do saldo anterior + 100×30 + 10 + 50×32 = 7110 para 250 ações.
+	if len(livro.Posicoes) != 1 || livro.Posicoes[0].Quantidade != 200 || math.Abs(livro.Posicoes[0].PrecoMedio-7110.0/250) > 1e-9 {
+		t.Errorf("Posição inesperada: %+v", livro.Posicoes)
+	}
+
+	// Excluir uma compra que deixaria a venda a descoberto é recusado; excluir a venda é aceito.
+	compraID := livro.Operacoes[1].ID
+	req, _ = http.NewRequest("DELETE", "/investimentos/operacoes/"+strconv.FormatInt(livro.Operacoes[3].ID, 10), nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK || quantidade("PETR4") != 250 {
+		t.Errorf("Esperado status 200 e 250 ações após excluir a venda (status %d, quantidade %d)", w.Code, quantidade("PETR4"))
+	}
+	alteracao := OperacaoPayload{Tipo: "C", Data: "2025-01-10", Quantidade: 10, Preco: 30}
+	if w := performInvestimentosJSONRequest(router, "POST", "/investimentos/operacoes/"+strconv.FormatInt(compraID, 10), alteracao); w.Code != http.StatusOK || quantidade("PETR4") != 160 {
+		t.Errorf("Esperado status 200 e 160 ações após alterar a compra (status %d, quantidade %d)", w.Code, quantidade("PETR4"))
+	}
+
+	// Ativo novo sem o tipo informado é recusado.
+	if w := performInvestimentosJSONRequest(router, "POST", "/investimentos/operacoes", OperacaoPayload{Ticker: "BBAS3", Tipo: "C", Quantidade: 1, Preco: 25}); w.Code != http.StatusBadRequest {
+		t.Errorf("Esperado status 400 para ativo novo sem tipo, obtido %d", w.Code)
+	}
+}
+
+func TestPrimeiraOperacaoVendaDoSaldoAnterior(t *testing.T) {
+	setupInvestimentosTestDB(t)
+	defer teardownInvestimentosTestDB()
+
+	// A primeira operação de PETR4 (100 cadastradas) é uma venda: ela sai do saldo anterior, ao
+	// preço médio informado, e não forma um day trade com a compra de abertura.
+	venda := Operacao{Ticker: "PETR4", Tipo: OperacaoVenda, Data: "2025-03-10", Quantidade: 40, Preco: 35}
+	if _, err := RegistrarOperacao(testUserID, venda, 0); !errors.Is(err, ErrPrecoSaldoAnterior) {
+		t.Fatalf("Esperado ErrPrecoSaldoAnterior, obtido %v", err)
+	}
+	if _, err := RegistrarOperacao(testUserID, venda, 20); err != nil {
+		t.Fatalf("Erro ao registrar a venda: %v", err)
+	}
+	vendas, err := VendasRealizadas(testUserID)
+	if err != nil {
+		t.Fatalf("Erro ao apurar as vendas: %v", err)
+	}
+	if len(vendas) != 1 || vendas[0].DayTrade || vendas[0].Data != "2025-03-10" || vendas[0].Custo != 800 || vendas[0].Resultado != 600 {
+		t.Errorf("Venda inesperada: %+v", vendas)
+	}
+	posicoes, err := PosicoesDoLivro(testUserID)
+	if err != nil || posicoes["PETR4"].Quantidade != 60 || posicoes["PETR4"].PrecoMedio != 20 {
+		t.Errorf("Posição inesperada: %+v (%v)", posicoes["PETR4"], err)
+	}
+}
+
+func TestApurarAtivoDayTrade(t *testing.T) {
+	ops := []Operacao{
+		{ID: 1, Ticker: "VALE3", TipoAtivo: "ACAO", Tipo: OperacaoCompra, Data: "2025-01-10", Quantidade: 100, Preco: 10},
+		// No mesmo pregão: 50 compradas e 50 vendidas são day trade; as outras 30 vendidas saem da posição.
+		{ID: 2, Ticker: "VALE3", TipoAtivo: "ACAO", Tipo: OperacaoVenda, Data: "2025-02-03", Quantidade: 80, Preco: 13, Taxas: 8},
+		{ID: 3, Ticker: "VALE3", TipoAtivo: "ACAO", Tipo: OperacaoCompra, Data: "2025-02-03", Quantidade: 50, Preco: 12},
+	}
+	p, vendas, err := ApurarAtivo(ops)
+	if err != nil {
+		t.Fatalf("Erro inesperado: %v", err)
+	}
+	if p.Quantidade != 70 || p.PrecoMedio != 10 {
+		t.Errorf("O day trade não deveria alterar o preço médio: %+v", p)
+	}
+	if len(vendas) != 2 {
+		t.Fatalf("Esperadas 2 vendas (day trade e comum), obtidas %+v", vendas)
+	}
+	dt, comum := vendas[0], vendas[1]
+	if !dt.DayTrade || dt.Quantidade != 50 || dt.ValorVenda != 650 || dt.Taxas != 5 || dt.Custo != 600 || dt.Resultado != 45 {
+		t.Errorf("Day trade inesperado: %+v", dt)
+	}
+	if comum.DayTrade || comum.Quantidade != 30 || comum.ValorVenda != 390 || comum.Taxas != 3 || comum.Custo != 300 || comum.Resultado != 87 {
+		t.Errorf("Venda comum inesperada: %+v", comum)
+	}
+}
+
+func TestApurarSaldoAnteriorSemPreco(t *testing.T) {
+	// Saldo anterior gravado sem preço: as vendas dessa posição (e só dela) são sinalizadas.
+	ops := []Operacao{
+		{ID: 1, Ticker: "PETR4", TipoAtivo: "ACAO", Tipo: OperacaoCompra, Data: "2024-05-09", Quantidade: 100, Corretora: corretoraSaldoAnterior},
+		{ID: 2, Ticker: "PETR4", TipoAtivo: "ACAO", Tipo: OperacaoVenda, Data: "2024-05-10", Quantidade: 100, Preco: 30},
+		{ID: 3, Ticker: "PETR4", TipoAtivo: "ACAO", Tipo: OperacaoCompra, Data: "2025-01-10", Quantidade: 10, Preco: 30},
+		{ID: 4, Ticker: "PETR4", TipoAtivo: "ACAO", Tipo: OperacaoVenda, Data: "2025-02-10", Quantidade: 10, Preco: 32},
+	}
+	_, vendas, err := ApurarAtivo(ops)
+	if err != nil {
+		t.Fatalf("Erro inesperado: %v", err)
+	}
+	if len(vendas) != 2 || !vendas[0].CustoDesconhecido || vendas[1].CustoDesconhecido {
+		t.Fatalf("Vendas inesperadas: %+v", vendas)
+	}
+	if a := ApurarImpostoRenda(vendas, 2024); len(a.CustoDesconhecido) != 1 || a.CustoDesconhecido[0] != "PETR4" {
+		t.Errorf("2024 deveria sinalizar PETR4: %v", a.CustoDesconhecido)
+	}
+	if a := ApurarImpostoRenda(vendas, 2025); len(a.CustoDesconhecido) != 0 {
+		t.Errorf("2025 não deveria sinalizar nada: %v", a.CustoDesconhecido)
+	}
+}
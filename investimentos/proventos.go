@@ -0,0 +1,531 @@
+package investimentos
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"minhas_economias/database"
+	"minhas_economias/households"
+	"minhas_economias/middleware"
+	"minhas_economias/models"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Tipos de provento.
+const (
+	ProventoDividendo  = "DIVIDENDO"
+	ProventoJCP        = "JCP"
+	ProventoRendimento = "RENDIMENTO" // Rendimentos de FIIs
+)
+
+// aliquotaJCP é o IR retido na fonte sobre juros sobre capital próprio, usado quando a retenção
+// não é informada. Dividendos e rendimentos de FIIs são isentos para pessoa física.
+const aliquotaJCP = 0.15
+
+// categoriaProventos é a categoria das movimentações de entrada criadas para os proventos.
+const categoriaProventos = "Proventos"
+
+// entityProvento é o tipo de entidade dos proventos no log de auditoria.
+const entityProvento = "provento"
+
+var nomesProventos = map[string]string{
+	ProventoDividendo:  "Dividendos",
+	ProventoJCP:        "JCP",
+	ProventoRendimento: "Rendimentos",
+}
+
+var (
+	// ErrSemPosicao indica um provento sem quantidade informada de um ativo que não estava em
+	// carteira na data ex.
+	ErrSemPosicao = errors.New("o ativo não estava em carteira na data ex; informe a quantidade")
+	// ErrRetencao indica um imposto retido maior que o valor bruto do provento.
+	ErrRetencao = errors.New("o imposto retido é maior que o valor bruto")
+)
+
+// Provento é um dividendo, JCP ou rendimento recebido por um ativo.
+type Provento struct {
+	ID             int64   `json:"id"`
+	Ticker         string  `json:"ticker"`
+	TipoAtivo      string  `json:"tipo_ativo"`
+	Tipo           string  `json:"tipo"`           // DIVIDENDO, JCP ou RENDIMENTO
+	DataEx         string  `json:"data_ex"`        // Primeiro pregão sem direito ao provento
+	DataPagamento  string  `json:"data_pagamento"` // AAAA-MM-DD
+	ValorPorCota   float64 `json:"valor_por_cota"`
+	Quantidade     int     `json:"quantidade"`     // Cotas com direito ao provento
+	ImpostoRetido  float64 `json:"imposto_retido"` // Total retido na fonte
+	ValorBruto     float64 `json:"valor_bruto"`
+	ValorLiquido   float64 `json:"valor_liquido"`
+	MovimentacaoID *int64  `json:"movimentacao_id,omitempty"` // Entrada criada na conta escolhida
+}
+
+// calcularValores preenche os valores bruto e líquido a partir da quantidade e do valor por cota.
+func (p *Provento) calcularValores() {
+	p.ValorBruto = arredondar(float64(p.Quantidade) * p.ValorPorCota)
+	p.ValorLiquido = arredondar(p.ValorBruto - p.ImpostoRetido)
+}
+
+// ProventoMensal soma os proventos líquidos pagos em um mês, por tipo.
+type ProventoMensal struct {
+	Mes         string  `json:"mes"` // AAAA-MM
+	Dividendos  float64 `json:"dividendos"`
+	JCP         float64 `json:"jcp"`
+	Rendimentos float64 `json:"rendimentos"`
+	Total       float64 `json:"total"`
+}
+
+// YieldOnCost compara os proventos líquidos dos últimos 12 meses com o custo da posição atual.
+type YieldOnCost struct {
+	Ticker       string  `json:"ticker"`
+	TipoAtivo    string  `json:"tipo_ativo"`
+	Proventos12M float64 `json:"proventos_12m"`
+	CustoTotal   float64 `json:"custo_total"`
+	YieldOnCost  float64 `json:"yield_on_cost"` // Em %; zero sem operações registradas
+}
+
+// ResumirProventos agrupa os proventos pagos nos últimos meses (terminando no mês de hoje,
+// inclusive os meses sem proventos) e calcula o yield on cost dos últimos 12 meses por ativo.
+func ResumirProventos(proventos []Provento, posicoes map[string]Posicao, hoje time.Time, meses int) ([]ProventoMensal, []YieldOnCost) {
+	inicioMes := time.Date(hoje.Year(), hoje.Month(), 1, 0, 0, 0, 0, time.UTC)
+	mensal := make([]ProventoMensal, meses)
+	indice := make(map[string]int, meses)
+	for i := range mensal {
+		mes := inicioMes.AddDate(0, i-meses+1, 0).Format("2006-01")
+		mensal[i].Mes = mes
+		indice[mes] = i
+	}
+
+	hojeStr := hoje.Format("2006-01-02")
+	inicio12M := hoje.AddDate(-1, 0, 0).Format("2006-01-02")
+	porTicker := make(map[string]*YieldOnCost)
+	for _, p := range proventos {
+		if i, ok := indice[p.DataPagamento[:7]]; ok {
+			m := &mensal[i]
+			switch p.Tipo {
+			case ProventoJCP:
+				m.JCP += p.ValorLiquido
+			case ProventoRendimento:
+				m.Rendimentos += p.ValorLiquido
+			default:
+				m.Dividendos += p.ValorLiquido
+			}
+			m.Total += p.ValorLiquido
+		}
+		if p.DataPagamento <= inicio12M || p.DataPagamento > hojeStr {
+			continue
+		}
+		y, ok := porTicker[p.Ticker]
+		if !ok {
+			y = &YieldOnCost{Ticker: p.Ticker, TipoAtivo: p.TipoAtivo}
+			porTicker[p.Ticker] = y
+		}
+		y.Proventos12M += p.ValorLiquido
+	}
+	for i := range mensal {
+		m := &mensal[i]
+		m.Dividendos, m.JCP, m.Rendimentos, m.Total = arredondar(m.Dividendos), arredondar(m.JCP), arredondar(m.Rendimentos), arredondar(m.Total)
+	}
+
+	yoc := make([]YieldOnCost, 0, len(porTicker))
+	for ticker, y := range porTicker {
+		y.Proventos12M = arredondar(y.Proventos12M)
+		if p, ok := posicoes[ticker]; ok && p.CustoTotal > 0 {
+			y.CustoTotal = arredondar(p.CustoTotal)
+			y.YieldOnCost = arredondar(y.Proventos12M / p.CustoTotal * 100)
+		}
+		yoc = append(yoc, *y)
+	}
+	sort.Slice(yoc, func(i, j int) bool { return yoc[i].Ticker < yoc[j].Ticker })
+	return mensal, yoc
+}
+
+// listarProventos retorna os proventos do usuário pela data de pagamento; ticker vazio lista todos.
+func listarProventos(userID int64, ticker string) ([]Provento, error) {
+	query := "SELECT id, ticker, tipo_ativo, tipo, data_ex, data_pagamento, valor_por_cota, quantidade, imposto_retido, movimentacao_id FROM proventos WHERE user_id = ?"
+	args := []interface{}{userID}
+	if ticker != "" {
+		query += " AND ticker = ?"
+		args = append(args, ticker)
+	}
+	rows, err := database.GetDB().Query(database.Rebind(query+" ORDER BY data_pagamento, id"), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	proventos := []Provento{}
+	for rows.Next() {
+		p, err := scanProvento(rows)
+		if err != nil {
+			return nil, err
+		}
+		proventos = append(proventos, p)
+	}
+	return proventos, rows.Err()
+}
+
+// scanner é atendido por *sql.Row e *sql.Rows.
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanProvento(s scanner) (Provento, error) {
+	var p Provento
+	var rawEx, rawPagamento interface{}
+	var movID sql.NullInt64
+	if err := s.Scan(&p.ID, &p.Ticker, &p.TipoAtivo, &p.Tipo, &rawEx, &rawPagamento, &p.ValorPorCota, &p.Quantidade, &p.ImpostoRetido, &movID); err != nil {
+		return p, err
+	}
+	p.DataEx, p.DataPagamento = dateString(rawEx), dateString(rawPagamento)
+	if movID.Valid {
+		p.MovimentacaoID = &movID.Int64
+	}
+	p.calcularValores()
+	return p, nil
+}
+
+// posicaoNaDataEx retorna a quantidade em carteira no pregão anterior à data ex e o tipo do
+// ativo. Sem operações registradas, vale a quantidade cadastrada hoje.
+func posicaoNaDataEx(userID int64, ticker, dataEx string) (int, string, error) {
+	ops, err := listarOperacoes(database.GetDB(), userID, ticker)
+	if err != nil {
+		return 0, "", err
+	}
+	if len(ops) == 0 {
+		var quantidade int
+		var tipo string
+		err := database.GetDB().QueryRow(database.Rebind("SELECT quantidade, COALESCE(tipo, '') FROM investimentos_nacionais WHERE user_id = ? AND ticker = ?"), userID, ticker).Scan(&quantidade, &tipo)
+		if err == sql.ErrNoRows {
+			return 0, "", nil
+		}
+		return quantidade, strings.ToUpper(tipo), err
+	}
+	var anteriores []Operacao
+	for _, op := range ops {
+		if op.Data < dataEx {
+			anteriores = append(anteriores, op)
+		}
+	}
+	p, err := CalcularPosicao(anteriores)
+	return p.Quantidade, ops[0].TipoAtivo, err
+}
+
+// validarProvento normaliza e confere os campos informados pelo usuário.
+func validarProvento(p *Provento) error {
+	p.Ticker = strings.ToUpper(strings.TrimSpace(p.Ticker))
+	p.Tipo = strings.ToUpper(strings.TrimSpace(p.Tipo))
+	switch {
+	case p.Ticker == "":
+		return errors.New("O ticker é obrigatório.")
+	case nomesProventos[p.Tipo] == "":
+		return errors.New("O tipo do provento deve ser DIVIDENDO, JCP ou RENDIMENTO.")
+	case p.ValorPorCota <= 0 || math.IsNaN(p.ValorPorCota):
+		return errors.New("O valor por cota deve ser positivo.")
+	case p.Quantidade < 0:
+		return errors.New("A quantidade não pode ser negativa.")
+	case p.ImpostoRetido < 0 || math.IsNaN(p.ImpostoRetido):
+		return errors.New("O imposto retido não pode ser negativo.")
+	}
+	ex, err := time.Parse("2006-01-02", p.DataEx)
+	if err != nil {
+		return errors.New("A data ex deve estar no formato AAAA-MM-DD.")
+	}
+	pagamento, err := time.Parse("2006-01-02", p.DataPagamento)
+	if err != nil {
+		return errors.New("A data de pagamento deve estar no formato AAAA-MM-DD.")
+	}
+	if pagamento.Before(ex) {
+		return errors.New("A data de pagamento não pode ser anterior à data ex.")
+	}
+	return nil
+}
+
+// inserirMovimentacaoProvento lança o valor líquido do provento como entrada na conta, no livro
+// pessoal ou no lar ativo, e retorna o ID da movimentação. Pagamentos futuros ficam pendentes.
+// No lar, a linha pertence ao proprietário do lar e o usuário fica registrado em created_by.
+func inserirMovimentacaoProvento(tx *sql.Tx, userID, householdID int64, p Provento, conta string) (int64, error) {
+	var household interface{}
+	dono := userID
+	if householdID != 0 {
+		household = householdID
+		if err := tx.QueryRow(database.Rebind("SELECT owner_id FROM households WHERE id = ?"), householdID).Scan(&dono); err != nil {
+			return 0, fmt.Errorf("erro ao buscar o proprietário do lar: %w", err)
+		}
+	}
+	descricao := fmt.Sprintf("%s %s", nomesProventos[p.Tipo], p.Ticker)
+	consolidado := p.DataPagamento <= time.Now().Format("2006-01-02")
+	query := fmt.Sprintf(`INSERT INTO %s (user_id, household_id, created_by, data_ocorrencia, descricao, valor, categoria, conta, consolidado) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`, database.TableName)
+	args := []interface{}{dono, household, userID, p.DataPagamento, descricao, p.ValorLiquido, categoriaProventos, conta, consolidado}
+	if database.DriverName == "postgres" {
+		var id int64
+		err := tx.QueryRow(database.Rebind(query+" RETURNING id"), args...).Scan(&id)
+		return id, err
+	}
+	res, err := tx.Exec(query, args...)
+	if err != nil {
+		return 0, err
+	}
+	return res.LastInsertId()
+}
+
+// RegistrarProvento grava o provento e, se conta não for vazia, a entrada correspondente no
+// livro-caixa. Sem quantidade informada, usa a posição na data ex; sem retenção informada
+// (retencao nil), aplica a alíquota do JCP.
+func RegistrarProvento(userID, householdID int64, p Provento, retencao *float64, conta string) (Provento, error) {
+	quantidade, tipoAtivo, err := posicaoNaDataEx(userID, p.Ticker, p.DataEx)
+	if err != nil {
+		return p, err
+	}
+	if p.Quantidade == 0 {
+		p.Quantidade = quantidade
+	}
+	if p.Quantidade == 0 {
+		return p, ErrSemPosicao
+	}
+	if p.TipoAtivo = tipoAtivo; p.TipoAtivo == "" {
+		p.TipoAtivo = "ACAO"
+		if p.Tipo == ProventoRendimento {
+			p.TipoAtivo = "FII"
+		}
+	}
+	p.ImpostoRetido = 0
+	if retencao != nil {
+		p.ImpostoRetido = *retencao
+	} else if p.Tipo == ProventoJCP {
+		p.ImpostoRetido = arredondar(float64(p.Quantidade) * p.ValorPorCota * aliquotaJCP)
+	}
+	p.calcularValores()
+	if p.ValorLiquido < 0 {
+		return p, ErrRetencao
+	}
+
+	tx, err := database.GetDB().Begin()
+	if err != nil {
+		return p, err
+	}
+	defer tx.Rollback()
+
+	var movID interface{}
+	if conta != "" {
+		id, err := inserirMovimentacaoProvento(tx, userID, householdID, p, conta)
+		if err != nil {
+			return p, err
+		}
+		p.MovimentacaoID, movID = &id, id
+	}
+	query := `INSERT INTO proventos (user_id, ticker, tipo_ativo, tipo, data_ex, data_pagamento, valor_por_cota, quantidade, imposto_retido, movimentacao_id, created_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+	args := []interface{}{userID, p.Ticker, p.TipoAtivo, p.Tipo, p.DataEx, p.DataPagamento, p.ValorPorCota, p.Quantidade, p.ImpostoRetido, movID, time.Now()}
+	if database.DriverName == "postgres" {
+		err = tx.QueryRow(database.Rebind(query+" RETURNING id"), args...).Scan(&p.ID)
+	} else {
+		var res sql.Result
+		if res, err = tx.Exec(query, args...); err == nil {
+			p.ID, err = res.LastInsertId()
+		}
+	}
+	if err != nil {
+		return p, err
+	}
+	return p, tx.Commit()
+}
+
+// ExcluirProvento remove o provento e move para a lixeira a entrada criada para ele.
+func ExcluirProvento(userID int64, p Provento) error {
+	tx, err := database.GetDB().Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if _, err := tx.Exec(database.Rebind("DELETE FROM proventos WHERE user_id = ? AND id = ?"), userID, p.ID); err != nil {
+		return err
+	}
+	if p.MovimentacaoID != nil {
+		// No lar a linha pertence ao proprietário; o autor do provento é quem consta em created_by.
+		query := fmt.Sprintf("UPDATE %s SET deleted_at = COALESCE(deleted_at, ?) WHERE id = ? AND (created_by = ? OR (household_id IS NULL AND user_id = ?))", database.TableName)
+		res, err := tx.Exec(database.Rebind(query), time.Now().UTC(), *p.MovimentacaoID, userID, userID)
+		if err != nil {
+			return err
+		}
+		if n, err := res.RowsAffected(); err != nil || n != 1 {
+			return fmt.Errorf("movimentação %d do provento %d não encontrada no livro do usuário", *p.MovimentacaoID, p.ID)
+		}
+	}
+	return tx.Commit()
+}
+
+// contasDoLivro lista as contas com movimentações no livro ativo (pessoal ou do lar), para a
+// escolha da conta que recebe os proventos.
+func contasDoLivro(userID, householdID int64) []string {
+	where, arg := "user_id = ? AND household_id IS NULL", userID
+	if householdID != 0 {
+		where, arg = "household_id = ?", householdID
+	}
+	query := fmt.Sprintf("SELECT DISTINCT conta FROM %s WHERE %s AND deleted_at IS NULL AND conta <> '' ORDER BY conta", database.TableName, where)
+	rows, err := database.GetDB().Query(database.Rebind(query), arg)
+	if err != nil {
+		log.Printf("Erro ao listar as contas do usuário %d: %v", userID, err)
+		return nil
+	}
+	defer rows.Close()
+	var contas []string
+	for rows.Next() {
+		var conta string
+		if err := rows.Scan(&conta); err == nil {
+			contas = append(contas, conta)
+		}
+	}
+	return contas
+}
+
+// householdDoPedido retorna o lar ativo da sessão, ou zero no livro pessoal.
+func householdDoPedido(c *gin.Context) int64 {
+	if id, ok := c.Get("householdID"); ok {
+		return id.(int64)
+	}
+	return 0
+}
+
+// podeAlterarLivro indica se o papel no lar ativo permite lançar no livro-caixa, como em
+// auth.RequireLedgerWrite; no livro pessoal sempre permite.
+func podeAlterarLivro(c *gin.Context) bool {
+	role := c.GetString("householdRole")
+	return role == "" || (models.Household{Role: role}).CanEdit()
+}
+
+// householdDaMovimentacao retorna o lar em que a movimentação foi lançada, ou zero no livro
+// pessoal; sql.ErrNoRows indica que ela já foi eliminada da lixeira.
+func householdDaMovimentacao(id int64) (int64, error) {
+	var householdID sql.NullInt64
+	query := fmt.Sprintf("SELECT household_id FROM %s WHERE id = ?", database.TableName)
+	err := database.GetDB().QueryRow(database.Rebind(query), id).Scan(&householdID)
+	return householdID.Int64, err
+}
+
+// podeAlterarLar indica se o papel do usuário no lar permite alterar o livro-caixa dele.
+func podeAlterarLar(householdID, userID int64) bool {
+	h, err := households.GetMembership(householdID, userID)
+	return err == nil && h.CanEdit()
+}
+
+// --- Handlers ---
+
+// ProventoPayload é o corpo da requisição de inclusão de proventos. Quantidade zero usa a posição
+// na data ex; ImpostoRetido ausente aplica 15% ao JCP; Conta vazia não cria a movimentação.
+type ProventoPayload struct {
+	Ticker        string   `json:"ticker" binding:"required"`
+	Tipo          string   `json:"tipo" binding:"required"`
+	DataEx        string   `json:"data_ex" binding:"required"`
+	DataPagamento string   `json:"data_pagamento" binding:"required"`
+	ValorPorCota  float64  `json:"valor_por_cota" binding:"required"`
+	Quantidade    int      `json:"quantidade"`
+	ImpostoRetido *float64 `json:"imposto_retido"`
+	Conta         string   `json:"conta"`
+}
+
+// GetProventosAPI lista os proventos (?ticker= filtra um ativo), a renda mensal (?meses=, padrão 12)
+// e o yield on cost dos últimos 12 meses.
+func GetProventosAPI(c *gin.Context) {
+	userID := c.MustGet("userID").(int64)
+	ticker := strings.ToUpper(strings.TrimSpace(c.Query("ticker")))
+	meses, err := strconv.Atoi(c.DefaultQuery("meses", "12"))
+	if err != nil || meses < 1 || meses > 120 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "O parâmetro 'meses' deve estar entre 1 e 120."})
+		return
+	}
+	proventos, err := listarProventos(userID, ticker)
+	if err != nil {
+		log.Printf("Erro ao listar os proventos do usuário %d: %v", userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao listar os proventos."})
+		return
+	}
+	posicoes, err := PosicoesDoLivro(userID)
+	if err != nil {
+		log.Printf("Erro ao calcular as posições do usuário %d: %v", userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao calcular as posições."})
+		return
+	}
+	mensal, yoc := ResumirProventos(proventos, posicoes, time.Now(), meses)
+	var total12M float64
+	for _, y := range yoc {
+		total12M += y.Proventos12M
+	}
+	c.JSON(http.StatusOK, gin.H{"proventos": proventos, "mensal": mensal, "yield_on_cost": yoc, "total_12m": arredondar(total12M)})
+}
+
+// AddProvento registra um provento e, opcionalmente, a entrada na conta escolhida.
+func AddProvento(c *gin.Context) {
+	userID := c.MustGet("userID").(int64)
+	var payload ProventoPayload
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Payload inválido: " + err.Error()})
+		return
+	}
+	p := Provento{Ticker: payload.Ticker, Tipo: payload.Tipo, DataEx: payload.DataEx, DataPagamento: payload.DataPagamento, ValorPorCota: payload.ValorPorCota, Quantidade: payload.Quantidade}
+	if payload.ImpostoRetido != nil {
+		p.ImpostoRetido = *payload.ImpostoRetido
+	}
+	if err := validarProvento(&p); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if strings.TrimSpace(payload.Conta) != "" && !podeAlterarLivro(c) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Seu papel neste lar é somente leitura: registre o provento sem a conta."})
+		return
+	}
+	p, err := RegistrarProvento(userID, householdDoPedido(c), p, payload.ImpostoRetido, strings.TrimSpace(payload.Conta))
+	if err != nil {
+		if errors.Is(err, ErrSemPosicao) || errors.Is(err, ErrRetencao) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Provento recusado: " + err.Error() + "."})
+			return
+		}
+		log.Printf("Erro ao registrar o provento do usuário %d: %v", userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao registrar o provento."})
+		return
+	}
+	middleware.SetAuditChange(c, entityProvento, strconv.FormatInt(p.ID, 10), nil, p)
+	c.JSON(http.StatusOK, gin.H{"message": "Provento registrado com sucesso!", "provento": p})
+}
+
+// DeleteProvento remove um provento; a entrada criada no livro-caixa vai para a lixeira.
+func DeleteProvento(c *gin.Context) {
+	userID := c.MustGet("userID").(int64)
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID de provento inválido."})
+		return
+	}
+	row := database.GetDB().QueryRow(database.Rebind("SELECT id, ticker, tipo_ativo, tipo, data_ex, data_pagamento, valor_por_cota, quantidade, imposto_retido, movimentacao_id FROM proventos WHERE user_id = ? AND id = ?"), userID, id)
+	p, err := scanProvento(row)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Provento não encontrado."})
+		return
+	}
+	// A movimentação vale pelo lar em que foi lançada, não pelo livro ativo na sessão.
+	if p.MovimentacaoID != nil {
+		householdID, err := householdDaMovimentacao(*p.MovimentacaoID)
+		switch {
+		case err == sql.ErrNoRows:
+			p.MovimentacaoID = nil
+		case err != nil:
+			log.Printf("Erro ao buscar a movimentação do provento %d: %v", id, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao excluir o provento."})
+			return
+		case householdID != 0 && !podeAlterarLar(householdID, userID):
+			c.JSON(http.StatusForbidden, gin.H{"error": "Seu papel neste lar é somente leitura."})
+			return
+		}
+	}
+	if err := ExcluirProvento(userID, p); err != nil {
+		log.Printf("Erro ao excluir o provento %d do usuário %d: %v", id, userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao excluir o provento."})
+		return
+	}
+	middleware.SetAuditChange(c, entityProvento, strconv.FormatInt(id, 10), p, nil)
+	c.JSON(http.StatusOK, gin.H{"message": "Provento excluído com sucesso!"})
+}
@@ -0,0 +1,145 @@
+package investimentos
+
+import (
+	"encoding/json"
+	"minhas_economias/database"
+	"minhas_economias/models"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestResumirProventos(t *testing.T) {
+	hoje := time.Date(2025, 3, 15, 0, 0, 0, 0, time.UTC)
+	proventos := []Provento{
+		{Ticker: "PETR4", Tipo: ProventoJCP, DataPagamento: "2024-03-10", ValorLiquido: 100}, // Fora da janela de 12 meses
+		{Ticker: "PETR4", Tipo: ProventoDividendo, DataPagamento: "2025-01-20", ValorLiquido: 60},
+		{Ticker: "PETR4", Tipo: ProventoJCP, DataPagamento: "2025-03-10", ValorLiquido: 42.5},
+		{Ticker: "MXRF11", Tipo: ProventoRendimento, DataPagamento: "2025-03-14", ValorLiquido: 10},
+		{Ticker: "MXRF11", Tipo: ProventoRendimento, DataPagamento: "2025-03-31", ValorLiquido: 10}, // Ainda não pago
+	}
+	posicoes := map[string]Posicao{"PETR4": {Ticker: "PETR4", Quantidade: 100, CustoTotal: 2500}}
+
+	mensal, yoc := ResumirProventos(proventos, posicoes, hoje, 3)
+	if len(mensal) != 3 || mensal[0].Mes != "2025-01" || mensal[1].Total != 0 || mensal[2].Mes != "2025-03" {
+		t.Fatalf("Meses inesperados: %+v", mensal)
+	}
+	if mensal[0].Dividendos != 60 || mensal[2].JCP != 42.5 || mensal[2].Rendimentos != 20 || mensal[2].Total != 62.5 {
+		t.Errorf("Totais mensais inesperados: %+v", mensal)
+	}
+	if len(yoc) != 2 || yoc[0].Ticker != "MXRF11" || yoc[0].Proventos12M != 10 || yoc[0].YieldOnCost != 0 {
+		t.Fatalf("Yield on cost inesperado: %+v", yoc)
+	}
+	if yoc[1].Proventos12M != 102.5 || yoc[1].CustoTotal != 2500 || yoc[1].YieldOnCost != 4.1 {
+		t.Errorf("Yield on cost de PETR4 inesperado: %+v", yoc[1])
+	}
+}
+
+func TestProventosCriaMovimentacao(t *testing.T) {
+	setupInvestimentosTestDB(t)
+	defer teardownInvestimentosTestDB()
+	router := createInvestimentosTestRouter()
+	db := database.GetDB()
+
+	// Sem quantidade informada, vale a posição de PETR4 (100); a retenção do JCP é de 15%.
+	payload := ProventoPayload{Ticker: "petr4", Tipo: "JCP", DataEx: "2025-01-10", DataPagamento: "2025-01-31", ValorPorCota: 0.5, Conta: "Corretora"}
+	w := performInvestimentosJSONRequest(router, "POST", "/investimentos/proventos", payload)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Esperado status 200, obtido %d: %s", w.Code, w.Body.String())
+	}
+	var resp struct{ Provento Provento }
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	p := resp.Provento
+	if p.Quantidade != 100 || p.ImpostoRetido != 7.5 || p.ValorLiquido != 42.5 || p.MovimentacaoID == nil {
+		t.Fatalf("Provento inesperado: %+v", p)
+	}
+	var valor float64
+	var categoria, conta string
+	db.QueryRow("SELECT valor, categoria, conta FROM movimentacoes WHERE id = ?", *p.MovimentacaoID).Scan(&valor, &categoria, &conta)
+	if valor != 42.5 || categoria != "Proventos" || conta != "Corretora" {
+		t.Errorf("Movimentação inesperada: valor %.2f, categoria %q, conta %q", valor, categoria, conta)
+	}
+
+	// Ativo fora da carteira exige a quantidade.
+	sem := ProventoPayload{Ticker: "ITSA4", Tipo: "DIVIDENDO", DataEx: "2025-01-10", DataPagamento: "2025-01-31", ValorPorCota: 0.1}
+	if w := performInvestimentosJSONRequest(router, "POST", "/investimentos/proventos", sem); w.Code != http.StatusBadRequest {
+		t.Errorf("Esperado status 400 para ativo fora da carteira, obtido %d", w.Code)
+	}
+
+	// Excluir o provento manda a movimentação para a lixeira.
+	if w := performInvestimentosJSONRequest(router, "DELETE", "/investimentos/proventos/"+strconv.FormatInt(p.ID, 10), nil); w.Code != http.StatusOK {
+		t.Fatalf("Esperado status 200 na exclusão, obtido %d", w.Code)
+	}
+	var naLixeira int
+	db.QueryRow("SELECT COUNT(*) FROM movimentacoes WHERE id = ? AND deleted_at IS NOT NULL", *p.MovimentacaoID).Scan(&naLixeira)
+	if naLixeira != 1 {
+		t.Error("Esperada a movimentação do provento na lixeira")
+	}
+}
+
+func TestProventoComContaLeitorDoLar(t *testing.T) {
+	setupInvestimentosTestDB(t)
+	defer teardownInvestimentosTestDB()
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(mockAuthMiddleware(), func(c *gin.Context) {
+		c.Set("householdID", int64(7))
+		c.Set("householdRole", models.RoleViewer)
+		c.Next()
+	})
+	r.POST("/investimentos/proventos", AddProvento)
+	db := database.GetDB()
+
+	// Leitor do lar ativo não pode lançar o provento no livro-caixa do lar.
+	payload := ProventoPayload{Ticker: "PETR4", Tipo: "DIVIDENDO", DataEx: "2025-01-10", DataPagamento: "2025-01-31", ValorPorCota: 0.5, Conta: "Corretora"}
+	if w := performInvestimentosJSONRequest(r, "POST", "/investimentos/proventos", payload); w.Code != http.StatusForbidden {
+		t.Fatalf("Esperado status 403, obtido %d: %s", w.Code, w.Body.String())
+	}
+	var movimentacoes, proventos int
+	db.QueryRow("SELECT COUNT(*) FROM movimentacoes").Scan(&movimentacoes)
+	db.QueryRow("SELECT COUNT(*) FROM proventos").Scan(&proventos)
+	if movimentacoes != 0 || proventos != 0 {
+		t.Errorf("Nada deveria ser gravado: %d movimentações, %d proventos", movimentacoes, proventos)
+	}
+
+	// Sem a conta, o provento é registrado só na carteira do usuário.
+	payload.Conta = ""
+	if w := performInvestimentosJSONRequest(r, "POST", "/investimentos/proventos", payload); w.Code != http.StatusOK {
+		t.Errorf("Esperado status 200 sem a conta, obtido %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestExcluirProventoDoLar(t *testing.T) {
+	setupInvestimentosTestDB(t)
+	defer teardownInvestimentosTestDB()
+	router := createInvestimentosTestRouter()
+	db := database.GetDB()
+
+	// O provento foi lançado no lar 7, do usuário 2, em que o usuário de teste é editor.
+	db.Exec("INSERT INTO households (id, name, owner_id, created_at) VALUES (7, 'Casa', 2, CURRENT_TIMESTAMP)")
+	db.Exec("INSERT INTO household_members (household_id, user_id, role, created_at) VALUES (7, 2, ?, CURRENT_TIMESTAMP), (7, ?, ?, CURRENT_TIMESTAMP)", models.RoleOwner, testUserID, models.RoleEditor)
+	p, err := RegistrarProvento(testUserID, 7, Provento{Ticker: "PETR4", Tipo: ProventoDividendo, DataEx: "2025-01-10", DataPagamento: "2025-01-31", ValorPorCota: 0.5}, nil, "Corretora")
+	if err != nil {
+		t.Fatalf("Erro ao registrar o provento: %v", err)
+	}
+	rota := "/investimentos/proventos/" + strconv.FormatInt(p.ID, 10)
+
+	// O papel que vale é o do lar da movimentação, mesmo com o livro pessoal ativo na sessão.
+	db.Exec("UPDATE household_members SET role = ? WHERE user_id = ?", models.RoleViewer, testUserID)
+	if w := performInvestimentosJSONRequest(router, "DELETE", rota, nil); w.Code != http.StatusForbidden {
+		t.Fatalf("Esperado status 403 para leitor do lar, obtido %d: %s", w.Code, w.Body.String())
+	}
+
+	db.Exec("UPDATE household_members SET role = ? WHERE user_id = ?", models.RoleEditor, testUserID)
+	if w := performInvestimentosJSONRequest(router, "DELETE", rota, nil); w.Code != http.StatusOK {
+		t.Fatalf("Esperado status 200 na exclusão, obtido %d: %s", w.Code, w.Body.String())
+	}
+	var naLixeira int
+	db.QueryRow("SELECT COUNT(*) FROM movimentacoes WHERE id = ? AND user_id = 2 AND deleted_at IS NOT NULL", *p.MovimentacaoID).Scan(&naLixeira)
+	if naLixeira != 1 {
+		t.Error("Esperada a movimentação do lar na lixeira")
+	}
+}
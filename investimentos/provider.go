@@ -0,0 +1,281 @@
+package investimentos
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"minhas_economias/middleware"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrNaoSuportado indica que o provedor não oferece aquele tipo de dado; a cadeia de
+// fallback passa para o próximo provedor sem contar como falha.
+var ErrNaoSuportado = errors.New("dado não oferecido por este provedor")
+
+// Origem identifica de onde e de quando veio uma cotação; é gravada no histórico de preços.
+type Origem struct {
+	Fonte string
+	Data  time.Time // Data do pregão; zero significa hoje
+}
+
+// DataPregao retorna a data da cotação no formato AAAA-MM-DD.
+func (o Origem) DataPregao() string {
+	if o.Data.IsZero() {
+		return time.Now().Format("2006-01-02")
+	}
+	return o.Data.Format("2006-01-02")
+}
+
+// Cotacao é um preço avulso (ativo internacional ou câmbio).
+type Cotacao struct {
+	Preco float64
+	Origem
+}
+
+// DadosAcao são os dados de mercado de uma ação (colunas da tabela do Fundamentus).
+type DadosAcao struct {
+	Origem
+	Cotacao  float64
+	PL       float64
+	PVP      float64
+	DivYield float64 // Fração (0,05 = 5%)
+}
+
+// Fundamentos deriva LPA e VPA da cotação e dos múltiplos, usado quando nenhum provedor
+// retorna os fundamentos diretamente.
+func (d DadosAcao) Fundamentos() (lpa, vpa float64) {
+	if d.PL != 0 {
+		lpa = d.Cotacao / d.PL
+	}
+	if d.PVP != 0 {
+		vpa = d.Cotacao / d.PVP
+	}
+	return lpa, vpa
+}
+
+// DadosFII são os dados de mercado de um fundo imobiliário.
+type DadosFII struct {
+	Origem
+	Segmento   string
+	Cotacao    float64
+	DivYield   float64 // Fração (0,05 = 5%)
+	PVP        float64
+	Vacancia   float64
+	NumImoveis int
+}
+
+// MarketDataProvider é uma fonte de dados de mercado. Cada implementação retorna
+// ErrNaoSuportado para os métodos que não cobre.
+type MarketDataProvider interface {
+	Nome() string
+	// CotacoesAcoes e CotacoesFIIs retornam a tabela completa do mercado, indexada pelo ticker.
+	CotacoesAcoes() (map[string]DadosAcao, error)
+	CotacoesFIIs() (map[string]DadosFII, error)
+	// Fundamentos retorna LPA e VPA de uma ação.
+	Fundamentos(ticker string) (lpa, vpa float64, err error)
+	// PrecosInternacionais retorna o preço em USD dos tickers encontrados.
+	PrecosInternacionais(tickers []string) (map[string]Cotacao, error)
+	// CotacaoDolar retorna quantos reais vale um dólar.
+	CotacaoDolar() (Cotacao, error)
+}
+
+var (
+	provider   MarketDataProvider
+	providerMu sync.RWMutex
+)
+
+// ProviderFromEnv monta a cadeia de provedores de MARKET_DATA_PROVIDERS, uma lista separada
+// por vírgulas tentada em ordem (padrão "web"). Nomes aceitos: fundamentus, statusinvest,
+// yahoo, frankfurter, web (os quatro anteriores) e fixtures (arquivos de
+// MARKET_DATA_FIXTURES_DIR, padrão "downloads").
+func ProviderFromEnv() (MarketDataProvider, error) {
+	spec := os.Getenv("MARKET_DATA_PROVIDERS")
+	if spec == "" {
+		spec = "web"
+	}
+	fixturesDir := os.Getenv("MARKET_DATA_FIXTURES_DIR")
+	if fixturesDir == "" {
+		fixturesDir = "downloads"
+	}
+	var chain []MarketDataProvider
+	for _, name := range strings.Split(spec, ",") {
+		switch name = strings.ToLower(strings.TrimSpace(name)); name {
+		case "web":
+			chain = append(chain, StatusInvestProvider{}, FundamentusProvider{}, YahooProvider{}, FrankfurterProvider{})
+		case "fundamentus":
+			chain = append(chain, FundamentusProvider{})
+		case "statusinvest":
+			chain = append(chain, StatusInvestProvider{})
+		case "yahoo":
+			chain = append(chain, YahooProvider{})
+		case "frankfurter":
+			chain = append(chain, FrankfurterProvider{})
+		case "fixtures":
+			chain = append(chain, FixtureProvider{Dir: fixturesDir})
+		case "":
+		default:
+			return nil, fmt.Errorf("provedor de dados de mercado '%s' desconhecido em MARKET_DATA_PROVIDERS", name)
+		}
+	}
+	if len(chain) == 1 {
+		return chain[0], nil
+	}
+	return FallbackProvider(chain), nil
+}
+
+// InitMarketData configura os provedores globais (dados de mercado e criptoativos) e o tamanho
+// do cache de cotações a partir das variáveis de ambiente.
+func InitMarketData() error {
+	SetCacheSize(CacheSizeFromEnv())
+	p, err := ProviderFromEnv()
+	if err != nil {
+		return err
+	}
+	SetProvider(p)
+	log.Printf("[MarketData] Provedor de dados de mercado: %s", p.Nome())
+	cp, err := CriptoProviderFromEnv()
+	if err != nil {
+		return err
+	}
+	SetCriptoProvider(cp)
+	log.Printf("[MarketData] Provedor de preços de criptoativos: %s", cp.Nome())
+	return nil
+}
+
+// SetProvider substitui o provedor global e limpa o cache de cotações (útil em testes).
+func SetProvider(p MarketDataProvider) {
+	providerMu.Lock()
+	provider = p
+	providerMu.Unlock()
+	limparCache("")
+}
+
+// Provider retorna o provedor global; sem configuração, usa os provedores web.
+func Provider() MarketDataProvider {
+	providerMu.RLock()
+	p := provider
+	providerMu.RUnlock()
+	if p != nil {
+		return p
+	}
+	return FallbackProvider{StatusInvestProvider{}, FundamentusProvider{}, YahooProvider{}, FrankfurterProvider{}}
+}
+
+// FallbackProvider tenta cada provedor em ordem até um deles responder. Nos preços
+// internacionais, os tickers que faltaram são pedidos ao provedor seguinte.
+type FallbackProvider []MarketDataProvider
+
+func (f FallbackProvider) Nome() string { return nomesEmCadeia(f) }
+
+// provedorNomeado é a parte comum a MarketDataProvider e CriptoProvider.
+type provedorNomeado interface {
+	Nome() string
+}
+
+// nomesEmCadeia descreve uma cadeia de provedores na ordem de consulta.
+func nomesEmCadeia[P provedorNomeado](provedores []P) string {
+	nomes := make([]string, len(provedores))
+	for i, p := range provedores {
+		nomes[i] = p.Nome()
+	}
+	return strings.Join(nomes, " -> ")
+}
+
+// precosEmCadeia pede a cada provedor, em ordem, as chaves que os anteriores não retornaram,
+// registrando as falhas. Só falha se nenhum provedor retornou preço algum.
+func precosEmCadeia[P provedorNomeado](provedores []P, dado string, chaves []string, buscar func(P, []string) (map[string]Cotacao, error)) (map[string]Cotacao, error) {
+	result := make(map[string]Cotacao)
+	faltando := chaves
+	var errs []string
+	for _, p := range provedores {
+		if len(faltando) == 0 {
+			break
+		}
+		precos, err := buscar(p, faltando)
+		if errors.Is(err, ErrNaoSuportado) {
+			continue
+		}
+		if err != nil {
+			middleware.ScrapingErrors.WithLabelValues(p.Nome()).Inc()
+			log.Printf("[MarketData] %s falhou em %s: %v", p.Nome(), dado, err)
+			errs = append(errs, fmt.Sprintf("%s: %v", p.Nome(), err))
+		}
+		var restantes []string
+		for _, k := range faltando {
+			if preco, ok := precos[k]; ok {
+				result[k] = preco
+			} else {
+				restantes = append(restantes, k)
+			}
+		}
+		faltando = restantes
+	}
+	if len(result) == 0 && len(errs) > 0 {
+		return nil, fmt.Errorf("nenhum provedor retornou %s (%s)", dado, strings.Join(errs, "; "))
+	}
+	return result, nil
+}
+
+// try executa fn em cada provedor até o primeiro sucesso, registrando as falhas.
+func (f FallbackProvider) try(dado string, fn func(MarketDataProvider) error) error {
+	var errs []string
+	for _, p := range f {
+		err := fn(p)
+		if err == nil {
+			return nil
+		}
+		if errors.Is(err, ErrNaoSuportado) {
+			continue
+		}
+		middleware.ScrapingErrors.WithLabelValues(p.Nome()).Inc()
+		log.Printf("[MarketData] %s falhou em %s: %v", p.Nome(), dado, err)
+		errs = append(errs, fmt.Sprintf("%s: %v", p.Nome(), err))
+	}
+	if len(errs) == 0 {
+		return fmt.Errorf("%s: %w", dado, ErrNaoSuportado)
+	}
+	return fmt.Errorf("nenhum provedor retornou %s (%s)", dado, strings.Join(errs, "; "))
+}
+
+func (f FallbackProvider) CotacoesAcoes() (map[string]DadosAcao, error) {
+	var result map[string]DadosAcao
+	err := f.try("cotações de ações", func(p MarketDataProvider) (err error) {
+		result, err = p.CotacoesAcoes()
+		return err
+	})
+	return result, err
+}
+
+func (f FallbackProvider) CotacoesFIIs() (map[string]DadosFII, error) {
+	var result map[string]DadosFII
+	err := f.try("cotações de FIIs", func(p MarketDataProvider) (err error) {
+		result, err = p.CotacoesFIIs()
+		return err
+	})
+	return result, err
+}
+
+func (f FallbackProvider) Fundamentos(ticker string) (float64, float64, error) {
+	var lpa, vpa float64
+	err := f.try("fundamentos de "+ticker, func(p MarketDataProvider) (err error) {
+		lpa, vpa, err = p.Fundamentos(ticker)
+		return err
+	})
+	return lpa, vpa, err
+}
+
+func (f FallbackProvider) PrecosInternacionais(tickers []string) (map[string]Cotacao, error) {
+	return precosEmCadeia(f, "preços internacionais", tickers, MarketDataProvider.PrecosInternacionais)
+}
+
+func (f FallbackProvider) CotacaoDolar() (Cotacao, error) {
+	var taxa Cotacao
+	err := f.try("cotação do dólar", func(p MarketDataProvider) (err error) {
+		taxa, err = p.CotacaoDolar()
+		return err
+	})
+	return taxa, err
+}
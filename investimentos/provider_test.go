@@ -0,0 +1,74 @@
+package investimentos
+
+import (
+	"errors"
+	"testing"
+)
+
+// falhaProvider simula uma fonte fora do ar.
+type falhaProvider struct{}
+
+func (falhaProvider) Nome() string { return "falha" }
+
+func (falhaProvider) CotacoesAcoes() (map[string]DadosAcao, error) {
+	return nil, errors.New("fora do ar")
+}
+
+func (falhaProvider) CotacoesFIIs() (map[string]DadosFII, error) {
+	return nil, errors.New("fora do ar")
+}
+
+func (falhaProvider) Fundamentos(string) (float64, float64, error) {
+	return 0, 0, errors.New("fora do ar")
+}
+
+func (falhaProvider) PrecosInternacionais([]string) (map[string]Cotacao, error) {
+	return map[string]Cotacao{"AAPL": {Preco: 200}}, errors.New("resposta parcial")
+}
+
+func (falhaProvider) CotacaoDolar() (Cotacao, error) { return Cotacao{}, errors.New("fora do ar") }
+
+func TestFallbackProvider(t *testing.T) {
+	chain := FallbackProvider{falhaProvider{}, FixtureProvider{Dir: "../downloads"}}
+
+	acoes, err := chain.CotacoesAcoes()
+	if err != nil || acoes["PETR4"].Cotacao != 32.52 {
+		t.Fatalf("Esperado fallback para as fixtures, obtido %+v, %v", acoes["PETR4"], err)
+	}
+	// A tabela de FIIs é indexada pelo ticker e mantém os acentos da página em ISO-8859-1.
+	fiis, err := chain.CotacoesFIIs()
+	if err != nil || fiis["MXRF11"].NumImoveis != 2 {
+		t.Errorf("Dados de MXRF11 inesperados: %+v, %v", fiis["MXRF11"], err)
+	}
+
+	// O primeiro provedor achou AAPL; VOO vem do seguinte e o ticker desconhecido fica de fora.
+	precos, err := chain.PrecosInternacionais([]string{"AAPL", "VOO", "XXXX"})
+	if err != nil || precos["AAPL"].Preco != 200 || precos["VOO"].Preco != 572.35 || len(precos) != 2 {
+		t.Errorf("Preços internacionais inesperados: %v, %v", precos, err)
+	}
+
+	// Nenhum provedor da cadeia oferece fundamentos com sucesso.
+	if _, _, err := chain.Fundamentos("PETR4"); err == nil {
+		t.Error("Esperado erro quando nenhum provedor retorna fundamentos")
+	}
+	lpa, vpa := acoes["PETR4"].Fundamentos()
+	if lpa <= 0 || vpa <= 0 {
+		t.Errorf("LPA/VPA derivados inválidos: %v, %v", lpa, vpa)
+	}
+
+	if _, err := (FixtureProvider{Dir: t.TempDir()}).CotacoesAcoes(); !errors.Is(err, ErrNaoSuportado) {
+		t.Errorf("Diretório sem fixtures deveria retornar ErrNaoSuportado, obtido %v", err)
+	}
+}
+
+func TestProviderFromEnv(t *testing.T) {
+	t.Setenv("MARKET_DATA_PROVIDERS", "fundamentus, fixtures")
+	p, err := ProviderFromEnv()
+	if err != nil || p.Nome() != "fundamentus -> fixtures" {
+		t.Errorf("Cadeia inesperada: %v, %v", p, err)
+	}
+	t.Setenv("MARKET_DATA_PROVIDERS", "bolsa")
+	if _, err := ProviderFromEnv(); err == nil {
+		t.Error("Esperado erro para provedor desconhecido")
+	}
+}
@@ -0,0 +1,437 @@
+package investimentos
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"minhas_economias/database"
+	"minhas_economias/middleware"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Indexadores dos títulos de renda fixa. A taxa do título é interpretada conforme o indexador:
+// PRE em % ao ano, CDI em % do CDI, IPCA e SELIC como spread em % ao ano sobre o índice.
+const (
+	IndexadorPre   = "PRE"
+	IndexadorCDI   = "CDI"
+	IndexadorIPCA  = "IPCA"
+	IndexadorSELIC = "SELIC"
+)
+
+// Liquidez dos títulos.
+const (
+	LiquidezDiaria     = "DIARIA"
+	LiquidezVencimento = "VENCIMENTO"
+)
+
+// entityRendaFixa é o tipo de entidade dos títulos de renda fixa no log de auditoria.
+const entityRendaFixa = "renda_fixa"
+
+// tiposRendaFixa lista os tipos aceitos; os marcados como true são isentos de IR para pessoa física.
+var tiposRendaFixa = map[string]bool{
+	"CDB":       false,
+	"LCI":       true,
+	"LCA":       true,
+	"CRI":       true,
+	"CRA":       true,
+	"TESOURO":   false,
+	"DEBENTURE": false,
+	"OUTRO":     false,
+}
+
+// indexadorSerie é a série de índices usada por cada indexador pós-fixado.
+var indexadorSerie = map[string]string{
+	IndexadorPre:   "",
+	IndexadorCDI:   SerieCDI,
+	IndexadorIPCA:  SerieIPCA,
+	IndexadorSELIC: SerieSELIC,
+}
+
+// TituloRendaFixa é uma aplicação em CDB, LCI/LCA, Tesouro Direto e similares.
+type TituloRendaFixa struct {
+	ID            int64   `json:"id"`
+	Nome          string  `json:"nome"`
+	Tipo          string  `json:"tipo"` // CDB, LCI, LCA, CRI, CRA, TESOURO, DEBENTURE ou OUTRO
+	Emissor       string  `json:"emissor"`
+	Indexador     string  `json:"indexador"` // PRE, CDI, IPCA ou SELIC
+	Taxa          float64 `json:"taxa"`
+	ValorAplicado float64 `json:"valor_aplicado"`
+	DataAplicacao string  `json:"data_aplicacao"` // AAAA-MM-DD
+	Vencimento    string  `json:"vencimento"`     // AAAA-MM-DD
+	Liquidez      string  `json:"liquidez"`       // DIARIA ou VENCIMENTO
+}
+
+// AvaliacaoRendaFixa é o valor do título na curva (marcação pelo modelo, não a mercado) na data
+// de avaliação e a projeção até o vencimento, com o IR pela tabela regressiva.
+type AvaliacaoRendaFixa struct {
+	TituloRendaFixa
+	DataAvaliacao          string  `json:"data_avaliacao"`
+	Isento                 bool    `json:"isento"`
+	ValorBruto             float64 `json:"valor_bruto"`
+	Rendimento             float64 `json:"rendimento"`
+	AliquotaIR             float64 `json:"aliquota_ir"`
+	ImpostoEstimado        float64 `json:"imposto_estimado"`
+	ValorLiquido           float64 `json:"valor_liquido"`
+	ValorBrutoVencimento   float64 `json:"valor_bruto_vencimento"`
+	AliquotaIRVencimento   float64 `json:"aliquota_ir_vencimento"`
+	ImpostoVencimento      float64 `json:"imposto_vencimento"`
+	ValorLiquidoVencimento float64 `json:"valor_liquido_vencimento"`
+	Aviso                  string  `json:"aviso,omitempty"` // Série de índices sem valores importados
+}
+
+// aliquotaRegressiva retorna a alíquota de IR da renda fixa pelo prazo da aplicação em dias corridos.
+func aliquotaRegressiva(dias int) float64 {
+	switch {
+	case dias <= 180:
+		return 0.225
+	case dias <= 360:
+		return 0.20
+	case dias <= 720:
+		return 0.175
+	}
+	return 0.15
+}
+
+// diaUtil considera úteis os dias de segunda a sexta (feriados não são descontados).
+func diaUtil(d time.Time) bool {
+	return d.Weekday() != time.Saturday && d.Weekday() != time.Sunday
+}
+
+// diasUteis conta os dias úteis em [de, ate).
+func diasUteis(de, ate time.Time) int {
+	n := 0
+	for d := de; d.Before(ate); d = d.AddDate(0, 0, 1) {
+		if diaUtil(d) {
+			n++
+		}
+	}
+	return n
+}
+
+// fatorCorrecao calcula o fator acumulado do título da aplicação até a data. O índice de cada dia
+// útil (ou mês, no IPCA) sem valor importado é o último conhecido, o que também projeta os
+// pós-fixados até o vencimento com a taxa atual.
+func fatorCorrecao(t TituloRendaFixa, series map[string]*SerieIndice, ate time.Time) (float64, error) {
+	inicio, err := time.Parse("2006-01-02", t.DataAplicacao)
+	if err != nil {
+		return 1, err
+	}
+	if !ate.After(inicio) {
+		return 1, nil
+	}
+	serie := series[indexadorSerie[t.Indexador]]
+	if t.Indexador != IndexadorPre && (serie == nil || len(serie.Indices) == 0) {
+		return 1, fmt.Errorf("a série %s não tem valores importados; o título está pelo valor aplicado", indexadorSerie[t.Indexador])
+	}
+	spread := math.Pow(1+t.Taxa/100, float64(diasUteis(inicio, ate))/252)
+
+	switch t.Indexador {
+	case IndexadorPre:
+		return spread, nil
+	case IndexadorCDI, IndexadorSELIC:
+		fator := 1.0
+		for d := inicio; d.Before(ate); d = d.AddDate(0, 0, 1) {
+			if !diaUtil(d) {
+				continue
+			}
+			taxaDia, _ := serie.valorEm(d.Format("2006-01-02"))
+			if t.Indexador == IndexadorCDI {
+				fator *= 1 + taxaDia/100*t.Taxa/100
+			} else {
+				fator *= 1 + taxaDia/100
+			}
+		}
+		if t.Indexador == IndexadorSELIC {
+			fator *= spread
+		}
+		return fator, nil
+	case IndexadorIPCA:
+		// Variação mensal pro rata pelos dias corridos do mês em que o título esteve aplicado.
+		fator := 1.0
+		for mes := time.Date(inicio.Year(), inicio.Month(), 1, 0, 0, 0, 0, time.UTC); mes.Before(ate); mes = mes.AddDate(0, 1, 0) {
+			proximo := mes.AddDate(0, 1, 0)
+			de, atePeriodo := mes, proximo
+			if inicio.After(de) {
+				de = inicio
+			}
+			if ate.Before(atePeriodo) {
+				atePeriodo = ate
+			}
+			variacao, _ := serie.valorEm(mes.Format("2006-01-02"))
+			fracao := atePeriodo.Sub(de).Hours() / proximo.Sub(mes).Hours()
+			fator *= math.Pow(1+variacao/100, fracao)
+		}
+		return fator * spread, nil
+	}
+	return 1, fmt.Errorf("indexador '%s' inválido", t.Indexador)
+}
+
+// AvaliarTitulo calcula o valor do título na data (limitada ao vencimento) e no vencimento,
+// descontando o IR estimado pela tabela regressiva nos títulos não isentos. IOF não é considerado.
+func AvaliarTitulo(t TituloRendaFixa, series map[string]*SerieIndice, data time.Time) AvaliacaoRendaFixa {
+	a := AvaliacaoRendaFixa{TituloRendaFixa: t, Isento: tiposRendaFixa[t.Tipo]}
+	inicio, _ := time.Parse("2006-01-02", t.DataAplicacao)
+	vencimento, _ := time.Parse("2006-01-02", t.Vencimento)
+	data = time.Date(data.Year(), data.Month(), data.Day(), 0, 0, 0, 0, time.UTC)
+	if data.After(vencimento) {
+		data = vencimento
+	}
+	a.DataAvaliacao = data.Format("2006-01-02")
+
+	// liquido aplica o fator e desconta o IR pelo prazo até a data.
+	liquido := func(fator float64, ate time.Time) (bruto, aliquota, imposto float64) {
+		bruto = arredondar(t.ValorAplicado * fator)
+		if rendimento := bruto - t.ValorAplicado; !a.Isento && rendimento > 0 {
+			aliquota = aliquotaRegressiva(int(ate.Sub(inicio).Hours() / 24))
+			imposto = arredondar(rendimento * aliquota)
+		}
+		return bruto, aliquota, imposto
+	}
+
+	fator, err := fatorCorrecao(t, series, data)
+	if err != nil {
+		a.Aviso = err.Error()
+	}
+	a.ValorBruto, a.AliquotaIR, a.ImpostoEstimado = liquido(fator, data)
+	a.Rendimento = arredondar(a.ValorBruto - t.ValorAplicado)
+	a.ValorLiquido = arredondar(a.ValorBruto - a.ImpostoEstimado)
+
+	fator, _ = fatorCorrecao(t, series, vencimento)
+	a.ValorBrutoVencimento, a.AliquotaIRVencimento, a.ImpostoVencimento = liquido(fator, vencimento)
+	a.ValorLiquidoVencimento = arredondar(a.ValorBrutoVencimento - a.ImpostoVencimento)
+	return a
+}
+
+// validarTitulo normaliza e confere os campos informados pelo usuário.
+func validarTitulo(t *TituloRendaFixa) error {
+	t.Tipo = strings.ToUpper(strings.TrimSpace(t.Tipo))
+	t.Indexador = strings.ToUpper(strings.TrimSpace(t.Indexador))
+	t.Liquidez = strings.ToUpper(strings.TrimSpace(t.Liquidez))
+	t.Emissor = strings.TrimSpace(t.Emissor)
+	t.Nome = strings.TrimSpace(t.Nome)
+	if t.Liquidez == "" {
+		t.Liquidez = LiquidezVencimento
+	}
+	if _, ok := tiposRendaFixa[t.Tipo]; !ok {
+		return errors.New("Tipo inválido. Use CDB, LCI, LCA, CRI, CRA, TESOURO, DEBENTURE ou OUTRO.")
+	}
+	if _, ok := indexadorSerie[t.Indexador]; !ok {
+		return errors.New("Indexador inválido. Use PRE, CDI, IPCA ou SELIC.")
+	}
+	if t.Liquidez != LiquidezDiaria && t.Liquidez != LiquidezVencimento {
+		return errors.New("Liquidez inválida. Use DIARIA ou VENCIMENTO.")
+	}
+	switch {
+	case t.ValorAplicado <= 0 || math.IsNaN(t.ValorAplicado):
+		return errors.New("O valor aplicado deve ser positivo.")
+	case t.Taxa < 0 || math.IsNaN(t.Taxa) || (t.Indexador == IndexadorCDI && t.Taxa == 0):
+		return errors.New("Taxa inválida.")
+	}
+	aplicacao, err := time.Parse("2006-01-02", t.DataAplicacao)
+	if err != nil {
+		return errors.New("A data de aplicação deve estar no formato AAAA-MM-DD.")
+	}
+	vencimento, err := time.Parse("2006-01-02", t.Vencimento)
+	if err != nil {
+		return errors.New("O vencimento deve estar no formato AAAA-MM-DD.")
+	}
+	if !vencimento.After(aplicacao) {
+		return errors.New("O vencimento deve ser posterior à data de aplicação.")
+	}
+	if t.Nome == "" {
+		t.Nome = strings.TrimSpace(t.Tipo + " " + t.Emissor)
+	}
+	return nil
+}
+
+const selectRendaFixa = "SELECT id, nome, tipo, COALESCE(emissor, ''), indexador, taxa, valor_aplicado, data_aplicacao, vencimento, liquidez FROM renda_fixa"
+
+func scanTitulo(s scanner) (TituloRendaFixa, error) {
+	var t TituloRendaFixa
+	var rawAplicacao, rawVencimento interface{}
+	err := s.Scan(&t.ID, &t.Nome, &t.Tipo, &t.Emissor, &t.Indexador, &t.Taxa, &t.ValorAplicado, &rawAplicacao, &rawVencimento, &t.Liquidez)
+	t.DataAplicacao, t.Vencimento = dateString(rawAplicacao), dateString(rawVencimento)
+	return t, err
+}
+
+// listarRendaFixa retorna os títulos do usuário pela data de vencimento.
+func listarRendaFixa(userID int64) ([]TituloRendaFixa, error) {
+	rows, err := database.GetDB().Query(database.Rebind(selectRendaFixa+" WHERE user_id = ? ORDER BY vencimento, id"), userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	titulos := []TituloRendaFixa{}
+	for rows.Next() {
+		t, err := scanTitulo(rows)
+		if err != nil {
+			return nil, err
+		}
+		titulos = append(titulos, t)
+	}
+	return titulos, rows.Err()
+}
+
+// carregarTitulo retorna o título do usuário, ou nil se ele não existir.
+func carregarTitulo(userID, id int64) *TituloRendaFixa {
+	t, err := scanTitulo(database.GetDB().QueryRow(database.Rebind(selectRendaFixa+" WHERE user_id = ? AND id = ?"), userID, id))
+	if err != nil {
+		return nil
+	}
+	return &t
+}
+
+func inserirTitulo(userID int64, t TituloRendaFixa) (int64, error) {
+	query := `INSERT INTO renda_fixa (user_id, nome, tipo, emissor, indexador, taxa, valor_aplicado, data_aplicacao, vencimento, liquidez, created_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+	args := []interface{}{userID, t.Nome, t.Tipo, t.Emissor, t.Indexador, t.Taxa, t.ValorAplicado, t.DataAplicacao, t.Vencimento, t.Liquidez, time.Now()}
+	db := database.GetDB()
+	if database.DriverName == "postgres" {
+		var id int64
+		err := db.QueryRow(database.Rebind(query+" RETURNING id"), args...).Scan(&id)
+		return id, err
+	}
+	res, err := db.Exec(query, args...)
+	if err != nil {
+		return 0, err
+	}
+	return res.LastInsertId()
+}
+
+// --- Handlers ---
+
+// GetRendaFixaAPI avalia os títulos do usuário na data (?data=AAAA-MM-DD, padrão hoje) e informa
+// até quando cada série de índices foi importada.
+func GetRendaFixaAPI(c *gin.Context) {
+	userID := c.MustGet("userID").(int64)
+	data := time.Now()
+	if d := c.Query("data"); d != "" {
+		var err error
+		if data, err = time.Parse("2006-01-02", d); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "A data deve estar no formato AAAA-MM-DD."})
+			return
+		}
+	}
+	titulos, err := listarRendaFixa(userID)
+	if err != nil {
+		log.Printf("Erro ao listar a renda fixa do usuário %d: %v", userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao listar os títulos de renda fixa."})
+		return
+	}
+	series, err := CarregarSeries()
+	if err != nil {
+		log.Printf("Erro ao carregar as séries de índices: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao carregar as séries de índices."})
+		return
+	}
+
+	avaliacoes := make([]AvaliacaoRendaFixa, 0, len(titulos))
+	var aplicado, bruto, liquido, liquidoVencimento float64
+	for _, t := range titulos {
+		a := AvaliarTitulo(t, series, data)
+		avaliacoes = append(avaliacoes, a)
+		aplicado += a.ValorAplicado
+		bruto += a.ValorBruto
+		liquido += a.ValorLiquido
+		liquidoVencimento += a.ValorLiquidoVencimento
+	}
+	indices := gin.H{}
+	for _, nome := range seriesIndices {
+		indices[nome] = series[nome].ultimaData()
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"titulos": avaliacoes,
+		"totais": gin.H{
+			"valor_aplicado":           arredondar(aplicado),
+			"valor_bruto":              arredondar(bruto),
+			"valor_liquido":            arredondar(liquido),
+			"valor_liquido_vencimento": arredondar(liquidoVencimento),
+		},
+		"indices": indices,
+	})
+}
+
+// AddRendaFixa cadastra um título de renda fixa.
+func AddRendaFixa(c *gin.Context) {
+	userID := c.MustGet("userID").(int64)
+	var t TituloRendaFixa
+	if err := c.ShouldBindJSON(&t); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Payload inválido: " + err.Error()})
+		return
+	}
+	if err := validarTitulo(&t); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	id, err := inserirTitulo(userID, t)
+	if err != nil {
+		log.Printf("Erro ao cadastrar o título de renda fixa do usuário %d: %v", userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao cadastrar o título."})
+		return
+	}
+	t.ID = id
+	middleware.SetAuditChange(c, entityRendaFixa, strconv.FormatInt(id, 10), nil, t)
+	middleware.InvestmentsCreated.WithLabelValues("renda_fixa").Inc()
+	c.JSON(http.StatusOK, gin.H{"message": "Título cadastrado com sucesso!", "titulo": t})
+}
+
+// UpdateRendaFixa altera todos os campos de um título.
+func UpdateRendaFixa(c *gin.Context) {
+	userID := c.MustGet("userID").(int64)
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID de título inválido."})
+		return
+	}
+	atual := carregarTitulo(userID, id)
+	if atual == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Título não encontrado."})
+		return
+	}
+	var t TituloRendaFixa
+	if err := c.ShouldBindJSON(&t); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Payload inválido: " + err.Error()})
+		return
+	}
+	if err := validarTitulo(&t); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	t.ID = id
+	_, err = database.GetDB().Exec(database.Rebind("UPDATE renda_fixa SET nome = ?, tipo = ?, emissor = ?, indexador = ?, taxa = ?, valor_aplicado = ?, data_aplicacao = ?, vencimento = ?, liquidez = ? WHERE user_id = ? AND id = ?"),
+		t.Nome, t.Tipo, t.Emissor, t.Indexador, t.Taxa, t.ValorAplicado, t.DataAplicacao, t.Vencimento, t.Liquidez, userID, id)
+	if err != nil {
+		log.Printf("Erro ao alterar o título %d do usuário %d: %v", id, userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao alterar o título."})
+		return
+	}
+	middleware.SetAuditChange(c, entityRendaFixa, strconv.FormatInt(id, 10), atual, t)
+	c.JSON(http.StatusOK, gin.H{"message": "Título atualizado com sucesso!", "titulo": t})
+}
+
+// DeleteRendaFixa remove um título (resgate ou cadastro incorreto).
+func DeleteRendaFixa(c *gin.Context) {
+	userID := c.MustGet("userID").(int64)
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID de título inválido."})
+		return
+	}
+	atual := carregarTitulo(userID, id)
+	if atual == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Título não encontrado."})
+		return
+	}
+	if _, err := database.GetDB().Exec(database.Rebind("DELETE FROM renda_fixa WHERE user_id = ? AND id = ?"), userID, id); err != nil {
+		log.Printf("Erro ao excluir o título %d do usuário %d: %v", id, userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao excluir o título."})
+		return
+	}
+	middleware.SetAuditChange(c, entityRendaFixa, strconv.FormatInt(id, 10), atual, nil)
+	c.JSON(http.StatusOK, gin.H{"message": "Título excluído com sucesso!"})
+}
@@ -0,0 +1,84 @@
+package investimentos
+
+import (
+	"encoding/json"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestAvaliarTitulo(t *testing.T) {
+	series := map[string]*SerieIndice{
+		SerieCDI:  {Nome: SerieCDI, Indices: []Indice{{Data: "2025-01-01", Valor: 0.05}}},
+		SerieIPCA: {Nome: SerieIPCA, Indices: []Indice{{Data: "2025-01-01", Valor: 0.5}, {Data: "2025-02-01", Valor: 0.3}}},
+	}
+	quase := func(a, b float64) bool { return math.Abs(a-b) < 0.01 }
+	data := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+	du := float64(diasUteis(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC), data))
+
+	pre := AvaliarTitulo(TituloRendaFixa{Tipo: "CDB", Indexador: IndexadorPre, Taxa: 10, ValorAplicado: 1000, DataAplicacao: "2025-01-01", Vencimento: "2027-01-01"}, series, data)
+	if !quase(pre.ValorBruto, 1000*math.Pow(1.10, du/252)) || pre.AliquotaIR != 0.225 || !quase(pre.ImpostoEstimado, pre.Rendimento*0.225) {
+		t.Errorf("Prefixado inesperado: %+v", pre)
+	}
+	// No vencimento (730 dias corridos), a alíquota é de 15%.
+	if pre.AliquotaIRVencimento != 0.15 || pre.ValorLiquidoVencimento <= pre.ValorLiquido {
+		t.Errorf("Projeção do prefixado inesperada: %+v", pre)
+	}
+
+	// 110% do CDI, com o último CDI conhecido valendo para todos os dias úteis; LCI é isenta.
+	lci := AvaliarTitulo(TituloRendaFixa{Tipo: "LCI", Indexador: IndexadorCDI, Taxa: 110, ValorAplicado: 1000, DataAplicacao: "2025-01-01", Vencimento: "2026-01-01"}, series, data)
+	if !quase(lci.ValorBruto, 1000*math.Pow(1+0.0005*1.1, du)) || !lci.Isento || lci.ImpostoEstimado != 0 || lci.ValorLiquido != lci.ValorBruto {
+		t.Errorf("LCI inesperada: %+v", lci)
+	}
+
+	ipca := AvaliarTitulo(TituloRendaFixa{Tipo: "TESOURO", Indexador: IndexadorIPCA, Taxa: 6, ValorAplicado: 1000, DataAplicacao: "2025-01-01", Vencimento: "2035-05-15"}, series, data)
+	if !quase(ipca.ValorBruto, 1000*1.005*1.003*math.Pow(1.06, du/252)) {
+		t.Errorf("IPCA+ inesperado: %+v", ipca)
+	}
+
+	// Sem a série importada, o título fica pelo valor aplicado, com aviso.
+	selic := AvaliarTitulo(TituloRendaFixa{Tipo: "TESOURO", Indexador: IndexadorSELIC, ValorAplicado: 1000, DataAplicacao: "2025-01-01", Vencimento: "2029-03-01"}, series, data)
+	if selic.ValorBruto != 1000 || selic.Aviso == "" {
+		t.Errorf("Esperado valor aplicado e aviso sem a série SELIC: %+v", selic)
+	}
+}
+
+func TestRendaFixaAPI(t *testing.T) {
+	setupInvestimentosTestDB(t)
+	defer teardownInvestimentosTestDB()
+	router := createInvestimentosTestRouter()
+
+	if err := RegistrarIndices("cdi", []Indice{{Data: "2025-01-02", Valor: 0.05}}); err != nil {
+		t.Fatalf("Erro ao registrar o CDI: %v", err)
+	}
+	titulo := TituloRendaFixa{Tipo: "cdb", Emissor: "Banco X", Indexador: "cdi", Taxa: 100, ValorAplicado: 5000, DataAplicacao: "2025-01-02", Vencimento: "2027-01-04", Liquidez: "diaria"}
+	w := performInvestimentosJSONRequest(router, "POST", "/investimentos/renda-fixa", titulo)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Esperado status 200, obtido %d: %s", w.Code, w.Body.String())
+	}
+
+	invalido := titulo
+	invalido.Vencimento = "2024-12-31"
+	if w := performInvestimentosJSONRequest(router, "POST", "/investimentos/renda-fixa", invalido); w.Code != http.StatusBadRequest {
+		t.Errorf("Esperado status 400 para vencimento anterior à aplicação, obtido %d", w.Code)
+	}
+
+	req, _ := http.NewRequest("GET", "/api/investimentos/renda-fixa?data=2025-01-09", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	var resp struct {
+		Titulos []AvaliacaoRendaFixa `json:"titulos"`
+		Indices map[string]string    `json:"indices"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	if w.Code != http.StatusOK || len(resp.Titulos) != 1 || resp.Indices[SerieCDI] != "2025-01-02" {
+		t.Fatalf("Resposta inesperada (%d): %s", w.Code, w.Body.String())
+	}
+	// 5 dias úteis de 2/1 a 9/1 a 0,05% ao dia.
+	a := resp.Titulos[0]
+	if a.Nome != "CDB Banco X" || a.ValorBruto != math.Round(5000*math.Pow(1.0005, 5)*100)/100 || a.AliquotaIR != 0.225 {
+		t.Errorf("Avaliação inesperada: %+v", a)
+	}
+}
@@ -3,65 +3,62 @@ package investimentos
 import (
 	"log"
 	"minhas_economias/database"
-	"strconv"
 	"strings"
 	"sync"
-	"time"
 )
 
-// --- Estrutura do Cache (sem alterações) ---
-type CacheItem struct {
-	Data      interface{}
-	Timestamp time.Time
-}
-
-var (
-	cache      = make(map[string]CacheItem)
-	cacheMutex = &sync.RWMutex{}
-	cacheTTL   = 15 * time.Minute
-)
+// --- Funções de Serviço (sem alterações na lógica principal) ---
 
-func getFromCache(key string) (interface{}, bool) {
-	cacheMutex.RLock()
-	defer cacheMutex.RUnlock()
-	item, found := cache[key]
-	if !found || time.Since(item.Timestamp) > cacheTTL {
-		return nil, false
-	}
-	return item.Data, true
+// PrecosCarteira reúne as posições cotadas do usuário.
+type PrecosCarteira struct {
+	Acoes          []AcaoNacional
+	FIIs           []FundoImobiliario
+	Internacionais []AtivoInternacional
+	Cripto         []AtivoCripto
+	CotacaoDolar   float64
 }
 
-func setToCache(key string, data interface{}) {
-	cacheMutex.Lock()
-	defer cacheMutex.Unlock()
-	cache[key] = CacheItem{
-		Data:      data,
-		Timestamp: time.Now(),
-	}
-}
-
-// --- NOVAS FUNÇÕES DE GERENCIAMENTO DE CACHE ---
+// BuscarPrecosCarteira busca em paralelo as cotações de cada classe; falhas são registradas no
+// log e deixam a classe sem preços, sem interromper as demais.
+func BuscarPrecosCarteira(userID int64) PrecosCarteira {
+	var p PrecosCarteira
+	var wg sync.WaitGroup
+	var errAcoes, errFIIs, errInt, errCripto error
 
-// ClearNacionalCache remove os dados de Ações e FIIs do cache.
-func ClearNacionalCache() {
-	cacheMutex.Lock()
-	defer cacheMutex.Unlock()
-	delete(cache, "market_data_acoes")
-	delete(cache, "market_data_fiis")
-	log.Println("[Cache] Cache de ativos nacionais (Ações e FIIs) limpo.")
-}
+	wg.Add(4)
+	go func() {
+		defer wg.Done()
+		p.Acoes, errAcoes = GetAcoesNacionais(userID)
+	}()
+	go func() {
+		defer wg.Done()
+		p.FIIs, errFIIs = GetFIIsNacionais(userID)
+	}()
+	go func() {
+		defer wg.Done()
+		p.Internacionais, p.CotacaoDolar, errInt = GetAtivosInternacionais(userID)
+	}()
+	go func() {
+		defer wg.Done()
+		p.Cripto, errCripto = GetAtivosCripto(userID)
+	}()
+	wg.Wait()
 
-// ClearInternacionalCache remove os dados de ativos internacionais do cache.
-func ClearInternacionalCache() {
-	cacheMutex.Lock()
-	defer cacheMutex.Unlock()
-	delete(cache, "mapa_precos_internacionais")
-	log.Println("[Cache] Cache de ativos internacionais limpo.")
+	if errAcoes != nil {
+		log.Printf("ERRO na busca de preços (Ações): %v", errAcoes)
+	}
+	if errFIIs != nil {
+		log.Printf("ERRO na busca de preços (FIIs): %v", errFIIs)
+	}
+	if errInt != nil {
+		log.Printf("ERRO na busca de preços (Internacionais): %v", errInt)
+	}
+	if errCripto != nil {
+		log.Printf("ERRO na busca de preços (Cripto): %v", errCripto)
+	}
+	return p
 }
 
-
-// --- Funções de Serviço (sem alterações na lógica principal) ---
-
 func GetAcoesNacionais(userID int64) ([]AcaoNacional, error) {
 	log.Println("[InvestimentosService] Iniciando busca de Ações Nacionais...")
 	dadosMercado, err := getDadosMercadoAcoes()
@@ -84,9 +81,9 @@ func GetAcoesNacionais(userID int64) ([]AcaoNacional, error) {
 		}
 		tickerLimpo := strings.TrimSpace(acao.Ticker)
 		if dados, ok := dadosMercado[tickerLimpo]; ok {
-			acao.Cotacao = ParsePtBrFloat(dados[1])
-			acao.PVP = ParsePtBrFloat(dados[3])
-			acao.DivYield = ParsePtBrFloat(dados[5]) / 100.0
+			acao.Cotacao = dados.Cotacao
+			acao.PVP = dados.PVP
+			acao.DivYield = dados.DivYield
 			acao.DivYieldPercent = acao.DivYield * 100.0
 			acao.ValorTotal = acao.Cotacao * float64(acao.Quantidade)
 		} else {
@@ -94,20 +91,24 @@ func GetAcoesNacionais(userID int64) ([]AcaoNacional, error) {
 		}
 		acoes = append(acoes, acao)
 	}
+	aplicarPosicoes(userID, len(acoes), func(i int) (string, float64, *Resultado) {
+		return acoes[i].Ticker, acoes[i].ValorTotal, &acoes[i].Resultado
+	})
 	var wg sync.WaitGroup
 	for i := range acoes {
 		wg.Add(1)
 		go func(index int) {
 			defer wg.Done()
 			ticker := acoes[index].Ticker
-			lpa, vpa, err := BuscarLPAeVPA(ticker)
-			if err == nil {
-				acoes[index].ValorGraham = CalcularValorGraham(lpa, vpa)
-				if acoes[index].ValorGraham > 0 && acoes[index].Cotacao > 0 && acoes[index].ValorGraham > acoes[index].Cotacao {
-					acoes[index].IsGrahamAdvantageous = true
-				}
-			} else {
-				log.Printf("AVISO (Graham): Falha ao buscar LPA/VPA para %s: %v", ticker, err)
+			lpa, vpa, err := getFundamentos(ticker)
+			if err != nil {
+				// Sem fonte de fundamentos, deriva LPA e VPA dos múltiplos da tabela de mercado.
+				log.Printf("AVISO (Graham): Falha ao buscar LPA/VPA para %s: %v. Usando P/L e P/VP.", ticker, err)
+				lpa, vpa = dadosMercado[strings.TrimSpace(ticker)].Fundamentos()
+			}
+			acoes[index].ValorGraham = CalcularValorGraham(lpa, vpa)
+			if acoes[index].ValorGraham > 0 && acoes[index].Cotacao > 0 && acoes[index].ValorGraham > acoes[index].Cotacao {
+				acoes[index].IsGrahamAdvantageous = true
 			}
 		}(i)
 	}
@@ -137,22 +138,44 @@ func GetFIIsNacionais(userID int64) ([]FundoImobiliario, error) {
 		}
 		tickerLimpo := strings.TrimSpace(fii.Ticker)
 		if dados, ok := dadosMercado[tickerLimpo]; ok {
-			fii.Segmento = dados[1]
-			fii.Cotacao = ParsePtBrFloat(dados[2])
-			fii.DivYield = ParsePtBrFloat(dados[4]) / 100.0
+			fii.Segmento = dados.Segmento
+			fii.Cotacao = dados.Cotacao
+			fii.DivYield = dados.DivYield
 			fii.DivYieldPercent = fii.DivYield * 100.0
-			fii.PVP = ParsePtBrFloat(dados[5])
-			fii.Vacancia = ParsePtBrFloat(dados[12])
-			fii.NumImoveis, _ = strconv.Atoi(dados[8])
+			fii.PVP = dados.PVP
+			fii.Vacancia = dados.Vacancia
+			fii.NumImoveis = dados.NumImoveis
 			fii.ValorTotal = fii.Cotacao * float64(fii.Quantidade)
 		} else {
 			log.Printf("AVISO (FIIs): Ticker '%s' da sua carteira não foi encontrado.", tickerLimpo)
 		}
 		fiis = append(fiis, fii)
 	}
+	aplicarPosicoes(userID, len(fiis), func(i int) (string, float64, *Resultado) {
+		return fiis[i].Ticker, fiis[i].ValorTotal, &fiis[i].Resultado
+	})
 	return fiis, nil
 }
 
+// aplicarPosicoes preenche o preço médio e o resultado não realizado dos n ativos a partir do
+// livro de operações; item retorna o ticker, o valor de mercado e onde gravar o resultado.
+func aplicarPosicoes(userID int64, n int, item func(i int) (string, float64, *Resultado)) {
+	if n == 0 {
+		return
+	}
+	posicoes, err := PosicoesDoLivro(userID)
+	if err != nil {
+		log.Printf("AVISO: Falha ao calcular o preço médio das posições: %v", err)
+		return
+	}
+	for i := 0; i < n; i++ {
+		ticker, valorMercado, resultado := item(i)
+		if p, ok := posicoes[strings.TrimSpace(ticker)]; ok {
+			*resultado = calcularResultado(p, valorMercado)
+		}
+	}
+}
+
 func GetAtivosInternacionais(userID int64) ([]AtivoInternacional, float64, error) {
 	log.Println("[InvestimentosService] Iniciando busca de Ativos Internacionais...")
 	cotacaoDolar, err := getCotacaoDolar()
@@ -168,7 +191,7 @@ func GetAtivosInternacionais(userID int64) ([]AtivoInternacional, float64, error
 	}
 	defer rows.Close()
 	var ativos []AtivoInternacional
-	carteiraParaBusca := make(map[string]string)
+	var tickers []string
 	for rows.Next() {
 		var ativo AtivoInternacional
 		if err := rows.Scan(&ativo.Ticker, &ativo.Descricao, &ativo.Quantidade, &ativo.Moeda); err != nil {
@@ -176,14 +199,14 @@ func GetAtivosInternacionais(userID int64) ([]AtivoInternacional, float64, error
 			continue
 		}
 		ativos = append(ativos, ativo)
-		carteiraParaBusca[ativo.Ticker] = ativo.Moeda
+		tickers = append(tickers, strings.TrimSpace(ativo.Ticker))
 	}
-	if len(carteiraParaBusca) == 0 {
+	if len(tickers) == 0 {
 		return ativos, cotacaoDolar, nil
 	}
-	mapaDePrecos, err := getMapaDePrecosInternacionais(carteiraParaBusca)
+	mapaDePrecos, err := getPrecosInternacionais(tickers)
 	if err != nil {
-		log.Printf("ERRO ao buscar mapa de preços internacionais: %v. Os preços não serão preenchidos.", err)
+		log.Printf("ERRO ao buscar preços internacionais: %v. Os preços que faltaram não serão preenchidos.", err)
 	}
 	for i := range ativos {
 		tickerLimpo := strings.TrimSpace(ativos[i].Ticker)
@@ -198,58 +221,122 @@ func GetAtivosInternacionais(userID int64) ([]AtivoInternacional, float64, error
 	return ativos, cotacaoDolar, nil
 }
 
-func getDadosMercadoAcoes() (map[string][]string, error) {
-	const cacheKey = "market_data_acoes"
-	if data, found := getFromCache(cacheKey); found {
+func getDadosMercadoAcoes() (map[string]DadosAcao, error) {
+	if data, found := getFromCache(cacheAcoes); found {
 		log.Println("[Cache] Usando dados de AÇÕES do cache.")
-		return data.(map[string][]string), nil
+		return data.(map[string]DadosAcao), nil
 	}
-	data, err := RasparDadosFundamentus("https://www.fundamentus.com.br/resultado.php", "acoes")
+	return buscarDadosMercadoAcoes()
+}
+
+// buscarDadosMercadoAcoes consulta o provedor e guarda a tabela no cache e no histórico.
+func buscarDadosMercadoAcoes() (map[string]DadosAcao, error) {
+	data, err := Provider().CotacoesAcoes()
 	if err != nil {
 		return nil, err
 	}
-	setToCache(cacheKey, data)
+	setToCache(cacheAcoes, data)
+	registrarNoHistorico(historicoAcoes(data))
 	return data, nil
 }
 
-func getDadosMercadoFIIs() (map[string][]string, error) {
-	const cacheKey = "market_data_fiis"
-	if data, found := getFromCache(cacheKey); found {
+func getDadosMercadoFIIs() (map[string]DadosFII, error) {
+	if data, found := getFromCache(cacheFIIs); found {
 		log.Println("[Cache] Usando dados de FIIs do cache.")
-		return data.(map[string][]string), nil
+		return data.(map[string]DadosFII), nil
 	}
-	data, err := RasparDadosFundamentus("https://www.fundamentus.com.br/fii_resultado.php", "fii")
+	return buscarDadosMercadoFIIs()
+}
+
+// buscarDadosMercadoFIIs consulta o provedor e guarda a tabela no cache e no histórico.
+func buscarDadosMercadoFIIs() (map[string]DadosFII, error) {
+	data, err := Provider().CotacoesFIIs()
 	if err != nil {
 		return nil, err
 	}
-	setToCache(cacheKey, data)
+	setToCache(cacheFIIs, data)
+	registrarNoHistorico(historicoFIIs(data))
 	return data, nil
 }
 
+// getFundamentos retorna LPA e VPA da ação; só os resultados obtidos ficam no cache.
+func getFundamentos(ticker string) (float64, float64, error) {
+	if data, found := getFromCache(chaveFundamentos(ticker)); found {
+		f := data.([2]float64)
+		return f[0], f[1], nil
+	}
+	return buscarFundamentos(ticker)
+}
+
+func chaveFundamentos(ticker string) string {
+	return cacheFundamentos + strings.TrimSpace(ticker)
+}
+
+// buscarFundamentos consulta LPA e VPA no provedor e os guarda no cache por ttlFundamentos.
+func buscarFundamentos(ticker string) (float64, float64, error) {
+	lpa, vpa, err := Provider().Fundamentos(ticker)
+	if err != nil {
+		return 0, 0, err
+	}
+	setToCacheTTL(chaveFundamentos(ticker), [2]float64{lpa, vpa}, ttlFundamentos)
+	return lpa, vpa, nil
+}
+
 func getCotacaoDolar() (float64, error) {
-	const cacheKey = "cotacao_dolar"
-	if data, found := getFromCache(cacheKey); found {
+	if data, found := getFromCache(cacheDolar); found {
 		log.Println("[Cache] Usando cotação do dólar do cache.")
 		return data.(float64), nil
 	}
-	data, err := BuscarCotacaoDolarBRL()
+	return buscarCotacaoDolar()
+}
+
+// buscarCotacaoDolar consulta o provedor e guarda a cotação no cache e no histórico.
+func buscarCotacaoDolar() (float64, error) {
+	cotacao, err := Provider().CotacaoDolar()
 	if err != nil {
 		return 0, err
 	}
-	setToCache(cacheKey, data)
-	return data, nil
+	setToCache(cacheDolar, cotacao.Preco)
+	registrarNoHistorico([]PrecoHistorico{historicoCotacao(tickerDolar, "BRL", cotacao)})
+	return cotacao.Preco, nil
 }
 
-func getMapaDePrecosInternacionais(carteira map[string]string) (map[string]float64, error) {
-	const cacheKey = "mapa_precos_internacionais"
-	if data, found := getFromCache(cacheKey); found {
-		log.Println("[Cache] Usando mapa de preços internacionais do cache.")
-		return data.(map[string]float64), nil
+// getPrecosInternacionais retorna os preços em USD dos tickers. Cada preço fica no cache com a
+// própria validade e só os tickers ausentes ou expirados são pedidos ao provedor.
+func getPrecosInternacionais(tickers []string) (map[string]float64, error) {
+	precos := make(map[string]float64, len(tickers))
+	var faltando []string
+	for _, ticker := range tickers {
+		if data, found := getFromCache(cacheInternacionais + ticker); found {
+			precos[ticker] = data.(float64)
+		} else {
+			faltando = append(faltando, ticker)
+		}
+	}
+	if len(faltando) == 0 {
+		return precos, nil
+	}
+	cotacoes, err := buscarPrecosInternacionais(faltando)
+	for ticker, preco := range cotacoes {
+		precos[ticker] = preco
 	}
-	data, err := BuscarMuitosPrecosInternacionais(carteira)
+	return precos, err
+}
+
+// buscarPrecosInternacionais consulta os preços dos tickers no provedor e guarda cada um no
+// cache e no histórico.
+func buscarPrecosInternacionais(tickers []string) (map[string]float64, error) {
+	cotacoes, err := Provider().PrecosInternacionais(tickers)
 	if err != nil {
 		return nil, err
 	}
-	setToCache(cacheKey, data)
+	data := make(map[string]float64, len(cotacoes))
+	historico := make([]PrecoHistorico, 0, len(cotacoes))
+	for ticker, cotacao := range cotacoes {
+		data[ticker] = cotacao.Preco
+		setToCache(cacheInternacionais+ticker, cotacao.Preco)
+		historico = append(historico, historicoCotacao(ticker, "USD", cotacao))
+	}
+	registrarNoHistorico(historico)
 	return data, nil
 }
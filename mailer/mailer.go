@@ -0,0 +1,202 @@
+// mailer/mailer.go
+package mailer
+
+import (
+	"fmt"
+	"log"
+	"net/smtp"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Message representa um e-mail simples em texto puro.
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer é a interface implementada por todos os mecanismos de envio de e-mail.
+type Mailer interface {
+	Send(msg Message) error
+}
+
+var (
+	current   Mailer
+	currentMu sync.RWMutex
+)
+
+// getEnv retorna o valor de uma variável de ambiente ou um valor padrão.
+func getEnv(key, fallback string) string {
+	if value, ok := os.LookupEnv(key); ok {
+		return value
+	}
+	return fallback
+}
+
+// FromEnv cria o Mailer configurado pela variável MAIL_DRIVER ("smtp", "file" ou "log").
+func FromEnv() (Mailer, error) {
+	switch driver := getEnv("MAIL_DRIVER", "log"); driver {
+	case "smtp":
+		host := os.Getenv("SMTP_HOST")
+		if host == "" {
+			return nil, fmt.Errorf("MAIL_DRIVER=smtp exige a variável SMTP_HOST")
+		}
+		return &SMTPMailer{
+			Host:     host,
+			Port:     getEnv("SMTP_PORT", "587"),
+			Username: os.Getenv("SMTP_USER"),
+			Password: os.Getenv("SMTP_PASS"),
+			From:     getEnv("SMTP_FROM", "no-reply@minhaseconomias.com.br"),
+		}, nil
+	case "file":
+		return &FileMailer{Dir: getEnv("MAIL_FILE_DIR", "mail_outbox")}, nil
+	case "log":
+		return &LogMailer{}, nil
+	default:
+		return nil, fmt.Errorf("MAIL_DRIVER '%s' não suportado", driver)
+	}
+}
+
+// ValidateBaseURL confere se APP_BASE_URL, quando definida, é uma URL absoluta http(s).
+// Os links enviados por e-mail são montados apenas a partir dela, nunca do Host da requisição.
+func ValidateBaseURL(base string) error {
+	if base == "" {
+		return nil
+	}
+	u, err := url.Parse(base)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("APP_BASE_URL inválida '%s': use uma URL absoluta, ex: https://economias.exemplo.com", base)
+	}
+	return nil
+}
+
+// Init configura o Mailer global a partir das variáveis de ambiente. Sem APP_BASE_URL apenas
+// avisa (os links de redefinição de senha ficam desativados); falha se ela for inválida.
+func Init() error {
+	base := os.Getenv("APP_BASE_URL")
+	if err := ValidateBaseURL(base); err != nil {
+		return err
+	}
+	if base == "" {
+		log.Println("AVISO: APP_BASE_URL não definida; os e-mails de redefinição de senha não serão enviados.")
+	}
+	m, err := FromEnv()
+	if err != nil {
+		return err
+	}
+	SetMailer(m)
+	return nil
+}
+
+// SetMailer substitui o Mailer global (útil em testes).
+func SetMailer(m Mailer) {
+	currentMu.Lock()
+	defer currentMu.Unlock()
+	current = m
+}
+
+// Send envia a mensagem pelo Mailer global. Se nenhum foi configurado, usa o LogMailer.
+func Send(msg Message) error {
+	currentMu.RLock()
+	m := current
+	currentMu.RUnlock()
+	if m == nil {
+		m = &LogMailer{}
+	}
+	return m.Send(msg)
+}
+
+// SMTPMailer envia e-mails através de um servidor SMTP com autenticação PLAIN.
+type SMTPMailer struct {
+	Host     string
+	Port     string
+	Username string
+	Password string
+	From     string
+}
+
+// Send implementa Mailer.
+func (m *SMTPMailer) Send(msg Message) error {
+	var auth smtp.Auth
+	if m.Username != "" {
+		auth = smtp.PlainAuth("", m.Username, m.Password, m.Host)
+	}
+	addr := m.Host + ":" + m.Port
+	if err := smtp.SendMail(addr, auth, m.From, []string{msg.To}, buildRFC822(m.From, msg)); err != nil {
+		return fmt.Errorf("erro ao enviar e-mail via SMTP para '%s': %w", msg.To, err)
+	}
+	return nil
+}
+
+// FileMailer grava cada e-mail como um arquivo .eml no diretório configurado (desenvolvimento local).
+type FileMailer struct {
+	Dir string
+}
+
+// Send implementa Mailer.
+func (m *FileMailer) Send(msg Message) error {
+	if err := os.MkdirAll(m.Dir, 0o755); err != nil {
+		return fmt.Errorf("erro ao criar diretório de e-mails '%s': %w", m.Dir, err)
+	}
+	safeTo := strings.NewReplacer("@", "_at_", "/", "_", "\\", "_").Replace(msg.To)
+	name := fmt.Sprintf("%s_%s.eml", time.Now().Format("20060102T150405.000000000"), safeTo)
+	path := filepath.Join(m.Dir, name)
+	if err := os.WriteFile(path, buildRFC822("no-reply@localhost", msg), 0o600); err != nil {
+		return fmt.Errorf("erro ao gravar e-mail em '%s': %w", path, err)
+	}
+	log.Printf("[Mailer] E-mail para %s gravado em %s", msg.To, path)
+	return nil
+}
+
+// LogMailer apenas escreve o e-mail no log da aplicação. Padrão quando nada é configurado.
+type LogMailer struct{}
+
+// Send implementa Mailer.
+func (m *LogMailer) Send(msg Message) error {
+	log.Printf("[Mailer] Para: %s | Assunto: %s\n%s", msg.To, msg.Subject, msg.Body)
+	return nil
+}
+
+// MemoryMailer guarda as mensagens enviadas em memória, para inspeção em testes.
+type MemoryMailer struct {
+	mu       sync.Mutex
+	Messages []Message
+}
+
+// Send implementa Mailer.
+func (m *MemoryMailer) Send(msg Message) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.Messages = append(m.Messages, msg)
+	return nil
+}
+
+// Last retorna a última mensagem enviada, se houver.
+func (m *MemoryMailer) Last() (Message, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if len(m.Messages) == 0 {
+		return Message{}, false
+	}
+	return m.Messages[len(m.Messages)-1], true
+}
+
+// headerSanitizer remove quebras de linha dos cabeçalhos para evitar injeção de cabeçalhos.
+var headerSanitizer = strings.NewReplacer("\r", "", "\n", "")
+
+func buildRFC822(from string, msg Message) []byte {
+	var b strings.Builder
+	b.WriteString("From: " + headerSanitizer.Replace(from) + "\r\n")
+	b.WriteString("To: " + headerSanitizer.Replace(msg.To) + "\r\n")
+	b.WriteString("Subject: " + headerSanitizer.Replace(msg.Subject) + "\r\n")
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=\"UTF-8\"\r\n")
+	b.WriteString("\r\n")
+	b.WriteString(msg.Body)
+	return []byte(b.String())
+}
@@ -2,57 +2,197 @@
 package middleware
 
 import (
+	"encoding/json"
+	"fmt"
 	"log"
+	"minhas_economias/config"
 	"minhas_economias/database"
 	"minhas_economias/models"
+	"reflect"
 	"time"
 
 	"github.com/gin-gonic/gin"
 )
 
+// auditChangesKey guarda no contexto as alterações de entidades informadas pelos handlers.
+const auditChangesKey = "auditChanges"
+
+// DefaultAuditRetentionDays é o período de retenção usado quando AUDIT_RETENTION_DAYS não é definido.
+const DefaultAuditRetentionDays = 365
+
+// AuditChange descreve a alteração de uma entidade (transação, ativo, perfil...) feita por um handler.
+type AuditChange struct {
+	EntityType string
+	EntityID   string
+	Before     interface{} // nil em criações
+	After      interface{} // nil em exclusões
+}
+
 // AuditLog intercepta operações de escrita e salva no banco de dados
 func AuditLogger() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// Só logamos métodos que alteram estado
 		if c.Request.Method == "POST" || c.Request.Method == "DELETE" || c.Request.Method == "PUT" {
 			start := time.Now()
-			
+
 			// Processa a requisição primeiro
 			c.Next()
 
 			// Recupera usuário do contexto (definido no AuthRequired)
-			user, exists := c.Get("user")
-			var userEmail string
-			if exists {
-				userEmail = user.(*models.User).Email
-			} else {
-				userEmail = "anonymous"
+			entry := models.AuditLogEntry{
+				UserEmail: "anonymous",
+				Action:    c.Request.Method,
+				Path:      c.FullPath(),
+				Status:    c.Writer.Status(),
+				LatencyMs: time.Since(start).Milliseconds(),
+			}
+			if user, exists := c.Get("user"); exists {
+				entry.UserEmail = user.(*models.User).Email
+				entry.UserID = user.(*models.User).ID
 			}
 
-			// Captura informações da ação
-			db := database.GetDB()
-			query := database.Rebind(`
-				INSERT INTO audit_logs (user_email, action, path, status, latency_ms, created_at)
-				VALUES (?, ?, ?, ?, ?, ?)
-			`)
-
-			duration := time.Since(start).Milliseconds()
-			status := c.Writer.Status()
-			
-			_, err := db.Exec(query, 
-				userEmail, 
-				c.Request.Method, 
-				c.FullPath(), 
-				status, 
-				duration, 
-				time.Now(),
-			)
-
-			if err != nil {
-				log.Printf("[AUDIT ERROR] Falha ao salvar log: %v", err)
+			// Uma linha por entidade alterada; requisições sem alteração informada geram uma linha simples.
+			changes, _ := c.Get(auditChangesKey)
+			list, _ := changes.([]AuditChange)
+			if len(list) == 0 {
+				RecordAuditEntry(entry)
+				return
+			}
+			for _, change := range list {
+				e := entry
+				e.EntityType = change.EntityType
+				e.EntityID = change.EntityID
+				e.Changes = DiffFields(change.Before, change.After)
+				RecordAuditEntry(e)
 			}
 		} else {
 			c.Next()
 		}
 	}
-}
\ No newline at end of file
+}
+
+// SetAuditChange informa ao AuditLogger qual entidade a requisição alterou e seu estado antes/depois.
+// Deve ser chamado apenas depois que a alteração foi gravada com sucesso.
+func SetAuditChange(c *gin.Context, entityType string, entityID interface{}, before, after interface{}) {
+	changes, _ := c.Get(auditChangesKey)
+	list, _ := changes.([]AuditChange)
+	c.Set(auditChangesKey, append(list, AuditChange{
+		EntityType: entityType,
+		EntityID:   fmt.Sprint(entityID),
+		Before:     before,
+		After:      after,
+	}))
+}
+
+// DiffFields compara as representações JSON de before e after e retorna apenas os campos alterados.
+func DiffFields(before, after interface{}) map[string]models.FieldChange {
+	old, current := toFieldMap(before), toFieldMap(after)
+	diff := make(map[string]models.FieldChange)
+	for field, value := range current {
+		if previous, ok := old[field]; !ok || !reflect.DeepEqual(previous, value) {
+			diff[field] = models.FieldChange{Antes: old[field], Depois: value}
+		}
+	}
+	for field, value := range old {
+		if _, ok := current[field]; !ok {
+			diff[field] = models.FieldChange{Antes: value}
+		}
+	}
+	return diff
+}
+
+func toFieldMap(v interface{}) map[string]interface{} {
+	if v == nil {
+		return nil
+	}
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return nil
+	}
+	var fields map[string]interface{}
+	if err := json.Unmarshal(raw, &fields); err != nil {
+		// Valores que não são objetos (ex: um número) viram um único campo.
+		var value interface{}
+		json.Unmarshal(raw, &value)
+		return map[string]interface{}{"valor": value}
+	}
+	return fields
+}
+
+// RecordAuditEvent grava uma entrada na tabela audit_logs.
+// Usado pelo AuditLogger e por eventos que não correspondem a uma requisição inteira (ex: bloqueio de login).
+func RecordAuditEvent(userEmail, action, path string, status int, latencyMs int64) {
+	RecordAuditEntry(models.AuditLogEntry{UserEmail: userEmail, Action: action, Path: path, Status: status, LatencyMs: latencyMs})
+}
+
+// RecordAuditEntry grava a entrada completa, incluindo a entidade alterada e o diff em JSON.
+func RecordAuditEntry(entry models.AuditLogEntry) {
+	db := database.GetDB()
+	if db == nil {
+		return
+	}
+	var userID, changes interface{}
+	if entry.UserID != 0 {
+		userID = entry.UserID
+	}
+	if len(entry.Changes) > 0 {
+		if raw, err := json.Marshal(entry.Changes); err == nil {
+			changes = string(raw)
+		}
+	}
+	query := database.Rebind(`
+		INSERT INTO audit_logs (user_id, user_email, action, path, status, latency_ms, entity_type, entity_id, changes, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`)
+
+	if _, err := db.Exec(query, userID, entry.UserEmail, entry.Action, entry.Path, entry.Status, entry.LatencyMs,
+		nullIfEmpty(entry.EntityType), nullIfEmpty(entry.EntityID), changes, time.Now().UTC()); err != nil {
+		log.Printf("[AUDIT ERROR] Falha ao salvar log: %v", err)
+	}
+}
+
+func nullIfEmpty(s string) interface{} {
+	if s == "" {
+		return nil
+	}
+	return s
+}
+
+// AuditRetentionFromEnv lê AUDIT_RETENTION_DAYS. Zero desativa a limpeza automática.
+func AuditRetentionFromEnv() time.Duration {
+	return time.Duration(config.IntFromEnv("AUDIT_RETENTION_DAYS", DefaultAuditRetentionDays, 0)) * 24 * time.Hour
+}
+
+// PurgeAuditLogs apaga as entradas mais antigas que o período de retenção e retorna quantas foram removidas.
+func PurgeAuditLogs(retention time.Duration) (int64, error) {
+	if retention <= 0 {
+		return 0, nil
+	}
+	query := database.Rebind("DELETE FROM audit_logs WHERE created_at < ?")
+	result, err := database.GetDB().Exec(query, time.Now().UTC().Add(-retention))
+	if err != nil {
+		return 0, fmt.Errorf("erro ao limpar audit_logs: %w", err)
+	}
+	return result.RowsAffected()
+}
+
+// StartAuditRetention executa a limpeza na inicialização e depois a cada 24 horas.
+func StartAuditRetention(retention time.Duration) {
+	if retention <= 0 {
+		log.Println("Retenção do log de auditoria desativada (AUDIT_RETENTION_DAYS=0).")
+		return
+	}
+	purge := func() {
+		if removed, err := PurgeAuditLogs(retention); err != nil {
+			log.Printf("Aviso: %v", err)
+		} else if removed > 0 {
+			log.Printf("Log de auditoria: %d entradas com mais de %d dias removidas.", removed, int(retention.Hours()/24))
+		}
+	}
+	go func() {
+		purge()
+		for range time.Tick(24 * time.Hour) {
+			purge()
+		}
+	}()
+}
@@ -45,6 +45,21 @@ var (
 		Help: "Total de erros ao buscar cotações externas",
 	}, []string{"provider"}) // "yahoo", "fundamentus"
 
+	MarketDataCacheHits = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "minhas_economias_market_data_cache_hits_total",
+		Help: "Total de consultas ao cache de cotações respondidas pelo cache",
+	}, []string{"type"}) // "acoes", "internacional", "cripto"...
+
+	MarketDataCacheMisses = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "minhas_economias_market_data_cache_misses_total",
+		Help: "Total de consultas ao cache de cotações sem item válido",
+	}, []string{"type"})
+
+	MarketDataCacheEvictions = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "minhas_economias_market_data_cache_evictions_total",
+		Help: "Total de itens descartados do cache de cotações por falta de espaço",
+	}, []string{"type"})
+
 	AiResponseDuration = promauto.NewHistogram(prometheus.HistogramOpts{
 		Name:    "minhas_economias_ai_duration_seconds",
 		Help:    "Tempo de resposta da API do Gemini",
@@ -56,6 +71,28 @@ var (
 		Help: "Número de conexões abertas no pool do Postgres",
 	})
 
+	// --- Backups Automáticos do Banco ---
+
+	BackupLastSuccess = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "minhas_economias_backup_last_success_timestamp_seconds",
+		Help: "Horário (Unix) do último backup automático do banco concluído com sucesso",
+	})
+
+	BackupFailures = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "minhas_economias_backup_failures_total",
+		Help: "Total de falhas nos backups automáticos do banco",
+	})
+
+	BackupDuration = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "minhas_economias_backup_last_duration_seconds",
+		Help: "Duração do último backup do banco concluído com sucesso",
+	})
+
+	BackupSizeBytes = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "minhas_economias_backup_last_size_bytes",
+		Help: "Tamanho do último arquivo de backup do banco (comprimido)",
+	})
+
 	// --- Métricas HTTP Internas ---
 
 	httpDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
@@ -0,0 +1,73 @@
+package models
+
+import (
+	"fmt"
+	"time"
+)
+
+// AdminUserSummary é a visão de um usuário no console de administração, com o uso de armazenamento.
+type AdminUserSummary struct {
+	ID           int64  `json:"id"`
+	Email        string `json:"email"`
+	IsAdmin      bool   `json:"is_admin"`
+	Disabled     bool   `json:"disabled"`
+	Transactions int64  `json:"transactions"`
+	Investments  int64  `json:"investments"`
+	ChatMessages int64  `json:"chat_messages"`
+	ApproxBytes  int64  `json:"approx_bytes"` // Soma do tamanho dos textos gravados (estimativa)
+}
+
+// KiloBytes retorna o armazenamento estimado em KB, para exibição.
+func (s AdminUserSummary) KiloBytes() float64 {
+	return float64(s.ApproxBytes) / 1024
+}
+
+// AuditLogEntry representa uma linha da tabela audit_logs.
+type AuditLogEntry struct {
+	ID         int64                  `json:"id"`
+	UserID     int64                  `json:"user_id,omitempty"`
+	UserEmail  string                 `json:"user_email"`
+	Action     string                 `json:"action"`
+	Path       string                 `json:"path"`
+	Status     int                    `json:"status"`
+	LatencyMs  int64                  `json:"latency_ms"`
+	EntityType string                 `json:"entity_type,omitempty"`
+	EntityID   string                 `json:"entity_id,omitempty"`
+	Changes    map[string]FieldChange `json:"changes,omitempty"`
+	CreatedAt  time.Time              `json:"created_at"`
+}
+
+// EntityLabel descreve a entidade alterada em português, para exibição.
+func (e AuditLogEntry) EntityLabel() string {
+	labels := map[string]string{
+		"movimentacao":               "Transação",
+		"investimento_nacional":      "Ativo nacional",
+		"investimento_internacional": "Ativo internacional",
+		"perfil":                     "Perfil",
+		"movimentacao_lote":          "Edição em lote",
+		"anexo":                      "Anexo",
+	}
+	if label, ok := labels[e.EntityType]; ok {
+		return label
+	}
+	return e.EntityType
+}
+
+// FieldChange é o valor de um campo antes e depois de uma alteração auditada.
+type FieldChange struct {
+	Antes  interface{} `json:"antes"`
+	Depois interface{} `json:"depois"`
+}
+
+// AntesTexto formata o valor anterior para exibição ("—" quando o campo não existia).
+func (f FieldChange) AntesTexto() string { return formatAuditValue(f.Antes) }
+
+// DepoisTexto formata o valor novo para exibição ("—" quando o campo deixou de existir).
+func (f FieldChange) DepoisTexto() string { return formatAuditValue(f.Depois) }
+
+func formatAuditValue(v interface{}) string {
+	if v == nil {
+		return "—"
+	}
+	return fmt.Sprint(v)
+}
@@ -0,0 +1,31 @@
+// models/anexo.go
+package models
+
+import (
+	"fmt"
+	"time"
+)
+
+// Anexo é um arquivo (imagem ou PDF) associado a uma movimentação. O conteúdo fica no
+// backend de armazenamento (pacote storage); a tabela 'anexos' guarda apenas os metadados.
+type Anexo struct {
+	ID             int64     `json:"id"`
+	MovimentacaoID int       `json:"movimentacao_id"`
+	UserID         int64     `json:"user_id"` // Quem enviou; a cota é contabilizada para este usuário
+	NomeArquivo    string    `json:"nome_arquivo"`
+	ContentType    string    `json:"content_type"`
+	Tamanho        int64     `json:"tamanho"`
+	StorageKey     string    `json:"-"`
+	ThumbnailKey   string    `json:"-"`
+	CreatedAt      time.Time `json:"created_at"`
+	URL            string    `json:"url"`
+	ThumbnailURL   string    `json:"thumbnail_url,omitempty"`
+}
+
+// TamanhoTexto formata o tamanho do arquivo para exibição (KB ou MB).
+func (a Anexo) TamanhoTexto() string {
+	if a.Tamanho >= 1024*1024 {
+		return fmt.Sprintf("%.1f MB", float64(a.Tamanho)/(1024*1024))
+	}
+	return fmt.Sprintf("%.0f KB", float64(a.Tamanho)/1024)
+}
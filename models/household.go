@@ -0,0 +1,66 @@
+package models
+
+import "time"
+
+// Papéis de um membro em um lar compartilhado.
+const (
+	RoleOwner  = "owner"  // Gerencia membros e edita o livro-caixa
+	RoleEditor = "editor" // Lança, edita e exclui transações
+	RoleViewer = "viewer" // Apenas consulta
+)
+
+// Household representa um lar (workspace) cujas contas e transações são compartilhadas.
+// As transações do lar são gravadas com user_id = OwnerID; quem as lançou fica em created_by.
+// Role é o papel do usuário que está consultando.
+type Household struct {
+	ID        int64             `json:"id"`
+	Name      string            `json:"name"`
+	OwnerID   int64             `json:"owner_id"`
+	Role      string            `json:"role"`
+	CreatedAt time.Time         `json:"created_at"`
+	Members   []HouseholdMember `json:"members,omitempty"`
+}
+
+// HouseholdMember representa um usuário participante de um lar.
+type HouseholdMember struct {
+	UserID int64  `json:"user_id"`
+	Email  string `json:"email"`
+	Role   string `json:"role"`
+}
+
+// CanEdit indica se o papel permite alterar contas e transações.
+func (h Household) CanEdit() bool {
+	return h.Role == RoleOwner || h.Role == RoleEditor
+}
+
+// CanManage indica se o papel permite gerenciar os membros do lar.
+func (h Household) CanManage() bool {
+	return h.Role == RoleOwner
+}
+
+// RoleLabel retorna o nome do papel em português, para exibição.
+func (h Household) RoleLabel() string {
+	return roleLabel(h.Role)
+}
+
+// RoleLabel retorna o nome do papel em português, para exibição.
+func (m HouseholdMember) RoleLabel() string {
+	return roleLabel(m.Role)
+}
+
+func roleLabel(role string) string {
+	switch role {
+	case RoleOwner:
+		return "Proprietário"
+	case RoleEditor:
+		return "Editor"
+	case RoleViewer:
+		return "Leitor"
+	}
+	return role
+}
+
+// IsValidMemberRole indica se o papel pode ser atribuído a um convidado (o proprietário é único).
+func IsValidMemberRole(role string) bool {
+	return role == RoleEditor || role == RoleViewer
+}
@@ -0,0 +1,76 @@
+package models
+
+// Categorias de apuração do imposto sobre ganhos de capital em renda variável. Os prejuízos
+// de cada categoria só compensam lucros da mesma categoria.
+const (
+	CategoriaComum    = "comum"     // Ações em operações comuns (swing trade)
+	CategoriaDayTrade = "day_trade" // Ações compradas e vendidas no mesmo pregão
+	CategoriaFII      = "fii"       // Cotas de fundos imobiliários (comum e day trade)
+)
+
+// VendaRealizada é o resultado de uma venda (ou da parte day trade de um pregão) de um ativo.
+type VendaRealizada struct {
+	Ticker     string  `json:"ticker"`
+	TipoAtivo  string  `json:"tipo_ativo"`
+	Data       string  `json:"data"`
+	DayTrade   bool    `json:"day_trade"`
+	Quantidade int     `json:"quantidade"`
+	ValorVenda float64 `json:"valor_venda"` // Valor de alienação (quantidade × preço)
+	Taxas      float64 `json:"taxas"`       // Custos da venda
+	Custo      float64 `json:"custo"`       // Custo de aquisição (preço médio, com taxas de compra)
+	Resultado  float64 `json:"resultado"`
+	// CustoDesconhecido indica uma venda de posição com "Saldo anterior" sem preço: o custo está
+	// subestimado (e o resultado, superestimado) até o usuário informar o preço dessa compra.
+	CustoDesconhecido bool `json:"custo_desconhecido,omitempty"`
+}
+
+// Categoria retorna a categoria de apuração da venda.
+func (v VendaRealizada) Categoria() string {
+	switch {
+	case v.TipoAtivo == "FII":
+		return CategoriaFII
+	case v.DayTrade:
+		return CategoriaDayTrade
+	}
+	return CategoriaComum
+}
+
+// ApuracaoCategoria é a apuração de uma categoria em um mês.
+type ApuracaoCategoria struct {
+	Categoria          string  `json:"categoria"`
+	Vendas             float64 `json:"vendas"`
+	Resultado          float64 `json:"resultado"`
+	Isento             bool    `json:"isento"` // Vendas de ações comuns até o limite mensal de isenção
+	LucroIsento        float64 `json:"lucro_isento"`
+	PrejuizoAnterior   float64 `json:"prejuizo_anterior"`
+	PrejuizoCompensado float64 `json:"prejuizo_compensado"`
+	BaseCalculo        float64 `json:"base_calculo"`
+	Aliquota           float64 `json:"aliquota"`
+	Imposto            float64 `json:"imposto"`
+	PrejuizoAcumulado  float64 `json:"prejuizo_acumulado"` // A compensar nos meses seguintes
+}
+
+// ApuracaoMensal é a apuração do mês e o DARF correspondente (código 6015).
+type ApuracaoMensal struct {
+	Mes            string              `json:"mes"` // AAAA-MM
+	Categorias     []ApuracaoCategoria `json:"categorias"`
+	Vendas         []VendaRealizada    `json:"vendas"`
+	ImpostoDevido  float64             `json:"imposto_devido"`
+	IRRF           float64             `json:"irrf"`            // Imposto retido na fonte ("dedo-duro") no mês
+	IRRFCompensado float64             `json:"irrf_compensado"` // Inclui retenções de meses anteriores
+	SaldoAnterior  float64             `json:"saldo_anterior"`  // Imposto abaixo do mínimo do DARF em meses anteriores
+	ValorDARF      float64             `json:"valor_darf"`      // Zero quando o total fica abaixo do mínimo
+	Vencimento     string              `json:"vencimento,omitempty"`
+	CodigoReceita  string              `json:"codigo_receita"`
+}
+
+// ApuracaoAnual reúne os meses com vendas de um ano.
+type ApuracaoAnual struct {
+	Ano                 int                `json:"ano"`
+	Meses               []ApuracaoMensal   `json:"meses"`
+	TotalDARF           float64            `json:"total_darf"`
+	LucroIsento         float64            `json:"lucro_isento"`
+	PrejuizosACompensar map[string]float64 `json:"prejuizos_a_compensar"` // Por categoria, ao fim do ano
+	SaldoAPagar         float64            `json:"saldo_a_pagar"`         // Imposto abaixo do mínimo ainda não recolhido
+	CustoDesconhecido   []string           `json:"custo_desconhecido"`    // Ativos com vendas no ano sem o custo de aquisição conhecido
+}
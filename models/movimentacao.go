@@ -1,15 +1,19 @@
 // models/movimentacao.go
 package models
 
+import "time"
+
 // Movimentacao representa uma linha na tabela 'movimentacoes'
 type Movimentacao struct {
-	ID             int     `json:"id"`
-	DataOcorrencia string  `json:"data_ocorrencia"`
-	Descricao      string  `json:"descricao"`
-	Valor          float64 `json:"valor"`
-	Categoria      string  `json:"categoria"`
-	Conta          string  `json:"conta"`
-	Consolidado    bool    `json:"consolidado"`
+	ID             int        `json:"id"`
+	DataOcorrencia string     `json:"data_ocorrencia"`
+	Descricao      string     `json:"descricao"`
+	Valor          float64    `json:"valor"`
+	Categoria      string     `json:"categoria"`
+	Conta          string     `json:"conta"`
+	Consolidado    bool       `json:"consolidado"`
+	CriadoPor      string     `json:"criado_por,omitempty"` // E-mail de quem lançou (preenchido na listagem de transações)
+	DeletedAt      *time.Time `json:"deleted_at,omitempty"` // Preenchido apenas na listagem da lixeira
 }
 
 // RelatorioCategoria representa o total de despesas por categoria.
@@ -34,4 +38,4 @@ type ContaSaldo struct {
 	Nome           string  `json:"nome"`
 	SaldoAtual     float64 `json:"saldo_atual"`
 	URLEncodedNome string  `json:"url_encoded_nome"` // <-- CAMPO ADICIONADO
-}
\ No newline at end of file
+}
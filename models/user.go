@@ -7,4 +7,9 @@ type User struct {
 	PasswordHash    string
 	IsAdmin         bool
 	DarkModeEnabled bool // <-- NOVO CAMPO ADICIONADO
+	Disabled        bool // Conta desativada por um administrador; não pode fazer login
+
+	// Preenchidos pelo middleware de autenticação a cada requisição.
+	Workspaces      []Household // Lares compartilhados dos quais o usuário participa
+	ActiveHousehold *Household  // Lar ativo; nil quando o usuário está no livro-caixa pessoal
 }
\ No newline at end of file
@@ -0,0 +1,14 @@
+package models
+
+import "time"
+
+// UserSession representa uma sessão ativa do usuário (um dispositivo/navegador logado).
+type UserSession struct {
+	Handle     string    `json:"handle"` // Identificador público; nunca expõe o ID real da sessão
+	Device     string    `json:"device"`
+	UserAgent  string    `json:"user_agent"`
+	IPAddress  string    `json:"ip_address"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastSeenAt time.Time `json:"last_seen_at"`
+	Current    bool      `json:"current"`
+}
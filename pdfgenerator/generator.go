@@ -33,7 +33,7 @@ const (
 // A função toCP1252 foi REMOVIDA para evitar conflito de codificação de caracteres.
 
 // headerFunc define a função que será usada como cabeçalho em todas as páginas.
-func headerFunc(pdf *gofpdf.Fpdf) {
+func headerFunc(pdf *gofpdf.Fpdf, subtitle string) {
 	logoFile := "static/minhaseconomias.png"
 	textLeftMargin := margin + logoWidth + 4
 
@@ -47,7 +47,7 @@ func headerFunc(pdf *gofpdf.Fpdf) {
 	pdf.SetXY(textLeftMargin, 18)
 	pdf.SetFont(fontName, "", subtitleFontSize)
 	pdf.SetTextColor(100, 100, 100)
-	pdf.Cell(0, 8, "Relatório Financeiro") // Texto passado diretamente em UTF-8
+	pdf.Cell(0, 8, subtitle) // Texto passado diretamente em UTF-8
 
 	lineY := 10 + logoHeight + 4
 	pdf.SetDrawColor(220, 220, 220)
@@ -143,8 +143,9 @@ func drawTable(pdf *gofpdf.Fpdf, headers []string, data [][]string, colWidths []
 	}
 }
 
-// GenerateReportPDF é a função principal que monta o PDF.
-func GenerateReportPDF(reportData []models.RelatorioCategoria, transactions []models.Movimentacao, chartImageBase64 string) (*gofpdf.Fpdf, error) {
+// newDocument cria o PDF com as fontes, o cabeçalho (com o subtítulo informado) e o rodapé
+// padrão, já com a primeira página.
+func newDocument(subtitle string) *gofpdf.Fpdf {
 	pdf := gofpdf.New("P", "mm", "A4", "")
 
 	// --- REGISTRO DA FONTE UTF-8 ---
@@ -154,14 +155,20 @@ func GenerateReportPDF(reportData []models.RelatorioCategoria, transactions []mo
 	// CORREÇÃO: Usando o arquivo da fonte em negrito para o estilo "B".
 	pdf.AddUTF8Font(fontName, "B", "fonts/RedHatText-Bold.ttf")
 	
-	pdf.SetHeaderFunc(func() { headerFunc(pdf) })
+	pdf.SetHeaderFunc(func() { headerFunc(pdf, subtitle) })
 	pdf.SetFooterFunc(func() { footerFunc(pdf) })
 
-	pdf.SetTitle("Relatório Financeiro", true)
+	pdf.SetTitle(subtitle, true)
 	pdf.SetAuthor("Minhas Economias", true)
 	pdf.AliasNbPages("{nb}")
 	pdf.SetMargins(margin, 15, margin)
 	pdf.AddPage()
+	return pdf
+}
+
+// GenerateReportPDF é a função principal que monta o PDF.
+func GenerateReportPDF(reportData []models.RelatorioCategoria, transactions []models.Movimentacao, chartImageBase64 string) (*gofpdf.Fpdf, error) {
+	pdf := newDocument("Relatório Financeiro")
 
 	if chartImageBase64 != "" {
 		drawChart(pdf, chartImageBase64)
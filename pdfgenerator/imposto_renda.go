@@ -0,0 +1,109 @@
+package pdfgenerator
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/jung-kurt/gofpdf"
+	"minhas_economias/models"
+)
+
+// nomesCategorias são os rótulos das categorias de apuração no relatório.
+var nomesCategorias = map[string]string{
+	models.CategoriaComum:    "Ações (comum)",
+	models.CategoriaDayTrade: "Day trade",
+	models.CategoriaFII:      "FII",
+}
+
+// sectionTitle escreve o título de uma seção do relatório.
+func sectionTitle(pdf *gofpdf.Fpdf, title string) {
+	pdf.SetFont(fontName, "B", 12)
+	pdf.SetTextColor(50, 50, 50)
+	pdf.Cell(0, 8, title)
+	pdf.Ln(10)
+}
+
+// dataBR converte AAAA-MM-DD (ou AAAA-MM) para o formato brasileiro.
+func dataBR(data string) string {
+	partes := strings.Split(data, "-")
+	for i, j := 0, len(partes)-1; i < j; i, j = i+1, j-1 {
+		partes[i], partes[j] = partes[j], partes[i]
+	}
+	return strings.Join(partes, "/")
+}
+
+// GenerateImpostoRendaPDF monta o relatório anual de ganhos de capital: o resumo dos DARFs
+// (código 6015) e, para cada mês com vendas, a apuração por categoria e as vendas realizadas.
+func GenerateImpostoRendaPDF(apuracao models.ApuracaoAnual) (*gofpdf.Fpdf, error) {
+	pdf := newDocument(fmt.Sprintf("Apuração de Ganhos de Capital %d", apuracao.Ano))
+	pdf.Ln(5)
+
+	sectionTitle(pdf, "Resumo dos DARFs (código 6015)")
+	var resumo [][]string
+	for _, m := range apuracao.Meses {
+		vencimento := "abaixo do mínimo"
+		if m.ValorDARF > 0 {
+			vencimento = dataBR(m.Vencimento)
+		}
+		resumo = append(resumo, []string{
+			dataBR(m.Mes), fmt.Sprintf("%.2f", m.ImpostoDevido), fmt.Sprintf("%.2f", m.IRRFCompensado),
+			fmt.Sprintf("%.2f", m.SaldoAnterior), vencimento, fmt.Sprintf("%.2f", m.ValorDARF),
+		})
+	}
+	resumo = append(resumo, []string{"TOTAL", "", "", "", "", fmt.Sprintf("%.2f", apuracao.TotalDARF)})
+	drawTable(pdf, []string{"Mês", "Imposto (R$)", "IRRF (R$)", "Saldo anterior (R$)", "Vencimento", "DARF (R$)"}, resumo,
+		[]float64{25.0, 35.0, 30.0, 35.0, 35.0, 30.0})
+
+	pdf.Ln(5)
+	pdf.SetFont(fontName, "", 9)
+	pdf.SetTextColor(80, 80, 80)
+	var prejuizos []string
+	for _, cat := range []string{models.CategoriaComum, models.CategoriaDayTrade, models.CategoriaFII} {
+		prejuizos = append(prejuizos, fmt.Sprintf("%s: R$ %.2f", nomesCategorias[cat], apuracao.PrejuizosACompensar[cat]))
+	}
+	pdf.MultiCell(0, 5, fmt.Sprintf("Lucro isento (vendas de ações até R$ 20.000,00 no mês): R$ %.2f\nPrejuízos a compensar em %d: %s\nImposto abaixo do mínimo ainda não recolhido: R$ %.2f",
+		apuracao.LucroIsento, apuracao.Ano+1, strings.Join(prejuizos, "; "), apuracao.SaldoAPagar), "", "L", false)
+	if len(apuracao.CustoDesconhecido) > 0 {
+		pdf.SetTextColor(180, 30, 30)
+		pdf.MultiCell(0, 5, fmt.Sprintf("Atenção: %s têm \"Saldo anterior\" sem preço médio; o custo das vendas (marcadas com *) está subestimado e o imposto, superestimado. Informe o preço no livro de operações antes de pagar os DARFs.",
+			strings.Join(apuracao.CustoDesconhecido, ", ")), "", "L", false)
+	}
+
+	for _, m := range apuracao.Meses {
+		pdf.Ln(8)
+		sectionTitle(pdf, "Apuração de "+dataBR(m.Mes))
+		var linhas [][]string
+		for _, cat := range m.Categorias {
+			nome := nomesCategorias[cat.Categoria]
+			if cat.Isento {
+				nome += " (isento)"
+			}
+			linhas = append(linhas, []string{
+				nome, fmt.Sprintf("%.2f", cat.Vendas), fmt.Sprintf("%.2f", cat.Resultado), fmt.Sprintf("%.2f", cat.PrejuizoCompensado),
+				fmt.Sprintf("%.2f", cat.BaseCalculo), fmt.Sprintf("%.0f%%", cat.Aliquota*100), fmt.Sprintf("%.2f", cat.Imposto),
+			})
+		}
+		drawTable(pdf, []string{"Categoria", "Vendas (R$)", "Resultado (R$)", "Compensado (R$)", "Base (R$)", "Alíquota", "Imposto (R$)"}, linhas,
+			[]float64{35.0, 27.0, 27.0, 28.0, 25.0, 20.0, 28.0})
+
+		pdf.Ln(4)
+		var vendas [][]string
+		for _, v := range m.Vendas {
+			operacao := "Comum"
+			if v.DayTrade {
+				operacao = "Day trade"
+			}
+			if v.CustoDesconhecido {
+				operacao += " *"
+			}
+			vendas = append(vendas, []string{
+				dataBR(v.Data), v.Ticker, operacao, fmt.Sprintf("%d", v.Quantidade), fmt.Sprintf("%.2f", v.ValorVenda),
+				fmt.Sprintf("%.2f", v.Custo+v.Taxas), fmt.Sprintf("%.2f", v.Resultado),
+			})
+		}
+		drawTable(pdf, []string{"Data", "Ticker", "Operação", "Qtde.", "Venda (R$)", "Custo (R$)", "Resultado (R$)"}, vendas,
+			[]float64{25.0, 25.0, 25.0, 20.0, 32.0, 32.0, 31.0})
+	}
+
+	return pdf, pdf.Error()
+}
@@ -0,0 +1,126 @@
+document.addEventListener('DOMContentLoaded', () => {
+    // Chamada JSON às rotas de administração; devolve o corpo ou lança o erro retornado.
+    async function adminRequest(url, method, payload) {
+        const options = {
+            method: method,
+            headers: { 'Accept': 'application/json', 'Content-Type': 'application/json' },
+        };
+        if (payload !== undefined) {
+            options.body = JSON.stringify(payload);
+        }
+        const response = await fetch(url, options);
+        const result = await response.json();
+        if (!response.ok) {
+            throw new Error(result.error || 'Erro desconhecido.');
+        }
+        return result;
+    }
+
+    // --- Ações por usuário ---
+    document.querySelectorAll('.admin-action').forEach(button => {
+        button.addEventListener('click', async () => {
+            const row = button.closest('tr');
+            const userId = row.dataset.userId;
+            const email = row.dataset.userEmail;
+            const action = button.dataset.action;
+            const value = button.dataset.enabled === 'true';
+
+            try {
+                let result;
+                if (action === 'disabled') {
+                    if (!confirm(`${value ? 'Desativar' : 'Reativar'} a conta ${email}?`)) return;
+                    result = await adminRequest(`/api/admin/users/${userId}/disabled`, 'POST', { value });
+                } else if (action === 'admin') {
+                    if (!confirm(`${value ? 'Conceder' : 'Remover'} o papel de administrador de ${email}?`)) return;
+                    result = await adminRequest(`/api/admin/users/${userId}/admin`, 'POST', { value });
+                } else if (action === 'password') {
+                    const password = prompt(`Nova senha para ${email} (deixe em branco para gerar uma senha temporária):`);
+                    if (password === null) return;
+                    result = await adminRequest(`/api/admin/users/${userId}/password`, 'POST', { password });
+                    if (result.temporary_password) {
+                        prompt('Senha temporária gerada. Copie e envie ao usuário:', result.temporary_password);
+                    }
+                } else if (action === 'delete') {
+                    if (!confirm(`Excluir ${email} e TODOS os seus dados? Esta ação não pode ser desfeita.`)) return;
+                    result = await adminRequest(`/api/admin/users/${userId}`, 'DELETE');
+                }
+                alert(result.message);
+                window.location.reload();
+            } catch (error) {
+                alert('Erro: ' + error.message);
+            }
+        });
+    });
+
+    // --- Criação de usuário ---
+    const createForm = document.getElementById('admin-create-user-form');
+    if (createForm) {
+        createForm.addEventListener('submit', async (event) => {
+            event.preventDefault();
+            const formData = new FormData(createForm);
+            try {
+                const result = await adminRequest('/api/admin/users', 'POST', {
+                    email: formData.get('email'),
+                    password: formData.get('password'),
+                    is_admin: formData.get('is_admin') === 'on',
+                });
+                alert(result.message);
+                window.location.reload();
+            } catch (error) {
+                alert('Erro: ' + error.message);
+            }
+        });
+    }
+
+    // --- Pesquisa no log de auditoria ---
+    const auditForm = document.getElementById('audit-search-form');
+    const auditBody = document.getElementById('audit-log-body');
+    if (auditForm && auditBody) {
+        auditForm.addEventListener('submit', async (event) => {
+            event.preventDefault();
+            const q = new FormData(auditForm).get('q') || '';
+            try {
+                const entries = await adminRequest(`/api/admin/audit?q=${encodeURIComponent(q)}`, 'GET') || [];
+                auditBody.innerHTML = '';
+                if (entries.length === 0) {
+                    auditBody.innerHTML = '<tr><td colspan="7" class="text-center text-gray-500">Nenhum registro encontrado.</td></tr>';
+                    return;
+                }
+                entries.forEach(entry => {
+                    const tr = document.createElement('tr');
+                    const cells = [
+                        new Date(entry.created_at).toLocaleString('pt-BR'),
+                        entry.user_email, entry.action, entry.path,
+                        entry.entity_type ? `${entry.entity_type} #${entry.entity_id}` : '',
+                        entry.status, entry.latency_ms,
+                    ];
+                    cells.forEach((value, i) => {
+                        const td = document.createElement('td');
+                        td.textContent = value;
+                        if (i >= 5) td.className = 'text-right';
+                        tr.appendChild(td);
+                    });
+                    auditBody.appendChild(tr);
+                });
+            } catch (error) {
+                alert('Erro: ' + error.message);
+            }
+        });
+    }
+
+    // --- Limpeza manual do log de auditoria ---
+    const purgeButton = document.getElementById('audit-purge-button');
+    if (purgeButton) {
+        purgeButton.addEventListener('click', async () => {
+            const days = prompt('Manter os registros dos últimos quantos dias? (deixe em branco para usar AUDIT_RETENTION_DAYS)');
+            if (days === null) return;
+            try {
+                const result = await adminRequest('/api/admin/audit/purge', 'POST', { days: parseInt(days, 10) || 0 });
+                alert(result.message);
+                window.location.reload();
+            } catch (error) {
+                alert('Erro: ' + error.message);
+            }
+        });
+    }
+});
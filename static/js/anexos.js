@@ -0,0 +1,106 @@
+document.addEventListener('DOMContentLoaded', () => {
+    const dialog = document.getElementById('attachments-dialog');
+    if (!dialog) return;
+
+    const list = document.getElementById('attachments-list');
+    const uploadForm = document.getElementById('attachments-upload-form');
+    const canEdit = dialog.dataset.canEdit === 'true';
+    let currentId = null;
+
+    function formatSize(bytes) {
+        return bytes >= 1024 * 1024 ? `${(bytes / (1024 * 1024)).toFixed(1)} MB` : `${Math.round(bytes / 1024)} KB`;
+    }
+
+    function renderAttachment(anexo) {
+        const item = document.createElement('li');
+        item.className = 'flex items-center gap-3';
+
+        const preview = document.createElement('a');
+        preview.href = anexo.url;
+        preview.target = '_blank';
+        if (anexo.thumbnail_url) {
+            const img = document.createElement('img');
+            img.src = anexo.thumbnail_url;
+            img.alt = anexo.nome_arquivo;
+            img.className = 'w-16 h-16 object-cover rounded-md';
+            preview.appendChild(img);
+        } else {
+            preview.textContent = '📄';
+            preview.className = 'text-3xl w-16 text-center';
+        }
+        item.appendChild(preview);
+
+        const info = document.createElement('a');
+        info.href = anexo.url;
+        info.target = '_blank';
+        info.className = 'flex-1 text-blue-600 dark:text-blue-400 hover:underline break-all';
+        info.textContent = `${anexo.nome_arquivo} (${formatSize(anexo.tamanho)})`;
+        item.appendChild(info);
+
+        if (canEdit) {
+            const remove = document.createElement('button');
+            remove.type = 'button';
+            remove.className = 'delete-button rounded-md';
+            remove.textContent = 'Excluir';
+            remove.addEventListener('click', async () => {
+                if (!confirm(`Excluir o anexo "${anexo.nome_arquivo}"?`)) return;
+                const response = await fetch(anexo.url, { method: 'DELETE', headers: { 'Accept': 'application/json' } });
+                if (!response.ok) {
+                    const err = await response.json();
+                    alert(`Erro: ${err.error}`);
+                    return;
+                }
+                loadAttachments();
+            });
+            item.appendChild(remove);
+        }
+        return item;
+    }
+
+    async function loadAttachments() {
+        list.innerHTML = '<li class="text-gray-500">Carregando...</li>';
+        try {
+            const response = await fetch(`/movimentacoes/${currentId}/anexos`, { headers: { 'Accept': 'application/json' } });
+            const result = await response.json();
+            if (!response.ok) throw new Error(result.error || 'Erro desconhecido.');
+            list.innerHTML = '';
+            if (result.length === 0) {
+                list.innerHTML = '<li class="text-gray-500">Nenhum anexo.</li>';
+            }
+            result.forEach(anexo => list.appendChild(renderAttachment(anexo)));
+        } catch (error) {
+            list.innerHTML = '';
+            alert('Erro: ' + error.message);
+        }
+    }
+
+    document.querySelectorAll('.attachments-button').forEach(button => {
+        button.addEventListener('click', () => {
+            currentId = button.dataset.id;
+            document.getElementById('attachments-title').textContent = button.dataset.descricao;
+            dialog.showModal();
+            loadAttachments();
+        });
+    });
+
+    document.getElementById('attachments-close').addEventListener('click', () => dialog.close());
+
+    if (uploadForm) {
+        uploadForm.addEventListener('submit', async (event) => {
+            event.preventDefault();
+            try {
+                const response = await fetch(`/movimentacoes/${currentId}/anexos`, {
+                    method: 'POST',
+                    headers: { 'Accept': 'application/json' },
+                    body: new FormData(uploadForm)
+                });
+                const result = await response.json();
+                if (!response.ok) throw new Error(result.error || 'Erro desconhecido.');
+                uploadForm.reset();
+                loadAttachments();
+            } catch (error) {
+                alert('Erro: ' + error.message);
+            }
+        });
+    }
+});
@@ -117,4 +117,88 @@ document.addEventListener('DOMContentLoaded', () => {
             }
         });
     }
+
+    // --- Encerrar sessões em outros dispositivos ---
+    const logoutOthersButton = document.getElementById('logout-others-button');
+    if (logoutOthersButton) {
+        logoutOthersButton.addEventListener('click', async () => {
+            if (!confirm('Deseja encerrar a sessão em todos os outros dispositivos?')) {
+                return;
+            }
+            logoutOthersButton.disabled = true;
+            try {
+                const response = await fetch('/api/user/sessions/logout-others', {
+                    method: 'POST',
+                    headers: { 'Accept': 'application/json' },
+                });
+                const result = await response.json();
+                if (response.ok) {
+                    alert(result.message);
+                    window.location.reload();
+                } else {
+                    alert('Erro: ' + result.error);
+                }
+            } catch (error) {
+                console.error('Erro de rede ao encerrar sessões:', error);
+                alert('Erro de conexão. Não foi possível encerrar as sessões.');
+            } finally {
+                logoutOthersButton.disabled = false;
+            }
+        });
+    }
+
+    // --- Lares compartilhados ---
+    async function householdRequest(url, method, payload) {
+        try {
+            const response = await fetch(url, {
+                method: method,
+                headers: { 'Content-Type': 'application/json', 'Accept': 'application/json' },
+                body: payload ? JSON.stringify(payload) : undefined,
+            });
+            const result = await response.json();
+            if (response.ok) {
+                window.location.reload();
+            } else {
+                alert('Erro: ' + result.error);
+            }
+        } catch (error) {
+            console.error('Erro de rede ao atualizar lar compartilhado:', error);
+            alert('Erro de conexão. Não foi possível concluir a operação.');
+        }
+    }
+
+    const createHouseholdForm = document.getElementById('create-household-form');
+    if (createHouseholdForm) {
+        createHouseholdForm.addEventListener('submit', (event) => {
+            event.preventDefault();
+            householdRequest('/api/households', 'POST', { name: createHouseholdForm.elements.name.value });
+        });
+    }
+
+    document.querySelectorAll('.add-member-form').forEach((form) => {
+        form.addEventListener('submit', (event) => {
+            event.preventDefault();
+            householdRequest(`/api/households/${form.dataset.householdId}/members`, 'POST', {
+                email: form.elements.email.value,
+                role: form.elements.role.value,
+            });
+        });
+    });
+
+    document.querySelectorAll('.remove-member-button, .leave-household-button').forEach((button) => {
+        button.addEventListener('click', () => {
+            const message = button.classList.contains('leave-household-button')
+                ? 'Deseja sair deste lar? Você perderá acesso às contas e transações compartilhadas.'
+                : 'Deseja remover este membro do lar?';
+            if (!confirm(message)) return;
+            householdRequest(`/api/households/${button.dataset.householdId}/members/${button.dataset.userId}`, 'DELETE');
+        });
+    });
+
+    document.querySelectorAll('.delete-household-button').forEach((button) => {
+        button.addEventListener('click', () => {
+            if (!confirm('Excluir o lar apaga todas as contas e transações compartilhadas. Deseja continuar?')) return;
+            householdRequest(`/api/households/${button.dataset.householdId}`, 'DELETE');
+        });
+    });
 });
@@ -0,0 +1,57 @@
+// static/js/csrf.js
+
+/**
+ * Proteção CSRF no navegador.
+ * O servidor guarda o token na sessão e o espelha no cookie legível "csrf_token".
+ * Este script o anexa automaticamente a:
+ *  - toda chamada fetch que altera estado (cabeçalho X-CSRF-Token);
+ *  - todo formulário POST enviado (campo oculto csrf_token).
+ * Deve ser carregado no <head>, antes dos scripts de cada página.
+ */
+(function () {
+    const SAFE_METHODS = ['GET', 'HEAD', 'OPTIONS'];
+
+    function getCsrfToken() {
+        const match = document.cookie.match(/(?:^|;\s*)csrf_token=([^;]+)/);
+        return match ? decodeURIComponent(match[1]) : '';
+    }
+
+    function isSameOrigin(url) {
+        try {
+            return new URL(url, window.location.href).origin === window.location.origin;
+        } catch (e) {
+            return false;
+        }
+    }
+
+    const originalFetch = window.fetch.bind(window);
+    window.fetch = function (input, init) {
+        init = init || {};
+        const isRequest = input instanceof Request;
+        const url = isRequest ? input.url : String(input);
+        const method = (init.method || (isRequest ? input.method : 'GET')).toUpperCase();
+
+        if (!SAFE_METHODS.includes(method) && isSameOrigin(url)) {
+            const headers = new Headers(init.headers || (isRequest ? input.headers : undefined));
+            headers.set('X-CSRF-Token', getCsrfToken());
+            init = Object.assign({}, init, { headers: headers });
+        }
+        return originalFetch(input, init);
+    };
+
+    // Fase de captura: o campo é preenchido antes dos handlers de submit de cada página.
+    document.addEventListener('submit', (event) => {
+        const form = event.target;
+        if ((form.getAttribute('method') || 'GET').toUpperCase() !== 'POST') return;
+
+        let field = form.querySelector('input[name="csrf_token"]');
+        if (!field) {
+            field = document.createElement('input');
+            field.type = 'hidden';
+            field.name = 'csrf_token';
+            form.appendChild(field);
+        }
+        field.value = getCsrfToken();
+    }, true);
+
+})();
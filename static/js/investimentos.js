@@ -15,6 +15,22 @@ document.addEventListener('DOMContentLoaded', () => {
         }
     }
 
+    // Preenche preço médio e resultado não realizado; ativos sem operações ficam com "—".
+    function updateResultado(row, ativo) {
+        const precoMedioCell = row.querySelector('[data-field="precoMedio"]');
+        const resultadoCell = row.querySelector('[data-field="resultado"]');
+        if (!ativo.custo_total) {
+            precoMedioCell.textContent = '—';
+            resultadoCell.textContent = '—';
+            resultadoCell.className = 'text-right';
+            return;
+        }
+        precoMedioCell.textContent = ativo.preco_medio.toFixed(2);
+        const sinal = ativo.resultado_nao_realizado >= 0 ? '+' : '';
+        resultadoCell.textContent = `${sinal}${ativo.resultado_nao_realizado.toFixed(2)} (${sinal}${ativo.resultado_percent.toFixed(2)}%)`;
+        resultadoCell.className = `text-right font-bold ${ativo.resultado_nao_realizado >= 0 ? 'text-green-500 dark:text-green-400' : 'text-red-500'}`;
+    }
+
     function updateTables(data) {
         // Atualiza cotação do dólar
         const dolarDisplay = document.getElementById('dolar-quote-display');
@@ -29,6 +45,7 @@ document.addEventListener('DOMContentLoaded', () => {
                 if (row) {
                     row.querySelector('[data-field="cotacao"]').textContent = acao.cotacao.toFixed(2);
                     row.querySelector('[data-field="valorTotal"]').textContent = acao.valor_total.toFixed(2);
+                    updateResultado(row, acao);
                     row.querySelector('[data-field="pvp"]').textContent = acao.pvp.toFixed(2);
                     row.querySelector('[data-field="divYield"]').textContent = `${acao.div_yield_percent.toFixed(2)}%`;
                     const grahamCell = row.querySelector('[data-field="valorGraham"]');
@@ -45,6 +62,7 @@ document.addEventListener('DOMContentLoaded', () => {
                 if (row) {
                     row.querySelector('[data-field="cotacao"]').textContent = fii.cotacao.toFixed(2);
                     row.querySelector('[data-field="valorTotal"]').textContent = fii.valor_total.toFixed(2);
+                    updateResultado(row, fii);
                     row.querySelector('[data-field="segmento"]').textContent = fii.segmento;
                     row.querySelector('[data-field="pvp"]').textContent = fii.pvp.toFixed(2);
                     row.querySelector('[data-field="divYield"]').textContent = `${fii.div_yield_percent.toFixed(2)}%`;
@@ -65,6 +83,38 @@ document.addEventListener('DOMContentLoaded', () => {
                 }
             });
         }
+
+        // Atualiza tabela de Criptoativos
+        if (data.cripto) {
+            data.cripto.forEach(ativo => {
+                const row = document.querySelector(`#cripto-table-body tr[data-ticker="${ativo.simbolo}"]`);
+                if (row) {
+                    const semPreco = !ativo.preco_usd;
+                    row.querySelector('[data-field="precoUSD"]').textContent = semPreco ? '—' : ativo.preco_usd.toFixed(2);
+                    row.querySelector('[data-field="precoBRL"]').textContent = semPreco ? '—' : ativo.preco_brl.toFixed(2);
+                    row.querySelector('[data-field="valorTotalUSD"]').textContent = semPreco ? '—' : ativo.valor_total_usd.toFixed(2);
+                    row.querySelector('[data-field="valorTotalBRL"]').textContent = semPreco ? '—' : ativo.valor_total_brl.toFixed(2);
+                    if (ativo.data_preco) row.title = `Preço de ${ativo.data_preco} (${ativo.fonte})`;
+                }
+            });
+        }
+
+        // Totais por classe, em reais
+        if (data.totais) {
+            Object.entries(data.totais).forEach(([classe, valor]) => {
+                const cell = document.querySelector(`#totais-carteira [data-total="${classe}"]`);
+                if (cell) {
+                    cell.textContent = `R$ ${valor.toLocaleString('pt-BR', { minimumFractionDigits: 2, maximumFractionDigits: 2 })}`;
+                }
+            });
+        }
+
+        // Momento do snapshot de cotações usado na resposta
+        const atualizadoEm = document.getElementById('precos-atualizados-em');
+        if (atualizadoEm && data.atualizado_em) {
+            const quando = new Date(data.atualizado_em);
+            atualizadoEm.textContent = `Cotações de ${quando.toLocaleDateString('pt-BR')} às ${quando.toLocaleTimeString('pt-BR', { hour: '2-digit', minute: '2-digit' })}.`;
+        }
     }
 
     // Chama a função principal ao carregar a página
@@ -128,7 +178,8 @@ document.addEventListener('DOMContentLoaded', () => {
                 const response = await fetch(apiUrl, {
                     method: 'POST',
                     headers: { 'Content-Type': 'application/json' },
-                    body: JSON.stringify({ quantidade: parseFloat(newQuantity.replace(',', '.')) })
+                    // Criptoativos mandam a quantidade como texto para não perder casas decimais.
+                    body: JSON.stringify({ quantidade: assetType === 'cripto' ? newQuantity.trim() : parseFloat(newQuantity.replace(',', '.')) })
                 });
                 const result = await response.json();
                 if (response.ok) {
@@ -149,7 +200,7 @@ document.addEventListener('DOMContentLoaded', () => {
             const ticker = event.target.dataset.ticker;
             const assetType = event.target.dataset.type;
 
-            if (!confirm(`Tem certeza que deseja excluir o ativo ${ticker}? Esta ação não pode ser desfeita.`)) {
+            if (!confirm(`Tem certeza que deseja excluir o ativo ${ticker}? As operações registradas também serão excluídas. Esta ação não pode ser desfeita.`)) {
                 return;
             }
 
@@ -170,39 +221,761 @@ document.addEventListener('DOMContentLoaded', () => {
         });
     });
 
-    // --- NOVA SEÇÃO: ADIÇÃO DE ATIVOS NACIONAIS ---
+    // --- SEÇÃO: OPERAÇÕES DE ATIVOS NACIONAIS (COMPRA/VENDA) ---
+    const parseDecimal = (value) => parseFloat((value || '0').replace(',', '.')) || 0;
+
     const addNacionalForm = document.getElementById('add-nacional-form');
     if (addNacionalForm) {
+        const dataInput = document.getElementById('add-nacional-data');
+        dataInput.value = new Date().toISOString().slice(0, 10);
+
         addNacionalForm.addEventListener('submit', async (event) => {
             event.preventDefault();
-            const ticker = document.getElementById('add-nacional-ticker').value.toUpperCase();
-            const tipo = document.getElementById('add-nacional-tipo').value;
+            const ticker = document.getElementById('add-nacional-ticker').value.trim().toUpperCase();
+            const tipoAtivo = document.getElementById('add-nacional-tipo').value;
+            const tipo = document.getElementById('add-nacional-operacao').value;
             const quantidade = document.getElementById('add-nacional-quantidade').value;
+            const preco = document.getElementById('add-nacional-preco').value;
 
-            if (!ticker || !tipo || !quantidade) {
-                alert('Por favor, preencha todos os campos.');
+            if (!ticker || !quantidade || !preco || !dataInput.value) {
+                alert('Por favor, preencha ticker, data, quantidade e preço.');
+                return;
+            }
+
+            const operacao = {
+                ticker: ticker,
+                tipo_ativo: tipoAtivo,
+                tipo: tipo,
+                data: dataInput.value,
+                quantidade: parseInt(quantidade, 10),
+                preco: parseDecimal(preco),
+                taxas: parseDecimal(document.getElementById('add-nacional-taxas').value),
+                corretora: document.getElementById('add-nacional-corretora').value
+            };
+            try {
+                let response = await fetch('/investimentos/operacoes', {
+                    method: 'POST',
+                    headers: { 'Content-Type': 'application/json' },
+                    body: JSON.stringify(operacao)
+                });
+                let result = await response.json();
+                if (!response.ok && result.precisa_preco_medio_anterior) {
+                    // Primeira operação de um ativo cadastrado antes do livro: o preço médio
+                    // da quantidade já em carteira é o custo dela na apuração do IR.
+                    const precoAnterior = prompt(`${ticker} já tinha uma quantidade em carteira. Informe o preço médio pago por ela:`);
+                    if (!precoAnterior) return;
+                    operacao.preco_medio_anterior = parseDecimal(precoAnterior);
+                    response = await fetch('/investimentos/operacoes', {
+                        method: 'POST',
+                        headers: { 'Content-Type': 'application/json' },
+                        body: JSON.stringify(operacao)
+                    });
+                    result = await response.json();
+                }
+                if (response.ok) {
+                    alert(result.message);
+                    window.location.reload();
+                } else {
+                    alert(`Erro: ${result.error}`);
+                }
+            } catch (error) {
+                alert('Erro de comunicação ao tentar registrar a operação.');
+            }
+        });
+    }
+
+    // --- SEÇÃO: IMPORTAÇÃO DA CARTEIRA (PRÉVIA E CONFIRMAÇÃO) ---
+    const importarForm = document.getElementById('importar-form');
+    if (importarForm) {
+        const previa = document.getElementById('importar-previa');
+        const previaBody = document.getElementById('importar-table-body');
+        const ignorados = document.getElementById('importar-ignorados');
+        const nomesClasses = { ACAO: 'Ação', FII: 'FII', INTERNACIONAL: 'Exterior' };
+        const nomesEfeitos = { incluir: 'Incluir', atualizar: 'Atualizar', manter: 'Sem alteração', remover: 'Remover', ignorar: 'Ignorar' };
+        const coresEfeitos = { incluir: 'text-green-500', atualizar: 'text-yellow-600', remover: 'text-red-500', ignorar: 'text-gray-500' };
+
+        // O arquivo é reenviado na confirmação; o servidor recalcula o plano sobre a carteira atual.
+        async function enviarImportacao(confirmar) {
+            const formData = new FormData(importarForm);
+            if (confirmar) formData.append('confirmar', 'true');
+            const response = await fetch('/investimentos/importar', { method: 'POST', body: formData });
+            return { ok: response.ok, result: await response.json() };
+        }
+
+        function renderPrevia(plano) {
+            previaBody.innerHTML = '';
+            ignorados.innerHTML = '';
+            if (plano.itens.length === 0) {
+                previaBody.innerHTML = '<tr><td colspan="7" class="no-data">Nenhum ativo a importar.</td></tr>';
+            }
+            plano.itens.forEach(item => {
+                const tr = document.createElement('tr');
+                tr.className = 'table-row-item';
+                [nomesClasses[item.classe] || item.classe, item.ticker, nomesEfeitos[item.acao],
+                    item.quantidade_atual.toLocaleString('pt-BR'), item.quantidade_nova.toLocaleString('pt-BR'),
+                    item.operacoes ? String(item.operacoes) : '—', item.aviso || ''].forEach((texto, i) => {
+                    const td = document.createElement('td');
+                    td.textContent = texto;
+                    if (i >= 3 && i <= 5) td.className = 'text-right';
+                    if (i === 2) td.className = `font-bold ${coresEfeitos[item.acao] || ''}`;
+                    tr.appendChild(td);
+                });
+                previaBody.appendChild(tr);
+            });
+            plano.ignorados.forEach(texto => {
+                const li = document.createElement('li');
+                li.textContent = texto;
+                ignorados.appendChild(li);
+            });
+            previa.classList.remove('select-hide');
+        }
+
+        importarForm.addEventListener('submit', async (event) => {
+            event.preventDefault();
+            try {
+                const { ok, result } = await enviarImportacao(false);
+                if (ok) {
+                    renderPrevia(result);
+                } else {
+                    alert(`Erro: ${result.error}`);
+                }
+            } catch (error) {
+                alert('Erro de comunicação ao tentar ler o arquivo.');
+            }
+        });
+
+        document.getElementById('importar-cancelar').addEventListener('click', () => {
+            previa.classList.add('select-hide');
+            importarForm.reset();
+        });
+
+        document.getElementById('importar-confirmar').addEventListener('click', async () => {
+            if (document.getElementById('importar-modo').value === 'substituir' &&
+                !confirm('Os ativos que não estão no arquivo serão removidos da carteira, com as suas operações. Continuar?')) {
+                return;
+            }
+            try {
+                const { ok, result } = await enviarImportacao(true);
+                if (ok) {
+                    alert(result.message);
+                    window.location.reload();
+                } else {
+                    alert(`Erro: ${result.error}`);
+                }
+            } catch (error) {
+                alert('Erro de comunicação ao tentar importar a carteira.');
+            }
+        });
+
+        // Uma prévia só vale para o arquivo e o modo com que foi gerada.
+        importarForm.addEventListener('change', () => previa.classList.add('select-hide'));
+    }
+
+    // Livro de operações de um ativo, com exclusão de lançamentos.
+    const operacoesSection = document.getElementById('operacoes-section');
+    const operacoesBody = document.getElementById('operacoes-table-body');
+
+    async function showOperacoes(ticker) {
+        try {
+            const response = await fetch(`/api/investimentos/operacoes?ticker=${encodeURIComponent(ticker)}`);
+            const result = await response.json();
+            if (!response.ok) {
+                alert(`Erro: ${result.error}`);
                 return;
             }
+            const posicao = result.posicoes[0];
+            document.getElementById('operacoes-title').textContent = posicao
+                ? `Operações: ${ticker} (preço médio R$ ${posicao.preco_medio.toFixed(2)})`
+                : `Operações: ${ticker}`;
+            operacoesBody.innerHTML = '';
+            if (result.operacoes.length === 0) {
+                operacoesBody.innerHTML = '<tr><td colspan="7" class="no-data">Nenhuma operação registrada; a quantidade foi cadastrada manualmente.</td></tr>';
+            }
+            result.operacoes.forEach(op => {
+                const tr = document.createElement('tr');
+                tr.className = 'table-row-item';
+                const [ano, mes, dia] = op.data.split('-');
+                [`${dia}/${mes}/${ano}`, op.tipo === 'C' ? 'Compra' : 'Venda', op.quantidade, op.preco.toFixed(2), op.taxas.toFixed(2), op.corretora].forEach((valor, i) => {
+                    const td = document.createElement('td');
+                    td.textContent = valor;
+                    if (i >= 2 && i <= 4) td.className = 'text-right';
+                    tr.appendChild(td);
+                });
+                const acoes = document.createElement('td');
+                acoes.className = 'action-buttons-cell';
+                const excluir = document.createElement('button');
+                excluir.className = 'delete-button rounded-md';
+                excluir.textContent = 'Excluir';
+                excluir.addEventListener('click', () => deleteOperacao(op, ticker));
+                acoes.appendChild(excluir);
+                tr.appendChild(acoes);
+                operacoesBody.appendChild(tr);
+            });
+            operacoesSection.classList.remove('select-hide');
+            operacoesSection.scrollIntoView({ behavior: 'smooth', block: 'start' });
+        } catch (error) {
+            alert('Erro de comunicação ao buscar as operações.');
+        }
+    }
+
+    async function deleteOperacao(op, ticker) {
+        if (!confirm(`Excluir a operação de ${op.tipo === 'C' ? 'compra' : 'venda'} de ${op.quantidade} ${ticker}?`)) {
+            return;
+        }
+        try {
+            const response = await fetch(`/investimentos/operacoes/${op.id}`, { method: 'DELETE' });
+            const result = await response.json();
+            if (response.ok) {
+                window.location.reload();
+            } else {
+                alert(`Erro: ${result.error}`);
+            }
+        } catch (error) {
+            alert('Erro de comunicação ao tentar excluir a operação.');
+        }
+    }
+
+    document.querySelectorAll('.operacoes-button').forEach(button => {
+        button.addEventListener('click', (event) => showOperacoes(event.target.dataset.ticker));
+    });
+
+    const closeOperacoesButton = document.getElementById('close-operacoes-button');
+    if (closeOperacoesButton) {
+        closeOperacoesButton.addEventListener('click', () => operacoesSection.classList.add('select-hide'));
+    }
+
+    // --- SEÇÃO: RENDA FIXA (AVALIAÇÃO NA CURVA E IR ESTIMADO) ---
+    const addRendaFixaForm = document.getElementById('add-renda-fixa-form');
+    if (addRendaFixaForm) {
+        const rendaFixaBody = document.getElementById('renda-fixa-table-body');
+        const formatMoney = (value) => value.toLocaleString('pt-BR', { minimumFractionDigits: 2, maximumFractionDigits: 2 });
+        const formatDate = (value) => value ? value.split('-').reverse().join('/') : '—';
+        const formatTaxa = (value) => value.toLocaleString('pt-BR', { maximumFractionDigits: 2 });
+        const rentabilidade = (t) => ({
+            PRE: `${formatTaxa(t.taxa)}% a.a.`,
+            CDI: `${formatTaxa(t.taxa)}% do CDI`,
+            IPCA: `IPCA + ${formatTaxa(t.taxa)}% a.a.`,
+            SELIC: `SELIC + ${formatTaxa(t.taxa)}% a.a.`,
+        })[t.indexador];
+        document.getElementById('rf-aplicacao').value = new Date().toISOString().slice(0, 10);
 
+        async function deleteTitulo(t) {
+            if (!confirm(`Excluir o título "${t.nome}"?`)) {
+                return;
+            }
             try {
-                const response = await fetch('/investimentos/nacional', {
+                const response = await fetch(`/investimentos/renda-fixa/${t.id}`, { method: 'DELETE' });
+                const result = await response.json();
+                if (response.ok) {
+                    loadRendaFixa();
+                } else {
+                    alert(`Erro: ${result.error}`);
+                }
+            } catch (error) {
+                alert('Erro de comunicação ao tentar excluir o título.');
+            }
+        }
+
+        async function loadRendaFixa() {
+            try {
+                const response = await fetch('/api/investimentos/renda-fixa');
+                const result = await response.json();
+                if (!response.ok) {
+                    rendaFixaBody.innerHTML = `<tr><td colspan="10" class="no-data">Erro: ${result.error}</td></tr>`;
+                    return;
+                }
+                rendaFixaBody.innerHTML = '';
+                if (result.titulos.length === 0) {
+                    rendaFixaBody.innerHTML = '<tr><td colspan="10" class="no-data">Nenhum título de renda fixa cadastrado.</td></tr>';
+                }
+                result.titulos.forEach(t => {
+                    const tr = document.createElement('tr');
+                    tr.className = 'table-row-item';
+                    if (t.aviso) tr.title = t.aviso;
+                    [
+                        t.nome + (t.isento ? ' (isento)' : '') + (t.aviso ? ' ⚠' : ''),
+                        rentabilidade(t),
+                        formatMoney(t.valor_aplicado),
+                        formatMoney(t.valor_bruto),
+                        t.isento ? '—' : `${formatMoney(t.imposto_estimado)} (${(t.aliquota_ir * 100).toLocaleString('pt-BR')}%)`,
+                        formatMoney(t.valor_liquido),
+                        formatDate(t.vencimento),
+                        formatMoney(t.valor_liquido_vencimento),
+                        t.liquidez === 'DIARIA' ? 'Diária' : 'No vencimento',
+                    ].forEach((valor, i) => {
+                        const td = document.createElement('td');
+                        td.textContent = valor;
+                        if ((i >= 2 && i <= 5) || i === 7) td.className = 'text-right';
+                        tr.appendChild(td);
+                    });
+                    const acoes = document.createElement('td');
+                    acoes.className = 'action-buttons-cell';
+                    const excluir = document.createElement('button');
+                    excluir.className = 'delete-button rounded-md';
+                    excluir.textContent = 'Excluir';
+                    excluir.addEventListener('click', () => deleteTitulo(t));
+                    acoes.appendChild(excluir);
+                    tr.appendChild(acoes);
+                    rendaFixaBody.appendChild(tr);
+                });
+                const totais = result.totais;
+                const indices = Object.entries(result.indices).map(([serie, data]) => `${serie} até ${formatDate(data)}`).join(', ');
+                document.getElementById('renda-fixa-resumo').textContent =
+                    `Aplicado: R$ ${formatMoney(totais.valor_aplicado)} · Bruto: R$ ${formatMoney(totais.valor_bruto)} · Líquido: R$ ${formatMoney(totais.valor_liquido)} · ` +
+                    `Líquido no vencimento: R$ ${formatMoney(totais.valor_liquido_vencimento)} · Índices importados: ${indices}`;
+            } catch (error) {
+                console.error('Falha ao buscar a renda fixa:', error);
+            }
+        }
+
+        addRendaFixaForm.addEventListener('submit', async (event) => {
+            event.preventDefault();
+            try {
+                const response = await fetch('/investimentos/renda-fixa', {
                     method: 'POST',
                     headers: { 'Content-Type': 'application/json' },
                     body: JSON.stringify({
-                        ticker: ticker,
-                        tipo: tipo,
-                        quantidade: parseInt(quantidade, 10)
+                        nome: document.getElementById('rf-nome').value,
+                        tipo: document.getElementById('rf-tipo').value,
+                        emissor: document.getElementById('rf-emissor').value,
+                        indexador: document.getElementById('rf-indexador').value,
+                        taxa: parseDecimal(document.getElementById('rf-taxa').value),
+                        valor_aplicado: parseDecimal(document.getElementById('rf-valor').value),
+                        data_aplicacao: document.getElementById('rf-aplicacao').value,
+                        vencimento: document.getElementById('rf-vencimento').value,
+                        liquidez: document.getElementById('rf-liquidez').value
                     })
                 });
                 const result = await response.json();
                 if (response.ok) {
-                    alert(result.message);
-                    window.location.reload();
+                    addRendaFixaForm.reset();
+                    document.getElementById('rf-aplicacao').value = new Date().toISOString().slice(0, 10);
+                    loadRendaFixa();
                 } else {
                     alert(`Erro: ${result.error}`);
                 }
             } catch (error) {
-                alert('Erro de comunicação ao tentar adicionar o ativo.');
+                alert('Erro de comunicação ao tentar adicionar o título.');
+            }
+        });
+
+        loadRendaFixa();
+    }
+
+    // --- SEÇÃO: DESEMPENHO (TWR, TIR E BENCHMARKS) ---
+    const desempenhoBody = document.getElementById('desempenho-table-body');
+    if (desempenhoBody) {
+        const benchmarks = [
+            { serie: 'IBOV', nome: 'Ibovespa', cor: '#2563eb' },
+            { serie: 'IFIX', nome: 'IFIX', cor: '#7c3aed' },
+            { serie: 'CDI', nome: 'CDI', cor: '#b45309' },
+            { serie: 'SP500', nome: 'S&P 500 (R$)', cor: '#dc2626' },
+        ];
+        const formatMoney = (value) => value.toLocaleString('pt-BR', { minimumFractionDigits: 2, maximumFractionDigits: 2 });
+        const formatDate = (value) => value.split('-').reverse().join('/');
+        const formatRetorno = (value) => value === null ? '—' : `${value.toLocaleString('pt-BR', { minimumFractionDigits: 2, maximumFractionDigits: 2 })}%`;
+        const isDarkMode = document.documentElement.classList.contains('dark');
+        const FONT_COLOR = isDarkMode ? '#e2e8f0' : '#475569';
+        let desempenhoChart;
+
+        function renderDesempenho(result) {
+            desempenhoBody.innerHTML = '';
+            if (result.periodos.length === 0) {
+                desempenhoBody.innerHTML = '<tr><td colspan="9" class="no-data">Registre operações ou títulos de renda fixa para acompanhar o desempenho.</td></tr>';
+            }
+            result.periodos.forEach(p => {
+                const tr = document.createElement('tr');
+                tr.className = 'table-row-item';
+                const celulas = [p.nome, formatDate(p.inicio), formatMoney(p.aportes), formatRetorno(p.twr), formatRetorno(p.xirr)]
+                    .concat(benchmarks.map(b => formatRetorno(p.benchmarks[b.serie])));
+                celulas.forEach((texto, i) => {
+                    const td = document.createElement('td');
+                    td.textContent = texto;
+                    if (i >= 2) td.className = 'text-right';
+                    if (i === 3 && p.twr !== null) td.className += ` font-bold ${p.twr >= 0 ? 'text-green-600' : 'text-red-500'}`;
+                    tr.appendChild(td);
+                });
+                desempenhoBody.appendChild(tr);
+            });
+            document.getElementById('desempenho-aviso').textContent = result.sem_historico.length
+                ? `Fora do cálculo (sem data de compra): ${result.sem_historico.join(', ')}.`
+                : '';
+
+            if (typeof Chart === 'undefined') return;
+            if (desempenhoChart) desempenhoChart.destroy();
+            desempenhoChart = new Chart(document.getElementById('desempenho-chart'), {
+                type: 'line',
+                data: {
+                    labels: result.serie.map(p => formatDate(p.data)),
+                    datasets: [{ label: 'Carteira', data: result.serie.map(p => p.carteira), borderColor: '#16a34a', backgroundColor: '#16a34a', borderWidth: 3 }]
+                        .concat(benchmarks.map(b => ({ label: b.nome, data: result.serie.map(p => p.benchmarks[b.serie]), borderColor: b.cor, backgroundColor: b.cor, borderWidth: 1.5 })))
+                },
+                options: {
+                    responsive: true,
+                    maintainAspectRatio: false,
+                    scales: { x: { ticks: { color: FONT_COLOR } }, y: { ticks: { color: FONT_COLOR } } },
+                    plugins: {
+                        legend: { labels: { color: FONT_COLOR } },
+                        title: { display: true, text: 'Retorno Acumulado (base 100)', color: FONT_COLOR, font: { size: 16 } },
+                        tooltip: { callbacks: { label: c => `${c.dataset.label}: ${c.parsed.y.toFixed(2).replace('.', ',')}` } }
+                    }
+                }
+            });
+        }
+
+        fetch('/api/investimentos/desempenho')
+            .then(response => response.json())
+            .then(result => {
+                if (result.error) {
+                    desempenhoBody.innerHTML = `<tr><td colspan="9" class="no-data">${result.error}</td></tr>`;
+                    return;
+                }
+                renderDesempenho(result);
+            })
+            .catch(error => console.error('Falha ao buscar o desempenho:', error));
+    }
+
+    // --- SEÇÃO: ALOCAÇÃO-ALVO E SUGESTÃO DE APORTE ---
+    const alocacaoForm = document.getElementById('alocacao-form');
+    if (alocacaoForm) {
+        const alvosAtivos = document.getElementById('alvos-ativos');
+        const classesBody = document.getElementById('alocacao-classes-body');
+        const ativosBody = document.getElementById('alocacao-ativos-body');
+        const formatMoney = (value) => value.toLocaleString('pt-BR', { minimumFractionDigits: 2, maximumFractionDigits: 2 });
+        const formatPercent = (value) => `${value.toLocaleString('pt-BR', { minimumFractionDigits: 1, maximumFractionDigits: 1 })}%`;
+        const nomesClasses = { ACAO: 'Ações', FII: 'FIIs', INTERNACIONAL: 'Exterior', RENDA_FIXA: 'Renda Fixa', CRIPTO: 'Cripto' };
+
+        function addAlvoAtivo(alvo = { classe: 'ACAO', ticker: '', percentual: '' }) {
+            const row = document.createElement('div');
+            row.className = 'form-row alvo-ativo';
+            const opcoes = Object.entries(nomesClasses)
+                .map(([classe, nome]) => `<option value="${classe}"${classe === alvo.classe ? ' selected' : ''}>${nome}</option>`).join('');
+            row.innerHTML = `<div class="form-group"><select class="select-input rounded-md" data-field="classe">${opcoes}</select></div>` +
+                '<div class="form-group"><input type="text" class="text-input rounded-md" data-field="ticker" placeholder="Ticker ou título"></div>' +
+                '<div class="form-group"><input type="text" class="text-input rounded-md" data-field="percentual" inputmode="decimal" placeholder="% na classe"></div>' +
+                '<div class="form-group"><button type="button" class="delete-button rounded-md">Remover</button></div>';
+            row.querySelector('[data-field="ticker"]').value = alvo.ticker;
+            row.querySelector('[data-field="percentual"]').value = alvo.percentual === '' ? '' : String(alvo.percentual).replace('.', ',');
+            row.querySelector('button').addEventListener('click', () => row.remove());
+            alvosAtivos.appendChild(row);
+        }
+
+        function celula(tr, texto, direita) {
+            const td = document.createElement('td');
+            td.textContent = texto;
+            if (direita) td.className = 'text-right';
+            tr.appendChild(td);
+            return td;
+        }
+
+        function renderAlocacao(alocacao) {
+            classesBody.innerHTML = '';
+            ativosBody.innerHTML = '';
+            if (alocacao.classes.length === 0) {
+                classesBody.innerHTML = '<tr><td colspan="6" class="no-data">Nenhum ativo ou alvo cadastrado.</td></tr>';
+            }
+            alocacao.classes.forEach(c => {
+                const tr = document.createElement('tr');
+                tr.className = 'table-row-item';
+                celula(tr, c.nome);
+                celula(tr, formatMoney(c.valor), true);
+                celula(tr, formatPercent(c.percentual_atual), true);
+                celula(tr, formatPercent(c.percentual_alvo), true);
+                const desvio = celula(tr, `${c.desvio >= 0 ? '+' : ''}${c.desvio.toLocaleString('pt-BR', { maximumFractionDigits: 1 })}`, true);
+                desvio.className = `text-right font-bold ${Math.abs(c.desvio) < 1 ? '' : (c.desvio > 0 ? 'text-red-500' : 'text-yellow-600')}`;
+                celula(tr, c.aporte ? formatMoney(c.aporte) : '—', true);
+                classesBody.appendChild(tr);
+                c.ativos.forEach(a => {
+                    const trAtivo = document.createElement('tr');
+                    trAtivo.className = 'table-row-item';
+                    celula(trAtivo, c.nome);
+                    celula(trAtivo, a.ticker);
+                    celula(trAtivo, formatMoney(a.valor), true);
+                    celula(trAtivo, formatPercent(a.percentual_atual), true);
+                    celula(trAtivo, a.tem_alvo ? formatPercent(a.percentual_alvo) : '—', true);
+                    celula(trAtivo, a.aporte ? formatMoney(a.aporte) : '—', true);
+                    celula(trAtivo, a.quantidade ? `${a.quantidade} × R$ ${formatMoney(a.cotacao)}` : '—', true);
+                    ativosBody.appendChild(trAtivo);
+                });
+            });
+            document.getElementById('alocacao-resumo').textContent = alocacao.aporte
+                ? `Carteira: R$ ${formatMoney(alocacao.total)} · Aporte: R$ ${formatMoney(alocacao.aporte)} · Desvio total após o aporte: ${alocacao.desvio_apos.toLocaleString('pt-BR')} p.p.`
+                : `Carteira: R$ ${formatMoney(alocacao.total)}. Informe um aporte para ver as compras sugeridas (sem vendas).`;
+        }
+
+        async function loadAlocacao(primeiraCarga) {
+            const aporte = document.getElementById('alocacao-aporte').value.trim();
+            try {
+                const response = await fetch(`/api/investimentos/alocacao?aporte=${encodeURIComponent(aporte)}`);
+                const result = await response.json();
+                if (!response.ok) {
+                    alert(`Erro: ${result.error}`);
+                    return;
+                }
+                if (primeiraCarga) {
+                    result.alvos.forEach(alvo => {
+                        if (alvo.ticker) {
+                            addAlvoAtivo(alvo);
+                        } else {
+                            document.getElementById(`alvo-${alvo.classe}`).value = String(alvo.percentual).replace('.', ',');
+                        }
+                    });
+                }
+                renderAlocacao(result.alocacao);
+            } catch (error) {
+                console.error('Falha ao buscar a alocação:', error);
+            }
+        }
+
+        document.getElementById('add-alvo-ativo').addEventListener('click', () => addAlvoAtivo());
+        document.getElementById('alocacao-calcular').addEventListener('click', () => loadAlocacao(false));
+
+        alocacaoForm.addEventListener('submit', async (event) => {
+            event.preventDefault();
+            const alvos = [];
+            alocacaoForm.querySelectorAll('input[data-classe]').forEach(input => {
+                if (input.value.trim() !== '') {
+                    alvos.push({ classe: input.dataset.classe, percentual: parseDecimal(input.value) });
+                }
+            });
+            alvosAtivos.querySelectorAll('.alvo-ativo').forEach(row => {
+                const ticker = row.querySelector('[data-field="ticker"]').value.trim();
+                if (ticker) {
+                    alvos.push({
+                        classe: row.querySelector('[data-field="classe"]').value,
+                        ticker: ticker,
+                        percentual: parseDecimal(row.querySelector('[data-field="percentual"]').value)
+                    });
+                }
+            });
+            try {
+                const response = await fetch('/investimentos/alocacao', {
+                    method: 'POST',
+                    headers: { 'Content-Type': 'application/json' },
+                    body: JSON.stringify({ alvos: alvos })
+                });
+                const result = await response.json();
+                if (response.ok) {
+                    loadAlocacao(false);
+                } else {
+                    alert(`Erro: ${result.error}`);
+                }
+            } catch (error) {
+                alert('Erro de comunicação ao tentar salvar a alocação-alvo.');
+            }
+        });
+
+        loadAlocacao(true);
+    }
+
+    // --- SEÇÃO: PROVENTOS (LISTA, RENDA MENSAL E YIELD ON COST) ---
+    const addProventoForm = document.getElementById('add-provento-form');
+    if (addProventoForm) {
+        const proventosBody = document.getElementById('proventos-table-body');
+        const nomesTipos = { DIVIDENDO: 'Dividendos', JCP: 'JCP', RENDIMENTO: 'Rendimentos' };
+        const formatMoney = (value) => value.toLocaleString('pt-BR', { minimumFractionDigits: 2, maximumFractionDigits: 2 });
+        const formatDate = (value) => value.split('-').reverse().join('/');
+        const isDarkMode = document.documentElement.classList.contains('dark');
+        const FONT_COLOR = isDarkMode ? '#e2e8f0' : '#475569';
+        let mensalChart, yocChart;
+
+        function renderCharts(result) {
+            if (typeof Chart === 'undefined') return;
+            if (mensalChart) mensalChart.destroy();
+            if (yocChart) yocChart.destroy();
+            const stacked = { x: { stacked: true, ticks: { color: FONT_COLOR } }, y: { stacked: true, ticks: { color: FONT_COLOR } } };
+            mensalChart = new Chart(document.getElementById('proventos-mensal-chart'), {
+                type: 'bar',
+                data: {
+                    labels: result.mensal.map(m => formatDate(m.mes)),
+                    datasets: [
+                        { label: 'Dividendos', data: result.mensal.map(m => m.dividendos), backgroundColor: '#16a34a' },
+                        { label: 'JCP', data: result.mensal.map(m => m.jcp), backgroundColor: '#2563eb' },
+                        { label: 'Rendimentos', data: result.mensal.map(m => m.rendimentos), backgroundColor: '#7c3aed' },
+                    ]
+                },
+                options: {
+                    responsive: true,
+                    maintainAspectRatio: false,
+                    scales: stacked,
+                    plugins: {
+                        legend: { labels: { color: FONT_COLOR } },
+                        title: { display: true, text: 'Renda Mensal (R$, líquido)', color: FONT_COLOR, font: { size: 16 } },
+                        tooltip: { callbacks: { label: c => `${c.dataset.label}: R$ ${formatMoney(c.parsed.y)}` } }
+                    }
+                }
+            });
+            const comCusto = result.yield_on_cost.filter(y => y.custo_total > 0);
+            yocChart = new Chart(document.getElementById('proventos-yoc-chart'), {
+                type: 'bar',
+                data: {
+                    labels: comCusto.map(y => y.ticker),
+                    datasets: [{ label: 'Yield on Cost (12 meses)', data: comCusto.map(y => y.yield_on_cost), backgroundColor: '#b45309' }]
+                },
+                options: {
+                    responsive: true,
+                    maintainAspectRatio: false,
+                    scales: { x: { ticks: { color: FONT_COLOR } }, y: { ticks: { color: FONT_COLOR, callback: v => `${v}%` } } },
+                    plugins: {
+                        legend: { display: false },
+                        title: { display: true, text: 'Yield on Cost (12 meses)', color: FONT_COLOR, font: { size: 16 } },
+                        tooltip: { callbacks: { label: c => `${c.parsed.y.toFixed(2).replace('.', ',')}%` } }
+                    }
+                }
+            });
+        }
+
+        async function deleteProvento(p) {
+            const aviso = p.movimentacao_id ? ' A entrada lançada na conta irá para a lixeira.' : '';
+            if (!confirm(`Excluir ${nomesTipos[p.tipo]} de ${p.ticker} pagos em ${formatDate(p.data_pagamento)}?${aviso}`)) {
+                return;
+            }
+            try {
+                const response = await fetch(`/investimentos/proventos/${p.id}`, { method: 'DELETE' });
+                const result = await response.json();
+                if (response.ok) {
+                    loadProventos();
+                } else {
+                    alert(`Erro: ${result.error}`);
+                }
+            } catch (error) {
+                alert('Erro de comunicação ao tentar excluir o provento.');
+            }
+        }
+
+        async function loadProventos() {
+            try {
+                const response = await fetch('/api/investimentos/proventos');
+                const result = await response.json();
+                if (!response.ok) {
+                    proventosBody.innerHTML = `<tr><td colspan="9" class="no-data">Erro: ${result.error}</td></tr>`;
+                    return;
+                }
+                proventosBody.innerHTML = '';
+                if (result.proventos.length === 0) {
+                    proventosBody.innerHTML = '<tr><td colspan="9" class="no-data">Nenhum provento registrado.</td></tr>';
+                }
+                result.proventos.slice().reverse().forEach(p => {
+                    const tr = document.createElement('tr');
+                    tr.className = 'table-row-item';
+                    [formatDate(p.data_pagamento), p.ticker, nomesTipos[p.tipo], formatDate(p.data_ex), p.quantidade,
+                        p.valor_por_cota.toFixed(4).replace('.', ','), formatMoney(p.imposto_retido), formatMoney(p.valor_liquido)].forEach((valor, i) => {
+                        const td = document.createElement('td');
+                        td.textContent = valor;
+                        if (i >= 4) td.className = 'text-right';
+                        tr.appendChild(td);
+                    });
+                    const acoes = document.createElement('td');
+                    acoes.className = 'action-buttons-cell';
+                    const excluir = document.createElement('button');
+                    excluir.className = 'delete-button rounded-md';
+                    excluir.textContent = 'Excluir';
+                    excluir.addEventListener('click', () => deleteProvento(p));
+                    acoes.appendChild(excluir);
+                    tr.appendChild(acoes);
+                    proventosBody.appendChild(tr);
+                });
+                document.getElementById('proventos-resumo').textContent = `Proventos líquidos nos últimos 12 meses: R$ ${formatMoney(result.total_12m)}`;
+                renderCharts(result);
+            } catch (error) {
+                console.error('Falha ao buscar os proventos:', error);
+            }
+        }
+
+        addProventoForm.addEventListener('submit', async (event) => {
+            event.preventDefault();
+            const imposto = document.getElementById('provento-imposto').value.trim();
+            const quantidade = document.getElementById('provento-quantidade').value;
+            const payload = {
+                ticker: document.getElementById('provento-ticker').value.trim().toUpperCase(),
+                tipo: document.getElementById('provento-tipo').value,
+                data_ex: document.getElementById('provento-data-ex').value,
+                data_pagamento: document.getElementById('provento-data-pagamento').value,
+                valor_por_cota: parseDecimal(document.getElementById('provento-valor').value),
+                quantidade: quantidade ? parseInt(quantidade, 10) : 0,
+                conta: document.getElementById('provento-conta').value
+            };
+            if (imposto !== '') {
+                payload.imposto_retido = parseDecimal(imposto);
+            }
+            try {
+                const response = await fetch('/investimentos/proventos', {
+                    method: 'POST',
+                    headers: { 'Content-Type': 'application/json' },
+                    body: JSON.stringify(payload)
+                });
+                const result = await response.json();
+                if (response.ok) {
+                    addProventoForm.reset();
+                    loadProventos();
+                } else {
+                    alert(`Erro: ${result.error}`);
+                }
+            } catch (error) {
+                alert('Erro de comunicação ao tentar registrar o provento.');
+            }
+        });
+
+        loadProventos();
+    }
+
+    // --- SEÇÃO: IMPOSTO DE RENDA (APURAÇÃO MENSAL E DARF) ---
+    const irAnoInput = document.getElementById('ir-ano');
+    if (irAnoInput) {
+        const irBody = document.getElementById('ir-table-body');
+        const irPdfLink = document.getElementById('ir-pdf-link');
+        const formatMoney = (value) => value.toLocaleString('pt-BR', { minimumFractionDigits: 2, maximumFractionDigits: 2 });
+        const formatDate = (value) => value.split('-').reverse().join('/');
+
+        irAnoInput.value = new Date().getFullYear();
+        const updatePdfLink = () => { irPdfLink.href = `/investimentos/ir/pdf?ano=${encodeURIComponent(irAnoInput.value)}`; };
+        updatePdfLink();
+        irAnoInput.addEventListener('change', updatePdfLink);
+
+        document.getElementById('ir-apurar-button').addEventListener('click', async () => {
+            try {
+                const response = await fetch(`/api/investimentos/ir?ano=${encodeURIComponent(irAnoInput.value)}`);
+                const result = await response.json();
+                if (!response.ok) {
+                    alert(`Erro: ${result.error}`);
+                    return;
+                }
+                irBody.innerHTML = '';
+                if (result.meses.length === 0) {
+                    irBody.innerHTML = '<tr><td colspan="9" class="no-data">Nenhuma venda registrada no ano.</td></tr>';
+                }
+                result.meses.forEach(mes => {
+                    const categoria = (nome) => mes.categorias.find(c => c.categoria === nome) || { vendas: 0, resultado: 0 };
+                    const comum = categoria('comum');
+                    const tr = document.createElement('tr');
+                    tr.className = 'table-row-item';
+                    [
+                        formatDate(mes.mes),
+                        formatMoney(comum.vendas) + (comum.isento && comum.vendas > 0 ? ' (isento)' : ''),
+                        formatMoney(comum.resultado),
+                        formatMoney(categoria('day_trade').resultado),
+                        formatMoney(categoria('fii').resultado),
+                        formatMoney(mes.imposto_devido),
+                        formatMoney(mes.irrf_compensado),
+                        mes.valor_darf > 0 ? formatMoney(mes.valor_darf) : '—',
+                        mes.vencimento ? formatDate(mes.vencimento) : (mes.imposto_devido > 0 ? 'Abaixo de R$ 10,00' : ''),
+                    ].forEach((valor, i) => {
+                        const td = document.createElement('td');
+                        td.textContent = valor;
+                        if (i > 0 && i < 8) td.className = 'text-right';
+                        tr.appendChild(td);
+                    });
+                    irBody.appendChild(tr);
+                });
+                const prejuizos = result.prejuizos_a_compensar;
+                document.getElementById('ir-resumo').textContent =
+                    `Total em DARFs: R$ ${formatMoney(result.total_darf)} · Lucro isento: R$ ${formatMoney(result.lucro_isento)} · ` +
+                    `Prejuízos a compensar: comum R$ ${formatMoney(prejuizos.comum)}, day trade R$ ${formatMoney(prejuizos.day_trade)}, FII R$ ${formatMoney(prejuizos.fii)}` +
+                    (result.custo_desconhecido.length > 0
+                        ? ` · Atenção: ${result.custo_desconhecido.join(', ')} têm saldo anterior sem preço médio; o imposto desses ativos está superestimado.`
+                        : '');
+            } catch (error) {
+                alert('Erro de comunicação ao apurar o imposto.');
             }
         });
     }
@@ -243,4 +1016,39 @@ document.addEventListener('DOMContentLoaded', () => {
             }
         });
     }
+
+    const addCriptoForm = document.getElementById('add-cripto-form');
+    if (addCriptoForm) {
+        addCriptoForm.addEventListener('submit', async (event) => {
+            event.preventDefault();
+            const simbolo = document.getElementById('add-cripto-simbolo').value.trim().toUpperCase();
+            const quantidade = document.getElementById('add-cripto-quantidade').value.trim();
+
+            if (!simbolo || !quantidade) {
+                alert('Por favor, preencha o símbolo e a quantidade.');
+                return;
+            }
+
+            try {
+                const response = await fetch('/investimentos/cripto', {
+                    method: 'POST',
+                    headers: { 'Content-Type': 'application/json' },
+                    body: JSON.stringify({
+                        simbolo: simbolo,
+                        descricao: document.getElementById('add-cripto-descricao').value,
+                        quantidade: quantidade
+                    })
+                });
+                const result = await response.json();
+                if (response.ok) {
+                    alert(result.message);
+                    window.location.reload();
+                } else {
+                    alert(`Erro: ${result.error}`);
+                }
+            } catch (error) {
+                alert('Erro de comunicação ao tentar adicionar o criptoativo.');
+            }
+        });
+    }
 });
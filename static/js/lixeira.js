@@ -0,0 +1,47 @@
+document.addEventListener('DOMContentLoaded', () => {
+    async function trashRequest(url, method) {
+        const response = await fetch(url, { method: method, headers: { 'Accept': 'application/json' } });
+        const result = await response.json();
+        if (!response.ok) {
+            throw new Error(result.error || 'Erro desconhecido.');
+        }
+        return result;
+    }
+
+    document.querySelectorAll('.restore-button').forEach(button => {
+        button.addEventListener('click', async () => {
+            try {
+                await trashRequest(`/movimentacoes/${button.dataset.id}/restore`, 'POST');
+                window.location.reload();
+            } catch (error) {
+                alert('Erro: ' + error.message);
+            }
+        });
+    });
+
+    document.querySelectorAll('.purge-button').forEach(button => {
+        button.addEventListener('click', async () => {
+            if (!confirm(`Apagar definitivamente a movimentação ${button.dataset.id}? Esta ação não pode ser desfeita.`)) return;
+            try {
+                await trashRequest(`/lixeira/${button.dataset.id}`, 'DELETE');
+                window.location.reload();
+            } catch (error) {
+                alert('Erro: ' + error.message);
+            }
+        });
+    });
+
+    const emptyButton = document.getElementById('empty-trash-button');
+    if (emptyButton) {
+        emptyButton.addEventListener('click', async () => {
+            if (!confirm('Apagar definitivamente todas as movimentações da lixeira?')) return;
+            try {
+                const result = await trashRequest('/lixeira', 'DELETE');
+                alert(result.message);
+                window.location.reload();
+            } catch (error) {
+                alert('Erro: ' + error.message);
+            }
+        });
+    }
+});
@@ -205,7 +205,15 @@ document.addEventListener('DOMContentLoaded', () => {
                 try {
                     const response = await fetch(`/movimentacoes/${id}`, { method: 'DELETE' });
                     if (response.ok) {
-                        alert('Movimentação excluída!');
+                        const result = await response.json();
+                        // Desfazer: a exclusão apenas move a movimentação para a lixeira.
+                        if (confirm('Movimentação movida para a lixeira. Deseja desfazer a exclusão?')) {
+                            const undo = await fetch(result.restore_url, { method: 'POST', headers: { 'Accept': 'application/json' } });
+                            if (!undo.ok) {
+                                const err = await undo.json();
+                                alert(`Erro ao desfazer: ${err.error}`);
+                            }
+                        }
                         window.location.reload();
                     } else {
                         const err = await response.json();
@@ -218,6 +226,82 @@ document.addEventListener('DOMContentLoaded', () => {
         });
     });
 
+    // --- Edição em lote ---
+    const bulkCheckboxes = Array.from(document.querySelectorAll('.bulk-select'));
+    const bulkSelectAll = document.getElementById('bulk-select-all');
+    const bulkApplySelected = document.getElementById('bulk-apply-selected');
+    const bulkApplyFilter = document.getElementById('bulk-apply-filter');
+
+    function selectedBulkIds() {
+        return bulkCheckboxes.filter(cb => cb.checked).map(cb => parseInt(cb.value, 10));
+    }
+
+    function updateBulkSelection() {
+        const count = selectedBulkIds().length;
+        document.getElementById('bulk-selected-count').textContent = count;
+        bulkApplySelected.disabled = count === 0;
+        if (bulkSelectAll) bulkSelectAll.checked = count > 0 && count === bulkCheckboxes.length;
+    }
+
+    // Filtro aplicado à página atual (o da URL, não o que ainda não foi submetido no formulário).
+    function currentBulkFilter() {
+        const params = new URLSearchParams(window.location.search);
+        return {
+            search_descricao: params.get('search_descricao') || '',
+            category: params.getAll('category'),
+            account: params.getAll('account'),
+            start_date: params.get('start_date') || '',
+            end_date: params.get('end_date') || '',
+            consolidated_filter: params.get('consolidated_filter') || '',
+            value_filter: params.get('value_filter') || ''
+        };
+    }
+
+    async function applyBulk(selection, description) {
+        const operation = document.getElementById('bulk-operation').value;
+        const value = document.getElementById('bulk-value').value;
+        if (!confirm(`Aplicar a edição em lote a ${description}?`)) return;
+        try {
+            const response = await fetch('/movimentacoes/bulk', {
+                method: 'POST',
+                headers: { 'Content-Type': 'application/json', 'Accept': 'application/json' },
+                body: JSON.stringify(Object.assign({ operation: operation, value: value }, selection))
+            });
+            const result = await response.json();
+            if (!response.ok) {
+                alert(`Erro: ${result.error}`);
+                return;
+            }
+            alert(result.message);
+            window.location.reload();
+        } catch (error) {
+            alert('Erro de comunicação.');
+        }
+    }
+
+    if (bulkApplySelected) {
+        bulkCheckboxes.forEach(cb => cb.addEventListener('change', updateBulkSelection));
+        if (bulkSelectAll) {
+            bulkSelectAll.addEventListener('change', () => {
+                bulkCheckboxes.forEach(cb => { cb.checked = bulkSelectAll.checked; });
+                updateBulkSelection();
+            });
+        }
+        document.getElementById('bulk-operation').addEventListener('change', (event) => {
+            const valueInput = document.getElementById('bulk-value');
+            const lists = { set_category: 'category-suggestions', set_account: 'account-suggestions' };
+            valueInput.setAttribute('list', lists[event.target.value] || '');
+            valueInput.disabled = event.target.value === 'delete';
+        });
+        bulkApplySelected.addEventListener('click', () => {
+            const ids = selectedBulkIds();
+            applyBulk({ ids: ids }, `${ids.length} movimentação(ões) selecionada(s)`);
+        });
+        bulkApplyFilter.addEventListener('click', () => {
+            applyBulk({ filter: currentBulkFilter() }, 'todas as movimentações do filtro atual');
+        });
+    }
+
     // --- Lógica dos Filtros (incluindo link de exportação) ---
     function updateExportLink() {
         const exportButton = document.getElementById(exportButtonId);
@@ -0,0 +1,89 @@
+package storage
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeS3 é um substituto local mínimo de um bucket S3 (PUT/GET/DELETE de objetos).
+func fakeS3(t *testing.T) *httptest.Server {
+	var mu sync.Mutex
+	objects := map[string][]byte{}
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=chave/") {
+			http.Error(w, "AccessDenied", http.StatusForbidden)
+			return
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		switch r.Method {
+		case http.MethodPut:
+			body, _ := io.ReadAll(r.Body)
+			if r.Header.Get("x-amz-content-sha256") != sha256Hex(body) {
+				http.Error(w, "XAmzContentSHA256Mismatch", http.StatusBadRequest)
+				return
+			}
+			objects[r.URL.Path] = body
+		case http.MethodGet:
+			body, ok := objects[r.URL.Path]
+			if !ok {
+				http.Error(w, "NoSuchKey", http.StatusNotFound)
+				return
+			}
+			w.Write(body)
+		case http.MethodDelete:
+			delete(objects, r.URL.Path)
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+}
+
+func TestS3Store_PutGetDelete(t *testing.T) {
+	server := fakeS3(t)
+	defer server.Close()
+
+	store := &S3Store{Endpoint: server.URL, Bucket: "anexos", Region: "us-east-1", AccessKey: "chave", SecretKey: "segredo"}
+	if err := store.Put("anexos/1/nota fiscal.pdf", []byte("%PDF-1.4"), "application/pdf"); err != nil {
+		t.Fatalf("Put falhou: %v", err)
+	}
+
+	r, err := store.Get("anexos/1/nota fiscal.pdf")
+	if err != nil {
+		t.Fatalf("Get falhou: %v", err)
+	}
+	data, _ := io.ReadAll(r)
+	r.Close()
+	if string(data) != "%PDF-1.4" {
+		t.Errorf("Conteúdo inesperado: %q", data)
+	}
+
+	if err := store.Delete("anexos/1/nota fiscal.pdf"); err != nil {
+		t.Fatalf("Delete falhou: %v", err)
+	}
+	if _, err := store.Get("anexos/1/nota fiscal.pdf"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Esperado ErrNotFound após apagar, obteve %v", err)
+	}
+
+	denied := &S3Store{Endpoint: server.URL, Bucket: "anexos", Region: "us-east-1", AccessKey: "outra", SecretKey: "x"}
+	if err := denied.Put("a.txt", []byte("x"), "text/plain"); err == nil {
+		t.Error("Esperado erro com credenciais recusadas pelo servidor")
+	}
+}
+
+func TestLocalStore_RejectsTraversal(t *testing.T) {
+	store := &LocalStore{Dir: t.TempDir()}
+	if err := store.Put("../fora.txt", []byte("x"), "text/plain"); err == nil {
+		t.Error("Esperado erro para chave fora do diretório")
+	}
+	if err := store.Put("anexos/1/ok.txt", []byte("x"), "text/plain"); err != nil {
+		t.Fatalf("Put falhou: %v", err)
+	}
+	if data, err := ReadAll(store, "anexos/1/ok.txt"); err != nil || string(data) != "x" {
+		t.Errorf("Esperado ler 'x', obteve %q (erro: %v)", data, err)
+	}
+}
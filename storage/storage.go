@@ -0,0 +1,191 @@
+// storage/storage.go
+package storage
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// ErrNotFound é retornado quando o objeto não existe no backend.
+var ErrNotFound = errors.New("objeto não encontrado no armazenamento")
+
+// Store é a interface implementada pelos backends de armazenamento de arquivos (anexos).
+// As chaves usam "/" como separador, independentemente do backend.
+type Store interface {
+	Put(key string, data []byte, contentType string) error
+	Get(key string) (io.ReadCloser, error)
+	Delete(key string) error
+}
+
+var (
+	current   Store
+	currentMu sync.RWMutex
+)
+
+// getEnv retorna o valor de uma variável de ambiente ou um valor padrão.
+func getEnv(key, fallback string) string {
+	if value, ok := os.LookupEnv(key); ok {
+		return value
+	}
+	return fallback
+}
+
+// FromEnv cria o Store configurado pela variável STORAGE_DRIVER ("local" ou "s3").
+func FromEnv() (Store, error) {
+	switch driver := getEnv("STORAGE_DRIVER", "local"); driver {
+	case "local":
+		return &LocalStore{Dir: getEnv("STORAGE_LOCAL_DIR", "uploads")}, nil
+	case "s3":
+		store := &S3Store{
+			Endpoint:  strings.TrimSuffix(os.Getenv("S3_ENDPOINT"), "/"),
+			Bucket:    os.Getenv("S3_BUCKET"),
+			Region:    getEnv("S3_REGION", "us-east-1"),
+			AccessKey: os.Getenv("S3_ACCESS_KEY"),
+			SecretKey: os.Getenv("S3_SECRET_KEY"),
+		}
+		if store.Endpoint == "" || store.Bucket == "" {
+			return nil, fmt.Errorf("STORAGE_DRIVER=s3 exige as variáveis S3_ENDPOINT e S3_BUCKET")
+		}
+		return store, nil
+	default:
+		return nil, fmt.Errorf("STORAGE_DRIVER '%s' não suportado", driver)
+	}
+}
+
+// Init configura o Store global a partir das variáveis de ambiente.
+func Init() error {
+	s, err := FromEnv()
+	if err != nil {
+		return err
+	}
+	SetStore(s)
+	return nil
+}
+
+// SetStore substitui o Store global (útil em testes).
+func SetStore(s Store) {
+	currentMu.Lock()
+	defer currentMu.Unlock()
+	current = s
+}
+
+// Current retorna o Store global. Se nenhum foi configurado, usa o diretório local padrão.
+func Current() Store {
+	currentMu.RLock()
+	s := current
+	currentMu.RUnlock()
+	if s == nil {
+		return &LocalStore{Dir: "uploads"}
+	}
+	return s
+}
+
+// ReadAll lê o objeto inteiro do Store informado.
+func ReadAll(s Store, key string) ([]byte, error) {
+	r, err := s.Get(key)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return io.ReadAll(r)
+}
+
+// LocalStore grava os objetos como arquivos dentro de Dir.
+type LocalStore struct {
+	Dir string
+}
+
+// path converte a chave em um caminho dentro de Dir, recusando chaves que escapem do diretório.
+func (s *LocalStore) path(key string) (string, error) {
+	clean := filepath.Clean(filepath.FromSlash(key))
+	if key == "" || filepath.IsAbs(clean) || clean == ".." || strings.HasPrefix(clean, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("chave de armazenamento inválida: '%s'", key)
+	}
+	return filepath.Join(s.Dir, clean), nil
+}
+
+// Put implementa Store.
+func (s *LocalStore) Put(key string, data []byte, contentType string) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0750); err != nil {
+		return fmt.Errorf("erro ao criar diretório de armazenamento: %w", err)
+	}
+	// Grava em arquivo temporário e renomeia, para nunca deixar um anexo pela metade.
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0640); err != nil {
+		return fmt.Errorf("erro ao gravar '%s': %w", key, err)
+	}
+	return os.Rename(tmp, path)
+}
+
+// Get implementa Store.
+func (s *LocalStore) Get(key string) (io.ReadCloser, error) {
+	path, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	return f, err
+}
+
+// Delete implementa Store. Apagar um objeto inexistente não é erro.
+func (s *LocalStore) Delete(key string) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("erro ao apagar '%s': %w", key, err)
+	}
+	return nil
+}
+
+// MemoryStore guarda os objetos em memória (testes).
+type MemoryStore struct {
+	mu      sync.Mutex
+	Objects map[string][]byte
+}
+
+// NewMemoryStore cria um MemoryStore vazio.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{Objects: make(map[string][]byte)}
+}
+
+// Put implementa Store.
+func (s *MemoryStore) Put(key string, data []byte, contentType string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.Objects[key] = append([]byte(nil), data...)
+	return nil
+}
+
+// Get implementa Store.
+func (s *MemoryStore) Get(key string) (io.ReadCloser, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	data, ok := s.Objects[key]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return io.NopCloser(bytes.NewReader(data)), nil
+}
+
+// Delete implementa Store.
+func (s *MemoryStore) Delete(key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.Objects, key)
+	return nil
+}